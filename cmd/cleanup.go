@@ -0,0 +1,167 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"simple-ec2/pkg/cfn"
+	"simple-ec2/pkg/cli"
+	"simple-ec2/pkg/ec2helper"
+	"simple-ec2/pkg/output"
+	"simple-ec2/pkg/question"
+	"simple-ec2/pkg/questionModel"
+	"simple-ec2/pkg/table"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/spf13/cobra"
+)
+
+// cleanupCmd represents the cleanup command
+var cleanupCmd = &cobra.Command{
+	Use:   "cleanup",
+	Short: "Delete orphaned resources created by simple-ec2",
+	Long: `Delete the resources created by simple-ec2 that no instance uses anymore: security groups,
+launch templates left behind by interrupted Spot launches, and the CloudFormation stacks of new VPCs`,
+	Run: cleanup,
+}
+
+// Add flags
+func init() {
+	rootCmd.AddCommand(cleanupCmd)
+
+	cleanupCmd.Flags().StringVarP(&regionFlag, "region", "r", "",
+		"The region in which to look for orphaned resources")
+	cleanupCmd.Flags().BoolVarP(&cli.IsAssumeYes, "yes", "y", false,
+		"Delete the orphaned resources without asking for confirmation. Only allowed with non-text output")
+	cleanupCmd.Flags().StringVar(&outputFormatFlag, "output", outputFormatText,
+		fmt.Sprintf("The output format, \"%s\", \"%s\" or \"%s\". Structured output lists the orphaned "+
+			"resources, and \"%s\" streams them as they are found", outputFormatText, outputFormatJson,
+			outputFormatJsonl, outputFormatJsonl))
+}
+
+// The main function
+func cleanup(cmd *cobra.Command, args []string) {
+	if !ValidateCleanupFlags() {
+		return
+	}
+
+	// Start a new session, with the default credentials and config loading
+	sess := session.Must(session.NewSessionWithOptions(session.Options{SharedConfigState: session.SharedConfigEnable}))
+	ec2helper.GetDefaultRegion(sess)
+	qh := questionModel.NewQuestionModelHelper()
+	sess = checkCredentials(sess, qh)
+	if sess == nil {
+		return
+	}
+	h := ec2helper.New(sess)
+	if regionFlag != "" {
+		if cli.ShowError(h.ValidateRegion(regionFlag), "Checking region failed") {
+			return
+		}
+		h.ChangeRegion(regionFlag)
+	}
+	c := cfn.New(h.Sess)
+
+	resources, err := findOrphanedResources(h, c, os.Stdout)
+	if cli.ShowError(err, "Finding orphaned resources failed") || len(resources) <= 0 {
+		return
+	}
+
+	// Structured output must be the only thing on stdout, so the progress is shown on stderr instead
+	out := io.Writer(os.Stdout)
+	if outputFormatFlag != outputFormatText {
+		out = os.Stderr
+	}
+
+	isConfirmed, err := question.AskCleanupConfirmation(qh, len(resources))
+	if cli.ShowError(err, "Asking cleanup confirmation failed") || !isConfirmed {
+		return
+	}
+
+	deleteOrphanedResources(h, c, resources, out)
+}
+
+// Find the orphaned resources, writing them to out in the output format
+func findOrphanedResources(h *ec2helper.EC2Helper, c *cfn.Cfn, out io.Writer) ([]ec2helper.OrphanedResource, error) {
+	switch outputFormatFlag {
+	case outputFormatJsonl:
+		resources := []ec2helper.OrphanedResource{}
+		writer := output.NewJsonLinesWriter(out)
+		err := h.FindOrphanedResources(c, func(resource ec2helper.OrphanedResource) error {
+			resources = append(resources, resource)
+			return writer.Write(resource)
+		})
+		return resources, err
+	case outputFormatJson:
+		resources, err := h.GetOrphanedResources(c)
+		if err != nil {
+			return nil, err
+		}
+		rendered, err := output.RenderJson(resources)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintln(out, rendered)
+		return resources, nil
+	}
+
+	resources, err := h.GetOrphanedResources(c)
+	if err != nil {
+		return nil, err
+	}
+	if len(resources) <= 0 {
+		fmt.Fprintln(out, "No orphaned resources found")
+		return resources, nil
+	}
+
+	data := [][]string{}
+	for _, resource := range resources {
+		data = append(data, []string{resource.Type, resource.Id, resource.Name})
+	}
+	fmt.Fprint(out, table.BuildTable(data, []string{"Type", "Id", "Name"}))
+
+	return resources, nil
+}
+
+/*
+Delete the orphaned resources, writing the progress to out. The other resources are still deleted when one fails,
+so a single stuck resource doesn't block the cleanup
+*/
+func deleteOrphanedResources(h *ec2helper.EC2Helper, c *cfn.Cfn, resources []ec2helper.OrphanedResource,
+	out io.Writer) {
+	for _, resource := range resources {
+		fmt.Fprintf(out, "Deleting %s %s...\n", resource.Type, resource.Id)
+		if err := h.DeleteOrphanedResource(c, resource); err != nil {
+			fmt.Fprintf(out, "Deleting %s failed: %s\n", resource.Id, err)
+		}
+	}
+}
+
+// Validate the cleanup flags. Return true if the flags are validated, false otherwise
+func ValidateCleanupFlags() bool {
+	if outputFormatFlag != outputFormatText && outputFormatFlag != outputFormatJson &&
+		outputFormatFlag != outputFormatJsonl {
+		fmt.Printf("Output format must be \"%s\", \"%s\" or \"%s\"\n", outputFormatText, outputFormatJson,
+			outputFormatJsonl)
+		return false
+	}
+	if err := cli.ValidateAssumeYes(outputFormatFlag != outputFormatText); err != nil {
+		fmt.Println("Error: " + err.Error())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"simple-ec2/pkg/cfn"
+	"simple-ec2/pkg/cli"
+	"simple-ec2/pkg/ec2helper"
+	th "simple-ec2/test/testhelper"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestValidateCleanupFlags_AssumeYes(t *testing.T) {
+	cli.IsAssumeYes = true
+	defer func() {
+		cli.IsAssumeYes = false
+		outputFormatFlag = outputFormatText
+	}()
+
+	// The same rule as terminate: the confirmation can only be skipped with non-text output
+	outputFormatFlag = outputFormatText
+	th.Assert(t, !ValidateCleanupFlags(), "--yes should be rejected with text output")
+
+	outputFormatFlag = outputFormatJsonl
+	th.Assert(t, ValidateCleanupFlags(), "--yes should be allowed with structured output")
+}
+
+func TestFindOrphanedResources_Json(t *testing.T) {
+	outputFormatFlag = outputFormatJson
+	defer func() {
+		outputFormatFlag = outputFormatText
+	}()
+
+	h := &ec2helper.EC2Helper{
+		Svc: &th.MockedEC2Svc{
+			LaunchTemplates: []*ec2.LaunchTemplate{
+				{LaunchTemplateId: aws.String("lt-orphan"), LaunchTemplateName: aws.String("SimpleEC2LaunchTemplate-orphan")},
+			},
+		},
+	}
+
+	out := &bytes.Buffer{}
+	resources, err := findOrphanedResources(h, &cfn.Cfn{Svc: &th.MockedCfnSvc{}}, out)
+	th.Ok(t, err)
+	th.Equals(t, 1, len(resources))
+
+	rendered := []ec2helper.OrphanedResource{}
+	th.Ok(t, json.Unmarshal(out.Bytes(), &rendered))
+	th.Equals(t, resources, rendered)
+}
+
+func TestDeleteOrphanedResources(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{}
+	h := &ec2helper.EC2Helper{Svc: mockedSvc}
+
+	out := &bytes.Buffer{}
+	deleteOrphanedResources(h, &cfn.Cfn{Svc: &th.MockedCfnSvc{}}, []ec2helper.OrphanedResource{
+		{Type: cfn.ResourceTypeLaunchTemplate, Id: "lt-orphan"},
+		{Type: "AWS::EC2::Volume", Id: "vol-orphan"},
+		{Type: cfn.ResourceTypeSecurityGroup, Id: "sg-orphan"},
+	}, out)
+
+	// A failed deletion doesn't stop the others, and all progress goes to the writer
+	th.Equals(t, []string{"lt-orphan"}, mockedSvc.DeletedLaunchTemplateIds)
+	th.Equals(t, []string{"sg-orphan"}, mockedSvc.DeletedSecurityGroupIds)
+	th.Equals(t, "Deleting AWS::EC2::LaunchTemplate lt-orphan...\n"+
+		"Deleting AWS::EC2::Volume vol-orphan...\n"+
+		"Deleting vol-orphan failed: Unsupported resource type AWS::EC2::Volume\n"+
+		"Deleting AWS::EC2::SecurityGroup sg-orphan...\n", out.String())
+}
@@ -15,7 +15,9 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"simple-ec2/pkg/cli"
 	"simple-ec2/pkg/config"
@@ -23,8 +25,11 @@ import (
 	ec2ichelper "simple-ec2/pkg/ec2instanceconnecthelper"
 	"simple-ec2/pkg/question"
 	"simple-ec2/pkg/questionModel"
+	"simple-ec2/pkg/tag"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/spf13/cobra"
 )
 
@@ -45,6 +50,19 @@ func init() {
 	connectCmd.Flags().StringVarP(&instanceIdConnectFlag, "instance-id", "n", "",
 		"The instance id of the instance you want to connect to")
 	connectCmd.Flags().BoolVarP(&isInteractive, "interactive", "i", false, "Interactive mode")
+	connectCmd.Flags().BoolVar(&isUsePrivateIp, "use-private-ip", false,
+		"Connect through the private IP address, which only works from within the network of the instance")
+	connectCmd.Flags().IntVar(&sshPortFlag, "ssh-port", config.DefaultSshPort,
+		"The port SSH listens on in the instance")
+	connectCmd.Flags().StringVar(&keyFileFlag, "key-file", "",
+		"The private key file of the key pair of the instance. By default, the key file of the key pair is looked up, "+
+			"and EC2 Instance Connect is used without one")
+	connectCmd.Flags().BoolVar(&isUseSsm, "use-ssm", false,
+		"Connect with Session Manager through the AWS CLI instead of SSH, which needs no open port or public IP address")
+	connectCmd.Flags().BoolVar(&isSearchAllRegions, "search-all-regions", false,
+		"Search all enabled regions for the instance if it isn't in the current region")
+	connectCmd.Flags().BoolVar(&isRecent, "recent", false,
+		"Select the instance among the instances connected to recently, without listing the instances of a region")
 }
 
 // The main function
@@ -56,10 +74,16 @@ func connect(cmd *cobra.Command, args []string) {
 	// Start a new session, with the default credentials and config loading
 	sess := session.Must(session.NewSessionWithOptions(session.Options{SharedConfigState: session.SharedConfigEnable}))
 	ec2helper.GetDefaultRegion(sess)
-	h := ec2helper.New(sess)
 	qh := questionModel.NewQuestionModelHelper()
+	sess = checkCredentials(sess, qh)
+	if sess == nil {
+		return
+	}
+	h := ec2helper.New(sess)
 
-	if isInteractive {
+	if isRecent {
+		connectRecent(h, qh)
+	} else if isInteractive {
 		connectInteractive(h, qh)
 	} else {
 		connectNonInteractive(h)
@@ -109,15 +133,49 @@ func connectNonInteractive(h *ec2helper.EC2Helper) {
 	// Trim leading and trailing whitespace of the instance id
 	instanceIdConnectFlag = strings.TrimSpace(instanceIdConnectFlag)
 
+	if isSearchAllRegions {
+		region, err := ChangeToInstanceRegion(h, instanceIdConnectFlag)
+		if cli.ShowError(err, "Searching all regions for the instance failed") {
+			return
+		}
+		if region != "" {
+			fmt.Printf("Instance %s found in region %s\n", instanceIdConnectFlag, region)
+		}
+	}
+
 	err := GetInstanceAndConnect(h, instanceIdConnectFlag)
 	if cli.ShowError(err, "Connecting to instance failed") {
 		return
 	}
 }
 
+// Reconnect to an instance of the connect history, in the region it was connected to in
+func connectRecent(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper) {
+	entry, err := question.AskRecentConnection(qh, config.ReadConnectHistory())
+	if cli.ShowError(err, "Asking recent connection failed") {
+		return
+	}
+
+	h.ChangeRegion(entry.Region)
+	err = CheckRecentInstanceExists(h, *entry)
+	if cli.ShowError(err, "Reconnecting to instance failed") {
+		return
+	}
+
+	err = GetInstanceAndConnect(h, entry.InstanceId)
+	if cli.ShowError(err, "Connecting to instance failed") {
+		return
+	}
+}
+
 // Validate flags using simple rules. Return true if the flags are validated, false otherwise
 func ValidateConnectFlags() bool {
-	if !isInteractive {
+	if isRecent && (isInteractive || instanceIdConnectFlag != "" || regionFlag != "" || isSearchAllRegions) {
+		fmt.Println("Error: Recent connections can't be used with interactive mode, an instance id, a region " +
+			"or a search of all regions")
+		return false
+	}
+	if !isInteractive && !isRecent {
 		if instanceIdConnectFlag == "" && regionFlag == "" {
 			fmt.Println("Not in interactive mode and no flag is specified")
 			return false
@@ -127,21 +185,186 @@ func ValidateConnectFlags() bool {
 			return false
 		}
 	}
+	if isSearchAllRegions && (isInteractive || regionFlag != "") {
+		fmt.Println("Error: All regions can only be searched in non-interactive mode without a region")
+		return false
+	}
+	if err := ec2helper.ValidateSshPort(sshPortFlag); err != nil {
+		fmt.Println("Error: " + err.Error())
+		return false
+	}
+	if isUseSsm && (keyFileFlag != "" || isUsePrivateIp) {
+		fmt.Println("Error: Session Manager doesn't connect over SSH, so it can't be used with a key file or " +
+			"the private IP address")
+		return false
+	}
+	if keyFileFlag != "" {
+		if _, err := os.Stat(keyFileFlag); err != nil {
+			fmt.Println("Error: The key file can't be read: " + err.Error())
+			return false
+		}
+	}
 
 	return true
 }
 
-// Get the information of the instance and connect to it
+/*
+Change to the region of the instance if the instance isn't in the current region, searching all enabled regions.
+Return the new region, or an empty string if the region isn't changed.
+*/
+func ChangeToInstanceRegion(h *ec2helper.EC2Helper, instanceId string) (string, error) {
+	if _, err := h.GetInstanceById(instanceId); err == nil {
+		return "", nil
+	}
+
+	region, err := h.FindInstanceRegion(instanceId)
+	if err != nil {
+		return "", err
+	}
+	h.ChangeRegion(region)
+
+	return region, nil
+}
+
+/*
+Check that an instance of the connect history still exists in the current region. An instance that no longer exists
+or is terminated is removed from the history.
+*/
+func CheckRecentInstanceExists(h *ec2helper.EC2Helper, entry config.ConnectHistoryEntry) error {
+	instance, err := h.GetInstanceById(entry.InstanceId)
+	if err != nil && !ec2helper.IsNotFoundError(err) {
+		return err
+	}
+	if err == nil && (instance.State == nil || (*instance.State.Name != ec2.InstanceStateNameShuttingDown &&
+		*instance.State.Name != ec2.InstanceStateNameTerminated)) {
+		return nil
+	}
+
+	config.RemoveFromConnectHistory(entry.InstanceId)
+	return fmt.Errorf("Instance %s no longer exists in region %s, so it is removed from the recent connections",
+		entry.InstanceId, entry.Region)
+}
+
+/*
+Record the connection to the instance in the connect history, so that it can be reconnected to with --recent.
+Recording is best effort, since the history is only a shortcut.
+*/
+func RecordConnection(h *ec2helper.EC2Helper, instance *ec2.Instance) {
+	config.RecordConnection(config.ConnectHistoryEntry{
+		InstanceId:  aws.StringValue(instance.InstanceId),
+		Region:      aws.StringValue(h.Sess.Config.Region),
+		Alias:       aws.StringValue(ec2helper.GetTagName(instance.Tags)),
+		ConnectedAt: time.Now(),
+	})
+}
+
+/*
+Tell if the instance is reached with Session Manager without --use-ssm, since it was launched without a public IP
+address. A key file or the private IP address connects over SSH instead, such as from within the network.
+*/
+func IsConnectWithSsm(instance *ec2.Instance) bool {
+	if isUsePrivateIp || keyFileFlag != "" {
+		return false
+	}
+
+	for _, instanceTag := range instance.Tags {
+		if aws.StringValue(instanceTag.Key) == tag.ConnectWithKey &&
+			aws.StringValue(instanceTag.Value) == tag.ConnectWithSsm {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Get the information of the instance and connect to it, with Session Manager if specified or if the instance was
+launched without a public IP address. The private key file of the key pair of the instance is used if given or found,
+as the default user of its AMI. Otherwise, a key is pushed with EC2 Instance Connect.
+*/
 func GetInstanceAndConnect(h *ec2helper.EC2Helper, instanceId string) error {
 	instance, err := h.GetInstanceById(instanceId)
 	if err != nil {
 		return err
 	}
 
-	err = ec2ichelper.ConnectInstance(h.Sess, instance, false)
+	// The instance may have changed state since it was selected
+	err = CheckInstanceRunning(instance)
+	if err != nil {
+		return err
+	}
+	RecordConnection(h, instance)
+
+	isSsm := isUseSsm
+	if !isSsm && IsConnectWithSsm(instance) {
+		fmt.Printf("Instance %s has no public IP address, so it is connected to with Session Manager\n", instanceId)
+		isSsm = true
+	}
+	if isSsm {
+		WarnIfNoInstanceProfile(instance)
+		return ec2ichelper.StartSsmSession(instanceId, *h.Sess.Config.Region)
+	}
+
+	keyPath := GetInstanceKeyFile(instance)
+	if keyPath != "" {
+		return ec2ichelper.ConnectInstanceWithKeyFile(instance, keyPath, h.GetInstanceUserName(instance),
+			isUsePrivateIp, sshPortFlag)
+	}
+
+	err = ec2ichelper.ConnectInstance(h.Sess, instance, false, isUsePrivateIp, sshPortFlag)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// Check that the instance is running, with a clear error otherwise, rather than a failure to connect
+func CheckInstanceRunning(instance *ec2.Instance) error {
+	state := ""
+	if instance.State != nil {
+		state = aws.StringValue(instance.State.Name)
+	}
+
+	switch state {
+	case ec2.InstanceStateNameRunning:
+		return nil
+	case ec2.InstanceStateNameStopping, ec2.InstanceStateNameStopped:
+		return fmt.Errorf("Instance %s is not running, since it is %s. Start it with simple-ec2 start and try again",
+			aws.StringValue(instance.InstanceId), state)
+	case ec2.InstanceStateNamePending:
+		return fmt.Errorf("Instance %s is not running yet, since it is pending. Please try again in a moment",
+			aws.StringValue(instance.InstanceId))
+	default:
+		return fmt.Errorf("Instance %s is not running, since it is %s", aws.StringValue(instance.InstanceId), state)
+	}
+}
+
+/*
+Warn if the instance has no instance profile. The SSM Agent of an instance can only register with Systems Manager
+with a role allowing it, such as with the AmazonSSMManagedInstanceCore policy.
+*/
+func WarnIfNoInstanceProfile(instance *ec2.Instance) {
+	if instance.IamInstanceProfile != nil {
+		return
+	}
+
+	fmt.Printf("Warning: Instance %s has no IAM instance profile, so it may not be managed by Systems Manager. "+
+		"Attach a profile with the AmazonSSMManagedInstanceCore policy if the session fails to start\n",
+		aws.StringValue(instance.InstanceId))
+}
+
+// Get the private key file to connect to the instance with, either the key file flag or the one of its key pair
+func GetInstanceKeyFile(instance *ec2.Instance) string {
+	if keyFileFlag != "" {
+		return keyFileFlag
+	}
+	if aws.StringValue(instance.KeyName) == "" {
+		return ""
+	}
+
+	keyPath := ec2ichelper.FindKeyFile(*instance.KeyName)
+	if keyPath != "" {
+		fmt.Printf("Using key file %s of key pair %s\n", keyPath, *instance.KeyName)
+	}
+	return keyPath
+}
@@ -0,0 +1,240 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"simple-ec2/pkg/config"
+	"simple-ec2/pkg/ec2helper"
+	"simple-ec2/pkg/tag"
+	th "simple-ec2/test/testhelper"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestGetInstanceKeyFile_Flag(t *testing.T) {
+	keyFileFlag = "/tmp/key.pem"
+	defer func() {
+		keyFileFlag = ""
+	}()
+
+	keyPath := GetInstanceKeyFile(&ec2.Instance{KeyName: aws.String("unit_test_key_pair")})
+	th.Equals(t, "/tmp/key.pem", keyPath)
+}
+
+func TestGetInstanceKeyFile_KeyName(t *testing.T) {
+	const testKeyName = "unit_test_key_pair"
+	err := ioutil.WriteFile(testKeyName+".pem", []byte("key"), 0600)
+	th.Ok(t, err)
+	defer os.Remove(testKeyName + ".pem")
+
+	err = th.TakeOverStdout()
+	th.Ok(t, err)
+	keyPath := GetInstanceKeyFile(&ec2.Instance{KeyName: aws.String(testKeyName)})
+	th.ReadStdout()
+	th.Equals(t, testKeyName+".pem", keyPath)
+}
+
+func TestGetInstanceKeyFile_NoKeyFile(t *testing.T) {
+	th.Equals(t, "", GetInstanceKeyFile(&ec2.Instance{}))
+	th.Equals(t, "", GetInstanceKeyFile(&ec2.Instance{KeyName: aws.String("missing_unit_test_key_pair")}))
+}
+
+func TestValidateConnectFlags_KeyFile(t *testing.T) {
+	isInteractive = true
+	sshPortFlag = config.DefaultSshPort
+	keyFileFlag = "missing_unit_test_key.pem"
+	defer func() {
+		isInteractive = false
+		sshPortFlag = 0
+		keyFileFlag = ""
+	}()
+
+	err := th.TakeOverStdout()
+	th.Ok(t, err)
+	isValid := ValidateConnectFlags()
+	th.ReadStdout()
+	th.Assert(t, !isValid, "A missing key file should not be valid")
+}
+
+func TestValidateConnectFlags_UseSsm(t *testing.T) {
+	isInteractive = true
+	sshPortFlag = config.DefaultSshPort
+	isUseSsm = true
+	defer func() {
+		isInteractive = false
+		sshPortFlag = 0
+		isUseSsm = false
+		isUsePrivateIp = false
+	}()
+
+	err := th.TakeOverStdout()
+	th.Ok(t, err)
+	isValid := ValidateConnectFlags()
+	th.ReadStdout()
+	th.Assert(t, isValid, "Session Manager should be valid on its own")
+
+	isUsePrivateIp = true
+	err = th.TakeOverStdout()
+	th.Ok(t, err)
+	isValid = ValidateConnectFlags()
+	th.ReadStdout()
+	th.Assert(t, !isValid, "Session Manager should not be valid with the private IP address")
+}
+
+func TestWarnIfNoInstanceProfile(t *testing.T) {
+	err := th.TakeOverStdout()
+	th.Ok(t, err)
+	WarnIfNoInstanceProfile(&ec2.Instance{InstanceId: aws.String("i-12345")})
+	stdout := th.ReadStdout()
+	th.Assert(t, strings.Contains(stdout, "Instance i-12345 has no IAM instance profile"),
+		"A missing instance profile should be warned about")
+
+	err = th.TakeOverStdout()
+	th.Ok(t, err)
+	WarnIfNoInstanceProfile(&ec2.Instance{
+		InstanceId:         aws.String("i-12345"),
+		IamInstanceProfile: &ec2.IamInstanceProfile{Arn: aws.String("arn:aws:iam::123456789012:instance-profile/ssm")},
+	})
+	stdout = th.ReadStdout()
+	th.Equals(t, "", stdout)
+}
+
+func TestGetInstanceAndConnect_NotRunning(t *testing.T) {
+	h := &ec2helper.EC2Helper{Svc: &th.MockedEC2Svc{
+		Instances: []*ec2.Instance{
+			{
+				InstanceId: aws.String("i-12345"),
+				State:      &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameStopped)},
+			},
+		},
+	}}
+
+	// The instance stopped after it was selected, so no connection is attempted
+	err := GetInstanceAndConnect(h, "i-12345")
+	th.Nok(t, err)
+	th.Assert(t, strings.Contains(err.Error(), "Instance i-12345 is not running"),
+		"The error should tell the instance is not running")
+}
+
+func TestCheckInstanceRunning(t *testing.T) {
+	for _, state := range []string{ec2.InstanceStateNameShuttingDown, ec2.InstanceStateNameTerminated,
+		ec2.InstanceStateNamePending} {
+		err := CheckInstanceRunning(&ec2.Instance{
+			InstanceId: aws.String("i-12345"),
+			State:      &ec2.InstanceState{Name: aws.String(state)},
+		})
+		th.Nok(t, err)
+	}
+
+	err := CheckInstanceRunning(&ec2.Instance{
+		InstanceId: aws.String("i-12345"),
+		State:      &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+	})
+	th.Ok(t, err)
+}
+
+func TestValidateConnectFlags_Recent(t *testing.T) {
+	isRecent = true
+	sshPortFlag = config.DefaultSshPort
+	defer func() {
+		isRecent = false
+		sshPortFlag = 0
+		instanceIdConnectFlag = ""
+	}()
+
+	err := th.TakeOverStdout()
+	th.Ok(t, err)
+	isValid := ValidateConnectFlags()
+	th.ReadStdout()
+	th.Assert(t, isValid, "Recent connections should be valid without an instance id")
+
+	instanceIdConnectFlag = "i-12345"
+	err = th.TakeOverStdout()
+	th.Ok(t, err)
+	isValid = ValidateConnectFlags()
+	th.ReadStdout()
+	th.Assert(t, !isValid, "Recent connections should not be valid with an instance id")
+}
+
+var testConnectHistoryPath = os.Getenv("HOME") + "/.simple-ec2/" + config.ConnectHistoryFileName
+
+// Remove the connect history for the test, and restore it afterwards
+func clearConnectHistory(t *testing.T) {
+	data, err := ioutil.ReadFile(testConnectHistoryPath)
+	os.Remove(testConnectHistoryPath)
+	t.Cleanup(func() {
+		os.Remove(testConnectHistoryPath)
+		if err == nil {
+			ioutil.WriteFile(testConnectHistoryPath, data, 0644)
+		}
+	})
+}
+
+func TestCheckRecentInstanceExists(t *testing.T) {
+	clearConnectHistory(t)
+	entry := config.ConnectHistoryEntry{InstanceId: "i-12345", Region: "us-east-1"}
+	newHelper := func(instances []*ec2.Instance) *ec2helper.EC2Helper {
+		return &ec2helper.EC2Helper{Svc: &th.MockedEC2Svc{Instances: instances}}
+	}
+
+	// A stopped instance still exists, so the error of connecting to it tells it isn't running
+	err := CheckRecentInstanceExists(newHelper([]*ec2.Instance{
+		{
+			InstanceId: aws.String("i-12345"),
+			State:      &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameStopped)},
+		},
+	}), entry)
+	th.Ok(t, err)
+
+	for _, instances := range [][]*ec2.Instance{
+		{},
+		{
+			{
+				InstanceId: aws.String("i-12345"),
+				State:      &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameTerminated)},
+			},
+		},
+	} {
+		th.Ok(t, config.RecordConnection(entry))
+		err = CheckRecentInstanceExists(newHelper(instances), entry)
+		th.Nok(t, err)
+		th.Equals(t, []config.ConnectHistoryEntry{}, config.ReadConnectHistory())
+		th.Equals(t, "Instance i-12345 no longer exists in region us-east-1, so it is removed from the recent "+
+			"connections", err.Error())
+	}
+}
+
+func TestIsConnectWithSsm(t *testing.T) {
+	defer func() {
+		isUsePrivateIp = false
+	}()
+	instance := &ec2.Instance{
+		InstanceId: aws.String("i-12345"),
+		Tags:       []*ec2.Tag{{Key: aws.String(tag.ConnectWithKey), Value: aws.String(tag.ConnectWithSsm)}},
+	}
+
+	th.Assert(t, IsConnectWithSsm(instance), "A private instance should be connected to with Session Manager")
+	th.Assert(t, !IsConnectWithSsm(&ec2.Instance{InstanceId: aws.String("i-67890")}),
+		"An instance without the tag should be connected to with SSH")
+
+	// The private IP address is reachable over SSH from within the network
+	isUsePrivateIp = true
+	th.Assert(t, !IsConnectWithSsm(instance), "The private IP address should be connected to with SSH")
+}
@@ -14,16 +14,116 @@
 package cmd
 
 import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
 	"simple-ec2/pkg/config"
+	"simple-ec2/pkg/output"
+	"simple-ec2/pkg/tag"
 )
 
 // Used for flags
 var (
-	instanceIdConnectFlag string
-	isInteractive         bool
-	isSaveConfig          bool
-	regionFlag            string
-	instanceIdFlag        []string
+	instanceIdConnectFlag  string
+	isInteractive          bool
+	isSaveConfig           bool
+	isNoSavePrompt         bool
+	regionFlag             string
+	instanceIdFlag         []string
+	subnetFromAzFlag       string
+	availabilityZoneIdFlag string
+	outputTemplateFlag     string
+	fromInstanceFlag       string
+	isClassicConfirmation  bool
+	isDescribeOnly         bool
+	isNoFallback           bool
+	isUsePrivateIp         bool
+	outputFormatFlag       string
+	isShowConsoleOutput    bool
+	timeoutFlag            time.Duration
+	imageNewestWithinFlag  int
+	isForceDefaultConfig   bool
+	isSpot                 bool
+	runCommandFlag         string
+	isTerminateAfter       bool
+	isCopy                 bool
+	isCreateMissingSg      bool
+	requirementsFileFlag   string
+	sshPortFlag            int
+	isSearchAllRegions     bool
+	userTagsFlag           []string
+	zoneTypeFlag           string
+	isValidateUserData     bool
+	rootDeviceTypeFlag     string
+	orgConfigFlag          string
+	isInstanceCountSet     bool
+	isSelectInstanceTypes  bool
+	sgRuleDescriptionFlag  string
+	isShowCli              bool
+	keyFileFlag            string
+	isUseSsm               bool
+	isDryRun               bool
+	targetGroupArnFlag     string
+	isCheapest             bool
+	vcpusFlag              int
+	memoryFlag             int
+	isRecent               bool
+	isPrivate              bool
+	filterTagsFlag         []string
 )
 
 var flagConfig = config.NewSimpleInfo()
+
+/*
+Parse the tags of the --tags flag into the flag config, warning about keys defined more than once.
+Return true if the tags are valid, false otherwise
+*/
+func ReadTagsFlag() bool {
+	userTags, duplicateKeys, err := tag.ParseTags(userTagsFlag, tag.FlagSeparator)
+	if err != nil {
+		fmt.Println("Error: " + err.Error())
+		return false
+	}
+	if len(duplicateKeys) > 0 {
+		fmt.Printf("Warning: Tags %s are defined more than once; the last values are used\n",
+			strings.Join(duplicateKeys, ", "))
+	}
+	flagConfig.UserTags = userTags
+
+	return true
+}
+
+// The failures of validating flags, collected so that they are reported together
+type validationErrors []*output.ValidationError
+
+// Add a failure of validating the flag
+func (errs *validationErrors) add(flagName, message string) {
+	*errs = append(*errs, &output.ValidationError{Field: flagName, Message: message})
+}
+
+/*
+Report the failures of validating flags to out, as a JSON array with --output json and as error lines
+otherwise. Return true if there is no failure, false otherwise
+*/
+func reportValidationErrors(errs validationErrors, out io.Writer) bool {
+	if len(errs) <= 0 {
+		return true
+	}
+
+	if outputFormatFlag == outputFormatJson {
+		rendered, err := output.RenderJson(errs)
+		if err != nil {
+			fmt.Fprintln(out, "Error: "+err.Error())
+			return false
+		}
+		fmt.Fprintln(out, rendered)
+		return false
+	}
+
+	for _, validationError := range errs {
+		fmt.Fprintln(out, "Error: "+validationError.Message)
+	}
+	return false
+}
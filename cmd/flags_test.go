@@ -0,0 +1,54 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	th "simple-ec2/test/testhelper"
+)
+
+func TestReadTagsFlag(t *testing.T) {
+	defer func() {
+		userTagsFlag = nil
+		flagConfig.UserTags = nil
+	}()
+
+	// Parse the flag like cobra does, which splits the values at commas
+	th.Ok(t, launchCmd.Flags().Set("tags", " env = dev,team=infra"))
+	th.Ok(t, launchCmd.Flags().Set("tags", "env=prod"))
+
+	th.Ok(t, th.TakeOverStdout())
+	isValid := ReadTagsFlag()
+	stdout := th.ReadStdout()
+
+	th.Assert(t, isValid, "Tags should be valid")
+	th.Equals(t, map[string]string{"env": "prod", "team": "infra"}, flagConfig.UserTags)
+	th.Assert(t, strings.Contains(stdout, "Warning: Tags env are defined more than once"),
+		"No duplicate warning in: "+stdout)
+}
+
+func TestReadTagsFlag_EmptyKey(t *testing.T) {
+	defer func() {
+		userTagsFlag = nil
+	}()
+	userTagsFlag = []string{"=value"}
+
+	th.Ok(t, th.TakeOverStdout())
+	isValid := ReadTagsFlag()
+	th.ReadStdout()
+
+	th.Assert(t, !isValid, "A tag with an empty key should be rejected")
+}
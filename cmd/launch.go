@@ -14,21 +14,31 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"simple-ec2/pkg/cli"
 	"simple-ec2/pkg/config"
 	"simple-ec2/pkg/ec2helper"
+	ec2ichelper "simple-ec2/pkg/ec2instanceconnecthelper"
+	"simple-ec2/pkg/elbhelper"
 	"simple-ec2/pkg/iamhelper"
+	"simple-ec2/pkg/output"
 	"simple-ec2/pkg/question"
 	"simple-ec2/pkg/questionModel"
+	"simple-ec2/pkg/tag"
 
 	"github.com/aws/amazon-ec2-instance-selector/v2/pkg/selector"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elbv2"
 	"github.com/spf13/cobra"
 	"golang.org/x/exp/slices"
 )
@@ -52,71 +62,353 @@ func init() {
 	launchCmd.Flags().StringVarP(&flagConfig.ImageId, "image-id", "m", "",
 		"The image id of the AMI used to launch the instance")
 	launchCmd.Flags().StringVarP(&flagConfig.SubnetId, "subnet-id", "s", "",
-		"The subnet id in which the instance will be launched")
+		fmt.Sprintf("The subnet id in which the instance will be launched, or \"%s\" or \"%s\" to pick "+
+			"a subnet of the default VPC that does or doesn't route to an internet gateway",
+			ec2helper.SubnetAutoPublic, ec2helper.SubnetAutoPrivate))
 	launchCmd.Flags().StringVarP(&flagConfig.LaunchTemplateId, "launch-template-id", "l", "",
 		"The launch template id with which the instance will be launched")
 	launchCmd.Flags().StringVarP(&flagConfig.LaunchTemplateVersion, "launch-template-version", "v", "",
 		"The launch template version with which the instance will be launched")
 	launchCmd.Flags().StringSliceVarP(&flagConfig.SecurityGroupIds, "security-group-ids", "g", nil,
 		"The security groups with which the instance will be launched")
+	launchCmd.Flags().BoolVar(&isCreateMissingSg, "create-missing-sg", false,
+		"Treat the values of --security-group-ids that aren't existing security group ids as names, "+
+			"and create security groups allowing SSH with the names that don't exist in the VPC")
 	launchCmd.Flags().BoolVarP(&isSaveConfig, "save-config", "c", false, "Save config as a JSON config file")
+	launchCmd.Flags().BoolVar(&isNoSavePrompt, "no-save-prompt", false,
+		"Don't ask whether to save the config at the end of interactive mode")
+	launchCmd.Flags().BoolVar(&isClassicConfirmation, "classic-confirmation", false,
+		"In interactive mode, confirm with a table and edit one configuration at a time instead of a form")
+	launchCmd.Flags().BoolVar(&question.IsCompactConfirmation, "compact-confirm", false,
+		"Confirm the launch with a single summary line instead of the configuration table")
+	launchCmd.Flags().BoolVar(&isSelectInstanceTypes, "select-instance-types", false,
+		"In interactive mode, select several instance types and launch one On-Demand instance of each, "+
+			"such as for benchmarking")
+	launchCmd.Flags().BoolVar(&flagConfig.NoAutoTermination, "no-auto-termination", false,
+		"Launch the instance without an auto-termination timer, even if the config file defines one")
 	launchCmd.Flags().BoolVarP(&flagConfig.KeepEbsVolumeAfterTermination, "keep-ebs", "k", false,
 		"Keep EBS volumes after instance termination")
+	launchCmd.Flags().Int64Var(&flagConfig.RootVolumeSize, "volume-size", 0,
+		"The size of the root EBS volume in GiB, which can't be smaller than the snapshot of the image")
+	launchCmd.Flags().StringVar(&flagConfig.RootVolumeType, "volume-type", "",
+		fmt.Sprintf("The type of the root EBS volume in place of the type of the image, one of %s",
+			strings.Join(ec2helper.RootVolumeTypes, ", ")))
+	launchCmd.Flags().Int64Var(&flagConfig.RootVolumeIops, "volume-iops", 0,
+		"The provisioned IOPS of the root EBS volume, needed by io1 and io2 and optional for gp3")
+	launchCmd.Flags().Int64Var(&flagConfig.RootVolumeThroughput, "volume-throughput", 0,
+		"The throughput of a gp3 root EBS volume in MiB/s")
 	launchCmd.Flags().IntVarP(&flagConfig.AutoTerminationTimerMinutes, "auto-termination-timer", "a", 0,
 		"The auto-termination timer for the instance in minutes")
+	launchCmd.Flags().StringVar(&flagConfig.AutoTerminationTimerAction, "timer-action", "",
+		fmt.Sprintf("The action when the auto-termination timer expires, \"%s\" (the default) or \"%s\"",
+			ec2.ShutdownBehaviorTerminate, ec2.ShutdownBehaviorStop))
+	launchCmd.Flags().StringVar(&flagConfig.KeyPairName, "key-pair-name", "",
+		"The name of the key pair whose public key is placed on the instance for SSH access")
 	launchCmd.Flags().StringVarP(&flagConfig.IamInstanceProfile, "iam-instance-profile", "p", "",
 		"The profile containing an IAM role to attach to the instance")
+	launchCmd.Flags().StringVar(&flagConfig.Imds, "imds", "",
+		fmt.Sprintf("The mode of the instance metadata service, one of %s. \"%s\" only allows IMDSv2, "+
+			"and \"%s\" turns the service off, which breaks IAM role credentials", strings.Join(ec2helper.ImdsModes, ", "),
+			ec2helper.ImdsRequired, ec2helper.ImdsDisabled))
+	launchCmd.Flags().BoolVar(&flagConfig.Force, "force", false,
+		"Launch even when the configuration conflicts with itself, such as an IAM instance profile "+
+			"with the metadata service disabled")
 	launchCmd.Flags().StringVarP(&flagConfig.BootScriptFilePath, "boot-script", "b", "",
 		"The absolute filepath to a bash script passed to the instance and executed after the instance starts (user data)")
-	launchCmd.Flags().StringToStringVar(&flagConfig.UserTags, "tags", nil,
-		"The tags applied to instances and volumes at launch (Example: tag1=val1,tag2=val2)")
+	launchCmd.Flags().StringVar(&flagConfig.BootScriptLinuxFilePath, "boot-script-linux", "",
+		"The absolute filepath to a boot script used when the image is Linux, in place of --boot-script")
+	launchCmd.Flags().StringVar(&flagConfig.BootScriptWindowsFilePath, "boot-script-windows", "",
+		"The absolute filepath to a boot script used when the image is Windows, in place of --boot-script")
+	launchCmd.Flags().BoolVar(&isValidateUserData, "validate-user-data", false,
+		"Check that user data starting with #cloud-config is valid YAML before launch, "+
+			"since cloud-init ignores invalid cloud-config on the instance")
+	launchCmd.Flags().StringSliceVar(&userTagsFlag, "tags", nil,
+		"The tags applied to the resources created at launch (Example: tag1=val1,tag2=val2)")
+	launchCmd.Flags().StringSliceVar(&flagConfig.InheritedVpcTagKeys, "tags-inherit-from-vpc", nil,
+		"The keys of the VPC tags applied to the instance, unless overridden by --tags (Example: key1,key2)")
+	launchCmd.Flags().IntVarP(&flagConfig.InstanceCount, "count", "n", 0,
+		"The number of instances to launch at once with the same configuration (Default: 1)")
+	launchCmd.Flags().StringVar(&flagConfig.InstanceNamePrefix, "instance-name-prefix", "",
+		"Name the instances with the prefix and their number, such as web-1 and web-2 for the prefix web")
+	launchCmd.Flags().StringSliceVar(&flagConfig.TagResourceTypes, "tag-specification-resource-types", nil,
+		fmt.Sprintf("The resource types tagged at launch, among %s (Default: %s)",
+			strings.Join(ec2helper.TagResourceTypes, ", "), strings.Join(ec2helper.DefaultTagResourceTypes, ",")))
 	launchCmd.Flags().StringVar(&flagConfig.CapacityType, "capacity-type", "",
 		fmt.Sprintf("Launch instance as \"%s\" (the default) or \"%s\"", question.DefaultCapacityTypeText.OnDemand, question.DefaultCapacityTypeText.Spot))
+	launchCmd.Flags().StringVar(&flagConfig.BootMode, "boot-mode", "",
+		fmt.Sprintf("The boot mode the image must use, \"%s\", \"%s\" or \"%s\"", ec2.BootModeValuesUefi,
+			ec2.BootModeValuesLegacyBios, ec2helper.BootModeUefiPreferred))
+	launchCmd.Flags().BoolVar(&flagConfig.NitroTpm, "tpm", false,
+		"Require NitroTPM, which needs an image with TPM 2.0 support and UEFI boot mode")
+	launchCmd.Flags().IntVar(&flagConfig.SpotBlockDurationMinutes, "spot-block-duration", 0,
+		"The duration in minutes of a defined-duration Spot instance, one of 60, 120, 180, 240, 300 or 360")
+	launchCmd.Flags().BoolVar(&isSpot, "spot", false,
+		fmt.Sprintf("Launch instance as \"%s\", a shorthand for --capacity-type %s",
+			question.DefaultCapacityTypeText.Spot, question.DefaultCapacityTypeText.Spot))
+	launchCmd.Flags().StringVar(&flagConfig.CapacityReservationPreference, "capacity-reservation-preference", "",
+		fmt.Sprintf("Launch the instance in any open capacity reservation (\"%s\") or outside of them (\"%s\")",
+			ec2.CapacityReservationPreferenceOpen, ec2.CapacityReservationPreferenceNone))
+	launchCmd.Flags().StringVar(&flagConfig.CapacityReservationId, "capacity-reservation-id", "",
+		"The id of the capacity reservation in which the instance will be launched")
+	launchCmd.Flags().StringVar(&flagConfig.HostResourceGroupArn, "host-resource-group-arn", "",
+		"The ARN of a host resource group, in which the instance is placed on a Dedicated Host by auto-placement")
+	launchCmd.Flags().StringVar(&flagConfig.Affinity, "affinity", "",
+		fmt.Sprintf("The affinity of the instance with its Dedicated Host, \"%s\" or \"%s\", which restarts the "+
+			"instance on the same host. Needs --host-resource-group-arn", ec2.AffinityDefault, ec2.AffinityHost))
+	launchCmd.Flags().BoolVar(&flagConfig.SpotSizeFallback, "spot-size-fallback", false,
+		fmt.Sprintf("When there is no Spot capacity for the instance type, try up to %d other sizes of its family",
+			ec2helper.MaxSpotSizeFallbacks))
+	launchCmd.Flags().StringVar(&flagConfig.PrivateDnsHostnameType, "private-dns-hostname-type", "",
+		fmt.Sprintf("The type of the private hostname of the instance, \"%s\" or \"%s\"", ec2.HostnameTypeIpName,
+			ec2.HostnameTypeResourceName))
+	launchCmd.Flags().StringVar(&flagConfig.CpuCredits, "cpu-credits", "",
+		fmt.Sprintf("The CPU credit mode of a burstable performance instance type, \"%s\" or \"%s\"",
+			ec2helper.CpuCreditsStandard, ec2helper.CpuCreditsUnlimited))
+	launchCmd.Flags().BoolVar(&flagConfig.EnableResourceNameDnsARecord, "enable-resource-name-dns-a-record", false,
+		"Answer DNS queries for the resource-based hostname of the instance with its IPv4 address")
+	launchCmd.Flags().BoolVar(&flagConfig.EnableResourceNameDnsAAAARecord, "enable-resource-name-dns-aaaa-record",
+		false, "Answer DNS queries for the resource-based hostname of the instance with its IPv6 address")
+	launchCmd.Flags().StringVar(&flagConfig.PrivateIpAddress, "private-ip", "",
+		"The private IP address of the instance, which must be in the CIDR block of the subnet")
+	launchCmd.Flags().StringVar(&flagConfig.SecondarySubnetId, "secondary-subnet", "",
+		"The subnet id in which a second network interface is created and attached to the instance")
+	launchCmd.Flags().StringSliceVar(&flagConfig.SecondarySecurityGroupIds, "secondary-security-groups", nil,
+		"The security groups of the second network interface")
+	launchCmd.Flags().StringVar(&subnetFromAzFlag, "subnet-from-az", "",
+		"The availability zone in which a subnet is picked for the instance, in place of a subnet id")
+	launchCmd.Flags().StringVar(&availabilityZoneIdFlag, "availability-zone-id", "",
+		"The id of the availability zone in which a subnet is picked for the instance, such as use1-az1. "+
+			"Unlike zone names, zone ids refer to the same zone in all accounts")
+	launchCmd.Flags().BoolVar(&flagConfig.AssociateCarrierIp, "associate-carrier-ip", false,
+		"Associate a carrier IP address from the carrier gateway instead of a public IP, in a Wavelength Zone subnet")
+	launchCmd.Flags().StringVar(&zoneTypeFlag, "zone-type", "",
+		fmt.Sprintf("Only offer subnets and zones of the zone type, among %s. Local Zones and Wavelength Zones "+
+			"must be opted in and only support some instance types", strings.Join(ec2helper.ZoneTypes, ", ")))
+	launchCmd.Flags().StringVar(&flagConfig.ClientToken, "client-token", "",
+		"A unique token of up to 64 ASCII characters that makes the launch idempotent, so that running the same "+
+			"command again doesn't launch another instance")
+	launchCmd.Flags().StringVar(&requirementsFileFlag, "requirements-file", "",
+		"A YAML or JSON file with the vCPUs, memory, architecture, GPUs, families and burstable performance "+
+			"an instance type must satisfy, used to select the instance type with instance selector")
+	launchCmd.Flags().BoolVar(&isCheapest, "cheapest", false,
+		"In non-interactive mode, select the instance type with the lowest price of the capacity type among the "+
+			"ones satisfying --vcpus and --memory or the requirements file, which can run the image")
+	launchCmd.Flags().IntVar(&vcpusFlag, "vcpus", 0,
+		"The vCPUs of the instance type selected with --cheapest, give or take 1")
+	launchCmd.Flags().IntVar(&memoryFlag, "memory", 0,
+		"The memory in GiB of the instance type selected with --cheapest, give or take 1 GiB")
+	launchCmd.Flags().BoolVar(&isPrivate, "private", false,
+		fmt.Sprintf("In non-interactive mode, launch without a public IP address, with IMDSv2 required and an "+
+			"instance profile allowing Session Manager, created as %s unless --iam-instance-profile is supplied. "+
+			"simple-ec2 connect reaches the instances with Session Manager", iamhelper.SsmInstanceProfileName))
+	launchCmd.Flags().StringVar(&fromInstanceFlag, "from-instance", "",
+		"The id of an existing, possibly terminated, instance whose configuration is used for the new instance")
+	launchCmd.Flags().BoolVar(&isForceDefaultConfig, "force-default-config", false,
+		"Ignore the saved config file and use system defaults for the configurations not supplied by flags")
+	launchCmd.Flags().BoolVar(&isNoFallback, "no-interactive-fallback", false,
+		"In non-interactive mode, fail with the flags to supply instead of using system defaults for missing configurations")
+	launchCmd.Flags().BoolVar(&isDescribeOnly, "describe-only", false,
+		"Validate the configuration and print a preview with the estimated cost, without launching the instance")
+	launchCmd.Flags().BoolVar(&isDryRun, "dry-run", false,
+		"Check the permissions and parameters of the launch with a dry run, without launching the instance or "+
+			"creating any resources")
+	launchCmd.Flags().BoolVar(&isShowCli, "show-cli", false,
+		"Print the equivalent AWS CLI commands for the resolved configuration, without launching the instance")
+	launchCmd.Flags().BoolVar(&isShowConsoleOutput, "show-console-output", false,
+		"Wait for the instance to boot and print its console output, to debug boot failures")
+	launchCmd.Flags().StringVar(&targetGroupArnFlag, "target-group-arn", "",
+		"The ARN of an Elastic Load Balancing target group of the VPC, with which the instances are registered "+
+			"once running")
+	launchCmd.Flags().DurationVar(&timeoutFlag, "timeout", 0,
+		"The maximum time to wait for the CloudFormation stack of a new VPC, which is deleted on timeout (Example: 10m)")
+	launchCmd.Flags().IntVar(&imageNewestWithinFlag, "image-newest-within", 0,
+		"The max age in days of the image picked by default, which fails the launch when no newer image is found")
+	launchCmd.Flags().StringVar(&rootDeviceTypeFlag, "root-device-type", "",
+		fmt.Sprintf("The root device type of the image picked by default, \"%s\" or \"%s\". By default, %s is "+
+			"picked when the instance type supports it", ec2.DeviceTypeEbs, ec2.DeviceTypeInstanceStore,
+			ec2.DeviceTypeInstanceStore))
+	launchCmd.Flags().StringVar(&outputTemplateFlag, "output-template", "",
+		"A Go template used to print each launched instance (Example: '{{.InstanceID}} {{.PublicDNS}}')")
+	launchCmd.Flags().StringVar(&runCommandFlag, "run", "",
+		"A command run over SSH on each launched instance once it accepts connections (Example: ./setup.sh)")
+	launchCmd.Flags().BoolVar(&isTerminateAfter, "terminate-after", false,
+		"Terminate the launched instances after the command of --run finishes, even if it fails")
+	launchCmd.Flags().StringVar(&sgRuleDescriptionFlag, "security-group-rule-description", "",
+		"The description of the SSH rules of the security groups created for SSH (Default: SSH from <cidr> via "+
+			"simple-ec2)")
+	launchCmd.Flags().IntVar(&flagConfig.SshPort, "ssh-port", 0,
+		fmt.Sprintf("The port SSH listens on in the instances, allowed by the security groups created for SSH "+
+			"and used by --run (Default: %d)", config.DefaultSshPort))
+	launchCmd.Flags().StringVar(&outputFormatFlag, "output", outputFormatText,
+		fmt.Sprintf("The output format of flag validation failures, of --describe-only and of the launch, \"%s\" "+
+			"or \"%s\", which reports failures as an array of {field, message} objects and exits with a non-zero "+
+			"code, describes the resolved image, instance type, VPC, subnet and security groups, and prints the "+
+			"launched instances as the only output on stdout without asking for confirmation in non-interactive "+
+			"mode", outputFormatText, outputFormatJson))
+	launchCmd.Flags().StringVar(&orgConfigFlag, "org-config", "",
+		fmt.Sprintf("The URL of a JSON document with default configurations shared by an organization, used below "+
+			"the config file and flags. It can also be supplied with the %s environment variable", config.OrgConfigEnv))
+	launchCmd.Flags().BoolVar(&isCopy, "copy", false,
+		"Copy the instance ids and SSH commands of the launched instances to the clipboard")
 }
 
+// The time EC2 takes to accept a newly created instance profile
+var instanceProfilePropagationDelay = 10 * time.Second
+
 // The main function
 func launch(cmd *cobra.Command, args []string) {
-	if !ValidateLaunchFlags(flagConfig) {
+	// A timer explicitly set to 0 clears the timer of the config file, like --no-auto-termination
+	if cmd.Flags().Changed("auto-termination-timer") && flagConfig.AutoTerminationTimerMinutes == 0 {
+		flagConfig.NoAutoTermination = true
+	}
+	// A count explicitly set to 0 is invalid, while an unset count launches a single instance
+	isInstanceCountSet = cmd.Flags().Changed("count")
+	if !ReadTagsFlag() || !ValidateLaunchFlags(flagConfig) {
+		exitOnStructuredFailure()
 		return
 	}
+	if isPrivate {
+		ApplyPrivatePreset(flagConfig)
+	}
+
+	// The launch result must be the only thing on stdout, so the questions and other messages are shown on stderr
+	stdout := os.Stdout
+	if outputFormatFlag == outputFormatJson {
+		os.Stdout = os.Stderr
+		defer func() {
+			os.Stdout = stdout
+		}()
+	}
+
+	if !launchWithOutput(stdout) {
+		exitOnStructuredFailure()
+	}
+}
 
+/*
+Launch the instances, writing the JSON description or launch result to out.
+Return true if the function is executed successfully, false otherwise
+*/
+func launchWithOutput(out io.Writer) bool {
 	// Start a new session, with the default credentials and config loading
 	sess := session.Must(session.NewSessionWithOptions(session.Options{SharedConfigState: session.SharedConfigEnable}))
 	ec2helper.GetDefaultRegion(sess)
-	h := ec2helper.New(sess)
 	qh := questionModel.NewQuestionModelHelper()
+	sess = checkCredentials(sess, qh)
+	if sess == nil {
+		return false
+	}
+	h := ec2helper.New(sess)
+	h.Timeout = timeoutFlag
+	h.ImageMaxAge = time.Duration(imageNewestWithinFlag) * 24 * time.Hour
+	h.ZoneType = zoneTypeFlag
+	h.RootDeviceType = rootDeviceTypeFlag
+	h.SecurityGroupRuleDescription = sgRuleDescriptionFlag
+	if flagConfig.Region != "" && cli.ShowError(h.ValidateRegion(flagConfig.Region), "Checking region failed") {
+		return false
+	}
+
+	orgConfig, isOrgConfigRead := ReadOrgConfig()
+	if !isOrgConfigRead {
+		return false
+	}
 
 	if isInteractive {
-		launchInteractive(h, qh)
-	} else {
-		launchNonInteractive(h, qh)
+		return launchInteractive(h, qh, orgConfig, out)
 	}
+	return launchNonInteractive(h, qh, orgConfig, out)
+}
+
+// Wrappers reading structured output detect failures from the exit code, since nothing is written to stdout
+func exitOnStructuredFailure() {
+	if outputFormatFlag == outputFormatJson {
+		os.Exit(1)
+	}
+}
+
+// Get the URL of the org config, from the --org-config flag or else the environment
+func getOrgConfigUrl() string {
+	if orgConfigFlag != "" {
+		return orgConfigFlag
+	}
+
+	return os.Getenv(config.OrgConfigEnv)
 }
 
-// Launch the instance interactively
-func launchInteractive(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper) {
+/*
+Read the org config, if a URL is supplied. A failure to read it only stops a non-interactive launch when the
+flags don't supply all the required configurations. Return false if the launch stops, true otherwise
+*/
+func ReadOrgConfig() (*config.SimpleInfo, bool) {
+	orgConfigUrl := getOrgConfigUrl()
+	if orgConfigUrl == "" {
+		return nil, true
+	}
+
+	orgConfig, err := config.ReadOrgConfig(orgConfigUrl)
+	if err == nil {
+		return orgConfig, true
+	}
+
+	missingFlags := config.GetMissingRequiredFlags(flagConfig)
+	if isInteractive || len(missingFlags) <= 0 {
+		fmt.Println("Warning: Org config not loaded; continuing without it:", err)
+		return nil, true
+	}
+	fmt.Printf("Error: Org config not loaded: %s. Supply the missing configurations with the following flags "+
+		"instead: %s\n", err, strings.Join(missingFlags, ", "))
+	return nil, false
+}
+
+/*
+Launch the instance interactively, writing the JSON launch result to out.
+Return true if the function is executed successfully, false otherwise
+*/
+func launchInteractive(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper, orgConfig *config.SimpleInfo,
+	out io.Writer) bool {
 	simpleConfig := config.NewSimpleInfo()
 
 	// Override config with flags if applicable
 	config.OverrideConfigWithFlags(simpleConfig, flagConfig)
 
 	simpleDefaultsConfig := config.NewSimpleInfo()
-	err := config.ReadConfig(simpleDefaultsConfig, nil)
-	if cli.ShowError(err, "Default config file not loaded; using system defaults instead") {
-		simpleDefaultsConfig = config.NewSimpleInfo()
+	var err error
+	if !isForceDefaultConfig {
+		err = config.ReadConfig(simpleDefaultsConfig, nil)
+		if cli.ShowError(err, "Default config file not loaded; using system defaults instead") {
+			simpleDefaultsConfig = config.NewSimpleInfo()
+		}
+	}
+	simpleDefaultsConfig = config.WithOrgDefaults(simpleDefaultsConfig, orgConfig)
+
+	// The SSH port isn't asked, so the saved one is used unless overridden
+	if simpleConfig.SshPort == 0 {
+		simpleConfig.SshPort = simpleDefaultsConfig.SshPort
 	}
 
 	if simpleConfig.Region == "" {
 		// Ask Region
 		region, err := question.AskRegion(h, qh, simpleDefaultsConfig.Region)
 		if cli.ShowError(err, "Asking region failed") {
-			return
+			return false
 		}
 		simpleConfig.Region = *region
 	}
 
 	h.ChangeRegion(simpleConfig.Region)
 
+	if fromInstanceFlag != "" && !ReadConfigFromInstance(h, simpleConfig) {
+		return false
+	}
+
+	if (subnetFromAzFlag != "" || availabilityZoneIdFlag != "") && !ReadSubnetFromAz(h, simpleConfig) {
+		return false
+	}
+
+	if ec2helper.IsAutoSubnet(simpleConfig.SubnetId) && !ReadAutoSubnet(h, simpleConfig) {
+		return false
+	}
+
 	detailedDefaultsConfig, err := h.ParseConfig(simpleDefaultsConfig)
 
 	// Ask Launch Template
@@ -124,43 +416,62 @@ func launchInteractive(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHe
 	if simpleConfig.LaunchTemplateId == "" {
 		launchTemplateId, err = question.AskLaunchTemplate(h, qh, simpleDefaultsConfig.LaunchTemplateId)
 		if err != nil {
-			return
+			return false
 		}
 	}
 
 	if *launchTemplateId != cli.ResponseNo {
 		// Use a launch template in this case.
 		simpleConfig.LaunchTemplateId = *launchTemplateId
-		UseLaunchTemplate(h, qh, simpleConfig, simpleDefaultsConfig)
-		return
+		return UseLaunchTemplate(h, qh, simpleConfig, simpleDefaultsConfig, out)
 	}
 
 	// Not using a launch template if the program is not terminated at the point
-	if simpleConfig.InstanceType == "" && !ReadInstanceType(h, qh, simpleConfig, simpleDefaultsConfig.InstanceType) {
-		return
+	var instanceTypes []string
+	if isSelectInstanceTypes {
+		instanceTypes = ReadInstanceTypes(h, qh, simpleConfig, nil)
+		if instanceTypes == nil {
+			return false
+		}
+	} else if simpleConfig.InstanceType == "" &&
+		!ReadInstanceType(h, qh, simpleConfig, simpleDefaultsConfig.InstanceType) {
+		return false
 	}
 
 	// Ask for image ID, auto-termination timer, and keeping EBS volumes after instance termination
 	if simpleConfig.ImageId == "" && !ReadImageId(h, qh, simpleConfig, simpleDefaultsConfig) {
-		return
+		return false
+	}
+	if isSelectInstanceTypes {
+		instanceTypes = ReadImageInstanceTypes(h, simpleConfig, instanceTypes)
+		if instanceTypes == nil {
+			return false
+		}
 	}
 
 	// Ask for network configuration
 	if (simpleConfig.SubnetId == "" || simpleConfig.SecurityGroupIds == nil) &&
 		!ReadNetworkConfiguration(h, qh, simpleConfig, detailedDefaultsConfig) {
-		return
+		return false
 	}
 
-	// Ask for IAM profile
-	if simpleConfig.IamInstanceProfile == "" && !ReadIamProfile(h, qh, simpleConfig, simpleDefaultsConfig.IamInstanceProfile) {
-		return
+	// Ask for IAM profile, which can't get credentials with the metadata service disabled
+	if simpleConfig.IamInstanceProfile == "" && simpleConfig.Imds != ec2helper.ImdsDisabled &&
+		!ReadIamProfile(h, qh, simpleConfig, simpleDefaultsConfig.IamInstanceProfile) {
+		return false
+	}
+
+	// Ask for key pair
+	if simpleConfig.KeyPairName == "" && !ReadKeyPair(h, qh, simpleConfig, simpleDefaultsConfig.KeyPairName) {
+		return false
 	}
 
 	// Ask for user boot data
-	if simpleConfig.BootScriptFilePath == "" {
+	if simpleConfig.BootScriptFilePath == "" && simpleConfig.BootScriptLinuxFilePath == "" &&
+		simpleConfig.BootScriptWindowsFilePath == "" {
 		err := ReadBootScript(h, qh, simpleConfig, simpleDefaultsConfig.BootScriptFilePath)
 		if err != nil {
-			return
+			return false
 		}
 	}
 
@@ -168,29 +479,59 @@ func launchInteractive(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHe
 	if len(simpleConfig.UserTags) == 0 {
 		err := ReadUserTags(h, qh, simpleConfig, simpleDefaultsConfig.UserTags)
 		if err != nil {
-			return
+			return false
 		}
 	}
-	// Ask for and set the capacity type
-	simpleConfig.CapacityType, err = question.AskCapacityType(qh, simpleConfig.InstanceType, simpleConfig.Region, simpleDefaultsConfig.CapacityType)
-	if cli.ShowError(err, "Asking capacity type failed") {
-		return
+	if isSelectInstanceTypes {
+		// A single On-Demand instance of each instance type is launched
+		simpleConfig.CapacityType = question.DefaultCapacityTypeText.OnDemand
+	} else {
+		// Ask for the number of instances
+		if simpleConfig.InstanceCount == 0 && !ReadInstanceCount(h, qh, simpleConfig, 1) {
+			return false
+		}
+
+		// Ask for and set the capacity type
+		simpleConfig.CapacityType, err = question.AskCapacityType(qh, simpleConfig.InstanceType, simpleConfig.Region, simpleDefaultsConfig.CapacityType)
+		if cli.ShowError(err, "Asking capacity type failed") {
+			return false
+		}
+	}
+
+	if isCreateMissingSg && !ReadMissingSecurityGroups(h, simpleConfig) {
+		return false
+	}
+
+	if warning := ec2helper.GetImdsWarning(simpleConfig.Imds); warning != "" {
+		fmt.Println(warning)
 	}
 
 	// Ask for confirmation or modification. Keep asking until the config is confirmed or denied
 	var detailedConfig *config.DetailedInfo
 	var confirmation string
+	var editedUserData *string
 	for {
+		// The configuration shown is the one of the first instance type, shared by the others
+		if len(instanceTypes) > 1 {
+			fmt.Printf("One instance of each instance type will be launched: %s\n", strings.Join(instanceTypes, ", "))
+		}
+
 		// Parse config first
 		detailedConfig, err = h.ParseConfig(simpleConfig)
 		if cli.ShowError(err, "Parsing config failed") {
-			return
+			return false
 		}
+		detailedConfig.UserData = editedUserData
 
 		// Ask for confirmation or modification
-		confirmation, err = question.AskConfirmationWithInput(qh, simpleConfig, detailedConfig, true)
+		if isClassicConfirmation || question.IsCompactConfirmation {
+			confirmation, err = question.AskConfirmationWithInput(qh, simpleConfig, detailedConfig, true)
+		} else {
+			confirmation, err = question.AskConfirmationForm(h, qh, simpleConfig, detailedConfig)
+			editedUserData = detailedConfig.UserData
+		}
 		if cli.ShowError(err, "Asking configuration confirmation failed") {
-			return
+			return false
 		}
 
 		// The users have confirmed or denied the config
@@ -205,181 +546,974 @@ func launchInteractive(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHe
 		// Ask questions to modify the config
 		case cli.ResourceVpc:
 			if !ReadNetworkConfiguration(h, qh, simpleConfig, detailedDefaultsConfig) {
-				return
+				return false
 			}
 		case cli.ResourceSubnet:
 			if !ReadSubnet(h, qh, simpleConfig, *detailedConfig.Subnet.VpcId, simpleDefaultsConfig.SubnetId) {
-				return
+				return false
 			}
 		case cli.ResourceSecurityGroup:
 			if !ReadSecurityGroups(h, qh, simpleConfig, *detailedConfig.Subnet.VpcId, detailedDefaultsConfig.SecurityGroups) {
-				return
+				return false
 			}
 		case cli.ResourceInstanceType:
-			if !ReadInstanceType(h, qh, simpleConfig, simpleDefaultsConfig.InstanceType) {
-				return
+			if isSelectInstanceTypes {
+				instanceTypes = ReadInstanceTypes(h, qh, simpleConfig, instanceTypes)
+				if instanceTypes == nil {
+					return false
+				}
+			} else if !ReadInstanceType(h, qh, simpleConfig, simpleDefaultsConfig.InstanceType) {
+				return false
 			}
 			if !ReadImageId(h, qh, simpleConfig, simpleDefaultsConfig) {
-				return
+				return false
+			}
+			if isSelectInstanceTypes {
+				instanceTypes = ReadImageInstanceTypes(h, simpleConfig, instanceTypes)
+				if instanceTypes == nil {
+					return false
+				}
 			}
 		case cli.ResourceImage:
 			if !ReadImageId(h, qh, simpleConfig, simpleDefaultsConfig) {
-				return
+				return false
+			}
+			if isSelectInstanceTypes {
+				instanceTypes = ReadImageInstanceTypes(h, simpleConfig, instanceTypes)
+				if instanceTypes == nil {
+					return false
+				}
+			}
+		case cli.ResourceInstanceCount:
+			if !ReadInstanceCount(h, qh, simpleConfig, simpleDefaultsConfig.InstanceCount) {
+				return false
 			}
 		case cli.ResourceKeepEbsVolume:
 			ebsVolumeAnswer, err := question.AskKeepEbsVolume(qh, simpleDefaultsConfig.KeepEbsVolumeAfterTermination)
 			if cli.ShowError(err, "Asking EBS volume persistence failed") {
-				return
+				return false
 			}
 			ReadKeepEbsVolume(simpleConfig, ebsVolumeAnswer == cli.ResponseYes)
 		case cli.ResourceAutoTerminationTimer:
 			if !ReadAutoTerminationTimer(h, qh, simpleConfig, simpleDefaultsConfig.AutoTerminationTimerMinutes) {
-				return
+				return false
 			}
 		case cli.ResourceIamInstanceProfile:
 			if !ReadIamProfile(h, qh, simpleConfig, simpleDefaultsConfig.IamInstanceProfile) {
-				return
+				return false
 			}
 		case cli.ResourceCapacityType:
 			simpleConfig.CapacityType, err = question.AskCapacityType(qh, simpleConfig.InstanceType, simpleConfig.Region, simpleDefaultsConfig.CapacityType)
 			if cli.ShowError(err, "Asking capacity type failed") {
-				return
+				return false
 			}
 		case cli.ResourceUserTags:
 			err := ReadUserTags(h, qh, simpleConfig, simpleDefaultsConfig.UserTags)
 			if err != nil {
-				return
+				return false
 			}
 		case cli.ResourceBootScriptFilePath:
 			err := ReadBootScript(h, qh, simpleConfig, simpleDefaultsConfig.BootScriptFilePath)
 			if err != nil {
-				return
+				return false
+			}
+		case cli.ResourceUserData:
+			userData := ec2helper.GetUserData(simpleConfig, detailedConfig)
+			if editedUserData != nil {
+				userData = *editedUserData
+			}
+			userData, saved, err := question.AskUserData(qh, userData)
+			if cli.ShowError(err, "Asking user data failed") {
+				return false
+			}
+			if saved {
+				editedUserData = &userData
 			}
 		}
+
+		// The edited user data is based on the previous image, timer and boot script, so it's assembled again
+		if editedUserData != nil && (confirmation == cli.ResourceInstanceType || confirmation == cli.ResourceImage ||
+			confirmation == cli.ResourceAutoTerminationTimer || confirmation == cli.ResourceBootScriptFilePath) {
+			editedUserData = nil
+			fmt.Println("The edited user data was discarded, since the values it is assembled from changed")
+		}
 	}
 
-	// Launch On-Demand or Spot instance based on capacity type
-	err = LaunchCapacityInstance(h, simpleConfig, detailedConfig, confirmation)
+	// Values edited in place in the form are parsed again before launching
+	if !isClassicConfirmation && !question.IsCompactConfirmation && confirmation == cli.ResponseYes {
+		detailedConfig, err = h.ParseConfig(simpleConfig)
+		if cli.ShowError(err, "Parsing config failed") {
+			return false
+		}
+		detailedConfig.UserData = editedUserData
+	}
+
+	// Launch On-Demand or Spot instance based on capacity type, or one instance of each selected instance type
+	if len(instanceTypes) > 1 {
+		err = LaunchInstanceTypes(h, simpleConfig, instanceTypes, confirmation, out)
+	} else {
+		_, err = LaunchCapacityInstance(h, simpleConfig, detailedConfig, confirmation, out)
+	}
 
 	if cli.ShowError(err, "Launching instance failed") {
-		return
+		return false
 	}
 	ReadSaveConfig(qh, simpleConfig)
+
+	return true
 }
 
-// Launch the instance non-interactively
-func launchNonInteractive(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper) {
+/*
+Launch the instance non-interactively, writing the JSON launch result to out.
+Return true if the function is executed successfully, false otherwise
+*/
+func launchNonInteractive(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
+	orgConfig *config.SimpleInfo, out io.Writer) bool {
 	simpleConfig := config.NewSimpleInfo()
+	isSavedConfig := false
 	if flagConfig.Region != "" {
 		simpleConfig.Region = flagConfig.Region
 		h.ChangeRegion(simpleConfig.Region)
 	}
 
-	// Try to get config from the config file
-	err := config.ReadConfig(simpleConfig, nil)
-	if cli.ShowError(err, "Default config file not loaded; using system defaults instead") {
-		// If getting config file fails, go for default values
-		simpleConfig, err = h.GetDefaultSimpleConfig()
+	if fromInstanceFlag != "" {
+		// Use the configuration of the existing instance in place of the config file
+		if !ReadConfigFromInstance(h, simpleConfig) {
+			return false
+		}
+	} else if isNoFallback {
+		// Only use the config file if it exists. Missing configurations are reported after applying the flags
+		if !isForceDefaultConfig {
+			config.ReadConfig(simpleConfig, nil)
+		}
+		simpleConfig = config.WithOrgDefaults(simpleConfig, orgConfig)
+		if simpleConfig.Region == "" {
+			simpleConfig.Region = *h.Sess.Config.Region
+		}
+	} else {
+		// The system defaults are looked up in the region of the org config, unless the flags define one
+		if flagConfig.Region == "" && orgConfig != nil && orgConfig.Region != "" {
+			h.ChangeRegion(orgConfig.Region)
+		}
+
+		/*
+			Try to get config from the config file, and go for default values if it fails or is ignored.
+			The default image is picked for the instance type of the flags, whose architecture may differ
+			from the default instance type's.
+		*/
+		var err error
+		simpleConfig, isSavedConfig, err = h.GetSavedOrDefaultConfig(simpleConfig, nil, isForceDefaultConfig,
+			flagConfig.InstanceType)
 		if cli.ShowError(err, "Generating config failed") {
-			return
+			return false
 		}
-	}
 
-	h.ChangeRegion(simpleConfig.Region)
+		// The org config is below the config file, but above the system defaults
+		if isSavedConfig {
+			simpleConfig = config.WithOrgDefaults(simpleConfig, orgConfig)
+		} else if orgConfig != nil {
+			config.OverrideConfigWithFlags(simpleConfig, orgConfig)
+		}
+	}
 
 	// Override config with flags if applicable
+	configRegion := simpleConfig.Region
 	config.OverrideConfigWithFlags(simpleConfig, flagConfig)
+	config.SetDefaultCapacityType(simpleConfig)
+	h.ChangeRegion(simpleConfig.Region)
+
+	// The instance type of a config read in another region may not be offered in the region of the flags
+	if simpleConfig.Region != configRegion && flagConfig.InstanceType == "" && simpleConfig.InstanceType != "" &&
+		cli.ShowError(h.ValidateInstanceTypeInRegion(simpleConfig.InstanceType), "Checking instance type failed") {
+		return false
+	}
+
+	if (subnetFromAzFlag != "" || availabilityZoneIdFlag != "") && !ReadSubnetFromAz(h, simpleConfig) {
+		return false
+	}
+
+	if ec2helper.IsAutoSubnet(simpleConfig.SubnetId) && !ReadAutoSubnet(h, simpleConfig) {
+		return false
+	}
+
+	if isCheapest {
+		if !ReadCheapestInstanceType(h, simpleConfig) {
+			return false
+		}
+	} else if requirementsFileFlag != "" && !ReadInstanceTypeFromRequirements(h, simpleConfig) {
+		return false
+	}
+
+	if isPrivate && !ReadSsmInstanceProfile(h, simpleConfig) {
+		return false
+	}
+
+	if isNoFallback {
+		missingFlags := config.GetMissingRequiredFlags(simpleConfig)
+		if len(missingFlags) > 0 {
+			fmt.Println("Error: Required configurations are missing. Supply them with the following flags: " +
+				strings.Join(missingFlags, ", "))
+			return false
+		}
+	}
 
 	// When the flags specify a launch template
 	if flagConfig.LaunchTemplateId != "" {
 		// If using a launch template, ignore the config file. Only read from the flags
-		UseLaunchTemplateWithConfig(h, qh, flagConfig, simpleConfig.CapacityType)
-		return
+		return UseLaunchTemplateWithConfig(h, qh, flagConfig, simpleConfig.CapacityType, out)
 	}
 
 	// When the config file specifies a launch template
 	if simpleConfig.LaunchTemplateId != "" {
-		UseLaunchTemplateWithConfig(h, qh, simpleConfig, simpleConfig.CapacityType)
-		return
+		return UseLaunchTemplateWithConfig(h, qh, simpleConfig, simpleConfig.CapacityType, out)
+	}
+
+	if isCreateMissingSg && !ReadMissingSecurityGroups(h, simpleConfig) {
+		return false
 	}
 
 	// Parse the simple string config to the detailed config with data structures for later use
 	detailedConfig, err := h.ParseConfig(simpleConfig)
+	if isSavedConfig {
+		err = ec2helper.AddForceDefaultConfigHint(err)
+	}
 	if cli.ShowError(err, "Parsing config failed") {
-		return
+		return false
+	}
+	if warning := ec2helper.GetImdsWarning(simpleConfig.Imds); warning != "" {
+		fmt.Println(warning)
 	}
 
-	confirmation, err := question.AskConfirmationWithInput(qh, simpleConfig, detailedConfig, false)
-	if cli.ShowError(err, "Asking configuration confirmation failed") {
-		return
+	/*
+		Nothing is launched when only describing, showing or dry running the launch, so no confirmation is needed.
+		Scripts reading the JSON launch result can't confirm either, so the flags are the confirmation
+	*/
+	confirmation := cli.ResponseYes
+	if !isDescribeOnly && !isShowCli && !isDryRun && outputFormatFlag != outputFormatJson {
+		confirmation, err = question.AskConfirmationWithInput(qh, simpleConfig, detailedConfig, false)
+		if cli.ShowError(err, "Asking configuration confirmation failed") {
+			return false
+		}
 	}
 
-	LaunchCapacityInstance(h, simpleConfig, detailedConfig, confirmation)
+	_, err = LaunchCapacityInstance(h, simpleConfig, detailedConfig, confirmation, out)
 
 	if cli.ShowError(err, "Launching instance failed") {
-		return
+		return false
 	}
 	ReadSaveConfig(qh, simpleConfig)
+
+	return true
 }
 
-// Launch On-Demand or Spot instance based on capacity type
+/*
+Launch On-Demand or Spot instance based on capacity type, writing the JSON description or launch result to out.
+Return the ids of the launched instances, also when a step after the launch fails
+*/
 func LaunchCapacityInstance(h *ec2helper.EC2Helper, simpleConfig *config.SimpleInfo, detailedConfig *config.DetailedInfo,
-	confirmation string) error {
+	confirmation string, out io.Writer) ([]string, error) {
+	// Config files saved by older versions have no capacity type, which means On-Demand
+	config.SetDefaultCapacityType(simpleConfig)
+
+	if isValidateUserData && detailedConfig != nil {
+		err := ec2helper.ValidateUserData(ec2helper.GetLaunchUserData(simpleConfig, detailedConfig))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if isDescribeOnly {
+		if detailedConfig == nil {
+			return nil, errors.New("Describe only mode doesn't support launch templates")
+		}
+		if confirmation != cli.ResponseYes {
+			return nil, nil
+		}
+
+		// The resolved resources are printed as structured output, for inventory
+		if outputFormatFlag == outputFormatJson {
+			description, err := output.RenderJson(output.NewLaunchDescription(detailedConfig))
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintln(out, description)
+		} else {
+			fmt.Println(question.GetLaunchPreview(h, simpleConfig, detailedConfig))
+		}
+		return nil, nil
+	}
+
+	// The dry run stops before a new VPC would be created, with the default network configuration in its place
+	if isDryRun {
+		if detailedConfig == nil {
+			return nil, errors.New("Dry run mode doesn't support launch templates")
+		}
+		if confirmation != cli.ResponseYes {
+			return nil, nil
+		}
+
+		var err error
+		if simpleConfig.CapacityType == question.DefaultCapacityTypeText.Spot {
+			err = h.DryRunLaunchSpotInstance(simpleConfig, detailedConfig)
+		} else {
+			err = h.DryRunLaunchInstance(simpleConfig, detailedConfig)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Dry run failed: %v", err)
+		}
+		fmt.Println("Dry run succeeded: you have permission to launch")
+		return nil, nil
+	}
+
+	if isShowCli {
+		if detailedConfig == nil {
+			return nil, errors.New("Showing the AWS CLI commands doesn't support launch templates")
+		}
+		if confirmation == cli.ResponseYes {
+			commands, err := ec2helper.GetLaunchCliCommands(simpleConfig, detailedConfig)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Println(strings.Join(commands, "\n"))
+		}
+		return nil, nil
+	}
+
+	// The target group is checked before launching, so that a mismatch doesn't leave instances behind
+	var elb *elbhelper.ELBHelper
+	var targetGroup *elbv2.TargetGroup
+	if targetGroupArnFlag != "" {
+		elb = elbhelper.New(h.Sess)
+		var err error
+		targetGroup, err = GetLaunchTargetGroup(elb, targetGroupArnFlag, detailedConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var instanceIds []string
 	var err error
 	if simpleConfig.CapacityType == question.DefaultCapacityTypeText.OnDemand {
-		_, err = h.LaunchInstance(simpleConfig, detailedConfig, confirmation == cli.ResponseYes)
+		instanceIds, err = h.LaunchInstance(simpleConfig, detailedConfig, confirmation == cli.ResponseYes)
 	} else {
-		err = h.LaunchSpotInstance(simpleConfig, detailedConfig, confirmation == cli.ResponseYes)
+		instanceIds, err = h.LaunchSpotInstance(simpleConfig, detailedConfig, confirmation == cli.ResponseYes)
+	}
+	if err != nil {
+		return nil, err
 	}
-	return err
+
+	if simpleConfig.InstanceNamePrefix != "" {
+		err = h.TagInstanceNames(instanceIds, simpleConfig.InstanceNamePrefix)
+		if err != nil {
+			return instanceIds, err
+		}
+	}
+
+	if simpleConfig.SecondarySubnetId != "" {
+		for _, instanceId := range instanceIds {
+			_, err = h.AttachSecondaryNetworkInterface(instanceId, simpleConfig.SecondarySubnetId,
+				simpleConfig.SecondarySecurityGroupIds)
+			if err != nil {
+				return instanceIds, err
+			}
+		}
+	}
+
+	if targetGroup != nil {
+		err = RegisterWithTargetGroup(h, elb, targetGroup, instanceIds)
+		if err != nil {
+			return instanceIds, err
+		}
+	}
+
+	if outputFormatFlag == outputFormatJson {
+		err = PrintLaunchResult(h, out, simpleConfig.CapacityType, instanceIds)
+		if err != nil {
+			return instanceIds, err
+		}
+	}
+
+	// The instances are launched already, so rendering failures are reported without failing the launch
+	cli.ShowError(PrintOutputTemplate(h, instanceIds), "Rendering the output template failed")
+
+	// The instances are launched already, so a missing clipboard is reported without failing the launch
+	if isCopy {
+		cli.ShowError(CopyLaunchDetails(h, instanceIds), "Copying instance details to the clipboard failed")
+	}
+
+	if isShowConsoleOutput {
+		err = PrintConsoleOutput(h, instanceIds)
+		if err != nil {
+			return instanceIds, err
+		}
+	}
+
+	if runCommandFlag != "" {
+		return instanceIds, h.RunCommandOnInstances(instanceIds, runCommandFlag, isTerminateAfter,
+			&ec2ichelper.InstanceCommandRunner{Sess: h.Sess, SshPort: config.GetSshPort(simpleConfig)})
+	}
+
+	return instanceIds, nil
+}
+
+/*
+Launch one On-Demand instance of each instance type, sharing the rest of the configuration, such as for
+benchmarking the instance types. The instance types that can't run the image are left out before the confirmation,
+and the configuration of every instance type is parsed again before anything is launched, so an image edited in
+the confirmation that an instance type can't run launches nothing. A failed launch doesn't stop the launches of
+the other instance types, and the results of all of them are printed at the end.
+*/
+func LaunchInstanceTypes(h *ec2helper.EC2Helper, simpleConfig *config.SimpleInfo, instanceTypes []string,
+	confirmation string, out io.Writer) error {
+	if confirmation != cli.ResponseYes {
+		return errors.New("Options not confirmed")
+	}
+	if simpleConfig.NewVPC {
+		return errors.New("A new VPC can't be created for several instance types. Please select a subnet")
+	}
+
+	typeConfigs := []*config.SimpleInfo{}
+	detailedConfigs := []*config.DetailedInfo{}
+	for _, instanceType := range instanceTypes {
+		typeConfig := *simpleConfig
+		typeConfig.InstanceType = instanceType
+		typeConfig.InstanceCount = 1
+		typeConfig.CapacityType = question.DefaultCapacityTypeText.OnDemand
+
+		// A client token can't be reused with another instance type, and instance names are kept apart
+		if simpleConfig.ClientToken != "" {
+			typeConfig.ClientToken = ec2helper.GetFallbackClientToken(simpleConfig.ClientToken, instanceType)
+		}
+		if simpleConfig.InstanceNamePrefix != "" {
+			typeConfig.InstanceNamePrefix = simpleConfig.InstanceNamePrefix + "-" + instanceType
+		}
+
+		detailedConfig, err := h.ParseConfig(&typeConfig)
+		if err != nil {
+			return fmt.Errorf("Instance type %s can't be launched: %v", instanceType, err)
+		}
+		typeConfigs = append(typeConfigs, &typeConfig)
+		detailedConfigs = append(detailedConfigs, detailedConfig)
+	}
+
+	// Nothing is launched when showing the AWS CLI commands or dry running, so there are no results to print
+	if isShowCli || isDryRun {
+		for i := range instanceTypes {
+			_, err := LaunchCapacityInstance(h, typeConfigs[i], detailedConfigs[i], confirmation, out)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	results := []string{}
+	failedCount := 0
+	for i, instanceType := range instanceTypes {
+		instanceIds, err := LaunchCapacityInstance(h, typeConfigs[i], detailedConfigs[i], confirmation, out)
+		result := fmt.Sprintf("%s: %s", instanceType, strings.Join(instanceIds, ", "))
+		if err != nil {
+			failedCount++
+			result = fmt.Sprintf("%s: failed: %v", instanceType, err)
+			if len(instanceIds) > 0 {
+				result = fmt.Sprintf("%s: %s, failed after launch: %v", instanceType, strings.Join(instanceIds, ", "),
+					err)
+			}
+		}
+		results = append(results, result)
+	}
+
+	fmt.Printf("Launched %d of %d instance types:\n", len(instanceTypes)-failedCount, len(instanceTypes))
+	for _, result := range results {
+		fmt.Println("  " + result)
+	}
+	if failedCount > 0 {
+		return fmt.Errorf("%d of %d instance types failed to launch", failedCount, len(instanceTypes))
+	}
+
+	return nil
+}
+
+/*
+Get the target group the launched instances are registered with, checking that it accepts instances of the VPC of
+the launch. The VPC of a new VPC or a launch template is only known after launching, so the check is left to the
+registration then.
+*/
+func GetLaunchTargetGroup(elb *elbhelper.ELBHelper, targetGroupArn string,
+	detailedConfig *config.DetailedInfo) (*elbv2.TargetGroup, error) {
+	targetGroup, err := elb.GetTargetGroup(targetGroupArn)
+	if err != nil {
+		return nil, err
+	}
+
+	if detailedConfig != nil && detailedConfig.Vpc != nil {
+		err = elbhelper.ValidateTargetGroupForVpc(targetGroup, aws.StringValue(detailedConfig.Vpc.VpcId))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return targetGroup, nil
+}
+
+/*
+Register the instances with the target group once they are running, since pending instances can't be registered.
+Every instance must be in the VPC of the target group.
+*/
+func RegisterWithTargetGroup(h *ec2helper.EC2Helper, elb *elbhelper.ELBHelper, targetGroup *elbv2.TargetGroup,
+	instanceIds []string) error {
+	instances, err := h.GetRunningInstances(instanceIds)
+	if err != nil {
+		return err
+	}
+
+	for _, instance := range instances {
+		err = elbhelper.ValidateTargetGroupForVpc(targetGroup, aws.StringValue(instance.VpcId))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = elb.RegisterInstances(aws.StringValue(targetGroup.TargetGroupArn), instanceIds)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Registered instances %s with target group %s\n", strings.Join(instanceIds, ", "),
+		aws.StringValue(targetGroup.TargetGroupName))
+
+	return nil
+}
+
+// Print the launched instances to out as a JSON object, with the configuration they were launched with
+func PrintLaunchResult(h *ec2helper.EC2Helper, out io.Writer, capacityType string, instanceIds []string) error {
+	instances := []*ec2.Instance{}
+	for _, instanceId := range instanceIds {
+		instance, err := h.GetInstanceById(instanceId)
+		if err != nil {
+			return err
+		}
+		instances = append(instances, instance)
+	}
+
+	rendered, err := output.RenderJson(output.NewLaunchResult(instances, *h.Sess.Config.Region, capacityType))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, rendered)
+
+	return nil
 }
 
-// Validate flags using some simple rules. Return true if the flags are validated, false otherwise
+// Wait for the instances to boot and print their console output
+func PrintConsoleOutput(h *ec2helper.EC2Helper, instanceIds []string) error {
+	for _, instanceId := range instanceIds {
+		fmt.Printf("Waiting for the console output of instance %s. This can take a few minutes\n", instanceId)
+		consoleOutput, err := h.GetConsoleOutput(instanceId)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Console output of instance %s:\n%s\n", instanceId, consoleOutput)
+	}
+
+	return nil
+}
+
+// Copy the instance ids and SSH commands of the launched instances to the clipboard, once they are running
+func CopyLaunchDetails(h *ec2helper.EC2Helper, instanceIds []string) error {
+	instances, err := h.GetRunningInstances(instanceIds)
+	if err != nil {
+		return err
+	}
+
+	summaries := []*output.InstanceSummary{}
+	for _, instance := range instances {
+		summaries = append(summaries, output.NewInstanceSummary(instance, *h.Sess.Config.Region))
+	}
+
+	err = output.CopyToClipboard(output.SystemClipboard{}, summaries)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Copied the instance details to the clipboard")
+
+	return nil
+}
+
+/*
+Render the launched instances with the output template, if specified. The instances are rendered once they are
+running, since pending instances don't have public addresses yet.
+*/
+func PrintOutputTemplate(h *ec2helper.EC2Helper, instanceIds []string) error {
+	if outputTemplateFlag == "" {
+		return nil
+	}
+
+	tmpl, err := output.ParseTemplate(outputTemplateFlag)
+	if err != nil {
+		return err
+	}
+
+	instances, err := h.GetRunningInstances(instanceIds)
+	if err != nil {
+		return err
+	}
+
+	for _, instance := range instances {
+		text, err := output.RenderTemplate(tmpl, output.NewInstanceSummary(instance, *h.Sess.Config.Region))
+		if err != nil {
+			return err
+		}
+		fmt.Println(text)
+	}
+
+	return nil
+}
+
+/*
+Validate flags using some simple rules. All the failures are reported together, as a JSON array with
+--output json. Return true if the flags are validated, false otherwise
+*/
 func ValidateLaunchFlags(flags *config.SimpleInfo) bool {
+	return reportValidationErrors(validateLaunchFlags(flags), os.Stdout)
+}
+
+// Collect the failures of validating the launch flags
+func validateLaunchFlags(flags *config.SimpleInfo) validationErrors {
+	errs := validationErrors{}
+	if flags.Region != "" {
+		if err := ec2helper.ValidateRegion(flags.Region); err != nil {
+			errs.add("region", err.Error())
+		}
+	}
+	if outputFormatFlag != outputFormatText && outputFormatFlag != outputFormatJson {
+		errs.add("output", fmt.Sprintf("Output format must be \"%s\" or \"%s\"", outputFormatText,
+			outputFormatJson))
+	}
+
+	capacityType, err := question.GetCapacityTypeFromFlags(flags.CapacityType, isSpot)
+	if err != nil {
+		errs.add("capacity-type", err.Error())
+	} else {
+		flags.CapacityType = capacityType
+	}
+
 	if flags.LaunchTemplateVersion != "" && flags.LaunchTemplateId == "" {
-		fmt.Println("Error: You can't define the version without launch template")
-		return false
+		errs.add("launch-template-version", "You can't define the version without launch template")
+	}
+	if isNoFallback && isInteractive {
+		errs.add("no-interactive-fallback", "You can't disable the interactive fallback in interactive mode")
+	}
+	if isDescribeOnly && flags.LaunchTemplateId != "" {
+		errs.add("describe-only", "You can't describe a launch with a launch template")
+	}
+	if isShowCli && flags.LaunchTemplateId != "" {
+		errs.add("show-cli", "You can't show the AWS CLI commands of a launch with a launch template")
+	}
+	if isShowCli && isDescribeOnly {
+		errs.add("show-cli", "You can't both describe a launch and show its AWS CLI commands")
+	}
+	if isDryRun && flags.LaunchTemplateId != "" {
+		errs.add("dry-run", "You can't dry run a launch with a launch template")
+	}
+	if isDryRun && (isDescribeOnly || isShowCli) {
+		errs.add("dry-run", "You can't dry run a launch while describing it or showing its AWS CLI commands. "+
+			"A description already includes the result of a dry run")
+	}
+	if fromInstanceFlag != "" && flags.LaunchTemplateId != "" {
+		errs.add("from-instance", "You can't launch from both an instance and a launch template")
+	}
+	if flags.AutoTerminationTimerAction != "" {
+		flags.AutoTerminationTimerAction = strings.ToLower(flags.AutoTerminationTimerAction)
+		if flags.AutoTerminationTimerAction != ec2.ShutdownBehaviorTerminate &&
+			flags.AutoTerminationTimerAction != ec2.ShutdownBehaviorStop {
+			errs.add("timer-action", fmt.Sprintf("Timer action must be \"%s\" or \"%s\"",
+				ec2.ShutdownBehaviorTerminate, ec2.ShutdownBehaviorStop))
+		}
+	}
+	if err := ec2helper.ValidateCapacityReservation(flags.CapacityReservationPreference,
+		flags.CapacityReservationId); err != nil {
+		errs.add("capacity-reservation-preference", err.Error())
+	}
+	if err := ec2helper.ValidateHostResourceGroupArn(flags.HostResourceGroupArn); err != nil {
+		errs.add("host-resource-group-arn", err.Error())
+	}
+	if flags.HostResourceGroupArn != "" && flags.CapacityType == question.DefaultCapacityTypeText.Spot {
+		errs.add("host-resource-group-arn", "Spot instances can't be launched on Dedicated Hosts")
+	}
+	if flags.HostResourceGroupArn != "" && flags.LaunchTemplateId != "" {
+		errs.add("host-resource-group-arn", "You can't define a host resource group with a launch template")
+	}
+	if err := ec2helper.ValidateAffinity(flags.Affinity); err != nil {
+		errs.add("affinity", err.Error())
+	}
+	if flags.KeyPairName != "" && flags.LaunchTemplateId != "" {
+		errs.add("key-pair-name", "You can't define a key pair with a launch template")
+	}
+	if flags.Affinity != "" && flags.LaunchTemplateId != "" {
+		errs.add("affinity", "You can't define an affinity with a launch template")
+	}
+	if err := ec2helper.ValidateImds(flags.Imds); err != nil {
+		errs.add("imds", err.Error())
+	}
+	if flags.Imds != "" && flags.LaunchTemplateId != "" {
+		errs.add("imds", "You can't define the metadata service mode with a launch template")
+	}
+	if flags.Imds == ec2helper.ImdsDisabled && flags.IamInstanceProfile != "" && !flags.Force {
+		errs.add("imds", "An IAM instance profile can't get credentials with the metadata service disabled. "+
+			"Use --force to launch anyway")
+	}
+	if err := ec2helper.ValidatePrivateDnsHostnameType(flags.PrivateDnsHostnameType); err != nil {
+		errs.add("private-dns-hostname-type", err.Error())
+	}
+	if err := ec2helper.ValidateCpuCredits(flags.CpuCredits); err != nil {
+		errs.add("cpu-credits", err.Error())
+	}
+	if err := ec2helper.ValidateTagResourceTypes(flags.TagResourceTypes); err != nil {
+		errs.add("tag-specification-resource-types", err.Error())
+	}
+	if err := ec2helper.ValidateInstanceNamePrefix(flags.InstanceNamePrefix, flags.UserTags); err != nil {
+		errs.add("instance-name-prefix", err.Error())
+	}
+	if err := ec2helper.ValidateSpotBlockDuration(flags.SpotBlockDurationMinutes); err != nil {
+		errs.add("spot-block-duration", err.Error())
+	}
+	if flags.SpotBlockDurationMinutes > 0 && flags.CapacityType == question.DefaultCapacityTypeText.OnDemand {
+		errs.add("spot-block-duration", "You can't define a Spot block duration for On-Demand instances")
+	}
+	if isForceDefaultConfig && fromInstanceFlag != "" {
+		errs.add("force-default-config", "You can't force the default config when launching from an instance")
+	}
+	if flags.NoAutoTermination && flags.AutoTerminationTimerMinutes > 0 {
+		errs.add("no-auto-termination", "You can't define an auto-termination timer without auto-termination")
+	}
+	if timeoutFlag < 0 {
+		errs.add("timeout", "Timeout must not be negative")
+	}
+	if imageNewestWithinFlag < 0 {
+		errs.add("image-newest-within", "The max image age must not be negative")
+	}
+	if flags.SpotSizeFallback && flags.CapacityType == question.DefaultCapacityTypeText.OnDemand {
+		errs.add("spot-size-fallback", "You can't define a Spot size fallback for On-Demand instances")
+	}
+	if flags.SpotSizeFallback && flags.LaunchTemplateId != "" {
+		errs.add("spot-size-fallback", "You can't define a Spot size fallback with a launch template")
+	}
+	if flags.BootMode != "" && flags.BootMode != ec2.BootModeValuesUefi &&
+		flags.BootMode != ec2.BootModeValuesLegacyBios && flags.BootMode != ec2helper.BootModeUefiPreferred {
+		errs.add("boot-mode", fmt.Sprintf("Boot mode must be \"%s\", \"%s\" or \"%s\"", ec2.BootModeValuesUefi,
+			ec2.BootModeValuesLegacyBios, ec2helper.BootModeUefiPreferred))
+	}
+	if flags.SecondarySecurityGroupIds != nil && flags.SecondarySubnetId == "" {
+		errs.add("secondary-security-groups", "You can't define secondary security groups without a secondary subnet")
+	}
+	if flags.SubnetId != "" && (subnetFromAzFlag != "" || availabilityZoneIdFlag != "") {
+		errs.add("subnet-id", "You can't define both the subnet id and the availability zone of the subnet")
+	}
+	if err := ec2helper.ValidateAvailabilityZone(subnetFromAzFlag, availabilityZoneIdFlag); err != nil {
+		errs.add("subnet-from-az", err.Error())
+	}
+	if err := ec2helper.ValidateZoneType(zoneTypeFlag); err != nil {
+		errs.add("zone-type", err.Error())
+	}
+	if flags.AssociateCarrierIp && zoneTypeFlag != "" && zoneTypeFlag != ec2helper.ZoneTypeWavelengthZone {
+		errs.add("associate-carrier-ip", fmt.Sprintf("A carrier IP can only be associated in a %s subnet, not a %s one",
+			ec2helper.ZoneTypeWavelengthZone, zoneTypeFlag))
+	}
+	if flags.AssociateCarrierIp && flags.LaunchTemplateId != "" {
+		errs.add("associate-carrier-ip", "You can't associate a carrier IP when launching with a launch template")
+	}
+	if orgConfigUrl := getOrgConfigUrl(); orgConfigUrl != "" {
+		if err := config.ValidateOrgConfigUrl(orgConfigUrl); err != nil {
+			errs.add("org-config", err.Error())
+		}
 	}
-	if flags.BootScriptFilePath != "" {
-		_, err := os.Stat(flags.BootScriptFilePath)
+	if err := ec2helper.ValidateSecurityGroupRuleDescription(sgRuleDescriptionFlag); err != nil {
+		errs.add("security-group-rule-description", err.Error())
+	}
+	if err := ec2helper.ValidateRootDeviceType(rootDeviceTypeFlag); err != nil {
+		errs.add("root-device-type", err.Error())
+	}
+	if rootDeviceTypeFlag != "" && (flags.ImageId != "" || flags.LaunchTemplateId != "") {
+		errs.add("root-device-type",
+			"You can't define the root device type of the default image with an image id or a launch template")
+	}
+	if requirementsFileFlag != "" && flags.InstanceType != "" {
+		errs.add("requirements-file", "You can't define both the instance type and a requirements file")
+	}
+	if requirementsFileFlag != "" && flags.LaunchTemplateId != "" {
+		errs.add("requirements-file",
+			"You can't define a requirements file when launching with a launch template")
+	}
+	if isCheapest && isInteractive {
+		errs.add("cheapest", "The cheapest instance type can only be selected in non-interactive mode")
+	}
+	if isCheapest && (flags.InstanceType != "" || flags.LaunchTemplateId != "") {
+		errs.add("cheapest",
+			"You can't select the cheapest instance type with an instance type or a launch template")
+	}
+	if isCheapest && requirementsFileFlag == "" && (vcpusFlag <= 0 || memoryFlag <= 0) {
+		errs.add("cheapest", "The cheapest instance type needs positive --vcpus and --memory, or a requirements file")
+	}
+	if (vcpusFlag != 0 || memoryFlag != 0) && (!isCheapest || requirementsFileFlag != "") {
+		errs.add("vcpus", "--vcpus and --memory only apply to --cheapest without a requirements file")
+	}
+	if isPrivate {
+		if isInteractive {
+			errs.add("private", "Private instances can only be launched in non-interactive mode")
+		}
+		if flags.LaunchTemplateId != "" {
+			errs.add("private", "You can't launch private instances with a launch template, whose network "+
+				"configuration is used")
+		}
+		if flags.Imds != "" && flags.Imds != ec2helper.ImdsRequired {
+			errs.add("private", fmt.Sprintf("Private instances require IMDSv2, so --imds can only be %s",
+				ec2helper.ImdsRequired))
+		}
+		if flags.AssociateCarrierIp {
+			errs.add("private", "You can't associate a carrier IP with private instances, which have no public exposure")
+		}
+		if runCommandFlag != "" {
+			errs.add("private", "You can't run a command on private instances, since --run connects over SSH "+
+				"through a public address")
+		}
+	}
+	if flags.SshPort != 0 {
+		if err := ec2helper.ValidateSshPort(flags.SshPort); err != nil {
+			errs.add("ssh-port", err.Error())
+		}
+	}
+	if err := ec2helper.ValidateClientToken(flags.ClientToken); err != nil {
+		errs.add("client-token", err.Error())
+	}
+	if flags.PrivateIpAddress != "" && net.ParseIP(flags.PrivateIpAddress) == nil {
+		errs.add("private-ip", "Private IP address is invalid")
+	}
+	if flags.InstanceCount != 0 || isInstanceCountSet {
+		if err := ec2helper.ValidateInstanceCount(flags.InstanceCount, flags.PrivateIpAddress); err != nil {
+			errs.add("count", err.Error())
+		}
+	}
+	if flags.RootVolumeSize < 0 {
+		errs.add("volume-size", "The root volume size must be at least 1 GiB")
+	}
+	if flags.RootVolumeSize != 0 && flags.LaunchTemplateId != "" {
+		errs.add("volume-size", "You can't set the root volume size when launching with a launch template")
+	}
+	if err := ec2helper.ValidateRootVolumeType(flags.RootVolumeType, flags.RootVolumeIops,
+		flags.RootVolumeThroughput); err != nil {
+		errs.add("volume-type", err.Error())
+	}
+	if flags.RootVolumeType != "" && flags.LaunchTemplateId != "" {
+		errs.add("volume-type", "You can't set the root volume type when launching with a launch template")
+	}
+	if isCreateMissingSg && flags.SecurityGroupIds == nil {
+		errs.add("create-missing-sg",
+			"Missing security groups can only be created for the values of --security-group-ids")
+	}
+	if isCreateMissingSg && flags.LaunchTemplateId != "" {
+		errs.add("create-missing-sg",
+			"You can't create missing security groups when launching with a launch template")
+	}
+	if isCreateMissingSg && (isDescribeOnly || isShowCli || isDryRun) {
+		errs.add("create-missing-sg",
+			"You can't create missing security groups when previewing a launch, since no resource is created")
+	}
+	if isSelectInstanceTypes && outputFormatFlag == outputFormatJson {
+		errs.add("select-instance-types", "Several instance types can't be launched with JSON output")
+	}
+	if isSelectInstanceTypes && !isInteractive {
+		errs.add("select-instance-types", "Several instance types can only be selected in interactive mode")
+	}
+	if isSelectInstanceTypes && (flags.InstanceType != "" || flags.LaunchTemplateId != "") {
+		errs.add("select-instance-types",
+			"You can't select several instance types with an instance type or a launch template")
+	}
+	if isSelectInstanceTypes && (flags.InstanceCount > 1 || flags.CapacityType == question.DefaultCapacityTypeText.Spot) {
+		errs.add("select-instance-types",
+			"Selecting several instance types launches a single On-Demand instance of each")
+	}
+	if isClassicConfirmation && question.IsCompactConfirmation {
+		errs.add("classic-confirmation", "You can't use both the classic and the compact confirmation")
+	}
+	if isCopy && isDescribeOnly {
+		errs.add("copy", "You can't copy instance details in describe only mode, since no instance is launched")
+	}
+	if isCopy && isShowCli {
+		errs.add("copy", "You can't copy instance details when showing the AWS CLI commands, since no instance is launched")
+	}
+	if isCopy && isDryRun {
+		errs.add("copy", "You can't copy instance details in dry run mode, since no instance is launched")
+	}
+	if isTerminateAfter && runCommandFlag == "" {
+		errs.add("terminate-after", "Instances can only be terminated after running a command with --run")
+	}
+	if runCommandFlag != "" && isDescribeOnly {
+		errs.add("run", "You can't run a command in describe only mode, since no instance is launched")
+	}
+	if runCommandFlag != "" && isShowCli {
+		errs.add("run", "You can't run a command when showing the AWS CLI commands, since no instance is launched")
+	}
+	if runCommandFlag != "" && isDryRun {
+		errs.add("run", "You can't run a command in dry run mode, since no instance is launched")
+	}
+	if targetGroupArnFlag != "" {
+		err := elbhelper.ValidateTargetGroupArn(targetGroupArnFlag)
 		if err != nil {
-			fmt.Println("Error: Boot script file path invalid or does not exist")
-			return false
+			errs.add("target-group-arn", err.Error())
 		}
 	}
-
-	if flags.CapacityType != "" {
-		if strings.ToLower(flags.CapacityType) == strings.ToLower(question.DefaultCapacityTypeText.OnDemand) {
-			flags.CapacityType = question.DefaultCapacityTypeText.OnDemand
-		} else if strings.ToLower(flags.CapacityType) == strings.ToLower(question.DefaultCapacityTypeText.Spot) {
-			flags.CapacityType = question.DefaultCapacityTypeText.Spot
-		} else {
-			fmt.Printf("Error: Capacity type must be \"%s\" or \"%s\"\n", question.DefaultCapacityTypeText.OnDemand, question.DefaultCapacityTypeText.Spot)
-			return false
+	if outputTemplateFlag != "" {
+		err := output.ValidateTemplate(outputTemplateFlag)
+		if err != nil {
+			errs.add("output-template", "Output template invalid: "+err.Error())
+		}
+	}
+	if flags.BootScriptFilePath != "" &&
+		(flags.BootScriptLinuxFilePath != "" || flags.BootScriptWindowsFilePath != "") {
+		errs.add("boot-script",
+			"You can't define both a boot script for all platforms and boot scripts per platform")
+	}
+	bootScriptFlags := map[string]string{
+		"boot-script":         flags.BootScriptFilePath,
+		"boot-script-linux":   flags.BootScriptLinuxFilePath,
+		"boot-script-windows": flags.BootScriptWindowsFilePath,
+	}
+	for _, flagName := range []string{"boot-script", "boot-script-linux", "boot-script-windows"} {
+		if bootScriptFilePath := bootScriptFlags[flagName]; bootScriptFilePath != "" {
+			_, err := os.Stat(bootScriptFilePath)
+			if err != nil {
+				errs.add(flagName, "Boot script file path invalid or does not exist")
+			}
 		}
 	}
 
-	return true
+	return errs
 }
 
-// Ask for version and launch with the launch template.
+/*
+Ask for version and launch with the launch template, writing the JSON launch result to out.
+Return true if the function is executed successfully, false otherwise
+*/
 func UseLaunchTemplate(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
-	simpleConfig *config.SimpleInfo, defaultsConfig *config.SimpleInfo) {
+	simpleConfig *config.SimpleInfo, defaultsConfig *config.SimpleInfo, out io.Writer) bool {
 	// Ask Launch Template version, if not specified already
 	if simpleConfig.LaunchTemplateVersion == "" {
 		launchTemplateVersion, err := question.AskLaunchTemplateVersion(h, qh, simpleConfig.LaunchTemplateId, defaultsConfig.LaunchTemplateVersion)
 		if cli.ShowError(err, "Asking launch template version failed") {
-			return
+			return false
 		}
 		simpleConfig.LaunchTemplateVersion = *launchTemplateVersion
 	}
 
-	LaunchWithLaunchTemplate(h, qh, simpleConfig, defaultsConfig.CapacityType)
+	return LaunchWithLaunchTemplate(h, qh, simpleConfig, defaultsConfig.CapacityType, out)
 }
 
-// Use a launch template with config
+/*
+Use a launch template with config, writing the JSON launch result to out.
+Return true if the function is executed successfully, false otherwise
+*/
 func UseLaunchTemplateWithConfig(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
-	simpleConfig *config.SimpleInfo, defaultCapacityType string) {
+	simpleConfig *config.SimpleInfo, defaultCapacityType string, out io.Writer) bool {
 	/*
 		Deciding the version of the launch template. If no version is specified,
 		use the default version.
@@ -388,7 +1522,7 @@ func UseLaunchTemplateWithConfig(h *ec2helper.EC2Helper, qh *questionModel.Quest
 	if simpleConfig.LaunchTemplateVersion == "" {
 		launchTemplate, err := h.GetLaunchTemplateById(simpleConfig.LaunchTemplateId)
 		if cli.ShowError(err, "The specified launch template is not available") {
-			return
+			return false
 		}
 		launchTemplateVersion = strconv.FormatInt(*launchTemplate.DefaultVersionNumber, 10)
 	} else {
@@ -396,31 +1530,43 @@ func UseLaunchTemplateWithConfig(h *ec2helper.EC2Helper, qh *questionModel.Quest
 	}
 	simpleConfig.LaunchTemplateVersion = launchTemplateVersion
 
-	LaunchWithLaunchTemplate(h, qh, simpleConfig, defaultCapacityType)
+	return LaunchWithLaunchTemplate(h, qh, simpleConfig, defaultCapacityType, out)
 }
 
-// Launch an instance with a launch template
+/*
+Launch an instance with a launch template, writing the JSON launch result to out.
+Return true if the function is executed successfully, false otherwise
+*/
 func LaunchWithLaunchTemplate(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
-	simpleConfig *config.SimpleInfo, defaultCapacityType string) {
+	simpleConfig *config.SimpleInfo, defaultCapacityType string, out io.Writer) bool {
+	err := h.ValidateLaunchTemplateVersion(simpleConfig.LaunchTemplateId, simpleConfig.LaunchTemplateVersion)
+	if cli.ShowError(err, "Checking launch template version failed") {
+		return false
+	}
 	versions, err := h.GetLaunchTemplateVersions(simpleConfig.LaunchTemplateId,
 		&simpleConfig.LaunchTemplateVersion)
+	if cli.ShowError(err, "Getting launch template version failed") {
+		return false
+	}
 	templateData := versions[0].LaunchTemplateData
 	simpleConfig.CapacityType, err = question.AskCapacityType(qh, *templateData.InstanceType, simpleConfig.Region, defaultCapacityType)
 	if cli.ShowError(err, "Asking capacity type failed") {
-		return
+		return false
 	}
 
 	confirmation, err := question.AskConfirmationWithTemplate(h, qh, simpleConfig)
 	if cli.ShowError(err, "Asking confirmation with launch template failed") {
-		return
+		return false
 	}
 
 	// Launch the instance.
-	err = LaunchCapacityInstance(h, simpleConfig, nil, *confirmation)
+	_, err = LaunchCapacityInstance(h, simpleConfig, nil, *confirmation, out)
 	if cli.ShowError(err, "Launching instance failed") {
-		return
+		return false
 	}
 	ReadSaveConfig(qh, simpleConfig)
+
+	return true
 }
 
 /*
@@ -429,6 +1575,22 @@ Return true if the function is executed successfully, false otherwise.
 */
 func ReadInstanceType(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
 	simpleConfig *config.SimpleInfo, defaultInstanceType string) bool {
+	// The requirements file replaces the vCPUs and memory questions
+	if requirementsFileFlag != "" {
+		filters, err := readRequirementsFilters()
+		if cli.ShowError(err, "Reading requirements file failed") {
+			return false
+		}
+
+		instanceType, err := question.AskInstanceTypeFromFilters(h, qh, selector.New(h.Sess), filters)
+		if cli.ShowError(err, "Asking instance type failed") {
+			return false
+		}
+		simpleConfig.InstanceType = *instanceType
+
+		return true
+	}
+
 	// Ask if the users want to enter an instance type
 	instanceTypeResponse, err := question.AskIfEnterInstanceType(h, qh, defaultInstanceType)
 	if cli.ShowError(err, "Asking instance type failed") {
@@ -450,17 +1612,7 @@ func ReadInstanceType(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHel
 		// Instantiate a new instance of a selector with the AWS session
 		instanceSelector := selector.New(h.Sess)
 
-		vcpus, err := question.AskInstanceTypeVCpu(h, qh)
-		if cli.ShowError(err, "Asking vCPUs failed") {
-			return false
-		}
-
-		memoryGib, err := question.AskInstanceTypeMemory(h, qh)
-		if cli.ShowError(err, "Asking memory failed") {
-			return false
-		}
-
-		instanceType, err = question.AskInstanceTypeInstanceSelector(h, qh, instanceSelector, vcpus, memoryGib)
+		instanceType, err = question.AskInstanceTypeFromRequirements(h, qh, instanceSelector)
 		if cli.ShowError(err, "Asking instance type failed") {
 			return false
 		}
@@ -474,6 +1626,53 @@ func ReadInstanceType(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHel
 	return true
 }
 
+/*
+Ask user input for several instance types, setting the first one in the config, which is shared by the others.
+Return the selected instance types if the function is executed successfully, nil otherwise
+*/
+func ReadInstanceTypes(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
+	simpleConfig *config.SimpleInfo, defaultInstanceTypes []string) []string {
+	instanceTypes, err := question.AskInstanceTypes(h, qh, defaultInstanceTypes)
+	if cli.ShowError(err, "Asking instance types failed") {
+		return nil
+	}
+	simpleConfig.InstanceType = instanceTypes[0]
+
+	return instanceTypes
+}
+
+/*
+Leave out the selected instance types that can't run the image, which is selected for the first instance type and
+shared by all of them, so that no instance type fails to launch after the others are launched.
+Return the instance types that can run the image, or nil if none can or the check fails
+*/
+func ReadImageInstanceTypes(h *ec2helper.EC2Helper, simpleConfig *config.SimpleInfo, instanceTypes []string) []string {
+	image, err := h.GetImageById(simpleConfig.ImageId)
+	if cli.ShowError(err, "Getting image failed") {
+		return nil
+	}
+
+	compatibleInstanceTypes := []string{}
+	for _, instanceType := range instanceTypes {
+		instanceTypeInfo, err := h.GetInstanceType(instanceType)
+		if cli.ShowError(err, "Getting instance type failed") {
+			return nil
+		}
+		if err = ec2helper.ValidateImageArchitecture(image, instanceTypeInfo); err != nil {
+			fmt.Printf("%v, so instance type %s is left out\n", err, instanceType)
+			continue
+		}
+		compatibleInstanceTypes = append(compatibleInstanceTypes, instanceType)
+	}
+	if len(compatibleInstanceTypes) <= 0 {
+		fmt.Printf("Error: None of the selected instance types can run image %s\n", simpleConfig.ImageId)
+		return nil
+	}
+	simpleConfig.InstanceType = compatibleInstanceTypes[0]
+
+	return compatibleInstanceTypes
+}
+
 /*
 Ask user input for an image id. The user can select from provided options orenter a valid image id.
 Return true if the function is executed successfully, false otherwise
@@ -496,9 +1695,19 @@ func ReadImageId(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
 		ReadKeepEbsVolume(simpleConfig, ebsVolumeAnswer == cli.ResponseYes)
 	}
 
+	rootVolume := ec2helper.GetRootEbsVolume(image)
+	if simpleConfig.RootVolumeSize == 0 && rootVolume != nil && rootVolume.VolumeSize != nil &&
+		!ReadRootVolumeSize(h, qh, simpleConfig, *rootVolume.VolumeSize, defaultsConfig.RootVolumeSize) {
+		return false
+	}
+	if simpleConfig.RootVolumeType == "" && rootVolume != nil &&
+		!ReadRootVolumeType(h, qh, simpleConfig, aws.StringValue(rootVolume.VolumeType), defaultsConfig) {
+		return false
+	}
+
 	// Auto-termination only supports Linux for now
-	if simpleConfig.AutoTerminationTimerMinutes == 0 && image.PlatformDetails != nil &&
-		ec2helper.IsLinux(*image.PlatformDetails) {
+	if simpleConfig.AutoTerminationTimerMinutes == 0 && !simpleConfig.NoAutoTermination &&
+		image.PlatformDetails != nil && ec2helper.IsLinux(*image.PlatformDetails) {
 		return ReadAutoTerminationTimer(h, qh, simpleConfig, defaultsConfig.AutoTerminationTimerMinutes)
 	}
 
@@ -524,6 +1733,89 @@ func ReadAutoTerminationTimer(h *ec2helper.EC2Helper, qh *questionModel.Question
 	return true
 }
 
+/*
+Ask user input for the number of instances to launch.
+Return true if the function is executed successfully, false otherwise
+*/
+func ReadInstanceCount(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
+	simpleConfig *config.SimpleInfo, defaultCount int) bool {
+	var instanceCount int
+	countResponse, err := question.AskInstanceCount(h, qh, defaultCount)
+	if err == nil {
+		instanceCount, err = strconv.Atoi(countResponse)
+	}
+	if cli.ShowError(err, "Asking instance count failed") {
+		return false
+	}
+	simpleConfig.InstanceCount = instanceCount
+	return true
+}
+
+/*
+Ask user input for the size of the root EBS volume. The size is only set when it differs from the snapshot of the
+image, so the image keeps its own size otherwise.
+Return true if the function is executed successfully, false otherwise
+*/
+func ReadRootVolumeSize(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
+	simpleConfig *config.SimpleInfo, snapshotSize, defaultSize int64) bool {
+	var rootVolumeSize int64
+	sizeResponse, err := question.AskEbsVolumeSize(h, qh, snapshotSize, defaultSize)
+	if err == nil {
+		rootVolumeSize, err = strconv.ParseInt(sizeResponse, 10, 64)
+	}
+	if cli.ShowError(err, "Asking root volume size failed") {
+		return false
+	}
+	if rootVolumeSize != snapshotSize {
+		simpleConfig.RootVolumeSize = rootVolumeSize
+	}
+	return true
+}
+
+/*
+Ask user input for the type of the root EBS volume, with the IOPS of provisioned IOPS volumes and the throughput
+of gp3 volumes. The type is only set when it differs from the type of the image.
+Return true if the function is executed successfully, false otherwise
+*/
+func ReadRootVolumeType(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
+	simpleConfig *config.SimpleInfo, imageVolumeType string, defaultsConfig *config.SimpleInfo) bool {
+	defaultVolumeType := imageVolumeType
+	if defaultsConfig.RootVolumeType != "" {
+		defaultVolumeType = defaultsConfig.RootVolumeType
+	}
+	volumeType, err := question.AskEbsVolumeType(qh, defaultVolumeType)
+	if cli.ShowError(err, "Asking root volume type failed") {
+		return false
+	}
+	if volumeType == imageVolumeType {
+		return true
+	}
+
+	var iops, throughput int64
+	if volumeType == ec2.VolumeTypeIo1 || volumeType == ec2.VolumeTypeIo2 {
+		iopsResponse, err := question.AskEbsVolumeIops(h, qh, volumeType, defaultsConfig.RootVolumeIops)
+		if err == nil {
+			iops, err = strconv.ParseInt(iopsResponse, 10, 64)
+		}
+		if cli.ShowError(err, "Asking root volume IOPS failed") {
+			return false
+		}
+	} else if volumeType == ec2.VolumeTypeGp3 {
+		throughputResponse, err := question.AskEbsVolumeThroughput(h, qh, defaultsConfig.RootVolumeThroughput)
+		if err == nil {
+			throughput, err = strconv.ParseInt(throughputResponse, 10, 64)
+		}
+		if cli.ShowError(err, "Asking root volume throughput failed") {
+			return false
+		}
+	}
+
+	simpleConfig.RootVolumeType = volumeType
+	simpleConfig.RootVolumeIops = iops
+	simpleConfig.RootVolumeThroughput = throughput
+	return true
+}
+
 /*
 Ask user input for keeping EBS volumes after instance termination.
 Return true if the function is executed successfully, false otherwise
@@ -595,6 +1887,254 @@ func ReadSubnet(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
 	return true
 }
 
+/*
+Copy the configuration of the instance specified by the flag, which may be terminated. Flags still take
+precedence over the copied configuration.
+Return true if the function is executed successfully, false otherwise
+*/
+func ReadConfigFromInstance(h *ec2helper.EC2Helper, simpleConfig *config.SimpleInfo) bool {
+	instance, err := h.GetInstanceById(fromInstanceFlag)
+	if cli.ShowError(err, "Getting instance to launch from failed") {
+		return false
+	}
+
+	*simpleConfig = *config.FromInstance(instance)
+	simpleConfig.Region = *h.Sess.Config.Region
+	config.OverrideConfigWithFlags(simpleConfig, flagConfig)
+
+	return true
+}
+
+/*
+Pick a subnet in the availability zone specified by the name or id flag. The subnet is picked from the VPC of the
+configured subnet if there is one, or the default VPC otherwise.
+Return true if the function is executed successfully, false otherwise
+*/
+func ReadSubnetFromAz(h *ec2helper.EC2Helper, simpleConfig *config.SimpleInfo) bool {
+	var vpcId string
+	if simpleConfig.SubnetId != "" {
+		subnet, err := h.GetSubnetById(simpleConfig.SubnetId)
+		if cli.ShowError(err, "Getting subnet failed") {
+			return false
+		}
+		vpcId = *subnet.VpcId
+	} else {
+		vpc, err := h.GetDefaultVpc()
+		if cli.ShowError(err, "Getting default VPC failed") {
+			return false
+		}
+		if vpc == nil {
+			fmt.Println("Error: No default VPC available to pick a subnet from")
+			return false
+		}
+		vpcId = *vpc.VpcId
+	}
+
+	az := subnetFromAzFlag
+	if availabilityZoneIdFlag != "" {
+		var err error
+		az, err = h.GetAvailabilityZoneNameById(availabilityZoneIdFlag)
+		if cli.ShowError(err, "Getting availability zone failed") {
+			return false
+		}
+	}
+
+	zoneTypes, err := h.GetZoneTypesByName()
+	if cli.ShowError(err, "Getting availability zones failed") {
+		return false
+	}
+	zoneType, found := zoneTypes[az]
+	if !found {
+		if h.ZoneType != "" {
+			fmt.Printf("Error: Zone %s is not an available zone of type %s\n", az, h.ZoneType)
+		} else {
+			fmt.Printf("Error: Zone %s is not available\n", az)
+		}
+		return false
+	}
+	if warning := ec2helper.GetZoneTypeWarning(az, zoneType); warning != "" {
+		fmt.Println(warning)
+	}
+
+	subnet, err := h.GetSubnetInVpcByAz(vpcId, az)
+	if cli.ShowError(err, "Picking subnet from availability zone failed") {
+		return false
+	}
+
+	// Treat the picked subnet as if it was specified with the subnet id flag
+	simpleConfig.SubnetId = *subnet.SubnetId
+	flagConfig.SubnetId = *subnet.SubnetId
+
+	return true
+}
+
+/*
+Pick a public or private subnet of the default VPC, in place of the auto-public or auto-private subnet id.
+Return true if the function is executed successfully, false otherwise
+*/
+func ReadAutoSubnet(h *ec2helper.EC2Helper, simpleConfig *config.SimpleInfo) bool {
+	subnet, err := h.GetAutoSubnet(simpleConfig.SubnetId)
+	if cli.ShowError(err, "Picking subnet failed") {
+		return false
+	}
+	fmt.Printf("Picked subnet %s for %s\n", *subnet.SubnetId, simpleConfig.SubnetId)
+
+	// Treat the picked subnet as if it was specified with the subnet id flag
+	simpleConfig.SubnetId = *subnet.SubnetId
+	flagConfig.SubnetId = *subnet.SubnetId
+
+	return true
+}
+
+/*
+Select the first instance type satisfying the requirements file, in place of the instance type flag.
+Return true if the function is executed successfully, false otherwise
+*/
+func ReadInstanceTypeFromRequirements(h *ec2helper.EC2Helper, simpleConfig *config.SimpleInfo) bool {
+	filters, err := readRequirementsFilters()
+	if cli.ShowError(err, "Reading requirements file failed") {
+		return false
+	}
+
+	instanceTypes, err := h.GetInstanceTypesFromInstanceSelector(selector.New(h.Sess), filters)
+	if cli.ShowError(err, "Selecting instance type failed") {
+		return false
+	}
+	if len(instanceTypes) == 0 {
+		fmt.Println("Error: No instance types satisfy the requirements file")
+		return false
+	}
+
+	simpleConfig.InstanceType = *instanceTypes[0].InstanceType
+	fmt.Printf("Selected instance type %s from the requirements file\n", simpleConfig.InstanceType)
+
+	return true
+}
+
+/*
+Select the instance type with the lowest price of the capacity type among the ones satisfying the vCPUs and memory
+flags or the requirements file, in place of the instance type flag. Unless the requirements define an architecture,
+only instance types of the architecture of the image are candidates.
+Return true if the function is executed successfully, false otherwise
+*/
+func ReadCheapestInstanceType(h *ec2helper.EC2Helper, simpleConfig *config.SimpleInfo) bool {
+	var filters *selector.Filters
+	var err error
+	if requirementsFileFlag != "" {
+		filters, err = readRequirementsFilters()
+	} else {
+		filters, err = ec2helper.GetInstanceSelectorFilters(vcpusFlag, memoryFlag)
+	}
+	if cli.ShowError(err, "Reading instance type requirements failed") {
+		return false
+	}
+
+	if filters.CPUArchitecture == nil && simpleConfig.ImageId != "" {
+		image, err := h.GetImageById(simpleConfig.ImageId)
+		if cli.ShowError(err, "Getting image failed") {
+			return false
+		}
+		filters.CPUArchitecture = image.Architecture
+	}
+
+	instanceTypes, err := h.GetInstanceTypesFromInstanceSelector(selector.New(h.Sess), filters)
+	if cli.ShowError(err, "Selecting instance type failed") {
+		return false
+	}
+	if len(instanceTypes) == 0 {
+		fmt.Println("Error: No instance types satisfy the requirements")
+		return false
+	}
+
+	isSpot := simpleConfig.CapacityType == question.DefaultCapacityTypeText.Spot
+	instanceType, price, err := question.GetCheapestInstanceType(instanceTypes, simpleConfig.Region, isSpot)
+	if cli.ShowError(err, "Selecting the cheapest instance type failed") {
+		return false
+	}
+
+	simpleConfig.InstanceType = *instanceType.InstanceType
+	fmt.Printf("Selected instance type %s, the cheapest of %d candidates at %s %s\n", simpleConfig.InstanceType,
+		len(instanceTypes), question.FormatHourlyPrice(price), simpleConfig.CapacityType)
+
+	return true
+}
+
+// Set the options of the private preset in the flags: no public IP address and IMDSv2 required
+func ApplyPrivatePreset(flags *config.SimpleInfo) {
+	flags.NoPublicIp = true
+	flags.Imds = ec2helper.ImdsRequired
+}
+
+/*
+Use the instance profile allowing Session Manager for private instances, unless the config has an instance profile.
+The instance profile is created if it doesn't exist, except when nothing is launched.
+Return true if the function is executed successfully, false otherwise
+*/
+func ReadSsmInstanceProfile(h *ec2helper.EC2Helper, simpleConfig *config.SimpleInfo) bool {
+	if simpleConfig.IamInstanceProfile != "" {
+		return true
+	}
+
+	simpleConfig.IamInstanceProfile = iamhelper.SsmInstanceProfileName
+	if isDescribeOnly || isShowCli || isDryRun {
+		return true
+	}
+
+	isCreated, err := iamhelper.New(h.Sess).GetOrCreateSsmInstanceProfile(*h.Sess.Config.Region)
+	if cli.ShowError(err, "Getting the Session Manager instance profile failed") {
+		return false
+	}
+
+	// EC2 may reject an instance profile created moments ago
+	if isCreated {
+		fmt.Printf("Created instance profile %s allowing Session Manager. Waiting for it to be usable...\n",
+			iamhelper.SsmInstanceProfileName)
+		time.Sleep(instanceProfilePropagationDelay)
+	}
+
+	return true
+}
+
+// Read the requirements file into instance selector filters
+func readRequirementsFilters() (*selector.Filters, error) {
+	requirements, err := config.ReadInstanceRequirements(requirementsFileFlag)
+	if err != nil {
+		return nil, err
+	}
+
+	return ec2helper.GetRequirementsFilters(requirements)
+}
+
+/*
+Create the security groups given by name with the security group ids flag that don't exist in the VPC of the subnet.
+Existing security groups are used as they are. Nothing is created when nothing is launched.
+Return true if the function is executed successfully, false otherwise
+*/
+func ReadMissingSecurityGroups(h *ec2helper.EC2Helper, simpleConfig *config.SimpleInfo) bool {
+	if isDescribeOnly || isShowCli || isDryRun {
+		fmt.Println("Error: Missing security groups can't be created when previewing a launch")
+		return false
+	}
+	if simpleConfig.NewVPC {
+		fmt.Println("Error: Missing security groups can't be created when a new VPC is created")
+		return false
+	}
+
+	subnet, err := h.GetSubnetById(simpleConfig.SubnetId)
+	if cli.ShowError(err, "Getting subnet failed") {
+		return false
+	}
+
+	groupIds, err := h.GetOrCreateSecurityGroups(*subnet.VpcId, simpleConfig.SecurityGroupIds,
+		config.GetSshPort(simpleConfig))
+	if cli.ShowError(err, "Creating missing security groups failed") {
+		return false
+	}
+	simpleConfig.SecurityGroupIds = groupIds
+
+	return true
+}
+
 /*
 Ask user input for subnet placeholder. The user can select from provided options.
 Return true if the function is executed successfully, false otherwise
@@ -629,7 +2169,7 @@ func ReadSecurityGroups(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelH
 
 	// Create a new security group for SSH if the users selects "new"
 	if slices.Contains(securityGroupAnswer, cli.ResponseNew) {
-		newSecurityGroupId, err := h.CreateSecurityGroupForSsh(vpcId)
+		newSecurityGroupId, err := h.CreateSecurityGroupForSsh(vpcId, config.GetSshPort(simpleConfig), "")
 		if cli.ShowError(err, "Creating new security group for SSH failed") {
 			return false
 		}
@@ -685,6 +2225,36 @@ func ReadIamProfile(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelpe
 	return true
 }
 
+/*
+Ask user input for a key pair. A new key pair is created right away, with its private key saved to a file.
+Return true if the function is executed successfully, false otherwise
+*/
+func ReadKeyPair(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
+	simpleConfig *config.SimpleInfo, defaultKeyPair string) bool {
+	keyPairAnswer, err := question.AskKeyPair(h, qh, defaultKeyPair)
+	if cli.ShowError(err, "Asking key pair failed") {
+		return false
+	}
+
+	switch keyPairAnswer {
+	case cli.ResponseNo:
+		simpleConfig.KeyPairName = ""
+	case cli.ResponseNew:
+		keyName, err := question.AskKeyPairName(h, qh)
+		if cli.ShowError(err, "Asking key pair name failed") {
+			return false
+		}
+		_, err = h.CreateKeyPair(keyName)
+		if cli.ShowError(err, "Creating key pair failed") {
+			return false
+		}
+		simpleConfig.KeyPairName = keyName
+	default:
+		simpleConfig.KeyPairName = keyPairAnswer
+	}
+	return true
+}
+
 /*
 Ask user input for filepath containing boot script.
 Return true if the function is executed successfully, false otherwise
@@ -739,10 +2309,12 @@ func ReadUserTags(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
 	}
 
 	//convert user input tag1|val1,tag2|val2 to map
-	tags := strings.Split(userTagsAnswer, ",") //[tag1|val1, tag2|val2]
-	for _, tag := range tags {
-		kv := strings.Split(tag, "|") //[tag1, val1]
-		simpleConfig.UserTags[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	userTags, _, err := tag.ParseTags(strings.Split(userTagsAnswer, ","), tag.InteractiveSeparator)
+	if cli.ShowError(err, "Parsing user tags failed") {
+		return err
+	}
+	for key, value := range userTags {
+		simpleConfig.UserTags[key] = value
 	}
 	return nil
 }
@@ -752,18 +2324,19 @@ Ask user input for config saving.
 If the user chooses to save the config, save the config as a JSON config file.
 */
 func ReadSaveConfig(qh *questionModel.QuestionModelHelper, simpleConfig *config.SimpleInfo) {
-	isSaveRequired := isSaveConfig
-	if !isSaveRequired && isInteractive {
-		// Ask if the user wants to save the config. If so, save the config
-		answer, err := question.AskSaveConfig(qh)
-		if cli.ShowError(err, "Asking save configurations failed") {
-			return
-		}
-		isSaveRequired = answer == cli.ResponseYes
+	// Nothing is launched when only describing, showing or dry running the launch, so there is no config to save
+	if isDescribeOnly || isShowCli || isDryRun {
+		return
+	}
+
+	// Ask if the user wants to save the config, unless the question is suppressed. If so, save the config
+	isSaveRequired, err := question.IsSaveConfigRequired(qh, isSaveConfig, isInteractive && !isNoSavePrompt)
+	if cli.ShowError(err, "Asking save configurations failed") {
+		return
 	}
 
 	if isSaveRequired {
-		err := config.SaveConfig(simpleConfig, nil)
+		err = config.SaveConfig(simpleConfig, nil)
 		cli.ShowError(err, "Saving config file failed")
 	}
 }
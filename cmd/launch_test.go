@@ -0,0 +1,906 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"simple-ec2/pkg/cli"
+	"simple-ec2/pkg/config"
+	"simple-ec2/pkg/ec2helper"
+	"simple-ec2/pkg/elbhelper"
+	"simple-ec2/pkg/iamhelper"
+	"simple-ec2/pkg/output"
+	"simple-ec2/pkg/question"
+	"simple-ec2/pkg/questionModel"
+	th "simple-ec2/test/testhelper"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Flags failing the timer action, CPU credits and private IP address validations
+func newInvalidLaunchFlags() *config.SimpleInfo {
+	return &config.SimpleInfo{
+		AutoTerminationTimerAction: "pause",
+		CpuCredits:                 "burst",
+		PrivateIpAddress:           "10.0.0",
+	}
+}
+
+func TestValidateLaunchFlags_JsonOutput(t *testing.T) {
+	outputFormatFlag = outputFormatJson
+	defer func() {
+		outputFormatFlag = outputFormatText
+	}()
+
+	out := &bytes.Buffer{}
+	isValid := reportValidationErrors(validateLaunchFlags(newInvalidLaunchFlags()), out)
+	th.Assert(t, !isValid, "The flags should be invalid")
+
+	validationErrors := []*output.ValidationError{}
+	th.Ok(t, json.Unmarshal(out.Bytes(), &validationErrors))
+	fields := []string{}
+	for _, validationError := range validationErrors {
+		fields = append(fields, validationError.Field)
+		th.Assert(t, validationError.Message != "", "No message for the "+validationError.Field+" flag")
+	}
+	th.Equals(t, []string{"timer-action", "cpu-credits", "private-ip"}, fields)
+}
+
+func TestValidateLaunchFlags_TextOutput(t *testing.T) {
+	out := &bytes.Buffer{}
+	isValid := reportValidationErrors(validateLaunchFlags(newInvalidLaunchFlags()), out)
+	th.Assert(t, !isValid, "The flags should be invalid")
+	th.Equals(t, 3, bytes.Count(out.Bytes(), []byte("Error: ")))
+}
+
+func TestValidateLaunchFlags_Valid(t *testing.T) {
+	outputFormatFlag = outputFormatJson
+	defer func() {
+		outputFormatFlag = outputFormatText
+	}()
+
+	out := &bytes.Buffer{}
+	isValid := reportValidationErrors(validateLaunchFlags(&config.SimpleInfo{}), out)
+	th.Assert(t, isValid, "The flags should be valid")
+	th.Equals(t, "", out.String())
+}
+
+func TestValidateLaunchFlags_CarrierIpConflicts(t *testing.T) {
+	defer func() {
+		zoneTypeFlag = ""
+	}()
+
+	// A carrier IP is only associated in Wavelength Zones
+	zoneTypeFlag = ec2helper.ZoneTypeLocalZone
+	errs := validateLaunchFlags(&config.SimpleInfo{AssociateCarrierIp: true})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "associate-carrier-ip", errs[0].Field)
+
+	zoneTypeFlag = ec2helper.ZoneTypeWavelengthZone
+	errs = validateLaunchFlags(&config.SimpleInfo{AssociateCarrierIp: true})
+	th.Equals(t, 0, len(errs))
+
+	// The network interface of a launch template can't be changed
+	errs = validateLaunchFlags(&config.SimpleInfo{AssociateCarrierIp: true, LaunchTemplateId: "lt-12345"})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "associate-carrier-ip", errs[0].Field)
+}
+
+func TestValidateLaunchFlags_InstanceCount(t *testing.T) {
+	defer func() {
+		isInstanceCountSet = false
+	}()
+
+	// A private IP address can only be assigned to one instance
+	errs := validateLaunchFlags(&config.SimpleInfo{InstanceCount: 2, PrivateIpAddress: "10.0.0.5"})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "count", errs[0].Field)
+
+	errs = validateLaunchFlags(&config.SimpleInfo{InstanceCount: 1, PrivateIpAddress: "10.0.0.5"})
+	th.Equals(t, 0, len(errs))
+
+	// An explicit count of 0 is invalid
+	isInstanceCountSet = true
+	errs = validateLaunchFlags(&config.SimpleInfo{})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "count", errs[0].Field)
+}
+
+func TestValidateLaunchFlags_RootVolumeSize(t *testing.T) {
+	errs := validateLaunchFlags(&config.SimpleInfo{RootVolumeSize: 30})
+	th.Equals(t, 0, len(errs))
+
+	errs = validateLaunchFlags(&config.SimpleInfo{RootVolumeSize: -1})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "volume-size", errs[0].Field)
+
+	// The block devices of a launch template can't be changed
+	errs = validateLaunchFlags(&config.SimpleInfo{RootVolumeSize: 30, LaunchTemplateId: "lt-12345"})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "volume-size", errs[0].Field)
+}
+
+func TestReadKeyPair_New(t *testing.T) {
+	const testKeyName = "unit_test_key_pair"
+	mockedSvc := &th.MockedEC2Svc{}
+	h := &ec2helper.EC2Helper{Svc: mockedSvc}
+	qh := &questionModel.QuestionModelHelper{
+		Svc: &th.MockedQMHelperSvc{
+			UserInputsPerQuestion: [][]tea.Msg{
+				{
+					tea.KeyMsg{Type: tea.KeyUp},
+					tea.KeyMsg{Type: tea.KeyEnter},
+				},
+				{
+					tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(testKeyName)},
+					tea.KeyMsg{Type: tea.KeyEnter},
+				},
+			},
+		},
+	}
+	simpleConfig := config.NewSimpleInfo()
+
+	th.Assert(t, ReadKeyPair(h, qh, simpleConfig, ""), "Reading the key pair should succeed")
+	defer os.Remove(testKeyName + ".pem")
+	th.Equals(t, testKeyName, simpleConfig.KeyPairName)
+	th.Equals(t, testKeyName, *mockedSvc.KeyPairs[0].KeyName)
+
+	_, err := os.Stat(testKeyName + ".pem")
+	th.Ok(t, err)
+}
+
+func TestValidateLaunchFlags_RootVolumeType(t *testing.T) {
+	errs := validateLaunchFlags(&config.SimpleInfo{RootVolumeType: "gp3", RootVolumeThroughput: 250})
+	th.Equals(t, 0, len(errs))
+
+	errs = validateLaunchFlags(&config.SimpleInfo{RootVolumeType: "gp2", RootVolumeIops: 3000})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "volume-type", errs[0].Field)
+
+	errs = validateLaunchFlags(&config.SimpleInfo{RootVolumeType: "io2", LaunchTemplateId: "lt-12345"})
+	th.Equals(t, 2, len(errs))
+	th.Equals(t, "volume-type", errs[0].Field)
+}
+
+func TestValidateLaunchFlags_Affinity(t *testing.T) {
+	errs := validateLaunchFlags(&config.SimpleInfo{Affinity: "host"})
+	th.Equals(t, 0, len(errs))
+
+	errs = validateLaunchFlags(&config.SimpleInfo{Affinity: "dedicated"})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "affinity", errs[0].Field)
+
+	errs = validateLaunchFlags(&config.SimpleInfo{Affinity: "host", LaunchTemplateId: "lt-12345"})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "affinity", errs[0].Field)
+}
+
+func TestValidateLaunchFlags_HostResourceGroupConflicts(t *testing.T) {
+	const testHostResourceGroupArn = "arn:aws:resource-groups:us-east-1:123456789012:group/hosts"
+
+	errs := validateLaunchFlags(&config.SimpleInfo{HostResourceGroupArn: testHostResourceGroupArn})
+	th.Equals(t, 0, len(errs))
+
+	// Dedicated Hosts only run On-Demand instances
+	errs = validateLaunchFlags(&config.SimpleInfo{HostResourceGroupArn: testHostResourceGroupArn,
+		CapacityType: question.DefaultCapacityTypeText.Spot})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "host-resource-group-arn", errs[0].Field)
+
+	errs = validateLaunchFlags(&config.SimpleInfo{HostResourceGroupArn: testHostResourceGroupArn,
+		LaunchTemplateId: "lt-12345"})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "host-resource-group-arn", errs[0].Field)
+
+	errs = validateLaunchFlags(&config.SimpleInfo{HostResourceGroupArn: "group/hosts"})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "host-resource-group-arn", errs[0].Field)
+}
+
+func TestLaunchWithLaunchTemplate_NonexistentVersion(t *testing.T) {
+	h := &ec2helper.EC2Helper{
+		Svc: &th.MockedEC2Svc{
+			LaunchTemplateVersions: []*ec2.LaunchTemplateVersion{
+				{
+					LaunchTemplateId: aws.String("lt-12345"),
+					VersionNumber:    aws.Int64(1),
+				},
+			},
+		},
+	}
+	qh := &questionModel.QuestionModelHelper{Svc: &th.MockedQMHelperSvc{}}
+	simpleConfig := &config.SimpleInfo{
+		LaunchTemplateId:      "lt-12345",
+		LaunchTemplateVersion: "7",
+	}
+
+	// The nonexistent version is reported instead of failing later on an empty version list
+	err := th.TakeOverStdout()
+	th.Ok(t, err)
+	isLaunched := LaunchWithLaunchTemplate(h, qh, simpleConfig, "", os.Stdout)
+	stdout := th.ReadStdout()
+
+	th.Assert(t, !isLaunched, "The launch should fail")
+	th.Assert(t, strings.Contains(stdout, "Launch template lt-12345 has no version 7. Available versions: 1"),
+		"The error should list the available versions")
+}
+
+// An EC2 service with an x86 image, two x86 instance types and an Arm instance type
+func newInstanceTypesSvc() *th.MockedEC2Svc {
+	return &th.MockedEC2Svc{
+		Subnets: []*ec2.Subnet{
+			{
+				SubnetId: aws.String("subnet-12345"),
+				VpcId:    aws.String("vpc-12345"),
+			},
+		},
+		Vpcs: []*ec2.Vpc{
+			{
+				VpcId: aws.String("vpc-12345"),
+			},
+		},
+		SecurityGroups: []*ec2.SecurityGroup{
+			{
+				GroupId: aws.String("sg-12345"),
+			},
+		},
+		Images: []*ec2.Image{
+			{
+				ImageId:             aws.String("ami-12345"),
+				Architecture:        aws.String("x86_64"),
+				RootDeviceType:      aws.String(ec2.DeviceTypeEbs),
+				PlatformDetails:     aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+				BlockDeviceMappings: []*ec2.BlockDeviceMapping{{Ebs: &ec2.EbsBlockDevice{}}},
+			},
+		},
+		InstanceTypes: []*ec2.InstanceTypeInfo{
+			{
+				InstanceType:  aws.String("t3.micro"),
+				ProcessorInfo: &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"x86_64"})},
+			},
+			{
+				InstanceType:  aws.String("m5.large"),
+				ProcessorInfo: &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"x86_64"})},
+			},
+			{
+				InstanceType:  aws.String("m6g.large"),
+				ProcessorInfo: &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"arm64"})},
+			},
+		},
+	}
+}
+
+func newInstanceTypesConfig() *config.SimpleInfo {
+	return &config.SimpleInfo{
+		ImageId:          "ami-12345",
+		InstanceType:     "t3.micro",
+		SubnetId:         "subnet-12345",
+		SecurityGroupIds: []string{"sg-12345"},
+		ClientToken:      "token-12345",
+	}
+}
+
+func TestLaunchInstanceTypes_Success(t *testing.T) {
+	mockedSvc := newInstanceTypesSvc()
+	h := &ec2helper.EC2Helper{Svc: mockedSvc}
+
+	err := th.TakeOverStdout()
+	th.Ok(t, err)
+	err = LaunchInstanceTypes(h, newInstanceTypesConfig(), []string{"t3.micro", "m5.large"}, cli.ResponseYes, os.Stdout)
+	stdout := th.ReadStdout()
+	th.Ok(t, err)
+
+	// One On-Demand instance is launched per instance type, each with its own client token
+	th.Equals(t, 2, len(mockedSvc.RunInstancesInputs))
+	for i, instanceType := range []string{"t3.micro", "m5.large"} {
+		input := mockedSvc.RunInstancesInputs[i]
+		th.Equals(t, instanceType, *input.InstanceType)
+		th.Equals(t, int64(1), *input.MinCount)
+		th.Equals(t, ec2helper.GetFallbackClientToken("token-12345", instanceType), *input.ClientToken)
+	}
+	th.Assert(t, strings.Contains(stdout, "Launched 2 of 2 instance types"), "The results should be summarized")
+	th.Assert(t, strings.Contains(stdout, "m5.large: i-12345"), "The instance of each type should be listed")
+}
+
+func TestLaunchInstanceTypes_IncompatibleImage(t *testing.T) {
+	mockedSvc := newInstanceTypesSvc()
+	h := &ec2helper.EC2Helper{Svc: mockedSvc}
+
+	// The image can't run on the Arm instance type, so nothing is launched
+	err := LaunchInstanceTypes(h, newInstanceTypesConfig(), []string{"t3.micro", "m6g.large"}, cli.ResponseYes, os.Stdout)
+	th.Nok(t, err)
+	th.Assert(t, strings.Contains(err.Error(), "m6g.large"), "The incompatible instance type should be reported")
+	th.Equals(t, 0, mockedSvc.RunInstancesCalls)
+}
+
+func TestReadImageInstanceTypes_LeavesOutOtherArchitectures(t *testing.T) {
+	h := &ec2helper.EC2Helper{Svc: newInstanceTypesSvc()}
+	simpleConfig := newInstanceTypesConfig()
+
+	err := th.TakeOverStdout()
+	th.Ok(t, err)
+	instanceTypes := ReadImageInstanceTypes(h, simpleConfig, []string{"t3.micro", "m6g.large", "m5.large"})
+	stdout := th.ReadStdout()
+
+	th.Equals(t, []string{"t3.micro", "m5.large"}, instanceTypes)
+	th.Assert(t, strings.Contains(stdout, "so instance type m6g.large is left out"),
+		"The left out instance type should be reported")
+}
+
+func TestReadImageInstanceTypes_NoneCompatible(t *testing.T) {
+	h := &ec2helper.EC2Helper{Svc: newInstanceTypesSvc()}
+	simpleConfig := newInstanceTypesConfig()
+	simpleConfig.InstanceType = "m6g.large"
+
+	err := th.TakeOverStdout()
+	th.Ok(t, err)
+	instanceTypes := ReadImageInstanceTypes(h, simpleConfig, []string{"m6g.large"})
+	th.ReadStdout()
+
+	th.Assert(t, instanceTypes == nil, "No instance type should be left")
+}
+
+func TestLaunchInstanceTypes_LaunchError(t *testing.T) {
+	mockedSvc := newInstanceTypesSvc()
+	mockedSvc.RunInstancesError = errors.New("Test error")
+	h := &ec2helper.EC2Helper{Svc: mockedSvc}
+
+	// A failed launch doesn't stop the launches of the other instance types
+	err := th.TakeOverStdout()
+	th.Ok(t, err)
+	err = LaunchInstanceTypes(h, newInstanceTypesConfig(), []string{"t3.micro", "m5.large"}, cli.ResponseYes, os.Stdout)
+	stdout := th.ReadStdout()
+	th.Nok(t, err)
+	th.Equals(t, 2, mockedSvc.RunInstancesCalls)
+	th.Assert(t, strings.Contains(stdout, "Launched 0 of 2 instance types"), "The results should be summarized")
+}
+
+func TestLaunchInstanceTypes_NotConfirmed(t *testing.T) {
+	mockedSvc := newInstanceTypesSvc()
+	h := &ec2helper.EC2Helper{Svc: mockedSvc}
+
+	err := LaunchInstanceTypes(h, newInstanceTypesConfig(), []string{"t3.micro", "m5.large"}, cli.ResponseNo, os.Stdout)
+	th.Nok(t, err)
+	th.Equals(t, 0, mockedSvc.RunInstancesCalls)
+}
+
+func TestValidateLaunchFlags_SelectInstanceTypes(t *testing.T) {
+	isSelectInstanceTypes = true
+	defer func() {
+		isSelectInstanceTypes = false
+		isInteractive = false
+	}()
+
+	// Several instance types are only selected interactively
+	errs := validateLaunchFlags(&config.SimpleInfo{})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "select-instance-types", errs[0].Field)
+
+	isInteractive = true
+	errs = validateLaunchFlags(&config.SimpleInfo{})
+	th.Equals(t, 0, len(errs))
+
+	errs = validateLaunchFlags(&config.SimpleInfo{InstanceType: "t3.micro"})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "select-instance-types", errs[0].Field)
+}
+
+func TestValidateLaunchFlags_ShowCli(t *testing.T) {
+	isShowCli = true
+	defer func() {
+		isShowCli = false
+		isDescribeOnly = false
+	}()
+
+	errs := validateLaunchFlags(&config.SimpleInfo{})
+	th.Equals(t, 0, len(errs))
+
+	errs = validateLaunchFlags(&config.SimpleInfo{LaunchTemplateId: "lt-12345"})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "show-cli", errs[0].Field)
+
+	isDescribeOnly = true
+	errs = validateLaunchFlags(&config.SimpleInfo{})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "show-cli", errs[0].Field)
+}
+
+func TestLaunchCapacityInstance_DescribeOnlyJson(t *testing.T) {
+	isDescribeOnly = true
+	outputFormatFlag = outputFormatJson
+	defer func() {
+		isDescribeOnly = false
+		outputFormatFlag = outputFormatText
+	}()
+
+	mockedSvc := newInstanceTypesSvc()
+	mockedSvc.Images[0].Name = aws.String("amzn2-ami-hvm-2.0.20230404.1-x86_64-gp2")
+	mockedSvc.Subnets[0].AvailabilityZone = aws.String("us-east-2a")
+	h := &ec2helper.EC2Helper{Svc: mockedSvc}
+	simpleConfig := newInstanceTypesConfig()
+	detailedConfig, err := h.ParseConfig(simpleConfig)
+	th.Ok(t, err)
+
+	out := &bytes.Buffer{}
+	_, err = LaunchCapacityInstance(h, simpleConfig, detailedConfig, cli.ResponseYes, out)
+	th.Ok(t, err)
+	th.Equals(t, 0, mockedSvc.RunInstancesCalls)
+
+	description := &output.LaunchDescription{}
+	th.Ok(t, json.Unmarshal(out.Bytes(), description))
+	th.Equals(t, "amzn2-ami-hvm-2.0.20230404.1-x86_64-gp2", description.Image.Name)
+	th.Equals(t, "us-east-2a", description.Subnet.AvailabilityZone)
+	th.Equals(t, 1, len(description.SecurityGroups))
+	th.Equals(t, "sg-12345", description.SecurityGroups[0].GroupID)
+}
+
+func TestLaunchCapacityInstance_DryRun(t *testing.T) {
+	isDryRun = true
+	defer func() {
+		isDryRun = false
+	}()
+
+	mockedSvc := newInstanceTypesSvc()
+	h := &ec2helper.EC2Helper{Svc: mockedSvc}
+	simpleConfig := newInstanceTypesConfig()
+	detailedConfig, err := h.ParseConfig(simpleConfig)
+	th.Ok(t, err)
+
+	err = th.TakeOverStdout()
+	th.Ok(t, err)
+	_, err = LaunchCapacityInstance(h, simpleConfig, detailedConfig, cli.ResponseYes, os.Stdout)
+	stdout := th.ReadStdout()
+	th.Ok(t, err)
+	th.Equals(t, 0, mockedSvc.RunInstancesCalls)
+	th.Equals(t, true, *mockedSvc.RunInstancesInputs[0].DryRun)
+	th.Assert(t, strings.Contains(stdout, "Dry run succeeded: you have permission to launch"),
+		"The dry run should be reported as succeeded")
+}
+
+func TestLaunchCapacityInstance_DryRunSpot(t *testing.T) {
+	isDryRun = true
+	defer func() {
+		isDryRun = false
+	}()
+
+	mockedSvc := newInstanceTypesSvc()
+	h := &ec2helper.EC2Helper{Svc: mockedSvc}
+	simpleConfig := newInstanceTypesConfig()
+	simpleConfig.CapacityType = question.DefaultCapacityTypeText.Spot
+	detailedConfig, err := h.ParseConfig(simpleConfig)
+	th.Ok(t, err)
+
+	err = th.TakeOverStdout()
+	th.Ok(t, err)
+	_, err = LaunchCapacityInstance(h, simpleConfig, detailedConfig, cli.ResponseYes, os.Stdout)
+	th.ReadStdout()
+	th.Ok(t, err)
+	th.Equals(t, 0, len(mockedSvc.LaunchTemplates))
+	th.Equals(t, 1, len(mockedSvc.CreateFleetInputs))
+	th.Equals(t, true, *mockedSvc.CreateFleetInputs[0].DryRun)
+}
+
+func TestLaunchCapacityInstance_DryRunError(t *testing.T) {
+	isDryRun = true
+	defer func() {
+		isDryRun = false
+	}()
+
+	mockedSvc := newInstanceTypesSvc()
+	mockedSvc.RunInstancesError = errors.New("Test error")
+	h := &ec2helper.EC2Helper{Svc: mockedSvc}
+	simpleConfig := newInstanceTypesConfig()
+	detailedConfig, err := h.ParseConfig(simpleConfig)
+	th.Ok(t, err)
+
+	_, err = LaunchCapacityInstance(h, simpleConfig, detailedConfig, cli.ResponseYes, os.Stdout)
+	th.Nok(t, err)
+	th.Assert(t, strings.Contains(err.Error(), "Dry run failed"), "The dry run should be reported as failed")
+}
+
+func TestValidateLaunchFlags_DryRun(t *testing.T) {
+	isDryRun = true
+	defer func() {
+		isDryRun = false
+		isDescribeOnly = false
+	}()
+
+	errs := validateLaunchFlags(&config.SimpleInfo{})
+	th.Equals(t, 0, len(errs))
+
+	errs = validateLaunchFlags(&config.SimpleInfo{LaunchTemplateId: "lt-12345"})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "dry-run", errs[0].Field)
+
+	isDescribeOnly = true
+	errs = validateLaunchFlags(&config.SimpleInfo{})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "dry-run", errs[0].Field)
+}
+
+func TestValidateLaunchFlags_CreateMissingSgPreview(t *testing.T) {
+	isCreateMissingSg = true
+	defer func() {
+		isCreateMissingSg = false
+		isDryRun = false
+		isDescribeOnly = false
+		isShowCli = false
+	}()
+
+	flags := &config.SimpleInfo{SecurityGroupIds: []string{"web"}}
+	errs := validateLaunchFlags(flags)
+	th.Equals(t, 0, len(errs))
+
+	for _, isPreview := range []*bool{&isDryRun, &isDescribeOnly, &isShowCli} {
+		*isPreview = true
+		errs = validateLaunchFlags(flags)
+		th.Equals(t, 1, len(errs))
+		th.Equals(t, "create-missing-sg", errs[0].Field)
+		*isPreview = false
+	}
+}
+
+func TestReadMissingSecurityGroups_DryRun(t *testing.T) {
+	isDryRun = true
+	defer func() {
+		isDryRun = false
+	}()
+
+	mockedSvc := &th.MockedEC2Svc{
+		Subnets: []*ec2.Subnet{
+			{
+				SubnetId: aws.String("subnet-12345"),
+				VpcId:    aws.String("vpc-12345"),
+			},
+		},
+	}
+	h := &ec2helper.EC2Helper{Svc: mockedSvc}
+	simpleConfig := &config.SimpleInfo{SubnetId: "subnet-12345", SecurityGroupIds: []string{"web"}}
+
+	th.Assert(t, !ReadMissingSecurityGroups(h, simpleConfig), "Security groups shouldn't be created in a dry run")
+	th.Equals(t, 0, len(mockedSvc.CreateSecurityGroupInputs))
+	th.Equals(t, []string{"web"}, simpleConfig.SecurityGroupIds)
+}
+
+func TestValidateLaunchFlags_Region(t *testing.T) {
+	errs := validateLaunchFlags(&config.SimpleInfo{Region: "us-east-1"})
+	th.Equals(t, 0, len(errs))
+
+	errs = validateLaunchFlags(&config.SimpleInfo{Region: "us-est-1"})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "region", errs[0].Field)
+	th.Equals(t, "Region us-est-1 is not a known region. Did you mean \"us-east-1\"?", errs[0].Message)
+}
+
+func TestApplyPrivatePreset(t *testing.T) {
+	flags := &config.SimpleInfo{InstanceType: "t3.micro"}
+	ApplyPrivatePreset(flags)
+
+	th.Equals(t, &config.SimpleInfo{
+		InstanceType: "t3.micro",
+		NoPublicIp:   true,
+		Imds:         ec2helper.ImdsRequired,
+	}, flags)
+}
+
+func TestValidateLaunchFlags_Private(t *testing.T) {
+	isPrivate = true
+	defer func() {
+		isPrivate = false
+		isInteractive = false
+		runCommandFlag = ""
+	}()
+
+	errs := validateLaunchFlags(&config.SimpleInfo{Imds: ec2helper.ImdsRequired, IamInstanceProfile: "ssm"})
+	th.Equals(t, 0, len(errs))
+
+	// The overrides undoing the preset are rejected
+	for _, flags := range []*config.SimpleInfo{
+		{Imds: ec2helper.ImdsOptional},
+		{AssociateCarrierIp: true},
+		{LaunchTemplateId: "lt-12345"},
+	} {
+		errs = validateLaunchFlags(flags)
+		th.Equals(t, "private", errs[len(errs)-1].Field)
+	}
+
+	runCommandFlag = "./setup.sh"
+	errs = validateLaunchFlags(&config.SimpleInfo{})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "private", errs[0].Field)
+
+	runCommandFlag = ""
+	isInteractive = true
+	errs = validateLaunchFlags(&config.SimpleInfo{})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "private", errs[0].Field)
+}
+
+func TestReadSsmInstanceProfile(t *testing.T) {
+	isDescribeOnly = true
+	defer func() {
+		isDescribeOnly = false
+	}()
+
+	// The instance profile of the config is kept
+	simpleConfig := &config.SimpleInfo{IamInstanceProfile: "my-ssm-profile"}
+	th.Assert(t, ReadSsmInstanceProfile(&ec2helper.EC2Helper{}, simpleConfig), "The profile should be read")
+	th.Equals(t, "my-ssm-profile", simpleConfig.IamInstanceProfile)
+
+	// Nothing is created when nothing is launched
+	simpleConfig = &config.SimpleInfo{}
+	th.Assert(t, ReadSsmInstanceProfile(&ec2helper.EC2Helper{}, simpleConfig), "The profile should be read")
+	th.Equals(t, iamhelper.SsmInstanceProfileName, simpleConfig.IamInstanceProfile)
+}
+
+func TestValidateLaunchFlags_Imds(t *testing.T) {
+	errs := validateLaunchFlags(&config.SimpleInfo{Imds: ec2helper.ImdsDisabled})
+	th.Equals(t, 0, len(errs))
+
+	errs = validateLaunchFlags(&config.SimpleInfo{Imds: "enabled"})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "imds", errs[0].Field)
+
+	errs = validateLaunchFlags(&config.SimpleInfo{Imds: ec2helper.ImdsDisabled, IamInstanceProfile: "my-profile"})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "imds", errs[0].Field)
+
+	errs = validateLaunchFlags(&config.SimpleInfo{Imds: ec2helper.ImdsDisabled, IamInstanceProfile: "my-profile",
+		Force: true})
+	th.Equals(t, 0, len(errs))
+}
+
+func TestLaunchCapacityInstance_EmptyCapacityType(t *testing.T) {
+	mockedSvc := newInstanceTypesSvc()
+	h := &ec2helper.EC2Helper{Svc: mockedSvc}
+	simpleConfig := newInstanceTypesConfig()
+	detailedConfig, err := h.ParseConfig(simpleConfig)
+	th.Ok(t, err)
+
+	// A config file without a capacity type launches On-Demand instances, not Spot instances
+	err = th.TakeOverStdout()
+	th.Ok(t, err)
+	_, err = LaunchCapacityInstance(h, simpleConfig, detailedConfig, cli.ResponseYes, os.Stdout)
+	th.ReadStdout()
+	th.Ok(t, err)
+	th.Equals(t, 1, mockedSvc.RunInstancesCalls)
+	th.Equals(t, 0, len(mockedSvc.CreateFleetInputs))
+	th.Equals(t, question.DefaultCapacityTypeText.OnDemand, simpleConfig.CapacityType)
+}
+
+func TestLaunchCapacityInstance_JsonOutput(t *testing.T) {
+	outputFormatFlag = outputFormatJson
+	defer func() {
+		outputFormatFlag = outputFormatText
+	}()
+
+	mockedSvc := newInstanceTypesSvc()
+	mockedSvc.Instances = []*ec2.Instance{{InstanceId: aws.String("i-12345")}}
+	h := &ec2helper.EC2Helper{
+		Svc:  mockedSvc,
+		Sess: session.Must(session.NewSession(aws.NewConfig().WithRegion("us-east-1"))),
+	}
+	simpleConfig := newInstanceTypesConfig()
+	detailedConfig, err := h.ParseConfig(simpleConfig)
+	th.Ok(t, err)
+
+	// The launch result is written to the writer, not to stdout
+	err = th.TakeOverStdout()
+	th.Ok(t, err)
+	out := &bytes.Buffer{}
+	instanceIds, err := LaunchCapacityInstance(h, simpleConfig, detailedConfig, cli.ResponseYes, out)
+	stdout := th.ReadStdout()
+	th.Ok(t, err)
+	th.Assert(t, !strings.Contains(stdout, "instanceIds"), "The launch result shouldn't be on stdout")
+
+	result := &output.LaunchResult{}
+	th.Ok(t, json.Unmarshal(out.Bytes(), result))
+	th.Equals(t, instanceIds, result.InstanceIDs)
+}
+
+func TestValidateLaunchFlags_TargetGroupArn(t *testing.T) {
+	defer func() { targetGroupArnFlag = "" }()
+
+	targetGroupArnFlag = "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/my-targets/73e2d6bc24d8a067"
+	errs := validateLaunchFlags(&config.SimpleInfo{})
+	th.Equals(t, 0, len(errs))
+
+	targetGroupArnFlag = "my-targets"
+	errs = validateLaunchFlags(&config.SimpleInfo{})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "target-group-arn", errs[0].Field)
+}
+
+func newTestTargetGroup() *elbv2.TargetGroup {
+	return &elbv2.TargetGroup{
+		TargetGroupArn: aws.String(
+			"arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/my-targets/73e2d6bc24d8a067"),
+		TargetGroupName: aws.String("my-targets"),
+		TargetType:      aws.String(elbv2.TargetTypeEnumInstance),
+		VpcId:           aws.String("vpc-12345"),
+	}
+}
+
+func TestGetLaunchTargetGroup_Vpc(t *testing.T) {
+	targetGroup := newTestTargetGroup()
+	elb := &elbhelper.ELBHelper{Client: &th.MockedELBSvc{TargetGroups: []*elbv2.TargetGroup{targetGroup}}}
+
+	_, err := GetLaunchTargetGroup(elb, *targetGroup.TargetGroupArn,
+		&config.DetailedInfo{Vpc: &ec2.Vpc{VpcId: aws.String("vpc-12345")}})
+	th.Ok(t, err)
+
+	_, err = GetLaunchTargetGroup(elb, *targetGroup.TargetGroupArn,
+		&config.DetailedInfo{Vpc: &ec2.Vpc{VpcId: aws.String("vpc-67890")}})
+	th.Nok(t, err)
+
+	// The VPC of a new VPC is only known after launching
+	_, err = GetLaunchTargetGroup(elb, *targetGroup.TargetGroupArn, &config.DetailedInfo{})
+	th.Ok(t, err)
+}
+
+func TestRegisterWithTargetGroup_Success(t *testing.T) {
+	h := &ec2helper.EC2Helper{Svc: &th.MockedEC2Svc{
+		Instances: []*ec2.Instance{
+			{InstanceId: aws.String("i-12345"), VpcId: aws.String("vpc-12345")},
+		},
+	}}
+	mockedElbSvc := &th.MockedELBSvc{}
+	elb := &elbhelper.ELBHelper{Client: mockedElbSvc}
+	targetGroup := newTestTargetGroup()
+
+	err := th.TakeOverStdout()
+	th.Ok(t, err)
+	err = RegisterWithTargetGroup(h, elb, targetGroup, []string{"i-12345"})
+	stdout := th.ReadStdout()
+
+	th.Ok(t, err)
+	th.Equals(t, 1, len(mockedElbSvc.RegisterTargetsInputs))
+	th.Equals(t, *targetGroup.TargetGroupArn, *mockedElbSvc.RegisterTargetsInputs[0].TargetGroupArn)
+	th.Equals(t, "i-12345", *mockedElbSvc.RegisterTargetsInputs[0].Targets[0].Id)
+	th.Assert(t, strings.Contains(stdout, "Registered instances i-12345 with target group my-targets"),
+		"The registration should be printed")
+}
+
+func TestRegisterWithTargetGroup_OtherVpc(t *testing.T) {
+	h := &ec2helper.EC2Helper{Svc: &th.MockedEC2Svc{
+		Instances: []*ec2.Instance{
+			{InstanceId: aws.String("i-12345"), VpcId: aws.String("vpc-67890")},
+		},
+	}}
+	mockedElbSvc := &th.MockedELBSvc{}
+	elb := &elbhelper.ELBHelper{Client: mockedElbSvc}
+
+	err := RegisterWithTargetGroup(h, elb, newTestTargetGroup(), []string{"i-12345"})
+	th.Nok(t, err)
+	th.Equals(t, 0, len(mockedElbSvc.RegisterTargetsInputs))
+}
+
+func TestValidateLaunchFlags_Cheapest(t *testing.T) {
+	defer func() {
+		isCheapest = false
+		vcpusFlag = 0
+		memoryFlag = 0
+		requirementsFileFlag = ""
+		isInteractive = false
+	}()
+
+	isCheapest = true
+	vcpusFlag = 4
+	memoryFlag = 8
+	errs := validateLaunchFlags(&config.SimpleInfo{})
+	th.Equals(t, 0, len(errs))
+
+	errs = validateLaunchFlags(&config.SimpleInfo{InstanceType: "m5.xlarge"})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "cheapest", errs[0].Field)
+
+	isInteractive = true
+	errs = validateLaunchFlags(&config.SimpleInfo{})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "cheapest", errs[0].Field)
+	isInteractive = false
+
+	// The requirements file replaces the vCPUs and memory
+	requirementsFileFlag = "requirements.yaml"
+	errs = validateLaunchFlags(&config.SimpleInfo{})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "vcpus", errs[0].Field)
+
+	vcpusFlag = 0
+	memoryFlag = 0
+	errs = validateLaunchFlags(&config.SimpleInfo{})
+	th.Equals(t, 0, len(errs))
+
+	requirementsFileFlag = ""
+	errs = validateLaunchFlags(&config.SimpleInfo{})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "cheapest", errs[0].Field)
+
+	isCheapest = false
+	vcpusFlag = 4
+	errs = validateLaunchFlags(&config.SimpleInfo{})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "vcpus", errs[0].Field)
+}
+
+func TestPrintLaunchResult(t *testing.T) {
+	h := &ec2helper.EC2Helper{
+		Svc: &th.MockedEC2Svc{
+			Instances: []*ec2.Instance{
+				{
+					InstanceId:     aws.String("i-12345"),
+					InstanceType:   aws.String("t3.micro"),
+					ImageId:        aws.String("ami-12345"),
+					SubnetId:       aws.String("subnet-12345"),
+					SecurityGroups: []*ec2.GroupIdentifier{{GroupId: aws.String("sg-12345")}},
+				},
+			},
+		},
+		Sess: session.Must(session.NewSession(aws.NewConfig().WithRegion("us-east-1"))),
+	}
+
+	out := &bytes.Buffer{}
+	err := PrintLaunchResult(h, out, question.DefaultCapacityTypeText.OnDemand, []string{"i-12345"})
+	th.Ok(t, err)
+
+	// The output is a single JSON object, so that it can be piped to jq
+	result := &output.LaunchResult{}
+	th.Ok(t, json.Unmarshal(out.Bytes(), result))
+	th.Equals(t, &output.LaunchResult{
+		InstanceIDs:      []string{"i-12345"},
+		Region:           "us-east-1",
+		InstanceType:     "t3.micro",
+		ImageID:          "ami-12345",
+		SubnetID:         "subnet-12345",
+		SecurityGroupIDs: []string{"sg-12345"},
+		CapacityType:     question.DefaultCapacityTypeText.OnDemand,
+	}, result)
+}
+
+func TestPrintLaunchResult_InstanceNotFound(t *testing.T) {
+	h := &ec2helper.EC2Helper{
+		Svc:  &th.MockedEC2Svc{},
+		Sess: session.Must(session.NewSession(aws.NewConfig().WithRegion("us-east-1"))),
+	}
+
+	out := &bytes.Buffer{}
+	err := PrintLaunchResult(h, out, question.DefaultCapacityTypeText.OnDemand, []string{"i-12345"})
+	th.Nok(t, err)
+	th.Equals(t, 0, out.Len())
+}
+
+func TestValidateLaunchFlags_JsonOutputSelectInstanceTypes(t *testing.T) {
+	isSelectInstanceTypes = true
+	isInteractive = true
+	outputFormatFlag = outputFormatJson
+	defer func() {
+		isSelectInstanceTypes = false
+		isInteractive = false
+		outputFormatFlag = outputFormatText
+	}()
+
+	errs := validateLaunchFlags(&config.SimpleInfo{})
+	th.Equals(t, 1, len(errs))
+	th.Equals(t, "select-instance-types", errs[0].Field)
+}
@@ -0,0 +1,54 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"simple-ec2/pkg/cli"
+	"simple-ec2/pkg/iampolicy"
+	"simple-ec2/pkg/output"
+
+	"github.com/spf13/cobra"
+)
+
+// printIamPolicyCmd represents the print-iam-policy command
+var printIamPolicyCmd = &cobra.Command{
+	Use:   "print-iam-policy [" + strings.Join(iampolicy.Workflows, "|") + "]",
+	Short: "Print the IAM policy needed by a simple-ec2 workflow",
+	Long: `Print an IAM policy document allowing the actions a simple-ec2 workflow uses, ready to be attached to a
+user or role. The spot workflow is a launch with the Spot capacity type`,
+	Args:      cobra.ExactValidArgs(1),
+	ValidArgs: iampolicy.Workflows,
+	Run:       printIamPolicy,
+}
+
+func init() {
+	rootCmd.AddCommand(printIamPolicyCmd)
+}
+
+// The main function
+func printIamPolicy(cmd *cobra.Command, args []string) {
+	policy, err := iampolicy.GetPolicyDocument(args[0])
+	if cli.ShowError(err, "Getting IAM policy failed") {
+		return
+	}
+
+	policyJson, err := output.RenderJson(policy)
+	if cli.ShowError(err, "Rendering IAM policy failed") {
+		return
+	}
+	fmt.Println(policyJson)
+}
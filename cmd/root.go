@@ -15,8 +15,16 @@ package cmd
 
 import (
 	"fmt"
-	"github.com/spf13/cobra"
 	"os"
+
+	"simple-ec2/pkg/cli"
+	"simple-ec2/pkg/ec2helper"
+	"simple-ec2/pkg/question"
+	"simple-ec2/pkg/questionModel"
+	"simple-ec2/pkg/stshelper"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/spf13/cobra"
 )
 
 var rootCmd = &cobra.Command{
@@ -26,6 +34,14 @@ var rootCmd = &cobra.Command{
 		"Users can easily launch an instance with or without custom configurations.",
 }
 
+// Add global flags
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&questionModel.IsTableWrap, "wrap", false,
+		"Wrap long values in question tables instead of truncating them to the terminal width")
+	rootCmd.PersistentFlags().BoolVar(&cli.IsNoSpinner, "no-spinner", false,
+		"Don't show spinners while waiting for AWS. Spinners are always skipped when output isn't a terminal")
+}
+
 // Execute adds all child commands to the root command sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
@@ -34,3 +50,38 @@ func Execute() {
 		os.Exit(1)
 	}
 }
+
+/*
+Check the credentials of the session. In interactive mode, when they can't be used, offer to switch to another
+profile of the shared config and credentials files, whose credentials are checked in turn.
+Return a session with usable credentials, or nil if there is none.
+*/
+func checkCredentials(sess *session.Session, qh *questionModel.QuestionModelHelper) *session.Session {
+	currentProfile := stshelper.GetCurrentProfile()
+	for {
+		err := stshelper.New(sess).CheckCredentials()
+		if err == nil {
+			return sess
+		}
+		cli.ShowError(err, "Checking credentials failed")
+		if !isInteractive {
+			return nil
+		}
+
+		profiles, err := stshelper.GetProfileNames(stshelper.GetSharedFilenames())
+		if cli.ShowError(err, "Reading profiles failed") || len(profiles) == 0 ||
+			(len(profiles) == 1 && profiles[0] == currentProfile) {
+			return nil
+		}
+		currentProfile, err = question.AskProfile(qh, profiles, currentProfile)
+		if cli.ShowError(err, "Asking profile failed") {
+			return nil
+		}
+
+		sess = session.Must(session.NewSessionWithOptions(session.Options{
+			SharedConfigState: session.SharedConfigEnable,
+			Profile:           currentProfile,
+		}))
+		ec2helper.GetDefaultRegion(sess)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"simple-ec2/pkg/cli"
+	"simple-ec2/pkg/config"
+	"simple-ec2/pkg/ec2helper"
+	"simple-ec2/pkg/question"
+	"simple-ec2/pkg/questionModel"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/spf13/cobra"
+)
+
+// startCmd represents the start command
+var startCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start stopped Amazon EC2 Instances",
+	Long:  `Start stopped Amazon EC2 Instances, given the region and instance ids`,
+	Run:   start,
+}
+
+// Add flags
+func init() {
+	rootCmd.AddCommand(startCmd)
+
+	startCmd.Flags().StringVarP(&regionFlag, "region", "r", "",
+		"The region in which the instances you want to start locates")
+	startCmd.Flags().StringSliceVarP(&instanceIdFlag, "instance-ids", "n", nil,
+		"The instance ids of the stopped instances you want to start")
+	startCmd.Flags().BoolVarP(&isInteractive, "interactive", "i", false, "Interactive mode")
+}
+
+// The main function
+func start(cmd *cobra.Command, args []string) {
+	if !ValidateStartFlags() {
+		return
+	}
+
+	// Start a new session, with the default credentials and config loading
+	sess := session.Must(session.NewSessionWithOptions(session.Options{SharedConfigState: session.SharedConfigEnable}))
+	ec2helper.GetDefaultRegion(sess)
+	qh := questionModel.NewQuestionModelHelper()
+	sess = checkCredentials(sess, qh)
+	if sess == nil {
+		return
+	}
+	h := ec2helper.New(sess)
+
+	if isInteractive {
+		startInteractive(h, qh)
+	} else {
+		startNonInteractive(h)
+	}
+}
+
+// Start instances interactively
+func startInteractive(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper) {
+	// If region is not specified in flags, ask region
+	var region *string
+	var err error
+	if regionFlag == "" {
+		defaultsConfig := config.NewSimpleInfo()
+		err = config.ReadConfig(defaultsConfig, nil)
+		if cli.ShowError(err, "Default config file not loaded; using system defaults instead") {
+			defaultsConfig = config.NewSimpleInfo()
+		}
+		region, err = question.AskRegion(h, qh, defaultsConfig.Region)
+		if cli.ShowError(err, "Asking region failed") {
+			return
+		}
+	} else {
+		region = &regionFlag
+	}
+
+	h.ChangeRegion(*region)
+
+	instanceIds, err := question.AskStoppedInstanceIds(h, qh)
+	if cli.ShowError(err, "Start Error") {
+		return
+	}
+
+	cli.ShowError(StartInstancesWithAddresses(h, instanceIds), "Starting instances failed")
+}
+
+// Start instances non-interactively
+func startNonInteractive(h *ec2helper.EC2Helper) {
+	// Override region if specified
+	if regionFlag != "" {
+		h.ChangeRegion(regionFlag)
+	}
+
+	// Trim leading and trailing whitespace of the instance ids
+	for i := 0; i < len(instanceIdFlag); i++ {
+		instanceIdFlag[i] = strings.TrimSpace(instanceIdFlag[i])
+	}
+
+	cli.ShowError(StartInstancesWithAddresses(h, instanceIdFlag), "Starting instances failed")
+}
+
+/*
+Start the instances and wait for them to run, printing their addresses. The public addresses of an instance
+usually change when it is stopped, so the new ones are printed.
+*/
+func StartInstancesWithAddresses(h *ec2helper.EC2Helper, instanceIds []string) error {
+	err := h.StartInstances(instanceIds)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Waiting for the instances to run")
+	instances, err := h.GetRunningInstances(instanceIds)
+	if err != nil {
+		return err
+	}
+
+	for _, instance := range instances {
+		if instance.PublicIpAddress == nil {
+			fmt.Printf("Instance %s is running without a public IP address\n", *instance.InstanceId)
+			continue
+		}
+		fmt.Printf("Instance %s is running. Public IP: %s, Public DNS: %s\n", *instance.InstanceId,
+			*instance.PublicIpAddress, aws.StringValue(instance.PublicDnsName))
+	}
+
+	return nil
+}
+
+// Validate flags using some simple rules. Return true if the flags are validated, false otherwise
+func ValidateStartFlags() bool {
+	if !isInteractive && instanceIdFlag == nil {
+		fmt.Println("Specify instanceIds or use interactive mode")
+		return false
+	}
+
+	return true
+}
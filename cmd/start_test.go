@@ -0,0 +1,65 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"simple-ec2/pkg/ec2helper"
+	th "simple-ec2/test/testhelper"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestStartInstancesWithAddresses(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{
+		Instances: []*ec2.Instance{
+			{
+				InstanceId:      aws.String("i-12345"),
+				State:           &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameStopped)},
+				PublicIpAddress: aws.String("203.0.113.10"),
+				PublicDnsName:   aws.String("ec2-203-0-113-10.compute-1.amazonaws.com"),
+			},
+		},
+	}
+	h := &ec2helper.EC2Helper{Svc: mockedSvc}
+
+	err := th.TakeOverStdout()
+	th.Ok(t, err)
+	err = StartInstancesWithAddresses(h, []string{"i-12345"})
+	stdout := th.ReadStdout()
+
+	th.Ok(t, err)
+	th.Equals(t, []string{"i-12345"}, mockedSvc.StartedInstanceIds)
+	th.Assert(t, strings.Contains(stdout, "Instance i-12345 is running. Public IP: 203.0.113.10, "+
+		"Public DNS: ec2-203-0-113-10.compute-1.amazonaws.com"), "The new addresses should be printed")
+}
+
+func TestStartInstancesWithAddresses_WaitError(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{
+		WaitUntilInstanceRunningError: errors.New("Test error"),
+	}
+	h := &ec2helper.EC2Helper{Svc: mockedSvc}
+
+	err := th.TakeOverStdout()
+	th.Ok(t, err)
+	err = StartInstancesWithAddresses(h, []string{"i-12345"})
+	th.ReadStdout()
+
+	th.Nok(t, err)
+	th.Equals(t, []string{"i-12345"}, mockedSvc.StartedInstanceIds)
+}
@@ -15,11 +15,14 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
 	"simple-ec2/pkg/cli"
 	"simple-ec2/pkg/config"
 	"simple-ec2/pkg/ec2helper"
+	"simple-ec2/pkg/output"
 	"simple-ec2/pkg/question"
 	"simple-ec2/pkg/questionModel"
 	"simple-ec2/pkg/tag"
@@ -45,21 +48,41 @@ func init() {
 	terminateCmd.Flags().StringSliceVarP(&instanceIdFlag, "instance-ids", "n", nil,
 		"The instance ids of the instances you want to terminate")
 	terminateCmd.Flags().BoolVarP(&isInteractive, "interactive", "i", false, "Interactive mode")
-	terminateCmd.Flags().StringToStringVar(&flagConfig.UserTags, "tags", nil,
+	terminateCmd.Flags().StringSliceVar(&userTagsFlag, "tags", nil,
 		"Terminate instances containing EXACT tag key-pair (Example: CreatedBy=simple-ec2)")
+	terminateCmd.Flags().StringSliceVar(&filterTagsFlag, "filter-tag", nil,
+		"In interactive mode, only list the instances with the EXACT tag key-pair. Can be repeated to require "+
+			"several tags (Example: Environment=scratch)")
+	terminateCmd.Flags().StringVar(&outputFormatFlag, "output", outputFormatText,
+		fmt.Sprintf("The output format, \"%s\" or \"%s\"", outputFormatText, outputFormatJson))
+	terminateCmd.Flags().BoolVar(&isSearchAllRegions, "search-all-regions", false,
+		"Search all enabled regions for the instances if they aren't in the current region. "+
+			"All the instances must be in the same region")
+	terminateCmd.Flags().BoolVarP(&cli.IsAssumeYes, "yes", "y", false,
+		"Skip the termination confirmation in interactive mode. Only allowed with non-text output")
 }
 
+const (
+	outputFormatText  = "text"
+	outputFormatJson  = "json"
+	outputFormatJsonl = "jsonl"
+)
+
 // The main function
 func terminate(cmd *cobra.Command, args []string) {
-	if !ValidateTerminateFlags() {
+	if !ReadTagsFlag() || !ValidateTerminateFlags() {
 		return
 	}
 
 	// Start a new session, with the default credentials and config loading
 	sess := session.Must(session.NewSessionWithOptions(session.Options{SharedConfigState: session.SharedConfigEnable}))
 	ec2helper.GetDefaultRegion(sess)
-	h := ec2helper.New(sess)
 	qh := questionModel.NewQuestionModelHelper()
+	sess = checkCredentials(sess, qh)
+	if sess == nil {
+		return
+	}
+	h := ec2helper.New(sess)
 
 	if isInteractive {
 		terminateInteractive(h, qh)
@@ -70,6 +93,15 @@ func terminate(cmd *cobra.Command, args []string) {
 
 // Terminate instances interactively
 func terminateInteractive(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper) {
+	// Structured output must be the only thing on stdout, so the questions are shown on stderr instead
+	stdout := os.Stdout
+	if outputFormatFlag == outputFormatJson {
+		os.Stdout = os.Stderr
+		defer func() {
+			os.Stdout = stdout
+		}()
+	}
+
 	// If region is not specified in flags, ask region
 	var region *string
 	var err error
@@ -89,19 +121,27 @@ func terminateInteractive(h *ec2helper.EC2Helper, qh *questionModel.QuestionMode
 
 	h.ChangeRegion(*region)
 
+	// The filter tags are validated with the flags
+	filterTags, _, _ := tag.ParseTags(filterTagsFlag, tag.FlagSeparator)
+	filters, err := tag.GetTagAsFilter(filterTags)
+	if cli.ShowError(err, "Reading filter tags failed") {
+		return
+	}
+
 	// Keep asking for instance ids for termination
-	instanceIdAnswer, err := question.AskInstanceIds(h, qh, []string{})
+	instanceIdAnswer, err := question.AskInstanceIds(h, qh, []string{}, filters)
 	if cli.ShowError(err, "Terminate Error") {
 		return
 	}
 
-	confirmationAnswer, err := question.AskTerminationConfirmation(qh, instanceIdAnswer)
+	// The confirmation is only skipped for structured output, which is meant for automation
+	isConfirmed, err := question.AskTerminationConfirmation(qh, instanceIdAnswer)
 	if cli.ShowError(err, "Asking termination confirmation failed") {
 		return
 	}
 
-	if confirmationAnswer == cli.ResponseYes {
-		cli.ShowError(h.TerminateInstances(instanceIdAnswer), "Terminating instances failed")
+	if isConfirmed {
+		cli.ShowError(TerminateInstancesWithOutput(h, instanceIdAnswer, stdout), "Terminating instances failed")
 	}
 }
 
@@ -117,6 +157,16 @@ func terminateNonInteractive(h *ec2helper.EC2Helper) {
 		instanceIdFlag[i] = strings.TrimSpace(instanceIdFlag[i])
 	}
 
+	if isSearchAllRegions {
+		region, err := ChangeToInstanceRegion(h, instanceIdFlag[0])
+		if cli.ShowError(err, "Searching all regions for the instances failed") {
+			return
+		}
+		if region != "" && outputFormatFlag == outputFormatText {
+			fmt.Printf("Instances found in region %s\n", region)
+		}
+	}
+
 	instFilters, err := tag.GetTagAsFilter(flagConfig.UserTags)
 	instancesToTerm, err := h.GetInstancesByFilter(instanceIdFlag, instFilters)
 	if err != nil {
@@ -124,17 +174,58 @@ func terminateNonInteractive(h *ec2helper.EC2Helper) {
 		return
 	}
 
-	err = h.TerminateInstances(instancesToTerm)
+	err = TerminateInstancesWithOutput(h, instancesToTerm, os.Stdout)
 	if err != nil {
 		cli.ShowError(err, "Terminating instances failed")
 	}
 }
 
+// Terminate the instances, writing the terminated instances to out in the output format
+func TerminateInstancesWithOutput(h *ec2helper.EC2Helper, instanceIds []string, out io.Writer) error {
+	if outputFormatFlag != outputFormatJson {
+		return h.TerminateInstances(instanceIds)
+	}
+
+	stateChanges, err := h.TerminateInstancesWithStates(instanceIds)
+	if err != nil {
+		return err
+	}
+
+	rendered, err := output.RenderJson(output.NewTerminationSummaries(stateChanges))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, rendered)
+
+	return nil
+}
+
 // Validate flags using some simple rules. Return true if the flags are validated, false otherwise
 func ValidateTerminateFlags() bool {
 	if !isInteractive && instanceIdFlag == nil && len(flagConfig.UserTags) == 0 {
 		fmt.Println("Specify instanceIds, tags, or use interactive mode")
 		return false
 	}
+	if outputFormatFlag != outputFormatText && outputFormatFlag != outputFormatJson {
+		fmt.Printf("Output format must be \"%s\" or \"%s\"\n", outputFormatText, outputFormatJson)
+		return false
+	}
+	if isSearchAllRegions && (isInteractive || regionFlag != "" || len(instanceIdFlag) == 0) {
+		fmt.Println("Error: All regions can only be searched for instance ids in non-interactive mode without a region")
+		return false
+	}
+	if len(filterTagsFlag) > 0 && !isInteractive {
+		fmt.Println("Error: Filter tags only narrow the instances listed in interactive mode. Use --tags to " +
+			"terminate the instances with tags in non-interactive mode")
+		return false
+	}
+	if _, _, err := tag.ParseTags(filterTagsFlag, tag.FlagSeparator); err != nil {
+		fmt.Println("Error: " + err.Error())
+		return false
+	}
+	if err := cli.ValidateAssumeYes(outputFormatFlag != outputFormatText); err != nil {
+		fmt.Println("Error: " + err.Error())
+		return false
+	}
 	return true
 }
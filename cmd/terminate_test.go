@@ -0,0 +1,113 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"simple-ec2/pkg/cli"
+	"simple-ec2/pkg/ec2helper"
+	"simple-ec2/pkg/output"
+	"simple-ec2/pkg/questionModel"
+	th "simple-ec2/test/testhelper"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Print the question to stdout like the real question UI does, then answer it with the mocked inputs
+type printingQMHelperSvc struct {
+	th.MockedQMHelperSvc
+}
+
+func (m *printingQMHelperSvc) AskQuestion(model questionModel.QuestionModel, questionInput *questionModel.QuestionInput) error {
+	fmt.Println(questionInput.QuestionString)
+	return m.MockedQMHelperSvc.AskQuestion(model, questionInput)
+}
+
+func TestTerminateInteractive_JsonOutput(t *testing.T) {
+	regionFlag = "us-east-1"
+	outputFormatFlag = outputFormatJson
+	cli.IsAssumeYes = true
+	defer func() {
+		regionFlag = ""
+		outputFormatFlag = outputFormatText
+		cli.IsAssumeYes = false
+	}()
+
+	h := &ec2helper.EC2Helper{
+		Sess: &session.Session{
+			Config: &aws.Config{
+				Region: aws.String(regionFlag),
+			},
+		},
+		Svc: &th.MockedEC2Svc{
+			Instances: []*ec2.Instance{
+				{
+					InstanceId: aws.String("i-12345"),
+					State: &ec2.InstanceState{
+						Name: aws.String(ec2.InstanceStateNameRunning),
+					},
+				},
+			},
+		},
+	}
+	qh := &questionModel.QuestionModelHelper{
+		Svc: &printingQMHelperSvc{
+			MockedQMHelperSvc: th.MockedQMHelperSvc{
+				UserInputs: []tea.Msg{
+					tea.KeyMsg{Type: tea.KeyEnter},
+				},
+			},
+		},
+	}
+
+	err := th.TakeOverStdout()
+	th.Ok(t, err)
+	terminateInteractive(h, qh)
+	stdout := th.ReadStdout()
+
+	summaries := []*output.TerminationSummary{}
+	th.Ok(t, json.Unmarshal([]byte(stdout), &summaries))
+	th.Equals(t, []*output.TerminationSummary{
+		{
+			InstanceID:    "i-12345",
+			PreviousState: ec2.InstanceStateNameRunning,
+		},
+	}, summaries)
+}
+
+func TestValidateTerminateFlags_FilterTags(t *testing.T) {
+	defer func() {
+		isInteractive = false
+		instanceIdFlag = nil
+		filterTagsFlag = nil
+	}()
+
+	isInteractive = true
+	filterTagsFlag = []string{"Environment=scratch", "Owner=me"}
+	th.Assert(t, ValidateTerminateFlags(), "Filter tags should be valid in interactive mode")
+
+	filterTagsFlag = []string{"Environment"}
+	th.Assert(t, !ValidateTerminateFlags(), "Filter tags without a value should be rejected")
+
+	isInteractive = false
+	instanceIdFlag = []string{"i-12345"}
+	filterTagsFlag = []string{"Environment=scratch"}
+	th.Assert(t, !ValidateTerminateFlags(), "Filter tags should be rejected in non-interactive mode")
+}
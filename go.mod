@@ -3,8 +3,9 @@ module simple-ec2
 go 1.23
 
 require (
+	github.com/atotto/clipboard v0.1.4
 	github.com/aws/amazon-ec2-instance-selector/v2 v2.4.0
-	github.com/aws/aws-sdk-go v1.44.86
+	github.com/aws/aws-sdk-go v1.55.8
 	github.com/briandowns/spinner v1.19.0
 	github.com/charmbracelet/bubbles v0.13.0
 	github.com/charmbracelet/bubbletea v0.22.1
@@ -15,10 +16,11 @@ require (
 	github.com/spf13/cobra v1.5.0
 	golang.org/x/crypto v0.31.0
 	golang.org/x/exp v0.0.0-20220827204233-334a2380cb91
+	golang.org/x/term v0.27.0
+	gopkg.in/yaml.v3 v3.0.0
 )
 
 require (
-	github.com/atotto/clipboard v0.1.4 // indirect
 	github.com/blang/semver/v4 v4.0.0 // indirect
 	github.com/containerd/console v1.0.3 // indirect
 	github.com/evertras/bubble-table v0.14.6 // indirect
@@ -43,6 +45,5 @@ require (
 	go.uber.org/atomic v1.10.0 // indirect
 	go.uber.org/multierr v1.8.0 // indirect
 	golang.org/x/sys v0.28.0 // indirect
-	golang.org/x/term v0.27.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
 )
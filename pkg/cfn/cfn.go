@@ -14,8 +14,10 @@
 package cfn
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"simple-ec2/pkg/tag"
@@ -39,6 +41,7 @@ const (
 	ResourceTypeInstance       = "AWS::EC2::Instance"
 	ResourceTypeLaunchTemplate = "AWS::EC2::LaunchTemplate"
 	ResourceTypeSecurityGroup  = "AWS::EC2::SecurityGroup"
+	ResourceTypeStack          = "AWS::CloudFormation::Stack"
 )
 
 func New(sess *session.Session) *Cfn {
@@ -47,8 +50,11 @@ func New(sess *session.Session) *Cfn {
 	}
 }
 
-// Create a stack and ger resources in it, including VPC ID, subnet ID and instance ID
-func (c Cfn) CreateStackAndGetResources(availabilityZones []*ec2.AvailabilityZone,
+/*
+Create a stack and ger resources in it, including VPC ID, subnet ID and instance ID.
+The stack creation is waited for until the context is done.
+*/
+func (c Cfn) CreateStackAndGetResources(ctx context.Context, availabilityZones []*ec2.AvailabilityZone,
 	stackName *string, template string) (vpcId *string, subnetIds []string, instanceId *string,
 	stackResources []*cloudformation.StackResource, err error) {
 	if stackName == nil {
@@ -65,7 +71,7 @@ func (c Cfn) CreateStackAndGetResources(availabilityZones []*ec2.AvailabilityZon
 	}
 
 	// Create a new stack
-	_, err = c.CreateStack(*stackName, template, zonesToUse)
+	_, err = c.CreateStack(ctx, *stackName, template, zonesToUse)
 	if err != nil {
 		return nil, nil, nil, nil, err
 	}
@@ -95,8 +101,12 @@ func (c Cfn) CreateStackAndGetResources(availabilityZones []*ec2.AvailabilityZon
 	return vpcId, subnetIds, instanceId, resources, nil
 }
 
-// Create a stack from a cloudformation template
-func (c Cfn) CreateStack(stackName, template string, zones []*ec2.AvailabilityZone) (*string, error) {
+/*
+Create a stack from a cloudformation template. If the context is done before the stack is created,
+the stack is deleted and a timeout error is returned.
+*/
+func (c Cfn) CreateStack(ctx context.Context, stackName, template string,
+	zones []*ec2.AvailabilityZone) (*string, error) {
 	fmt.Println("Creating CloudFormation stack...")
 
 	input := &cloudformation.CreateStackInput{
@@ -153,8 +163,12 @@ func (c Cfn) CreateStack(stackName, template string, zones []*ec2.AvailabilityZo
 			break
 		}
 
-		// Sleep to prevent rate exceeded error
-		time.Sleep(creationCheckInterval)
+		// Sleep to prevent rate exceeded error, unless the deadline is exceeded in the meantime
+		select {
+		case <-ctx.Done():
+			return nil, c.deleteTimedOutStack(stackName, ctx.Err())
+		case <-time.After(creationCheckInterval):
+		}
 	}
 
 	fmt.Println("CloudFormation stack", stackName, "created successfully")
@@ -162,6 +176,19 @@ func (c Cfn) CreateStack(stackName, template string, zones []*ec2.AvailabilityZo
 	return output.StackId, nil
 }
 
+// Delete a stack whose creation timed out, and get the error to report
+func (c Cfn) deleteTimedOutStack(stackName string, ctxErr error) error {
+	fmt.Println("Deleting CloudFormation stack", stackName, "...")
+	err := c.DeleteStack(stackName)
+	if err != nil {
+		return fmt.Errorf("Timed out waiting for stack %s to be created (%v), and deleting it failed: %v",
+			stackName, ctxErr, err)
+	}
+
+	return fmt.Errorf("Timed out waiting for stack %s to be created (%v), the stack is being deleted",
+		stackName, ctxErr)
+}
+
 // Get the resources of a stack
 func (c Cfn) GetStackResources(name string) ([]*cloudformation.StackResource, error) {
 	input := &cloudformation.DescribeStackResourcesInput{
@@ -201,6 +228,39 @@ func (c Cfn) GetStackEventsByName(stackName string) ([]*cloudformation.StackEven
 	return allEvents, nil
 }
 
+/*
+Get the stacks created by simple-ec2 in a stable state. Stacks still in progress are skipped, since a stack
+being created by a running launch has no instances yet, and deleted stacks are skipped as well.
+*/
+func (c Cfn) GetSimpleEc2Stacks() ([]*cloudformation.Stack, error) {
+	simpleEc2Stacks := []*cloudformation.Stack{}
+
+	err := c.Svc.DescribeStacksPages(&cloudformation.DescribeStacksInput{},
+		func(page *cloudformation.DescribeStacksOutput, lastPage bool) bool {
+			for _, stack := range page.Stacks {
+				status := aws.StringValue(stack.StackStatus)
+				if !IsStackStatusStable(status) || status == cloudformation.StackStatusDeleteComplete {
+					continue
+				}
+				for _, stackTag := range stack.Tags {
+					if aws.StringValue(stackTag.Key) == tag.CreatedByKey &&
+						aws.StringValue(stackTag.Value) == tag.CreatedByValue {
+						simpleEc2Stacks = append(simpleEc2Stacks, stack)
+						break
+					}
+				}
+			}
+			return !lastPage
+		})
+
+	return simpleEc2Stacks, err
+}
+
+// Tell if a stack status is final, such as CREATE_COMPLETE or ROLLBACK_FAILED, rather than in progress
+func IsStackStatusStable(status string) bool {
+	return strings.HasSuffix(status, "_COMPLETE") || strings.HasSuffix(status, "_FAILED")
+}
+
 // Delete a stack by name
 func (c Cfn) DeleteStack(stackName string) error {
 	input := &cloudformation.DeleteStackInput{
@@ -14,8 +14,10 @@
 package cfn_test
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"simple-ec2/pkg/cfn"
 	th "simple-ec2/test/testhelper"
@@ -28,6 +30,9 @@ import (
 
 const testStackName = "TestStack"
 
+// Well under the interval between stack checks, so a test exceeding it didn't stop at the deadline
+const creationTimeoutBound = 900 * time.Millisecond
+
 var testCfn = &cfn.Cfn{}
 
 func TestNew(t *testing.T) {
@@ -92,7 +97,7 @@ func TestCreateStackAndGetResources_Success(t *testing.T) {
 		StackEvents:    mockedEvents,
 	}
 
-	vpcId, subnetIds, instanceId, _, err := testCfn.CreateStackAndGetResources(testAzs, aws.String(cfn.DefaultStackName), "")
+	vpcId, subnetIds, instanceId, _, err := testCfn.CreateStackAndGetResources(context.Background(), testAzs, aws.String(cfn.DefaultStackName), "")
 	th.Ok(t, err)
 	th.Equals(t, testVpcId, *vpcId)
 	th.Equals(t, testSubnetIds, subnetIds)
@@ -106,7 +111,7 @@ func TestCreateStackAndGetResources_DescribeStackEventsPagesError(t *testing.T)
 		DescribeStackEventsPagesError: errors.New("Test error"),
 	}
 
-	_, _, _, _, err := testCfn.CreateStackAndGetResources(testAzs, aws.String(cfn.DefaultStackName), "")
+	_, _, _, _, err := testCfn.CreateStackAndGetResources(context.Background(), testAzs, aws.String(cfn.DefaultStackName), "")
 	th.Nok(t, err)
 }
 
@@ -118,7 +123,7 @@ func TestCreateStackAndGetResources_DescribeStackResourcesError(t *testing.T) {
 		DescribeStackResourcesError: errors.New("Test error"),
 	}
 
-	_, _, _, _, err := testCfn.CreateStackAndGetResources(testAzs, aws.String(cfn.DefaultStackName), "")
+	_, _, _, _, err := testCfn.CreateStackAndGetResources(context.Background(), testAzs, aws.String(cfn.DefaultStackName), "")
 	th.Nok(t, err)
 }
 
@@ -133,7 +138,7 @@ func TestCreateStackAndGetResources_NoSubnet(t *testing.T) {
 		StackEvents: mockedEvents,
 	}
 
-	_, _, _, _, err := testCfn.CreateStackAndGetResources(testAzs, aws.String(cfn.DefaultStackName), "")
+	_, _, _, _, err := testCfn.CreateStackAndGetResources(context.Background(), testAzs, aws.String(cfn.DefaultStackName), "")
 	th.Nok(t, err)
 }
 
@@ -152,7 +157,7 @@ func TestCreateStackAndGetResources_NoVpc(t *testing.T) {
 		StackEvents: mockedEvents,
 	}
 
-	_, _, _, _, err := testCfn.CreateStackAndGetResources(testAzs, aws.String(cfn.DefaultStackName), "")
+	_, _, _, _, err := testCfn.CreateStackAndGetResources(context.Background(), testAzs, aws.String(cfn.DefaultStackName), "")
 	th.Nok(t, err)
 }
 
@@ -166,7 +171,7 @@ func TestCreateStack_Success(t *testing.T) {
 		StackId:        aws.String("stack-12345"),
 	}
 
-	_, err := testCfn.CreateStack(testStackName, "", testAzs)
+	_, err := testCfn.CreateStack(context.Background(), testStackName, "", testAzs)
 	th.Ok(t, err)
 }
 
@@ -178,7 +183,7 @@ func TestCreateStack_CreateStackError(t *testing.T) {
 		CreateStackError: errors.New("Test error"),
 	}
 
-	_, err := testCfn.CreateStack(testStackName, "", testAzs)
+	_, err := testCfn.CreateStack(context.Background(), testStackName, "", testAzs)
 	th.Nok(t, err)
 }
 
@@ -190,7 +195,7 @@ func TestCreateStack_DescribeStackEventsPagesError(t *testing.T) {
 		DescribeStackEventsPagesError: errors.New("Test error"),
 	}
 
-	_, err := testCfn.CreateStack(testStackName, "", testAzs)
+	_, err := testCfn.CreateStack(context.Background(), testStackName, "", testAzs)
 	th.Nok(t, err)
 }
 
@@ -204,10 +209,54 @@ func TestCreateStack_EventError(t *testing.T) {
 		StackId:        aws.String("stack-12345"),
 	}
 
-	_, err := testCfn.CreateStack(testStackName, "", testAzs)
+	_, err := testCfn.CreateStack(context.Background(), testStackName, "", testAzs)
 	th.Nok(t, err)
 }
 
+func TestCreateStack_Timeout(t *testing.T) {
+	// The stack never completes
+	mockedSvc := &th.MockedCfnSvc{
+		StackEvents: []*cloudformation.StackEvent{
+			{
+				LogicalResourceId: aws.String(testStackName),
+				ResourceStatus:    aws.String(cloudformation.ResourceStatusCreateInProgress),
+			},
+		},
+		StackId: aws.String("stack-12345"),
+	}
+	testCfn.Svc = mockedSvc
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := testCfn.CreateStack(ctx, testStackName, "", testAzs)
+	th.Nok(t, err)
+	th.Assert(t, time.Since(start) < creationTimeoutBound, "Stack polling didn't respect the deadline")
+	th.Equals(t, []string{testStackName}, mockedSvc.DeletedStackNames)
+}
+
+func TestCreateStack_TimeoutDeleteStackError(t *testing.T) {
+	mockedSvc := &th.MockedCfnSvc{
+		StackEvents: []*cloudformation.StackEvent{
+			{
+				LogicalResourceId: aws.String(testStackName),
+				ResourceStatus:    aws.String(cloudformation.ResourceStatusCreateInProgress),
+			},
+		},
+		StackId:          aws.String("stack-12345"),
+		DeleteStackError: errors.New("Test error"),
+	}
+	testCfn.Svc = mockedSvc
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := testCfn.CreateStack(ctx, testStackName, "", testAzs)
+	th.Nok(t, err)
+	th.Equals(t, []string{testStackName}, mockedSvc.DeletedStackNames)
+}
+
 func TestGetStackResources_Success(t *testing.T) {
 	testCfn.Svc = &th.MockedCfnSvc{
 		StackResources: mockedResources,
@@ -281,3 +330,68 @@ func TestDeleteStack_DeleteStackError(t *testing.T) {
 	err := testCfn.DeleteStack("")
 	th.Nok(t, err)
 }
+
+func TestGetSimpleEc2Stacks(t *testing.T) {
+	simpleEc2Tags := []*cloudformation.Tag{
+		{Key: aws.String("CreatedBy"), Value: aws.String("simple-ec2")},
+	}
+	testCfn.Svc = &th.MockedCfnSvc{
+		Stacks: []*cloudformation.Stack{
+			{
+				StackName:   aws.String("simple-ec2-active"),
+				StackStatus: aws.String(cloudformation.StackStatusCreateComplete),
+				Tags:        simpleEc2Tags,
+			},
+			{
+				StackName:   aws.String("simple-ec2-deleting"),
+				StackStatus: aws.String(cloudformation.StackStatusDeleteInProgress),
+				Tags:        simpleEc2Tags,
+			},
+			{
+				StackName:   aws.String("simple-ec2-creating"),
+				StackStatus: aws.String(cloudformation.StackStatusCreateInProgress),
+				Tags:        simpleEc2Tags,
+			},
+			{
+				StackName:   aws.String("simple-ec2-rolling-back"),
+				StackStatus: aws.String(cloudformation.StackStatusRollbackInProgress),
+				Tags:        simpleEc2Tags,
+			},
+			{
+				StackName:   aws.String("simple-ec2-deleted"),
+				StackStatus: aws.String(cloudformation.StackStatusDeleteComplete),
+				Tags:        simpleEc2Tags,
+			},
+			{
+				StackName:   aws.String("other-stack"),
+				StackStatus: aws.String(cloudformation.StackStatusCreateComplete),
+				Tags: []*cloudformation.Tag{
+					{Key: aws.String("CreatedBy"), Value: aws.String("someone-else")},
+				},
+			},
+		},
+	}
+
+	stacks, err := testCfn.GetSimpleEc2Stacks()
+	th.Ok(t, err)
+	th.Equals(t, 1, len(stacks))
+	th.Equals(t, "simple-ec2-active", *stacks[0].StackName)
+}
+
+func TestIsStackStatusStable(t *testing.T) {
+	th.Equals(t, true, cfn.IsStackStatusStable(cloudformation.StackStatusCreateComplete))
+	th.Equals(t, true, cfn.IsStackStatusStable(cloudformation.StackStatusRollbackFailed))
+	th.Equals(t, true, cfn.IsStackStatusStable(cloudformation.StackStatusUpdateRollbackComplete))
+	th.Equals(t, false, cfn.IsStackStatusStable(cloudformation.StackStatusCreateInProgress))
+	th.Equals(t, false, cfn.IsStackStatusStable(cloudformation.StackStatusUpdateCompleteCleanupInProgress))
+	th.Equals(t, false, cfn.IsStackStatusStable(cloudformation.StackStatusReviewInProgress))
+}
+
+func TestGetSimpleEc2Stacks_DescribeStacksPagesError(t *testing.T) {
+	testCfn.Svc = &th.MockedCfnSvc{
+		DescribeStacksPagesError: errors.New("Test error"),
+	}
+
+	_, err := testCfn.GetSimpleEc2Stacks()
+	th.Nok(t, err)
+}
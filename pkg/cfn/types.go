@@ -22,6 +22,7 @@ type CfnSvc interface {
 	DescribeStackResources(input *cfn.DescribeStackResourcesInput) (*cfn.DescribeStackResourcesOutput, error)
 	DescribeStackEventsPages(input *cfn.DescribeStackEventsInput, fn func(*cfn.DescribeStackEventsOutput, bool) bool) error
 	DeleteStack(input *cfn.DeleteStackInput) (*cfn.DeleteStackOutput, error)
+	DescribeStacksPages(input *cfn.DescribeStacksInput, fn func(*cfn.DescribeStacksOutput, bool) bool) error
 }
 
 type Cfn struct {
@@ -14,9 +14,23 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/briandowns/spinner"
+	"golang.org/x/term"
 )
 
+// IsAssumeYes skips the confirmation of destructive actions. It is set by the --yes flag of commands deleting resources
+var IsAssumeYes = false
+
+// IsNoSpinner disables the spinners shown while waiting for AWS, which are also skipped when output isn't a terminal
+var IsNoSpinner = false
+
 // Enum values for response messages
 const (
 	ResponseYes = "Yes"
@@ -33,7 +47,9 @@ const (
 	ResourceSubnetPlaceholder        = "Subnet Placeholder"
 	ResourceInstanceType             = "Instance Type"
 	ResourceImage                    = "Image"
+	ResourceImageDeprecation         = "Image Deprecation"
 	ResourceAutoTerminationTimer     = "Auto Termination Timer in Minutes"
+	ResourceAutoTerminationAction    = "Action on Timer Expiry"
 	ResourceKeepEbsVolume            = "Keep EBS Volume(s) After Termination"
 	ResourceSecurityGroup            = "Security Group"
 	ResourceSecurityGroupPlaceholder = "Security Group Placeholder"
@@ -41,6 +57,25 @@ const (
 	ResourceBootScriptFilePath       = "Boot Script Filepath"
 	ResourceUserTags                 = "Tag Specification(key|value)"
 	ResourceCapacityType             = "Capacity Type"
+	ResourcePrivateIpAddress         = "Private IP Address"
+	ResourceSecondarySubnet          = "Secondary Subnet"
+	ResourceSpotBlockDuration        = "Spot Block Duration in Minutes"
+	ResourceUserData                 = "User Data"
+	ResourceBootMode                 = "Boot Mode"
+	ResourceCapacityReservation      = "Capacity Reservation"
+	ResourcePrivateDnsName           = "Private DNS Name Options"
+	ResourceCpuCredits               = "CPU Credits"
+	ResourceCarrierIp                = "Associate Carrier IP"
+	ResourceNetworkPerformance       = "Network Performance"
+	ResourceEbsBandwidth             = "EBS Bandwidth"
+	ResourceHostResourceGroup        = "Host Resource Group"
+	ResourceInstanceCount            = "Instance Count"
+	ResourceRootVolumeSize           = "Root Volume Size in GiB"
+	ResourceAffinity                 = "Host Affinity"
+	ResourceRootVolumeType           = "Root Volume Type"
+	ResourceKeyPair                  = "Key Pair"
+	ResourceImds                     = "Instance Metadata Service"
+	ResourcePublicIp                 = "Public IP Address"
 )
 
 // Show errors if there are any. Return true when there are errors, and false when there is none
@@ -51,3 +86,117 @@ func ShowError(err error, message string) bool {
 	}
 	return false
 }
+
+/*
+SuggestClosest returns the candidate closest to the input by Levenshtein distance, compared case-insensitively.
+An empty string is returned when no candidate is close enough for the input to be a likely typo of it.
+*/
+func SuggestClosest(input string, candidates []string) string {
+	input = strings.ToLower(input)
+	maxDistance := len(input) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+
+	suggestion := ""
+	for _, candidate := range candidates {
+		distance := levenshteinDistance(input, strings.ToLower(candidate))
+		if distance <= maxDistance {
+			suggestion = candidate
+			maxDistance = distance - 1
+		}
+	}
+
+	return suggestion
+}
+
+// DidYouMean returns a "did you mean" hint with the closest candidate, or an empty string if none is close enough
+func DidYouMean(input string, candidates []string) string {
+	suggestion := SuggestClosest(input, candidates)
+	if suggestion == "" {
+		return ""
+	}
+	return fmt.Sprintf(" Did you mean \"%s\"?", suggestion)
+}
+
+// Get the minimum number of single-character edits needed to change a string into another
+func levenshteinDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
+
+/*
+StartSpinner starts a spinner with a suffix on stdout, and returns a function to stop it. The spinner is skipped
+if disabled or if stdout isn't a terminal, since its control sequences garble redirected output and CI logs.
+*/
+func StartSpinner(suffix string) (stop func()) {
+	if IsNoSpinner || !isTerminal(os.Stdout) {
+		return func() {}
+	}
+
+	s := spinner.New(spinner.CharSets[11], 100*time.Millisecond, spinner.WithWriter(os.Stdout))
+	s.Suffix = suffix
+	s.Color("blue", "bold")
+	s.Start()
+	return s.Stop
+}
+
+// isTerminal determines whether the writer is a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+/*
+ConfirmDestructive confirms a destructive action described by the summary, such as the termination of instances.
+The yes or no question is asked with ask, unless skipped with IsAssumeYes. It is asked on stderr, so structured
+output on stdout is never mixed with it. Returns true if the action is confirmed.
+*/
+func ConfirmDestructive(summary string, ask func(question string) (string, error)) (bool, error) {
+	if IsAssumeYes {
+		return true, nil
+	}
+
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	defer func() {
+		os.Stdout = stdout
+	}()
+
+	answer, err := ask(summary + " This can't be undone. Are you sure?")
+	if err != nil {
+		return false, err
+	}
+
+	return answer == ResponseYes, nil
+}
+
+/*
+ValidateAssumeYes validates skipping the confirmation of destructive actions against the output format, with the
+same rule for all destructive commands: the confirmation can only be skipped with structured output, which is
+meant for scripts. With text output, the user is always asked.
+*/
+func ValidateAssumeYes(isStructuredOutput bool) error {
+	if IsAssumeYes && !isStructuredOutput {
+		return errors.New("The confirmation can only be skipped with non-text output")
+	}
+	return nil
+}
@@ -16,7 +16,9 @@ package cli_test
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"simple-ec2/pkg/cli"
 	th "simple-ec2/test/testhelper"
@@ -41,3 +43,102 @@ func TestShowError_Error(t *testing.T) {
 	th.Equals(t, true, isError)
 	th.Equals(t, correctOutput, output)
 }
+
+func TestSuggestClosest(t *testing.T) {
+	regions := []string{"ap-east-1", "eu-west-1", "us-east-1", "us-east-2", "us-west-2"}
+	th.Equals(t, "us-east-1", cli.SuggestClosest("us-est-1", regions))
+	th.Equals(t, "us-west-2", cli.SuggestClosest("US-WEST-2", regions))
+	th.Equals(t, "t2.micro", cli.SuggestClosest("t2.mirco", []string{"t2.nano", "t2.micro", "t3.micro"}))
+	th.Equals(t, "Spot", cli.SuggestClosest("spto", []string{"On-Demand", "Spot"}))
+}
+
+func TestSuggestClosest_NoSuggestion(t *testing.T) {
+	th.Equals(t, "", cli.SuggestClosest("mars-north-9", []string{"us-east-1", "eu-west-1"}))
+	th.Equals(t, "", cli.SuggestClosest("t2.micro", nil))
+}
+
+func TestDidYouMean(t *testing.T) {
+	th.Equals(t, " Did you mean \"Spot\"?", cli.DidYouMean("Spt", []string{"On-Demand", "Spot"}))
+	th.Equals(t, "", cli.DidYouMean("Reserved", []string{"On-Demand", "Spot"}))
+}
+
+func TestStartSpinner_NonTerminal(t *testing.T) {
+	err := th.TakeOverStdout()
+	th.Ok(t, err)
+
+	stop := cli.StartSpinner(" fetching images")
+	time.Sleep(300 * time.Millisecond)
+	stop()
+	output := th.ReadStdout()
+
+	th.Equals(t, "", output)
+}
+
+func TestConfirmDestructive_Confirm(t *testing.T) {
+	askedQuestion := ""
+	isConfirmed, err := cli.ConfirmDestructive("1 instance(s) will be terminated.", func(question string) (string, error) {
+		askedQuestion = question
+		return cli.ResponseYes, nil
+	})
+	th.Ok(t, err)
+	th.Assert(t, isConfirmed, "The action should be confirmed")
+	th.Assert(t, strings.HasPrefix(askedQuestion, "1 instance(s) will be terminated."),
+		"The question should start with the summary")
+}
+
+func TestConfirmDestructive_Deny(t *testing.T) {
+	isConfirmed, err := cli.ConfirmDestructive("1 instance(s) will be terminated.", func(question string) (string, error) {
+		return cli.ResponseNo, nil
+	})
+	th.Ok(t, err)
+	th.Assert(t, !isConfirmed, "The action should be denied")
+
+	isConfirmed, err = cli.ConfirmDestructive("1 instance(s) will be terminated.", func(question string) (string, error) {
+		return "", errors.New("Exiting the questionnaire")
+	})
+	th.Nok(t, err)
+	th.Assert(t, !isConfirmed, "The action should be denied when asking fails")
+}
+
+func TestConfirmDestructive_AssumeYes(t *testing.T) {
+	cli.IsAssumeYes = true
+	defer func() {
+		cli.IsAssumeYes = false
+	}()
+
+	isAsked := false
+	isConfirmed, err := cli.ConfirmDestructive("1 instance(s) will be terminated.", func(question string) (string, error) {
+		isAsked = true
+		return cli.ResponseNo, nil
+	})
+	th.Ok(t, err)
+	th.Assert(t, isConfirmed, "The action should be confirmed with --yes")
+	th.Assert(t, !isAsked, "No question should be asked with --yes")
+}
+
+func TestConfirmDestructive_AskedOnStderr(t *testing.T) {
+	err := th.TakeOverStdout()
+	th.Ok(t, err)
+
+	_, err = cli.ConfirmDestructive("1 instance(s) will be terminated.", func(question string) (string, error) {
+		fmt.Println(question)
+		return cli.ResponseYes, nil
+	})
+	output := th.ReadStdout()
+
+	th.Ok(t, err)
+	th.Equals(t, "", output)
+}
+
+func TestValidateAssumeYes(t *testing.T) {
+	th.Ok(t, cli.ValidateAssumeYes(false))
+	th.Ok(t, cli.ValidateAssumeYes(true))
+
+	cli.IsAssumeYes = true
+	defer func() {
+		cli.IsAssumeYes = false
+	}()
+
+	th.Ok(t, cli.ValidateAssumeYes(true))
+	th.Nok(t, cli.ValidateAssumeYes(false))
+}
@@ -18,7 +18,13 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
+	"time"
+
+	"simple-ec2/pkg/tag"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
@@ -27,27 +33,84 @@ import (
 
 const defaultConfigFileName = "simple-ec2.json"
 
+// The capacity types of a launch
+const (
+	CapacityTypeOnDemand = "On-Demand"
+	CapacityTypeSpot     = "Spot"
+)
+
+// The port SSH listens on, unless another port is configured
+const DefaultSshPort = 22
+
 var simpleEc2Dir = getHomeDir() + "/.simple-ec2"
 
+// The environment variable with the URL of the org config, used when the --org-config flag is not supplied
+const OrgConfigEnv = "SIMPLE_EC2_ORG_CONFIG"
+
+// The file in the config folder caching the org config fetched last
+const OrgConfigCacheFileName = "org-config-cache.json"
+
+// The time the org config is cached for, so that it isn't fetched at every launch
+var OrgConfigCacheTtl = 15 * time.Minute
+
+const orgConfigFetchTimeout = 10 * time.Second
+
+// Fetch the org config document at the URL, replaced in tests
+var FetchOrgConfig = fetchOrgConfig
+
 /*
 A simple config for reading config files or flags into primitive type information.
 The config will later be used to parse into a detailed config and to launch an instance.
 */
 type SimpleInfo struct {
-	Region                        string
-	ImageId                       string
-	InstanceType                  string
-	SubnetId                      string
-	LaunchTemplateId              string
-	LaunchTemplateVersion         string
-	SecurityGroupIds              []string
-	NewVPC                        bool
-	AutoTerminationTimerMinutes   int
-	KeepEbsVolumeAfterTermination bool
-	IamInstanceProfile            string
-	BootScriptFilePath            string
-	UserTags                      map[string]string
-	CapacityType                  string
+	Region                          string
+	ImageId                         string
+	InstanceType                    string
+	SubnetId                        string
+	LaunchTemplateId                string
+	LaunchTemplateVersion           string
+	SecurityGroupIds                []string
+	NewVPC                          bool
+	AutoTerminationTimerMinutes     int
+	KeepEbsVolumeAfterTermination   bool
+	IamInstanceProfile              string
+	BootScriptFilePath              string
+	UserTags                        map[string]string
+	CapacityType                    string
+	PrivateIpAddress                string `json:"-"` // Can only be used by one instance at a time, so it's never saved
+	SecondarySubnetId               string
+	SecondarySecurityGroupIds       []string
+	AutoTerminationTimerAction      string
+	SpotBlockDurationMinutes        int
+	InheritedVpcTagKeys             []string
+	BootMode                        string
+	NitroTpm                        bool
+	BootScriptLinuxFilePath         string
+	BootScriptWindowsFilePath       string
+	CapacityReservationPreference   string
+	CapacityReservationId           string
+	SpotSizeFallback                bool
+	PrivateDnsHostnameType          string
+	EnableResourceNameDnsARecord    bool
+	EnableResourceNameDnsAAAARecord bool
+	NoAutoTermination               bool `json:"-"` // Clears the timer for a launch, so it's never saved
+	TagResourceTypes                []string
+	InstanceNamePrefix              string `json:"-"` // Names the instances of a launch, so it's never saved
+	ClientToken                     string `json:"-"` // Makes a launch idempotent across invocations, so it's never saved
+	SshPort                         int
+	CpuCredits                      string
+	AssociateCarrierIp              bool
+	HostResourceGroupArn            string
+	InstanceCount                   int `json:"-"` // Launches several instances at once, so it's never saved
+	RootVolumeSize                  int64
+	Affinity                        string
+	RootVolumeType                  string
+	RootVolumeIops                  int64
+	RootVolumeThroughput            int64
+	KeyPairName                     string
+	Imds                            string
+	NoPublicIp                      bool
+	Force                           bool `json:"-"` // Overrides the safety checks of a launch, so it's never saved
 }
 
 /*
@@ -61,6 +124,7 @@ type DetailedInfo struct {
 	InstanceTypeInfo *ec2.InstanceTypeInfo
 	SecurityGroups   []*ec2.SecurityGroup
 	TagSpecs         []*ec2.TagSpecification
+	UserData         *string // User data edited before launch, which replaces the assembled user data
 }
 
 type RequestInstanceInfo struct {
@@ -75,6 +139,19 @@ type RequestInstanceInfo struct {
 	InstanceInitiatedShutdownBehavior *string
 	UserData                          *string
 	LaunchTemplateTagSpecs            []*ec2.LaunchTemplateTagSpecificationRequest
+	PrivateIpAddress                  *string
+	InstanceMarketOptions             *ec2.LaunchTemplateInstanceMarketOptionsRequest
+	CapacityReservationSpecification  *ec2.CapacityReservationSpecification
+	LaunchTemplateCapacityReservation *ec2.LaunchTemplateCapacityReservationSpecificationRequest
+	PrivateDnsNameOptions             *ec2.PrivateDnsNameOptionsRequest
+	LaunchTemplatePrivateDnsName      *ec2.LaunchTemplatePrivateDnsNameOptionsRequest
+	CreditSpecification               *ec2.CreditSpecificationRequest
+	AssociateCarrierIpAddress         *bool
+	AssociatePublicIpAddress          *bool
+	Placement                         *ec2.Placement
+	KeyName                           *string
+	MetadataOptions                   *ec2.InstanceMetadataOptionsRequest
+	LaunchTemplateMetadataOptions     *ec2.LaunchTemplateInstanceMetadataOptionsRequest
 }
 
 func NewSimpleInfo() *SimpleInfo {
@@ -83,6 +160,48 @@ func NewSimpleInfo() *SimpleInfo {
 	return &s
 }
 
+/*
+Create a simple config from the attributes of an existing instance, which may be terminated.
+Tags added by simple-ec2 and AWS reserved tags are not copied, since they can't be applied at launch.
+*/
+func FromInstance(instance *ec2.Instance) *SimpleInfo {
+	simpleConfig := NewSimpleInfo()
+	simpleConfig.ImageId = aws.StringValue(instance.ImageId)
+	simpleConfig.InstanceType = aws.StringValue(instance.InstanceType)
+	simpleConfig.SubnetId = aws.StringValue(instance.SubnetId)
+
+	for _, group := range instance.SecurityGroups {
+		simpleConfig.SecurityGroupIds = append(simpleConfig.SecurityGroupIds, aws.StringValue(group.GroupId))
+	}
+
+	// The instance only references the profile by ARN, which ends with the profile name
+	if instance.IamInstanceProfile != nil && instance.IamInstanceProfile.Arn != nil {
+		arn := *instance.IamInstanceProfile.Arn
+		simpleConfig.IamInstanceProfile = arn[strings.LastIndex(arn, "/")+1:]
+	}
+
+	simpleEc2Tags := tag.GetSimpleEc2Tags()
+	for _, instanceTag := range instance.Tags {
+		key := aws.StringValue(instanceTag.Key)
+		if key == tag.ConnectWithKey {
+			simpleConfig.NoPublicIp = aws.StringValue(instanceTag.Value) == tag.ConnectWithSsm
+			continue
+		}
+		if _, found := (*simpleEc2Tags)[key]; found || strings.HasPrefix(key, "aws:") {
+			continue
+		}
+		simpleConfig.UserTags[key] = aws.StringValue(instanceTag.Value)
+	}
+
+	if aws.StringValue(instance.InstanceLifecycle) == ec2.InstanceLifecycleSpot {
+		simpleConfig.CapacityType = CapacityTypeSpot
+	} else {
+		simpleConfig.CapacityType = CapacityTypeOnDemand
+	}
+
+	return simpleConfig
+}
+
 // Get the home directory
 func getHomeDir() string {
 	var err error
@@ -111,9 +230,27 @@ func ReadConfig(simpleConfig *SimpleInfo, configFileName *string) error {
 		return err
 	}
 
+	// The boot script is read again at launch, so warn early if it has been moved or deleted since saving
+	if simpleConfig.BootScriptFilePath != "" {
+		if _, err := os.Stat(simpleConfig.BootScriptFilePath); err != nil {
+			fmt.Printf("Warning: The boot script %s in the config file no longer exists. "+
+				"Supply a new one with the --boot-script flag\n", simpleConfig.BootScriptFilePath)
+		}
+	}
+
 	return nil
 }
 
+/*
+Default the capacity type to On-Demand, only when neither the config file nor the flags specify it, such as in
+config files saved by older versions
+*/
+func SetDefaultCapacityType(simpleConfig *SimpleInfo) {
+	if simpleConfig.CapacityType == "" {
+		simpleConfig.CapacityType = CapacityTypeOnDemand
+	}
+}
+
 // Override config fields, if they are specified in flags
 func OverrideConfigWithFlags(simpleConfig *SimpleInfo, flagConfig *SimpleInfo) {
 	if flagConfig.Region != "" {
@@ -140,7 +277,10 @@ func OverrideConfigWithFlags(simpleConfig *SimpleInfo, flagConfig *SimpleInfo) {
 	if flagConfig.NewVPC != false {
 		simpleConfig.NewVPC = flagConfig.NewVPC
 	}
-	if flagConfig.AutoTerminationTimerMinutes != 0 {
+	if flagConfig.NoAutoTermination {
+		simpleConfig.AutoTerminationTimerMinutes = 0
+		simpleConfig.NoAutoTermination = true
+	} else if flagConfig.AutoTerminationTimerMinutes != 0 {
 		simpleConfig.AutoTerminationTimerMinutes = flagConfig.AutoTerminationTimerMinutes
 	}
 	if flagConfig.KeepEbsVolumeAfterTermination != false {
@@ -158,6 +298,135 @@ func OverrideConfigWithFlags(simpleConfig *SimpleInfo, flagConfig *SimpleInfo) {
 	if flagConfig.CapacityType != "" {
 		simpleConfig.CapacityType = flagConfig.CapacityType
 	}
+	if flagConfig.PrivateIpAddress != "" {
+		simpleConfig.PrivateIpAddress = flagConfig.PrivateIpAddress
+	}
+	if flagConfig.SecondarySubnetId != "" {
+		simpleConfig.SecondarySubnetId = flagConfig.SecondarySubnetId
+	}
+	if flagConfig.SecondarySecurityGroupIds != nil {
+		simpleConfig.SecondarySecurityGroupIds = flagConfig.SecondarySecurityGroupIds
+	}
+	if flagConfig.AutoTerminationTimerAction != "" {
+		simpleConfig.AutoTerminationTimerAction = flagConfig.AutoTerminationTimerAction
+	}
+	if flagConfig.SpotBlockDurationMinutes != 0 {
+		simpleConfig.SpotBlockDurationMinutes = flagConfig.SpotBlockDurationMinutes
+	}
+	if flagConfig.InheritedVpcTagKeys != nil {
+		simpleConfig.InheritedVpcTagKeys = flagConfig.InheritedVpcTagKeys
+	}
+	if flagConfig.BootMode != "" {
+		simpleConfig.BootMode = flagConfig.BootMode
+	}
+	if flagConfig.NitroTpm != false {
+		simpleConfig.NitroTpm = flagConfig.NitroTpm
+	}
+	if flagConfig.BootScriptLinuxFilePath != "" {
+		simpleConfig.BootScriptLinuxFilePath = flagConfig.BootScriptLinuxFilePath
+	}
+	if flagConfig.BootScriptWindowsFilePath != "" {
+		simpleConfig.BootScriptWindowsFilePath = flagConfig.BootScriptWindowsFilePath
+	}
+	if flagConfig.CapacityReservationPreference != "" {
+		simpleConfig.CapacityReservationPreference = flagConfig.CapacityReservationPreference
+	}
+	if flagConfig.CapacityReservationId != "" {
+		simpleConfig.CapacityReservationId = flagConfig.CapacityReservationId
+	}
+	if flagConfig.SpotSizeFallback != false {
+		simpleConfig.SpotSizeFallback = flagConfig.SpotSizeFallback
+	}
+	if flagConfig.PrivateDnsHostnameType != "" {
+		simpleConfig.PrivateDnsHostnameType = flagConfig.PrivateDnsHostnameType
+	}
+	if flagConfig.EnableResourceNameDnsARecord != false {
+		simpleConfig.EnableResourceNameDnsARecord = flagConfig.EnableResourceNameDnsARecord
+	}
+	if flagConfig.EnableResourceNameDnsAAAARecord != false {
+		simpleConfig.EnableResourceNameDnsAAAARecord = flagConfig.EnableResourceNameDnsAAAARecord
+	}
+	if flagConfig.TagResourceTypes != nil {
+		simpleConfig.TagResourceTypes = flagConfig.TagResourceTypes
+	}
+	if flagConfig.InstanceNamePrefix != "" {
+		simpleConfig.InstanceNamePrefix = flagConfig.InstanceNamePrefix
+	}
+	if flagConfig.ClientToken != "" {
+		simpleConfig.ClientToken = flagConfig.ClientToken
+	}
+	if flagConfig.SshPort != 0 {
+		simpleConfig.SshPort = flagConfig.SshPort
+	}
+	if flagConfig.CpuCredits != "" {
+		simpleConfig.CpuCredits = flagConfig.CpuCredits
+	}
+	if flagConfig.AssociateCarrierIp != false {
+		simpleConfig.AssociateCarrierIp = flagConfig.AssociateCarrierIp
+	}
+	if flagConfig.HostResourceGroupArn != "" {
+		simpleConfig.HostResourceGroupArn = flagConfig.HostResourceGroupArn
+	}
+	if flagConfig.InstanceCount != 0 {
+		simpleConfig.InstanceCount = flagConfig.InstanceCount
+	}
+	if flagConfig.RootVolumeSize != 0 {
+		simpleConfig.RootVolumeSize = flagConfig.RootVolumeSize
+	}
+	if flagConfig.Affinity != "" {
+		simpleConfig.Affinity = flagConfig.Affinity
+	}
+	if flagConfig.RootVolumeType != "" {
+		simpleConfig.RootVolumeType = flagConfig.RootVolumeType
+		simpleConfig.RootVolumeIops = flagConfig.RootVolumeIops
+		simpleConfig.RootVolumeThroughput = flagConfig.RootVolumeThroughput
+	}
+	if flagConfig.KeyPairName != "" {
+		simpleConfig.KeyPairName = flagConfig.KeyPairName
+	}
+	if flagConfig.Imds != "" {
+		simpleConfig.Imds = flagConfig.Imds
+	}
+	if flagConfig.NoPublicIp {
+		simpleConfig.NoPublicIp = flagConfig.NoPublicIp
+	}
+	if flagConfig.Force {
+		simpleConfig.Force = flagConfig.Force
+	}
+}
+
+// Get the SSH port of the config, which is the default port if not configured
+func GetSshPort(simpleConfig *SimpleInfo) int {
+	if simpleConfig.SshPort == 0 {
+		return DefaultSshPort
+	}
+
+	return simpleConfig.SshPort
+}
+
+/*
+Get the flags that supply the required fields missing from the config, in the order they are checked.
+A launch template provides the instance type, image and network, and a new VPC provides the subnet.
+*/
+func GetMissingRequiredFlags(simpleConfig *SimpleInfo) []string {
+	missingFlags := []string{}
+	if simpleConfig.Region == "" {
+		missingFlags = append(missingFlags, "--region")
+	}
+	if simpleConfig.LaunchTemplateId != "" {
+		return missingFlags
+	}
+	if simpleConfig.InstanceType == "" {
+		missingFlags = append(missingFlags, "--instance-type")
+	}
+	if simpleConfig.ImageId == "" {
+		missingFlags = append(missingFlags, "--image-id")
+	}
+	if simpleConfig.SubnetId == "" && !simpleConfig.NewVPC {
+		missingFlags = append(missingFlags, "--subnet-id")
+	}
+
+	return missingFlags
 }
 
 // Save the config as a JSON config file
@@ -202,3 +471,105 @@ func SaveInConfigFolder(fileName string, data []byte, perm os.FileMode) (*string
 
 	return &path, nil
 }
+
+// Validate the URL of the org config, which must be an HTTP or HTTPS URL
+func ValidateOrgConfigUrl(orgConfigUrl string) error {
+	parsedUrl, err := url.Parse(orgConfigUrl)
+	if err != nil || (parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https") || parsedUrl.Host == "" {
+		return fmt.Errorf("Org config URL %s must be an http or https URL", orgConfigUrl)
+	}
+
+	return nil
+}
+
+// The org config fetched last, cached with the URL it was fetched from
+type orgConfigCache struct {
+	Url  string
+	Data json.RawMessage
+}
+
+/*
+Read the org config, a JSON document with the same fields as the config file, which an organization shares at a URL
+to distribute default configurations. The document is cached in the config folder for OrgConfigCacheTtl.
+*/
+func ReadOrgConfig(orgConfigUrl string) (*SimpleInfo, error) {
+	data := readOrgConfigCache(orgConfigUrl)
+	isCached := data != nil
+	if !isCached {
+		var err error
+		data, err = FetchOrgConfig(orgConfigUrl)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	orgConfig := NewSimpleInfo()
+	err := json.Unmarshal(data, orgConfig)
+	if err != nil {
+		return nil, fmt.Errorf("The org config at %s is not a valid config: %w", orgConfigUrl, err)
+	}
+
+	// Caching is best effort, since the org config is fetched again if it isn't cached
+	if !isCached {
+		if cache, err := json.Marshal(&orgConfigCache{Url: orgConfigUrl, Data: data}); err == nil {
+			SaveInConfigFolder(OrgConfigCacheFileName, cache, 0644)
+		}
+	}
+
+	return orgConfig, nil
+}
+
+// Read the cached org config of the URL. Nil is returned if there is no cache for the URL or the cache expired
+func readOrgConfigCache(orgConfigUrl string) []byte {
+	path := simpleEc2Dir + "/" + OrgConfigCacheFileName
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > OrgConfigCacheTtl {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	cache := &orgConfigCache{}
+	if json.Unmarshal(data, cache) != nil || cache.Url != orgConfigUrl {
+		return nil
+	}
+
+	return cache.Data
+}
+
+func fetchOrgConfig(orgConfigUrl string) ([]byte, error) {
+	client := &http.Client{Timeout: orgConfigFetchTimeout}
+	response, err := client.Get(orgConfigUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Fetching the org config at %s failed with status %s", orgConfigUrl, response.Status)
+	}
+
+	return ioutil.ReadAll(response.Body)
+}
+
+/*
+Get the config with the org config as the lowest precedence defaults: the fields set in simpleConfig, read from the
+config file or flags, take precedence over the org config. A nil org config leaves the config as it is.
+*/
+func WithOrgDefaults(simpleConfig *SimpleInfo, orgConfig *SimpleInfo) *SimpleInfo {
+	if orgConfig == nil {
+		return simpleConfig
+	}
+
+	mergedConfig := *orgConfig
+	OverrideConfigWithFlags(&mergedConfig, simpleConfig)
+
+	// A config without tags has an empty map, which shouldn't hide the tags of the org config
+	if len(simpleConfig.UserTags) == 0 {
+		mergedConfig.UserTags = orgConfig.UserTags
+	}
+
+	return &mergedConfig
+}
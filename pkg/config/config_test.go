@@ -14,14 +14,21 @@
 package config_test
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"simple-ec2/pkg/config"
+	"simple-ec2/pkg/tag"
 	th "simple-ec2/test/testhelper"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
 )
 
 const testConfigFileName = "unit_test_config_temp.json"
@@ -56,33 +63,91 @@ const testKeepEBSVolume = true
 const testIamProfile = "iam-profile"
 const testBootScriptFilePath = "some/path/to/bootscript"
 const testCapacityType = "On-Spot-Demand"
+const testPrivateIpAddress = "10.0.0.10"
+const testSecondarySubnetId = "s-67890"
+const testAutoTerminationTimerAction = "stop"
+const testSpotBlockDurationMinutes = 120
+const testBootMode = "uefi"
+const testNitroTpm = true
+const testBootScriptLinuxFilePath = "some/path/to/linux/bootscript"
+const testBootScriptWindowsFilePath = "some/path/to/windows/bootscript"
+const testCapacityReservationPreference = "open"
+const testCapacityReservationId = "cr-12345"
+const testSpotSizeFallback = true
+const testPrivateDnsHostnameType = "resource-name"
+const testEnableResourceNameDnsARecord = true
+const testEnableResourceNameDnsAAAARecord = true
+const testInstanceNamePrefix = "web"
+const testSshPort = 2222
+const testCpuCredits = "unlimited"
+const testAssociateCarrierIp = true
+const testHostResourceGroupArn = "arn:aws:resource-groups:us-east-1:123456789012:group/hosts"
+const testRootVolumeSize = 16
+const testAffinity = "host"
+const testRootVolumeType = "io2"
+const testRootVolumeIops = 10000
+const testKeyPairName = "my-key"
+const testImds = "required"
+const testNoPublicIp = true
 
 var testTags = map[string]string{"testedBy": "BRYAN", "brokenBy": "CBASKIN"}
 var testSecurityGroup = []string{"sg-12345", "sg-67890"}
+var testSecondarySecurityGroup = []string{"sg-24680"}
+var testInheritedVpcTagKeys = []string{"CostCenter"}
+var testTagResourceTypes = []string{"instance", "network-interface"}
 
 // This JSON must match the above values used for testing
-const expectedJson = `{"Region":"us-somewhere","ImageId":"ami-12345","InstanceType":"t2.micro","SubnetId":"s-12345","LaunchTemplateId":"lt-12345","LaunchTemplateVersion":"1","SecurityGroupIds":["sg-12345","sg-67890"],"NewVPC":true,"AutoTerminationTimerMinutes":37,"KeepEbsVolumeAfterTermination":true,"IamInstanceProfile":"iam-profile","BootScriptFilePath":"some/path/to/bootscript","UserTags":{"brokenBy":"CBASKIN","testedBy":"BRYAN"},"CapacityType":"On-Spot-Demand"}`
+const expectedJson = `{"Region":"us-somewhere","ImageId":"ami-12345","InstanceType":"t2.micro","SubnetId":"s-12345","LaunchTemplateId":"lt-12345","LaunchTemplateVersion":"1","SecurityGroupIds":["sg-12345","sg-67890"],"NewVPC":true,"AutoTerminationTimerMinutes":37,"KeepEbsVolumeAfterTermination":true,"IamInstanceProfile":"iam-profile","BootScriptFilePath":"some/path/to/bootscript","UserTags":{"brokenBy":"CBASKIN","testedBy":"BRYAN"},"CapacityType":"On-Spot-Demand","SecondarySubnetId":"s-67890","SecondarySecurityGroupIds":["sg-24680"],"AutoTerminationTimerAction":"stop","SpotBlockDurationMinutes":120,"InheritedVpcTagKeys":["CostCenter"],"BootMode":"uefi","NitroTpm":true,"BootScriptLinuxFilePath":"some/path/to/linux/bootscript","BootScriptWindowsFilePath":"some/path/to/windows/bootscript","CapacityReservationPreference":"open","CapacityReservationId":"cr-12345","SpotSizeFallback":true,"PrivateDnsHostnameType":"resource-name","EnableResourceNameDnsARecord":true,"EnableResourceNameDnsAAAARecord":true,"TagResourceTypes":["instance","network-interface"],"SshPort":2222,"CpuCredits":"unlimited","AssociateCarrierIp":true,"HostResourceGroupArn":"arn:aws:resource-groups:us-east-1:123456789012:group/hosts","RootVolumeSize":16,"Affinity":"host","RootVolumeType":"io2","RootVolumeIops":10000,"RootVolumeThroughput":0,"KeyPairName":"my-key","Imds":"required","NoPublicIp":true}`
 
 // This JSON must NOT match the above values, to verify overriding with flags
-const overridableJson = `{"Region":"us-nowhere","ImageId":"ami-67890","InstanceType":"t2.nano","SubnetId":"s-67890","LaunchTemplateId":"lt-67890","LaunchTemplateVersion":"2","SecurityGroupIds":["sg-98765","sg-43210"],"NewVPC":false,"AutoTerminationTimerMinutes":0,"KeepEbsVolumeAfterTermination":false,"IamInstanceProfile":"you-are-profile","BootScriptFilePath":"some/other/path/to/bootscript","UserTags":{"brokenBy":"JFINLAY","testedBy":"BRYAN"},"CapacityType":"On-Demand"}`
+const overridableJson = `{"Region":"us-nowhere","ImageId":"ami-67890","InstanceType":"t2.nano","SubnetId":"s-67890","LaunchTemplateId":"lt-67890","LaunchTemplateVersion":"2","SecurityGroupIds":["sg-98765","sg-43210"],"NewVPC":false,"AutoTerminationTimerMinutes":0,"KeepEbsVolumeAfterTermination":false,"IamInstanceProfile":"you-are-profile","BootScriptFilePath":"some/other/path/to/bootscript","UserTags":{"brokenBy":"JFINLAY","testedBy":"BRYAN"},"CapacityType":"On-Demand","SecondarySubnetId":"s-12345","SecondarySecurityGroupIds":["sg-13579"],"AutoTerminationTimerAction":"terminate","SpotBlockDurationMinutes":240,"InheritedVpcTagKeys":["Project"],"BootMode":"legacy-bios","NitroTpm":false,"BootScriptLinuxFilePath":"some/other/path/to/linux/bootscript","BootScriptWindowsFilePath":"some/other/path/to/windows/bootscript","CapacityReservationPreference":"none","CapacityReservationId":"cr-67890","SpotSizeFallback":false,"PrivateDnsHostnameType":"ip-name","EnableResourceNameDnsARecord":false,"EnableResourceNameDnsAAAARecord":false,"TagResourceTypes":["instance","spot-instances-request"],"SshPort":22,"CpuCredits":"standard","AssociateCarrierIp":false,"HostResourceGroupArn":"arn:aws:resource-groups:us-east-1:123456789012:group/other-hosts","RootVolumeSize":8,"Affinity":"default","RootVolumeType":"gp3","RootVolumeIops":0,"RootVolumeThroughput":250,"KeyPairName":"other-key","Imds":"disabled","NoPublicIp":false}`
 
 // TestSaveConfig writes a config to a temporary file and verifies that the resulting JSON is correct
 func TestSaveConfig(t *testing.T) {
 	testConfig := &config.SimpleInfo{
-		Region:                        testRegion,
-		ImageId:                       testImageId,
-		InstanceType:                  testInstanceType,
-		SubnetId:                      testSubnetId,
-		LaunchTemplateId:              testLaunchTemplateId,
-		LaunchTemplateVersion:         testLaunchTemplateVersion,
-		SecurityGroupIds:              testSecurityGroup,
-		NewVPC:                        testNewVPC,
-		AutoTerminationTimerMinutes:   testAutoTerminationTimerMinutes,
-		KeepEbsVolumeAfterTermination: testKeepEBSVolume,
-		IamInstanceProfile:            testIamProfile,
-		BootScriptFilePath:            testBootScriptFilePath,
-		UserTags:                      testTags,
-		CapacityType:                  testCapacityType,
+		Region:                          testRegion,
+		ImageId:                         testImageId,
+		InstanceType:                    testInstanceType,
+		SubnetId:                        testSubnetId,
+		LaunchTemplateId:                testLaunchTemplateId,
+		LaunchTemplateVersion:           testLaunchTemplateVersion,
+		SecurityGroupIds:                testSecurityGroup,
+		NewVPC:                          testNewVPC,
+		AutoTerminationTimerMinutes:     testAutoTerminationTimerMinutes,
+		KeepEbsVolumeAfterTermination:   testKeepEBSVolume,
+		IamInstanceProfile:              testIamProfile,
+		BootScriptFilePath:              testBootScriptFilePath,
+		UserTags:                        testTags,
+		CapacityType:                    testCapacityType,
+		PrivateIpAddress:                testPrivateIpAddress,
+		SecondarySubnetId:               testSecondarySubnetId,
+		SecondarySecurityGroupIds:       testSecondarySecurityGroup,
+		AutoTerminationTimerAction:      testAutoTerminationTimerAction,
+		SpotBlockDurationMinutes:        testSpotBlockDurationMinutes,
+		InheritedVpcTagKeys:             testInheritedVpcTagKeys,
+		BootMode:                        testBootMode,
+		NitroTpm:                        testNitroTpm,
+		BootScriptLinuxFilePath:         testBootScriptLinuxFilePath,
+		BootScriptWindowsFilePath:       testBootScriptWindowsFilePath,
+		CapacityReservationPreference:   testCapacityReservationPreference,
+		CapacityReservationId:           testCapacityReservationId,
+		SpotSizeFallback:                testSpotSizeFallback,
+		PrivateDnsHostnameType:          testPrivateDnsHostnameType,
+		EnableResourceNameDnsARecord:    testEnableResourceNameDnsARecord,
+		EnableResourceNameDnsAAAARecord: testEnableResourceNameDnsAAAARecord,
+		TagResourceTypes:                testTagResourceTypes,
+		InstanceNamePrefix:              testInstanceNamePrefix,
+		SshPort:                         testSshPort,
+		CpuCredits:                      testCpuCredits,
+		AssociateCarrierIp:              testAssociateCarrierIp,
+		HostResourceGroupArn:            testHostResourceGroupArn,
+		RootVolumeSize:                  testRootVolumeSize,
+		Affinity:                        testAffinity,
+		RootVolumeType:                  testRootVolumeType,
+		RootVolumeIops:                  testRootVolumeIops,
+		KeyPairName:                     testKeyPairName,
+		Imds:                            testImds,
+		NoPublicIp:                      testNoPublicIp,
 	}
 
 	err := config.SaveConfig(testConfig, aws.String(testConfigFileName))
@@ -101,25 +166,171 @@ func TestOverrideConfigWithFlags(t *testing.T) {
 	actualConfig, err := readConfigFromFile(overridableJson)
 	th.Ok(t, err)
 	expectedConfig := &config.SimpleInfo{
-		Region:                        testRegion,
-		ImageId:                       testImageId,
-		InstanceType:                  testInstanceType,
-		SubnetId:                      testSubnetId,
-		LaunchTemplateId:              testLaunchTemplateId,
-		LaunchTemplateVersion:         testLaunchTemplateVersion,
-		SecurityGroupIds:              testSecurityGroup,
-		NewVPC:                        testNewVPC,
-		AutoTerminationTimerMinutes:   testAutoTerminationTimerMinutes,
-		KeepEbsVolumeAfterTermination: testKeepEBSVolume,
-		IamInstanceProfile:            testIamProfile,
-		BootScriptFilePath:            testBootScriptFilePath,
-		UserTags:                      testTags,
-		CapacityType:                  testCapacityType,
+		Region:                          testRegion,
+		ImageId:                         testImageId,
+		InstanceType:                    testInstanceType,
+		SubnetId:                        testSubnetId,
+		LaunchTemplateId:                testLaunchTemplateId,
+		LaunchTemplateVersion:           testLaunchTemplateVersion,
+		SecurityGroupIds:                testSecurityGroup,
+		NewVPC:                          testNewVPC,
+		AutoTerminationTimerMinutes:     testAutoTerminationTimerMinutes,
+		KeepEbsVolumeAfterTermination:   testKeepEBSVolume,
+		IamInstanceProfile:              testIamProfile,
+		BootScriptFilePath:              testBootScriptFilePath,
+		UserTags:                        testTags,
+		CapacityType:                    testCapacityType,
+		PrivateIpAddress:                testPrivateIpAddress,
+		SecondarySubnetId:               testSecondarySubnetId,
+		SecondarySecurityGroupIds:       testSecondarySecurityGroup,
+		AutoTerminationTimerAction:      testAutoTerminationTimerAction,
+		SpotBlockDurationMinutes:        testSpotBlockDurationMinutes,
+		InheritedVpcTagKeys:             testInheritedVpcTagKeys,
+		BootMode:                        testBootMode,
+		NitroTpm:                        testNitroTpm,
+		BootScriptLinuxFilePath:         testBootScriptLinuxFilePath,
+		BootScriptWindowsFilePath:       testBootScriptWindowsFilePath,
+		CapacityReservationPreference:   testCapacityReservationPreference,
+		CapacityReservationId:           testCapacityReservationId,
+		SpotSizeFallback:                testSpotSizeFallback,
+		PrivateDnsHostnameType:          testPrivateDnsHostnameType,
+		EnableResourceNameDnsARecord:    testEnableResourceNameDnsARecord,
+		EnableResourceNameDnsAAAARecord: testEnableResourceNameDnsAAAARecord,
+		TagResourceTypes:                testTagResourceTypes,
+		InstanceNamePrefix:              testInstanceNamePrefix,
+		SshPort:                         testSshPort,
+		CpuCredits:                      testCpuCredits,
+		AssociateCarrierIp:              testAssociateCarrierIp,
+		HostResourceGroupArn:            testHostResourceGroupArn,
+		RootVolumeSize:                  testRootVolumeSize,
+		Affinity:                        testAffinity,
+		RootVolumeType:                  testRootVolumeType,
+		RootVolumeIops:                  testRootVolumeIops,
+		KeyPairName:                     testKeyPairName,
+		Imds:                            testImds,
+		NoPublicIp:                      testNoPublicIp,
+		Force:                           true,
 	}
 	config.OverrideConfigWithFlags(actualConfig, expectedConfig)
 	th.Equals(t, expectedConfig, actualConfig)
 }
 
+func TestOverrideConfigWithFlags_NoAutoTermination(t *testing.T) {
+	actualConfig, err := readConfigFromFile(expectedJson)
+	th.Ok(t, err)
+	th.Equals(t, testAutoTerminationTimerMinutes, actualConfig.AutoTerminationTimerMinutes)
+
+	config.OverrideConfigWithFlags(actualConfig, &config.SimpleInfo{NoAutoTermination: true})
+	th.Equals(t, 0, actualConfig.AutoTerminationTimerMinutes)
+	th.Equals(t, true, actualConfig.NoAutoTermination)
+}
+
+func TestOverrideConfigWithFlags_UnsetAutoTermination(t *testing.T) {
+	actualConfig, err := readConfigFromFile(expectedJson)
+	th.Ok(t, err)
+
+	config.OverrideConfigWithFlags(actualConfig, &config.SimpleInfo{})
+	th.Equals(t, testAutoTerminationTimerMinutes, actualConfig.AutoTerminationTimerMinutes)
+	th.Equals(t, false, actualConfig.NoAutoTermination)
+}
+
+func TestSaveConfig_SpotRoundTrip(t *testing.T) {
+	err := config.SaveConfig(&config.SimpleInfo{
+		Region:       testRegion,
+		CapacityType: config.CapacityTypeSpot,
+	}, aws.String(testConfigFileName))
+	th.Ok(t, err)
+	defer os.Remove(testConfigFilePath)
+
+	// Without a capacity type flag, the capacity type of the config file is kept
+	actualConfig := config.NewSimpleInfo()
+	err = config.ReadConfig(actualConfig, aws.String(testConfigFileName))
+	th.Ok(t, err)
+	config.OverrideConfigWithFlags(actualConfig, &config.SimpleInfo{})
+	config.SetDefaultCapacityType(actualConfig)
+	th.Equals(t, config.CapacityTypeSpot, actualConfig.CapacityType)
+
+	config.OverrideConfigWithFlags(actualConfig, &config.SimpleInfo{CapacityType: config.CapacityTypeOnDemand})
+	th.Equals(t, config.CapacityTypeOnDemand, actualConfig.CapacityType)
+}
+
+func TestSetDefaultCapacityType(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{}
+	config.SetDefaultCapacityType(simpleConfig)
+	th.Equals(t, config.CapacityTypeOnDemand, simpleConfig.CapacityType)
+
+	simpleConfig = &config.SimpleInfo{CapacityType: config.CapacityTypeSpot}
+	config.SetDefaultCapacityType(simpleConfig)
+	th.Equals(t, config.CapacityTypeSpot, simpleConfig.CapacityType)
+}
+
+func TestSaveConfig_NoAutoTerminationNotSaved(t *testing.T) {
+	data, err := json.Marshal(&config.SimpleInfo{NoAutoTermination: true})
+	th.Ok(t, err)
+	th.Assert(t, !strings.Contains(string(data), "NoAutoTermination"),
+		"The auto-termination override should not be saved")
+}
+
+func TestSaveConfig_PerLaunchFieldsNotSaved(t *testing.T) {
+	data, err := json.Marshal(&config.SimpleInfo{
+		PrivateIpAddress:   testPrivateIpAddress,
+		InstanceNamePrefix: testInstanceNamePrefix,
+		InstanceCount:      3,
+		Force:              true,
+	})
+	th.Ok(t, err)
+	th.Assert(t, !strings.Contains(string(data), "PrivateIpAddress"), "The private IP address should not be saved")
+	th.Assert(t, !strings.Contains(string(data), "InstanceNamePrefix"),
+		"The instance name prefix should not be saved")
+	th.Assert(t, !strings.Contains(string(data), "InstanceCount"), "The instance count should not be saved")
+	th.Assert(t, !strings.Contains(string(data), "Force"), "The force override should not be saved")
+}
+
+func TestGetMissingRequiredFlags_None(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		Region:       testRegion,
+		ImageId:      testImageId,
+		InstanceType: testInstanceType,
+		SubnetId:     testSubnetId,
+	}
+	th.Equals(t, []string{}, config.GetMissingRequiredFlags(simpleConfig))
+}
+
+func TestGetMissingRequiredFlags_MissingSubnet(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		Region:       testRegion,
+		ImageId:      testImageId,
+		InstanceType: testInstanceType,
+	}
+	th.Equals(t, []string{"--subnet-id"}, config.GetMissingRequiredFlags(simpleConfig))
+
+	// A new VPC provides the subnet
+	simpleConfig.NewVPC = true
+	th.Equals(t, []string{}, config.GetMissingRequiredFlags(simpleConfig))
+}
+
+func TestGetMissingRequiredFlags_MissingImage(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		Region:       testRegion,
+		InstanceType: testInstanceType,
+		SubnetId:     testSubnetId,
+	}
+	th.Equals(t, []string{"--image-id"}, config.GetMissingRequiredFlags(simpleConfig))
+}
+
+func TestGetMissingRequiredFlags_MissingAll(t *testing.T) {
+	expectedFlags := []string{"--region", "--instance-type", "--image-id", "--subnet-id"}
+	th.Equals(t, expectedFlags, config.GetMissingRequiredFlags(config.NewSimpleInfo()))
+}
+
+func TestGetMissingRequiredFlags_LaunchTemplate(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		Region:           testRegion,
+		LaunchTemplateId: testLaunchTemplateId,
+	}
+	th.Equals(t, []string{}, config.GetMissingRequiredFlags(simpleConfig))
+}
+
 // readConfigFromFile writes the given JSON string to a temporary file and unmarshals it into a SimpleInfo object
 func readConfigFromFile(configJson string) (*config.SimpleInfo, error) {
 	err := ioutil.WriteFile(testConfigFilePath, []byte(configJson), 0644)
@@ -143,20 +354,318 @@ func TestReadConfig(t *testing.T) {
 
 	// Check if the config is read correctly
 	expectedConfig := &config.SimpleInfo{
-		Region:                        testRegion,
-		ImageId:                       testImageId,
-		InstanceType:                  testInstanceType,
-		SubnetId:                      testSubnetId,
-		LaunchTemplateId:              testLaunchTemplateId,
-		LaunchTemplateVersion:         testLaunchTemplateVersion,
-		SecurityGroupIds:              testSecurityGroup,
-		NewVPC:                        testNewVPC,
-		AutoTerminationTimerMinutes:   testAutoTerminationTimerMinutes,
-		KeepEbsVolumeAfterTermination: testKeepEBSVolume,
-		IamInstanceProfile:            testIamProfile,
-		BootScriptFilePath:            testBootScriptFilePath,
-		UserTags:                      testTags,
-		CapacityType:                  testCapacityType,
+		Region:                          testRegion,
+		ImageId:                         testImageId,
+		InstanceType:                    testInstanceType,
+		SubnetId:                        testSubnetId,
+		LaunchTemplateId:                testLaunchTemplateId,
+		LaunchTemplateVersion:           testLaunchTemplateVersion,
+		SecurityGroupIds:                testSecurityGroup,
+		NewVPC:                          testNewVPC,
+		AutoTerminationTimerMinutes:     testAutoTerminationTimerMinutes,
+		KeepEbsVolumeAfterTermination:   testKeepEBSVolume,
+		IamInstanceProfile:              testIamProfile,
+		BootScriptFilePath:              testBootScriptFilePath,
+		UserTags:                        testTags,
+		CapacityType:                    testCapacityType,
+		SecondarySubnetId:               testSecondarySubnetId,
+		SecondarySecurityGroupIds:       testSecondarySecurityGroup,
+		AutoTerminationTimerAction:      testAutoTerminationTimerAction,
+		SpotBlockDurationMinutes:        testSpotBlockDurationMinutes,
+		InheritedVpcTagKeys:             testInheritedVpcTagKeys,
+		BootMode:                        testBootMode,
+		NitroTpm:                        testNitroTpm,
+		BootScriptLinuxFilePath:         testBootScriptLinuxFilePath,
+		BootScriptWindowsFilePath:       testBootScriptWindowsFilePath,
+		CapacityReservationPreference:   testCapacityReservationPreference,
+		CapacityReservationId:           testCapacityReservationId,
+		SpotSizeFallback:                testSpotSizeFallback,
+		PrivateDnsHostnameType:          testPrivateDnsHostnameType,
+		EnableResourceNameDnsARecord:    testEnableResourceNameDnsARecord,
+		EnableResourceNameDnsAAAARecord: testEnableResourceNameDnsAAAARecord,
+		TagResourceTypes:                testTagResourceTypes,
+		SshPort:                         testSshPort,
+		CpuCredits:                      testCpuCredits,
+		AssociateCarrierIp:              testAssociateCarrierIp,
+		HostResourceGroupArn:            testHostResourceGroupArn,
+		RootVolumeSize:                  testRootVolumeSize,
+		Affinity:                        testAffinity,
+		RootVolumeType:                  testRootVolumeType,
+		RootVolumeIops:                  testRootVolumeIops,
+		KeyPairName:                     testKeyPairName,
+		Imds:                            testImds,
+		NoPublicIp:                      testNoPublicIp,
 	}
 	th.Equals(t, expectedConfig, actualConfig)
 }
+
+// TestReadConfig_MissingBootScript reads a config whose boot script no longer exists,
+// and verifies that the config is still loaded with a warning
+func TestReadConfig_MissingBootScript(t *testing.T) {
+	const missingBootScriptFilePath = "/tmp/unit_test_missing_boot_script.sh"
+	os.Remove(missingBootScriptFilePath)
+	configJson := `{"BootScriptFilePath":"` + missingBootScriptFilePath + `"}`
+
+	err := th.TakeOverStdout()
+	th.Ok(t, err)
+	actualConfig, err := readConfigFromFile(configJson)
+	out := th.ReadStdout()
+
+	th.Ok(t, err)
+	th.Equals(t, missingBootScriptFilePath, actualConfig.BootScriptFilePath)
+	th.Assert(t, strings.Contains(out, "Warning"), "A warning should be printed for the missing boot script")
+}
+
+// TestFromInstance converts a sample instance to a config and verifies that only user-defined attributes are copied
+func TestFromInstance(t *testing.T) {
+	testInstance := &ec2.Instance{
+		ImageId:      aws.String(testImageId),
+		InstanceType: aws.String(testInstanceType),
+		SubnetId:     aws.String(testSubnetId),
+		SecurityGroups: []*ec2.GroupIdentifier{
+			{GroupId: aws.String("sg-12345")},
+			{GroupId: aws.String("sg-67890")},
+		},
+		IamInstanceProfile: &ec2.IamInstanceProfile{
+			Arn: aws.String("arn:aws:iam::123456789012:instance-profile/" + testIamProfile),
+		},
+		InstanceLifecycle: aws.String(ec2.InstanceLifecycleSpot),
+		Tags: []*ec2.Tag{
+			{Key: aws.String("testedBy"), Value: aws.String("BRYAN")},
+			{Key: aws.String("brokenBy"), Value: aws.String("CBASKIN")},
+			{Key: aws.String("CreatedBy"), Value: aws.String("simple-ec2")},
+			{Key: aws.String("aws:ec2launchtemplate:id"), Value: aws.String("lt-12345")},
+			{Key: aws.String(tag.ConnectWithKey), Value: aws.String(tag.ConnectWithSsm)},
+		},
+	}
+
+	expectedConfig := &config.SimpleInfo{
+		ImageId:            testImageId,
+		InstanceType:       testInstanceType,
+		SubnetId:           testSubnetId,
+		SecurityGroupIds:   testSecurityGroup,
+		IamInstanceProfile: testIamProfile,
+		UserTags:           testTags,
+		CapacityType:       "Spot",
+		NoPublicIp:         true,
+	}
+	th.Equals(t, expectedConfig, config.FromInstance(testInstance))
+}
+
+func TestReadInstanceRequirements_Json(t *testing.T) {
+	path := t.TempDir() + "/requirements.json"
+	requirementsJson := `{"vcpus": {"min": 2, "max": 4}, "memoryGib": {"min": 0.5}, "architecture": "arm64",
+		"allowedFamilies": ["t4g"], "burstableOk": true}`
+	th.Ok(t, ioutil.WriteFile(path, []byte(requirementsJson), 0644))
+
+	requirements, err := config.ReadInstanceRequirements(path)
+	th.Ok(t, err)
+	th.Equals(t, 2, requirements.VCpus.Min)
+	th.Equals(t, 4, *requirements.VCpus.Max)
+	th.Equals(t, 0.5, requirements.MemoryGib.Min)
+	th.Assert(t, requirements.MemoryGib.Max == nil, "Memory max should not be set")
+	th.Equals(t, "arm64", requirements.Architecture)
+	th.Assert(t, requirements.Gpus == nil, "GPUs should not be set")
+	th.Equals(t, []string{"t4g"}, requirements.AllowedFamilies)
+	th.Equals(t, true, *requirements.BurstableOk)
+}
+
+func TestReadInstanceRequirements_Empty(t *testing.T) {
+	path := t.TempDir() + "/requirements.yaml"
+	th.Ok(t, ioutil.WriteFile(path, []byte{}, 0644))
+
+	requirements, err := config.ReadInstanceRequirements(path)
+	th.Ok(t, err)
+	th.Equals(t, &config.InstanceRequirements{}, requirements)
+}
+
+func TestReadInstanceRequirements_UnknownField(t *testing.T) {
+	path := t.TempDir() + "/requirements.yaml"
+	th.Ok(t, ioutil.WriteFile(path, []byte("vcpu:\n  min: 2\n"), 0644))
+
+	_, err := config.ReadInstanceRequirements(path)
+	th.Nok(t, err)
+}
+
+func TestReadInstanceRequirements_NoFile(t *testing.T) {
+	_, err := config.ReadInstanceRequirements(t.TempDir() + "/requirements.yaml")
+	th.Nok(t, err)
+}
+
+func TestGetSshPort(t *testing.T) {
+	simpleConfig := config.NewSimpleInfo()
+	th.Equals(t, config.DefaultSshPort, config.GetSshPort(simpleConfig))
+
+	simpleConfig.SshPort = 2222
+	th.Equals(t, 2222, config.GetSshPort(simpleConfig))
+}
+
+var testOrgConfigCachePath = os.Getenv("HOME") + "/.simple-ec2/" + config.OrgConfigCacheFileName
+
+// Mock the fetch of the org config, counting the fetches
+func mockFetchOrgConfig(t *testing.T, data string, err error) *int {
+	fetchCount := 0
+	fetchOrgConfig := config.FetchOrgConfig
+	config.FetchOrgConfig = func(orgConfigUrl string) ([]byte, error) {
+		fetchCount++
+		return []byte(data), err
+	}
+	os.Remove(testOrgConfigCachePath)
+	t.Cleanup(func() {
+		config.FetchOrgConfig = fetchOrgConfig
+		os.Remove(testOrgConfigCachePath)
+	})
+
+	return &fetchCount
+}
+
+func TestReadOrgConfig_Cached(t *testing.T) {
+	const testOrgConfigUrl = "https://config.example.com/simple-ec2.json"
+	fetchCount := mockFetchOrgConfig(t, `{"Region":"us-org","InstanceType":"t3.small"}`, nil)
+
+	orgConfig, err := config.ReadOrgConfig(testOrgConfigUrl)
+	th.Ok(t, err)
+	th.Equals(t, "us-org", orgConfig.Region)
+	th.Equals(t, "t3.small", orgConfig.InstanceType)
+
+	// The cached org config is used, unless it is from another URL
+	_, err = config.ReadOrgConfig(testOrgConfigUrl)
+	th.Ok(t, err)
+	th.Equals(t, 1, *fetchCount)
+	_, err = config.ReadOrgConfig("https://config.example.com/other.json")
+	th.Ok(t, err)
+	th.Equals(t, 2, *fetchCount)
+}
+
+func TestReadOrgConfig_FetchError(t *testing.T) {
+	mockFetchOrgConfig(t, "", errors.New("Test error"))
+
+	_, err := config.ReadOrgConfig("https://config.example.com/simple-ec2.json")
+	th.Nok(t, err)
+}
+
+func TestReadOrgConfig_InvalidDocument(t *testing.T) {
+	mockFetchOrgConfig(t, `["us-org"]`, nil)
+
+	_, err := config.ReadOrgConfig("https://config.example.com/simple-ec2.json")
+	th.Nok(t, err)
+}
+
+func TestWithOrgDefaults_Precedence(t *testing.T) {
+	mockFetchOrgConfig(t, `{"Region":"us-org","InstanceType":"t3.small","IamInstanceProfile":"org-profile",`+
+		`"UserTags":{"team":"platform"}}`, nil)
+	orgConfig, err := config.ReadOrgConfig("https://config.example.com/simple-ec2.json")
+	th.Ok(t, err)
+
+	// The config file overrides the org config, and the flags override both
+	fileConfig, err := readConfigFromFile(`{"Region":"us-file","InstanceType":"t3.medium","UserTags":{}}`)
+	th.Ok(t, err)
+	simpleConfig := config.WithOrgDefaults(fileConfig, orgConfig)
+	config.OverrideConfigWithFlags(simpleConfig, &config.SimpleInfo{InstanceType: "t3.large"})
+
+	th.Equals(t, "us-file", simpleConfig.Region)
+	th.Equals(t, "t3.large", simpleConfig.InstanceType)
+	th.Equals(t, "org-profile", simpleConfig.IamInstanceProfile)
+	th.Equals(t, map[string]string{"team": "platform"}, simpleConfig.UserTags)
+}
+
+func TestWithOrgDefaults_NoOrgConfig(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{Region: testRegion}
+	th.Equals(t, simpleConfig, config.WithOrgDefaults(simpleConfig, nil))
+}
+
+func TestValidateOrgConfigUrl(t *testing.T) {
+	th.Ok(t, config.ValidateOrgConfigUrl("https://config.example.com/simple-ec2.json"))
+	th.Ok(t, config.ValidateOrgConfigUrl("http://localhost:8080/simple-ec2.json"))
+	th.Nok(t, config.ValidateOrgConfigUrl("config.example.com/simple-ec2.json"))
+	th.Nok(t, config.ValidateOrgConfigUrl("file:///etc/simple-ec2.json"))
+}
+
+var testConnectHistoryPath = os.Getenv("HOME") + "/.simple-ec2/" + config.ConnectHistoryFileName
+
+// Start from an empty connect history, restoring the existing history after the test
+func clearConnectHistory(t *testing.T) {
+	data, err := ioutil.ReadFile(testConnectHistoryPath)
+	os.Remove(testConnectHistoryPath)
+	t.Cleanup(func() {
+		os.Remove(testConnectHistoryPath)
+		if err == nil {
+			ioutil.WriteFile(testConnectHistoryPath, data, 0644)
+		}
+	})
+}
+
+func TestReadConnectHistory_NoFile(t *testing.T) {
+	clearConnectHistory(t)
+	th.Equals(t, []config.ConnectHistoryEntry{}, config.ReadConnectHistory())
+}
+
+func TestReadConnectHistory_InvalidFile(t *testing.T) {
+	clearConnectHistory(t)
+	_, err := config.SaveInConfigFolder(config.ConnectHistoryFileName, []byte("not json"), 0644)
+	th.Ok(t, err)
+
+	th.Equals(t, []config.ConnectHistoryEntry{}, config.ReadConnectHistory())
+}
+
+func TestRecordConnection(t *testing.T) {
+	clearConnectHistory(t)
+	now := time.Now().Round(0)
+
+	th.Ok(t, config.RecordConnection(config.ConnectHistoryEntry{InstanceId: "i-12345", Region: testRegion,
+		Alias: "web", ConnectedAt: now.Add(-time.Hour)}))
+	th.Ok(t, config.RecordConnection(config.ConnectHistoryEntry{InstanceId: "i-67890", Region: "us-west-2",
+		ConnectedAt: now.Add(-time.Minute)}))
+
+	// Connecting again replaces the previous entry of the instance, and moves it first
+	th.Ok(t, config.RecordConnection(config.ConnectHistoryEntry{InstanceId: "i-12345", Region: testRegion,
+		Alias: "web", ConnectedAt: now}))
+
+	entries := config.ReadConnectHistory()
+	th.Equals(t, 2, len(entries))
+	th.Equals(t, "i-12345", entries[0].InstanceId)
+	th.Equals(t, "web", entries[0].Alias)
+	th.Assert(t, now.Equal(entries[0].ConnectedAt), "The last connection should be recorded")
+	th.Equals(t, "i-67890", entries[1].InstanceId)
+	th.Equals(t, "us-west-2", entries[1].Region)
+}
+
+func TestRemoveFromConnectHistory(t *testing.T) {
+	clearConnectHistory(t)
+	th.Ok(t, config.RecordConnection(config.ConnectHistoryEntry{InstanceId: "i-12345", ConnectedAt: time.Now()}))
+	th.Ok(t, config.RecordConnection(config.ConnectHistoryEntry{InstanceId: "i-67890", ConnectedAt: time.Now()}))
+
+	th.Ok(t, config.RemoveFromConnectHistory("i-12345"))
+	entries := config.ReadConnectHistory()
+	th.Equals(t, 1, len(entries))
+	th.Equals(t, "i-67890", entries[0].InstanceId)
+}
+
+func TestPruneConnectHistory_Stale(t *testing.T) {
+	now := time.Now()
+	entries := []config.ConnectHistoryEntry{
+		{InstanceId: "i-stale", ConnectedAt: now.Add(-config.ConnectHistoryTtl - time.Hour)},
+		{InstanceId: "i-12345", ConnectedAt: now.Add(-time.Hour)},
+	}
+
+	prunedEntries := config.PruneConnectHistory(entries, now)
+	th.Equals(t, []config.ConnectHistoryEntry{entries[1]}, prunedEntries)
+}
+
+func TestPruneConnectHistory_MaxEntries(t *testing.T) {
+	now := time.Now()
+	entries := []config.ConnectHistoryEntry{}
+	for i := 0; i < config.ConnectHistoryMaxEntries+2; i++ {
+		entries = append(entries, config.ConnectHistoryEntry{
+			InstanceId:  fmt.Sprintf("i-%d", i),
+			ConnectedAt: now.Add(-time.Duration(i) * time.Minute),
+		})
+	}
+
+	// The latest entries are kept, even when out of order
+	entries[0], entries[len(entries)-1] = entries[len(entries)-1], entries[0]
+	prunedEntries := config.PruneConnectHistory(entries, now)
+	th.Equals(t, config.ConnectHistoryMaxEntries, len(prunedEntries))
+	th.Equals(t, "i-0", prunedEntries[0].InstanceId)
+	th.Equals(t, fmt.Sprintf("i-%d", config.ConnectHistoryMaxEntries-1), prunedEntries[len(prunedEntries)-1].InstanceId)
+}
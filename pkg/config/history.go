@@ -0,0 +1,113 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"time"
+)
+
+// The file in the config folder with the instances connected to recently
+const ConnectHistoryFileName = "connect-history.json"
+
+// The number of instances kept in the connect history
+const ConnectHistoryMaxEntries = 10
+
+// The time an instance is kept in the connect history after the last connection to it
+var ConnectHistoryTtl = 30 * 24 * time.Hour
+
+// An instance connected to recently, with the region it is in and its Name tag as the alias
+type ConnectHistoryEntry struct {
+	InstanceId  string
+	Region      string
+	Alias       string
+	ConnectedAt time.Time
+}
+
+/*
+Read the connect history, with the last connection first. Stale entries are left out.
+A missing or unreadable history is empty, since the history is only a shortcut.
+*/
+func ReadConnectHistory() []ConnectHistoryEntry {
+	data, err := ioutil.ReadFile(simpleEc2Dir + "/" + ConnectHistoryFileName)
+	if err != nil {
+		return []ConnectHistoryEntry{}
+	}
+
+	entries := []ConnectHistoryEntry{}
+	if json.Unmarshal(data, &entries) != nil {
+		return []ConnectHistoryEntry{}
+	}
+
+	return PruneConnectHistory(entries, time.Now())
+}
+
+// Record a connection in the connect history, replacing the previous entry of the instance
+func RecordConnection(entry ConnectHistoryEntry) error {
+	entries := []ConnectHistoryEntry{entry}
+	for _, oldEntry := range ReadConnectHistory() {
+		if oldEntry.InstanceId != entry.InstanceId {
+			entries = append(entries, oldEntry)
+		}
+	}
+
+	return saveConnectHistory(PruneConnectHistory(entries, time.Now()))
+}
+
+// Remove an instance from the connect history, such as when the instance no longer exists
+func RemoveFromConnectHistory(instanceId string) error {
+	entries := []ConnectHistoryEntry{}
+	for _, entry := range ReadConnectHistory() {
+		if entry.InstanceId != instanceId {
+			entries = append(entries, entry)
+		}
+	}
+
+	return saveConnectHistory(entries)
+}
+
+/*
+Prune the connect history: the entries older than ConnectHistoryTtl are dropped, and only the
+ConnectHistoryMaxEntries latest entries are kept, with the last connection first.
+*/
+func PruneConnectHistory(entries []ConnectHistoryEntry, now time.Time) []ConnectHistoryEntry {
+	sortedEntries := append([]ConnectHistoryEntry{}, entries...)
+	sort.SliceStable(sortedEntries, func(i, j int) bool {
+		return sortedEntries[i].ConnectedAt.After(sortedEntries[j].ConnectedAt)
+	})
+
+	prunedEntries := []ConnectHistoryEntry{}
+	for _, entry := range sortedEntries {
+		if len(prunedEntries) >= ConnectHistoryMaxEntries {
+			break
+		}
+		if now.Sub(entry.ConnectedAt) <= ConnectHistoryTtl {
+			prunedEntries = append(prunedEntries, entry)
+		}
+	}
+
+	return prunedEntries
+}
+
+func saveConnectHistory(entries []ConnectHistoryEntry) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	_, err = SaveInConfigFolder(ConnectHistoryFileName, data, 0644)
+	return err
+}
@@ -0,0 +1,70 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v3"
+)
+
+/*
+The instance type requirements read from a requirements file.
+Every field is optional. A range without a max has no upper bound.
+*/
+type InstanceRequirements struct {
+	VCpus           *IntRange   `yaml:"vcpus"`
+	MemoryGib       *FloatRange `yaml:"memoryGib"`
+	Architecture    string      `yaml:"architecture"`
+	Gpus            *IntRange   `yaml:"gpus"`
+	AllowedFamilies []string    `yaml:"allowedFamilies"`
+	BurstableOk     *bool       `yaml:"burstableOk"`
+}
+
+// An inclusive range of whole numbers in a requirements file
+type IntRange struct {
+	Min int  `yaml:"min"`
+	Max *int `yaml:"max"`
+}
+
+// An inclusive range of decimal numbers in a requirements file
+type FloatRange struct {
+	Min float64  `yaml:"min"`
+	Max *float64 `yaml:"max"`
+}
+
+/*
+Read instance type requirements from a YAML or JSON file.
+JSON is a subset of YAML, so both are read by the YAML decoder. Unknown fields are rejected to catch typos.
+*/
+func ReadInstanceRequirements(path string) (*InstanceRequirements, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	requirements := &InstanceRequirements{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err = decoder.Decode(requirements)
+
+	// An empty file has no requirements
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return requirements, nil
+}
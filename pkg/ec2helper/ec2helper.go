@@ -14,40 +14,175 @@
 package ec2helper
 
 import (
+	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
+	"net"
 	"os"
+	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"simple-ec2/pkg/cfn"
 	"simple-ec2/pkg/cli"
 	"simple-ec2/pkg/config"
+	"simple-ec2/pkg/iamhelper"
+	"simple-ec2/pkg/output"
 	"simple-ec2/pkg/tag"
 
 	"github.com/aws/amazon-ec2-instance-selector/v2/pkg/bytequantity"
 	"github.com/aws/amazon-ec2-instance-selector/v2/pkg/instancetypes"
 	"github.com/aws/amazon-ec2-instance-selector/v2/pkg/selector"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/google/uuid"
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
 )
 
 const DefaultRegion = "us-east-2"
 const tagNameKey = "Name"
 const RegionEnv = "AWS_DEFAULT_REGION"
-const cpuArchitecture = "x86_64"
+const maxRangeUpperBound = math.MaxInt32
+const launchTemplateNamePrefix = "SimpleEC2LaunchTemplate-"
+const securityGroupIdPrefix = "sg-"
+const maxClientTokenLength = 64
+const cloudConfigHeader = "#cloud-config"
+
+// The characters allowed in the names of security groups in a VPC
+var securityGroupNameRegex = regexp.MustCompile(`^[a-zA-Z0-9 ._\-:/()#,@\[\]+=&;{}!$*]+$`)
+
+// The tags EC2 and CloudFormation put on the resources they create
+const (
+	launchTemplateIdTagKey = "aws:ec2launchtemplate:id"
+	stackNameTagKey        = "aws:cloudformation:stack-name"
+)
+
+// The instance states in which an instance still uses its security groups and network
+var activeInstanceStates = []string{
+	ec2.InstanceStateNamePending,
+	ec2.InstanceStateNameRunning,
+	ec2.InstanceStateNameShuttingDown,
+	ec2.InstanceStateNameStopping,
+	ec2.InstanceStateNameStopped,
+}
+
+// The period before the deprecation of an image in which the image is reported as deprecating soon
+const ImageDeprecationWarningPeriod = 30 * 24 * time.Hour
+
+// The boot mode of images that boot with UEFI when the instance type supports it
+const BootModeUefiPreferred = "uefi-preferred"
+
+// The resource types that can be tagged at launch, in the order of the tag specifications
+var TagResourceTypes = []string{
+	ec2.ResourceTypeInstance,
+	ec2.ResourceTypeVolume,
+	ec2.ResourceTypeNetworkInterface,
+	ec2.ResourceTypeSpotInstancesRequest,
+}
+
+// The resource types tagged at launch, unless specified
+var DefaultTagResourceTypes = []string{
+	ec2.ResourceTypeInstance,
+	ec2.ResourceTypeVolume,
+	ec2.ResourceTypeNetworkInterface,
+}
+
+// The maximum number of other sizes tried when there is no Spot capacity for the instance type
+const MaxSpotSizeFallbacks = 3
+
+// The time for a created service-linked role to be usable by EC2, since IAM is eventually consistent
+var ServiceLinkedRoleWait = time.Second * 10
+
+// The types of zones. Local Zones and Wavelength Zones must be opted in and only support some instance types
+const (
+	ZoneTypeAvailabilityZone = "availability-zone"
+	ZoneTypeLocalZone        = "local-zone"
+	ZoneTypeWavelengthZone   = "wavelength-zone"
+)
+
+var ZoneTypes = []string{ZoneTypeAvailabilityZone, ZoneTypeLocalZone, ZoneTypeWavelengthZone}
+
+// The aliases of the latest and the default versions of a launch template
+const (
+	LaunchTemplateVersionLatest  = "$Latest"
+	LaunchTemplateVersionDefault = "$Default"
+)
+
+/*
+The EBS volume types a root volume can use. Throughput Optimized HDD (st1) and Cold HDD (sc1) volumes can't be
+boot volumes, so they are left out.
+*/
+var RootVolumeTypes = []string{ec2.VolumeTypeGp2, ec2.VolumeTypeGp3, ec2.VolumeTypeIo1, ec2.VolumeTypeIo2}
+
+/*
+The monthly prices in USD of a GiB of storage of the EBS volume types, at the rates of US East (N. Virginia).
+Other regions are often priced somewhat higher, and provisioned IOPS and throughput are priced separately.
+*/
+var ebsMonthlyPricesPerGiB = map[string]float64{
+	ec2.VolumeTypeGp2:      0.10,
+	ec2.VolumeTypeGp3:      0.08,
+	ec2.VolumeTypeIo1:      0.125,
+	ec2.VolumeTypeIo2:      0.125,
+	ec2.VolumeTypeSt1:      0.045,
+	ec2.VolumeTypeSc1:      0.015,
+	ec2.VolumeTypeStandard: 0.05,
+}
+
+// The CPU credit modes of burstable performance instance types
+const (
+	CpuCreditsStandard  = "standard"
+	CpuCreditsUnlimited = "unlimited"
+)
+
+/*
+The modes of the instance metadata service (IMDS). Required only allows IMDSv2 session tokens, and disabled turns
+off the metadata endpoint of the instance altogether.
+*/
+const (
+	ImdsOptional = "optional"
+	ImdsRequired = "required"
+	ImdsDisabled = "disabled"
+)
+
+var ImdsModes = []string{ImdsOptional, ImdsRequired, ImdsDisabled}
+
+// The subnet ids picking a subnet of the default VPC, whether or not it routes to an internet gateway
+const (
+	SubnetAutoPublic  = "auto-public"
+	SubnetAutoPrivate = "auto-private"
+)
+
+// The number of images in a page of DescribeImages results, which is the maximum allowed
+const describeImagesPageSize = 1000
+
+// The maximum number of regions searched at the same time for an instance
+const maxConcurrentRegionSearches = 8
+
+// The SSH user of Amazon Linux AMIs, and of the AMIs whose user isn't known
+const DefaultUserName = "ec2-user"
+
+// The console output is only available some time after the instance starts, so fetching it is retried
+var ConsoleOutputRetries = 20
+var ConsoleOutputRetryInterval = time.Second * 15
 
 func New(sess *session.Session) *EC2Helper {
 	return &EC2Helper{
 		Svc:  ec2.New(sess),
 		Sess: sess,
+		Iam:  iamhelper.New(sess),
 	}
 }
 
@@ -109,7 +244,115 @@ func (h *EC2Helper) GetEnabledRegions() ([]*ec2.Region, error) {
 }
 
 /*
-Get all available availability zone.
+Validate that a region is known to the SDK without calling AWS, to catch a typo before any call fails with a
+confusing error. The closest region is suggested if one is close enough, and the known regions are listed otherwise.
+The regions of every partition are known, so that China and GovCloud regions are valid too.
+*/
+func ValidateRegion(region string) error {
+	regionNames := []string{}
+	for _, partition := range endpoints.DefaultPartitions() {
+		for id := range partition.Regions() {
+			if id == region {
+				return nil
+			}
+			regionNames = append(regionNames, id)
+		}
+	}
+
+	// Sorted so that the suggestion among equally close regions doesn't depend on the map order
+	sort.Strings(regionNames)
+	suggestion := cli.DidYouMean(region, regionNames)
+	if suggestion == "" {
+		suggestion = " Valid regions are " + strings.Join(regionNames, ", ")
+	}
+	return fmt.Errorf("Region %s is not a known region.%s", region, suggestion)
+}
+
+/*
+Validate that a region is enabled for the account, suggesting the closest enabled region if it isn't.
+*/
+func (h *EC2Helper) ValidateRegion(region string) error {
+	regions, err := h.GetEnabledRegions()
+	if err != nil {
+		return err
+	}
+
+	regionNames := []string{}
+	for _, enabledRegion := range regions {
+		if *enabledRegion.RegionName == region {
+			return nil
+		}
+		regionNames = append(regionNames, *enabledRegion.RegionName)
+	}
+
+	return fmt.Errorf("Region %s is not enabled for the account.%s", region, cli.DidYouMean(region, regionNames))
+}
+
+// Get the client of a region, which doesn't change the region of the helper
+func (h *EC2Helper) getRegionalSvc(region string) EC2Svc {
+	if h.RegionalSvc != nil {
+		return h.RegionalSvc(region)
+	}
+
+	return ec2.New(h.Sess, aws.NewConfig().WithRegion(region))
+}
+
+/*
+Find the region of an instance by searching the enabled regions, a few at a time.
+Return a NotFoundError if no enabled region has the instance.
+*/
+func (h *EC2Helper) FindInstanceRegion(instanceId string) (string, error) {
+	regions, err := h.GetEnabledRegions()
+	if err != nil {
+		return "", err
+	}
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	foundRegion := ""
+	var searchErr error
+	limiter := make(chan struct{}, maxConcurrentRegionSearches)
+	for _, region := range regions {
+		regionName := aws.StringValue(region.RegionName)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limiter <- struct{}{}
+			defer func() { <-limiter }()
+
+			regionalHelper := &EC2Helper{Svc: h.getRegionalSvc(regionName)}
+			instances, err := regionalHelper.getInstances(&ec2.DescribeInstancesInput{
+				InstanceIds: aws.StringSlice([]string{instanceId}),
+			})
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				// Other regions may still have the instance, so only errors other than a missing instance are kept
+				if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != "InvalidInstanceID.NotFound" {
+					searchErr = err
+				}
+				return
+			}
+			if len(instances) > 0 {
+				foundRegion = regionName
+			}
+		}()
+	}
+	wg.Wait()
+
+	if foundRegion != "" {
+		return foundRegion, nil
+	}
+	if searchErr != nil {
+		return "", searchErr
+	}
+
+	return "", &NotFoundError{Message: fmt.Sprintf("Instance %s is not in any enabled region", instanceId)}
+}
+
+/*
+Get all available availability zone, of the zone type of the helper if specified.
 Empty result is not allowed.
 */
 func (h *EC2Helper) GetAvailableAvailabilityZones() ([]*ec2.AvailabilityZone, error) {
@@ -130,7 +373,94 @@ func (h *EC2Helper) GetAvailableAvailabilityZones() ([]*ec2.AvailabilityZone, er
 		return nil, errors.New("No availability zone available")
 	}
 
-	return azOutput.AvailabilityZones, nil
+	if h.ZoneType == "" {
+		return azOutput.AvailabilityZones, nil
+	}
+	zones := FilterZonesByType(azOutput.AvailabilityZones, h.ZoneType)
+	if len(zones) <= 0 {
+		return nil, fmt.Errorf("No zone of type %s available. Local Zones and Wavelength Zones must be opted in "+
+			"before use", h.ZoneType)
+	}
+
+	return zones, nil
+}
+
+// Get the type of a zone. Zones without a type are availability zones
+func GetZoneType(zone *ec2.AvailabilityZone) string {
+	if aws.StringValue(zone.ZoneType) == "" {
+		return ZoneTypeAvailabilityZone
+	}
+
+	return *zone.ZoneType
+}
+
+// Get the zones of the zone type
+func FilterZonesByType(zones []*ec2.AvailabilityZone, zoneType string) []*ec2.AvailabilityZone {
+	filteredZones := []*ec2.AvailabilityZone{}
+	for _, zone := range zones {
+		if GetZoneType(zone) == zoneType {
+			filteredZones = append(filteredZones, zone)
+		}
+	}
+
+	return filteredZones
+}
+
+// Validate the zone type. An empty zone type means all types
+func ValidateZoneType(zoneType string) error {
+	if zoneType != "" && !slices.Contains(ZoneTypes, zoneType) {
+		return fmt.Errorf("Zone type must be one of %s", strings.Join(ZoneTypes, ", "))
+	}
+
+	return nil
+}
+
+// Get the warning about the limited instance types in a Local Zone or Wavelength Zone, or "" for other zones
+func GetZoneTypeWarning(zoneName, zoneType string) string {
+	switch zoneType {
+	case ZoneTypeLocalZone:
+		return fmt.Sprintf("Warning: %s is a Local Zone, which only supports some instance types", zoneName)
+	case ZoneTypeWavelengthZone:
+		return fmt.Sprintf("Warning: %s is a Wavelength Zone, which only supports some instance types", zoneName)
+	}
+
+	return ""
+}
+
+// Get the types of the available zones by zone name, of the zone type of the helper if specified
+func (h *EC2Helper) GetZoneTypesByName() (map[string]string, error) {
+	zones, err := h.GetAvailableAvailabilityZones()
+	if err != nil {
+		return nil, err
+	}
+
+	zoneTypes := map[string]string{}
+	for _, zone := range zones {
+		zoneTypes[aws.StringValue(zone.ZoneName)] = GetZoneType(zone)
+	}
+
+	return zoneTypes, nil
+}
+
+/*
+Get the name of an available availability zone given its id. Zone ids, such as use1-az1, refer to the same
+physical zone in all accounts, while zone names are mapped differently across accounts.
+*/
+func (h *EC2Helper) GetAvailabilityZoneNameById(zoneId string) (string, error) {
+	zones, err := h.GetAvailableAvailabilityZones()
+	if err != nil {
+		return "", err
+	}
+
+	zoneIds := []string{}
+	for _, zone := range zones {
+		if aws.StringValue(zone.ZoneId) == zoneId {
+			return aws.StringValue(zone.ZoneName), nil
+		}
+		zoneIds = append(zoneIds, aws.StringValue(zone.ZoneId))
+	}
+
+	return "", fmt.Errorf("Availability zone id %s is not available.%s", zoneId, cli.DidYouMean(zoneId, zoneIds))
 }
 
 /*
@@ -225,6 +555,33 @@ func (h *EC2Helper) GetLaunchTemplateVersions(launchTemplateId string,
 	return allVersions, nil
 }
 
+/*
+Validate that a version of a launch template exists. Besides version numbers, the $Latest and $Default aliases
+are accepted. The error of a nonexistent version lists the available versions.
+*/
+func (h *EC2Helper) ValidateLaunchTemplateVersion(launchTemplateId, version string) error {
+	if version == LaunchTemplateVersionLatest || version == LaunchTemplateVersionDefault {
+		return nil
+	}
+
+	versions, err := h.GetLaunchTemplateVersions(launchTemplateId, nil)
+	if err != nil {
+		return err
+	}
+
+	versionNumbers := []string{}
+	for _, launchTemplateVersion := range versions {
+		versionNumber := strconv.FormatInt(*launchTemplateVersion.VersionNumber, 10)
+		if versionNumber == version {
+			return nil
+		}
+		versionNumbers = append(versionNumbers, versionNumber)
+	}
+
+	return fmt.Errorf("Launch template %s has no version %s. Available versions: %s", launchTemplateId, version,
+		strings.Join(versionNumbers, ", "))
+}
+
 /*
 Get a default instance type, which is a free-tier eligible type.
 Empty result is allowed.
@@ -283,22 +640,78 @@ func (h *EC2Helper) GetInstanceType(instanceType string) (*ec2.InstanceTypeInfo,
 	}
 
 	instanceTypes, err := h.getInstanceTypes(input)
-	if err != nil {
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "InvalidInstanceType" {
+		return nil, errors.New(aerr.Message() + h.suggestInstanceType(instanceType))
+	} else if err != nil {
 		return nil, err
 	}
 	if len(instanceTypes) <= 0 {
-		return nil, errors.New("Instance type " + instanceType + " is not available")
+		return nil, errors.New("Instance type " + instanceType + " is not available." +
+			h.suggestInstanceType(instanceType))
 	}
 
 	return instanceTypes[0], err
 }
 
+// Suggest the instance type of the region closest to an unavailable one, if any
+func (h *EC2Helper) suggestInstanceType(instanceType string) string {
+	instanceTypes, err := h.GetInstanceTypesInRegion()
+	if err != nil {
+		return ""
+	}
+
+	instanceTypeNames := []string{}
+	for _, instanceTypeInfo := range instanceTypes {
+		instanceTypeNames = append(instanceTypeNames, *instanceTypeInfo.InstanceType)
+	}
+
+	return cli.DidYouMean(instanceType, instanceTypeNames)
+}
+
+// Get the names of the instance types offered in the region
+func (h *EC2Helper) GetInstanceTypeOfferingsInRegion() ([]string, error) {
+	input := &ec2.DescribeInstanceTypeOfferingsInput{
+		LocationType: aws.String(ec2.LocationTypeRegion),
+	}
+
+	instanceTypeNames := []string{}
+	err := h.Svc.DescribeInstanceTypeOfferingsPages(input, func(page *ec2.DescribeInstanceTypeOfferingsOutput,
+		lastPage bool) bool {
+		for _, offering := range page.InstanceTypeOfferings {
+			instanceTypeNames = append(instanceTypeNames, *offering.InstanceType)
+		}
+		return !lastPage
+	})
+
+	return instanceTypeNames, err
+}
+
 /*
-Get the instance types selected by instance selector.
-Empty result is allowed.
+Validate that an instance type is offered in the region, such as the type of a config saved in another region.
+The error suggests the offered instance type closest to the unavailable one, if any.
+*/
+func (h *EC2Helper) ValidateInstanceTypeInRegion(instanceType string) error {
+	instanceTypeNames, err := h.GetInstanceTypeOfferingsInRegion()
+	if err != nil {
+		return err
+	}
+
+	for _, instanceTypeName := range instanceTypeNames {
+		if instanceTypeName == instanceType {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Instance type %s is not offered in region %s.%s", instanceType, *h.Sess.Config.Region,
+		cli.DidYouMean(instanceType, instanceTypeNames))
+}
+
+/*
+Get the instance selector filters for instance types around the given vCPUs and memory, of any architecture.
+The images offered for the selected instance type match its architectures, so Graviton instance types are offered
+as well.
 */
-func (h *EC2Helper) GetInstanceTypesFromInstanceSelector(instanceSelector InstanceSelector, vcpus,
-	memoryGib int) ([]*instancetypes.Details, error) {
+func GetInstanceSelectorFilters(vcpus, memoryGib int) (*selector.Filters, error) {
 	if vcpus <= 0 {
 		return nil, errors.New("Invalid vCPUs: " + fmt.Sprint(vcpus))
 	}
@@ -323,13 +736,103 @@ func (h *EC2Helper) GetInstanceTypesFromInstanceSelector(instanceSelector Instan
 	// The full struct definition can be found here for all of the supported filters:
 	// https://github.com/aws/amazon-ec2-instance-selector/blob/main/pkg/selector/types.go
 	filters := selector.Filters{
-		VCpusRange:      &vcpusRange,
-		MemoryRange:     &memoryRange,
-		CPUArchitecture: aws.String(cpuArchitecture),
+		VCpusRange:  &vcpusRange,
+		MemoryRange: &memoryRange,
+	}
+
+	return &filters, nil
+}
+
+/*
+Get the instance selector filters for the requirements read from a requirements file.
+Instance types of any architecture are selected unless the requirements define one, as for the vCPUs and memory
+questions.
+*/
+func GetRequirementsFilters(requirements *config.InstanceRequirements) (*selector.Filters, error) {
+	filters := selector.Filters{}
+	if requirements.Architecture != "" {
+		filters.CPUArchitecture = aws.String(requirements.Architecture)
+	}
+
+	if requirements.VCpus != nil {
+		vcpusRange, err := getIntRangeFilter("vCPUs", requirements.VCpus)
+		if err != nil {
+			return nil, err
+		}
+		filters.VCpusRange = vcpusRange
+	}
+
+	if requirements.MemoryGib != nil {
+		memory := requirements.MemoryGib
+		if memory.Min < 0 {
+			return nil, fmt.Errorf("Invalid memory: %v", memory.Min)
+		}
+
+		upperBound := bytequantity.FromGiB(maxRangeUpperBound)
+		if memory.Max != nil {
+			if *memory.Max < memory.Min {
+				return nil, fmt.Errorf("Invalid memory range: %v - %v", memory.Min, *memory.Max)
+			}
+			upperBound = bytequantity.FromMiB(uint64(*memory.Max * 1024))
+		}
+		filters.MemoryRange = &selector.ByteQuantityRangeFilter{
+			LowerBound: bytequantity.FromMiB(uint64(memory.Min * 1024)),
+			UpperBound: upperBound,
+		}
+	}
+
+	if requirements.Gpus != nil {
+		gpusRange, err := getIntRangeFilter("GPUs", requirements.Gpus)
+		if err != nil {
+			return nil, err
+		}
+		filters.GpusRange = gpusRange
+	}
+
+	// Instance type names are the family followed by a dot and the size, such as t3.micro
+	if len(requirements.AllowedFamilies) > 0 {
+		families := []string{}
+		for _, family := range requirements.AllowedFamilies {
+			families = append(families, regexp.QuoteMeta(family))
+		}
+		filters.AllowList = regexp.MustCompile(`^(` + strings.Join(families, "|") + `)\.`)
+	}
+
+	if requirements.BurstableOk != nil && !*requirements.BurstableOk {
+		filters.Burstable = aws.Bool(false)
+	}
+
+	return &filters, nil
+}
+
+// Convert a range in a requirements file to an instance selector range. A missing max means no upper bound
+func getIntRangeFilter(name string, intRange *config.IntRange) (*selector.IntRangeFilter, error) {
+	if intRange.Min < 0 {
+		return nil, fmt.Errorf("Invalid %s: %d", name, intRange.Min)
+	}
+
+	upperBound := maxRangeUpperBound
+	if intRange.Max != nil {
+		if *intRange.Max < intRange.Min {
+			return nil, fmt.Errorf("Invalid %s range: %d - %d", name, intRange.Min, *intRange.Max)
+		}
+		upperBound = *intRange.Max
 	}
 
+	return &selector.IntRangeFilter{
+		LowerBound: intRange.Min,
+		UpperBound: upperBound,
+	}, nil
+}
+
+/*
+Get the instance types selected by instance selector.
+Empty result is allowed.
+*/
+func (h *EC2Helper) GetInstanceTypesFromInstanceSelector(instanceSelector InstanceSelector,
+	filters *selector.Filters) ([]*instancetypes.Details, error) {
 	// Pass the Filter struct to the Filter function of your selector instance
-	instanceTypesSlice, err := instanceSelector.FilterVerbose(filters)
+	instanceTypesSlice, err := instanceSelector.FilterVerbose(*filters)
 	if err != nil {
 		return nil, err
 	}
@@ -484,6 +987,37 @@ func (h *EC2Helper) GetImageIdsFromSSM(ssmClient *ssm.SSM, ssmPath string) ([]*s
 	return imageIds, nil
 }
 
+// Validate the root device type of default images. An empty value means it is chosen from the instance type
+func ValidateRootDeviceType(rootDeviceType string) error {
+	if rootDeviceType != "" && rootDeviceType != ec2.DeviceTypeEbs && rootDeviceType != ec2.DeviceTypeInstanceStore {
+		return fmt.Errorf("Root device type must be \"%s\" or \"%s\"", ec2.DeviceTypeEbs,
+			ec2.DeviceTypeInstanceStore)
+	}
+
+	return nil
+}
+
+/*
+Get the root device type of the default images for the instance type. Instance store is used when the
+instance type supports it, unless a root device type is forced, which must be supported by the instance type.
+*/
+func (h *EC2Helper) GetRootDeviceType(instanceTypeInfo *ec2.InstanceTypeInfo) (string, error) {
+	isInstanceStorageSupported := aws.BoolValue(instanceTypeInfo.InstanceStorageSupported)
+	if h.RootDeviceType == "" {
+		if isInstanceStorageSupported {
+			return ec2.DeviceTypeInstanceStore, nil
+		}
+		return ec2.DeviceTypeEbs, nil
+	}
+
+	if h.RootDeviceType == ec2.DeviceTypeInstanceStore && !isInstanceStorageSupported {
+		return "", fmt.Errorf("Instance type %s doesn't support instance store root devices",
+			aws.StringValue(instanceTypeInfo.InstanceType))
+	}
+
+	return h.RootDeviceType, nil
+}
+
 // Sort interface for images
 type byCreationDate []*ec2.Image
 
@@ -503,19 +1037,38 @@ func (h *EC2Helper) GetLatestImages(rootDeviceType *string, architectures []*str
 		inputs = h.GetDescribeImagesInputs(*rootDeviceType, architectures)
 	}
 
+	// The operating systems are looked up at the same time, keeping the first error
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
 	images := map[string]*ec2.Image{}
+	var firstErr error
 	for osName, input := range *inputs {
-		output, err := h.Svc.DescribeImages(&input)
-		if err != nil {
-			return nil, err
-		}
-		if len(output.Images) <= 0 {
-			continue
-		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			osImages, err := h.getImages(&input)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			if len(osImages) <= 0 {
+				return
+			}
+
+			// Sort the images and get the latest one
+			sort.Sort(byCreationDate(osImages))
+			images[osName] = osImages[len(osImages)-1]
+		}()
+	}
+	wg.Wait()
 
-		// Sort the images and get the latest one
-		sort.Sort(byCreationDate(output.Images))
-		images[osName] = output.Images[len(output.Images)-1]
+	if firstErr != nil {
+		return nil, firstErr
 	}
 	if len(images) <= 0 {
 		return nil, nil
@@ -552,6 +1105,12 @@ func (h *EC2Helper) GetDefaultImage(rootDeviceType *string, architectures []*str
 		}
 	}
 
+	// The image is the newest one matching its name pattern, so an old image means no recent image in the region
+	err = ValidateImageFreshness(topImage, h.ImageMaxAge, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
 	return topImage, nil
 }
 
@@ -574,15 +1133,75 @@ func (h *EC2Helper) GetImageById(imageId string) (*ec2.Image, error) {
 		},
 	}
 
-	output, err := h.Svc.DescribeImages(input)
+	images, err := h.getImages(input)
 	if err != nil {
 		return nil, err
 	}
-	if output == nil || output.Images == nil || len(output.Images) <= 0 {
-		return nil, errors.New("Image " + imageId + " is not found")
+	if len(images) <= 0 {
+		return nil, &NotFoundError{Message: "Image " + imageId + " is not found"}
+	}
+
+	return images[0], nil
+}
+
+// The user of the AMIs that don't use ec2-user, by a part of their lowercase name
+var imageUserNames = []struct {
+	namePart string
+	userName string
+}{
+	{"ubuntu", "ubuntu"},
+	{"debian", "admin"},
+	{"centos", "centos"},
+	{"fedora", "fedora"},
+	{"bitnami", "bitnami"},
+}
+
+// Get the default SSH user of an AMI. Amazon Linux, Red Hat and SUSE use ec2-user, which is assumed for unknown AMIs
+func GetDefaultUserName(image *ec2.Image) string {
+	if strings.EqualFold(aws.StringValue(image.Platform), ec2.PlatformValuesWindows) {
+		return "Administrator"
+	}
+
+	name := strings.ToLower(aws.StringValue(image.Name))
+	for _, imageUserName := range imageUserNames {
+		if strings.Contains(name, imageUserName.namePart) {
+			return imageUserName.userName
+		}
+	}
+
+	return DefaultUserName
+}
+
+/*
+Get the default SSH user of the AMI of an instance. The default user of unknown AMIs is used if the AMI can't
+be described, such as once it is deregistered.
+*/
+func (h *EC2Helper) GetInstanceUserName(instance *ec2.Instance) string {
+	image, err := h.GetImageById(aws.StringValue(instance.ImageId))
+	if err != nil {
+		return DefaultUserName
+	}
+
+	return GetDefaultUserName(image)
+}
+
+/*
+Get images given the input, across all pages of results. Without a page size, DescribeImages returns
+all the images at once, which can exceed the result size limit with broad filters.
+*/
+func (h *EC2Helper) getImages(input *ec2.DescribeImagesInput) ([]*ec2.Image, error) {
+	// The page size can't be specified along with image ids
+	if len(input.ImageIds) <= 0 {
+		input.MaxResults = aws.Int64(describeImagesPageSize)
 	}
 
-	return output.Images[0], nil
+	allImages := []*ec2.Image{}
+	err := h.Svc.DescribeImagesPages(input, func(page *ec2.DescribeImagesOutput, lastPage bool) bool {
+		allImages = append(allImages, page.Images...)
+		return !lastPage
+	})
+
+	return allImages, err
 }
 
 /*
@@ -620,7 +1239,7 @@ func (h *EC2Helper) GetVpcById(vpcId string) (*ec2.Vpc, error) {
 		return nil, err
 	}
 	if len(vpcs) <= 0 {
-		return nil, errors.New("The specified VPC " + vpcId + " is not found")
+		return nil, &NotFoundError{Message: "The specified VPC " + vpcId + " is not found"}
 	}
 
 	return (vpcs)[0], err
@@ -630,7 +1249,7 @@ func (h *EC2Helper) GetVpcById(vpcId string) (*ec2.Vpc, error) {
 Get the default VPC. If no VPC is default, simply return nil.
 Empty result is allowed.
 */
-func (h *EC2Helper) getDefaultVpc() (*ec2.Vpc, error) {
+func (h *EC2Helper) GetDefaultVpc() (*ec2.Vpc, error) {
 	input := &ec2.DescribeVpcsInput{}
 
 	vpcs, err := h.getVpcs(input)
@@ -691,7 +1310,27 @@ func (h *EC2Helper) GetSubnetsByVpc(vpcId string) ([]*ec2.Subnet, error) {
 }
 
 /*
-Get the specified subnet given a subnet ID.
+Get a subnet in the specified VPC and availability zone.
+Empty result is not allowed.
+*/
+func (h *EC2Helper) GetSubnetInVpcByAz(vpcId, az string) (*ec2.Subnet, error) {
+	subnets, err := h.GetSubnetsByVpc(vpcId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the first subnet in the availability zone
+	for _, subnet := range subnets {
+		if subnet.AvailabilityZone != nil && *subnet.AvailabilityZone == az {
+			return subnet, nil
+		}
+	}
+
+	return nil, errors.New("No subnet in availability zone " + az + " of the specified VPC " + vpcId)
+}
+
+/*
+Get the specified subnet given a subnet ID.
 Empty result is not allowed.
 */
 func (h *EC2Helper) GetSubnetById(subnetId string) (*ec2.Subnet, error) {
@@ -712,7 +1351,7 @@ func (h *EC2Helper) GetSubnetById(subnetId string) (*ec2.Subnet, error) {
 		return nil, err
 	}
 	if len(subnets) <= 0 {
-		return nil, errors.New("Specified subnet " + subnetId + " does not exist")
+		return nil, &NotFoundError{Message: "Specified subnet " + subnetId + " does not exist"}
 	}
 
 	// Find the only subnet in the output
@@ -731,6 +1370,117 @@ func (h *EC2Helper) getSubnets(input *ec2.DescribeSubnetsInput) ([]*ec2.Subnet,
 	return allSubnets, err
 }
 
+// Tell if the subnet id asks for a subnet of the default VPC to be picked, rather than naming a subnet
+func IsAutoSubnet(subnetId string) bool {
+	return subnetId == SubnetAutoPublic || subnetId == SubnetAutoPrivate
+}
+
+/*
+Get the route tables of a VPC.
+Empty result is allowed.
+*/
+func (h *EC2Helper) GetRouteTablesByVpc(vpcId string) ([]*ec2.RouteTable, error) {
+	input := &ec2.DescribeRouteTablesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name: aws.String("vpc-id"),
+				Values: []*string{
+					aws.String(vpcId),
+				},
+			},
+		},
+	}
+
+	allRouteTables := []*ec2.RouteTable{}
+	err := h.Svc.DescribeRouteTablesPages(input, func(page *ec2.DescribeRouteTablesOutput, lastPage bool) bool {
+		allRouteTables = append(allRouteTables, page.RouteTables...)
+		return !lastPage
+	})
+
+	return allRouteTables, err
+}
+
+// Tell if the route table has an active default route to an internet gateway
+func HasInternetRoute(routeTable *ec2.RouteTable) bool {
+	for _, route := range routeTable.Routes {
+		isDefaultRoute := aws.StringValue(route.DestinationCidrBlock) == "0.0.0.0/0" ||
+			aws.StringValue(route.DestinationIpv6CidrBlock) == "::/0"
+		if isDefaultRoute && strings.HasPrefix(aws.StringValue(route.GatewayId), "igw-") &&
+			aws.StringValue(route.State) != ec2.RouteStateBlackhole {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+Split the subnets into public and private subnets. A subnet uses the route table it is explicitly associated with,
+or the main route table of the VPC otherwise, and is public when that route table routes to an internet gateway.
+*/
+func ClassifySubnets(subnets []*ec2.Subnet, routeTables []*ec2.RouteTable) (public, private []*ec2.Subnet) {
+	var mainRouteTable *ec2.RouteTable
+	subnetRouteTables := map[string]*ec2.RouteTable{}
+	for _, routeTable := range routeTables {
+		for _, association := range routeTable.Associations {
+			if aws.BoolValue(association.Main) {
+				mainRouteTable = routeTable
+			} else if association.SubnetId != nil {
+				subnetRouteTables[*association.SubnetId] = routeTable
+			}
+		}
+	}
+
+	for _, subnet := range subnets {
+		routeTable, found := subnetRouteTables[aws.StringValue(subnet.SubnetId)]
+		if !found {
+			routeTable = mainRouteTable
+		}
+
+		if routeTable != nil && HasInternetRoute(routeTable) {
+			public = append(public, subnet)
+		} else {
+			private = append(private, subnet)
+		}
+	}
+
+	return public, private
+}
+
+/*
+Pick a public or private subnet of the default VPC, given SubnetAutoPublic or SubnetAutoPrivate.
+Empty result is not allowed.
+*/
+func (h *EC2Helper) GetAutoSubnet(autoSubnet string) (*ec2.Subnet, error) {
+	vpc, err := h.GetDefaultVpc()
+	if err != nil {
+		return nil, err
+	}
+	if vpc == nil {
+		return nil, errors.New("No default VPC available to pick a subnet from")
+	}
+
+	subnets, err := h.GetSubnetsByVpc(*vpc.VpcId)
+	if err != nil {
+		return nil, err
+	}
+	routeTables, err := h.GetRouteTablesByVpc(*vpc.VpcId)
+	if err != nil {
+		return nil, err
+	}
+
+	public, private := ClassifySubnets(subnets, routeTables)
+	kind, picked := "public", public
+	if autoSubnet == SubnetAutoPrivate {
+		kind, picked = "private", private
+	}
+	if len(picked) <= 0 {
+		return nil, fmt.Errorf("No %s subnet in the default VPC %s", kind, *vpc.VpcId)
+	}
+
+	return picked[0], nil
+}
+
 // Get the security groups based on the input, with all pages concatenated
 func (h *EC2Helper) getSecurityGroups(input *ec2.DescribeSecurityGroupsInput) ([]*ec2.SecurityGroup, error) {
 	allSecurityGroups := []*ec2.SecurityGroup{}
@@ -757,7 +1507,7 @@ func (h *EC2Helper) GetSecurityGroupsByIds(ids []string) ([]*ec2.SecurityGroup,
 		return nil, err
 	}
 	if len(securityGroups) <= 0 {
-		return nil, errors.New("The specified security groups do not exist")
+		return nil, &NotFoundError{Message: "The specified security groups do not exist"}
 	}
 
 	return securityGroups, err
@@ -822,15 +1572,30 @@ func (h *EC2Helper) GetSecurityGroupsByVpc(vpcId string) ([]*ec2.SecurityGroup,
 	return securityGroups, nil
 }
 
-// Create a security group that enables SSH connection to instances
-func (h *EC2Helper) CreateSecurityGroupForSsh(vpcId string) (*string, error) {
+/*
+Create a security group that enables SSH connection to instances on the port.
+The connection is allowed from the CIDR block, or from anywhere if the CIDR block is empty.
+*/
+func (h *EC2Helper) CreateSecurityGroupForSsh(vpcId string, port int, cidr string) (*string, error) {
+	groupNameUuid := uuid.New()
+	return h.createSecurityGroupForSsh(vpcId, fmt.Sprintf("simple-ec2 SSH-%s", groupNameUuid),
+		"simple-ec2 SSH Security Group", port, cidr)
+}
+
+// Create a security group with the name that enables SSH connection to instances, tagged with the name tag
+func (h *EC2Helper) createSecurityGroupForSsh(vpcId, groupName, nameTag string, port int,
+	cidr string) (*string, error) {
+	err := ValidateSshPort(port)
+	if err != nil {
+		return nil, err
+	}
+
 	fmt.Println("Creating new security group...")
 
-	groupNameUuid := uuid.New()
 	// Create a new security group
 	creationInput := &ec2.CreateSecurityGroupInput{
 		Description: aws.String("Created by simple-ec2 for SSH connection to instances"),
-		GroupName:   aws.String(fmt.Sprintf("simple-ec2 SSH-%s", groupNameUuid)),
+		GroupName:   aws.String(groupName),
 		VpcId:       aws.String(vpcId),
 	}
 
@@ -842,24 +1607,8 @@ func (h *EC2Helper) CreateSecurityGroupForSsh(vpcId string) (*string, error) {
 	// Add ingress rule for SSH
 	groupId := *creationOutput.GroupId
 	ingressInput := &ec2.AuthorizeSecurityGroupIngressInput{
-		GroupId: aws.String(groupId),
-		IpPermissions: []*ec2.IpPermission{
-			{
-				FromPort:   aws.Int64(22),
-				IpProtocol: aws.String("tcp"),
-				IpRanges: []*ec2.IpRange{
-					{
-						CidrIp: aws.String("0.0.0.0/0"),
-					},
-				},
-				Ipv6Ranges: []*ec2.Ipv6Range{
-					{
-						CidrIpv6: aws.String("::/0"),
-					},
-				},
-				ToPort: aws.Int64(22),
-			},
-		},
+		GroupId:       aws.String(groupId),
+		IpPermissions: []*ec2.IpPermission{getSshIpPermission(port, cidr, h.SecurityGroupRuleDescription)},
 	}
 
 	_, err = h.Svc.AuthorizeSecurityGroupIngress(ingressInput)
@@ -870,7 +1619,7 @@ func (h *EC2Helper) CreateSecurityGroupForSsh(vpcId string) (*string, error) {
 	// Create tags
 	tags := append(getSimpleEc2Tags(), &ec2.Tag{
 		Key:   aws.String("Name"),
-		Value: aws.String("simple-ec2 SSH Security Group"),
+		Value: aws.String(nameTag),
 	})
 	err = h.createTags([]string{groupId}, tags)
 	if err != nil {
@@ -882,6 +1631,182 @@ func (h *EC2Helper) CreateSecurityGroupForSsh(vpcId string) (*string, error) {
 	return creationOutput.GroupId, nil
 }
 
+/*
+Get the ingress rule for SSH on the port from the CIDR block, or from anywhere if the CIDR block is empty.
+Each range carries the description, or a description naming its CIDR block if the description is empty.
+*/
+func getSshIpPermission(port int, cidr, description string) *ec2.IpPermission {
+	permission := &ec2.IpPermission{
+		FromPort:   aws.Int64(int64(port)),
+		IpProtocol: aws.String("tcp"),
+		ToPort:     aws.Int64(int64(port)),
+	}
+
+	switch {
+	case cidr == "":
+		permission.IpRanges = []*ec2.IpRange{getSshIpRange("0.0.0.0/0", description)}
+		permission.Ipv6Ranges = []*ec2.Ipv6Range{getSshIpv6Range("::/0", description)}
+	case strings.Contains(cidr, ":"):
+		permission.Ipv6Ranges = []*ec2.Ipv6Range{getSshIpv6Range(cidr, description)}
+	default:
+		permission.IpRanges = []*ec2.IpRange{getSshIpRange(cidr, description)}
+	}
+
+	return permission
+}
+
+func getSshIpRange(cidr, description string) *ec2.IpRange {
+	return &ec2.IpRange{
+		CidrIp:      aws.String(cidr),
+		Description: aws.String(getSshRuleDescription(cidr, description)),
+	}
+}
+
+func getSshIpv6Range(cidr, description string) *ec2.Ipv6Range {
+	return &ec2.Ipv6Range{
+		CidrIpv6:    aws.String(cidr),
+		Description: aws.String(getSshRuleDescription(cidr, description)),
+	}
+}
+
+// Get the description of an SSH rule, which names the CIDR block unless overridden
+func getSshRuleDescription(cidr, description string) string {
+	if description != "" {
+		return description
+	}
+
+	return fmt.Sprintf("SSH from %s via simple-ec2", cidr)
+}
+
+// The characters allowed in the descriptions of security group rules
+var securityGroupRuleDescriptionRegex = regexp.MustCompile(`^[a-zA-Z0-9 ._\-:/()#,@\[\]+=&;{}!$*]*$`)
+
+// Validate the description of security group rules, which is limited in length and characters by EC2
+func ValidateSecurityGroupRuleDescription(description string) error {
+	if len(description) > 255 {
+		return errors.New("The security group rule description must be at most 255 characters")
+	}
+	if !securityGroupRuleDescriptionRegex.MatchString(description) {
+		return errors.New("The security group rule description can only contain letters, digits, spaces and " +
+			"._-:/()#,@[]+=&;{}!$*")
+	}
+
+	return nil
+}
+
+// Validate the SSH port, which must be a valid TCP port
+func ValidateSshPort(port int) error {
+	if port < 1 || port > 65535 {
+		return errors.New("SSH port must be between 1 and 65535")
+	}
+
+	return nil
+}
+
+/*
+Get the ids of the security groups given by ids or names in a VPC. A value that isn't the id of a security group
+in the VPC is treated as a name: the security group with the name is used if it exists in the VPC, or a new
+security group that enables SSH connection on the port is created with the name otherwise.
+*/
+func (h *EC2Helper) GetOrCreateSecurityGroups(vpcId string, idsOrNames []string, sshPort int) ([]string, error) {
+	securityGroups, err := h.GetSecurityGroupsByVpc(vpcId)
+	if err != nil {
+		return nil, err
+	}
+
+	groupIds := []string{}
+	for _, value := range idsOrNames {
+		groupId := ""
+		for _, group := range securityGroups {
+			if aws.StringValue(group.GroupId) == value || aws.StringValue(group.GroupName) == value {
+				groupId = aws.StringValue(group.GroupId)
+				break
+			}
+		}
+
+		if groupId == "" {
+			// An id is never created as a name, since names starting with "sg-" are not allowed
+			if strings.HasPrefix(value, securityGroupIdPrefix) {
+				return nil, &NotFoundError{Message: fmt.Sprintf("Security group %s does not exist in VPC %s",
+					value, vpcId)}
+			}
+			err = ValidateSecurityGroupName(value)
+			if err != nil {
+				return nil, err
+			}
+
+			newGroupId, err := h.createSecurityGroupForSsh(vpcId, value, value, sshPort, "")
+			if err != nil {
+				return nil, err
+			}
+			groupId = *newGroupId
+		}
+		groupIds = append(groupIds, groupId)
+	}
+
+	return groupIds, nil
+}
+
+// The key pair names simple-ec2 accepts, which are also safe as the names of private key files
+var keyPairNameRegex = regexp.MustCompile(`^[a-zA-Z0-9._\-]{1,255}$`)
+
+/*
+Validate the name of a key pair, which is also the name of its private key file, so it's limited to characters
+that are safe in file names
+*/
+func ValidateKeyPairName(keyName string) error {
+	if !keyPairNameRegex.MatchString(keyName) {
+		return errors.New("A key pair name must be 1 to 255 letters, digits, periods, underscores and hyphens")
+	}
+
+	return nil
+}
+
+// Get all key pairs in the region
+func (h *EC2Helper) GetKeyPairs() ([]*ec2.KeyPairInfo, error) {
+	output, err := h.Svc.DescribeKeyPairs(&ec2.DescribeKeyPairsInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	return output.KeyPairs, nil
+}
+
+/*
+Create a key pair and save its private key to a .pem file named after the key pair in the working directory,
+readable only by the user. Return the path of the file. An existing file is never overwritten.
+*/
+func (h *EC2Helper) CreateKeyPair(keyName string) (string, error) {
+	keyPath, err := filepath.Abs(keyName + ".pem")
+	if err != nil {
+		return "", err
+	}
+	if _, err = os.Stat(keyPath); err == nil {
+		return "", fmt.Errorf("The private key file %s already exists", keyPath)
+	}
+
+	output, err := h.Svc.CreateKeyPair(&ec2.CreateKeyPairInput{
+		KeyName: aws.String(keyName),
+		TagSpecifications: []*ec2.TagSpecification{
+			{
+				ResourceType: aws.String(ec2.ResourceTypeKeyPair),
+				Tags:         getSimpleEc2Tags(),
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	err = ioutil.WriteFile(keyPath, []byte(aws.StringValue(output.KeyMaterial)), 0600)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Created key pair %s. The private key is saved to %s\n", keyName, keyPath)
+	return keyPath, nil
+}
+
 // Get the reservations based on the input, with all pages concatenated
 func (h *EC2Helper) getInstances(input *ec2.DescribeInstancesInput) ([]*ec2.Instance, error) {
 	allReservations := []*ec2.Reservation{}
@@ -917,24 +1842,24 @@ func (h *EC2Helper) GetInstanceById(instanceId string) (*ec2.Instance, error) {
 		return nil, err
 	}
 	if len(instances) <= 0 {
-		return nil, errors.New("No instance found")
+		return nil, &NotFoundError{Message: "No instance found"}
 	}
 
 	return instances[0], nil
 }
 
 /*
-Get all instances based on states provided.
+Get all instances based on states provided, narrowed by the filters if any, such as tag filters.
 Empty result is allowed.
 */
-func (h *EC2Helper) GetInstancesByState(states []string) ([]*ec2.Instance, error) {
+func (h *EC2Helper) GetInstancesByState(states []string, filters ...*ec2.Filter) ([]*ec2.Instance, error) {
 	input := &ec2.DescribeInstancesInput{
-		Filters: []*ec2.Filter{
+		Filters: append([]*ec2.Filter{
 			{
 				Name:   aws.String("instance-state-name"),
 				Values: aws.StringSlice(states),
 			},
-		},
+		}, filters...),
 	}
 
 	instances, err := h.getInstances(input)
@@ -973,6 +1898,28 @@ func (h *EC2Helper) GetInstancesByFilter(instanceIds []string, filters []*ec2.Fi
 	return result, nil
 }
 
+/*
+Name the instances with the prefix and their number in launch order, such as web-1 and web-2.
+RunInstances applies the same tags to all instances, so the Name tags are created after the launch.
+*/
+func (h *EC2Helper) TagInstanceNames(instanceIds []string, prefix string) error {
+	for i, instanceId := range instanceIds {
+		name := fmt.Sprintf("%s-%d", prefix, i+1)
+		err := h.createTags([]string{instanceId}, []*ec2.Tag{
+			{
+				Key:   aws.String("Name"),
+				Value: aws.String(name),
+			},
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Instance %s named %s\n", instanceId, name)
+	}
+
+	return nil
+}
+
 // Create tags for the resources specified
 func (h *EC2Helper) createTags(resources []string, tags []*ec2.Tag) error {
 	input := &ec2.CreateTagsInput{
@@ -988,6 +1935,27 @@ func (h *EC2Helper) createTags(resources []string, tags []*ec2.Tag) error {
 	return nil
 }
 
+/*
+Get the tags of the VPC with the specified keys. Tags missing from the VPC are skipped,
+and tags provided by the user take precedence over the VPC tags.
+*/
+func getInheritedVpcTags(vpc *ec2.Vpc, keys []string, userTags map[string]string) []*ec2.Tag {
+	inheritedTags := []*ec2.Tag{}
+	for _, key := range keys {
+		if _, found := userTags[key]; found {
+			continue
+		}
+		for _, tag := range vpc.Tags {
+			if *tag.Key == key {
+				inheritedTags = append(inheritedTags, tag)
+				break
+			}
+		}
+	}
+
+	return inheritedTags
+}
+
 // Parse the simple config into detailed config
 func (h *EC2Helper) ParseConfig(simpleConfig *config.SimpleInfo) (*config.DetailedInfo, error) {
 	// If new VPC and subnets will be created, skip formatting subnet and vpc
@@ -1003,6 +1971,20 @@ func (h *EC2Helper) ParseConfig(simpleConfig *config.SimpleInfo) (*config.Detail
 			return nil, err
 		}
 
+		if simpleConfig.PrivateIpAddress != "" {
+			err = ValidatePrivateIpInSubnet(simpleConfig.PrivateIpAddress, subnet)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if simpleConfig.AssociateCarrierIp {
+			err = h.ValidateCarrierIpSubnet(subnet)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		vpc, err = h.GetVpcById(*subnet.VpcId)
 		if err != nil {
 			return nil, err
@@ -1012,10 +1994,38 @@ func (h *EC2Helper) ParseConfig(simpleConfig *config.SimpleInfo) (*config.Detail
 		if err != nil {
 			return nil, err
 		}
+
+		if simpleConfig.SecondarySubnetId != "" {
+			secondarySubnet, err := h.GetSubnetById(simpleConfig.SecondarySubnetId)
+			if err != nil {
+				return nil, err
+			}
+
+			err = ValidateSecondarySubnet(subnet, secondarySubnet)
+			if err != nil {
+				return nil, err
+			}
+		}
+	} else if simpleConfig.SecondarySubnetId != "" {
+		return nil, errors.New("A secondary subnet can't be used when a new VPC is created")
+	} else if simpleConfig.AssociateCarrierIp {
+		return nil, errors.New("A carrier IP can't be associated when a new VPC is created, which only has " +
+			"availability zones")
+	} else if len(simpleConfig.InheritedVpcTagKeys) > 0 {
+		return nil, errors.New("Tags can't be inherited from the VPC when a new VPC is created")
+	} else if simpleConfig.NoPublicIp {
+		return nil, errors.New("Instances without a public IP address can't be launched in a new VPC, whose " +
+			"subnets only reach Systems Manager through public IP addresses")
 	}
 
 	// Add simple-ec2 tags to created resources
 	resourceTags := getSimpleEc2Tags()
+	if simpleConfig.NoPublicIp {
+		resourceTags = append(resourceTags, &ec2.Tag{
+			Key:   aws.String(tag.ConnectWithKey),
+			Value: aws.String(tag.ConnectWithSsm),
+		})
+	}
 	if len(simpleConfig.UserTags) > 0 {
 		for k, v := range simpleConfig.UserTags {
 			resourceTags = append(resourceTags, &ec2.Tag{
@@ -1024,73 +2034,243 @@ func (h *EC2Helper) ParseConfig(simpleConfig *config.SimpleInfo) (*config.Detail
 			})
 		}
 	}
-	tagSpecs = []*ec2.TagSpecification{
-		{
-			ResourceType: aws.String("instance"),
-			Tags:         resourceTags,
-		},
+	if vpc != nil {
+		resourceTags = append(resourceTags, getInheritedVpcTags(vpc, simpleConfig.InheritedVpcTagKeys,
+			simpleConfig.UserTags)...)
 	}
 	image, err := h.GetImageById(simpleConfig.ImageId)
 	if err != nil {
 		return nil, err
-	} else {
-		if *image.RootDeviceType == "ebs" {
-			tagSpecs = append(tagSpecs,
-				&ec2.TagSpecification{
-					ResourceType: aws.String("volume"),
-					Tags:         resourceTags,
-				})
-		}
 	}
 
-	instanceTypeInfo, err := h.GetInstanceType(simpleConfig.InstanceType)
+	err = ValidateTagResourceTypes(simpleConfig.TagResourceTypes)
 	if err != nil {
 		return nil, err
 	}
+	tagSpecs = getTagSpecs(simpleConfig, image, resourceTags)
 
-	detailedConfig := config.DetailedInfo{
-		Image:            image,
-		Vpc:              vpc,
-		Subnet:           subnet,
-		InstanceTypeInfo: instanceTypeInfo,
-		SecurityGroups:   securityGroups,
-		TagSpecs:         tagSpecs,
+	if simpleConfig.AutoTerminationTimerMinutes > 0 {
+		err = ValidateTimerAction(simpleConfig.AutoTerminationTimerAction, image)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return &detailedConfig, nil
-}
+	if simpleConfig.BootScriptFilePath == "" &&
+		(simpleConfig.BootScriptLinuxFilePath != "" || simpleConfig.BootScriptWindowsFilePath != "") &&
+		GetBootScriptFilePath(simpleConfig, image) == "" {
+		return nil, fmt.Errorf("None of the boot scripts matches the %s platform of image %s",
+			aws.StringValue(image.PlatformDetails), aws.StringValue(image.ImageId))
+	}
 
-// Get a RunInstanceInput given a structured config
-func getRunInstanceInput(simpleConfig *config.SimpleInfo, detailedConfig *config.DetailedInfo) *ec2.RunInstancesInput {
-	dataConfig := createRequestInstanceConfig(simpleConfig, detailedConfig)
-	return &ec2.RunInstancesInput{
-		MaxCount:                          aws.Int64(1),
-		MinCount:                          aws.Int64(1),
-		LaunchTemplate:                    dataConfig.LaunchTemplate,
-		ImageId:                           dataConfig.ImageId,
-		InstanceType:                      dataConfig.InstanceType,
-		SubnetId:                          dataConfig.SubnetId,
-		SecurityGroupIds:                  dataConfig.SecurityGroupIds,
-		IamInstanceProfile:                dataConfig.IamInstanceProfile,
-		BlockDeviceMappings:               dataConfig.BlockDeviceMappings,
-		InstanceInitiatedShutdownBehavior: dataConfig.InstanceInitiatedShutdownBehavior,
-		UserData:                          dataConfig.UserData,
+	err = ValidateCapacityReservation(simpleConfig.CapacityReservationPreference, simpleConfig.CapacityReservationId)
+	if err != nil {
+		return nil, err
 	}
-}
 
-// Get the default string config
-func (h *EC2Helper) GetDefaultSimpleConfig() (*config.SimpleInfo, error) {
-	simpleConfig := config.NewSimpleInfo()
-	simpleConfig.Region = *h.Sess.Config.Region
+	err = ValidatePrivateDnsHostnameType(simpleConfig.PrivateDnsHostnameType)
+	if err != nil {
+		return nil, err
+	}
 
-	// get info about the instance type
-	simpleConfig.InstanceType = "t2.micro"
-	defaultInstanceType, err := h.GetDefaultFreeTierInstanceType()
+	err = ValidateSpotBlockDuration(simpleConfig.SpotBlockDurationMinutes)
+	if err != nil {
+		return nil, err
+	}
+	if simpleConfig.SpotBlockDurationMinutes > 0 && simpleConfig.CapacityType == config.CapacityTypeOnDemand {
+		return nil, errors.New("Spot block duration can't be used with On-Demand instances")
+	}
+
+	if simpleConfig.InstanceCount != 0 {
+		err = ValidateInstanceCount(simpleConfig.InstanceCount, simpleConfig.PrivateIpAddress)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = ValidateHostResourceGroupArn(simpleConfig.HostResourceGroupArn)
+	if err != nil {
+		return nil, err
+	}
+	if simpleConfig.HostResourceGroupArn != "" && simpleConfig.CapacityType == config.CapacityTypeSpot {
+		return nil, errors.New("Spot instances can't be launched on Dedicated Hosts of a host resource group")
+	}
+
+	err = ValidateAffinity(simpleConfig.Affinity)
+	if err != nil {
+		return nil, err
+	}
+	if simpleConfig.Affinity != "" && simpleConfig.HostResourceGroupArn == "" {
+		return nil, errors.New("Affinity can only be set with the host tenancy of a host resource group")
+	}
+
+	instanceTypeInfo, err := h.GetInstanceType(simpleConfig.InstanceType)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ValidateImageArchitecture(image, instanceTypeInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ValidateBootMode(simpleConfig.BootMode, simpleConfig.NitroTpm, image, instanceTypeInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	if simpleConfig.RootVolumeSize != 0 {
+		err = ValidateRootVolumeSize(simpleConfig.RootVolumeSize, image)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = ValidateRootVolumeType(simpleConfig.RootVolumeType, simpleConfig.RootVolumeIops,
+		simpleConfig.RootVolumeThroughput)
+	if err != nil {
+		return nil, err
+	}
+	if simpleConfig.RootVolumeType != "" && GetRootEbsVolume(image) == nil {
+		return nil, fmt.Errorf("Image %s has no EBS root volume whose type can be changed", aws.StringValue(image.ImageId))
+	}
+
+	err = ValidateCpuCreditsForInstanceType(simpleConfig.CpuCredits, instanceTypeInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ValidateImds(simpleConfig.Imds)
 	if err != nil {
 		return nil, err
 	}
-	if defaultInstanceType != nil {
-		simpleConfig.InstanceType = *defaultInstanceType.InstanceType
+	if simpleConfig.Imds == ImdsDisabled && simpleConfig.IamInstanceProfile != "" && !simpleConfig.Force {
+		return nil, errors.New("An IAM instance profile can't get credentials with the metadata service disabled. " +
+			"Use force to launch anyway")
+	}
+
+	detailedConfig := config.DetailedInfo{
+		Image:            image,
+		Vpc:              vpc,
+		Subnet:           subnet,
+		InstanceTypeInfo: instanceTypeInfo,
+		SecurityGroups:   securityGroups,
+		TagSpecs:         tagSpecs,
+	}
+
+	return &detailedConfig, nil
+}
+
+// Get a RunInstanceInput given a structured config
+func getRunInstanceInput(simpleConfig *config.SimpleInfo, detailedConfig *config.DetailedInfo) *ec2.RunInstancesInput {
+	dataConfig := createRequestInstanceConfig(simpleConfig, detailedConfig)
+	input := &ec2.RunInstancesInput{
+		MaxCount:                          aws.Int64(GetInstanceCount(simpleConfig)),
+		MinCount:                          aws.Int64(GetInstanceCount(simpleConfig)),
+		LaunchTemplate:                    dataConfig.LaunchTemplate,
+		ImageId:                           dataConfig.ImageId,
+		InstanceType:                      dataConfig.InstanceType,
+		SubnetId:                          dataConfig.SubnetId,
+		SecurityGroupIds:                  dataConfig.SecurityGroupIds,
+		IamInstanceProfile:                dataConfig.IamInstanceProfile,
+		KeyName:                           dataConfig.KeyName,
+		BlockDeviceMappings:               dataConfig.BlockDeviceMappings,
+		InstanceInitiatedShutdownBehavior: dataConfig.InstanceInitiatedShutdownBehavior,
+		UserData:                          dataConfig.UserData,
+		PrivateIpAddress:                  dataConfig.PrivateIpAddress,
+		CapacityReservationSpecification:  dataConfig.CapacityReservationSpecification,
+		PrivateDnsNameOptions:             dataConfig.PrivateDnsNameOptions,
+		CreditSpecification:               dataConfig.CreditSpecification,
+		Placement:                         dataConfig.Placement,
+		MetadataOptions:                   dataConfig.MetadataOptions,
+	}
+
+	/*
+		A carrier IP can only be associated, and a public IP only be turned off regardless of the subnet, through a
+		network interface, which then holds the network configuration
+	*/
+	if dataConfig.AssociateCarrierIpAddress != nil || dataConfig.AssociatePublicIpAddress != nil {
+		input.NetworkInterfaces = []*ec2.InstanceNetworkInterfaceSpecification{
+			{
+				AssociateCarrierIpAddress: dataConfig.AssociateCarrierIpAddress,
+				AssociatePublicIpAddress:  dataConfig.AssociatePublicIpAddress,
+				DeviceIndex:               aws.Int64(0),
+				Groups:                    dataConfig.SecurityGroupIds,
+				SubnetId:                  dataConfig.SubnetId,
+				PrivateIpAddress:          dataConfig.PrivateIpAddress,
+			},
+		}
+		input.SubnetId = nil
+		input.SecurityGroupIds = nil
+		input.PrivateIpAddress = nil
+	}
+
+	return input
+}
+
+/*
+Get the config to launch with in non-interactive mode. The saved config is read into simpleConfig from the
+config file, unless forceDefaultConfig is set or the config file can't be read, in which case a config is
+generated with system defaults for the instance type, if not empty. isSaved tells if the saved config is used.
+*/
+func (h *EC2Helper) GetSavedOrDefaultConfig(simpleConfig *config.SimpleInfo, configFileName *string,
+	forceDefaultConfig bool, instanceType string) (resultConfig *config.SimpleInfo, isSaved bool, err error) {
+	if !forceDefaultConfig {
+		err = config.ReadConfig(simpleConfig, configFileName)
+		if !cli.ShowError(err, "Default config file not loaded; using system defaults instead") {
+			return simpleConfig, true, nil
+		}
+	}
+
+	resultConfig, err = h.GetDefaultSimpleConfig(instanceType)
+	return resultConfig, false, err
+}
+
+/*
+Tell if an error is caused by a resource that doesn't exist, either reported by AWS
+or found missing by simple-ec2
+*/
+func IsNotFoundError(err error) bool {
+	var notFoundErr *NotFoundError
+	if errors.As(err, &notFoundErr) {
+		return true
+	}
+	aerr, ok := err.(awserr.Error)
+	return ok && strings.HasSuffix(aerr.Code(), ".NotFound")
+}
+
+/*
+Suggest ignoring the saved config in a ParseConfig error, when the error is caused by a resource that
+doesn't exist. The saved config may refer to resources deleted since it was saved.
+*/
+func AddForceDefaultConfigHint(err error) error {
+	if IsNotFoundError(err) {
+		return fmt.Errorf("%w. The saved config may refer to deleted resources, "+
+			"use --force-default-config to ignore it", err)
+	}
+
+	return err
+}
+
+/*
+Get the default string config. The instance type defaults to a free-tier eligible type when empty, and the
+default image is picked for the architectures of the instance type, such as arm64 for Graviton instance types.
+*/
+func (h *EC2Helper) GetDefaultSimpleConfig(instanceType string) (*config.SimpleInfo, error) {
+	simpleConfig := config.NewSimpleInfo()
+	simpleConfig.Region = *h.Sess.Config.Region
+
+	// get info about the instance type
+	simpleConfig.InstanceType = instanceType
+	if instanceType == "" {
+		simpleConfig.InstanceType = "t2.micro"
+		defaultInstanceType, err := h.GetDefaultFreeTierInstanceType()
+		if err != nil {
+			return nil, err
+		}
+		if defaultInstanceType != nil {
+			simpleConfig.InstanceType = *defaultInstanceType.InstanceType
+		}
 	}
 
 	instanceTypeInfo, err := h.GetInstanceType(simpleConfig.InstanceType)
@@ -1098,10 +2278,9 @@ func (h *EC2Helper) GetDefaultSimpleConfig() (*config.SimpleInfo, error) {
 		return nil, err
 	}
 
-	// Use instance-store if supported
-	rootDeviceType := "ebs"
-	if *instanceTypeInfo.InstanceStorageSupported {
-		rootDeviceType = "instance-store"
+	rootDeviceType, err := h.GetRootDeviceType(instanceTypeInfo)
+	if err != nil {
+		return nil, err
 	}
 
 	image, err := h.GetDefaultImage(&rootDeviceType, instanceTypeInfo.ProcessorInfo.SupportedArchitectures)
@@ -1111,7 +2290,7 @@ func (h *EC2Helper) GetDefaultSimpleConfig() (*config.SimpleInfo, error) {
 
 	simpleConfig.ImageId = *image.ImageId
 
-	vpc, err := h.getDefaultVpc()
+	vpc, err := h.GetDefaultVpc()
 	if err != nil {
 		return nil, err
 	}
@@ -1134,8 +2313,7 @@ func (h *EC2Helper) GetDefaultSimpleConfig() (*config.SimpleInfo, error) {
 		simpleConfig.SecurityGroupIds = []string{*defaultSg.GroupId}
 	}
 
-	simpleConfig.CapacityType = "On-Demand"
-
+	// The capacity type is left to the org config and the flags, and defaults to On-Demand once they are applied
 	return simpleConfig, nil
 }
 
@@ -1161,6 +2339,7 @@ func (h *EC2Helper) LaunchInstance(simpleConfig *config.SimpleInfo, detailedConf
 		}
 
 		input.TagSpecifications = detailedConfig.TagSpecs
+		input.ClientToken = aws.String(GetClientToken(simpleConfig))
 
 		resp, err := h.Svc.RunInstances(input)
 		if err != nil {
@@ -1179,112 +2358,528 @@ func (h *EC2Helper) LaunchInstance(simpleConfig *config.SimpleInfo, detailedConf
 	}
 }
 
-func (h *EC2Helper) LaunchSpotInstance(simpleConfig *config.SimpleInfo, detailedConfig *config.DetailedInfo, confirmation bool) error {
+/*
+Check if an instance can be launched with the config, without launching it. A new VPC is not created,
+so the dry run uses the default network configuration in that case.
+Return nil if the launch would succeed, or the error explaining why it would fail.
+*/
+func (h *EC2Helper) DryRunLaunchInstance(simpleConfig *config.SimpleInfo, detailedConfig *config.DetailedInfo) error {
+	input := getRunInstanceInput(simpleConfig, detailedConfig)
+	input.TagSpecifications = detailedConfig.TagSpecs
+	input.DryRun = aws.Bool(true)
+	if simpleConfig.NewVPC {
+		input.SubnetId = nil
+		input.SecurityGroupIds = nil
+	}
+
+	_, err := h.Svc.RunInstances(input)
+	if isDryRunPassed(err) {
+		return nil
+	}
+
+	return err
+}
+
+/*
+Check if a Spot instance can be launched with the config, without launching it. The creation of the launch template
+and of the fleet are both dry run. The launch template isn't created, so the fleet can't find it, which means the
+fleet would be created otherwise. A new VPC is not created, so the default network configuration is used in that case.
+Return nil if the launch would succeed, or the error explaining why it would fail.
+*/
+func (h *EC2Helper) DryRunLaunchSpotInstance(simpleConfig *config.SimpleInfo,
+	detailedConfig *config.DetailedInfo) error {
+	templateInput := getLaunchTemplateInput(simpleConfig, detailedConfig)
+	templateInput.DryRun = aws.Bool(true)
+	if simpleConfig.NewVPC {
+		templateInput.LaunchTemplateData.NetworkInterfaces[0].SubnetId = nil
+		templateInput.LaunchTemplateData.NetworkInterfaces[0].Groups = nil
+	}
+
+	_, err := h.Svc.CreateLaunchTemplate(templateInput)
+	if err != nil && !isDryRunPassed(err) {
+		return err
+	}
+
+	fleetInput := getFleetInput(&ec2.FleetLaunchTemplateSpecificationRequest{
+		LaunchTemplateName: templateInput.LaunchTemplateName,
+		Version:            aws.String("$Latest"),
+	}, nil, GetInstanceCount(simpleConfig), uuid.New().String())
+	fleetInput.DryRun = aws.Bool(true)
+
+	_, err = h.Svc.CreateFleet(fleetInput)
+	if err != nil && !isDryRunPassed(err) && !isLaunchTemplateNotFoundError(err) {
+		return err
+	}
+
+	return nil
+}
+
+/*
+Get the AWS CLI commands equivalent to a launch, without launching. A Spot launch creates a launch template and an
+instant fleet using it, so both commands are returned. A new VPC is created with a CloudFormation stack, which has
+no equivalent command, so it isn't supported.
+*/
+func GetLaunchCliCommands(simpleConfig *config.SimpleInfo, detailedConfig *config.DetailedInfo) ([]string, error) {
+	if simpleConfig.NewVPC {
+		return nil, errors.New("The equivalent AWS CLI commands can't be shown for a launch creating a new VPC")
+	}
+
+	if simpleConfig.CapacityType == config.CapacityTypeSpot {
+		templateInput := getLaunchTemplateInput(simpleConfig, detailedConfig)
+		fleetInput := getFleetInput(&ec2.FleetLaunchTemplateSpecificationRequest{
+			LaunchTemplateName: templateInput.LaunchTemplateName,
+			Version:            aws.String("$Latest"),
+		}, nil, GetInstanceCount(simpleConfig), GetClientToken(simpleConfig))
+
+		templateCommand, err := output.RenderCliCommand("ec2", "create-launch-template", templateInput)
+		if err != nil {
+			return nil, err
+		}
+		fleetCommand, err := output.RenderCliCommand("ec2", "create-fleet", fleetInput)
+		if err != nil {
+			return nil, err
+		}
+		return []string{templateCommand, fleetCommand}, nil
+	}
+
+	input := getRunInstanceInput(simpleConfig, detailedConfig)
+	input.TagSpecifications = detailedConfig.TagSpecs
+	input.ClientToken = aws.String(GetClientToken(simpleConfig))
+
+	// The CLI takes the instance count as a single option, and encodes the user data itself
+	instanceCount := GetInstanceCount(simpleConfig)
+	input.MinCount = nil
+	input.MaxCount = nil
+	if input.UserData != nil {
+		userData, err := base64.StdEncoding.DecodeString(*input.UserData)
+		if err != nil {
+			return nil, err
+		}
+		input.UserData = aws.String(string(userData))
+	}
+
+	command, err := output.RenderCliCommand("ec2", "run-instances", input)
+	if err != nil {
+		return nil, err
+	}
+	return []string{fmt.Sprintf("%s --count %d", command, instanceCount)}, nil
+}
+
+// Tell if a dry run succeeded, which AWS reports with the DryRunOperation error
+func isDryRunPassed(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == "DryRunOperation"
+}
+
+// Tell if an error is caused by a launch template that doesn't exist
+func isLaunchTemplateNotFoundError(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && (aerr.Code() == "InvalidLaunchTemplateName.NotFoundException" ||
+		aerr.Code() == "InvalidLaunchTemplateId.NotFound")
+}
+
+func (h *EC2Helper) LaunchSpotInstance(simpleConfig *config.SimpleInfo, detailedConfig *config.DetailedInfo,
+	confirmation bool) ([]string, error) {
 	var err error
+	var fleet *ec2.CreateFleetOutput
 	if confirmation {
 		fmt.Println("Options confirmed! Launching spot instance...")
+		clientToken := GetClientToken(simpleConfig)
 		if simpleConfig.LaunchTemplateId != "" {
-			_, err = h.LaunchFleet(aws.String(simpleConfig.LaunchTemplateId))
+			fleet, err = h.LaunchFleet(aws.String(simpleConfig.LaunchTemplateId), GetInstanceCount(simpleConfig),
+				clientToken)
 		} else {
 			// Create new stack, if specified.
 			if simpleConfig.NewVPC {
 				err := h.createNetworkConfiguration(simpleConfig, nil)
 				if err != nil {
-					return err
+					return nil, err
 				}
 			}
 
-			template, err := h.CreateLaunchTemplate(simpleConfig, detailedConfig)
+			var template *ec2.LaunchTemplate
+			template, err = h.CreateLaunchTemplate(simpleConfig, detailedConfig)
 			if err != nil {
 				if aerr, ok := err.(awserr.Error); ok {
 					fmt.Println(aerr.Error())
 				} else {
 					fmt.Println(err.Error())
 				}
-				return err
+				return nil, err
 			}
-			_, err = h.LaunchFleet(template.LaunchTemplateId)
-			err = h.DeleteLaunchTemplate(template.LaunchTemplateId)
+			var fleetErr error
+			fleet, fleetErr = h.LaunchFleet(template.LaunchTemplateId, GetInstanceCount(simpleConfig), clientToken)
+			if simpleConfig.SpotSizeFallback && IsInsufficientCapacityError(fleetErr) {
+				fleet, fleetErr = h.launchFleetWithSizeFallback(template.LaunchTemplateId,
+					simpleConfig.InstanceType, GetInstanceCount(simpleConfig), clientToken, fleetErr)
+			}
+
+			// The fleet error tells why no instance was launched, so it takes precedence over a failed deletion
+			deleteErr := h.DeleteLaunchTemplate(template.LaunchTemplateId)
+			if fleetErr != nil {
+				return nil, fleetErr
+			}
+			err = deleteErr
 		}
 	} else {
 		// Abort
-		return errors.New("Options not confirmed")
+		return nil, errors.New("Options not confirmed")
 	}
 
-	return err
+	launchedInstances := []string{}
+	if fleet != nil {
+		for _, instance := range fleet.Instances {
+			launchedInstances = append(launchedInstances, aws.StringValueSlice(instance.InstanceIds)...)
+		}
+	}
+
+	return launchedInstances, err
 }
 
-// Create a new stack and update simpleConfig for config saving
-func (h *EC2Helper) createNetworkConfiguration(simpleConfig *config.SimpleInfo,
-	input *ec2.RunInstancesInput) error {
-	// Get all available azs for later use
-	availabilityZones, err := h.GetAvailableAvailabilityZones()
+/*
+Launch the Spot instance with other sizes of the instance type family, since there is no Spot capacity for
+the instance type. The sizes closest to the instance type are tried first, and the last error is returned
+if none of them has capacity either.
+*/
+func (h *EC2Helper) launchFleetWithSizeFallback(templateId *string, instanceType string, instanceCount int64,
+	clientToken string, launchErr error) (*ec2.CreateFleetOutput, error) {
+	instanceTypes, err := h.GetInstanceTypesInRegion()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Retrieve resources from the stack
-	c := cfn.New(h.Sess)
-	vpcId, subnetIds, _, _, err := c.CreateStackAndGetResources(availabilityZones, nil,
-		cfn.SimpleEc2CloudformationTemplate)
-	if err != nil {
-		return err
+	for _, fallbackInstanceType := range GetSpotFallbackInstanceTypes(instanceType, instanceTypes,
+		MaxSpotSizeFallbacks) {
+		fmt.Printf("No Spot capacity for %s, trying %s...\n", instanceType, fallbackInstanceType)
+		fleet, err := h.launchFleet(templateId, aws.String(fallbackInstanceType), instanceCount,
+			GetFallbackClientToken(clientToken, fallbackInstanceType))
+		if !IsInsufficientCapacityError(err) {
+			return fleet, err
+		}
+		instanceType = fallbackInstanceType
+		launchErr = err
 	}
 
-	// Find the subnetId with the correct availability zone
-	var selectedSubnetId *string
-	for _, subnetId := range subnetIds {
-		subnet, err := h.GetSubnetById(subnetId)
-		if err != nil {
-			return err
+	return nil, launchErr
+}
+
+/*
+Get the client token of a launch, which makes retrying the launch idempotent. The token of the config is used if
+specified, so that separate invocations are idempotent too. Otherwise, a new token is generated for each launch,
+which must be reused by all retries of the launch.
+*/
+func GetClientToken(simpleConfig *config.SimpleInfo) string {
+	if simpleConfig.ClientToken != "" {
+		return simpleConfig.ClientToken
+	}
+
+	return uuid.New().String()
+}
+
+/*
+Get the client token of a Spot size fallback. A client token can't be reused with another instance type,
+so each fallback instance type gets its own token, derived from the token of the launch to stay idempotent.
+*/
+func GetFallbackClientToken(clientToken, instanceType string) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(clientToken+"/"+instanceType)).String()
+}
+
+/*
+Get the instance types to try, in order, when there is no Spot capacity for the instance type.
+They are the sizes of the same family closest to the instance type, alternating between the next size up
+and the next size down. At most maxFallbacks instance types are returned.
+*/
+func GetSpotFallbackInstanceTypes(instanceType string, instanceTypes []*ec2.InstanceTypeInfo,
+	maxFallbacks int) []string {
+	family := strings.Split(instanceType, ".")[0]
+	familyTypes := []*ec2.InstanceTypeInfo{}
+	for _, instanceTypeInfo := range instanceTypes {
+		if strings.Split(aws.StringValue(instanceTypeInfo.InstanceType), ".")[0] == family {
+			familyTypes = append(familyTypes, instanceTypeInfo)
 		}
+	}
+	sort.SliceStable(familyTypes, func(i, j int) bool {
+		return isSmallerInstanceType(familyTypes[i], familyTypes[j])
+	})
 
-		if *subnet.AvailabilityZone == simpleConfig.SubnetId {
-			selectedSubnetId = subnet.SubnetId
+	index := -1
+	for i, instanceTypeInfo := range familyTypes {
+		if aws.StringValue(instanceTypeInfo.InstanceType) == instanceType {
+			index = i
 			break
 		}
 	}
-	if selectedSubnetId == nil {
-		return errors.New("No subnet with the selected availability zone found")
-	}
-
-	if input != nil {
-		input.SubnetId = selectedSubnetId
+	if index < 0 {
+		return nil
 	}
 
-	/*
-		Get the security group.
-		If the users choose "all", simply add all existing security groups to the selection.
-		If the users choose "new", create a new security group for SSH and add it to the selection.
-		Otherwise, report an error.
-	*/
-	securityGroupPlaceholder := simpleConfig.SecurityGroupIds[0]
-	selectedSecurityGroupIds := []string{}
-
-	if securityGroupPlaceholder == cli.ResponseAll {
-		securityGroups, err := h.GetSecurityGroupsByVpc(*vpcId)
-		if err != nil {
-			return err
+	fallbackInstanceTypes := []string{}
+	for offset := 1; len(fallbackInstanceTypes) < maxFallbacks; offset++ {
+		larger, smaller := index+offset, index-offset
+		if larger >= len(familyTypes) && smaller < 0 {
+			break
 		}
-
-		if securityGroups != nil {
-			for _, group := range securityGroups {
-				selectedSecurityGroupIds = append(selectedSecurityGroupIds, *group.GroupId)
-			}
+		if larger < len(familyTypes) {
+			fallbackInstanceTypes = append(fallbackInstanceTypes, *familyTypes[larger].InstanceType)
 		}
-	} else if securityGroupPlaceholder == cli.ResponseNew {
-		groupId, err := h.CreateSecurityGroupForSsh(*vpcId)
-		if err != nil {
-			return err
+		if smaller >= 0 && len(fallbackInstanceTypes) < maxFallbacks {
+			fallbackInstanceTypes = append(fallbackInstanceTypes, *familyTypes[smaller].InstanceType)
 		}
-
-		selectedSecurityGroupIds = append(selectedSecurityGroupIds, *groupId)
-	} else {
-		return errors.New("Unknown security group placeholder")
 	}
 
-	if len(selectedSecurityGroupIds) <= 0 {
-		return errors.New("No security group available for stack")
+	return fallbackInstanceTypes
+}
+
+// Compare the sizes of two instance types by vCPUs, then by memory
+func isSmallerInstanceType(a, b *ec2.InstanceTypeInfo) bool {
+	var vcpusA, vcpusB, memoryA, memoryB int64
+	if a.VCpuInfo != nil {
+		vcpusA = aws.Int64Value(a.VCpuInfo.DefaultVCpus)
+	}
+	if b.VCpuInfo != nil {
+		vcpusB = aws.Int64Value(b.VCpuInfo.DefaultVCpus)
+	}
+	if vcpusA != vcpusB {
+		return vcpusA < vcpusB
+	}
+	if a.MemoryInfo != nil {
+		memoryA = aws.Int64Value(a.MemoryInfo.SizeInMiB)
+	}
+	if b.MemoryInfo != nil {
+		memoryB = aws.Int64Value(b.MemoryInfo.SizeInMiB)
+	}
+
+	return memoryA < memoryB
+}
+
+// Tell if an error is caused by a lack of capacity for the instance type
+func IsInsufficientCapacityError(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == "InsufficientInstanceCapacity"
+}
+
+/*
+Create a network interface in the subnet and attach it to the instance as its second interface.
+The instance is waited for first, since interfaces can only be attached to running or stopped instances.
+The created interface is tagged like other resources created by simple-ec2, so it can be cleaned up.
+*/
+func (h *EC2Helper) AttachSecondaryNetworkInterface(instanceId, subnetId string,
+	securityGroupIds []string) (*string, error) {
+	createInput := &ec2.CreateNetworkInterfaceInput{
+		SubnetId: aws.String(subnetId),
+		TagSpecifications: []*ec2.TagSpecification{
+			{
+				ResourceType: aws.String(ec2.ResourceTypeNetworkInterface),
+				Tags:         getSimpleEc2Tags(),
+			},
+		},
+	}
+	if len(securityGroupIds) > 0 {
+		createInput.Groups = aws.StringSlice(securityGroupIds)
+	}
+
+	createOutput, err := h.Svc.CreateNetworkInterface(createInput)
+	if err != nil {
+		return nil, err
+	}
+	networkInterfaceId := createOutput.NetworkInterface.NetworkInterfaceId
+
+	err = h.Svc.WaitUntilInstanceRunning(&ec2.DescribeInstancesInput{
+		InstanceIds: aws.StringSlice([]string{instanceId}),
+	})
+	if err != nil {
+		return networkInterfaceId, err
+	}
+
+	_, err = h.Svc.AttachNetworkInterface(&ec2.AttachNetworkInterfaceInput{
+		DeviceIndex:        aws.Int64(1),
+		InstanceId:         aws.String(instanceId),
+		NetworkInterfaceId: networkInterfaceId,
+	})
+	if err != nil {
+		return networkInterfaceId, err
+	}
+
+	fmt.Printf("Attached network interface %s to instance %s\n", *networkInterfaceId, instanceId)
+	return networkInterfaceId, nil
+}
+
+// Wait for the instances to be running and get them, with the addresses assigned once they run
+func (h *EC2Helper) GetRunningInstances(instanceIds []string) ([]*ec2.Instance, error) {
+	err := h.Svc.WaitUntilInstanceRunning(&ec2.DescribeInstancesInput{
+		InstanceIds: aws.StringSlice(instanceIds),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	instances := []*ec2.Instance{}
+	for _, instanceId := range instanceIds {
+		instance, err := h.GetInstanceById(instanceId)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, instance)
+	}
+
+	return instances, nil
+}
+
+/*
+Run a command on the instances once they accept SSH connections, and terminate them afterward if specified.
+The instances are terminated even if the command fails, so that no one-shot worker is left running.
+*/
+func (h *EC2Helper) RunCommandOnInstances(instanceIds []string, command string, terminateAfter bool,
+	runner CommandRunner) error {
+	err := h.runCommandOnInstances(instanceIds, command, runner)
+	if terminateAfter {
+		terminateErr := h.TerminateInstances(instanceIds)
+		if err == nil {
+			err = terminateErr
+		}
+	}
+
+	return err
+}
+
+// Wait for the instances to accept SSH connections and run a command on each of them
+func (h *EC2Helper) runCommandOnInstances(instanceIds []string, command string, runner CommandRunner) error {
+	instances, err := h.GetRunningInstances(instanceIds)
+	if err != nil {
+		return err
+	}
+
+	for _, instance := range instances {
+		instanceId := aws.StringValue(instance.InstanceId)
+		fmt.Printf("Waiting for instance %s to accept SSH connections. This can take a few minutes\n", instanceId)
+		err = runner.WaitForSSH(instance)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Running \"%s\" on instance %s\n", command, instanceId)
+		err = runner.RunCommand(instance, command)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+Get the console output of an instance, decoded from base64. The console output isn't available right after
+the instance starts, so fetching it is retried until it's not empty.
+*/
+func (h *EC2Helper) GetConsoleOutput(instanceId string) (string, error) {
+	for i := 0; i < ConsoleOutputRetries; i++ {
+		if i > 0 {
+			time.Sleep(ConsoleOutputRetryInterval)
+		}
+
+		consoleOutput, err := h.Svc.GetConsoleOutput(&ec2.GetConsoleOutputInput{
+			InstanceId: aws.String(instanceId),
+		})
+		if err != nil {
+			return "", err
+		}
+		if aws.StringValue(consoleOutput.Output) == "" {
+			continue
+		}
+
+		decodedOutput, err := base64.StdEncoding.DecodeString(*consoleOutput.Output)
+		if err != nil {
+			return "", err
+		}
+		return string(decodedOutput), nil
+	}
+
+	return "", errors.New("The console output of instance " + instanceId + " is not available yet")
+}
+
+// Create a new stack and update simpleConfig for config saving
+func (h *EC2Helper) createNetworkConfiguration(simpleConfig *config.SimpleInfo,
+	input *ec2.RunInstancesInput) error {
+	// Get all available azs for later use. The subnets of the new VPC are only created in availability zones
+	availabilityZones, err := h.GetAvailableAvailabilityZones()
+	if err != nil {
+		return err
+	}
+	availabilityZones = FilterZonesByType(availabilityZones, ZoneTypeAvailabilityZone)
+	if len(availabilityZones) <= 0 {
+		return errors.New("A new VPC can only be created with availability zones, not Local or Wavelength Zones")
+	}
+
+	// Bound the stack creation with the timeout, if specified
+	ctx := context.Background()
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+
+	// Retrieve resources from the stack
+	c := cfn.New(h.Sess)
+	vpcId, subnetIds, _, _, err := c.CreateStackAndGetResources(ctx, availabilityZones, nil,
+		cfn.SimpleEc2CloudformationTemplate)
+	if err != nil {
+		return err
+	}
+
+	// Find the subnetId with the correct availability zone
+	var selectedSubnetId *string
+	for _, subnetId := range subnetIds {
+		subnet, err := h.GetSubnetById(subnetId)
+		if err != nil {
+			return err
+		}
+
+		if *subnet.AvailabilityZone == simpleConfig.SubnetId {
+			selectedSubnetId = subnet.SubnetId
+			break
+		}
+	}
+	if selectedSubnetId == nil {
+		return errors.New("No subnet with the selected availability zone found")
+	}
+
+	if input != nil {
+		input.SubnetId = selectedSubnetId
+	}
+
+	/*
+		Get the security group.
+		If the users choose "all", simply add all existing security groups to the selection.
+		If the users choose "new", create a new security group for SSH and add it to the selection.
+		Otherwise, report an error.
+	*/
+	securityGroupPlaceholder := simpleConfig.SecurityGroupIds[0]
+	selectedSecurityGroupIds := []string{}
+
+	if securityGroupPlaceholder == cli.ResponseAll {
+		securityGroups, err := h.GetSecurityGroupsByVpc(*vpcId)
+		if err != nil {
+			return err
+		}
+
+		if securityGroups != nil {
+			for _, group := range securityGroups {
+				selectedSecurityGroupIds = append(selectedSecurityGroupIds, *group.GroupId)
+			}
+		}
+	} else if securityGroupPlaceholder == cli.ResponseNew {
+		groupId, err := h.CreateSecurityGroupForSsh(*vpcId, config.GetSshPort(simpleConfig), "")
+		if err != nil {
+			return err
+		}
+
+		selectedSecurityGroupIds = append(selectedSecurityGroupIds, *groupId)
+	} else {
+		return errors.New("Unknown security group placeholder")
+	}
+
+	if len(selectedSecurityGroupIds) <= 0 {
+		return errors.New("No security group available for stack")
 	}
 
 	if input != nil {
@@ -1301,14 +2896,9 @@ func (h *EC2Helper) createNetworkConfiguration(simpleConfig *config.SimpleInfo,
 
 // Terminate the instances based on ids
 func (h *EC2Helper) TerminateInstances(instanceIds []string) error {
-	// Get instance id
-	input := &ec2.TerminateInstancesInput{
-		InstanceIds: aws.StringSlice(instanceIds),
-	}
-
 	fmt.Println("Terminating instances")
 
-	_, err := h.Svc.TerminateInstances(input)
+	_, err := h.TerminateInstancesWithStates(instanceIds)
 	if err != nil {
 		return err
 	}
@@ -1318,6 +2908,41 @@ func (h *EC2Helper) TerminateInstances(instanceIds []string) error {
 	return nil
 }
 
+// Start the stopped instances, without waiting for them to run
+func (h *EC2Helper) StartInstances(instanceIds []string) error {
+	fmt.Println("Starting instances")
+
+	input := &ec2.StartInstancesInput{
+		InstanceIds: aws.StringSlice(instanceIds),
+	}
+
+	_, err := h.Svc.StartInstances(input)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(fmt.Sprintf("Instances %s started successfully", instanceIds))
+
+	return nil
+}
+
+/*
+Terminate the instances without printing progress, and return the state changes of the instances,
+which include their states before termination.
+*/
+func (h *EC2Helper) TerminateInstancesWithStates(instanceIds []string) ([]*ec2.InstanceStateChange, error) {
+	input := &ec2.TerminateInstancesInput{
+		InstanceIds: aws.StringSlice(instanceIds),
+	}
+
+	output, err := h.Svc.TerminateInstances(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return output.TerminatingInstances, nil
+}
+
 // Get the name tag of the resource
 func GetTagName(tags []*ec2.Tag) *string {
 	for _, tag := range tags {
@@ -1342,6 +2967,55 @@ func getSimpleEc2Tags() []*ec2.Tag {
 	return simpleEc2Tags
 }
 
+/*
+Get the deprecation status of an image at a time, such as "deprecated since 2023-10-03" or
+"to be deprecated on 2023-10-03". The status is empty for images not deprecated within the warning period.
+*/
+func GetImageDeprecationStatus(image *ec2.Image, now time.Time) string {
+	if image == nil || image.DeprecationTime == nil {
+		return ""
+	}
+
+	deprecationTime, err := time.Parse(time.RFC3339, *image.DeprecationTime)
+	if err != nil {
+		return ""
+	}
+
+	date := deprecationTime.Format("2006-01-02")
+	if !deprecationTime.After(now) {
+		return "deprecated since " + date
+	}
+	if deprecationTime.Sub(now) <= ImageDeprecationWarningPeriod {
+		return "to be deprecated on " + date
+	}
+
+	return ""
+}
+
+/*
+Validate that an image was created within the max age at a time. A max age of 0 means no limit.
+Images without a valid creation date can't be validated, so they are rejected when there is a limit.
+*/
+func ValidateImageFreshness(image *ec2.Image, maxAge time.Duration, now time.Time) error {
+	if maxAge <= 0 || image == nil {
+		return nil
+	}
+
+	maxAgeDays := int(maxAge / (24 * time.Hour))
+	creationTime, err := time.Parse(time.RFC3339, aws.StringValue(image.CreationDate))
+	if err != nil {
+		return fmt.Errorf("The creation date of image %s is unknown, so it can't be checked to be newer than %d days",
+			aws.StringValue(image.ImageId), maxAgeDays)
+	}
+	if now.Sub(creationTime) > maxAge {
+		return fmt.Errorf("The newest default image %s was created on %s, more than %d days ago. "+
+			"Specify a recent image with the --image-id flag", aws.StringValue(image.ImageId),
+			creationTime.Format("2006-01-02"), maxAgeDays)
+	}
+
+	return nil
+}
+
 // Validate an image id. Used as a function interface to validate question input
 func ValidateImageId(h *EC2Helper, imageId string) bool {
 	image, _ := h.GetImageById(imageId)
@@ -1357,12 +3031,8 @@ func ValidateFilepath(h *EC2Helper, userFilePath string) bool {
 // Validate user's tag input. Used as a function interface to validate question input
 func ValidateTags(h *EC2Helper, userTags string) bool {
 	//tag1|val1,tag2|val2
-	for _, rawTag := range strings.Split(userTags, ",") { //[tag1|val1, tag2|val2]
-		if len(strings.Split(rawTag, "|")) != 2 { //[tag1,val1]
-			return false
-		}
-	}
-	return true
+	_, _, err := tag.ParseTags(strings.Split(userTags, ","), tag.InteractiveSeparator)
+	return err == nil
 }
 
 // ValidateInteger checks if a given string is an integer
@@ -1374,6 +3044,413 @@ func ValidateInteger(h *EC2Helper, intString string) bool {
 	return true
 }
 
+// Validate that a string is an integer of at least 1
+func ValidatePositiveInteger(h *EC2Helper, intString string) bool {
+	value, err := strconv.Atoi(intString)
+	return err == nil && value >= 1
+}
+
+/*
+Validate that a private IP address falls within the CIDR block of the subnet.
+Return an error if the address is malformed or outside the subnet.
+*/
+func ValidatePrivateIpInSubnet(privateIp string, subnet *ec2.Subnet) error {
+	ip := net.ParseIP(privateIp)
+	if ip == nil {
+		return errors.New("Private IP address " + privateIp + " is invalid")
+	}
+
+	_, cidr, err := net.ParseCIDR(*subnet.CidrBlock)
+	if err != nil {
+		return err
+	}
+	if !cidr.Contains(ip) {
+		return errors.New("Private IP address " + privateIp + " is not in the CIDR block " +
+			*subnet.CidrBlock + " of subnet " + *subnet.SubnetId)
+	}
+
+	return nil
+}
+
+/*
+Get the boot mode of an image. Images without a boot mode use legacy BIOS,
+except for Arm images, which only support UEFI.
+*/
+func GetImageBootMode(image *ec2.Image) string {
+	if aws.StringValue(image.BootMode) != "" {
+		return *image.BootMode
+	}
+	if strings.HasPrefix(aws.StringValue(image.Architecture), ec2.ArchitectureValuesArm64) {
+		return ec2.BootModeValuesUefi
+	}
+
+	return ec2.BootModeValuesLegacyBios
+}
+
+/*
+Validate the requested boot mode and NitroTPM against the image and instance type. EC2 takes both from
+the image at launch, so the image must have the requested boot mode, and NitroTPM requires an image with
+TPM 2.0 support that boots with UEFI. Empty boot mode means no boot mode is requested.
+*/
+func ValidateBootMode(bootMode string, nitroTpm bool, image *ec2.Image, instanceTypeInfo *ec2.InstanceTypeInfo) error {
+	if bootMode != "" && bootMode != ec2.BootModeValuesUefi && bootMode != ec2.BootModeValuesLegacyBios &&
+		bootMode != BootModeUefiPreferred {
+		return fmt.Errorf("Boot mode must be \"%s\", \"%s\" or \"%s\"", ec2.BootModeValuesUefi,
+			ec2.BootModeValuesLegacyBios, BootModeUefiPreferred)
+	}
+
+	// An image preferring UEFI boots with UEFI if the instance type supports it, and legacy BIOS otherwise
+	supportedBootModes := aws.StringValueSlice(instanceTypeInfo.SupportedBootModes)
+	imageBootMode := GetImageBootMode(image)
+	effectiveBootMode := imageBootMode
+	if imageBootMode == BootModeUefiPreferred {
+		effectiveBootMode = ec2.BootModeValuesLegacyBios
+		if slices.Contains(supportedBootModes, ec2.BootModeValuesUefi) {
+			effectiveBootMode = ec2.BootModeValuesUefi
+		}
+	}
+
+	if bootMode == BootModeUefiPreferred && imageBootMode != BootModeUefiPreferred {
+		return fmt.Errorf("Image %s uses boot mode %s, not %s", aws.StringValue(image.ImageId), imageBootMode,
+			bootMode)
+	}
+	if bootMode != "" && bootMode != BootModeUefiPreferred && bootMode != effectiveBootMode {
+		return fmt.Errorf("Instance type %s with image %s boots with %s, not %s",
+			aws.StringValue(instanceTypeInfo.InstanceType), aws.StringValue(image.ImageId), effectiveBootMode, bootMode)
+	}
+
+	if nitroTpm {
+		if aws.StringValue(image.TpmSupport) != ec2.TpmSupportValuesV20 {
+			return fmt.Errorf("Image %s doesn't support NitroTPM", aws.StringValue(image.ImageId))
+		}
+		if effectiveBootMode != ec2.BootModeValuesUefi {
+			return errors.New("NitroTPM requires UEFI boot mode")
+		}
+	}
+
+	if (bootMode != "" || nitroTpm) && instanceTypeInfo.SupportedBootModes != nil &&
+		!slices.Contains(supportedBootModes, effectiveBootMode) {
+		return fmt.Errorf("Instance type %s doesn't support boot mode %s",
+			aws.StringValue(instanceTypeInfo.InstanceType), effectiveBootMode)
+	}
+
+	return nil
+}
+
+/*
+Validate that a secondary subnet can hold a network interface for an instance in the primary subnet.
+The subnets must be in the same VPC and availability zone.
+*/
+func ValidateSecondarySubnet(primarySubnet, secondarySubnet *ec2.Subnet) error {
+	if *primarySubnet.VpcId != *secondarySubnet.VpcId {
+		return errors.New("Secondary subnet " + *secondarySubnet.SubnetId + " is not in VPC " + *primarySubnet.VpcId)
+	}
+	if *primarySubnet.AvailabilityZone != *secondarySubnet.AvailabilityZone {
+		return errors.New("Secondary subnet " + *secondarySubnet.SubnetId + " is not in availability zone " +
+			*primarySubnet.AvailabilityZone)
+	}
+
+	return nil
+}
+
+// Get the shutdown behavior for an auto-termination timer action. Instances are terminated by default
+func GetShutdownBehavior(timerAction string) string {
+	if timerAction == ec2.ShutdownBehaviorStop {
+		return ec2.ShutdownBehaviorStop
+	}
+
+	return ec2.ShutdownBehaviorTerminate
+}
+
+/*
+Validate the auto-termination timer action against the image.
+Instances with an instance store root device can't be stopped.
+*/
+func ValidateTimerAction(timerAction string, image *ec2.Image) error {
+	if timerAction != "" && timerAction != ec2.ShutdownBehaviorTerminate && timerAction != ec2.ShutdownBehaviorStop {
+		return fmt.Errorf("Timer action must be \"%s\" or \"%s\"", ec2.ShutdownBehaviorTerminate,
+			ec2.ShutdownBehaviorStop)
+	}
+	if timerAction == ec2.ShutdownBehaviorStop && image.RootDeviceType != nil &&
+		*image.RootDeviceType == ec2.DeviceTypeInstanceStore {
+		return errors.New("Instances launched from instance store-backed image " + *image.ImageId +
+			" can't be stopped when the timer expires")
+	}
+
+	return nil
+}
+
+/*
+Validate the Spot block duration. Blocks last from 1 to 6 hours, in whole hours.
+A duration of 0 means no block is requested.
+*/
+func ValidateSpotBlockDuration(minutes int) error {
+	if minutes < 0 || minutes > 360 || minutes%60 != 0 {
+		return errors.New("Spot block duration must be 60, 120, 180, 240, 300 or 360 minutes")
+	}
+
+	return nil
+}
+
+// Get the number of instances to launch, which is 1 unless specified
+func GetInstanceCount(simpleConfig *config.SimpleInfo) int64 {
+	if simpleConfig.InstanceCount > 1 {
+		return int64(simpleConfig.InstanceCount)
+	}
+
+	return 1
+}
+
+/*
+Validate the number of instances to launch, which must be at least 1. A private IP address can only be assigned
+to one of the instances, so it can't be used when launching more.
+*/
+func ValidateInstanceCount(instanceCount int, privateIpAddress string) error {
+	if instanceCount < 1 {
+		return errors.New("The instance count must be at least 1")
+	}
+	if instanceCount > 1 && privateIpAddress != "" {
+		return errors.New("A private IP address can only be assigned when launching a single instance")
+	}
+
+	return nil
+}
+
+// Validate the instance name prefix, which can't be combined with a Name tag
+func ValidateInstanceNamePrefix(prefix string, userTags map[string]string) error {
+	if _, found := userTags["Name"]; prefix != "" && found {
+		return errors.New("You can't define both an instance name prefix and a Name tag")
+	}
+
+	return nil
+}
+
+// Validate the availability zone of the subnet, which can be defined by either its name or its id
+func ValidateAvailabilityZone(zoneName, zoneId string) error {
+	if zoneName != "" && zoneId != "" {
+		return errors.New("You can't define both the availability zone name and the availability zone id")
+	}
+
+	return nil
+}
+
+// Validate the name of a security group to be created, following the naming rules of security groups in a VPC
+func ValidateSecurityGroupName(name string) error {
+	if len(name) < 1 || len(name) > 255 {
+		return errors.New("Security group name must be 1 to 255 characters long")
+	}
+	if strings.HasPrefix(name, securityGroupIdPrefix) {
+		return fmt.Errorf("Security group name %s can't start with \"%s\"", name, securityGroupIdPrefix)
+	}
+	if !securityGroupNameRegex.MatchString(name) {
+		return fmt.Errorf("Security group name %s can only contain letters, numbers, spaces and ._-:/()#,@[]+=&;{}!$*",
+			name)
+	}
+
+	return nil
+}
+
+// Validate the client token of a launch, which EC2 limits to 64 ASCII characters
+func ValidateClientToken(clientToken string) error {
+	if len(clientToken) > maxClientTokenLength {
+		return fmt.Errorf("Client token must be at most %d characters long", maxClientTokenLength)
+	}
+	for _, char := range clientToken {
+		if char < ' ' || char > '~' {
+			return errors.New("Client token can only contain printable ASCII characters")
+		}
+	}
+
+	return nil
+}
+
+/*
+Validate the capacity reservation. The preference must be "open" or "none", and it can't be combined
+with an explicit capacity reservation. Empty values mean the AWS default is used.
+*/
+func ValidateCapacityReservation(preference, capacityReservationId string) error {
+	if preference != "" && preference != ec2.CapacityReservationPreferenceOpen &&
+		preference != ec2.CapacityReservationPreferenceNone {
+		return fmt.Errorf("Capacity reservation preference must be \"%s\" or \"%s\"",
+			ec2.CapacityReservationPreferenceOpen, ec2.CapacityReservationPreferenceNone)
+	}
+	if preference != "" && capacityReservationId != "" {
+		return errors.New("You can't define both a capacity reservation preference and a capacity reservation id")
+	}
+
+	return nil
+}
+
+// Validate the private DNS hostname type. An empty value means the hostname type of the subnet is used
+func ValidatePrivateDnsHostnameType(hostnameType string) error {
+	if hostnameType != "" && hostnameType != ec2.HostnameTypeIpName &&
+		hostnameType != ec2.HostnameTypeResourceName {
+		return fmt.Errorf("Private DNS hostname type must be \"%s\" or \"%s\"", ec2.HostnameTypeIpName,
+			ec2.HostnameTypeResourceName)
+	}
+
+	return nil
+}
+
+/*
+Validate the ARN of a host resource group, in which the instance is placed on a Dedicated Host
+by auto-placement. An empty value means the instance isn't launched on a Dedicated Host.
+*/
+func ValidateHostResourceGroupArn(hostResourceGroupArn string) error {
+	if hostResourceGroupArn == "" {
+		return nil
+	}
+
+	resourceArn, err := arn.Parse(hostResourceGroupArn)
+	if err != nil || resourceArn.Service != "resource-groups" || !strings.HasPrefix(resourceArn.Resource, "group/") {
+		return fmt.Errorf("%s is not the ARN of a host resource group, such as "+
+			"arn:aws:resource-groups:us-east-1:123456789012:group/my-hosts", hostResourceGroupArn)
+	}
+
+	return nil
+}
+
+// Validate the affinity of the instance with its Dedicated Host. An empty value means the default affinity is used
+func ValidateAffinity(affinity string) error {
+	if affinity != "" && affinity != ec2.AffinityDefault && affinity != ec2.AffinityHost {
+		return fmt.Errorf("Affinity must be \"%s\" or \"%s\"", ec2.AffinityDefault, ec2.AffinityHost)
+	}
+
+	return nil
+}
+
+// Validate the CPU credit mode. An empty value means the default mode of the instance type is used
+func ValidateCpuCredits(cpuCredits string) error {
+	if cpuCredits != "" && cpuCredits != CpuCreditsStandard && cpuCredits != CpuCreditsUnlimited {
+		return fmt.Errorf("CPU credits must be \"%s\" or \"%s\"", CpuCreditsStandard, CpuCreditsUnlimited)
+	}
+
+	return nil
+}
+
+// Validate the mode of the instance metadata service. An empty mode keeps the default of the image
+func ValidateImds(imds string) error {
+	if imds != "" && !slices.Contains(ImdsModes, imds) {
+		return fmt.Errorf("The metadata service mode must be one of %s", strings.Join(ImdsModes, ", "))
+	}
+
+	return nil
+}
+
+// Get the warning about what stops working without the instance metadata service, or "" for other modes
+func GetImdsWarning(imds string) string {
+	if imds != ImdsDisabled {
+		return ""
+	}
+
+	return "WARNING: The instance metadata service is disabled. The instance can't retrieve the credentials of " +
+		"an IAM role, and boot-time features reading the metadata, such as user data, cloud-init boot scripts " +
+		"and SSH keys, won't work"
+}
+
+// Tell if the instance type is a burstable performance type, such as the T family, which earns CPU credits
+func IsBurstableInstanceType(instanceTypeInfo *ec2.InstanceTypeInfo) bool {
+	if instanceTypeInfo.BurstablePerformanceSupported != nil {
+		return aws.BoolValue(instanceTypeInfo.BurstablePerformanceSupported)
+	}
+
+	return strings.HasPrefix(aws.StringValue(instanceTypeInfo.InstanceType), "t")
+}
+
+// Validate the CPU credit mode, which only applies to burstable performance instance types
+func ValidateCpuCreditsForInstanceType(cpuCredits string, instanceTypeInfo *ec2.InstanceTypeInfo) error {
+	err := ValidateCpuCredits(cpuCredits)
+	if err != nil {
+		return err
+	}
+	if cpuCredits != "" && !IsBurstableInstanceType(instanceTypeInfo) {
+		return fmt.Errorf("CPU credits only apply to burstable performance instance types, not %s",
+			aws.StringValue(instanceTypeInfo.InstanceType))
+	}
+
+	return nil
+}
+
+/*
+Validate that the image can run on the instance type, whose processors only support some architectures.
+The check is skipped when the architectures are unknown.
+*/
+func ValidateImageArchitecture(image *ec2.Image, instanceTypeInfo *ec2.InstanceTypeInfo) error {
+	if image.Architecture == nil || instanceTypeInfo.ProcessorInfo == nil {
+		return nil
+	}
+
+	architectures := aws.StringValueSlice(instanceTypeInfo.ProcessorInfo.SupportedArchitectures)
+	if !slices.Contains(architectures, *image.Architecture) {
+		return fmt.Errorf("Image %s is built for %s, which instance type %s doesn't support. Supported "+
+			"architectures: %s", aws.StringValue(image.ImageId), *image.Architecture,
+			aws.StringValue(instanceTypeInfo.InstanceType), strings.Join(architectures, ", "))
+	}
+
+	return nil
+}
+
+// Validate that the subnet is in a Wavelength Zone, the only zones whose subnets can associate a carrier IP
+func (h *EC2Helper) ValidateCarrierIpSubnet(subnet *ec2.Subnet) error {
+	zoneTypes, err := h.GetZoneTypesByName()
+	if err != nil {
+		return err
+	}
+
+	zoneName := aws.StringValue(subnet.AvailabilityZone)
+	if zoneTypes[zoneName] != ZoneTypeWavelengthZone {
+		return fmt.Errorf("A carrier IP can only be associated in a Wavelength Zone subnet, but subnet %s is in %s",
+			aws.StringValue(subnet.SubnetId), zoneName)
+	}
+
+	return nil
+}
+
+/*
+Validate the resource types to tag at launch. Empty resource types mean the default ones are tagged.
+*/
+func ValidateTagResourceTypes(resourceTypes []string) error {
+	for _, resourceType := range resourceTypes {
+		if !slices.Contains(TagResourceTypes, resourceType) {
+			return fmt.Errorf("Resource type %s can't be tagged at launch, it must be one of: %s", resourceType,
+				strings.Join(TagResourceTypes, ", "))
+		}
+	}
+
+	return nil
+}
+
+/*
+Get the tag specifications of the resources created at launch. Volumes are only tagged for images
+with EBS root devices, and Spot instance requests only for Spot instances.
+*/
+func getTagSpecs(simpleConfig *config.SimpleInfo, image *ec2.Image, tags []*ec2.Tag) []*ec2.TagSpecification {
+	resourceTypes := simpleConfig.TagResourceTypes
+	if len(resourceTypes) == 0 {
+		resourceTypes = DefaultTagResourceTypes
+	}
+
+	tagSpecs := []*ec2.TagSpecification{}
+	for _, resourceType := range TagResourceTypes {
+		if !slices.Contains(resourceTypes, resourceType) {
+			continue
+		}
+		if resourceType == ec2.ResourceTypeVolume && aws.StringValue(image.RootDeviceType) != "ebs" {
+			continue
+		}
+		if resourceType == ec2.ResourceTypeSpotInstancesRequest && simpleConfig.CapacityType != config.CapacityTypeSpot {
+			continue
+		}
+
+		tagSpecs = append(tagSpecs, &ec2.TagSpecification{
+			ResourceType: aws.String(resourceType),
+			Tags:         tags,
+		})
+	}
+
+	return tagSpecs
+}
+
 // Given an AWS platform string, tell if it's a Linux platform
 func IsLinux(platform string) bool {
 	return platform == ec2.CapacityReservationInstancePlatformLinuxUnix ||
@@ -1384,6 +3461,131 @@ func IsLinux(platform string) bool {
 		platform == ec2.CapacityReservationInstancePlatformLinuxwithSqlserverEnterprise
 }
 
+/*
+Get the block device mappings of an image for a launch, keeping its EBS volumes after termination and
+resizing or changing the type of its root volume if specified. The mappings are copied, so the image itself
+is left unchanged.
+*/
+func getBlockDeviceMappings(image *ec2.Image, simpleConfig *config.SimpleInfo) []*ec2.BlockDeviceMapping {
+	blockDevices := []*ec2.BlockDeviceMapping{}
+	for _, block := range image.BlockDeviceMappings {
+		blockDevice := &ec2.BlockDeviceMapping{
+			DeviceName:  block.DeviceName,
+			NoDevice:    block.NoDevice,
+			VirtualName: block.VirtualName,
+		}
+		if block.Ebs != nil {
+			ebs := *block.Ebs
+			if simpleConfig.KeepEbsVolumeAfterTermination {
+				ebs.DeleteOnTermination = aws.Bool(false)
+			}
+			if aws.StringValue(block.DeviceName) == aws.StringValue(image.RootDeviceName) {
+				setRootVolumeOptions(&ebs, simpleConfig)
+			}
+			blockDevice.Ebs = &ebs
+		}
+		blockDevices = append(blockDevices, blockDevice)
+	}
+
+	return blockDevices
+}
+
+/*
+Set the size and the type of the root volume, if specified. The IOPS and the throughput of the image are
+replaced along with the type, since they may not apply to the new type.
+*/
+func setRootVolumeOptions(ebs *ec2.EbsBlockDevice, simpleConfig *config.SimpleInfo) {
+	if simpleConfig.RootVolumeSize > 0 {
+		ebs.VolumeSize = aws.Int64(simpleConfig.RootVolumeSize)
+	}
+	if simpleConfig.RootVolumeType != "" {
+		ebs.VolumeType = aws.String(simpleConfig.RootVolumeType)
+		ebs.Iops = nil
+		ebs.Throughput = nil
+		if simpleConfig.RootVolumeIops > 0 {
+			ebs.Iops = aws.Int64(simpleConfig.RootVolumeIops)
+		}
+		if simpleConfig.RootVolumeThroughput > 0 {
+			ebs.Throughput = aws.Int64(simpleConfig.RootVolumeThroughput)
+		}
+	}
+}
+
+// Get the root EBS volume of an image, or nil if its root device isn't an EBS volume
+func GetRootEbsVolume(image *ec2.Image) *ec2.EbsBlockDevice {
+	for _, block := range image.BlockDeviceMappings {
+		if block.Ebs != nil && aws.StringValue(block.DeviceName) == aws.StringValue(image.RootDeviceName) {
+			return block.Ebs
+		}
+	}
+
+	return nil
+}
+
+// Get the estimated monthly storage cost in USD of an EBS volume, given its type and its size in GiB
+func GetEbsMonthlyStorageCost(volumeType string, sizeGiB int64) (float64, error) {
+	price, found := ebsMonthlyPricesPerGiB[volumeType]
+	if !found {
+		return 0, fmt.Errorf("The price of EBS volume type %s is unknown", volumeType)
+	}
+
+	return price * float64(sizeGiB), nil
+}
+
+/*
+Validate the root volume size in GiB, which can only be set for an image with an EBS root volume and can't be
+smaller than the snapshot of the volume
+*/
+func ValidateRootVolumeSize(rootVolumeSize int64, image *ec2.Image) error {
+	if rootVolumeSize < 1 {
+		return errors.New("The root volume size must be at least 1 GiB")
+	}
+
+	rootVolume := GetRootEbsVolume(image)
+	if rootVolume == nil {
+		return fmt.Errorf("Image %s has no EBS root volume to resize", aws.StringValue(image.ImageId))
+	}
+	if rootVolume.VolumeSize != nil && rootVolumeSize < *rootVolume.VolumeSize {
+		return fmt.Errorf("The root volume size of %d GiB is smaller than the %d GiB snapshot of image %s",
+			rootVolumeSize, *rootVolume.VolumeSize, aws.StringValue(image.ImageId))
+	}
+
+	return nil
+}
+
+/*
+Validate the type of the root volume with its IOPS and throughput, which only some types accept. Provisioned IOPS
+volumes need their IOPS. An empty type means the type of the image is used, which can't be combined with the others.
+*/
+func ValidateRootVolumeType(volumeType string, iops, throughput int64) error {
+	if volumeType == "" {
+		if iops != 0 || throughput != 0 {
+			return errors.New("The IOPS and the throughput of the root volume can only be set with its volume type")
+		}
+		return nil
+	}
+
+	if !slices.Contains(RootVolumeTypes, volumeType) {
+		return fmt.Errorf("The root volume type must be one of %s", strings.Join(RootVolumeTypes, ", "))
+	}
+	if iops < 0 || throughput < 0 {
+		return errors.New("The IOPS and the throughput of the root volume must be positive")
+	}
+	isProvisionedIops := volumeType == ec2.VolumeTypeIo1 || volumeType == ec2.VolumeTypeIo2
+	if isProvisionedIops && iops == 0 {
+		return fmt.Errorf("The IOPS must be set for %s volumes", volumeType)
+	}
+	if iops > 0 && !isProvisionedIops && volumeType != ec2.VolumeTypeGp3 {
+		return fmt.Errorf("IOPS can only be set for %s, %s and %s volumes", ec2.VolumeTypeGp3, ec2.VolumeTypeIo1,
+			ec2.VolumeTypeIo2)
+	}
+	if throughput > 0 && volumeType != ec2.VolumeTypeGp3 {
+		return fmt.Errorf("Throughput can only be set for %s volumes", ec2.VolumeTypeGp3)
+	}
+
+	return nil
+}
+
 // Determine if an image contains at least one EBS volume
 func HasEbsVolume(image *ec2.Image) bool {
 	if image.BlockDeviceMappings != nil {
@@ -1398,10 +3600,17 @@ func HasEbsVolume(image *ec2.Image) bool {
 }
 
 func (h *EC2Helper) CreateLaunchTemplate(simpleConfig *config.SimpleInfo, detailedConfig *config.DetailedInfo) (*ec2.LaunchTemplate, error) {
-	launchIdentifier := uuid.New()
-
 	fmt.Println("Creating Launch Template...")
 
+	input := getLaunchTemplateInput(simpleConfig, detailedConfig)
+	result, err := h.Svc.CreateLaunchTemplate(input)
+	return result.LaunchTemplate, err
+}
+
+// Get the input of a launch template for a Spot launch, with a unique name
+func getLaunchTemplateInput(simpleConfig *config.SimpleInfo,
+	detailedConfig *config.DetailedInfo) *ec2.CreateLaunchTemplateInput {
+	launchIdentifier := uuid.New()
 	dataConfig := createRequestInstanceConfig(simpleConfig, detailedConfig)
 	input := &ec2.CreateLaunchTemplateInput{
 		LaunchTemplateData: &ec2.RequestLaunchTemplateData{
@@ -1411,22 +3620,44 @@ func (h *EC2Helper) CreateLaunchTemplate(simpleConfig *config.SimpleInfo, detail
 					DeviceIndex:              aws.Int64(0),
 					Groups:                   dataConfig.SecurityGroupIds,
 					SubnetId:                 dataConfig.SubnetId,
+					PrivateIpAddress:         dataConfig.PrivateIpAddress,
 				},
 			},
 			IamInstanceProfile:                (*ec2.LaunchTemplateIamInstanceProfileSpecificationRequest)(dataConfig.IamInstanceProfile),
 			ImageId:                           dataConfig.ImageId,
 			InstanceType:                      dataConfig.InstanceType,
+			KeyName:                           dataConfig.KeyName,
 			BlockDeviceMappings:               dataConfig.LaunchTemplateBlockMappings,
 			InstanceInitiatedShutdownBehavior: dataConfig.InstanceInitiatedShutdownBehavior,
 			UserData:                          dataConfig.UserData,
 			TagSpecifications:                 dataConfig.LaunchTemplateTagSpecs,
+			InstanceMarketOptions:             dataConfig.InstanceMarketOptions,
+			CapacityReservationSpecification:  dataConfig.LaunchTemplateCapacityReservation,
+			PrivateDnsNameOptions:             dataConfig.LaunchTemplatePrivateDnsName,
+			CreditSpecification:               dataConfig.CreditSpecification,
+			MetadataOptions:                   dataConfig.LaunchTemplateMetadataOptions,
 		},
-		LaunchTemplateName: aws.String(fmt.Sprintf("SimpleEC2LaunchTemplate-%s", launchIdentifier)),
+		LaunchTemplateName: aws.String(fmt.Sprintf("%s%s", launchTemplateNamePrefix, launchIdentifier)),
 		VersionDescription: aws.String(fmt.Sprintf("Launch Template %s", launchIdentifier)),
+		TagSpecifications: []*ec2.TagSpecification{
+			{
+				ResourceType: aws.String(ec2.ResourceTypeLaunchTemplate),
+				Tags:         getSimpleEc2Tags(),
+			},
+		},
 	}
 
-	result, err := h.Svc.CreateLaunchTemplate(input)
-	return result.LaunchTemplate, err
+	// A Wavelength Zone subnet gets a carrier IP from its carrier gateway instead of a public IP
+	if dataConfig.AssociateCarrierIpAddress != nil {
+		networkInterface := input.LaunchTemplateData.NetworkInterfaces[0]
+		networkInterface.AssociatePublicIpAddress = nil
+		networkInterface.AssociateCarrierIpAddress = dataConfig.AssociateCarrierIpAddress
+	}
+	if dataConfig.AssociatePublicIpAddress != nil {
+		input.LaunchTemplateData.NetworkInterfaces[0].AssociatePublicIpAddress = dataConfig.AssociatePublicIpAddress
+	}
+
+	return input
 }
 
 func createRequestInstanceConfig(simpleConfig *config.SimpleInfo, detailedConfig *config.DetailedInfo) config.RequestInstanceInfo {
@@ -1448,19 +3679,105 @@ func createRequestInstanceConfig(simpleConfig *config.SimpleInfo, detailedConfig
 	if simpleConfig.SubnetId != "" {
 		requestInstanceConfig.SubnetId = aws.String(simpleConfig.SubnetId)
 	}
+	if simpleConfig.PrivateIpAddress != "" {
+		requestInstanceConfig.PrivateIpAddress = aws.String(simpleConfig.PrivateIpAddress)
+	}
+	if simpleConfig.SpotBlockDurationMinutes > 0 {
+		requestInstanceConfig.InstanceMarketOptions = &ec2.LaunchTemplateInstanceMarketOptionsRequest{
+			MarketType: aws.String(ec2.MarketTypeSpot),
+			SpotOptions: &ec2.LaunchTemplateSpotMarketOptionsRequest{
+				BlockDurationMinutes: aws.Int64(int64(simpleConfig.SpotBlockDurationMinutes)),
+			},
+		}
+	}
+	if simpleConfig.CapacityReservationId != "" {
+		requestInstanceConfig.CapacityReservationSpecification = &ec2.CapacityReservationSpecification{
+			CapacityReservationTarget: &ec2.CapacityReservationTarget{
+				CapacityReservationId: aws.String(simpleConfig.CapacityReservationId),
+			},
+		}
+		requestInstanceConfig.LaunchTemplateCapacityReservation = &ec2.LaunchTemplateCapacityReservationSpecificationRequest{
+			CapacityReservationTarget: &ec2.CapacityReservationTarget{
+				CapacityReservationId: aws.String(simpleConfig.CapacityReservationId),
+			},
+		}
+	} else if simpleConfig.CapacityReservationPreference != "" {
+		requestInstanceConfig.CapacityReservationSpecification = &ec2.CapacityReservationSpecification{
+			CapacityReservationPreference: aws.String(simpleConfig.CapacityReservationPreference),
+		}
+		requestInstanceConfig.LaunchTemplateCapacityReservation = &ec2.LaunchTemplateCapacityReservationSpecificationRequest{
+			CapacityReservationPreference: aws.String(simpleConfig.CapacityReservationPreference),
+		}
+	}
+	if simpleConfig.PrivateDnsHostnameType != "" || simpleConfig.EnableResourceNameDnsARecord ||
+		simpleConfig.EnableResourceNameDnsAAAARecord {
+		requestInstanceConfig.PrivateDnsNameOptions = &ec2.PrivateDnsNameOptionsRequest{
+			EnableResourceNameDnsARecord:    aws.Bool(simpleConfig.EnableResourceNameDnsARecord),
+			EnableResourceNameDnsAAAARecord: aws.Bool(simpleConfig.EnableResourceNameDnsAAAARecord),
+		}
+		requestInstanceConfig.LaunchTemplatePrivateDnsName = &ec2.LaunchTemplatePrivateDnsNameOptionsRequest{
+			EnableResourceNameDnsARecord:    aws.Bool(simpleConfig.EnableResourceNameDnsARecord),
+			EnableResourceNameDnsAAAARecord: aws.Bool(simpleConfig.EnableResourceNameDnsAAAARecord),
+		}
+		if simpleConfig.PrivateDnsHostnameType != "" {
+			requestInstanceConfig.PrivateDnsNameOptions.HostnameType = aws.String(simpleConfig.PrivateDnsHostnameType)
+			requestInstanceConfig.LaunchTemplatePrivateDnsName.HostnameType =
+				aws.String(simpleConfig.PrivateDnsHostnameType)
+		}
+	}
+	if simpleConfig.CpuCredits != "" {
+		requestInstanceConfig.CreditSpecification = &ec2.CreditSpecificationRequest{
+			CpuCredits: aws.String(simpleConfig.CpuCredits),
+		}
+	}
+	if simpleConfig.AssociateCarrierIp {
+		requestInstanceConfig.AssociateCarrierIpAddress = aws.Bool(true)
+	}
+	if simpleConfig.NoPublicIp {
+		requestInstanceConfig.AssociatePublicIpAddress = aws.Bool(false)
+	}
+	if simpleConfig.HostResourceGroupArn != "" {
+		requestInstanceConfig.Placement = &ec2.Placement{
+			Tenancy:              aws.String(ec2.TenancyHost),
+			HostResourceGroupArn: aws.String(simpleConfig.HostResourceGroupArn),
+		}
+		if simpleConfig.Affinity != "" {
+			requestInstanceConfig.Placement.Affinity = aws.String(simpleConfig.Affinity)
+		}
+	}
 	if simpleConfig.SecurityGroupIds != nil && len(simpleConfig.SecurityGroupIds) > 0 {
 		requestInstanceConfig.SecurityGroupIds = aws.StringSlice(simpleConfig.SecurityGroupIds)
 	}
-	if simpleConfig.IamInstanceProfile != "" {
-		requestInstanceConfig.IamInstanceProfile = &ec2.IamInstanceProfileSpecification{
-			Name: aws.String(simpleConfig.IamInstanceProfile),
+	if simpleConfig.KeyPairName != "" {
+		requestInstanceConfig.KeyName = aws.String(simpleConfig.KeyPairName)
+	}
+	if simpleConfig.IamInstanceProfile != "" {
+		requestInstanceConfig.IamInstanceProfile = &ec2.IamInstanceProfileSpecification{
+			Name: aws.String(simpleConfig.IamInstanceProfile),
+		}
+	}
+	switch simpleConfig.Imds {
+	case ImdsOptional, ImdsRequired:
+		requestInstanceConfig.MetadataOptions = &ec2.InstanceMetadataOptionsRequest{
+			HttpEndpoint: aws.String(ec2.InstanceMetadataEndpointStateEnabled),
+			HttpTokens:   aws.String(simpleConfig.Imds),
+		}
+	case ImdsDisabled:
+		requestInstanceConfig.MetadataOptions = &ec2.InstanceMetadataOptionsRequest{
+			HttpEndpoint: aws.String(ec2.InstanceMetadataEndpointStateDisabled),
+		}
+	}
+	if requestInstanceConfig.MetadataOptions != nil {
+		requestInstanceConfig.LaunchTemplateMetadataOptions = &ec2.LaunchTemplateInstanceMetadataOptionsRequest{
+			HttpEndpoint: requestInstanceConfig.MetadataOptions.HttpEndpoint,
+			HttpTokens:   requestInstanceConfig.MetadataOptions.HttpTokens,
 		}
 	}
 	if detailedConfig.TagSpecs != nil {
 		requestInstanceConfig.LaunchTemplateTagSpecs = []*ec2.LaunchTemplateTagSpecificationRequest{}
 		for _, tagSpec := range detailedConfig.TagSpecs {
 			ltTagSpec := ec2.LaunchTemplateTagSpecificationRequest{
-				ResourceType: aws.String("instance"),
+				ResourceType: tagSpec.ResourceType,
 				Tags:         tagSpec.Tags,
 			}
 			requestInstanceConfig.LaunchTemplateTagSpecs = append(requestInstanceConfig.LaunchTemplateTagSpecs, &ltTagSpec)
@@ -1469,16 +3786,12 @@ func createRequestInstanceConfig(simpleConfig *config.SimpleInfo, detailedConfig
 
 	setAutoTermination := false
 	if detailedConfig != nil {
-		// Set all EBS volumes not to be deleted, if specified
-		if HasEbsVolume(detailedConfig.Image) && simpleConfig.KeepEbsVolumeAfterTermination {
-			requestInstanceConfig.BlockDeviceMappings = detailedConfig.Image.BlockDeviceMappings
-			for _, block := range requestInstanceConfig.BlockDeviceMappings {
-				if block.Ebs != nil {
-					block.Ebs.DeleteOnTermination = aws.Bool(false)
-				}
-			}
+		// Set all EBS volumes not to be deleted and change the root volume, if specified
+		isKeepEbsVolume := HasEbsVolume(detailedConfig.Image) && simpleConfig.KeepEbsVolumeAfterTermination
+		if isKeepEbsVolume || simpleConfig.RootVolumeSize > 0 || simpleConfig.RootVolumeType != "" {
+			requestInstanceConfig.BlockDeviceMappings = getBlockDeviceMappings(detailedConfig.Image, simpleConfig)
 			blockDevices := []*ec2.LaunchTemplateBlockDeviceMappingRequest{}
-			for index, block := range detailedConfig.Image.BlockDeviceMappings {
+			for index, block := range requestInstanceConfig.BlockDeviceMappings {
 				blockDevices = append(blockDevices, &ec2.LaunchTemplateBlockDeviceMappingRequest{
 					DeviceName:  block.DeviceName,
 					NoDevice:    block.NoDevice,
@@ -1486,7 +3799,7 @@ func createRequestInstanceConfig(simpleConfig *config.SimpleInfo, detailedConfig
 				})
 				if block.Ebs != nil {
 					blockDeviceEbs := &ec2.LaunchTemplateEbsBlockDeviceRequest{
-						DeleteOnTermination: aws.Bool(false),
+						DeleteOnTermination: block.Ebs.DeleteOnTermination,
 						Encrypted:           block.Ebs.Encrypted,
 						Iops:                block.Ebs.Iops,
 						KmsKeyId:            block.Ebs.KmsKeyId,
@@ -1504,31 +3817,129 @@ func createRequestInstanceConfig(simpleConfig *config.SimpleInfo, detailedConfig
 	}
 
 	if setAutoTermination {
-		requestInstanceConfig.InstanceInitiatedShutdownBehavior = aws.String("terminate")
+		requestInstanceConfig.InstanceInitiatedShutdownBehavior = aws.String(
+			GetShutdownBehavior(simpleConfig.AutoTerminationTimerAction))
+	}
+
+	userData := GetLaunchUserData(simpleConfig, detailedConfig)
+	if userData != "" {
+		requestInstanceConfig.UserData = aws.String(base64.StdEncoding.EncodeToString([]byte(userData)))
+	}
+
+	return requestInstanceConfig
+}
+
+/*
+Get the user data assembled from the auto-termination timer and the boot script, before base64 encoding.
+The edited user data in the detailed config is not considered. Empty result means no user data.
+*/
+func GetUserData(simpleConfig *config.SimpleInfo, detailedConfig *config.DetailedInfo) string {
+	setAutoTermination := detailedConfig != nil && IsLinux(*detailedConfig.Image.PlatformDetails) &&
+		simpleConfig.AutoTerminationTimerMinutes > 0
+	bootScriptFilePath := simpleConfig.BootScriptFilePath
+	if detailedConfig != nil {
+		bootScriptFilePath = GetBootScriptFilePath(simpleConfig, detailedConfig.Image)
+	}
+
+	if setAutoTermination {
 		autoTermCmd := fmt.Sprintf("#!/bin/bash\necho \"sudo poweroff\" | at now + %d minutes\n",
 			simpleConfig.AutoTerminationTimerMinutes)
-		if simpleConfig.BootScriptFilePath == "" {
-			requestInstanceConfig.UserData = aws.String(base64.StdEncoding.EncodeToString([]byte(autoTermCmd)))
-		} else {
-			bootScriptRaw, _ := ioutil.ReadFile(simpleConfig.BootScriptFilePath)
-			bootScriptLines := strings.Split(string(bootScriptRaw), "\n")
-			//if #!/bin/bash is first, then replace first line otherwise, prepend termination
-			if len(bootScriptLines) >= 1 && bootScriptLines[0] == "#!/bin/bash" {
-				bootScriptLines[0] = autoTermCmd
-			} else {
-				bootScriptLines = append([]string{autoTermCmd}, bootScriptLines...)
-			}
-			bootScriptRaw = []byte(strings.Join(bootScriptLines, "\n"))
-			requestInstanceConfig.UserData = aws.String(base64.StdEncoding.EncodeToString(bootScriptRaw))
+		if bootScriptFilePath == "" {
+			return autoTermCmd
 		}
-	} else {
-		if simpleConfig.BootScriptFilePath != "" {
-			bootScriptRaw, _ := ioutil.ReadFile(simpleConfig.BootScriptFilePath)
-			requestInstanceConfig.UserData = aws.String(base64.StdEncoding.EncodeToString(bootScriptRaw))
+
+		bootScriptRaw, _ := ioutil.ReadFile(bootScriptFilePath)
+		bootScriptLines := strings.Split(string(bootScriptRaw), "\n")
+		//if #!/bin/bash is first, then replace first line otherwise, prepend termination
+		if len(bootScriptLines) >= 1 && bootScriptLines[0] == "#!/bin/bash" {
+			bootScriptLines[0] = autoTermCmd
+		} else {
+			bootScriptLines = append([]string{autoTermCmd}, bootScriptLines...)
 		}
+		return strings.Join(bootScriptLines, "\n")
 	}
 
-	return requestInstanceConfig
+	if bootScriptFilePath != "" {
+		bootScriptRaw, _ := ioutil.ReadFile(bootScriptFilePath)
+		return string(bootScriptRaw)
+	}
+
+	return ""
+}
+
+// Get the user data passed to the instance, which is the edited user data if any, before base64 encoding
+func GetLaunchUserData(simpleConfig *config.SimpleInfo, detailedConfig *config.DetailedInfo) string {
+	if detailedConfig != nil && detailedConfig.UserData != nil {
+		return *detailedConfig.UserData
+	}
+
+	return GetUserData(simpleConfig, detailedConfig)
+}
+
+/*
+Validate user data starting with #cloud-config, which cloud-init silently ignores on the instance if it isn't
+valid YAML. Other user data, such as shell scripts, is not validated.
+*/
+func ValidateUserData(userData string) error {
+	if !strings.HasPrefix(userData, cloudConfigHeader) {
+		return nil
+	}
+
+	var cloudConfig interface{}
+	err := yaml.Unmarshal([]byte(userData), &cloudConfig)
+	if err != nil {
+		return fmt.Errorf("The cloud-config user data is not valid YAML: %s", err)
+	}
+	if _, isMap := cloudConfig.(map[string]interface{}); cloudConfig != nil && !isMap {
+		return errors.New("The cloud-config user data must be a YAML mapping of modules, such as \"packages:\"")
+	}
+
+	return nil
+}
+
+/*
+Get the boot script for the platform of the image. The boot script for all platforms is used if specified,
+otherwise the boot script for Linux or Windows is picked. Empty result means no boot script.
+*/
+func GetBootScriptFilePath(simpleConfig *config.SimpleInfo, image *ec2.Image) string {
+	if simpleConfig.BootScriptFilePath != "" {
+		return simpleConfig.BootScriptFilePath
+	}
+	if platformFilePath := getPlatformBootScriptFilePath(simpleConfig, image); platformFilePath != nil {
+		return *platformFilePath
+	}
+
+	return ""
+}
+
+/*
+Set the boot script for the platform of the image, so that it isn't used for images of the other platform.
+The boot script for all platforms is set instead if it is already used or the image has no matching platform.
+*/
+func SetBootScriptFilePath(simpleConfig *config.SimpleInfo, image *ec2.Image, filePath string) {
+	platformFilePath := getPlatformBootScriptFilePath(simpleConfig, image)
+	if simpleConfig.BootScriptFilePath == "" && platformFilePath != nil {
+		*platformFilePath = filePath
+		return
+	}
+
+	simpleConfig.BootScriptFilePath = filePath
+	// Removing the boot script must not reveal the boot script for the platform
+	if filePath == "" && platformFilePath != nil {
+		*platformFilePath = ""
+	}
+}
+
+// Get the config field of the boot script for the platform of the image, or nil if no platform matches
+func getPlatformBootScriptFilePath(simpleConfig *config.SimpleInfo, image *ec2.Image) *string {
+	if IsLinux(aws.StringValue(image.PlatformDetails)) {
+		return &simpleConfig.BootScriptLinuxFilePath
+	}
+	if aws.StringValue(image.Platform) == ec2.PlatformValuesWindows {
+		return &simpleConfig.BootScriptWindowsFilePath
+	}
+
+	return nil
 }
 
 func (h *EC2Helper) DeleteLaunchTemplate(templateId *string) error {
@@ -1541,17 +3952,343 @@ func (h *EC2Helper) DeleteLaunchTemplate(templateId *string) error {
 	return err
 }
 
-func (h *EC2Helper) LaunchFleet(templateId *string) (*ec2.CreateFleetOutput, error) {
-	fleetTemplateSpecs := &ec2.FleetLaunchTemplateSpecificationRequest{
+/*
+Get the resources created by simple-ec2 that are no longer used by any instance: security groups,
+launch templates left behind by interrupted Spot launches, and the stacks of new VPCs without instances.
+Empty result is allowed.
+*/
+func (h *EC2Helper) GetOrphanedResources(c *cfn.Cfn) ([]OrphanedResource, error) {
+	orphanedResources := []OrphanedResource{}
+	err := h.FindOrphanedResources(c, func(resource OrphanedResource) error {
+		orphanedResources = append(orphanedResources, resource)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return orphanedResources, nil
+}
+
+/*
+Find the orphaned resources like GetOrphanedResources, passing each resource to the function as soon as its
+kind of resource has been searched, so that results can be streamed without waiting for the full search.
+The search stops at the first error returned by the function.
+*/
+func (h *EC2Helper) FindOrphanedResources(c *cfn.Cfn, fn func(OrphanedResource) error) error {
+	instances, err := h.GetInstancesByState(activeInstanceStates)
+	if err != nil {
+		return err
+	}
+
+	finders := []func() ([]OrphanedResource, error){
+		func() ([]OrphanedResource, error) { return h.GetOrphanedSecurityGroups(instances) },
+		func() ([]OrphanedResource, error) { return h.GetOrphanedLaunchTemplates(instances) },
+		func() ([]OrphanedResource, error) { return h.GetOrphanedStacks(c, instances) },
+	}
+	for _, find := range finders {
+		resources, err := find()
+		if err != nil {
+			return err
+		}
+		for _, resource := range resources {
+			err = fn(resource)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+Get the security groups created by simple-ec2 that no instance or network interface of the instances uses.
+Security groups belonging to a stack are left to the deletion of the stack.
+Empty result is allowed.
+*/
+func (h *EC2Helper) GetOrphanedSecurityGroups(instances []*ec2.Instance) ([]OrphanedResource, error) {
+	input := &ec2.DescribeSecurityGroupsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("tag:" + tag.CreatedByKey),
+				Values: aws.StringSlice([]string{tag.CreatedByValue}),
+			},
+		},
+	}
+
+	securityGroups, err := h.getSecurityGroups(input)
+	if err != nil {
+		return nil, err
+	}
+
+	usedGroupIds := map[string]bool{}
+	for _, instance := range instances {
+		for _, group := range instance.SecurityGroups {
+			usedGroupIds[aws.StringValue(group.GroupId)] = true
+		}
+		for _, networkInterface := range instance.NetworkInterfaces {
+			for _, group := range networkInterface.Groups {
+				usedGroupIds[aws.StringValue(group.GroupId)] = true
+			}
+		}
+	}
+
+	orphanedResources := []OrphanedResource{}
+	for _, group := range securityGroups {
+		if usedGroupIds[aws.StringValue(group.GroupId)] || getTagValue(group.Tags, stackNameTagKey) != "" {
+			continue
+		}
+		orphanedResources = append(orphanedResources, OrphanedResource{
+			Type: cfn.ResourceTypeSecurityGroup,
+			Id:   aws.StringValue(group.GroupId),
+			Name: aws.StringValue(group.GroupName),
+		})
+	}
+
+	return orphanedResources, nil
+}
+
+/*
+Get the launch templates created by simple-ec2 that no fleet or instance uses anymore.
+Empty result is allowed.
+*/
+func (h *EC2Helper) GetOrphanedLaunchTemplates(instances []*ec2.Instance) ([]OrphanedResource, error) {
+	launchTemplates, err := h.GetLaunchTemplatesInRegion()
+	if err != nil {
+		return nil, err
+	}
+
+	usedTemplateIds := map[string]bool{}
+	for _, instance := range instances {
+		if templateId := getTagValue(instance.Tags, launchTemplateIdTagKey); templateId != "" {
+			usedTemplateIds[templateId] = true
+		}
+	}
+
+	input := &ec2.DescribeFleetsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name: aws.String("fleet-state"),
+				Values: aws.StringSlice([]string{
+					ec2.FleetStateCodeSubmitted,
+					ec2.FleetStateCodeActive,
+					ec2.FleetStateCodeModifying,
+				}),
+			},
+		},
+	}
+	err = h.Svc.DescribeFleetsPages(input, func(page *ec2.DescribeFleetsOutput, lastPage bool) bool {
+		for _, fleet := range page.Fleets {
+			for _, templateConfig := range fleet.LaunchTemplateConfigs {
+				if templateConfig.LaunchTemplateSpecification != nil {
+					usedTemplateIds[aws.StringValue(templateConfig.LaunchTemplateSpecification.LaunchTemplateId)] = true
+				}
+			}
+		}
+		return !lastPage
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	orphanedResources := []OrphanedResource{}
+	for _, template := range launchTemplates {
+		name := aws.StringValue(template.LaunchTemplateName)
+		if !strings.HasPrefix(name, launchTemplateNamePrefix) &&
+			getTagValue(template.Tags, tag.CreatedByKey) != tag.CreatedByValue {
+			continue
+		}
+		if usedTemplateIds[aws.StringValue(template.LaunchTemplateId)] {
+			continue
+		}
+		orphanedResources = append(orphanedResources, OrphanedResource{
+			Type: cfn.ResourceTypeLaunchTemplate,
+			Id:   aws.StringValue(template.LaunchTemplateId),
+			Name: name,
+		})
+	}
+
+	return orphanedResources, nil
+}
+
+/*
+Get the stacks created by simple-ec2 whose VPC has none of the instances.
+Empty result is allowed.
+*/
+func (h *EC2Helper) GetOrphanedStacks(c *cfn.Cfn, instances []*ec2.Instance) ([]OrphanedResource, error) {
+	stacks, err := c.GetSimpleEc2Stacks()
+	if err != nil {
+		return nil, err
+	}
+
+	usedVpcIds := map[string]bool{}
+	for _, instance := range instances {
+		usedVpcIds[aws.StringValue(instance.VpcId)] = true
+	}
+
+	orphanedResources := []OrphanedResource{}
+	for _, stack := range stacks {
+		stackName := aws.StringValue(stack.StackName)
+		resources, err := c.GetStackResources(stackName)
+		if err != nil {
+			return nil, err
+		}
+
+		isUsed := false
+		for _, resource := range resources {
+			if aws.StringValue(resource.ResourceType) == cfn.ResourceTypeVpc &&
+				usedVpcIds[aws.StringValue(resource.PhysicalResourceId)] {
+				isUsed = true
+				break
+			}
+		}
+		if !isUsed {
+			orphanedResources = append(orphanedResources, OrphanedResource{
+				Type: cfn.ResourceTypeStack,
+				Id:   aws.StringValue(stack.StackId),
+				Name: stackName,
+			})
+		}
+	}
+
+	return orphanedResources, nil
+}
+
+// Delete an orphaned resource found by GetOrphanedResources
+func (h *EC2Helper) DeleteOrphanedResource(c *cfn.Cfn, resource OrphanedResource) error {
+	switch resource.Type {
+	case cfn.ResourceTypeSecurityGroup:
+		_, err := h.Svc.DeleteSecurityGroup(&ec2.DeleteSecurityGroupInput{
+			GroupId: aws.String(resource.Id),
+		})
+		return err
+	case cfn.ResourceTypeLaunchTemplate:
+		_, err := h.Svc.DeleteLaunchTemplate(&ec2.DeleteLaunchTemplateInput{
+			LaunchTemplateId: aws.String(resource.Id),
+		})
+		return err
+	case cfn.ResourceTypeStack:
+		return c.DeleteStack(resource.Name)
+	default:
+		return fmt.Errorf("Unsupported resource type %s", resource.Type)
+	}
+}
+
+// Get the value of a tag, or an empty string if the tag is not present
+func getTagValue(tags []*ec2.Tag, key string) string {
+	for _, t := range tags {
+		if aws.StringValue(t.Key) == key {
+			return aws.StringValue(t.Value)
+		}
+	}
+	return ""
+}
+
+/*
+Launch Spot instances with a fleet. If the launch fails because the service-linked role for EC2 Spot
+is missing, as in accounts that never launched Spot instances, the role is created and the launch is retried.
+*/
+func (h *EC2Helper) LaunchFleet(templateId *string, instanceCount int64,
+	clientToken string) (*ec2.CreateFleetOutput, error) {
+	fleet, err := h.launchFleet(templateId, nil, instanceCount, clientToken)
+	if IsSpotServiceLinkedRoleError(err) && h.createSpotServiceLinkedRole() {
+		fleet, err = h.launchFleet(templateId, nil, instanceCount, clientToken)
+	}
+
+	return fleet, err
+}
+
+// Tell if an error is caused by the missing service-linked role for EC2 Spot
+func IsSpotServiceLinkedRoleError(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && (aerr.Code() == "AuthFailure.ServiceLinkedRoleCreationNotPermitted" ||
+		strings.Contains(aerr.Message(), "AWSServiceRoleForEC2Spot"))
+}
+
+/*
+Create the service-linked role for EC2 Spot. If the role can't be created, for example for lack of
+permissions, the steps to create it are printed. Return true if the role is created, false otherwise
+*/
+func (h *EC2Helper) createSpotServiceLinkedRole() bool {
+	fmt.Println("The service-linked role for EC2 Spot is missing. Creating it...")
+	err := errors.New("No IAM client available")
+	if h.Iam != nil {
+		err = h.Iam.CreateSpotServiceLinkedRole()
+	}
+	if err != nil {
+		fmt.Println("Creating the service-linked role failed:", err)
+		fmt.Println("Ask an administrator allowed to call iam:CreateServiceLinkedRole to run the following " +
+			"command, then launch again:")
+		fmt.Println("  aws iam create-service-linked-role --aws-service-name " + iamhelper.SpotServiceName)
+		return false
+	}
+
+	fmt.Println("Service-linked role created. Launching spot instance again...")
+	time.Sleep(ServiceLinkedRoleWait)
+	return true
+}
+
+// Launch a fleet with the launch template, overriding its instance type if specified
+func (h *EC2Helper) launchFleet(templateId *string, instanceType *string, instanceCount int64,
+	clientToken string) (*ec2.CreateFleetOutput, error) {
+	input := getFleetInput(&ec2.FleetLaunchTemplateSpecificationRequest{
 		LaunchTemplateId: templateId,
 		Version:          aws.String("$Latest"),
+	}, instanceType, instanceCount, clientToken)
+
+	result, err := h.Svc.CreateFleet(input)
+
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			fmt.Println(aerr.Error())
+		} else {
+			fmt.Println(err.Error())
+		}
+		return nil, err
+	}
+
+	// Report every error of the fleet. The first one tells why nothing was launched, such as missing Spot capacity
+	fulfillment := GetFleetFulfillment(aws.Int64Value(input.TargetCapacitySpecification.TotalTargetCapacity), result)
+	if fulfillment.FulfilledCapacity == 0 {
+		for _, fleetErr := range fulfillment.Errors {
+			cli.ShowError(fleetErr, "Creating spot instance failed")
+		}
+		if len(fulfillment.Errors) == 0 {
+			return nil, errors.New("No Spot instance was launched")
+		}
+		return nil, fulfillment.Errors[0]
+	}
+
+	fmt.Println("Launch Spot Instance Success!")
+	for _, instance := range result.Instances {
+		for _, id := range instance.InstanceIds {
+			fmt.Printf("Spot Instance ID: %s\n", *id)
+		}
 	}
+	PrintFleetFulfillment(fulfillment)
+
+	return result, nil
+}
 
+/*
+Get the input of an instant Spot fleet of a number of instances with the launch template, overriding its
+instance type if specified
+*/
+func getFleetInput(fleetTemplateSpecs *ec2.FleetLaunchTemplateSpecificationRequest, instanceType *string,
+	instanceCount int64, clientToken string) *ec2.CreateFleetInput {
 	fleetTemplateConfig := []*ec2.FleetLaunchTemplateConfigRequest{
 		{
 			LaunchTemplateSpecification: fleetTemplateSpecs,
 		},
 	}
+	if instanceType != nil {
+		fleetTemplateConfig[0].Overrides = []*ec2.FleetLaunchTemplateOverridesRequest{
+			{
+				InstanceType: instanceType,
+			},
+		}
+	}
 
 	spotRequest := &ec2.SpotOptionsRequest{
 		AllocationStrategy: aws.String("capacity-optimized"),
@@ -1560,40 +4297,46 @@ func (h *EC2Helper) LaunchFleet(templateId *string) (*ec2.CreateFleetOutput, err
 	targetCapacity := &ec2.TargetCapacitySpecificationRequest{
 		DefaultTargetCapacityType: aws.String("spot"),
 		OnDemandTargetCapacity:    aws.Int64(0),
-		SpotTargetCapacity:        aws.Int64(1),
-		TotalTargetCapacity:       aws.Int64(1),
+		SpotTargetCapacity:        aws.Int64(instanceCount),
+		TotalTargetCapacity:       aws.Int64(instanceCount),
 	}
 
 	input := &ec2.CreateFleetInput{
+		ClientToken:                 aws.String(clientToken),
 		LaunchTemplateConfigs:       fleetTemplateConfig,
 		SpotOptions:                 spotRequest,
 		TargetCapacitySpecification: targetCapacity,
 		Type:                        aws.String("instant"),
 	}
 
-	result, err := h.Svc.CreateFleet(input)
+	return input
+}
 
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			fmt.Println(aerr.Error())
-		} else {
-			fmt.Println(err.Error())
-		}
-		return nil, err
-	} else {
-		if len(result.Errors) != 0 {
-			err = errors.New(*result.Errors[0].ErrorMessage)
-			cli.ShowError(err, "Creating spot instance failed")
-			return nil, err
-		}
+// Get the capacity launched by an instant fleet, in number of instances, and the errors of the capacity not launched
+func GetFleetFulfillment(requestedCapacity int64, output *ec2.CreateFleetOutput) *FleetFulfillment {
+	fulfillment := &FleetFulfillment{
+		RequestedCapacity: requestedCapacity,
+		Errors:            []error{},
+	}
+	for _, instance := range output.Instances {
+		fulfillment.FulfilledCapacity += int64(len(instance.InstanceIds))
+	}
+	for _, fleetErr := range output.Errors {
+		fulfillment.Errors = append(fulfillment.Errors, awserr.New(aws.StringValue(fleetErr.ErrorCode),
+			aws.StringValue(fleetErr.ErrorMessage), nil))
 	}
 
-	fmt.Println("Launch Spot Instance Success!")
-	for _, instance := range result.Instances {
-		for _, id := range instance.InstanceIds {
-			fmt.Printf("Spot Instance ID: %s\n", *id)
+	return fulfillment
+}
+
+// Print the fulfilled capacity of a fleet, warning about the errors when only part of the capacity is launched
+func PrintFleetFulfillment(fulfillment *FleetFulfillment) {
+	fmt.Printf("Spot capacity fulfilled: %d of %d\n", fulfillment.FulfilledCapacity, fulfillment.RequestedCapacity)
+	if fulfillment.FulfilledCapacity < fulfillment.RequestedCapacity {
+		fmt.Printf("Warning: Only %d of the %d requested Spot instances were launched\n",
+			fulfillment.FulfilledCapacity, fulfillment.RequestedCapacity)
+		for _, fleetErr := range fulfillment.Errors {
+			fmt.Println("  " + fleetErr.Error())
 		}
 	}
-
-	return result, err
 }
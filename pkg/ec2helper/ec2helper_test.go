@@ -14,19 +14,32 @@
 package ec2helper_test
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"simple-ec2/pkg/cfn"
 	"simple-ec2/pkg/config"
 	"simple-ec2/pkg/ec2helper"
+	"simple-ec2/pkg/iamhelper"
+	"simple-ec2/pkg/output"
+	"simple-ec2/pkg/tag"
 	th "simple-ec2/test/testhelper"
 
+	"github.com/aws/amazon-ec2-instance-selector/v2/pkg/bytequantity"
 	"github.com/aws/amazon-ec2-instance-selector/v2/pkg/instancetypes"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/aws/aws-sdk-go/service/ec2"
 )
 
@@ -113,6 +126,120 @@ func TestGetEnabledRegions_NoResult(t *testing.T) {
 	th.Nok(t, err)
 }
 
+func TestValidateRegion_Known(t *testing.T) {
+	th.Ok(t, ec2helper.ValidateRegion("us-east-1"))
+	th.Ok(t, ec2helper.ValidateRegion("cn-north-1"))
+}
+
+func TestValidateRegion_Unknown(t *testing.T) {
+	err := ec2helper.ValidateRegion("us-est-1")
+	th.Nok(t, err)
+	th.Equals(t, "Region us-est-1 is not a known region. Did you mean \"us-east-1\"?", err.Error())
+
+	// Without a close region, the known regions are listed
+	err = ec2helper.ValidateRegion("mars")
+	th.Nok(t, err)
+	th.Assert(t, strings.HasPrefix(err.Error(), "Region mars is not a known region. Valid regions are "),
+		"No region list in: "+err.Error())
+	th.Assert(t, strings.Contains(err.Error(), "us-west-2"), "No region list in: "+err.Error())
+}
+
+func TestValidateRegion(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		Regions: []*ec2.Region{
+			{RegionName: aws.String("us-east-1")},
+			{RegionName: aws.String("us-west-2")},
+		},
+	}
+
+	th.Ok(t, testEC2.ValidateRegion("us-west-2"))
+
+	err := testEC2.ValidateRegion("us-est-1")
+	th.Nok(t, err)
+	th.Assert(t, strings.HasSuffix(err.Error(), "Did you mean \"us-east-1\"?"), "No suggestion in: "+err.Error())
+}
+
+func TestValidateRegion_DescribeRegionsError(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		DescribeRegionsError: errors.New("Test error"),
+	}
+
+	th.Nok(t, testEC2.ValidateRegion("us-east-1"))
+}
+
+func newRegionSearchHelper(regionalSvcs map[string]*th.MockedEC2Svc) *ec2helper.EC2Helper {
+	regions := []*ec2.Region{}
+	for regionName := range regionalSvcs {
+		regions = append(regions, &ec2.Region{RegionName: aws.String(regionName)})
+	}
+
+	return &ec2helper.EC2Helper{
+		Svc: &th.MockedEC2Svc{
+			Regions: regions,
+		},
+		RegionalSvc: func(region string) ec2helper.EC2Svc {
+			return regionalSvcs[region]
+		},
+	}
+}
+
+func TestFindInstanceRegion_Found(t *testing.T) {
+	h := newRegionSearchHelper(map[string]*th.MockedEC2Svc{
+		"us-east-1": {},
+		"us-west-2": {
+			Instances: []*ec2.Instance{
+				{InstanceId: aws.String("i-12345")},
+			},
+		},
+		"eu-west-1": {
+			DescribeInstancesPagesError: awserr.New("InvalidInstanceID.NotFound", "Test error", nil),
+		},
+	})
+
+	region, err := h.FindInstanceRegion("i-12345")
+	th.Ok(t, err)
+	th.Equals(t, "us-west-2", region)
+}
+
+func TestFindInstanceRegion_NotFound(t *testing.T) {
+	h := newRegionSearchHelper(map[string]*th.MockedEC2Svc{
+		"us-east-1": {},
+		"us-west-2": {
+			DescribeInstancesPagesError: awserr.New("InvalidInstanceID.NotFound", "Test error", nil),
+		},
+	})
+
+	_, err := h.FindInstanceRegion("i-12345")
+	th.Nok(t, err)
+	_, isNotFound := err.(*ec2helper.NotFoundError)
+	th.Assert(t, isNotFound, "Expected a not found error, got: "+err.Error())
+}
+
+func TestFindInstanceRegion_SearchError(t *testing.T) {
+	h := newRegionSearchHelper(map[string]*th.MockedEC2Svc{
+		"us-east-1": {},
+		"us-west-2": {
+			DescribeInstancesPagesError: errors.New("Test error"),
+		},
+	})
+
+	_, err := h.FindInstanceRegion("i-12345")
+	th.Nok(t, err)
+	_, isNotFound := err.(*ec2helper.NotFoundError)
+	th.Assert(t, !isNotFound, "Expected the search error, got a not found error")
+}
+
+func TestFindInstanceRegion_DescribeRegionsError(t *testing.T) {
+	h := &ec2helper.EC2Helper{
+		Svc: &th.MockedEC2Svc{
+			DescribeRegionsError: errors.New("Test error"),
+		},
+	}
+
+	_, err := h.FindInstanceRegion("i-12345")
+	th.Nok(t, err)
+}
+
 /*
 Availability Zone Tests
 */
@@ -151,11 +278,157 @@ func TestGetAvailableAvailabilityZones_NoResult(t *testing.T) {
 	th.Nok(t, err)
 }
 
+var testMixedZones = []*ec2.AvailabilityZone{
+	{
+		ZoneName: aws.String("us-west-2a"),
+		ZoneType: aws.String(ec2helper.ZoneTypeAvailabilityZone),
+	},
+	{
+		ZoneName: aws.String("us-west-2b"),
+	},
+	{
+		ZoneName: aws.String("us-west-2-lax-1a"),
+		ZoneType: aws.String(ec2helper.ZoneTypeLocalZone),
+	},
+	{
+		ZoneName: aws.String("us-west-2-wl1-sfo-wlz-1"),
+		ZoneType: aws.String(ec2helper.ZoneTypeWavelengthZone),
+	},
+}
+
+func TestGetAvailableAvailabilityZones_ZoneType(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		AvailabilityZones: testMixedZones,
+	}
+	defer func() {
+		testEC2.ZoneType = ""
+	}()
+
+	testEC2.ZoneType = ec2helper.ZoneTypeLocalZone
+	zones, err := testEC2.GetAvailableAvailabilityZones()
+	th.Ok(t, err)
+	th.Equals(t, []*ec2.AvailabilityZone{testMixedZones[2]}, zones)
+
+	// Zones without a type are availability zones
+	testEC2.ZoneType = ec2helper.ZoneTypeAvailabilityZone
+	zones, err = testEC2.GetAvailableAvailabilityZones()
+	th.Ok(t, err)
+	th.Equals(t, testMixedZones[:2], zones)
+
+	testEC2.ZoneType = ""
+	zones, err = testEC2.GetAvailableAvailabilityZones()
+	th.Ok(t, err)
+	th.Equals(t, testMixedZones, zones)
+}
+
+func TestGetAvailableAvailabilityZones_NoZoneOfType(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		AvailabilityZones: testMixedZones[:2],
+	}
+	testEC2.ZoneType = ec2helper.ZoneTypeWavelengthZone
+	defer func() {
+		testEC2.ZoneType = ""
+	}()
+
+	_, err := testEC2.GetAvailableAvailabilityZones()
+	th.Nok(t, err)
+}
+
+func TestGetZoneTypesByName(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		AvailabilityZones: testMixedZones,
+	}
+
+	zoneTypes, err := testEC2.GetZoneTypesByName()
+	th.Ok(t, err)
+	th.Equals(t, map[string]string{
+		"us-west-2a":              ec2helper.ZoneTypeAvailabilityZone,
+		"us-west-2b":              ec2helper.ZoneTypeAvailabilityZone,
+		"us-west-2-lax-1a":        ec2helper.ZoneTypeLocalZone,
+		"us-west-2-wl1-sfo-wlz-1": ec2helper.ZoneTypeWavelengthZone,
+	}, zoneTypes)
+}
+
+func TestValidateZoneType(t *testing.T) {
+	th.Ok(t, ec2helper.ValidateZoneType(""))
+	th.Ok(t, ec2helper.ValidateZoneType(ec2helper.ZoneTypeLocalZone))
+	th.Nok(t, ec2helper.ValidateZoneType("edge-zone"))
+}
+
+func TestGetZoneTypeWarning(t *testing.T) {
+	th.Equals(t, "", ec2helper.GetZoneTypeWarning("us-west-2a", ec2helper.ZoneTypeAvailabilityZone))
+	th.Assert(t, strings.Contains(ec2helper.GetZoneTypeWarning("us-west-2-lax-1a", ec2helper.ZoneTypeLocalZone),
+		"Local Zone"), "No Local Zone warning")
+	th.Assert(t, strings.Contains(ec2helper.GetZoneTypeWarning("us-west-2-wl1-sfo-wlz-1",
+		ec2helper.ZoneTypeWavelengthZone), "Wavelength Zone"), "No Wavelength Zone warning")
+}
+
+func TestValidateCarrierIpSubnet(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		AvailabilityZones: testMixedZones,
+	}
+
+	th.Ok(t, testEC2.ValidateCarrierIpSubnet(&ec2.Subnet{
+		SubnetId:         aws.String("subnet-12345"),
+		AvailabilityZone: aws.String("us-west-2-wl1-sfo-wlz-1"),
+	}))
+	th.Nok(t, testEC2.ValidateCarrierIpSubnet(&ec2.Subnet{
+		SubnetId:         aws.String("subnet-67890"),
+		AvailabilityZone: aws.String("us-west-2a"),
+	}))
+	th.Nok(t, testEC2.ValidateCarrierIpSubnet(&ec2.Subnet{
+		SubnetId:         aws.String("subnet-24680"),
+		AvailabilityZone: aws.String("us-west-2-lax-1a"),
+	}))
+}
+
+func TestGetAvailabilityZoneNameById_Success(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		AvailabilityZones: []*ec2.AvailabilityZone{
+			{ZoneName: aws.String("us-east-1a"), ZoneId: aws.String("use1-az4")},
+			{ZoneName: aws.String("us-east-1b"), ZoneId: aws.String("use1-az1")},
+		},
+	}
+
+	zoneName, err := testEC2.GetAvailabilityZoneNameById("use1-az1")
+	th.Ok(t, err)
+	th.Equals(t, "us-east-1b", zoneName)
+}
+
+func TestGetAvailabilityZoneNameById_NotFound(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		AvailabilityZones: []*ec2.AvailabilityZone{
+			{ZoneName: aws.String("us-east-1a"), ZoneId: aws.String("use1-az4")},
+		},
+	}
+
+	_, err := testEC2.GetAvailabilityZoneNameById("use1-az3")
+	th.Nok(t, err)
+	th.Assert(t, strings.Contains(err.Error(), "Did you mean \"use1-az4\"?"), "Error should suggest the closest zone id")
+}
+
+func TestGetAvailabilityZoneNameById_DescribeAvailabilityZonesError(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		DescribeAvailabilityZonesError: errors.New("Test error"),
+	}
+
+	_, err := testEC2.GetAvailabilityZoneNameById("use1-az1")
+	th.Nok(t, err)
+}
+
+func TestValidateAvailabilityZone(t *testing.T) {
+	th.Ok(t, ec2helper.ValidateAvailabilityZone("us-east-1a", ""))
+	th.Ok(t, ec2helper.ValidateAvailabilityZone("", "use1-az1"))
+	th.Ok(t, ec2helper.ValidateAvailabilityZone("", ""))
+	th.Nok(t, ec2helper.ValidateAvailabilityZone("us-east-1a", "use1-az1"))
+}
+
 /*
 Launch Template Tests
 */
 
 var testLaunchId = "lt-12345"
+var testClientToken = "test-client-token"
 
 func TestGetLaunchTemplatesInRegion_Success(t *testing.T) {
 	expectedTemplates := []*ec2.LaunchTemplate{
@@ -347,6 +620,28 @@ func TestGetLaunchTemplateVersions_DescribeLaunchTemplateVersionsPagesError(t *t
 	th.Nok(t, err)
 }
 
+func TestValidateLaunchTemplateVersion(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		LaunchTemplateVersions: []*ec2.LaunchTemplateVersion{
+			{
+				LaunchTemplateId: aws.String(testLaunchTemplateId),
+				VersionNumber:    aws.Int64(1),
+			},
+			{
+				LaunchTemplateId: aws.String(testLaunchTemplateId),
+				VersionNumber:    aws.Int64(2),
+			},
+		},
+	}
+
+	th.Ok(t, testEC2.ValidateLaunchTemplateVersion(testLaunchTemplateId, "2"))
+	th.Ok(t, testEC2.ValidateLaunchTemplateVersion(testLaunchTemplateId, ec2helper.LaunchTemplateVersionLatest))
+
+	err := testEC2.ValidateLaunchTemplateVersion(testLaunchTemplateId, "3")
+	th.Nok(t, err)
+	th.Equals(t, "Launch template lt-12345 has no version 3. Available versions: 1, 2", err.Error())
+}
+
 /*
 Instance Type Tests
 */
@@ -440,6 +735,16 @@ func TestGetInstanceType_NoResult(t *testing.T) {
 	th.Nok(t, err)
 }
 
+func TestGetInstanceType_Suggestion(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		InstanceTypes: testInstanceTypes,
+	}
+
+	_, err := testEC2.GetInstanceType("t2.nnao")
+	th.Nok(t, err)
+	th.Assert(t, strings.HasSuffix(err.Error(), "Did you mean \"t2.nano\"?"), "No suggestion in: "+err.Error())
+}
+
 func TestGetInstanceType_DescribeInstanceTypesPagesError(t *testing.T) {
 	testEC2.Svc = &th.MockedEC2Svc{
 		DescribeInstanceTypesPagesError: errors.New("Test error"),
@@ -449,6 +754,32 @@ func TestGetInstanceType_DescribeInstanceTypesPagesError(t *testing.T) {
 	th.Nok(t, err)
 }
 
+// A config saved with a type offered in its region, used after overriding the region with one not offering it
+func TestValidateInstanceTypeInRegion_RegionOverride(t *testing.T) {
+	testEC2.ChangeRegion("us-west-2")
+	defer testEC2.ChangeRegion(testRegion)
+	testEC2.Svc = &th.MockedEC2Svc{
+		InstanceTypeOfferings: []*ec2.InstanceTypeOffering{
+			{InstanceType: aws.String("m5.large"), LocationType: aws.String(ec2.LocationTypeRegion)},
+			{InstanceType: aws.String("t3.micro"), LocationType: aws.String(ec2.LocationTypeRegion)},
+		},
+	}
+
+	th.Ok(t, testEC2.ValidateInstanceTypeInRegion("t3.micro"))
+
+	err := testEC2.ValidateInstanceTypeInRegion("m6.large")
+	th.Nok(t, err)
+	th.Equals(t, "Instance type m6.large is not offered in region us-west-2. Did you mean \"m5.large\"?", err.Error())
+}
+
+func TestValidateInstanceTypeInRegion_DescribeInstanceTypeOfferingsPagesError(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		DescribeInstanceTypeOfferingsPagesError: errors.New("Test error"),
+	}
+
+	th.Nok(t, testEC2.ValidateInstanceTypeInRegion(testInstanceType))
+}
+
 /*
 Instance Selector Tests
 */
@@ -469,32 +800,138 @@ var selector = &th.MockedSelector{
 	InstanceTypes: testInstanceTypeInfos,
 }
 
-func TestGetInstanceTypesFromInstanceSelector_Success(t *testing.T) {
-	actualInstanceTypes, err := testEC2.GetInstanceTypesFromInstanceSelector(selector, 2, 4)
+func TestGetInstanceSelectorFilters_Success(t *testing.T) {
+	filters, err := ec2helper.GetInstanceSelectorFilters(2, 4)
 	th.Ok(t, err)
-	th.Equals(t, testInstanceTypeInfos, actualInstanceTypes)
+	th.Equals(t, 1, filters.VCpusRange.LowerBound)
+	th.Equals(t, 3, filters.VCpusRange.UpperBound)
+	th.Equals(t, bytequantity.FromGiB(3), filters.MemoryRange.LowerBound)
+	th.Equals(t, bytequantity.FromGiB(5), filters.MemoryRange.UpperBound)
+	th.Assert(t, filters.CPUArchitecture == nil, "Instance types of any architecture should be selected")
+}
+
+func TestGetInstanceSelectorFilters_BadVCpus(t *testing.T) {
+	_, err := ec2helper.GetInstanceSelectorFilters(-1, 4)
+	th.Nok(t, err)
+}
+
+func TestGetInstanceSelectorFilters_BadMemory(t *testing.T) {
+	_, err := ec2helper.GetInstanceSelectorFilters(2, -1)
+	th.Nok(t, err)
+}
+
+func TestGetRequirementsFilters_AllRequirements(t *testing.T) {
+	requirements, err := config.ReadInstanceRequirements(writeRequirementsFile(t, testRequirementsYaml))
+	th.Ok(t, err)
+
+	filters, err := ec2helper.GetRequirementsFilters(requirements)
+	th.Ok(t, err)
+	th.Equals(t, 2, filters.VCpusRange.LowerBound)
+	th.Equals(t, 4, filters.VCpusRange.UpperBound)
+	th.Equals(t, bytequantity.FromMiB(1536), filters.MemoryRange.LowerBound)
+	th.Equals(t, bytequantity.FromGiB(8), filters.MemoryRange.UpperBound)
+	th.Equals(t, "arm64", *filters.CPUArchitecture)
+	th.Equals(t, 0, filters.GpusRange.LowerBound)
+	th.Equals(t, 0, filters.GpusRange.UpperBound)
+	th.Equals(t, false, *filters.Burstable)
+	th.Assert(t, filters.AllowList.MatchString("t4g.micro"), "Allowed family should match")
+	th.Assert(t, filters.AllowList.MatchString("m6g.large"), "Allowed family should match")
+	th.Assert(t, !filters.AllowList.MatchString("m6gd.large"), "Family prefix should not match")
+	th.Assert(t, !filters.AllowList.MatchString("c5.large"), "Other family should not match")
+}
+
+func TestGetRequirementsFilters_Defaults(t *testing.T) {
+	filters, err := ec2helper.GetRequirementsFilters(&config.InstanceRequirements{
+		VCpus: &config.IntRange{Min: 8},
+	})
+	th.Ok(t, err)
+	th.Equals(t, 8, filters.VCpusRange.LowerBound)
+	th.Assert(t, filters.VCpusRange.UpperBound > 8, "A range without max should have no upper bound")
+	th.Assert(t, filters.CPUArchitecture == nil, "Instance types of any architecture should be selected")
+	th.Assert(t, filters.MemoryRange == nil, "Memory should not be filtered")
+	th.Assert(t, filters.GpusRange == nil, "GPUs should not be filtered")
+	th.Assert(t, filters.AllowList == nil, "Families should not be filtered")
+	th.Assert(t, filters.Burstable == nil, "Burstable instance types should be allowed")
 }
 
-func TestGetInstanceTypesFromInstanceSelector_BadVCpus(t *testing.T) {
-	_, err := testEC2.GetInstanceTypesFromInstanceSelector(selector, -1, 4)
+func TestGetRequirementsFilters_BadRange(t *testing.T) {
+	max := 2
+	_, err := ec2helper.GetRequirementsFilters(&config.InstanceRequirements{
+		VCpus: &config.IntRange{Min: 4, Max: &max},
+	})
 	th.Nok(t, err)
 }
 
-func TestGetInstanceTypesFromInstanceSelector_BadMemory(t *testing.T) {
-	_, err := testEC2.GetInstanceTypesFromInstanceSelector(selector, 2, -1)
+func TestGetRequirementsFilters_BadMemory(t *testing.T) {
+	_, err := ec2helper.GetRequirementsFilters(&config.InstanceRequirements{
+		MemoryGib: &config.FloatRange{Min: -1},
+	})
 	th.Nok(t, err)
 }
 
+func TestGetInstanceTypesFromInstanceSelector_Success(t *testing.T) {
+	filters, err := ec2helper.GetInstanceSelectorFilters(2, 4)
+	th.Ok(t, err)
+
+	actualInstanceTypes, err := testEC2.GetInstanceTypesFromInstanceSelector(selector, filters)
+	th.Ok(t, err)
+	th.Equals(t, testInstanceTypeInfos, actualInstanceTypes)
+}
+
+func TestGetInstanceTypesFromInstanceSelector_RequirementsFile(t *testing.T) {
+	requirements, err := config.ReadInstanceRequirements(writeRequirementsFile(t, testRequirementsYaml))
+	th.Ok(t, err)
+	filters, err := ec2helper.GetRequirementsFilters(requirements)
+	th.Ok(t, err)
+
+	// The requirements don't allow burstable instance types, so only the M family type is selected
+	nonBurstableType := &instancetypes.Details{
+		InstanceTypeInfo: ec2.InstanceTypeInfo{
+			InstanceType: aws.String("m6g.large"),
+		},
+	}
+	requirementsSelector := &th.MockedSelector{
+		InstanceTypes: append(append([]*instancetypes.Details{}, testInstanceTypeInfos...), nonBurstableType),
+	}
+
+	actualInstanceTypes, err := testEC2.GetInstanceTypesFromInstanceSelector(requirementsSelector, filters)
+	th.Ok(t, err)
+	th.Equals(t, []*instancetypes.Details{nonBurstableType}, actualInstanceTypes)
+}
+
 func TestGetInstanceTypesFromInstanceSelector_SelectorError(t *testing.T) {
 	selector = &th.MockedSelector{
 		InstanceTypes: testInstanceTypeInfos,
 		SelectorError: errors.New("Test error"),
 	}
+	filters, err := ec2helper.GetInstanceSelectorFilters(2, 4)
+	th.Ok(t, err)
 
-	_, err := testEC2.GetInstanceTypesFromInstanceSelector(selector, 2, 4)
+	_, err = testEC2.GetInstanceTypesFromInstanceSelector(selector, filters)
 	th.Nok(t, err)
 }
 
+const testRequirementsYaml = `
+vcpus:
+  min: 2
+  max: 4
+memoryGib:
+  min: 1.5
+  max: 8
+architecture: arm64
+gpus:
+  max: 0
+allowedFamilies: [t4g, m6g]
+burstableOk: false
+`
+
+// writeRequirementsFile writes the given requirements to a temporary file and returns its path
+func writeRequirementsFile(t *testing.T, requirements string) string {
+	path := t.TempDir() + "/requirements.yaml"
+	th.Ok(t, ioutil.WriteFile(path, []byte(requirements), 0644))
+	return path
+}
+
 /*
 Image Tests
 */
@@ -557,6 +994,92 @@ func TestGetLatestImages_DescribeImagesError(t *testing.T) {
 	th.Nok(t, err)
 }
 
+func TestGetLatestImages_SingleOsDescribeImagesError(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		Images:                         testImages,
+		DescribeImagesError:            errors.New("Test error"),
+		DescribeImagesErrorNamePattern: "suse-sles-*",
+	}
+
+	// The other operating systems succeed, but the failed one still fails the lookup
+	actualImages, err := testEC2.GetLatestImages(nil, defaultArchitecture)
+	th.Nok(t, err)
+	th.Equals(t, "Test error", err.Error())
+	th.Assert(t, actualImages == nil, "No images should be returned")
+}
+
+func TestValidateRootDeviceType(t *testing.T) {
+	for _, rootDeviceType := range []string{"", ec2.DeviceTypeEbs, ec2.DeviceTypeInstanceStore} {
+		th.Ok(t, ec2helper.ValidateRootDeviceType(rootDeviceType))
+	}
+	th.Nok(t, ec2helper.ValidateRootDeviceType("nvme"))
+}
+
+func TestGetRootDeviceType(t *testing.T) {
+	storageType := &ec2.InstanceTypeInfo{
+		InstanceType:             aws.String("m5d.large"),
+		InstanceStorageSupported: aws.Bool(true),
+	}
+	ebsOnlyType := &ec2.InstanceTypeInfo{
+		InstanceType:             aws.String("m5.large"),
+		InstanceStorageSupported: aws.Bool(false),
+	}
+
+	// Instance store is picked when supported, unless EBS is forced
+	h := &ec2helper.EC2Helper{}
+	rootDeviceType, err := h.GetRootDeviceType(storageType)
+	th.Ok(t, err)
+	th.Equals(t, ec2.DeviceTypeInstanceStore, rootDeviceType)
+	rootDeviceType, err = h.GetRootDeviceType(ebsOnlyType)
+	th.Ok(t, err)
+	th.Equals(t, ec2.DeviceTypeEbs, rootDeviceType)
+
+	h.RootDeviceType = ec2.DeviceTypeEbs
+	rootDeviceType, err = h.GetRootDeviceType(storageType)
+	th.Ok(t, err)
+	th.Equals(t, ec2.DeviceTypeEbs, rootDeviceType)
+
+	// Instance store can't be forced on an instance type without instance storage
+	h.RootDeviceType = ec2.DeviceTypeInstanceStore
+	_, err = h.GetRootDeviceType(ebsOnlyType)
+	th.Nok(t, err)
+}
+
+func TestGetLatestImages_Paginated(t *testing.T) {
+	newestImage := &ec2.Image{
+		ImageId:      aws.String("ami-newest"),
+		CreationDate: aws.String("2024-03-01T00:00:00.000Z"),
+	}
+	mockedSvc := &th.MockedEC2Svc{
+		Images: []*ec2.Image{
+			{
+				ImageId:      aws.String("ami-older"),
+				CreationDate: aws.String("2024-01-01T00:00:00.000Z"),
+			},
+			newestImage,
+			{
+				ImageId:      aws.String("ami-old"),
+				CreationDate: aws.String("2024-02-01T00:00:00.000Z"),
+			},
+		},
+		ImagesPageSize: 1,
+	}
+	testEC2.Svc = mockedSvc
+
+	// The newest image is on the second of three pages
+	actualImages, err := testEC2.GetLatestImages(nil, defaultArchitecture)
+	th.Ok(t, err)
+	th.Equals(t, len(testMapEbs), len(*actualImages))
+	for _, image := range *actualImages {
+		th.Equals(t, newestImage, image)
+	}
+	for _, input := range mockedSvc.DescribeImagesInputs {
+		if input.ImageIds == nil {
+			th.Equals(t, int64(1000), *input.MaxResults)
+		}
+	}
+}
+
 func TestGetDefaultImage_Success(t *testing.T) {
 	testEC2.Svc = &th.MockedEC2Svc{
 		Images: testImages,
@@ -585,6 +1108,38 @@ func TestGetDefaultImage_DescribeImagesError(t *testing.T) {
 	th.Nok(t, err)
 }
 
+func TestGetDefaultImage_Stale(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		Images: []*ec2.Image{
+			{
+				ImageId:      aws.String("ami-12345"),
+				CreationDate: aws.String(time.Now().AddDate(0, 0, -100).Format(time.RFC3339)),
+			},
+		},
+	}
+	testEC2.ImageMaxAge = 30 * 24 * time.Hour
+	defer func() { testEC2.ImageMaxAge = 0 }()
+
+	_, err := testEC2.GetDefaultImage(nil, defaultArchitecture)
+	th.Nok(t, err)
+}
+
+func TestValidateImageFreshness(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	maxAge := 30 * 24 * time.Hour
+	freshImage := &ec2.Image{ImageId: aws.String("ami-12345"), CreationDate: aws.String("2024-05-20T10:00:00.000Z")}
+	staleImage := &ec2.Image{ImageId: aws.String("ami-67890"), CreationDate: aws.String("2024-04-01T10:00:00.000Z")}
+
+	th.Ok(t, ec2helper.ValidateImageFreshness(freshImage, maxAge, now))
+	th.Nok(t, ec2helper.ValidateImageFreshness(staleImage, maxAge, now))
+
+	// Without a limit, any image is fresh
+	th.Ok(t, ec2helper.ValidateImageFreshness(staleImage, 0, now))
+
+	// An image without a creation date can't be checked
+	th.Nok(t, ec2helper.ValidateImageFreshness(&ec2.Image{ImageId: aws.String("ami-12345")}, maxAge, now))
+}
+
 func TestGetImageById_Success(t *testing.T) {
 	const testAmi = "ami-12345"
 	testEC2.Svc = &th.MockedEC2Svc{
@@ -728,26 +1283,66 @@ func TestGetSubnetsByVpc_NoResult(t *testing.T) {
 	th.Nok(t, err)
 }
 
-func TestGetSubnetById_Success(t *testing.T) {
-	const testSubnetId = "subnet-12345"
+func TestGetSubnetInVpcByAz_Success(t *testing.T) {
+	const testVpcId = "vpc-12345"
+	const testAz = "us-east-1b"
 	testSubnets := []*ec2.Subnet{
 		{
-			SubnetId: aws.String(testSubnetId),
+			SubnetId:         aws.String("subnet-12345"),
+			VpcId:            aws.String(testVpcId),
+			AvailabilityZone: aws.String("us-east-1a"),
+		},
+		{
+			SubnetId:         aws.String("subnet-67890"),
+			VpcId:            aws.String(testVpcId),
+			AvailabilityZone: aws.String(testAz),
 		},
 	}
 	testEC2.Svc = &th.MockedEC2Svc{
 		Subnets: testSubnets,
 	}
 
-	actualSubnet, err := testEC2.GetSubnetById(testSubnetId)
+	actualSubnet, err := testEC2.GetSubnetInVpcByAz(testVpcId, testAz)
 	th.Ok(t, err)
-	th.Equals(t, testSubnetId, *actualSubnet.SubnetId)
+	th.Equals(t, testSubnets[1], actualSubnet)
 }
 
-func TestGetSubnetById_DescribeSubnetsPagesError(t *testing.T) {
+func TestGetSubnetInVpcByAz_NoMatch(t *testing.T) {
+	const testVpcId = "vpc-12345"
 	testEC2.Svc = &th.MockedEC2Svc{
-		DescribeSubnetsPagesError: errors.New("Test error"),
-	}
+		Subnets: []*ec2.Subnet{
+			{
+				SubnetId:         aws.String("subnet-12345"),
+				VpcId:            aws.String(testVpcId),
+				AvailabilityZone: aws.String("us-east-1a"),
+			},
+		},
+	}
+
+	_, err := testEC2.GetSubnetInVpcByAz(testVpcId, "us-east-1b")
+	th.Nok(t, err)
+}
+
+func TestGetSubnetById_Success(t *testing.T) {
+	const testSubnetId = "subnet-12345"
+	testSubnets := []*ec2.Subnet{
+		{
+			SubnetId: aws.String(testSubnetId),
+		},
+	}
+	testEC2.Svc = &th.MockedEC2Svc{
+		Subnets: testSubnets,
+	}
+
+	actualSubnet, err := testEC2.GetSubnetById(testSubnetId)
+	th.Ok(t, err)
+	th.Equals(t, testSubnetId, *actualSubnet.SubnetId)
+}
+
+func TestGetSubnetById_DescribeSubnetsPagesError(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		DescribeSubnetsPagesError: errors.New("Test error"),
+	}
 
 	_, err := testEC2.GetSubnetById("")
 	th.Nok(t, err)
@@ -832,8 +1427,79 @@ func TestGetSecurityGroupsByVpc_NoResult(t *testing.T) {
 }
 
 func TestCreateSecurityGroupForSsh_Success(t *testing.T) {
-	_, err := testEC2.CreateSecurityGroupForSsh("")
+	_, err := testEC2.CreateSecurityGroupForSsh("", 22, "")
+	th.Ok(t, err)
+}
+
+func TestCreateSecurityGroupForSsh_NonDefaultPort(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	_, err := testEC2.CreateSecurityGroupForSsh("vpc-12345", 2222, "10.0.0.0/16")
 	th.Ok(t, err)
+	th.Equals(t, 1, len(mockedSvc.AuthorizeSecurityGroupIngressInputs))
+	permissions := mockedSvc.AuthorizeSecurityGroupIngressInputs[0].IpPermissions
+	th.Equals(t, 1, len(permissions))
+	th.Equals(t, int64(2222), *permissions[0].FromPort)
+	th.Equals(t, int64(2222), *permissions[0].ToPort)
+	th.Equals(t, []*ec2.IpRange{{
+		CidrIp:      aws.String("10.0.0.0/16"),
+		Description: aws.String("SSH from 10.0.0.0/16 via simple-ec2"),
+	}}, permissions[0].IpRanges)
+	th.Equals(t, 0, len(permissions[0].Ipv6Ranges))
+}
+
+func TestCreateSecurityGroupForSsh_AnyAddress(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	_, err := testEC2.CreateSecurityGroupForSsh("vpc-12345", 22, "")
+	th.Ok(t, err)
+	permission := mockedSvc.AuthorizeSecurityGroupIngressInputs[0].IpPermissions[0]
+	th.Equals(t, int64(22), *permission.FromPort)
+	th.Equals(t, []*ec2.IpRange{{
+		CidrIp:      aws.String("0.0.0.0/0"),
+		Description: aws.String("SSH from 0.0.0.0/0 via simple-ec2"),
+	}}, permission.IpRanges)
+	th.Equals(t, []*ec2.Ipv6Range{{
+		CidrIpv6:    aws.String("::/0"),
+		Description: aws.String("SSH from ::/0 via simple-ec2"),
+	}}, permission.Ipv6Ranges)
+}
+
+func TestCreateSecurityGroupForSsh_RuleDescription(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+	testEC2.SecurityGroupRuleDescription = "SSH for the benchmark team"
+	defer func() { testEC2.SecurityGroupRuleDescription = "" }()
+
+	_, err := testEC2.CreateSecurityGroupForSsh("vpc-12345", 22, "2001:db8::/32")
+	th.Ok(t, err)
+	permission := mockedSvc.AuthorizeSecurityGroupIngressInputs[0].IpPermissions[0]
+	th.Equals(t, "SSH for the benchmark team", *permission.Ipv6Ranges[0].Description)
+}
+
+func TestValidateSecurityGroupRuleDescription(t *testing.T) {
+	th.Ok(t, ec2helper.ValidateSecurityGroupRuleDescription(""))
+	th.Ok(t, ec2helper.ValidateSecurityGroupRuleDescription("SSH from the office (team: ops) #42"))
+	th.Nok(t, ec2helper.ValidateSecurityGroupRuleDescription("SSH from the café"))
+	th.Nok(t, ec2helper.ValidateSecurityGroupRuleDescription(strings.Repeat("a", 256)))
+}
+
+func TestCreateSecurityGroupForSsh_InvalidPort(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	_, err := testEC2.CreateSecurityGroupForSsh("vpc-12345", 70000, "")
+	th.Nok(t, err)
+	th.Equals(t, 0, len(mockedSvc.CreateSecurityGroupInputs))
+}
+
+func TestValidateSshPort(t *testing.T) {
+	th.Ok(t, ec2helper.ValidateSshPort(22))
+	th.Ok(t, ec2helper.ValidateSshPort(65535))
+	th.Nok(t, ec2helper.ValidateSshPort(0))
+	th.Nok(t, ec2helper.ValidateSshPort(65536))
 }
 
 func TestCreateSecurityGroupForSsh_CreateSecurityGroupError(t *testing.T) {
@@ -841,7 +1507,7 @@ func TestCreateSecurityGroupForSsh_CreateSecurityGroupError(t *testing.T) {
 		CreateSecurityGroupError: errors.New("Test error"),
 	}
 
-	_, err := testEC2.CreateSecurityGroupForSsh("")
+	_, err := testEC2.CreateSecurityGroupForSsh("", 22, "")
 	th.Nok(t, err)
 }
 
@@ -850,7 +1516,7 @@ func TestCreateSecurityGroupForSsh_AuthorizeSecurityGroupIngressError(t *testing
 		AuthorizeSecurityGroupIngressError: errors.New("Test error"),
 	}
 
-	_, err := testEC2.CreateSecurityGroupForSsh("")
+	_, err := testEC2.CreateSecurityGroupForSsh("", 22, "")
 	th.Nok(t, err)
 }
 
@@ -859,8 +1525,67 @@ func TestCreateSecurityGroupForSsh_CreateTagsError(t *testing.T) {
 		CreateTagsError: errors.New("Test error"),
 	}
 
-	_, err := testEC2.CreateSecurityGroupForSsh("")
+	_, err := testEC2.CreateSecurityGroupForSsh("", 22, "")
+	th.Nok(t, err)
+}
+
+var testVpcSecurityGroups = []*ec2.SecurityGroup{
+	{GroupId: aws.String("sg-67890"), GroupName: aws.String("web")},
+	{GroupId: aws.String("sg-abcde"), GroupName: aws.String("db")},
+}
+
+func TestGetOrCreateSecurityGroups_ExistingIdsAndNames(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{
+		SecurityGroups: testVpcSecurityGroups,
+	}
+	testEC2.Svc = mockedSvc
+
+	groupIds, err := testEC2.GetOrCreateSecurityGroups("vpc-12345", []string{"sg-67890", "db"}, 22)
+	th.Ok(t, err)
+	th.Equals(t, []string{"sg-67890", "sg-abcde"}, groupIds)
+	th.Equals(t, 0, len(mockedSvc.CreateSecurityGroupInputs))
+}
+
+func TestGetOrCreateSecurityGroups_CreateByName(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{
+		SecurityGroups: testVpcSecurityGroups,
+	}
+	testEC2.Svc = mockedSvc
+
+	groupIds, err := testEC2.GetOrCreateSecurityGroups("vpc-12345", []string{"sg-67890", "bastion"}, 22)
+	th.Ok(t, err)
+	th.Equals(t, []string{"sg-67890", "sg-12345"}, groupIds)
+	th.Equals(t, 1, len(mockedSvc.CreateSecurityGroupInputs))
+	th.Equals(t, "bastion", *mockedSvc.CreateSecurityGroupInputs[0].GroupName)
+	th.Equals(t, "vpc-12345", *mockedSvc.CreateSecurityGroupInputs[0].VpcId)
+}
+
+func TestGetOrCreateSecurityGroups_MissingId(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{
+		SecurityGroups: testVpcSecurityGroups,
+	}
+	testEC2.Svc = mockedSvc
+
+	_, err := testEC2.GetOrCreateSecurityGroups("vpc-12345", []string{"sg-00000"}, 22)
+	th.Nok(t, err)
+	th.Equals(t, 0, len(mockedSvc.CreateSecurityGroupInputs))
+}
+
+func TestGetOrCreateSecurityGroups_InvalidName(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	_, err := testEC2.GetOrCreateSecurityGroups("vpc-12345", []string{"web<admin>"}, 22)
 	th.Nok(t, err)
+	th.Equals(t, 0, len(mockedSvc.CreateSecurityGroupInputs))
+}
+
+func TestValidateSecurityGroupName(t *testing.T) {
+	th.Ok(t, ec2helper.ValidateSecurityGroupName("web servers (prod)"))
+	th.Nok(t, ec2helper.ValidateSecurityGroupName(""))
+	th.Nok(t, ec2helper.ValidateSecurityGroupName(strings.Repeat("a", 256)))
+	th.Nok(t, ec2helper.ValidateSecurityGroupName("sg-web"))
+	th.Nok(t, ec2helper.ValidateSecurityGroupName("web<admin>"))
 }
 
 /*
@@ -1050,7 +1775,225 @@ func TestParseConfig_Success(t *testing.T) {
 	th.Equals(t, testInstanceType, *actualDetailedConfig.InstanceTypeInfo.InstanceType)
 }
 
+var inheritVpcTagsSvc = &th.MockedEC2Svc{
+	Subnets: []*ec2.Subnet{
+		{
+			SubnetId: aws.String(testSubnetId),
+			VpcId:    aws.String(testVpcId),
+		},
+	},
+	Vpcs: []*ec2.Vpc{
+		{
+			VpcId: aws.String(testVpcId),
+			Tags: []*ec2.Tag{
+				{Key: aws.String("CostCenter"), Value: aws.String("vpc-cost-center")},
+				{Key: aws.String("Project"), Value: aws.String("vpc-project")},
+				{Key: aws.String("Team"), Value: aws.String("vpc-team")},
+			},
+		},
+	},
+	Images: []*ec2.Image{
+		{
+			ImageId:        aws.String(testImageId),
+			RootDeviceType: aws.String(testDeviceType),
+		},
+	},
+	InstanceTypes: []*ec2.InstanceTypeInfo{
+		{
+			InstanceType: aws.String(testInstanceType),
+		},
+	},
+	SecurityGroups: []*ec2.SecurityGroup{
+		{
+			GroupId: aws.String(testSecurityGroupIds[0]),
+		},
+	},
+}
+
+func TestParseConfig_InheritVpcTags(t *testing.T) {
+	testEC2.Svc = inheritVpcTagsSvc
+	simpleConfig := &config.SimpleInfo{
+		SubnetId:            testSubnetId,
+		ImageId:             testImageId,
+		InstanceType:        testInstanceType,
+		SecurityGroupIds:    testSecurityGroupIds,
+		InheritedVpcTagKeys: []string{"CostCenter", "Project", "Missing"},
+	}
+
+	actualDetailedConfig, err := testEC2.ParseConfig(simpleConfig)
+	th.Ok(t, err)
+
+	instanceTags := map[string]string{}
+	for _, tag := range actualDetailedConfig.TagSpecs[0].Tags {
+		instanceTags[*tag.Key] = *tag.Value
+	}
+	th.Equals(t, "vpc-cost-center", instanceTags["CostCenter"])
+	th.Equals(t, "vpc-project", instanceTags["Project"])
+	_, found := instanceTags["Team"]
+	th.Equals(t, false, found)
+	_, found = instanceTags["Missing"]
+	th.Equals(t, false, found)
+}
+
+func TestParseConfig_InheritVpcTagsExplicitTagsWin(t *testing.T) {
+	testEC2.Svc = inheritVpcTagsSvc
+	simpleConfig := &config.SimpleInfo{
+		SubnetId:            testSubnetId,
+		ImageId:             testImageId,
+		InstanceType:        testInstanceType,
+		SecurityGroupIds:    testSecurityGroupIds,
+		UserTags:            map[string]string{"CostCenter": "user-cost-center"},
+		InheritedVpcTagKeys: []string{"CostCenter"},
+	}
+
+	actualDetailedConfig, err := testEC2.ParseConfig(simpleConfig)
+	th.Ok(t, err)
+
+	costCenters := []string{}
+	for _, tag := range actualDetailedConfig.TagSpecs[0].Tags {
+		if *tag.Key == "CostCenter" {
+			costCenters = append(costCenters, *tag.Value)
+		}
+	}
+	th.Equals(t, []string{"user-cost-center"}, costCenters)
+}
+
+func TestParseConfig_InheritVpcTagsNewVpc(t *testing.T) {
+	testEC2.Svc = inheritVpcTagsSvc
+	simpleConfig := &config.SimpleInfo{
+		ImageId:             testImageId,
+		InstanceType:        testInstanceType,
+		NewVPC:              true,
+		InheritedVpcTagKeys: []string{"CostCenter"},
+	}
+
+	_, err := testEC2.ParseConfig(simpleConfig)
+	th.Nok(t, err)
+}
+
+func TestParseConfig_NoPublicIp(t *testing.T) {
+	testEC2.Svc = inheritVpcTagsSvc
+	simpleConfig := &config.SimpleInfo{
+		SubnetId:         testSubnetId,
+		ImageId:          testImageId,
+		InstanceType:     testInstanceType,
+		SecurityGroupIds: testSecurityGroupIds,
+		NoPublicIp:       true,
+	}
+
+	// The instances are tagged for simple-ec2 connect to use Session Manager
+	actualDetailedConfig, err := testEC2.ParseConfig(simpleConfig)
+	th.Ok(t, err)
+	instanceTags := map[string]string{}
+	for _, instanceTag := range actualDetailedConfig.TagSpecs[0].Tags {
+		instanceTags[*instanceTag.Key] = *instanceTag.Value
+	}
+	th.Equals(t, tag.ConnectWithSsm, instanceTags[tag.ConnectWithKey])
+
+	// The subnets of a new VPC have no route to Systems Manager without public IP addresses
+	_, err = testEC2.ParseConfig(&config.SimpleInfo{
+		ImageId:      testImageId,
+		InstanceType: testInstanceType,
+		NewVPC:       true,
+		NoPublicIp:   true,
+	})
+	th.Nok(t, err)
+}
+
+func getTagSpecResourceTypes(tagSpecs []*ec2.TagSpecification) []string {
+	resourceTypes := []string{}
+	for _, tagSpec := range tagSpecs {
+		resourceTypes = append(resourceTypes, *tagSpec.ResourceType)
+	}
+	return resourceTypes
+}
+
+func TestParseConfig_DefaultTagResourceTypes(t *testing.T) {
+	testEC2.Svc = inheritVpcTagsSvc
+	simpleConfig := &config.SimpleInfo{
+		SubnetId:         testSubnetId,
+		ImageId:          testImageId,
+		InstanceType:     testInstanceType,
+		SecurityGroupIds: testSecurityGroupIds,
+	}
+
+	actualDetailedConfig, err := testEC2.ParseConfig(simpleConfig)
+	th.Ok(t, err)
+	th.Equals(t, []string{"instance", "volume", "network-interface"},
+		getTagSpecResourceTypes(actualDetailedConfig.TagSpecs))
+}
+
+func TestParseConfig_TagResourceTypes(t *testing.T) {
+	testEC2.Svc = inheritVpcTagsSvc
+	simpleConfig := &config.SimpleInfo{
+		SubnetId:         testSubnetId,
+		ImageId:          testImageId,
+		InstanceType:     testInstanceType,
+		SecurityGroupIds: testSecurityGroupIds,
+		CapacityType:     "Spot",
+		TagResourceTypes: []string{"spot-instances-request", "instance"},
+	}
+
+	actualDetailedConfig, err := testEC2.ParseConfig(simpleConfig)
+	th.Ok(t, err)
+	th.Equals(t, []string{"instance", "spot-instances-request"},
+		getTagSpecResourceTypes(actualDetailedConfig.TagSpecs))
+
+	// Spot instance requests are not tagged for On-Demand instances
+	simpleConfig.CapacityType = "On-Demand"
+	actualDetailedConfig, err = testEC2.ParseConfig(simpleConfig)
+	th.Ok(t, err)
+	th.Equals(t, []string{"instance"}, getTagSpecResourceTypes(actualDetailedConfig.TagSpecs))
+}
+
+func TestParseConfig_TagResourceTypesInstanceStore(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		Subnets:       inheritVpcTagsSvc.Subnets,
+		Vpcs:          inheritVpcTagsSvc.Vpcs,
+		InstanceTypes: inheritVpcTagsSvc.InstanceTypes,
+		Images: []*ec2.Image{
+			{
+				ImageId:        aws.String(testImageId),
+				RootDeviceType: aws.String("instance-store"),
+			},
+		},
+		SecurityGroups: inheritVpcTagsSvc.SecurityGroups,
+	}
+	simpleConfig := &config.SimpleInfo{
+		SubnetId:         testSubnetId,
+		ImageId:          testImageId,
+		InstanceType:     testInstanceType,
+		SecurityGroupIds: testSecurityGroupIds,
+		TagResourceTypes: []string{"volume", "network-interface"},
+	}
+
+	actualDetailedConfig, err := testEC2.ParseConfig(simpleConfig)
+	th.Ok(t, err)
+	th.Equals(t, []string{"network-interface"}, getTagSpecResourceTypes(actualDetailedConfig.TagSpecs))
+}
+
+func TestParseConfig_InvalidTagResourceTypes(t *testing.T) {
+	testEC2.Svc = inheritVpcTagsSvc
+	simpleConfig := &config.SimpleInfo{
+		SubnetId:         testSubnetId,
+		ImageId:          testImageId,
+		InstanceType:     testInstanceType,
+		SecurityGroupIds: testSecurityGroupIds,
+		TagResourceTypes: []string{"instance", "snapshot"},
+	}
+
+	_, err := testEC2.ParseConfig(simpleConfig)
+	th.Nok(t, err)
+}
+
+func TestValidateTagResourceTypes(t *testing.T) {
+	th.Ok(t, ec2helper.ValidateTagResourceTypes(nil))
+	th.Ok(t, ec2helper.ValidateTagResourceTypes(ec2helper.TagResourceTypes))
+	th.Nok(t, ec2helper.ValidateTagResourceTypes([]string{"image"}))
+}
+
 func TestParseConfig_DescribeInstanceTypesPagesError(t *testing.T) {
+	testEC2.Svc = parseConfigSvc
 	parseConfigSvc.DescribeInstanceTypesPagesError = errors.New("Test error")
 
 	_, err := testEC2.ParseConfig(&testSimpleConfig)
@@ -1058,6 +2001,7 @@ func TestParseConfig_DescribeInstanceTypesPagesError(t *testing.T) {
 }
 
 func TestParseConfig_DescribeImagesError(t *testing.T) {
+	testEC2.Svc = parseConfigSvc
 	parseConfigSvc.DescribeImagesError = errors.New("Test error")
 
 	_, err := testEC2.ParseConfig(&testSimpleConfig)
@@ -1065,6 +2009,7 @@ func TestParseConfig_DescribeImagesError(t *testing.T) {
 }
 
 func TestParseConfig_DescribeSecurityGroupsPagesError(t *testing.T) {
+	testEC2.Svc = parseConfigSvc
 	parseConfigSvc.DescribeSecurityGroupsPagesError = errors.New("Test error")
 
 	_, err := testEC2.ParseConfig(&testSimpleConfig)
@@ -1072,6 +2017,7 @@ func TestParseConfig_DescribeSecurityGroupsPagesError(t *testing.T) {
 }
 
 func TestParseConfig_DescribeVpcsPagesError(t *testing.T) {
+	testEC2.Svc = parseConfigSvc
 	parseConfigSvc.DescribeVpcsPagesError = errors.New("Test error")
 
 	_, err := testEC2.ParseConfig(&testSimpleConfig)
@@ -1079,6 +2025,7 @@ func TestParseConfig_DescribeVpcsPagesError(t *testing.T) {
 }
 
 func TestParseConfig_DescribeSubnetsPagesError(t *testing.T) {
+	testEC2.Svc = parseConfigSvc
 	parseConfigSvc.DescribeSubnetsPagesError = errors.New("Test error")
 
 	_, err := testEC2.ParseConfig(&testSimpleConfig)
@@ -1121,63 +2068,194 @@ var defaultConfigSvc = &th.MockedEC2Svc{
 	},
 }
 
+const testSavedConfigFileName = "unit_test_saved_config_temp.json"
+
+func TestGetSavedOrDefaultConfig_Saved(t *testing.T) {
+	path, err := config.SaveInConfigFolder(testSavedConfigFileName, []byte(`{"ImageId":"ami-saved"}`), 0644)
+	th.Ok(t, err)
+	defer os.Remove(*path)
+
+	simpleConfig, isSaved, err := testEC2.GetSavedOrDefaultConfig(config.NewSimpleInfo(),
+		aws.String(testSavedConfigFileName), false, "")
+	th.Ok(t, err)
+	th.Equals(t, true, isSaved)
+	th.Equals(t, "ami-saved", simpleConfig.ImageId)
+}
+
+func TestGetSavedOrDefaultConfig_ForceDefaultConfig(t *testing.T) {
+	path, err := config.SaveInConfigFolder(testSavedConfigFileName, []byte(`{"ImageId":"ami-saved"}`), 0644)
+	th.Ok(t, err)
+	defer os.Remove(*path)
+	testEC2.Svc = &th.MockedEC2Svc{
+		DescribeInstanceTypesPagesError: errors.New("Test error"),
+	}
+	testEC2.Sess = session.Must(session.NewSession(aws.NewConfig().WithRegion(testRegion)))
+
+	// The saved config is valid, so only generating the default config can fail
+	_, isSaved, err := testEC2.GetSavedOrDefaultConfig(config.NewSimpleInfo(),
+		aws.String(testSavedConfigFileName), true, "")
+	th.Nok(t, err)
+	th.Equals(t, false, isSaved)
+}
+
+func TestAddForceDefaultConfigHint_NotFound(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{}
+
+	_, err := testEC2.ParseConfig(&config.SimpleInfo{SubnetId: "subnet-deleted"})
+	err = ec2helper.AddForceDefaultConfigHint(err)
+	th.Nok(t, err)
+	th.Assert(t, strings.Contains(err.Error(), "--force-default-config"),
+		"The error should suggest ignoring the saved config")
+}
+
+func TestAddForceDefaultConfigHint_AwsNotFound(t *testing.T) {
+	err := ec2helper.AddForceDefaultConfigHint(awserr.New("InvalidGroup.NotFound", "Test error", nil))
+	th.Assert(t, strings.Contains(err.Error(), "--force-default-config"),
+		"The error should suggest ignoring the saved config")
+}
+
+func TestAddForceDefaultConfigHint_OtherError(t *testing.T) {
+	testError := errors.New("Test error")
+	th.Equals(t, testError, ec2helper.AddForceDefaultConfigHint(testError))
+	th.Equals(t, nil, ec2helper.AddForceDefaultConfigHint(nil))
+}
+
 func TestGetDefaultSimpleConfig_Success(t *testing.T) {
 	testEC2.Svc = defaultConfigSvc
 	testEC2.Sess = session.Must(session.NewSession())
 
-	actualSimpleConfig, err := testEC2.GetDefaultSimpleConfig()
+	actualSimpleConfig, err := testEC2.GetDefaultSimpleConfig("")
 	th.Ok(t, err)
 	th.Equals(t, testImageId, actualSimpleConfig.ImageId)
 	th.Equals(t, testSubnetId, actualSimpleConfig.SubnetId)
 	th.Equals(t, testInstanceType, actualSimpleConfig.InstanceType)
 }
 
+func TestGetDefaultSimpleConfig_ForcedEbs(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{
+		InstanceTypes: []*ec2.InstanceTypeInfo{
+			{
+				InstanceType:             aws.String(testInstanceType),
+				FreeTierEligible:         aws.Bool(true),
+				InstanceStorageSupported: aws.Bool(true),
+				ProcessorInfo:            &ec2.ProcessorInfo{SupportedArchitectures: defaultArchitecture},
+			},
+		},
+		Images: []*ec2.Image{
+			{
+				ImageId: aws.String(testImageId),
+			},
+		},
+	}
+	h := &ec2helper.EC2Helper{
+		Svc:            mockedSvc,
+		Sess:           session.Must(session.NewSession()),
+		RootDeviceType: ec2.DeviceTypeEbs,
+	}
+
+	// The instance type supports instance store, but EBS-backed images are looked up
+	actualSimpleConfig, err := h.GetDefaultSimpleConfig("")
+	th.Ok(t, err)
+	th.Equals(t, testImageId, actualSimpleConfig.ImageId)
+	th.Assert(t, len(mockedSvc.DescribeImagesInputs) > 0, "No images were looked up")
+	for _, input := range mockedSvc.DescribeImagesInputs {
+		for _, filter := range input.Filters {
+			if *filter.Name == "root-device-type" {
+				th.Equals(t, []*string{aws.String(ec2.DeviceTypeEbs)}, filter.Values)
+			}
+		}
+	}
+}
+
+func TestGetDefaultSimpleConfig_ArmInstanceType(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{
+		InstanceTypes: []*ec2.InstanceTypeInfo{
+			{
+				InstanceType:             aws.String(testInstanceType),
+				FreeTierEligible:         aws.Bool(true),
+				InstanceStorageSupported: aws.Bool(false),
+				ProcessorInfo:            &ec2.ProcessorInfo{SupportedArchitectures: defaultArchitecture},
+			},
+			{
+				InstanceType:             aws.String("t4g.micro"),
+				FreeTierEligible:         aws.Bool(false),
+				InstanceStorageSupported: aws.Bool(false),
+				ProcessorInfo:            &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"arm64"})},
+			},
+		},
+		Images: []*ec2.Image{
+			{
+				ImageId:      aws.String("ami-x86"),
+				Architecture: aws.String("x86_64"),
+				CreationDate: aws.String("2021-01-01T00:00:00.000Z"),
+			},
+			{
+				ImageId:      aws.String("ami-arm"),
+				Architecture: aws.String("arm64"),
+				CreationDate: aws.String("2021-01-01T00:00:00.000Z"),
+			},
+		},
+	}
+	h := &ec2helper.EC2Helper{Svc: mockedSvc, Sess: session.Must(session.NewSession())}
+
+	// The default image of a Graviton instance type is an arm64 image, not the one of the free-tier type
+	actualSimpleConfig, err := h.GetDefaultSimpleConfig("t4g.micro")
+	th.Ok(t, err)
+	th.Equals(t, "t4g.micro", actualSimpleConfig.InstanceType)
+	th.Equals(t, "ami-arm", actualSimpleConfig.ImageId)
+
+	actualSimpleConfig, err = h.GetDefaultSimpleConfig("")
+	th.Ok(t, err)
+	th.Equals(t, testInstanceType, actualSimpleConfig.InstanceType)
+	th.Equals(t, "ami-x86", actualSimpleConfig.ImageId)
+}
+
 func TestGetDefaultSimpleConfig_DescribeSecurityGroupsPagesError(t *testing.T) {
 	defaultConfigSvc.DescribeSecurityGroupsPagesError = errors.New("Test error")
 
-	_, err := testEC2.GetDefaultSimpleConfig()
+	_, err := testEC2.GetDefaultSimpleConfig("")
 	th.Nok(t, err)
 }
 
 func TestGetDefaultSimpleConfig_DescribeSubnetsPagesError(t *testing.T) {
 	defaultConfigSvc.DescribeSubnetsPagesError = errors.New("Test error")
 
-	_, err := testEC2.GetDefaultSimpleConfig()
+	_, err := testEC2.GetDefaultSimpleConfig("")
 	th.Nok(t, err)
 }
 
 func TestGetDefaultSimpleConfig_NoDefaultVpc(t *testing.T) {
 	defaultConfigSvc.Vpcs[0].SetIsDefault(false)
 
-	_, err := testEC2.GetDefaultSimpleConfig()
+	_, err := testEC2.GetDefaultSimpleConfig("")
 	th.Ok(t, err)
 }
 
 func TestGetDefaultSimpleConfig_NoVpc(t *testing.T) {
 	defaultConfigSvc.Vpcs = []*ec2.Vpc{}
 
-	_, err := testEC2.GetDefaultSimpleConfig()
+	_, err := testEC2.GetDefaultSimpleConfig("")
 	th.Ok(t, err)
 }
 
 func TestGetDefaultSimpleConfig_DescribeVpcsPagesError(t *testing.T) {
 	defaultConfigSvc.DescribeVpcsPagesError = errors.New("Test error")
 
-	_, err := testEC2.GetDefaultSimpleConfig()
+	_, err := testEC2.GetDefaultSimpleConfig("")
 	th.Nok(t, err)
 }
 
 func TestGetDefaultSimpleConfig_DescribeImagesError(t *testing.T) {
 	defaultConfigSvc.DescribeImagesError = errors.New("Test error")
 
-	_, err := testEC2.GetDefaultSimpleConfig()
+	_, err := testEC2.GetDefaultSimpleConfig("")
 	th.Nok(t, err)
 }
 
 func TestGetDefaultSimpleConfig_DescribeInstanceTypesPagesError(t *testing.T) {
 	defaultConfigSvc.DescribeInstanceTypesPagesError = errors.New("Test error")
 
-	_, err := testEC2.GetDefaultSimpleConfig()
+	_, err := testEC2.GetDefaultSimpleConfig("")
 	th.Nok(t, err)
 }
 
@@ -1271,175 +2349,2621 @@ func TestLaunchInstance_DescribeImagesError(t *testing.T) {
 	th.Nok(t, err)
 }
 
-func TestLaunchFleet(t *testing.T) {
-	const testInstanceId = ("i-12345")
-	testEC2.Svc = &th.MockedEC2Svc{}
-	fleetOutput, _ := testEC2.LaunchFleet(&testLaunchId)
+func TestLaunchInstance_ClientToken(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+	simpleConfig := &config.SimpleInfo{
+		ImageId:      "ami-12345",
+		InstanceType: "t2.micro",
+		SubnetId:     "subnet-12345",
+	}
 
-	th.Equals(t, 1, len(fleetOutput.Instances))
-	th.Equals(t, testInstanceId, *fleetOutput.Instances[0].InstanceIds[0])
+	// Independent launches get different tokens
+	_, err := testEC2.LaunchInstance(simpleConfig, &testDetailedConfig, true)
+	th.Ok(t, err)
+	_, err = testEC2.LaunchInstance(simpleConfig, &testDetailedConfig, true)
+	th.Ok(t, err)
+	th.Equals(t, 2, len(mockedSvc.RunInstancesInputs))
+	th.Assert(t, aws.StringValue(mockedSvc.RunInstancesInputs[0].ClientToken) != "", "A client token should be set")
+	th.Assert(t, *mockedSvc.RunInstancesInputs[0].ClientToken != *mockedSvc.RunInstancesInputs[1].ClientToken,
+		"Independent launches should have different client tokens")
+
+	// An explicit token is used as it is, so separate invocations are idempotent
+	simpleConfig.ClientToken = testClientToken
+	_, err = testEC2.LaunchInstance(simpleConfig, &testDetailedConfig, true)
+	th.Ok(t, err)
+	th.Equals(t, testClientToken, *mockedSvc.RunInstancesInputs[2].ClientToken)
 }
 
-/*
-Terminate Tests
-*/
-
-func TestTerminateInstances_Success(t *testing.T) {
-	testEC2.Svc = &th.MockedEC2Svc{}
+func TestTagInstanceNames(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+	instanceIds := []string{"i-12345", "i-12346", "i-12347"}
 
-	err := testEC2.TerminateInstances([]string{})
+	err := testEC2.TagInstanceNames(instanceIds, "web")
 	th.Ok(t, err)
+	th.Equals(t, 3, len(mockedSvc.CreateTagsInputs))
+	for i, input := range mockedSvc.CreateTagsInputs {
+		th.Equals(t, []string{instanceIds[i]}, aws.StringValueSlice(input.Resources))
+		th.Equals(t, "Name", *input.Tags[0].Key)
+		th.Equals(t, fmt.Sprintf("web-%d", i+1), *input.Tags[0].Value)
+	}
 }
 
-func TestTerminateInstances_TerminateInstancesError(t *testing.T) {
+func TestTagInstanceNames_CreateTagsError(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		CreateTagsError: errors.New("Test error"),
+	}
+
+	err := testEC2.TagInstanceNames([]string{"i-12345"}, "web")
+	th.Nok(t, err)
+}
+
+func TestValidateInstanceNamePrefix(t *testing.T) {
+	th.Ok(t, ec2helper.ValidateInstanceNamePrefix("web", map[string]string{"Team": "platform"}))
+	th.Ok(t, ec2helper.ValidateInstanceNamePrefix("", map[string]string{"Name": "web"}))
+	th.Nok(t, ec2helper.ValidateInstanceNamePrefix("web", map[string]string{"Name": "web"}))
+}
+
+func TestGetInstanceCount(t *testing.T) {
+	th.Equals(t, int64(1), ec2helper.GetInstanceCount(&config.SimpleInfo{}))
+	th.Equals(t, int64(3), ec2helper.GetInstanceCount(&config.SimpleInfo{InstanceCount: 3}))
+}
+
+func TestValidateInstanceCount(t *testing.T) {
+	th.Ok(t, ec2helper.ValidateInstanceCount(1, "10.0.0.5"))
+	th.Ok(t, ec2helper.ValidateInstanceCount(3, ""))
+	th.Nok(t, ec2helper.ValidateInstanceCount(0, ""))
+	th.Nok(t, ec2helper.ValidateInstanceCount(-2, ""))
+	th.Nok(t, ec2helper.ValidateInstanceCount(2, "10.0.0.5"))
+}
+
+func TestLaunchInstance_InstanceCount(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+	simpleConfig := &config.SimpleInfo{
+		ImageId:       "ami-12345",
+		InstanceType:  "t2.micro",
+		SubnetId:      "subnet-12345",
+		InstanceCount: 3,
+	}
+
+	instanceIds, err := testEC2.LaunchInstance(simpleConfig, &testDetailedConfig, true)
+	th.Ok(t, err)
+	th.Equals(t, int64(3), *mockedSvc.RunInstancesInputs[0].MinCount)
+	th.Equals(t, int64(3), *mockedSvc.RunInstancesInputs[0].MaxCount)
+	th.Equals(t, []string{"i-12345", "i-12346", "i-12347"}, instanceIds)
+}
+
+func TestDryRunLaunchInstance_Success(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	err := testEC2.DryRunLaunchInstance(&testSimpleConfig, &testDetailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, 0, mockedSvc.RunInstancesCalls)
+}
+
+func TestDryRunLaunchInstance_RunInstancesError(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{
+		RunInstancesError: errors.New("Test error"),
+	}
+	testEC2.Svc = mockedSvc
+
+	err := testEC2.DryRunLaunchInstance(&testSimpleConfig, &testDetailedConfig)
+	th.Nok(t, err)
+	th.Equals(t, 0, mockedSvc.RunInstancesCalls)
+}
+
+func TestDryRunLaunchSpotInstance_Success(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	err := testEC2.DryRunLaunchSpotInstance(&testSimpleConfig, &testDetailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, 0, len(mockedSvc.LaunchTemplates))
+	th.Equals(t, 1, len(mockedSvc.CreateFleetInputs))
+	th.Equals(t, true, *mockedSvc.CreateFleetInputs[0].DryRun)
+	th.Equals(t, 0, mockedSvc.RunInstancesCalls)
+}
+
+func TestDryRunLaunchSpotInstance_CreateLaunchTemplateError(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{
+		CreateLaunchTemplateError: awserr.New("UnauthorizedOperation",
+			"You are not authorized to perform this operation.", nil),
+	}
+	testEC2.Svc = mockedSvc
+
+	err := testEC2.DryRunLaunchSpotInstance(&testSimpleConfig, &testDetailedConfig)
+	th.Nok(t, err)
+	th.Equals(t, 0, len(mockedSvc.CreateFleetInputs))
+}
+
+func TestDryRunLaunchSpotInstance_CreateFleetError(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{
+		CreateFleetError: awserr.New("UnauthorizedOperation",
+			"You are not authorized to perform this operation.", nil),
+	}
+	testEC2.Svc = mockedSvc
+
+	err := testEC2.DryRunLaunchSpotInstance(&testSimpleConfig, &testDetailedConfig)
+	th.Nok(t, err)
+}
+
+func TestLaunchFleet(t *testing.T) {
+	const testInstanceId = ("i-12345")
+	testEC2.Svc = &th.MockedEC2Svc{}
+	fleetOutput, _ := testEC2.LaunchFleet(&testLaunchId, 1, testClientToken)
+
+	th.Equals(t, 1, len(fleetOutput.Instances))
+	th.Equals(t, testInstanceId, *fleetOutput.Instances[0].InstanceIds[0])
+}
+
+func TestLaunchFleet_InstanceCount(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+	_, err := testEC2.LaunchFleet(&testLaunchId, 3, testClientToken)
+	th.Ok(t, err)
+
+	targetCapacity := mockedSvc.CreateFleetInputs[0].TargetCapacitySpecification
+	th.Equals(t, int64(3), *targetCapacity.SpotTargetCapacity)
+	th.Equals(t, int64(3), *targetCapacity.TotalTargetCapacity)
+}
+
+var testFamilyInstanceTypes = []*ec2.InstanceTypeInfo{
+	{
+		InstanceType: aws.String("m5.2xlarge"),
+		VCpuInfo:     &ec2.VCpuInfo{DefaultVCpus: aws.Int64(8)},
+		MemoryInfo:   &ec2.MemoryInfo{SizeInMiB: aws.Int64(32768)},
+	},
+	{
+		InstanceType: aws.String("m5.large"),
+		VCpuInfo:     &ec2.VCpuInfo{DefaultVCpus: aws.Int64(2)},
+		MemoryInfo:   &ec2.MemoryInfo{SizeInMiB: aws.Int64(8192)},
+	},
+	{
+		InstanceType: aws.String("c5.xlarge"),
+		VCpuInfo:     &ec2.VCpuInfo{DefaultVCpus: aws.Int64(4)},
+		MemoryInfo:   &ec2.MemoryInfo{SizeInMiB: aws.Int64(8192)},
+	},
+	{
+		InstanceType: aws.String("m5.4xlarge"),
+		VCpuInfo:     &ec2.VCpuInfo{DefaultVCpus: aws.Int64(16)},
+		MemoryInfo:   &ec2.MemoryInfo{SizeInMiB: aws.Int64(65536)},
+	},
+	{
+		InstanceType: aws.String("m5.xlarge"),
+		VCpuInfo:     &ec2.VCpuInfo{DefaultVCpus: aws.Int64(4)},
+		MemoryInfo:   &ec2.MemoryInfo{SizeInMiB: aws.Int64(16384)},
+	},
+	{
+		InstanceType: aws.String("m5.8xlarge"),
+		VCpuInfo:     &ec2.VCpuInfo{DefaultVCpus: aws.Int64(32)},
+		MemoryInfo:   &ec2.MemoryInfo{SizeInMiB: aws.Int64(131072)},
+	},
+}
+
+func TestGetSpotFallbackInstanceTypes_Adjacent(t *testing.T) {
+	fallbackInstanceTypes := ec2helper.GetSpotFallbackInstanceTypes("m5.xlarge", testFamilyInstanceTypes, 3)
+	th.Equals(t, []string{"m5.2xlarge", "m5.large", "m5.4xlarge"}, fallbackInstanceTypes)
+}
+
+func TestGetSpotFallbackInstanceTypes_Smallest(t *testing.T) {
+	fallbackInstanceTypes := ec2helper.GetSpotFallbackInstanceTypes("m5.large", testFamilyInstanceTypes, 2)
+	th.Equals(t, []string{"m5.xlarge", "m5.2xlarge"}, fallbackInstanceTypes)
+}
+
+func TestGetSpotFallbackInstanceTypes_NoOtherSize(t *testing.T) {
+	fallbackInstanceTypes := ec2helper.GetSpotFallbackInstanceTypes("c5.xlarge", testFamilyInstanceTypes, 3)
+	th.Equals(t, []string{}, fallbackInstanceTypes)
+}
+
+func TestGetSpotFallbackInstanceTypes_UnknownInstanceType(t *testing.T) {
+	fallbackInstanceTypes := ec2helper.GetSpotFallbackInstanceTypes("r5.large", testFamilyInstanceTypes, 3)
+	th.Equals(t, 0, len(fallbackInstanceTypes))
+}
+
+var testSpotFallbackSimpleConfig = &config.SimpleInfo{
+	ImageId:          "ami-12345",
+	InstanceType:     "m5.xlarge",
+	SubnetId:         "subnet-12345",
+	CapacityType:     "Spot",
+	SpotSizeFallback: true,
+}
+var testSpotFallbackDetailedConfig = &config.DetailedInfo{
+	Image: &ec2.Image{
+		ImageId:         aws.String("ami-12345"),
+		PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+	},
+}
+
+func TestLaunchSpotInstance_SizeFallback(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{
+		InstanceTypes:         testFamilyInstanceTypes,
+		CreateFleetErrorCodes: []string{"InsufficientInstanceCapacity", "InsufficientInstanceCapacity"},
+	}
+	testEC2.Svc = mockedSvc
+
+	instanceIds, err := testEC2.LaunchSpotInstance(testSpotFallbackSimpleConfig, testSpotFallbackDetailedConfig, true)
+	th.Ok(t, err)
+	th.Equals(t, []string{"i-12345"}, instanceIds)
+	th.Equals(t, 3, len(mockedSvc.CreateFleetInputs))
+	th.Equals(t, 0, len(mockedSvc.CreateFleetInputs[0].LaunchTemplateConfigs[0].Overrides))
+	th.Equals(t, "m5.2xlarge", *mockedSvc.CreateFleetInputs[1].LaunchTemplateConfigs[0].Overrides[0].InstanceType)
+	th.Equals(t, "m5.large", *mockedSvc.CreateFleetInputs[2].LaunchTemplateConfigs[0].Overrides[0].InstanceType)
+
+	// Each instance type gets its own client token, since a token can't be reused with other parameters
+	th.Equals(t, ec2helper.GetFallbackClientToken(*mockedSvc.CreateFleetInputs[0].ClientToken, "m5.2xlarge"),
+		*mockedSvc.CreateFleetInputs[1].ClientToken)
+	th.Equals(t, ec2helper.GetFallbackClientToken(*mockedSvc.CreateFleetInputs[0].ClientToken, "m5.large"),
+		*mockedSvc.CreateFleetInputs[2].ClientToken)
+}
+
+func TestLaunchSpotInstance_ClientToken(t *testing.T) {
+	ec2helper.ServiceLinkedRoleWait = 0
+	mockedSvc := &th.MockedEC2Svc{
+		CreateFleetErrorCodes: []string{"AuthFailure.ServiceLinkedRoleCreationNotPermitted"},
+	}
+	testEC2.Svc = mockedSvc
+	testEC2.Iam = &iamhelper.IAMHelper{Client: &th.MockedIAMSvc{}}
+	defer func() { testEC2.Iam = nil }()
+	simpleConfig := &config.SimpleInfo{
+		LaunchTemplateId: testLaunchId,
+	}
+
+	// The retry after creating the service-linked role reuses the token of the launch
+	_, err := testEC2.LaunchSpotInstance(simpleConfig, nil, true)
+	th.Ok(t, err)
+	th.Equals(t, 2, len(mockedSvc.CreateFleetInputs))
+	th.Assert(t, aws.StringValue(mockedSvc.CreateFleetInputs[0].ClientToken) != "", "A client token should be set")
+	th.Equals(t, *mockedSvc.CreateFleetInputs[0].ClientToken, *mockedSvc.CreateFleetInputs[1].ClientToken)
+
+	// An independent launch gets another token
+	_, err = testEC2.LaunchSpotInstance(simpleConfig, nil, true)
+	th.Ok(t, err)
+	th.Equals(t, 3, len(mockedSvc.CreateFleetInputs))
+	th.Assert(t, *mockedSvc.CreateFleetInputs[0].ClientToken != *mockedSvc.CreateFleetInputs[2].ClientToken,
+		"Independent launches should have different client tokens")
+}
+
+func TestValidateClientToken(t *testing.T) {
+	th.Ok(t, ec2helper.ValidateClientToken(""))
+	th.Ok(t, ec2helper.ValidateClientToken(testClientToken))
+	th.Nok(t, ec2helper.ValidateClientToken(strings.Repeat("a", 65)))
+	th.Nok(t, ec2helper.ValidateClientToken("token\n"))
+}
+
+func TestLaunchSpotInstance_SizeFallbackBounded(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{
+		InstanceTypes: testFamilyInstanceTypes,
+		CreateFleetErrorCodes: []string{"InsufficientInstanceCapacity", "InsufficientInstanceCapacity",
+			"InsufficientInstanceCapacity", "InsufficientInstanceCapacity", "InsufficientInstanceCapacity"},
+	}
+	testEC2.Svc = mockedSvc
+
+	instanceIds, err := testEC2.LaunchSpotInstance(testSpotFallbackSimpleConfig, testSpotFallbackDetailedConfig, true)
+	th.Nok(t, err)
+	th.Equals(t, 0, len(instanceIds))
+	th.Equals(t, 1+ec2helper.MaxSpotSizeFallbacks, len(mockedSvc.CreateFleetInputs))
+}
+
+func TestLaunchSpotInstance_NoFallbackForOtherErrors(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{
+		InstanceTypes:         testFamilyInstanceTypes,
+		CreateFleetErrorCodes: []string{"InvalidParameterValue"},
+	}
+	testEC2.Svc = mockedSvc
+
+	instanceIds, err := testEC2.LaunchSpotInstance(testSpotFallbackSimpleConfig, testSpotFallbackDetailedConfig, true)
+	th.Nok(t, err)
+	th.Equals(t, 0, len(instanceIds))
+	th.Equals(t, 1, len(mockedSvc.CreateFleetInputs))
+}
+
+func TestLaunchFleet_CreateSpotServiceLinkedRole(t *testing.T) {
+	ec2helper.ServiceLinkedRoleWait = 0
+	mockedSvc := &th.MockedEC2Svc{
+		CreateFleetErrorCodes: []string{"AuthFailure.ServiceLinkedRoleCreationNotPermitted"},
+	}
+	mockedIam := &th.MockedIAMSvc{}
+	testEC2.Svc = mockedSvc
+	testEC2.Iam = &iamhelper.IAMHelper{Client: mockedIam}
+	defer func() { testEC2.Iam = nil }()
+
+	fleetOutput, err := testEC2.LaunchFleet(&testLaunchId, 1, testClientToken)
+	th.Ok(t, err)
+	th.Equals(t, "i-12345", *fleetOutput.Instances[0].InstanceIds[0])
+	th.Equals(t, []string{iamhelper.SpotServiceName}, mockedIam.ServiceLinkedRoleServices)
+	th.Equals(t, 2, len(mockedSvc.CreateFleetInputs))
+}
+
+func TestLaunchFleet_CreateSpotServiceLinkedRoleError(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{
+		CreateFleetErrorCodes: []string{"AuthFailure.ServiceLinkedRoleCreationNotPermitted"},
+	}
+	testEC2.Svc = mockedSvc
+	testEC2.Iam = &iamhelper.IAMHelper{Client: &th.MockedIAMSvc{
+		CreateServiceLinkedRoleError: errors.New("Test error"),
+	}}
+	defer func() { testEC2.Iam = nil }()
+
+	err := th.TakeOverStdout()
+	th.Ok(t, err)
+	_, err = testEC2.LaunchFleet(&testLaunchId, 1, testClientToken)
+	out := th.ReadStdout()
+
+	th.Nok(t, err)
+	th.Equals(t, 1, len(mockedSvc.CreateFleetInputs))
+	th.Assert(t, strings.Contains(out, "aws iam create-service-linked-role --aws-service-name "+
+		iamhelper.SpotServiceName), "The remediation steps should be printed")
+}
+
+func TestLaunchFleet_OtherErrorNoServiceLinkedRole(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{
+		CreateFleetErrorCodes: []string{"InsufficientInstanceCapacity"},
+	}
+	mockedIam := &th.MockedIAMSvc{}
+	testEC2.Svc = mockedSvc
+	testEC2.Iam = &iamhelper.IAMHelper{Client: mockedIam}
+	defer func() { testEC2.Iam = nil }()
+
+	_, err := testEC2.LaunchFleet(&testLaunchId, 1, testClientToken)
+	th.Nok(t, err)
+	th.Equals(t, 0, len(mockedIam.ServiceLinkedRoleServices))
+}
+
+var testPartialFleetOutput = &ec2.CreateFleetOutput{
+	Instances: []*ec2.CreateFleetInstance{
+		{InstanceIds: []*string{aws.String("i-12345"), aws.String("i-67890")}},
+	},
+	Errors: []*ec2.CreateFleetError{
+		{ErrorCode: aws.String("InsufficientInstanceCapacity"), ErrorMessage: aws.String("Test error: capacity")},
+		{ErrorCode: aws.String("SpotMaxPriceTooLow"), ErrorMessage: aws.String("Test error: price")},
+	},
+}
+
+func TestGetFleetFulfillment_Partial(t *testing.T) {
+	fulfillment := ec2helper.GetFleetFulfillment(3, testPartialFleetOutput)
+	th.Equals(t, int64(3), fulfillment.RequestedCapacity)
+	th.Equals(t, int64(2), fulfillment.FulfilledCapacity)
+	th.Equals(t, 2, len(fulfillment.Errors))
+	th.Equals(t, "SpotMaxPriceTooLow", fulfillment.Errors[1].(awserr.Error).Code())
+}
+
+func TestPrintFleetFulfillment_Partial(t *testing.T) {
+	err := th.TakeOverStdout()
+	th.Ok(t, err)
+	ec2helper.PrintFleetFulfillment(ec2helper.GetFleetFulfillment(3, testPartialFleetOutput))
+	out := th.ReadStdout()
+
+	th.Assert(t, strings.Contains(out, "Spot capacity fulfilled: 2 of 3"), "The fulfilled capacity should be printed")
+	th.Assert(t, strings.Contains(out, "Warning: Only 2 of the 3 requested Spot instances were launched"),
+		"Partial fulfillment should be warned about")
+	th.Assert(t, strings.Contains(out, "InsufficientInstanceCapacity: Test error: capacity") &&
+		strings.Contains(out, "SpotMaxPriceTooLow: Test error: price"), "Every error should be printed")
+}
+
+func TestPrintFleetFulfillment_Fulfilled(t *testing.T) {
+	err := th.TakeOverStdout()
+	th.Ok(t, err)
+	ec2helper.PrintFleetFulfillment(ec2helper.GetFleetFulfillment(2, testPartialFleetOutput))
+	out := th.ReadStdout()
+
+	th.Assert(t, strings.Contains(out, "Spot capacity fulfilled: 2 of 2"), "The fulfilled capacity should be printed")
+	th.Assert(t, !strings.Contains(out, "Warning"), "Full fulfillment should not be warned about")
+}
+
+func TestLaunchSpotInstance_PartialFulfillment(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{
+		CreateFleetOutput: &ec2.CreateFleetOutput{
+			Instances: []*ec2.CreateFleetInstance{
+				{InstanceIds: []*string{aws.String("i-12345")}},
+			},
+			Errors: testPartialFleetOutput.Errors,
+		},
+	}
+	testEC2.Svc = mockedSvc
+	simpleConfig := &config.SimpleInfo{
+		LaunchTemplateId: testLaunchId,
+		InstanceCount:    3,
+	}
+
+	err := th.TakeOverStdout()
+	th.Ok(t, err)
+	_, err = testEC2.LaunchSpotInstance(simpleConfig, nil, true)
+	out := th.ReadStdout()
+
+	// The instance count is the target capacity of the fleet, so a partial launch is warned about
+	th.Ok(t, err)
+	th.Equals(t, int64(3), *mockedSvc.CreateFleetInputs[0].TargetCapacitySpecification.TotalTargetCapacity)
+	th.Assert(t, strings.Contains(out, "Spot capacity fulfilled: 1 of 3"), "The fulfilled capacity should be printed")
+	th.Assert(t, strings.Contains(out, "Warning: Only 1 of the 3 requested Spot instances were launched"),
+		"Partial fulfillment should be warned about")
+}
+
+func TestLaunchFleet_AllErrorsReported(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		CreateFleetOutput: &ec2.CreateFleetOutput{Errors: testPartialFleetOutput.Errors},
+	}
+
+	err := th.TakeOverStdout()
+	th.Ok(t, err)
+	_, err = testEC2.LaunchFleet(&testLaunchId, 1, testClientToken)
+	out := th.ReadStdout()
+
+	th.Nok(t, err)
+	th.Assert(t, ec2helper.IsInsufficientCapacityError(err), "The first error should be returned")
+	th.Assert(t, strings.Contains(out, "InsufficientInstanceCapacity: Test error: capacity") &&
+		strings.Contains(out, "SpotMaxPriceTooLow: Test error: price"), "Every error should be printed")
+}
+
+func TestLaunchFleet_LaunchedWithErrors(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		CreateFleetOutput: testPartialFleetOutput,
+	}
+
+	err := th.TakeOverStdout()
+	th.Ok(t, err)
+	fleetOutput, err := testEC2.LaunchFleet(&testLaunchId, 1, testClientToken)
+	out := th.ReadStdout()
+
+	// The launched instances are kept, despite the errors of the fleet
+	th.Ok(t, err)
+	th.Equals(t, testPartialFleetOutput, fleetOutput)
+	th.Assert(t, strings.Contains(out, "Spot Instance ID: i-67890"), "The launched instances should be printed")
+}
+
+func TestAttachSecondaryNetworkInterface_Success(t *testing.T) {
+	const testInstanceId = "i-12345"
+	const testSubnetId = "subnet-67890"
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	networkInterfaceId, err := testEC2.AttachSecondaryNetworkInterface(testInstanceId, testSubnetId,
+		[]string{"sg-12345"})
+	th.Ok(t, err)
+	th.Equals(t, 1, len(mockedSvc.NetworkInterfaces))
+
+	networkInterface := mockedSvc.NetworkInterfaces[0]
+	th.Equals(t, *networkInterfaceId, *networkInterface.NetworkInterfaceId)
+	th.Equals(t, testSubnetId, *networkInterface.SubnetId)
+	th.Equals(t, "sg-12345", *networkInterface.Groups[0].GroupId)
+	th.Equals(t, testInstanceId, *networkInterface.Attachment.InstanceId)
+	th.Equals(t, int64(1), *networkInterface.Attachment.DeviceIndex)
+	th.Assert(t, len(networkInterface.TagSet) > 0, "The network interface should be tagged for cleanup")
+}
+
+func TestAttachSecondaryNetworkInterface_CreateNetworkInterfaceError(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		CreateNetworkInterfaceError: errors.New("Test error"),
+	}
+
+	_, err := testEC2.AttachSecondaryNetworkInterface("i-12345", "subnet-67890", nil)
+	th.Nok(t, err)
+}
+
+func TestAttachSecondaryNetworkInterface_AttachNetworkInterfaceError(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{
+		AttachNetworkInterfaceError: errors.New("Test error"),
+	}
+	testEC2.Svc = mockedSvc
+
+	networkInterfaceId, err := testEC2.AttachSecondaryNetworkInterface("i-12345", "subnet-67890", nil)
+	th.Nok(t, err)
+	th.Equals(t, *mockedSvc.NetworkInterfaces[0].NetworkInterfaceId, *networkInterfaceId)
+}
+
+func TestGetConsoleOutput_Decode(t *testing.T) {
+	const consoleOutput = "Cloud-init finished\n"
+	mockedSvc := &th.MockedEC2Svc{
+		ConsoleOutputs: []string{base64.StdEncoding.EncodeToString([]byte(consoleOutput))},
+	}
+	testEC2.Svc = mockedSvc
+
+	actualOutput, err := testEC2.GetConsoleOutput("i-12345")
+	th.Ok(t, err)
+	th.Equals(t, consoleOutput, actualOutput)
+	th.Equals(t, 1, mockedSvc.GetConsoleOutputCalls)
+}
+
+func TestGetConsoleOutput_Retry(t *testing.T) {
+	const consoleOutput = "Cloud-init finished\n"
+	ec2helper.ConsoleOutputRetryInterval = 0
+	mockedSvc := &th.MockedEC2Svc{
+		ConsoleOutputs: []string{"", "", base64.StdEncoding.EncodeToString([]byte(consoleOutput))},
+	}
+	testEC2.Svc = mockedSvc
+
+	actualOutput, err := testEC2.GetConsoleOutput("i-12345")
+	th.Ok(t, err)
+	th.Equals(t, consoleOutput, actualOutput)
+	th.Equals(t, 3, mockedSvc.GetConsoleOutputCalls)
+}
+
+func TestGetConsoleOutput_NotAvailable(t *testing.T) {
+	ec2helper.ConsoleOutputRetryInterval = 0
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	_, err := testEC2.GetConsoleOutput("i-12345")
+	th.Nok(t, err)
+	th.Equals(t, ec2helper.ConsoleOutputRetries, mockedSvc.GetConsoleOutputCalls)
+}
+
+func TestGetConsoleOutput_InvalidEncoding(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		ConsoleOutputs: []string{"not base64!"},
+	}
+
+	_, err := testEC2.GetConsoleOutput("i-12345")
+	th.Nok(t, err)
+}
+
+func TestGetConsoleOutput_GetConsoleOutputError(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		GetConsoleOutputError: errors.New("Test error"),
+	}
+
+	_, err := testEC2.GetConsoleOutput("i-12345")
+	th.Nok(t, err)
+}
+
+var runCommandInstances = []*ec2.Instance{
+	{
+		InstanceId: aws.String("i-12345"),
+	},
+}
+
+func TestRunCommandOnInstances(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{
+		Instances: runCommandInstances,
+	}
+	testEC2.Svc = mockedSvc
+	runner := &th.MockedCommandRunner{}
+
+	err := testEC2.RunCommandOnInstances([]string{"i-12345"}, "./setup.sh", false, runner)
+	th.Ok(t, err)
+	th.Equals(t, []string{"wait i-12345", "run i-12345 ./setup.sh"}, runner.Calls)
+	th.Equals(t, 0, len(mockedSvc.TerminatedInstanceIds))
+}
+
+func TestRunCommandOnInstances_TerminateAfter(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{
+		Instances: runCommandInstances,
+	}
+	testEC2.Svc = mockedSvc
+	runner := &th.MockedCommandRunner{}
+
+	err := testEC2.RunCommandOnInstances([]string{"i-12345"}, "./setup.sh", true, runner)
+	th.Ok(t, err)
+	th.Equals(t, []string{"wait i-12345", "run i-12345 ./setup.sh"}, runner.Calls)
+	th.Equals(t, []string{"i-12345"}, mockedSvc.TerminatedInstanceIds)
+}
+
+func TestRunCommandOnInstances_CommandErrorStillTerminates(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{
+		Instances: runCommandInstances,
+	}
+	testEC2.Svc = mockedSvc
+	runner := &th.MockedCommandRunner{
+		RunCommandError: errors.New("Test error"),
+	}
+
+	err := testEC2.RunCommandOnInstances([]string{"i-12345"}, "./setup.sh", true, runner)
+	th.Nok(t, err)
+	th.Equals(t, []string{"i-12345"}, mockedSvc.TerminatedInstanceIds)
+}
+
+func TestRunCommandOnInstances_WaitForSSHError(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{
+		Instances: runCommandInstances,
+	}
+	testEC2.Svc = mockedSvc
+	runner := &th.MockedCommandRunner{
+		WaitForSSHError: errors.New("Test error"),
+	}
+
+	err := testEC2.RunCommandOnInstances([]string{"i-12345"}, "./setup.sh", false, runner)
+	th.Nok(t, err)
+	th.Equals(t, []string{"wait i-12345"}, runner.Calls)
+}
+
+func TestRunCommandOnInstances_WaitUntilInstanceRunningError(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{
+		Instances:                     runCommandInstances,
+		WaitUntilInstanceRunningError: errors.New("Test error"),
+	}
+	testEC2.Svc = mockedSvc
+	runner := &th.MockedCommandRunner{}
+
+	err := testEC2.RunCommandOnInstances([]string{"i-12345"}, "./setup.sh", true, runner)
+	th.Nok(t, err)
+	th.Equals(t, 0, len(runner.Calls))
+	th.Equals(t, []string{"i-12345"}, mockedSvc.TerminatedInstanceIds)
+}
+
+func TestGetRunningInstances(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		Instances: runCommandInstances,
+	}
+
+	instances, err := testEC2.GetRunningInstances([]string{"i-12345"})
+	th.Ok(t, err)
+	th.Equals(t, runCommandInstances, instances)
+}
+
+func TestGetRunningInstances_WaitUntilInstanceRunningError(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		Instances:                     runCommandInstances,
+		WaitUntilInstanceRunningError: errors.New("Test error"),
+	}
+
+	_, err := testEC2.GetRunningInstances([]string{"i-12345"})
+	th.Nok(t, err)
+}
+
+func TestValidateSecondarySubnet_Success(t *testing.T) {
+	primarySubnet := &ec2.Subnet{
+		SubnetId:         aws.String("subnet-12345"),
+		VpcId:            aws.String("vpc-12345"),
+		AvailabilityZone: aws.String("us-east-1a"),
+	}
+	secondarySubnet := &ec2.Subnet{
+		SubnetId:         aws.String("subnet-67890"),
+		VpcId:            aws.String("vpc-12345"),
+		AvailabilityZone: aws.String("us-east-1a"),
+	}
+
+	err := ec2helper.ValidateSecondarySubnet(primarySubnet, secondarySubnet)
+	th.Ok(t, err)
+}
+
+func TestValidateSecondarySubnet_DifferentVpc(t *testing.T) {
+	primarySubnet := &ec2.Subnet{
+		SubnetId:         aws.String("subnet-12345"),
+		VpcId:            aws.String("vpc-12345"),
+		AvailabilityZone: aws.String("us-east-1a"),
+	}
+	secondarySubnet := &ec2.Subnet{
+		SubnetId:         aws.String("subnet-67890"),
+		VpcId:            aws.String("vpc-67890"),
+		AvailabilityZone: aws.String("us-east-1a"),
+	}
+
+	err := ec2helper.ValidateSecondarySubnet(primarySubnet, secondarySubnet)
+	th.Nok(t, err)
+}
+
+func TestValidateSecondarySubnet_DifferentAz(t *testing.T) {
+	primarySubnet := &ec2.Subnet{
+		SubnetId:         aws.String("subnet-12345"),
+		VpcId:            aws.String("vpc-12345"),
+		AvailabilityZone: aws.String("us-east-1a"),
+	}
+	secondarySubnet := &ec2.Subnet{
+		SubnetId:         aws.String("subnet-67890"),
+		VpcId:            aws.String("vpc-12345"),
+		AvailabilityZone: aws.String("us-east-1b"),
+	}
+
+	err := ec2helper.ValidateSecondarySubnet(primarySubnet, secondarySubnet)
+	th.Nok(t, err)
+}
+
+/*
+Terminate Tests
+*/
+
+func TestTerminateInstances_Success(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{}
+
+	err := testEC2.TerminateInstances([]string{})
+	th.Ok(t, err)
+}
+
+func TestTerminateInstancesWithStates_Success(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		Instances: []*ec2.Instance{
+			{
+				InstanceId: aws.String("i-12345"),
+				State:      &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+			},
+			{
+				InstanceId: aws.String("i-67890"),
+				State:      &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameStopped)},
+			},
+		},
+	}
+
+	stateChanges, err := testEC2.TerminateInstancesWithStates([]string{"i-67890"})
+	th.Ok(t, err)
+	th.Equals(t, 1, len(stateChanges))
+	th.Equals(t, "i-67890", *stateChanges[0].InstanceId)
+	th.Equals(t, ec2.InstanceStateNameStopped, *stateChanges[0].PreviousState.Name)
+}
+
+func TestTerminateInstancesWithStates_TerminateInstancesError(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		TerminateInstancesError: errors.New("Test error"),
+	}
+
+	_, err := testEC2.TerminateInstancesWithStates([]string{"i-12345"})
+	th.Nok(t, err)
+}
+
+func TestTerminateInstances_TerminateInstancesError(t *testing.T) {
 	testEC2.Svc = &th.MockedEC2Svc{
 		TerminateInstancesError: errors.New("Test error"),
 	}
 
-	err := testEC2.TerminateInstances([]string{})
-	th.Nok(t, err)
+	err := testEC2.TerminateInstances([]string{})
+	th.Nok(t, err)
+}
+
+/*
+Start Tests
+*/
+
+func TestStartInstances_Success(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	err := testEC2.StartInstances([]string{"i-12345", "i-67890"})
+	th.Ok(t, err)
+	th.Equals(t, []string{"i-12345", "i-67890"}, mockedSvc.StartedInstanceIds)
+}
+
+func TestStartInstances_StartInstancesError(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		StartInstancesError: errors.New("Test error"),
+	}
+
+	err := testEC2.StartInstances([]string{"i-12345"})
+	th.Nok(t, err)
+}
+
+/*
+Tag Tests
+*/
+
+func TestGetTagName_Success(t *testing.T) {
+	const testName = "Test Name"
+	testTags := []*ec2.Tag{
+		{
+			Key:   aws.String("Name"),
+			Value: aws.String(testName),
+		},
+		{
+			Key:   aws.String("CreatedBy"),
+			Value: aws.String("simple-ec2"),
+		},
+	}
+
+	actualName := ec2helper.GetTagName(testTags)
+	th.Equals(t, testName, *actualName)
+}
+
+func TestGetTagName_NoResult(t *testing.T) {
+	testTags := []*ec2.Tag{
+		{
+			Key:   aws.String("CreatedTime"),
+			Value: aws.String("012345"),
+		},
+		{
+			Key:   aws.String("CreatedBy"),
+			Value: aws.String("simple-ec2"),
+		},
+	}
+
+	actualName := ec2helper.GetTagName(testTags)
+	th.Equals(t, (*string)(nil), actualName)
+}
+
+/*
+Validation Tests
+*/
+
+func TestGetImageDeprecationStatus(t *testing.T) {
+	now := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	th.Equals(t, "", ec2helper.GetImageDeprecationStatus(&ec2.Image{}, now))
+	th.Equals(t, "deprecated since 2023-09-01", ec2helper.GetImageDeprecationStatus(&ec2.Image{
+		DeprecationTime: aws.String("2023-09-01T00:00:00.000Z"),
+	}, now))
+	th.Equals(t, "to be deprecated on 2023-10-15", ec2helper.GetImageDeprecationStatus(&ec2.Image{
+		DeprecationTime: aws.String("2023-10-15T00:00:00.000Z"),
+	}, now))
+	th.Equals(t, "", ec2helper.GetImageDeprecationStatus(&ec2.Image{
+		DeprecationTime: aws.String("2024-10-15T00:00:00.000Z"),
+	}, now))
+}
+
+func TestValidateImageId_True(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		Images: []*ec2.Image{
+			{
+				ImageId: aws.String(testImageId),
+			},
+		},
+	}
+
+	result := ec2helper.ValidateImageId(testEC2, testImageId)
+	th.Equals(t, true, result)
+}
+
+func TestValidateImageId_False(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		Images: []*ec2.Image{},
+	}
+
+	result := ec2helper.ValidateImageId(testEC2, testImageId)
+	th.Equals(t, false, result)
+}
+
+func TestValidateFilepath_True(t *testing.T) {
+	tmpFile, err := ioutil.TempFile("", "mocked_filepath")
+	defer os.Remove(tmpFile.Name())
+	if err != nil {
+		t.Errorf("There was an error creating tempfile: %v", err)
+	}
+	result := ec2helper.ValidateFilepath(testEC2, tmpFile.Name())
+	th.Equals(t, true, result)
+}
+
+func TestValidateFilepath_False(t *testing.T) {
+	result := ec2helper.ValidateFilepath(testEC2, "file/does/not/exist")
+	th.Equals(t, false, result)
+}
+
+func TestValidateTags_True(t *testing.T) {
+	testUserInput := "tag1|val1,tag2|val2"
+	result := ec2helper.ValidateTags(testEC2, testUserInput)
+	th.Equals(t, true, result)
+}
+
+func TestValidateTags_False(t *testing.T) {
+	testUserInput := "tag1|val1,tag2|val2,tag3"
+	result := ec2helper.ValidateTags(testEC2, testUserInput)
+	th.Equals(t, false, result)
+}
+
+func TestValidateInteger_True(t *testing.T) {
+	testUserInput := "123"
+	result := ec2helper.ValidateInteger(testEC2, testUserInput)
+	th.Equals(t, true, result)
+}
+
+func TestValidateInteger_False(t *testing.T) {
+	floatInput := "123.456"
+	floatResult := ec2helper.ValidateInteger(testEC2, floatInput)
+	th.Equals(t, false, floatResult)
+
+	stringInput := "abcdefg"
+	stringResult := ec2helper.ValidateInteger(testEC2, stringInput)
+	th.Equals(t, false, stringResult)
+
+	alphanumericInput := "123abc"
+	alphanumericResult := ec2helper.ValidateInteger(testEC2, alphanumericInput)
+	th.Equals(t, false, alphanumericResult)
+}
+
+func TestValidatePrivateIpInSubnet_Success(t *testing.T) {
+	testSubnet := &ec2.Subnet{
+		SubnetId:  aws.String("subnet-12345"),
+		CidrBlock: aws.String("10.0.1.0/24"),
+	}
+
+	err := ec2helper.ValidatePrivateIpInSubnet("10.0.1.25", testSubnet)
+	th.Ok(t, err)
+}
+
+func TestValidatePrivateIpInSubnet_OutsideCidr(t *testing.T) {
+	testSubnet := &ec2.Subnet{
+		SubnetId:  aws.String("subnet-12345"),
+		CidrBlock: aws.String("10.0.1.0/24"),
+	}
+
+	err := ec2helper.ValidatePrivateIpInSubnet("10.0.2.25", testSubnet)
+	th.Nok(t, err)
+}
+
+func TestValidatePrivateIpInSubnet_InvalidIp(t *testing.T) {
+	testSubnet := &ec2.Subnet{
+		SubnetId:  aws.String("subnet-12345"),
+		CidrBlock: aws.String("10.0.1.0/24"),
+	}
+
+	err := ec2helper.ValidatePrivateIpInSubnet("10.0.1.256", testSubnet)
+	th.Nok(t, err)
+}
+
+func TestGetShutdownBehavior(t *testing.T) {
+	th.Equals(t, ec2.ShutdownBehaviorTerminate, ec2helper.GetShutdownBehavior(""))
+	th.Equals(t, ec2.ShutdownBehaviorTerminate, ec2helper.GetShutdownBehavior(ec2.ShutdownBehaviorTerminate))
+	th.Equals(t, ec2.ShutdownBehaviorStop, ec2helper.GetShutdownBehavior(ec2.ShutdownBehaviorStop))
+}
+
+func TestValidateTimerAction_Success(t *testing.T) {
+	testImage := &ec2.Image{
+		ImageId:        aws.String("ami-12345"),
+		RootDeviceType: aws.String(ec2.DeviceTypeEbs),
+	}
+
+	err := ec2helper.ValidateTimerAction(ec2.ShutdownBehaviorStop, testImage)
+	th.Ok(t, err)
+}
+
+func TestValidateTimerAction_InstanceStoreStop(t *testing.T) {
+	testImage := &ec2.Image{
+		ImageId:        aws.String("ami-12345"),
+		RootDeviceType: aws.String(ec2.DeviceTypeInstanceStore),
+	}
+
+	err := ec2helper.ValidateTimerAction(ec2.ShutdownBehaviorStop, testImage)
+	th.Nok(t, err)
+
+	err = ec2helper.ValidateTimerAction(ec2.ShutdownBehaviorTerminate, testImage)
+	th.Ok(t, err)
+}
+
+func TestValidateTimerAction_Invalid(t *testing.T) {
+	testImage := &ec2.Image{
+		ImageId:        aws.String("ami-12345"),
+		RootDeviceType: aws.String(ec2.DeviceTypeEbs),
+	}
+
+	err := ec2helper.ValidateTimerAction("hibernate", testImage)
+	th.Nok(t, err)
+}
+
+func TestValidateSpotBlockDuration_Success(t *testing.T) {
+	for _, minutes := range []int{0, 60, 120, 180, 240, 300, 360} {
+		err := ec2helper.ValidateSpotBlockDuration(minutes)
+		th.Ok(t, err)
+	}
+}
+
+func TestValidateSpotBlockDuration_Invalid(t *testing.T) {
+	for _, minutes := range []int{-60, 30, 90, 420} {
+		err := ec2helper.ValidateSpotBlockDuration(minutes)
+		th.Nok(t, err)
+	}
+}
+
+func TestCreateLaunchTemplate_SpotBlockDuration(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		ImageId:                  "ami-12345",
+		InstanceType:             "t2.micro",
+		SubnetId:                 "subnet-12345",
+		SpotBlockDurationMinutes: 120,
+	}
+	detailedConfig := &config.DetailedInfo{
+		Image: &ec2.Image{
+			ImageId:         aws.String("ami-12345"),
+			PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+		},
+	}
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	_, err := testEC2.CreateLaunchTemplate(simpleConfig, detailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, 1, len(mockedSvc.LaunchTemplateData))
+
+	marketOptions := mockedSvc.LaunchTemplateData[0].InstanceMarketOptions
+	th.Equals(t, ec2.MarketTypeSpot, *marketOptions.MarketType)
+	th.Equals(t, int64(120), *marketOptions.SpotOptions.BlockDurationMinutes)
+}
+
+func TestValidateCapacityReservation_Valid(t *testing.T) {
+	for _, preference := range []string{"", ec2.CapacityReservationPreferenceOpen,
+		ec2.CapacityReservationPreferenceNone} {
+		err := ec2helper.ValidateCapacityReservation(preference, "")
+		th.Ok(t, err)
+	}
+	err := ec2helper.ValidateCapacityReservation("", "cr-12345")
+	th.Ok(t, err)
+}
+
+func TestValidateCapacityReservation_InvalidPreference(t *testing.T) {
+	err := ec2helper.ValidateCapacityReservation("targeted", "")
+	th.Nok(t, err)
+}
+
+func TestValidateCapacityReservation_PreferenceAndId(t *testing.T) {
+	err := ec2helper.ValidateCapacityReservation(ec2.CapacityReservationPreferenceOpen, "cr-12345")
+	th.Nok(t, err)
+}
+
+func TestCreateLaunchTemplate_CapacityReservationPreference(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		ImageId:                       "ami-12345",
+		InstanceType:                  "t2.micro",
+		SubnetId:                      "subnet-12345",
+		CapacityReservationPreference: ec2.CapacityReservationPreferenceNone,
+	}
+	detailedConfig := &config.DetailedInfo{
+		Image: &ec2.Image{
+			ImageId:         aws.String("ami-12345"),
+			PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+		},
+	}
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	_, err := testEC2.CreateLaunchTemplate(simpleConfig, detailedConfig)
+	th.Ok(t, err)
+
+	capacityReservation := mockedSvc.LaunchTemplateData[0].CapacityReservationSpecification
+	th.Equals(t, ec2.CapacityReservationPreferenceNone, *capacityReservation.CapacityReservationPreference)
+	th.Equals(t, (*ec2.CapacityReservationTarget)(nil), capacityReservation.CapacityReservationTarget)
+}
+
+func TestDryRunLaunchInstance_CapacityReservationPreference(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		ImageId:                       "ami-12345",
+		InstanceType:                  "t2.micro",
+		SubnetId:                      "subnet-12345",
+		CapacityReservationPreference: ec2.CapacityReservationPreferenceOpen,
+	}
+	detailedConfig := &config.DetailedInfo{
+		Image: &ec2.Image{
+			ImageId:         aws.String("ami-12345"),
+			PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+		},
+	}
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	err := testEC2.DryRunLaunchInstance(simpleConfig, detailedConfig)
+	th.Ok(t, err)
+
+	capacityReservation := mockedSvc.RunInstancesInputs[0].CapacityReservationSpecification
+	th.Equals(t, ec2.CapacityReservationPreferenceOpen, *capacityReservation.CapacityReservationPreference)
+}
+
+func TestDryRunLaunchInstance_NoCapacityReservation(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		ImageId:      "ami-12345",
+		InstanceType: "t2.micro",
+		SubnetId:     "subnet-12345",
+	}
+	detailedConfig := &config.DetailedInfo{
+		Image: &ec2.Image{
+			ImageId:         aws.String("ami-12345"),
+			PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+		},
+	}
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	err := testEC2.DryRunLaunchInstance(simpleConfig, detailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, (*ec2.CapacityReservationSpecification)(nil),
+		mockedSvc.RunInstancesInputs[0].CapacityReservationSpecification)
+}
+
+func TestCreateLaunchTemplate_TagSpecResourceTypes(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		ImageId:      "ami-12345",
+		InstanceType: "t2.micro",
+		SubnetId:     "subnet-12345",
+	}
+	tags := []*ec2.Tag{{Key: aws.String("CreatedBy"), Value: aws.String("simple-ec2")}}
+	detailedConfig := &config.DetailedInfo{
+		Image: &ec2.Image{
+			ImageId:         aws.String("ami-12345"),
+			PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+		},
+		TagSpecs: []*ec2.TagSpecification{
+			{ResourceType: aws.String("instance"), Tags: tags},
+			{ResourceType: aws.String("network-interface"), Tags: tags},
+		},
+	}
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	_, err := testEC2.CreateLaunchTemplate(simpleConfig, detailedConfig)
+	th.Ok(t, err)
+
+	ltTagSpecs := mockedSvc.LaunchTemplateData[0].TagSpecifications
+	th.Equals(t, 2, len(ltTagSpecs))
+	th.Equals(t, "instance", *ltTagSpecs[0].ResourceType)
+	th.Equals(t, "network-interface", *ltTagSpecs[1].ResourceType)
+}
+
+func TestCreateLaunchTemplate_SimpleEc2Tags(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		ImageId:      "ami-12345",
+		InstanceType: "t2.micro",
+		SubnetId:     "subnet-12345",
+	}
+	detailedConfig := &config.DetailedInfo{
+		Image: &ec2.Image{
+			ImageId:         aws.String("ami-12345"),
+			PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+		},
+	}
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	_, err := testEC2.CreateLaunchTemplate(simpleConfig, detailedConfig)
+	th.Ok(t, err)
+
+	// The template itself is tagged, so that the cleanup command can find it if it's abandoned
+	tagSpecs := mockedSvc.CreateLaunchTemplateInputs[0].TagSpecifications
+	th.Equals(t, 1, len(tagSpecs))
+	th.Equals(t, ec2.ResourceTypeLaunchTemplate, *tagSpecs[0].ResourceType)
+	templateTags := map[string]string{}
+	for _, tag := range tagSpecs[0].Tags {
+		templateTags[*tag.Key] = *tag.Value
+	}
+	th.Equals(t, "simple-ec2", templateTags["CreatedBy"])
+	_, found := templateTags["CreatedTime"]
+	th.Assert(t, found, "The CreatedTime tag should be set")
+}
+
+func TestValidatePrivateDnsHostnameType(t *testing.T) {
+	for _, hostnameType := range []string{"", ec2.HostnameTypeIpName, ec2.HostnameTypeResourceName} {
+		th.Ok(t, ec2helper.ValidatePrivateDnsHostnameType(hostnameType))
+	}
+	th.Nok(t, ec2helper.ValidatePrivateDnsHostnameType("dns-name"))
+}
+
+func TestValidateCpuCredits(t *testing.T) {
+	for _, cpuCredits := range []string{"", ec2helper.CpuCreditsStandard, ec2helper.CpuCreditsUnlimited} {
+		th.Ok(t, ec2helper.ValidateCpuCredits(cpuCredits))
+	}
+	th.Nok(t, ec2helper.ValidateCpuCredits("burst"))
+}
+
+func TestIsBurstableInstanceType(t *testing.T) {
+	th.Assert(t, ec2helper.IsBurstableInstanceType(&ec2.InstanceTypeInfo{
+		InstanceType:                  aws.String("t3.micro"),
+		BurstablePerformanceSupported: aws.Bool(true),
+	}), "t3.micro should be burstable")
+	th.Assert(t, !ec2helper.IsBurstableInstanceType(&ec2.InstanceTypeInfo{
+		InstanceType:                  aws.String("m5.large"),
+		BurstablePerformanceSupported: aws.Bool(false),
+	}), "m5.large should not be burstable")
+
+	// Without the burstable performance info, the type name tells
+	th.Assert(t, ec2helper.IsBurstableInstanceType(&ec2.InstanceTypeInfo{InstanceType: aws.String("t4g.nano")}),
+		"t4g.nano should be burstable")
+	th.Assert(t, !ec2helper.IsBurstableInstanceType(&ec2.InstanceTypeInfo{InstanceType: aws.String("c5.xlarge")}),
+		"c5.xlarge should not be burstable")
+}
+
+func TestValidateCpuCreditsForInstanceType(t *testing.T) {
+	burstable := &ec2.InstanceTypeInfo{
+		InstanceType:                  aws.String("t3.micro"),
+		BurstablePerformanceSupported: aws.Bool(true),
+	}
+	nonBurstable := &ec2.InstanceTypeInfo{
+		InstanceType:                  aws.String("m5.large"),
+		BurstablePerformanceSupported: aws.Bool(false),
+	}
+
+	th.Ok(t, ec2helper.ValidateCpuCreditsForInstanceType(ec2helper.CpuCreditsUnlimited, burstable))
+	th.Ok(t, ec2helper.ValidateCpuCreditsForInstanceType("", nonBurstable))
+	th.Nok(t, ec2helper.ValidateCpuCreditsForInstanceType(ec2helper.CpuCreditsStandard, nonBurstable))
+	th.Nok(t, ec2helper.ValidateCpuCreditsForInstanceType("burst", burstable))
+}
+
+func TestValidateImageArchitecture(t *testing.T) {
+	x86Image := &ec2.Image{ImageId: aws.String("ami-12345"), Architecture: aws.String("x86_64")}
+	armInstanceType := &ec2.InstanceTypeInfo{
+		InstanceType:  aws.String("m6g.large"),
+		ProcessorInfo: &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"arm64"})},
+	}
+	x86InstanceType := &ec2.InstanceTypeInfo{
+		InstanceType:  aws.String("m5.large"),
+		ProcessorInfo: &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"i386", "x86_64"})},
+	}
+
+	th.Ok(t, ec2helper.ValidateImageArchitecture(x86Image, x86InstanceType))
+	th.Nok(t, ec2helper.ValidateImageArchitecture(x86Image, armInstanceType))
+
+	// Unknown architectures aren't checked
+	th.Ok(t, ec2helper.ValidateImageArchitecture(&ec2.Image{}, armInstanceType))
+	th.Ok(t, ec2helper.ValidateImageArchitecture(x86Image, &ec2.InstanceTypeInfo{}))
+}
+
+// An image with an 8 GiB root volume and a 100 GiB data volume
+var rootVolumeImage = &ec2.Image{
+	ImageId:         aws.String("ami-12345"),
+	PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+	RootDeviceName:  aws.String("/dev/xvda"),
+	BlockDeviceMappings: []*ec2.BlockDeviceMapping{
+		{
+			DeviceName: aws.String("/dev/xvda"),
+			Ebs: &ec2.EbsBlockDevice{
+				DeleteOnTermination: aws.Bool(true),
+				SnapshotId:          aws.String("snap-12345"),
+				VolumeSize:          aws.Int64(8),
+			},
+		},
+		{
+			DeviceName: aws.String("/dev/xvdb"),
+			Ebs: &ec2.EbsBlockDevice{
+				DeleteOnTermination: aws.Bool(true),
+				VolumeSize:          aws.Int64(100),
+			},
+		},
+	},
+}
+
+func TestValidateRootVolumeSize(t *testing.T) {
+	th.Ok(t, ec2helper.ValidateRootVolumeSize(8, rootVolumeImage))
+	th.Ok(t, ec2helper.ValidateRootVolumeSize(30, rootVolumeImage))
+	th.Nok(t, ec2helper.ValidateRootVolumeSize(0, rootVolumeImage))
+
+	err := ec2helper.ValidateRootVolumeSize(4, rootVolumeImage)
+	th.Nok(t, err)
+	th.Equals(t, "The root volume size of 4 GiB is smaller than the 8 GiB snapshot of image ami-12345", err.Error())
+
+	// An instance store root volume can't be resized
+	instanceStoreImage := &ec2.Image{
+		ImageId:        aws.String("ami-67890"),
+		RootDeviceName: aws.String("/dev/sda1"),
+		BlockDeviceMappings: []*ec2.BlockDeviceMapping{
+			{
+				DeviceName:  aws.String("/dev/sda1"),
+				VirtualName: aws.String("ephemeral0"),
+			},
+		},
+	}
+	th.Nok(t, ec2helper.ValidateRootVolumeSize(30, instanceStoreImage))
+}
+
+func TestLaunchInstance_RootVolumeSize(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+	simpleConfig := &config.SimpleInfo{
+		ImageId:        "ami-12345",
+		InstanceType:   "t2.micro",
+		SubnetId:       "subnet-12345",
+		RootVolumeSize: 30,
+	}
+	detailedConfig := &config.DetailedInfo{Image: rootVolumeImage}
+
+	_, err := testEC2.LaunchInstance(simpleConfig, detailedConfig, true)
+	th.Ok(t, err)
+
+	// Only the root volume is resized, and the volumes are still deleted on termination
+	blocks := mockedSvc.RunInstancesInputs[0].BlockDeviceMappings
+	th.Equals(t, 2, len(blocks))
+	th.Equals(t, int64(30), *blocks[0].Ebs.VolumeSize)
+	th.Equals(t, "snap-12345", *blocks[0].Ebs.SnapshotId)
+	th.Equals(t, true, *blocks[0].Ebs.DeleteOnTermination)
+	th.Equals(t, int64(100), *blocks[1].Ebs.VolumeSize)
+
+	// The image itself is left unchanged
+	th.Equals(t, int64(8), *rootVolumeImage.BlockDeviceMappings[0].Ebs.VolumeSize)
+}
+
+func TestValidateRootVolumeType(t *testing.T) {
+	th.Ok(t, ec2helper.ValidateRootVolumeType("", 0, 0))
+	th.Ok(t, ec2helper.ValidateRootVolumeType(ec2.VolumeTypeGp2, 0, 0))
+	th.Ok(t, ec2helper.ValidateRootVolumeType(ec2.VolumeTypeGp3, 4000, 250))
+	th.Ok(t, ec2helper.ValidateRootVolumeType(ec2.VolumeTypeIo2, 10000, 0))
+
+	// HDD volumes can't be root volumes
+	th.Nok(t, ec2helper.ValidateRootVolumeType(ec2.VolumeTypeSt1, 0, 0))
+	// Provisioned IOPS volumes need their IOPS
+	th.Nok(t, ec2helper.ValidateRootVolumeType(ec2.VolumeTypeIo1, 0, 0))
+	// The API rejects IOPS and throughput on volumes that don't support them
+	th.Nok(t, ec2helper.ValidateRootVolumeType(ec2.VolumeTypeGp2, 3000, 0))
+	th.Nok(t, ec2helper.ValidateRootVolumeType(ec2.VolumeTypeIo2, 10000, 250))
+	th.Nok(t, ec2helper.ValidateRootVolumeType("", 3000, 0))
+}
+
+func TestLaunchInstance_RootVolumeType(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+	simpleConfig := &config.SimpleInfo{
+		ImageId:        "ami-12345",
+		InstanceType:   "t2.micro",
+		SubnetId:       "subnet-12345",
+		RootVolumeType: ec2.VolumeTypeIo2,
+		RootVolumeIops: 10000,
+	}
+	detailedConfig := &config.DetailedInfo{Image: rootVolumeImage}
+
+	_, err := testEC2.LaunchInstance(simpleConfig, detailedConfig, true)
+	th.Ok(t, err)
+
+	blocks := mockedSvc.RunInstancesInputs[0].BlockDeviceMappings
+	th.Equals(t, ec2.VolumeTypeIo2, *blocks[0].Ebs.VolumeType)
+	th.Equals(t, int64(10000), *blocks[0].Ebs.Iops)
+	th.Equals(t, (*int64)(nil), blocks[0].Ebs.Throughput)
+	th.Equals(t, int64(8), *blocks[0].Ebs.VolumeSize)
+	th.Equals(t, (*string)(nil), blocks[1].Ebs.VolumeType)
+}
+
+func TestCreateLaunchTemplate_RootVolumeSize(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		ImageId:                       "ami-12345",
+		InstanceType:                  "t2.micro",
+		SubnetId:                      "subnet-12345",
+		KeepEbsVolumeAfterTermination: true,
+		RootVolumeSize:                30,
+		RootVolumeType:                ec2.VolumeTypeGp3,
+		RootVolumeThroughput:          250,
+	}
+	detailedConfig := &config.DetailedInfo{Image: rootVolumeImage}
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	_, err := testEC2.CreateLaunchTemplate(simpleConfig, detailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, 1, len(mockedSvc.LaunchTemplateData))
+
+	blocks := mockedSvc.LaunchTemplateData[0].BlockDeviceMappings
+	th.Equals(t, 2, len(blocks))
+	th.Equals(t, int64(30), *blocks[0].Ebs.VolumeSize)
+	th.Equals(t, ec2.VolumeTypeGp3, *blocks[0].Ebs.VolumeType)
+	th.Equals(t, int64(250), *blocks[0].Ebs.Throughput)
+	th.Equals(t, false, *blocks[0].Ebs.DeleteOnTermination)
+	th.Equals(t, int64(100), *blocks[1].Ebs.VolumeSize)
+	th.Equals(t, false, *blocks[1].Ebs.DeleteOnTermination)
+}
+
+func TestCreateLaunchTemplate_CpuCredits(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		ImageId:      "ami-12345",
+		InstanceType: "t3.micro",
+		SubnetId:     "subnet-12345",
+		CpuCredits:   ec2helper.CpuCreditsUnlimited,
+	}
+	detailedConfig := &config.DetailedInfo{
+		Image: &ec2.Image{
+			ImageId:         aws.String("ami-12345"),
+			PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+		},
+	}
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	_, err := testEC2.CreateLaunchTemplate(simpleConfig, detailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, ec2helper.CpuCreditsUnlimited, *mockedSvc.LaunchTemplateData[0].CreditSpecification.CpuCredits)
+}
+
+func TestDryRunLaunchInstance_CpuCredits(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		ImageId:      "ami-12345",
+		InstanceType: "t3.micro",
+		SubnetId:     "subnet-12345",
+		CpuCredits:   ec2helper.CpuCreditsStandard,
+	}
+	detailedConfig := &config.DetailedInfo{
+		Image: &ec2.Image{
+			ImageId:         aws.String("ami-12345"),
+			PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+		},
+	}
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	err := testEC2.DryRunLaunchInstance(simpleConfig, detailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, ec2helper.CpuCreditsStandard, *mockedSvc.RunInstancesInputs[0].CreditSpecification.CpuCredits)
+
+	// No credit specification is requested without a CPU credit mode
+	simpleConfig.CpuCredits = ""
+	err = testEC2.DryRunLaunchInstance(simpleConfig, detailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, (*ec2.CreditSpecificationRequest)(nil), mockedSvc.RunInstancesInputs[1].CreditSpecification)
+}
+
+func TestCreateLaunchTemplate_CarrierIp(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		ImageId:            "ami-12345",
+		InstanceType:       "t3.medium",
+		SubnetId:           "subnet-12345",
+		AssociateCarrierIp: true,
+	}
+	detailedConfig := &config.DetailedInfo{
+		Image: &ec2.Image{
+			ImageId:         aws.String("ami-12345"),
+			PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+		},
+	}
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	_, err := testEC2.CreateLaunchTemplate(simpleConfig, detailedConfig)
+	th.Ok(t, err)
+
+	// The carrier IP replaces the public IP, since EC2 rejects both on one network interface
+	networkInterface := mockedSvc.LaunchTemplateData[0].NetworkInterfaces[0]
+	th.Equals(t, true, *networkInterface.AssociateCarrierIpAddress)
+	th.Equals(t, (*bool)(nil), networkInterface.AssociatePublicIpAddress)
+	th.Equals(t, "subnet-12345", *networkInterface.SubnetId)
+}
+
+func TestDryRunLaunchInstance_CarrierIp(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		ImageId:            "ami-12345",
+		InstanceType:       "t3.medium",
+		SubnetId:           "subnet-12345",
+		SecurityGroupIds:   []string{"sg-12345"},
+		PrivateIpAddress:   "10.0.0.10",
+		AssociateCarrierIp: true,
+	}
+	detailedConfig := &config.DetailedInfo{
+		Image: &ec2.Image{
+			ImageId:         aws.String("ami-12345"),
+			PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+		},
+	}
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	err := testEC2.DryRunLaunchInstance(simpleConfig, detailedConfig)
+	th.Ok(t, err)
+
+	// The network configuration moves to the network interface associating the carrier IP
+	input := mockedSvc.RunInstancesInputs[0]
+	th.Equals(t, []*ec2.InstanceNetworkInterfaceSpecification{
+		{
+			AssociateCarrierIpAddress: aws.Bool(true),
+			DeviceIndex:               aws.Int64(0),
+			Groups:                    aws.StringSlice([]string{"sg-12345"}),
+			SubnetId:                  aws.String("subnet-12345"),
+			PrivateIpAddress:          aws.String("10.0.0.10"),
+		},
+	}, input.NetworkInterfaces)
+	th.Equals(t, (*string)(nil), input.SubnetId)
+	th.Equals(t, ([]*string)(nil), input.SecurityGroupIds)
+	th.Equals(t, (*string)(nil), input.PrivateIpAddress)
+}
+
+func TestDryRunLaunchInstance_NoPublicIp(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		ImageId:          "ami-12345",
+		InstanceType:     "t3.medium",
+		SubnetId:         "subnet-12345",
+		SecurityGroupIds: []string{"sg-12345"},
+		NoPublicIp:       true,
+	}
+	detailedConfig := &config.DetailedInfo{
+		Image: &ec2.Image{
+			ImageId:         aws.String("ami-12345"),
+			PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+		},
+	}
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	err := testEC2.DryRunLaunchInstance(simpleConfig, detailedConfig)
+	th.Ok(t, err)
+
+	// The public IP is turned off on the network interface, whatever the setting of the subnet
+	input := mockedSvc.RunInstancesInputs[0]
+	th.Equals(t, []*ec2.InstanceNetworkInterfaceSpecification{
+		{
+			AssociatePublicIpAddress: aws.Bool(false),
+			DeviceIndex:              aws.Int64(0),
+			Groups:                   aws.StringSlice([]string{"sg-12345"}),
+			SubnetId:                 aws.String("subnet-12345"),
+		},
+	}, input.NetworkInterfaces)
+	th.Equals(t, (*string)(nil), input.SubnetId)
+
+	_, err = testEC2.CreateLaunchTemplate(simpleConfig, detailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, false, *mockedSvc.LaunchTemplateData[0].NetworkInterfaces[0].AssociatePublicIpAddress)
+}
+
+func TestValidateHostResourceGroupArn(t *testing.T) {
+	th.Ok(t, ec2helper.ValidateHostResourceGroupArn(""))
+	th.Ok(t, ec2helper.ValidateHostResourceGroupArn("arn:aws:resource-groups:us-east-1:123456789012:group/hosts"))
+	th.Nok(t, ec2helper.ValidateHostResourceGroupArn("hosts"))
+	th.Nok(t, ec2helper.ValidateHostResourceGroupArn("arn:aws:ec2:us-east-1:123456789012:dedicated-host/h-12345"))
+}
+
+func TestGetKeyPairs(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		KeyPairs: []*ec2.KeyPairInfo{
+			{KeyName: aws.String("key-1")},
+			{KeyName: aws.String("key-2")},
+		},
+	}
+
+	keyPairs, err := testEC2.GetKeyPairs()
+	th.Ok(t, err)
+	th.Equals(t, 2, len(keyPairs))
+
+	testEC2.Svc = &th.MockedEC2Svc{DescribeKeyPairsError: errors.New("Test error")}
+	_, err = testEC2.GetKeyPairs()
+	th.Nok(t, err)
+}
+
+func TestCreateKeyPair(t *testing.T) {
+	const testKeyName = "unit_test_key_pair"
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	keyPath, err := testEC2.CreateKeyPair(testKeyName)
+	defer os.Remove(keyPath)
+	th.Ok(t, err)
+	th.Equals(t, testKeyName+".pem", filepath.Base(keyPath))
+	th.Equals(t, testKeyName, *mockedSvc.KeyPairs[0].KeyName)
+
+	// The private key is only readable by the user
+	info, err := os.Stat(keyPath)
+	th.Ok(t, err)
+	th.Equals(t, os.FileMode(0600), info.Mode().Perm())
+
+	// An existing private key file is never overwritten
+	_, err = testEC2.CreateKeyPair(testKeyName)
+	th.Nok(t, err)
+	th.Equals(t, 1, len(mockedSvc.KeyPairs))
+}
+
+func TestValidateKeyPairName(t *testing.T) {
+	th.Ok(t, ec2helper.ValidateKeyPairName("simple-ec2-20240101-120000"))
+	th.Ok(t, ec2helper.ValidateKeyPairName("my_key.v2"))
+	th.Nok(t, ec2helper.ValidateKeyPairName(""))
+	th.Nok(t, ec2helper.ValidateKeyPairName("../key"))
+}
+
+func TestDryRunLaunchInstance_KeyPair(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		ImageId:      "ami-12345",
+		InstanceType: "t2.micro",
+		SubnetId:     "subnet-12345",
+		KeyPairName:  "my-key",
+	}
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	err := testEC2.DryRunLaunchInstance(simpleConfig, &testDetailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, "my-key", *mockedSvc.RunInstancesInputs[0].KeyName)
+
+	_, err = testEC2.CreateLaunchTemplate(simpleConfig, &testDetailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, "my-key", *mockedSvc.LaunchTemplateData[0].KeyName)
+}
+
+func TestValidateAffinity(t *testing.T) {
+	th.Ok(t, ec2helper.ValidateAffinity(""))
+	th.Ok(t, ec2helper.ValidateAffinity(ec2.AffinityDefault))
+	th.Ok(t, ec2helper.ValidateAffinity(ec2.AffinityHost))
+	th.Nok(t, ec2helper.ValidateAffinity("dedicated"))
+}
+
+func TestParseConfig_AffinityWithoutHostTenancy(t *testing.T) {
+	testEC2.Svc = inheritVpcTagsSvc
+	simpleConfig := &config.SimpleInfo{
+		SubnetId:         testSubnetId,
+		ImageId:          testImageId,
+		InstanceType:     testInstanceType,
+		SecurityGroupIds: testSecurityGroupIds,
+		Affinity:         ec2.AffinityHost,
+	}
+
+	_, err := testEC2.ParseConfig(simpleConfig)
+	th.Nok(t, err)
+	th.Equals(t, "Affinity can only be set with the host tenancy of a host resource group", err.Error())
+}
+
+func TestDryRunLaunchInstance_Affinity(t *testing.T) {
+	const testHostResourceGroupArn = "arn:aws:resource-groups:us-east-1:123456789012:group/hosts"
+	simpleConfig := &config.SimpleInfo{
+		ImageId:              "ami-12345",
+		InstanceType:         "m5.large",
+		SubnetId:             "subnet-12345",
+		HostResourceGroupArn: testHostResourceGroupArn,
+		Affinity:             ec2.AffinityHost,
+	}
+	detailedConfig := &config.DetailedInfo{
+		Image: &ec2.Image{
+			ImageId:         aws.String("ami-12345"),
+			PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+		},
+	}
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	err := testEC2.DryRunLaunchInstance(simpleConfig, detailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, &ec2.Placement{
+		Affinity:             aws.String(ec2.AffinityHost),
+		Tenancy:              aws.String(ec2.TenancyHost),
+		HostResourceGroupArn: aws.String(testHostResourceGroupArn),
+	}, mockedSvc.RunInstancesInputs[0].Placement)
+}
+
+func TestDryRunLaunchInstance_HostResourceGroup(t *testing.T) {
+	const testHostResourceGroupArn = "arn:aws:resource-groups:us-east-1:123456789012:group/hosts"
+	simpleConfig := &config.SimpleInfo{
+		ImageId:              "ami-12345",
+		InstanceType:         "m5.large",
+		SubnetId:             "subnet-12345",
+		HostResourceGroupArn: testHostResourceGroupArn,
+	}
+	detailedConfig := &config.DetailedInfo{
+		Image: &ec2.Image{
+			ImageId:         aws.String("ami-12345"),
+			PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+		},
+	}
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	err := testEC2.DryRunLaunchInstance(simpleConfig, detailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, &ec2.Placement{
+		Tenancy:              aws.String(ec2.TenancyHost),
+		HostResourceGroupArn: aws.String(testHostResourceGroupArn),
+	}, mockedSvc.RunInstancesInputs[0].Placement)
+
+	// Instances aren't placed on Dedicated Hosts without a host resource group
+	simpleConfig.HostResourceGroupArn = ""
+	err = testEC2.DryRunLaunchInstance(simpleConfig, detailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, (*ec2.Placement)(nil), mockedSvc.RunInstancesInputs[1].Placement)
+}
+
+func TestCreateLaunchTemplate_PrivateDnsNameOptions(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		ImageId:                         "ami-12345",
+		InstanceType:                    "t2.micro",
+		SubnetId:                        "subnet-12345",
+		PrivateDnsHostnameType:          ec2.HostnameTypeResourceName,
+		EnableResourceNameDnsAAAARecord: true,
+	}
+	detailedConfig := &config.DetailedInfo{
+		Image: &ec2.Image{
+			ImageId:         aws.String("ami-12345"),
+			PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+		},
+	}
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	_, err := testEC2.CreateLaunchTemplate(simpleConfig, detailedConfig)
+	th.Ok(t, err)
+
+	privateDnsNameOptions := mockedSvc.LaunchTemplateData[0].PrivateDnsNameOptions
+	th.Equals(t, ec2.HostnameTypeResourceName, *privateDnsNameOptions.HostnameType)
+	th.Equals(t, false, *privateDnsNameOptions.EnableResourceNameDnsARecord)
+	th.Equals(t, true, *privateDnsNameOptions.EnableResourceNameDnsAAAARecord)
+}
+
+func TestDryRunLaunchInstance_PrivateDnsNameOptions(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		ImageId:                      "ami-12345",
+		InstanceType:                 "t2.micro",
+		SubnetId:                     "subnet-12345",
+		EnableResourceNameDnsARecord: true,
+	}
+	detailedConfig := &config.DetailedInfo{
+		Image: &ec2.Image{
+			ImageId:         aws.String("ami-12345"),
+			PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+		},
+	}
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	err := testEC2.DryRunLaunchInstance(simpleConfig, detailedConfig)
+	th.Ok(t, err)
+
+	privateDnsNameOptions := mockedSvc.RunInstancesInputs[0].PrivateDnsNameOptions
+	th.Equals(t, (*string)(nil), privateDnsNameOptions.HostnameType)
+	th.Equals(t, true, *privateDnsNameOptions.EnableResourceNameDnsARecord)
+	th.Equals(t, false, *privateDnsNameOptions.EnableResourceNameDnsAAAARecord)
+}
+
+func TestDryRunLaunchInstance_NoPrivateDnsNameOptions(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		ImageId:      "ami-12345",
+		InstanceType: "t2.micro",
+		SubnetId:     "subnet-12345",
+	}
+	detailedConfig := &config.DetailedInfo{
+		Image: &ec2.Image{
+			ImageId:         aws.String("ami-12345"),
+			PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+		},
+	}
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	err := testEC2.DryRunLaunchInstance(simpleConfig, detailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, (*ec2.PrivateDnsNameOptionsRequest)(nil), mockedSvc.RunInstancesInputs[0].PrivateDnsNameOptions)
+}
+
+func TestGetUserData_TimerAndBootScript(t *testing.T) {
+	bootScript, err := ioutil.TempFile("", "boot_script")
+	th.Ok(t, err)
+	defer os.Remove(bootScript.Name())
+	_, err = bootScript.WriteString("#!/bin/bash\necho hello\n")
+	th.Ok(t, err)
+	bootScript.Close()
+
+	simpleConfig := &config.SimpleInfo{
+		AutoTerminationTimerMinutes: 30,
+		BootScriptFilePath:          bootScript.Name(),
+	}
+	detailedConfig := &config.DetailedInfo{
+		Image: &ec2.Image{
+			PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+		},
+	}
+
+	expectedUserData := "#!/bin/bash\necho \"sudo poweroff\" | at now + 30 minutes\n\necho hello\n"
+	th.Equals(t, expectedUserData, ec2helper.GetUserData(simpleConfig, detailedConfig))
+}
+
+func TestGetUserData_None(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{}
+	detailedConfig := &config.DetailedInfo{
+		Image: &ec2.Image{
+			PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+		},
+	}
+
+	th.Equals(t, "", ec2helper.GetUserData(simpleConfig, detailedConfig))
+}
+
+func TestGetLaunchUserData_EditedUserData(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		AutoTerminationTimerMinutes: 30,
+	}
+	detailedConfig := &config.DetailedInfo{
+		Image: &ec2.Image{
+			PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+		},
+		UserData: aws.String("#cloud-config\npackages:\n  - git\n"),
+	}
+
+	th.Equals(t, *detailedConfig.UserData, ec2helper.GetLaunchUserData(simpleConfig, detailedConfig))
+}
+
+func TestValidateUserData_ValidCloudConfig(t *testing.T) {
+	th.Ok(t, ec2helper.ValidateUserData("#cloud-config\npackages:\n  - git\nruncmd:\n  - [echo, hello]\n"))
+}
+
+func TestValidateUserData_EmptyCloudConfig(t *testing.T) {
+	th.Ok(t, ec2helper.ValidateUserData("#cloud-config\n"))
+}
+
+func TestValidateUserData_InvalidYaml(t *testing.T) {
+	err := ec2helper.ValidateUserData("#cloud-config\npackages:\n  - git\n runcmd: [echo\n")
+	th.Nok(t, err)
+	th.Assert(t, strings.Contains(err.Error(), "not valid YAML"), "Unexpected error: "+err.Error())
+}
+
+func TestValidateUserData_NotMapping(t *testing.T) {
+	th.Nok(t, ec2helper.ValidateUserData("#cloud-config\n- git\n"))
+}
+
+func TestValidateUserData_ShellScript(t *testing.T) {
+	th.Ok(t, ec2helper.ValidateUserData("#!/bin/bash\necho: [unbalanced\n"))
+}
+
+var testPlatformBootScriptConfig = &config.SimpleInfo{
+	BootScriptLinuxFilePath:   "linux_boot_script",
+	BootScriptWindowsFilePath: "windows_boot_script",
+}
+
+func TestGetBootScriptFilePath_Linux(t *testing.T) {
+	image := &ec2.Image{
+		PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+	}
+
+	th.Equals(t, "linux_boot_script", ec2helper.GetBootScriptFilePath(testPlatformBootScriptConfig, image))
+}
+
+func TestGetBootScriptFilePath_Windows(t *testing.T) {
+	image := &ec2.Image{
+		Platform:        aws.String(ec2.PlatformValuesWindows),
+		PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformWindows),
+	}
+
+	th.Equals(t, "windows_boot_script", ec2helper.GetBootScriptFilePath(testPlatformBootScriptConfig, image))
+}
+
+func TestGetBootScriptFilePath_AllPlatformsWins(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		BootScriptFilePath:      "boot_script",
+		BootScriptLinuxFilePath: "linux_boot_script",
+	}
+	image := &ec2.Image{
+		PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+	}
+
+	th.Equals(t, "boot_script", ec2helper.GetBootScriptFilePath(simpleConfig, image))
+}
+
+func TestGetBootScriptFilePath_NoMatch(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		BootScriptWindowsFilePath: "windows_boot_script",
+	}
+	image := &ec2.Image{
+		PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+	}
+
+	th.Equals(t, "", ec2helper.GetBootScriptFilePath(simpleConfig, image))
+}
+
+func TestSetBootScriptFilePath_Platform(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		BootScriptLinuxFilePath:   "linux_boot_script",
+		BootScriptWindowsFilePath: "windows_boot_script",
+	}
+	image := &ec2.Image{
+		PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+	}
+
+	ec2helper.SetBootScriptFilePath(simpleConfig, image, "new_linux_boot_script")
+	th.Equals(t, &config.SimpleInfo{
+		BootScriptLinuxFilePath:   "new_linux_boot_script",
+		BootScriptWindowsFilePath: "windows_boot_script",
+	}, simpleConfig)
+}
+
+func TestSetBootScriptFilePath_AllPlatforms(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		BootScriptFilePath:      "boot_script",
+		BootScriptLinuxFilePath: "linux_boot_script",
+	}
+	image := &ec2.Image{
+		PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+	}
+
+	ec2helper.SetBootScriptFilePath(simpleConfig, image, "new_boot_script")
+	th.Equals(t, "new_boot_script", simpleConfig.BootScriptFilePath)
+	th.Equals(t, "linux_boot_script", simpleConfig.BootScriptLinuxFilePath)
+
+	// Removing the boot script removes the boot script for the platform too
+	ec2helper.SetBootScriptFilePath(simpleConfig, image, "")
+	th.Equals(t, "", ec2helper.GetBootScriptFilePath(simpleConfig, image))
+}
+
+func TestSetBootScriptFilePath_NoPlatform(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{}
+
+	ec2helper.SetBootScriptFilePath(simpleConfig, &ec2.Image{}, "boot_script")
+	th.Equals(t, "boot_script", simpleConfig.BootScriptFilePath)
+}
+
+func TestParseConfig_PlatformBootScriptNoMatch(t *testing.T) {
+	testEC2.Svc = inheritVpcTagsSvc
+	simpleConfig := &config.SimpleInfo{
+		SubnetId:                  testSubnetId,
+		ImageId:                   testImageId,
+		InstanceType:              testInstanceType,
+		SecurityGroupIds:          testSecurityGroupIds,
+		BootScriptWindowsFilePath: "windows_boot_script",
+	}
+
+	_, err := testEC2.ParseConfig(simpleConfig)
+	th.Nok(t, err)
+}
+
+func TestCreateLaunchTemplate_EditedUserData(t *testing.T) {
+	const editedUserData = "#!/bin/bash\necho edited\n"
+	simpleConfig := &config.SimpleInfo{
+		ImageId:                     "ami-12345",
+		InstanceType:                "t2.micro",
+		SubnetId:                    "subnet-12345",
+		AutoTerminationTimerMinutes: 30,
+	}
+	detailedConfig := &config.DetailedInfo{
+		Image: &ec2.Image{
+			ImageId:         aws.String("ami-12345"),
+			PlatformDetails: aws.String(ec2.CapacityReservationInstancePlatformLinuxUnix),
+		},
+		UserData: aws.String(editedUserData),
+	}
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	_, err := testEC2.CreateLaunchTemplate(simpleConfig, detailedConfig)
+	th.Ok(t, err)
+
+	userData, err := base64.StdEncoding.DecodeString(*mockedSvc.LaunchTemplateData[0].UserData)
+	th.Ok(t, err)
+	th.Equals(t, editedUserData, string(userData))
+	th.Equals(t, ec2.ShutdownBehaviorTerminate, *mockedSvc.LaunchTemplateData[0].InstanceInitiatedShutdownBehavior)
+}
+
+var testUefiInstanceType = &ec2.InstanceTypeInfo{
+	InstanceType:       aws.String("m6i.large"),
+	SupportedBootModes: aws.StringSlice([]string{ec2.BootModeValuesLegacyBios, ec2.BootModeValuesUefi}),
+}
+var testLegacyInstanceType = &ec2.InstanceTypeInfo{
+	InstanceType:       aws.String("t2.micro"),
+	SupportedBootModes: aws.StringSlice([]string{ec2.BootModeValuesLegacyBios}),
+}
+
+func TestGetImageBootMode(t *testing.T) {
+	th.Equals(t, ec2.BootModeValuesUefi, ec2helper.GetImageBootMode(&ec2.Image{
+		BootMode: aws.String(ec2.BootModeValuesUefi),
+	}))
+	th.Equals(t, ec2.BootModeValuesLegacyBios, ec2helper.GetImageBootMode(&ec2.Image{
+		Architecture: aws.String(ec2.ArchitectureValuesX8664),
+	}))
+	th.Equals(t, ec2.BootModeValuesUefi, ec2helper.GetImageBootMode(&ec2.Image{
+		Architecture: aws.String(ec2.ArchitectureValuesArm64),
+	}))
+}
+
+func TestValidateBootMode_NotRequested(t *testing.T) {
+	err := ec2helper.ValidateBootMode("", false, &ec2.Image{}, testLegacyInstanceType)
+	th.Ok(t, err)
+}
+
+func TestValidateBootMode_UefiWithTpm(t *testing.T) {
+	testImage := &ec2.Image{
+		ImageId:    aws.String("ami-12345"),
+		BootMode:   aws.String(ec2.BootModeValuesUefi),
+		TpmSupport: aws.String(ec2.TpmSupportValuesV20),
+	}
+
+	err := ec2helper.ValidateBootMode(ec2.BootModeValuesUefi, true, testImage, testUefiInstanceType)
+	th.Ok(t, err)
+}
+
+func TestValidateBootMode_ImageMismatch(t *testing.T) {
+	testImage := &ec2.Image{
+		ImageId:      aws.String("ami-12345"),
+		Architecture: aws.String(ec2.ArchitectureValuesX8664),
+	}
+
+	err := ec2helper.ValidateBootMode(ec2.BootModeValuesUefi, false, testImage, testUefiInstanceType)
+	th.Nok(t, err)
+}
+
+func TestValidateBootMode_InstanceTypeNotSupported(t *testing.T) {
+	testImage := &ec2.Image{
+		ImageId:  aws.String("ami-12345"),
+		BootMode: aws.String(ec2.BootModeValuesUefi),
+	}
+
+	err := ec2helper.ValidateBootMode(ec2.BootModeValuesUefi, false, testImage, testLegacyInstanceType)
+	th.Nok(t, err)
+}
+
+func TestValidateBootMode_UefiPreferred(t *testing.T) {
+	testImage := &ec2.Image{
+		ImageId:  aws.String("ami-12345"),
+		BootMode: aws.String(ec2helper.BootModeUefiPreferred),
+	}
+
+	// The image boots with UEFI when the instance type supports it, and legacy BIOS otherwise
+	err := ec2helper.ValidateBootMode(ec2.BootModeValuesUefi, false, testImage, testUefiInstanceType)
+	th.Ok(t, err)
+	err = ec2helper.ValidateBootMode(ec2.BootModeValuesLegacyBios, false, testImage, testUefiInstanceType)
+	th.Nok(t, err)
+	err = ec2helper.ValidateBootMode(ec2.BootModeValuesLegacyBios, false, testImage, testLegacyInstanceType)
+	th.Ok(t, err)
+	err = ec2helper.ValidateBootMode(ec2helper.BootModeUefiPreferred, false, testImage, testLegacyInstanceType)
+	th.Ok(t, err)
+}
+
+func TestValidateBootMode_TpmNotSupportedByImage(t *testing.T) {
+	testImage := &ec2.Image{
+		ImageId:  aws.String("ami-12345"),
+		BootMode: aws.String(ec2.BootModeValuesUefi),
+	}
+
+	err := ec2helper.ValidateBootMode("", true, testImage, testUefiInstanceType)
+	th.Nok(t, err)
+}
+
+func TestValidateBootMode_TpmRequiresUefi(t *testing.T) {
+	testImage := &ec2.Image{
+		ImageId:    aws.String("ami-12345"),
+		BootMode:   aws.String(ec2helper.BootModeUefiPreferred),
+		TpmSupport: aws.String(ec2.TpmSupportValuesV20),
+	}
+
+	err := ec2helper.ValidateBootMode("", true, testImage, testLegacyInstanceType)
+	th.Nok(t, err)
+}
+
+func TestValidateBootMode_Invalid(t *testing.T) {
+	err := ec2helper.ValidateBootMode("bios", false, &ec2.Image{}, testUefiInstanceType)
+	th.Nok(t, err)
+}
+
+func TestIsLinux_True(t *testing.T) {
+	actualIsLinux := ec2helper.IsLinux(ec2.CapacityReservationInstancePlatformLinuxUnix)
+	th.Equals(t, true, actualIsLinux)
+}
+
+func TestIsLinux_False(t *testing.T) {
+	actualIsLinux := ec2helper.IsLinux(ec2.CapacityReservationInstancePlatformWindows)
+	th.Equals(t, false, actualIsLinux)
+}
+
+func TestHasEbsVolume_True(t *testing.T) {
+	testImage := &ec2.Image{
+		BlockDeviceMappings: []*ec2.BlockDeviceMapping{
+			{
+				Ebs: &ec2.EbsBlockDevice{},
+			},
+		},
+	}
+
+	actualHasEbsVolume := ec2helper.HasEbsVolume(testImage)
+	th.Equals(t, true, actualHasEbsVolume)
+}
+
+func TestHasEbsVolume_False(t *testing.T) {
+	testImage := &ec2.Image{
+		BlockDeviceMappings: []*ec2.BlockDeviceMapping{
+			{},
+		},
+	}
+
+	actualHasEbsVolume := ec2helper.HasEbsVolume(testImage)
+	th.Equals(t, false, actualHasEbsVolume)
 }
 
 /*
-Tag Tests
+Cleanup Tests
 */
 
-func TestGetTagName_Success(t *testing.T) {
-	const testName = "Test Name"
-	testTags := []*ec2.Tag{
-		{
-			Key:   aws.String("Name"),
-			Value: aws.String(testName),
+var simpleEc2ResourceTags = []*ec2.Tag{
+	{Key: aws.String("CreatedBy"), Value: aws.String("simple-ec2")},
+}
+
+func TestGetOrphanedSecurityGroups(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		SecurityGroups: []*ec2.SecurityGroup{
+			{GroupId: aws.String("sg-instance"), GroupName: aws.String("used by instance"), Tags: simpleEc2ResourceTags},
+			{GroupId: aws.String("sg-eni"), GroupName: aws.String("used by eni"), Tags: simpleEc2ResourceTags},
+			{GroupId: aws.String("sg-orphan"), GroupName: aws.String("orphan"), Tags: simpleEc2ResourceTags},
+			{
+				GroupId:   aws.String("sg-stack"),
+				GroupName: aws.String("in stack"),
+				Tags: append([]*ec2.Tag{
+					{Key: aws.String("aws:cloudformation:stack-name"), Value: aws.String("simple-ec2-stack")},
+				}, simpleEc2ResourceTags...),
+			},
 		},
+	}
+	instances := []*ec2.Instance{
 		{
-			Key:   aws.String("CreatedBy"),
-			Value: aws.String("simple-ec2"),
+			InstanceId:     aws.String("i-12345"),
+			SecurityGroups: []*ec2.GroupIdentifier{{GroupId: aws.String("sg-instance")}},
+			NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+				{Groups: []*ec2.GroupIdentifier{{GroupId: aws.String("sg-eni")}}},
+			},
 		},
 	}
 
-	actualName := ec2helper.GetTagName(testTags)
-	th.Equals(t, testName, *actualName)
+	resources, err := testEC2.GetOrphanedSecurityGroups(instances)
+	th.Ok(t, err)
+	th.Equals(t, []ec2helper.OrphanedResource{
+		{Type: cfn.ResourceTypeSecurityGroup, Id: "sg-orphan", Name: "orphan"},
+	}, resources)
 }
 
-func TestGetTagName_NoResult(t *testing.T) {
-	testTags := []*ec2.Tag{
-		{
-			Key:   aws.String("CreatedTime"),
-			Value: aws.String("012345"),
-		},
-		{
-			Key:   aws.String("CreatedBy"),
-			Value: aws.String("simple-ec2"),
-		},
+func TestGetOrphanedSecurityGroups_DescribeSecurityGroupsPagesError(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		DescribeSecurityGroupsPagesError: errors.New("Test error"),
 	}
 
-	actualName := ec2helper.GetTagName(testTags)
-	th.Equals(t, (*string)(nil), actualName)
+	_, err := testEC2.GetOrphanedSecurityGroups(nil)
+	th.Nok(t, err)
 }
 
-/*
-Validation Tests
-*/
-
-func TestValidateImageId_True(t *testing.T) {
+func TestGetOrphanedLaunchTemplates(t *testing.T) {
 	testEC2.Svc = &th.MockedEC2Svc{
-		Images: []*ec2.Image{
+		LaunchTemplates: []*ec2.LaunchTemplate{
+			{LaunchTemplateId: aws.String("lt-fleet"), LaunchTemplateName: aws.String("SimpleEC2LaunchTemplate-fleet")},
+			{LaunchTemplateId: aws.String("lt-instance"), LaunchTemplateName: aws.String("SimpleEC2LaunchTemplate-instance")},
+			{LaunchTemplateId: aws.String("lt-orphan"), LaunchTemplateName: aws.String("SimpleEC2LaunchTemplate-orphan")},
+			{LaunchTemplateId: aws.String("lt-other"), LaunchTemplateName: aws.String("other-template")},
 			{
-				ImageId: aws.String(testImageId),
+				LaunchTemplateId:   aws.String("lt-tagged"),
+				LaunchTemplateName: aws.String("tagged-template"),
+				Tags:               simpleEc2ResourceTags,
+			},
+		},
+		Fleets: []*ec2.FleetData{
+			{
+				LaunchTemplateConfigs: []*ec2.FleetLaunchTemplateConfig{
+					{
+						LaunchTemplateSpecification: &ec2.FleetLaunchTemplateSpecification{
+							LaunchTemplateId: aws.String("lt-fleet"),
+						},
+					},
+				},
+			},
+		},
+	}
+	instances := []*ec2.Instance{
+		{
+			InstanceId: aws.String("i-12345"),
+			Tags: []*ec2.Tag{
+				{Key: aws.String("aws:ec2launchtemplate:id"), Value: aws.String("lt-instance")},
 			},
 		},
 	}
 
-	result := ec2helper.ValidateImageId(testEC2, testImageId)
-	th.Equals(t, true, result)
+	resources, err := testEC2.GetOrphanedLaunchTemplates(instances)
+	th.Ok(t, err)
+	th.Equals(t, []ec2helper.OrphanedResource{
+		{Type: cfn.ResourceTypeLaunchTemplate, Id: "lt-orphan", Name: "SimpleEC2LaunchTemplate-orphan"},
+		{Type: cfn.ResourceTypeLaunchTemplate, Id: "lt-tagged", Name: "tagged-template"},
+	}, resources)
 }
 
-func TestValidateImageId_False(t *testing.T) {
+func TestGetOrphanedLaunchTemplates_DescribeFleetsPagesError(t *testing.T) {
 	testEC2.Svc = &th.MockedEC2Svc{
-		Images: []*ec2.Image{},
+		DescribeFleetsPagesError: errors.New("Test error"),
 	}
 
-	result := ec2helper.ValidateImageId(testEC2, testImageId)
-	th.Equals(t, false, result)
+	_, err := testEC2.GetOrphanedLaunchTemplates(nil)
+	th.Nok(t, err)
 }
 
-func TestValidateFilepath_True(t *testing.T) {
-	tmpFile, err := ioutil.TempFile("", "mocked_filepath")
-	defer os.Remove(tmpFile.Name())
-	if err != nil {
-		t.Errorf("There was an error creating tempfile: %v", err)
+func TestGetOrphanedStacks(t *testing.T) {
+	const testStackVpcId = "vpc-stack"
+	testCfn := &cfn.Cfn{
+		Svc: &th.MockedCfnSvc{
+			Stacks: []*cloudformation.Stack{
+				{
+					StackId:     aws.String("stack-id"),
+					StackName:   aws.String("simple-ec2-stack"),
+					StackStatus: aws.String(cloudformation.StackStatusCreateComplete),
+					Tags: []*cloudformation.Tag{
+						{Key: aws.String("CreatedBy"), Value: aws.String("simple-ec2")},
+					},
+				},
+			},
+			StackResources: []*cloudformation.StackResource{
+				{
+					ResourceType:       aws.String(cfn.ResourceTypeVpc),
+					PhysicalResourceId: aws.String(testStackVpcId),
+				},
+			},
+		},
 	}
-	result := ec2helper.ValidateFilepath(testEC2, tmpFile.Name())
-	th.Equals(t, true, result)
+
+	// An instance in the VPC of the stack keeps the stack
+	resources, err := testEC2.GetOrphanedStacks(testCfn, []*ec2.Instance{
+		{InstanceId: aws.String("i-12345"), VpcId: aws.String(testStackVpcId)},
+	})
+	th.Ok(t, err)
+	th.Equals(t, 0, len(resources))
+
+	resources, err = testEC2.GetOrphanedStacks(testCfn, []*ec2.Instance{
+		{InstanceId: aws.String("i-12345"), VpcId: aws.String("vpc-other")},
+	})
+	th.Ok(t, err)
+	th.Equals(t, []ec2helper.OrphanedResource{
+		{Type: cfn.ResourceTypeStack, Id: "stack-id", Name: "simple-ec2-stack"},
+	}, resources)
 }
 
-func TestValidateFilepath_False(t *testing.T) {
-	result := ec2helper.ValidateFilepath(testEC2, "file/does/not/exist")
-	th.Equals(t, false, result)
+func TestGetOrphanedStacks_InProgress(t *testing.T) {
+	testCfn := &cfn.Cfn{
+		Svc: &th.MockedCfnSvc{
+			Stacks: []*cloudformation.Stack{
+				{
+					StackId:     aws.String("stack-id"),
+					StackName:   aws.String("simple-ec2-stack"),
+					StackStatus: aws.String(cloudformation.StackStatusCreateInProgress),
+					Tags: []*cloudformation.Tag{
+						{Key: aws.String("CreatedBy"), Value: aws.String("simple-ec2")},
+					},
+				},
+			},
+			StackResources: []*cloudformation.StackResource{
+				{
+					ResourceType:       aws.String(cfn.ResourceTypeVpc),
+					PhysicalResourceId: aws.String("vpc-stack"),
+				},
+			},
+		},
+	}
+
+	// The stack of a launch still in progress has no instances yet, so it isn't orphaned
+	resources, err := testEC2.GetOrphanedStacks(testCfn, nil)
+	th.Ok(t, err)
+	th.Equals(t, 0, len(resources))
 }
 
-func TestValidateTags_True(t *testing.T) {
-	testUserInput := "tag1|val1,tag2|val2"
-	result := ec2helper.ValidateTags(testEC2, testUserInput)
-	th.Equals(t, true, result)
+func TestGetOrphanedStacks_DescribeStackResourcesError(t *testing.T) {
+	testCfn := &cfn.Cfn{
+		Svc: &th.MockedCfnSvc{
+			Stacks: []*cloudformation.Stack{
+				{
+					StackName:   aws.String("simple-ec2-stack"),
+					StackStatus: aws.String(cloudformation.StackStatusCreateComplete),
+					Tags: []*cloudformation.Tag{
+						{Key: aws.String("CreatedBy"), Value: aws.String("simple-ec2")},
+					},
+				},
+			},
+			DescribeStackResourcesError: errors.New("Test error"),
+		},
+	}
+
+	_, err := testEC2.GetOrphanedStacks(testCfn, nil)
+	th.Nok(t, err)
 }
 
-func TestValidateTags_False(t *testing.T) {
-	testUserInput := "tag1|val1,tag2|val2,tag3"
-	result := ec2helper.ValidateTags(testEC2, testUserInput)
-	th.Equals(t, false, result)
+func TestFindOrphanedResources_JsonLines(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		SecurityGroups: []*ec2.SecurityGroup{
+			{GroupId: aws.String("sg-orphan"), GroupName: aws.String("orphan"), Tags: simpleEc2ResourceTags},
+		},
+		LaunchTemplates: []*ec2.LaunchTemplate{
+			{LaunchTemplateId: aws.String("lt-orphan"), LaunchTemplateName: aws.String("SimpleEC2LaunchTemplate-orphan")},
+		},
+	}
+	testCfn := &cfn.Cfn{
+		Svc: &th.MockedCfnSvc{
+			Stacks: []*cloudformation.Stack{
+				{
+					StackId:     aws.String("stack-id"),
+					StackName:   aws.String("simple-ec2-stack"),
+					StackStatus: aws.String(cloudformation.StackStatusCreateComplete),
+					Tags: []*cloudformation.Tag{
+						{Key: aws.String("CreatedBy"), Value: aws.String("simple-ec2")},
+					},
+				},
+			},
+			StackResources: []*cloudformation.StackResource{
+				{
+					ResourceType:       aws.String(cfn.ResourceTypeVpc),
+					PhysicalResourceId: aws.String("vpc-stack"),
+				},
+			},
+		},
+	}
+
+	buffer := &bytes.Buffer{}
+	writer := output.NewJsonLinesWriter(buffer)
+	err := testEC2.FindOrphanedResources(testCfn, func(resource ec2helper.OrphanedResource) error {
+		return writer.Write(resource)
+	})
+	th.Ok(t, err)
+
+	// Every line is a well-formed JSON object of a resource, in the order the resources are found
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	th.Equals(t, []string{
+		`{"type":"AWS::EC2::SecurityGroup","id":"sg-orphan","name":"orphan"}`,
+		`{"type":"AWS::EC2::LaunchTemplate","id":"lt-orphan","name":"SimpleEC2LaunchTemplate-orphan"}`,
+		`{"type":"AWS::CloudFormation::Stack","id":"stack-id","name":"simple-ec2-stack"}`,
+	}, lines)
+	for _, line := range lines {
+		var resource ec2helper.OrphanedResource
+		th.Ok(t, json.Unmarshal([]byte(line), &resource))
+	}
 }
 
-func TestValidateInteger_True(t *testing.T) {
-	testUserInput := "123"
-	result := ec2helper.ValidateInteger(testEC2, testUserInput)
-	th.Equals(t, true, result)
+func TestFindOrphanedResources_FunctionError(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		SecurityGroups: []*ec2.SecurityGroup{
+			{GroupId: aws.String("sg-orphan"), Tags: simpleEc2ResourceTags},
+		},
+	}
+	testCfn := &cfn.Cfn{Svc: &th.MockedCfnSvc{}}
+
+	calls := 0
+	err := testEC2.FindOrphanedResources(testCfn, func(resource ec2helper.OrphanedResource) error {
+		calls++
+		return errors.New("Test error")
+	})
+	th.Nok(t, err)
+	th.Equals(t, 1, calls)
 }
 
-func TestValidateInteger_False(t *testing.T) {
-	floatInput := "123.456"
-	floatResult := ec2helper.ValidateInteger(testEC2, floatInput)
-	th.Equals(t, false, floatResult)
+func TestDeleteOrphanedResource(t *testing.T) {
+	mockedEc2 := &th.MockedEC2Svc{}
+	mockedCfn := &th.MockedCfnSvc{}
+	testEC2.Svc = mockedEc2
+	testCfn := &cfn.Cfn{Svc: mockedCfn}
+
+	th.Ok(t, testEC2.DeleteOrphanedResource(testCfn, ec2helper.OrphanedResource{
+		Type: cfn.ResourceTypeSecurityGroup, Id: "sg-orphan",
+	}))
+	th.Ok(t, testEC2.DeleteOrphanedResource(testCfn, ec2helper.OrphanedResource{
+		Type: cfn.ResourceTypeLaunchTemplate, Id: "lt-orphan",
+	}))
+	th.Ok(t, testEC2.DeleteOrphanedResource(testCfn, ec2helper.OrphanedResource{
+		Type: cfn.ResourceTypeStack, Id: "stack-id", Name: "simple-ec2-stack",
+	}))
+	th.Nok(t, testEC2.DeleteOrphanedResource(testCfn, ec2helper.OrphanedResource{
+		Type: "AWS::EC2::Volume", Id: "vol-orphan",
+	}))
+
+	th.Equals(t, []string{"sg-orphan"}, mockedEc2.DeletedSecurityGroupIds)
+	th.Equals(t, []string{"lt-orphan"}, mockedEc2.DeletedLaunchTemplateIds)
+	th.Equals(t, []string{"simple-ec2-stack"}, mockedCfn.DeletedStackNames)
+}
 
-	stringInput := "abcdefg"
-	stringResult := ec2helper.ValidateInteger(testEC2, stringInput)
-	th.Equals(t, false, stringResult)
+func TestGetLaunchCliCommands_OnDemand(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		SubnetId:         testSubnetId,
+		ImageId:          testImageId,
+		InstanceType:     testInstanceType,
+		SecurityGroupIds: testSecurityGroupIds,
+		InstanceCount:    2,
+	}
 
-	alphanumericInput := "123abc"
-	alphanumericResult := ec2helper.ValidateInteger(testEC2, alphanumericInput)
-	th.Equals(t, false, alphanumericResult)
+	commands, err := ec2helper.GetLaunchCliCommands(simpleConfig, &testDetailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, 1, len(commands))
+	for _, part := range []string{
+		"aws ec2 run-instances ",
+		"--image-id " + testImageId,
+		"--instance-type " + testInstanceType,
+		"--subnet-id " + testSubnetId,
+		"--security-group-ids " + strings.Join(testSecurityGroupIds, " "),
+		"--client-token ",
+		"--count 2",
+	} {
+		th.Assert(t, strings.Contains(commands[0], part), commands[0]+" should contain "+part)
+	}
+	th.Assert(t, !strings.Contains(commands[0], "--max-count"), "The instance count should only be given by --count")
 }
 
-func TestIsLinux_True(t *testing.T) {
-	actualIsLinux := ec2helper.IsLinux(ec2.CapacityReservationInstancePlatformLinuxUnix)
-	th.Equals(t, true, actualIsLinux)
+func TestGetLaunchCliCommands_Spot(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		SubnetId:         testSubnetId,
+		ImageId:          testImageId,
+		InstanceType:     testInstanceType,
+		SecurityGroupIds: testSecurityGroupIds,
+		CapacityType:     config.CapacityTypeSpot,
+	}
+
+	commands, err := ec2helper.GetLaunchCliCommands(simpleConfig, &testDetailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, 2, len(commands))
+	th.Assert(t, strings.HasPrefix(commands[0], "aws ec2 create-launch-template "),
+		"The launch template should be created first")
+	th.Assert(t, strings.Contains(commands[0], `"ImageId":"`+testImageId+`"`), commands[0]+" should contain the image")
+	th.Assert(t, strings.HasPrefix(commands[1], "aws ec2 create-fleet "), "The fleet should be created last")
+	th.Assert(t, strings.Contains(commands[1], "$Latest"), commands[1]+" should use the latest template version")
 }
 
-func TestIsLinux_False(t *testing.T) {
-	actualIsLinux := ec2helper.IsLinux(ec2.CapacityReservationInstancePlatformWindows)
-	th.Equals(t, false, actualIsLinux)
+func TestGetLaunchCliCommands_NewVPC(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		ImageId:      testImageId,
+		InstanceType: testInstanceType,
+		NewVPC:       true,
+	}
+
+	_, err := ec2helper.GetLaunchCliCommands(simpleConfig, &testDetailedConfig)
+	th.Nok(t, err)
 }
 
-func TestHasEbsVolume_True(t *testing.T) {
-	testImage := &ec2.Image{
-		BlockDeviceMappings: []*ec2.BlockDeviceMapping{
+func TestGetDefaultUserName(t *testing.T) {
+	for name, expectedUserName := range map[string]string{
+		"amzn2-ami-hvm-2.0.20230404.1-x86_64-gp2":                              "ec2-user",
+		"ubuntu/images/hvm-ssd/ubuntu-jammy-22.04-amd64-server-20230516":       "ubuntu",
+		"debian-12-amd64-20230711-1438":                                        "admin",
+		"RHEL-9.2.0_HVM-20230503-x86_64-41-Hourly2-GP2":                        "ec2-user",
+		"CentOS-7-2111-20220825_1.x86_64-d9a3032a-921c-4c6d-b150-bde168105e42": "centos",
+	} {
+		th.Equals(t, expectedUserName, ec2helper.GetDefaultUserName(&ec2.Image{Name: aws.String(name)}))
+	}
+
+	th.Equals(t, "Administrator", ec2helper.GetDefaultUserName(&ec2.Image{
+		Name:     aws.String("Windows_Server-2022-English-Full-Base-2023.06.14"),
+		Platform: aws.String(ec2.PlatformValuesWindows),
+	}))
+}
+
+func TestGetInstanceUserName(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		Images: []*ec2.Image{
 			{
-				Ebs: &ec2.EbsBlockDevice{},
+				ImageId: aws.String(testImageId),
+				Name:    aws.String("ubuntu/images/hvm-ssd/ubuntu-jammy-22.04-amd64-server-20230516"),
 			},
 		},
 	}
+	userName := testEC2.GetInstanceUserName(&ec2.Instance{ImageId: aws.String(testImageId)})
+	th.Equals(t, "ubuntu", userName)
 
-	actualHasEbsVolume := ec2helper.HasEbsVolume(testImage)
-	th.Equals(t, true, actualHasEbsVolume)
+	// The AMI of the instance may be deregistered
+	testEC2.Svc = &th.MockedEC2Svc{}
+	userName = testEC2.GetInstanceUserName(&ec2.Instance{ImageId: aws.String(testImageId)})
+	th.Equals(t, ec2helper.DefaultUserName, userName)
 }
 
-func TestHasEbsVolume_False(t *testing.T) {
-	testImage := &ec2.Image{
-		BlockDeviceMappings: []*ec2.BlockDeviceMapping{
-			{},
+func TestValidateImds(t *testing.T) {
+	th.Ok(t, ec2helper.ValidateImds(""))
+	th.Ok(t, ec2helper.ValidateImds(ec2helper.ImdsOptional))
+	th.Ok(t, ec2helper.ValidateImds(ec2helper.ImdsRequired))
+	th.Ok(t, ec2helper.ValidateImds(ec2helper.ImdsDisabled))
+	th.Nok(t, ec2helper.ValidateImds("enabled"))
+}
+
+func TestGetImdsWarning(t *testing.T) {
+	th.Equals(t, "", ec2helper.GetImdsWarning(""))
+	th.Equals(t, "", ec2helper.GetImdsWarning(ec2helper.ImdsRequired))
+	th.Assert(t, strings.Contains(ec2helper.GetImdsWarning(ec2helper.ImdsDisabled), "IAM role"),
+		"The warning should mention IAM role credentials")
+}
+
+func TestDryRunLaunchInstance_Imds(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		ImageId:      "ami-12345",
+		InstanceType: "t2.micro",
+		SubnetId:     "subnet-12345",
+	}
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	// Without a mode, the metadata options of the image are kept
+	err := testEC2.DryRunLaunchInstance(simpleConfig, &testDetailedConfig)
+	th.Ok(t, err)
+	th.Assert(t, mockedSvc.RunInstancesInputs[0].MetadataOptions == nil, "No metadata options should be set")
+
+	simpleConfig.Imds = ec2helper.ImdsRequired
+	err = testEC2.DryRunLaunchInstance(simpleConfig, &testDetailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, ec2.InstanceMetadataEndpointStateEnabled,
+		*mockedSvc.RunInstancesInputs[1].MetadataOptions.HttpEndpoint)
+	th.Equals(t, ec2.HttpTokensStateRequired, *mockedSvc.RunInstancesInputs[1].MetadataOptions.HttpTokens)
+
+	simpleConfig.Imds = ec2helper.ImdsDisabled
+	err = testEC2.DryRunLaunchInstance(simpleConfig, &testDetailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, ec2.InstanceMetadataEndpointStateDisabled,
+		*mockedSvc.RunInstancesInputs[2].MetadataOptions.HttpEndpoint)
+	th.Assert(t, mockedSvc.RunInstancesInputs[2].MetadataOptions.HttpTokens == nil, "No token mode should be set")
+
+	_, err = testEC2.CreateLaunchTemplate(simpleConfig, &testDetailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, ec2.LaunchTemplateInstanceMetadataEndpointStateDisabled,
+		*mockedSvc.LaunchTemplateData[0].MetadataOptions.HttpEndpoint)
+}
+
+func TestParseConfig_ImdsDisabledWithIamProfile(t *testing.T) {
+	testEC2.Svc = inheritVpcTagsSvc
+	simpleConfig := &config.SimpleInfo{
+		SubnetId:           testSubnetId,
+		ImageId:            testImageId,
+		InstanceType:       testInstanceType,
+		SecurityGroupIds:   testSecurityGroupIds,
+		IamInstanceProfile: "my-profile",
+		Imds:               ec2helper.ImdsDisabled,
+	}
+
+	_, err := testEC2.ParseConfig(simpleConfig)
+	th.Nok(t, err)
+
+	// Forcing the launch accepts the conflict
+	simpleConfig.Force = true
+	_, err = testEC2.ParseConfig(simpleConfig)
+	th.Ok(t, err)
+}
+
+// A default VPC with a public subnet, routed by its own route table, and a private subnet using the main route table
+func newRouteTablesSvc() *th.MockedEC2Svc {
+	return &th.MockedEC2Svc{
+		Vpcs: []*ec2.Vpc{
+			{VpcId: aws.String("vpc-12345"), IsDefault: aws.Bool(true)},
+		},
+		Subnets: []*ec2.Subnet{
+			{SubnetId: aws.String("subnet-public"), VpcId: aws.String("vpc-12345")},
+			{SubnetId: aws.String("subnet-private"), VpcId: aws.String("vpc-12345")},
+		},
+		RouteTables: []*ec2.RouteTable{
+			{
+				VpcId: aws.String("vpc-12345"),
+				Associations: []*ec2.RouteTableAssociation{
+					{Main: aws.Bool(true)},
+				},
+				Routes: []*ec2.Route{
+					{DestinationCidrBlock: aws.String("172.31.0.0/16"), GatewayId: aws.String("local")},
+				},
+			},
+			{
+				VpcId: aws.String("vpc-12345"),
+				Associations: []*ec2.RouteTableAssociation{
+					{Main: aws.Bool(false), SubnetId: aws.String("subnet-public")},
+				},
+				Routes: []*ec2.Route{
+					{DestinationCidrBlock: aws.String("172.31.0.0/16"), GatewayId: aws.String("local")},
+					{DestinationCidrBlock: aws.String("0.0.0.0/0"), GatewayId: aws.String("igw-12345"),
+						State: aws.String(ec2.RouteStateActive)},
+				},
+			},
 		},
 	}
+}
 
-	actualHasEbsVolume := ec2helper.HasEbsVolume(testImage)
-	th.Equals(t, false, actualHasEbsVolume)
+func TestHasInternetRoute(t *testing.T) {
+	th.Assert(t, ec2helper.HasInternetRoute(&ec2.RouteTable{Routes: []*ec2.Route{
+		{DestinationCidrBlock: aws.String("0.0.0.0/0"), GatewayId: aws.String("igw-12345")},
+	}}), "A default route to an internet gateway should be an internet route")
+	th.Assert(t, ec2helper.HasInternetRoute(&ec2.RouteTable{Routes: []*ec2.Route{
+		{DestinationIpv6CidrBlock: aws.String("::/0"), GatewayId: aws.String("igw-12345")},
+	}}), "An IPv6 default route to an internet gateway should be an internet route")
+	th.Assert(t, !ec2helper.HasInternetRoute(&ec2.RouteTable{Routes: []*ec2.Route{
+		{DestinationCidrBlock: aws.String("0.0.0.0/0"), NatGatewayId: aws.String("nat-12345")},
+	}}), "A default route to a NAT gateway should not be an internet route")
+	th.Assert(t, !ec2helper.HasInternetRoute(&ec2.RouteTable{Routes: []*ec2.Route{
+		{DestinationCidrBlock: aws.String("0.0.0.0/0"), GatewayId: aws.String("igw-12345"),
+			State: aws.String(ec2.RouteStateBlackhole)},
+	}}), "A blackhole route should not be an internet route")
+}
+
+func TestClassifySubnets(t *testing.T) {
+	mockedSvc := newRouteTablesSvc()
+	public, private := ec2helper.ClassifySubnets(mockedSvc.Subnets, mockedSvc.RouteTables)
+	th.Equals(t, 1, len(public))
+	th.Equals(t, "subnet-public", *public[0].SubnetId)
+	th.Equals(t, 1, len(private))
+	th.Equals(t, "subnet-private", *private[0].SubnetId)
+
+	// Subnets without an explicit association use the main route table, which is public here
+	mockedSvc.RouteTables[0].Routes = mockedSvc.RouteTables[1].Routes
+	public, private = ec2helper.ClassifySubnets(mockedSvc.Subnets, mockedSvc.RouteTables)
+	th.Equals(t, 2, len(public))
+	th.Equals(t, 0, len(private))
+}
+
+func TestGetAutoSubnet(t *testing.T) {
+	testEC2.Svc = newRouteTablesSvc()
+
+	subnet, err := testEC2.GetAutoSubnet(ec2helper.SubnetAutoPublic)
+	th.Ok(t, err)
+	th.Equals(t, "subnet-public", *subnet.SubnetId)
+
+	subnet, err = testEC2.GetAutoSubnet(ec2helper.SubnetAutoPrivate)
+	th.Ok(t, err)
+	th.Equals(t, "subnet-private", *subnet.SubnetId)
+}
+
+func TestGetAutoSubnet_NoneOfKind(t *testing.T) {
+	mockedSvc := newRouteTablesSvc()
+	mockedSvc.Subnets = mockedSvc.Subnets[1:]
+	testEC2.Svc = mockedSvc
+
+	_, err := testEC2.GetAutoSubnet(ec2helper.SubnetAutoPublic)
+	th.Nok(t, err)
+	th.Equals(t, "No public subnet in the default VPC vpc-12345", err.Error())
+}
+
+func TestGetAutoSubnet_NoDefaultVpc(t *testing.T) {
+	mockedSvc := newRouteTablesSvc()
+	mockedSvc.Vpcs[0].IsDefault = aws.Bool(false)
+	testEC2.Svc = mockedSvc
+
+	_, err := testEC2.GetAutoSubnet(ec2helper.SubnetAutoPrivate)
+	th.Nok(t, err)
+}
+
+func TestIsAutoSubnet(t *testing.T) {
+	th.Assert(t, ec2helper.IsAutoSubnet(ec2helper.SubnetAutoPublic), "auto-public should pick a subnet")
+	th.Assert(t, ec2helper.IsAutoSubnet(ec2helper.SubnetAutoPrivate), "auto-private should pick a subnet")
+	th.Assert(t, !ec2helper.IsAutoSubnet("subnet-12345"), "A subnet id should not pick a subnet")
+}
+
+func TestGetEbsMonthlyStorageCost(t *testing.T) {
+	cost, err := ec2helper.GetEbsMonthlyStorageCost(ec2.VolumeTypeGp3, 100)
+	th.Ok(t, err)
+	th.Equals(t, 8.0, cost)
+
+	cost, err = ec2helper.GetEbsMonthlyStorageCost(ec2.VolumeTypeGp2, 8)
+	th.Ok(t, err)
+	th.Equals(t, 0.8, cost)
+
+	cost, err = ec2helper.GetEbsMonthlyStorageCost(ec2.VolumeTypeIo2, 0)
+	th.Ok(t, err)
+	th.Equals(t, 0.0, cost)
+
+	_, err = ec2helper.GetEbsMonthlyStorageCost("gp4", 8)
+	th.Nok(t, err)
 }
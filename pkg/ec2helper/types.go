@@ -14,6 +14,10 @@
 package ec2helper
 
 import (
+	"time"
+
+	"simple-ec2/pkg/iamhelper"
+
 	"github.com/aws/amazon-ec2-instance-selector/v2/pkg/instancetypes"
 	"github.com/aws/amazon-ec2-instance-selector/v2/pkg/selector"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -26,9 +30,11 @@ type EC2Svc interface {
 	DescribeLaunchTemplatesPages(input *ec2.DescribeLaunchTemplatesInput, fn func(*ec2.DescribeLaunchTemplatesOutput, bool) bool) error
 	DescribeLaunchTemplateVersionsPages(input *ec2.DescribeLaunchTemplateVersionsInput, fn func(*ec2.DescribeLaunchTemplateVersionsOutput, bool) bool) error
 	DescribeInstanceTypesPages(input *ec2.DescribeInstanceTypesInput, fn func(*ec2.DescribeInstanceTypesOutput, bool) bool) error
-	DescribeImages(input *ec2.DescribeImagesInput) (*ec2.DescribeImagesOutput, error)
+	DescribeInstanceTypeOfferingsPages(input *ec2.DescribeInstanceTypeOfferingsInput, fn func(*ec2.DescribeInstanceTypeOfferingsOutput, bool) bool) error
+	DescribeImagesPages(input *ec2.DescribeImagesInput, fn func(*ec2.DescribeImagesOutput, bool) bool) error
 	DescribeVpcsPages(input *ec2.DescribeVpcsInput, fn func(*ec2.DescribeVpcsOutput, bool) bool) error
 	DescribeSubnetsPages(input *ec2.DescribeSubnetsInput, fn func(*ec2.DescribeSubnetsOutput, bool) bool) error
+	DescribeRouteTablesPages(input *ec2.DescribeRouteTablesInput, fn func(*ec2.DescribeRouteTablesOutput, bool) bool) error
 	DescribeSecurityGroupsPages(input *ec2.DescribeSecurityGroupsInput, fn func(*ec2.DescribeSecurityGroupsOutput, bool) bool) error
 	CreateSecurityGroup(input *ec2.CreateSecurityGroupInput) (*ec2.CreateSecurityGroupOutput, error)
 	AuthorizeSecurityGroupIngress(input *ec2.AuthorizeSecurityGroupIngressInput) (*ec2.AuthorizeSecurityGroupIngressOutput, error)
@@ -36,17 +42,63 @@ type EC2Svc interface {
 	CreateTags(input *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error)
 	RunInstances(input *ec2.RunInstancesInput) (*ec2.Reservation, error)
 	TerminateInstances(input *ec2.TerminateInstancesInput) (*ec2.TerminateInstancesOutput, error)
+	StartInstances(input *ec2.StartInstancesInput) (*ec2.StartInstancesOutput, error)
 	DeleteSecurityGroup(input *ec2.DeleteSecurityGroupInput) (*ec2.DeleteSecurityGroupOutput, error)
 	CreateLaunchTemplate(input *ec2.CreateLaunchTemplateInput) (*ec2.CreateLaunchTemplateOutput, error)
 	DeleteLaunchTemplate(input *ec2.DeleteLaunchTemplateInput) (*ec2.DeleteLaunchTemplateOutput, error)
 	CreateFleet(input *ec2.CreateFleetInput) (*ec2.CreateFleetOutput, error)
+	DescribeFleetsPages(input *ec2.DescribeFleetsInput, fn func(*ec2.DescribeFleetsOutput, bool) bool) error
+	CreateNetworkInterface(input *ec2.CreateNetworkInterfaceInput) (*ec2.CreateNetworkInterfaceOutput, error)
+	AttachNetworkInterface(input *ec2.AttachNetworkInterfaceInput) (*ec2.AttachNetworkInterfaceOutput, error)
+	WaitUntilInstanceRunning(input *ec2.DescribeInstancesInput) error
+	GetConsoleOutput(input *ec2.GetConsoleOutputInput) (*ec2.GetConsoleOutputOutput, error)
+	DescribeKeyPairs(input *ec2.DescribeKeyPairsInput) (*ec2.DescribeKeyPairsOutput, error)
+	CreateKeyPair(input *ec2.CreateKeyPairInput) (*ec2.CreateKeyPairOutput, error)
 }
 
 type EC2Helper struct {
-	Svc  EC2Svc
-	Sess *session.Session
+	Svc         EC2Svc
+	Sess        *session.Session
+	Timeout     time.Duration // Bounds the wait for resources created for a launch, 0 means no bound
+	Iam         *iamhelper.IAMHelper
+	ImageMaxAge time.Duration              // Rejects default images created longer ago, 0 means no limit
+	RegionalSvc func(region string) EC2Svc // Creates the client of another region, replaced in tests
+	ZoneType    string                     // Limits the zones offered to the zone type, empty means all types
+	// Forces the root device type of default images, empty means instance store when the instance type supports it
+	RootDeviceType string
+	// Describes the SSH rules of created security groups, empty means a description naming the CIDR block
+	SecurityGroupRuleDescription string
+}
+
+// An error for a resource that doesn't exist, such as a deleted subnet referred to by a saved config
+type NotFoundError struct {
+	Message string
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Message
+}
+
+// A resource created by simple-ec2 that no instance uses anymore. Type is one of the cfn.ResourceType constants
+type OrphanedResource struct {
+	Type string `json:"type"`
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// The capacity launched by an instant fleet out of the requested capacity, with the errors of the capacity not launched
+type FleetFulfillment struct {
+	RequestedCapacity int64
+	FulfilledCapacity int64
+	Errors            []error
 }
 
 type InstanceSelector interface {
 	FilterVerbose(filters selector.Filters) ([]*instancetypes.Details, error)
 }
+
+// CommandRunner runs commands on instances, once they accept SSH connections
+type CommandRunner interface {
+	WaitForSSH(instance *ec2.Instance) error
+	RunCommand(instance *ec2.Instance, command string) error
+}
@@ -23,6 +23,8 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"time"
 
 	"simple-ec2/pkg/config"
 
@@ -35,22 +37,34 @@ import (
 
 const userName = "ec2-user"
 
-// Push an SSH key to an EC2 instance
+// The number of attempts to connect to an instance while it boots, and the interval between them
+var SSHRetries = 30
+var SSHRetryInterval = 10 * time.Second
+
+type KeyPusher interface {
+	SendSSHPublicKey(input *ec2instanceconnect.SendSSHPublicKeyInput) (*ec2instanceconnect.SendSSHPublicKeyOutput, error)
+}
+
+// Create the client pushing SSH keys. Replaced with a mock in tests
+var NewClient = func(sess *session.Session) KeyPusher {
+	return ec2instanceconnect.New(sess)
+}
+
+// Push an SSH key to an EC2 instance. The key is only usable for 60 seconds
 func SendSSHPublicKey(sess *session.Session, availabilityZone, instanceId,
 	publicKey string) error {
-	svc := ec2instanceconnect.New(sess)
 	input := &ec2instanceconnect.SendSSHPublicKeyInput{
 		AvailabilityZone: aws.String(availabilityZone),
 		InstanceId:       aws.String(instanceId),
-		InstanceOSUser:   aws.String("ec2-user"),
+		InstanceOSUser:   aws.String(userName),
 		SSHPublicKey:     aws.String(publicKey),
 	}
 
-	result, err := svc.SendSSHPublicKey(input)
+	result, err := NewClient(sess).SendSSHPublicKey(input)
 	if err != nil {
 		return err
 	}
-	if !*result.Success {
+	if !aws.BoolValue(result.Success) {
 		return errors.New("Sending public key failed")
 	}
 
@@ -82,6 +96,16 @@ func GenerateSSHKeyPair() (publicKeyString, privateKeyString *string, err error)
 
 // Establish an SSH connection to the instance
 func EstablishSSHConnection(privateKey, instanceDnsName string, exitAtOnce bool) error {
+	command := ""
+	if exitAtOnce {
+		command = "exit"
+	}
+
+	return RunSSHCommand(privateKey, instanceDnsName, command, config.DefaultSshPort)
+}
+
+// Run a command on the instance over SSH on the port. An interactive session is opened if the command is empty
+func RunSSHCommand(privateKey, instanceDnsName, command string, port int) error {
 	// Create the folder if it doesn't exist
 	simpleEc2Dir := os.Getenv("HOME") + "/.simple-ec2"
 	if _, err := os.Stat(simpleEc2Dir); os.IsNotExist(err) {
@@ -97,19 +121,7 @@ func EstablishSSHConnection(privateKey, instanceDnsName string, exitAtOnce bool)
 		return err
 	}
 
-	// Arguments for the ssh command
-	args := []string{
-		fmt.Sprintf("-i%s", *keyPath),
-		fmt.Sprintf("%s@%s", userName, instanceDnsName),
-		"-oStrictHostKeyChecking=no",
-	}
-
-	// Decide whether to include additional arguments or not.
-	if exitAtOnce {
-		args = append(args, "exit")
-	}
-
-	cmd := exec.Command("ssh", args...)
+	cmd := exec.Command("ssh", GetSSHArgs(*keyPath, instanceDnsName, command, port)...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	var errb bytes.Buffer
@@ -127,9 +139,47 @@ func EstablishSSHConnection(privateKey, instanceDnsName string, exitAtOnce bool)
 	return nil
 }
 
-// Connect to an instance
-func ConnectInstance(sess *session.Session, instance *ec2.Instance, exitAtOnce bool) error {
-	instanceDnsName, err := GetInstancePublicDnsName(instance)
+/*
+Get the arguments for the ssh command. The port is only passed if it isn't the default one,
+and the command is left out if empty, which opens an interactive session.
+*/
+func GetSSHArgs(keyPath, instanceAddress, command string, port int) []string {
+	return GetSSHArgsForUser(keyPath, userName, instanceAddress, command, port)
+}
+
+// Get the arguments for the ssh command, logging in as the user
+func GetSSHArgsForUser(keyPath, user, instanceAddress, command string, port int) []string {
+	args := []string{
+		fmt.Sprintf("-i%s", keyPath),
+		fmt.Sprintf("%s@%s", user, instanceAddress),
+		"-oStrictHostKeyChecking=no",
+	}
+	if port != config.DefaultSshPort {
+		args = append(args, fmt.Sprintf("-p%d", port))
+	}
+
+	// Decide whether to include additional arguments or not.
+	if command != "" {
+		args = append(args, command)
+	}
+
+	return args
+}
+
+// Connect to an instance, through its private IP address if specified or if it has no public address
+func ConnectInstance(sess *session.Session, instance *ec2.Instance, exitAtOnce, usePrivateIp bool, port int) error {
+	command := ""
+	if exitAtOnce {
+		command = "exit"
+	}
+
+	return RunInstanceCommand(sess, instance, command, usePrivateIp, port)
+}
+
+// Run a command on an instance over SSH. An interactive session is opened if the command is empty
+func RunInstanceCommand(sess *session.Session, instance *ec2.Instance, command string, usePrivateIp bool,
+	port int) error {
+	instanceAddress, err := getConnectAddress(instance, usePrivateIp)
 	if err != nil {
 		return err
 	}
@@ -147,7 +197,7 @@ func ConnectInstance(sess *session.Session, instance *ec2.Instance, exitAtOnce b
 		return err
 	}
 
-	err = EstablishSSHConnection(*privateKey, *instanceDnsName, exitAtOnce)
+	err = RunSSHCommand(*privateKey, *instanceAddress, command, port)
 	if err != nil {
 		return err
 	}
@@ -155,6 +205,132 @@ func ConnectInstance(sess *session.Session, instance *ec2.Instance, exitAtOnce b
 	return nil
 }
 
+/*
+Open an SSH session to an instance with the private key of its key pair, as the user. Unlike with EC2 Instance
+Connect, no key is pushed to the instance.
+*/
+func ConnectInstanceWithKeyFile(instance *ec2.Instance, keyPath, user string, usePrivateIp bool, port int) error {
+	instanceAddress, err := getConnectAddress(instance, usePrivateIp)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("ssh", GetSSHArgsForUser(keyPath, user, *instanceAddress, "", port)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	var errb bytes.Buffer
+	cmd.Stderr = &errb
+
+	err = cmd.Run()
+	if err != nil {
+		return errors.New(err.Error() + errb.String())
+	}
+
+	return nil
+}
+
+/*
+Open a Session Manager session to an instance with the AWS CLI, which needs the Session Manager plugin.
+No port needs to be open, and the instance needs no public address.
+*/
+func StartSsmSession(instanceId, region string) error {
+	if _, err := exec.LookPath("aws"); err != nil {
+		return errors.New("The AWS CLI is needed to connect with Session Manager, along with the Session Manager " +
+			"plugin. Please install them and try again")
+	}
+
+	cmd := exec.Command("aws", GetSsmSessionArgs(instanceId, region)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	var errb bytes.Buffer
+	cmd.Stderr = &errb
+
+	err := cmd.Run()
+	if err != nil {
+		return errors.New(err.Error() + errb.String())
+	}
+
+	return nil
+}
+
+// Get the arguments of the AWS CLI to start a Session Manager session to the instance in the region
+func GetSsmSessionArgs(instanceId, region string) []string {
+	return []string{"ssm", "start-session", "--target", instanceId, "--region", region}
+}
+
+/*
+Get the address to connect to the instance, telling how to reach it when only its private IP address can be used.
+An instance without a public address is usually reached through a bastion host or Session Manager.
+*/
+func getConnectAddress(instance *ec2.Instance, usePrivateIp bool) (*string, error) {
+	instanceAddress, isPrivate, err := GetInstanceAddress(instance, usePrivateIp)
+	if err != nil {
+		return nil, err
+	}
+	if isPrivate && usePrivateIp {
+		fmt.Printf("Connecting to private IP address %s. This only works from within the network of the instance, "+
+			"such as over a VPN or Direct Connect\n", *instanceAddress)
+	} else if isPrivate {
+		fmt.Printf("Instance %s has no public IP address, so connecting to private IP address %s. This only works "+
+			"from within the network of the instance, such as from a bastion host in its VPC, or over a VPN or "+
+			"Direct Connect. Otherwise, connect with Session Manager: aws ssm start-session --target %s\n",
+			aws.StringValue(instance.InstanceId), *instanceAddress, aws.StringValue(instance.InstanceId))
+	}
+
+	return instanceAddress, nil
+}
+
+/*
+Find the private key file of a key pair, saved as <key name>.pem in the working directory, where simple-ec2 saves
+the key pairs it creates, or in ~/.ssh. Return an empty string if there is none.
+*/
+func FindKeyFile(keyName string) string {
+	paths := []string{keyName + ".pem"}
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".ssh", keyName+".pem"), filepath.Join(home, ".ssh", keyName))
+	}
+
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+
+	return ""
+}
+
+// InstanceCommandRunner runs commands on instances over SSH, with keys pushed through EC2 Instance Connect
+type InstanceCommandRunner struct {
+	Sess         *session.Session
+	UsePrivateIp bool
+	SshPort      int
+}
+
+/*
+Wait until the instance accepts SSH connections, which takes a while after it starts running.
+The connection is retried, exiting at once, until it succeeds.
+*/
+func (r *InstanceCommandRunner) WaitForSSH(instance *ec2.Instance) error {
+	var err error
+	for i := 0; i < SSHRetries; i++ {
+		if i > 0 {
+			time.Sleep(SSHRetryInterval)
+		}
+
+		err = ConnectInstance(r.Sess, instance, true, r.UsePrivateIp, r.SshPort)
+		if err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Instance %s doesn't accept SSH connections: %s", aws.StringValue(instance.InstanceId), err)
+}
+
+// Run a command on the instance over SSH
+func (r *InstanceCommandRunner) RunCommand(instance *ec2.Instance, command string) error {
+	return RunInstanceCommand(r.Sess, instance, command, r.UsePrivateIp, r.SshPort)
+}
+
 // Check if the instance has a public DNS name. If so, return it. Return an error otherwise.
 func GetInstancePublicDnsName(instance *ec2.Instance) (*string, error) {
 	if instance == nil || instance.NetworkInterfaces == nil || len(instance.NetworkInterfaces) <= 0 {
@@ -169,3 +345,37 @@ func GetInstancePublicDnsName(instance *ec2.Instance) (*string, error) {
 
 	return nil, errors.New("No public DNS name available")
 }
+
+/*
+Get the address to connect to the instance, in the order of public DNS name, public IP address and private IP address.
+Only the private IP address is returned if usePrivateIp is true. Also return whether the address is private.
+*/
+func GetInstanceAddress(instance *ec2.Instance, usePrivateIp bool) (address *string, isPrivate bool, err error) {
+	if instance == nil || instance.NetworkInterfaces == nil || len(instance.NetworkInterfaces) <= 0 {
+		return nil, false, errors.New("No network interfaces available for the instance")
+	}
+
+	if !usePrivateIp {
+		for _, ni := range instance.NetworkInterfaces {
+			if ni.Association != nil && aws.StringValue(ni.Association.PublicDnsName) != "" {
+				return ni.Association.PublicDnsName, false, nil
+			}
+		}
+		for _, ni := range instance.NetworkInterfaces {
+			if ni.Association != nil && aws.StringValue(ni.Association.PublicIp) != "" {
+				return ni.Association.PublicIp, false, nil
+			}
+		}
+	}
+
+	if aws.StringValue(instance.PrivateIpAddress) != "" {
+		return instance.PrivateIpAddress, true, nil
+	}
+	for _, ni := range instance.NetworkInterfaces {
+		if aws.StringValue(ni.PrivateIpAddress) != "" {
+			return ni.PrivateIpAddress, true, nil
+		}
+	}
+
+	return nil, false, errors.New("No IP address available for the instance")
+}
@@ -15,12 +15,16 @@ package ec2instanceconnecthelper_test
 
 import (
 	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"os"
 	"testing"
 
 	ec2ichelper "simple-ec2/pkg/ec2instanceconnecthelper"
 	th "simple-ec2/test/testhelper"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 )
 
@@ -80,6 +84,185 @@ func TestGetInstancePublicDnsName_NoDnsNameInNetworkInterface(t *testing.T) {
 	th.Nok(t, err)
 }
 
+func TestGetInstanceAddress_PublicDnsName(t *testing.T) {
+	const testDnsName = "test dns name"
+	instance := &ec2.Instance{
+		PrivateIpAddress: aws.String("10.0.0.10"),
+		NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+			{
+				Association: &ec2.InstanceNetworkInterfaceAssociation{
+					PublicDnsName: aws.String(testDnsName),
+					PublicIp:      aws.String("1.2.3.4"),
+				},
+			},
+		},
+	}
+
+	address, isPrivate, err := ec2ichelper.GetInstanceAddress(instance, false)
+	th.Ok(t, err)
+	th.Equals(t, testDnsName, *address)
+	th.Equals(t, false, isPrivate)
+}
+
+func TestGetInstanceAddress_PublicIp(t *testing.T) {
+	const testPublicIp = "1.2.3.4"
+	instance := &ec2.Instance{
+		PrivateIpAddress: aws.String("10.0.0.10"),
+		NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+			{
+				Association: &ec2.InstanceNetworkInterfaceAssociation{
+					PublicDnsName: aws.String(""),
+					PublicIp:      aws.String(testPublicIp),
+				},
+			},
+		},
+	}
+
+	address, isPrivate, err := ec2ichelper.GetInstanceAddress(instance, false)
+	th.Ok(t, err)
+	th.Equals(t, testPublicIp, *address)
+	th.Equals(t, false, isPrivate)
+}
+
+func TestGetInstanceAddress_PrivateIpFallback(t *testing.T) {
+	const testPrivateIp = "10.0.0.10"
+	instance := &ec2.Instance{
+		PrivateIpAddress: aws.String(testPrivateIp),
+		NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+			{
+				PrivateIpAddress: aws.String(testPrivateIp),
+			},
+		},
+	}
+
+	address, isPrivate, err := ec2ichelper.GetInstanceAddress(instance, false)
+	th.Ok(t, err)
+	th.Equals(t, testPrivateIp, *address)
+	th.Equals(t, true, isPrivate)
+}
+
+func TestGetInstanceAddress_UsePrivateIp(t *testing.T) {
+	const testPrivateIp = "10.0.0.10"
+	instance := &ec2.Instance{
+		PrivateIpAddress: aws.String(testPrivateIp),
+		NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+			{
+				Association: &ec2.InstanceNetworkInterfaceAssociation{
+					PublicDnsName: aws.String("test dns name"),
+					PublicIp:      aws.String("1.2.3.4"),
+				},
+				PrivateIpAddress: aws.String(testPrivateIp),
+			},
+		},
+	}
+
+	address, isPrivate, err := ec2ichelper.GetInstanceAddress(instance, true)
+	th.Ok(t, err)
+	th.Equals(t, testPrivateIp, *address)
+	th.Equals(t, true, isPrivate)
+}
+
+func TestGetInstanceAddress_NoAddress(t *testing.T) {
+	instance := &ec2.Instance{
+		NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+			{
+				Association: &ec2.InstanceNetworkInterfaceAssociation{},
+			},
+		},
+	}
+
+	_, _, err := ec2ichelper.GetInstanceAddress(instance, false)
+	th.Nok(t, err)
+}
+
+func TestGetInstanceAddress_NoNetworkInterface(t *testing.T) {
+	instance := &ec2.Instance{
+		NetworkInterfaces: []*ec2.InstanceNetworkInterface{},
+	}
+
+	_, _, err := ec2ichelper.GetInstanceAddress(instance, false)
+	th.Nok(t, err)
+}
+
+// Replace the EC2 Instance Connect client with the mock for the duration of a test
+func mockClient(t *testing.T, svc *th.MockedEC2InstanceConnectSvc) {
+	newClient := ec2ichelper.NewClient
+	ec2ichelper.NewClient = func(sess *session.Session) ec2ichelper.KeyPusher {
+		return svc
+	}
+	t.Cleanup(func() {
+		ec2ichelper.NewClient = newClient
+	})
+}
+
+func TestSendSSHPublicKey_Success(t *testing.T) {
+	svc := &th.MockedEC2InstanceConnectSvc{}
+	mockClient(t, svc)
+
+	err := ec2ichelper.SendSSHPublicKey(nil, "us-east-1a", "i-12345", "ssh-rsa AAAA")
+	th.Ok(t, err)
+	th.Equals(t, 1, len(svc.SendSSHPublicKeyInputs))
+	input := svc.SendSSHPublicKeyInputs[0]
+	th.Equals(t, "us-east-1a", aws.StringValue(input.AvailabilityZone))
+	th.Equals(t, "i-12345", aws.StringValue(input.InstanceId))
+	th.Equals(t, "ec2-user", aws.StringValue(input.InstanceOSUser))
+	th.Equals(t, "ssh-rsa AAAA", aws.StringValue(input.SSHPublicKey))
+}
+
+func TestSendSSHPublicKey_Error(t *testing.T) {
+	mockClient(t, &th.MockedEC2InstanceConnectSvc{
+		SendSSHPublicKeyError: errors.New("Test error"),
+	})
+
+	err := ec2ichelper.SendSSHPublicKey(nil, "us-east-1a", "i-12345", "ssh-rsa AAAA")
+	th.Nok(t, err)
+}
+
+func TestSendSSHPublicKey_Unsuccessful(t *testing.T) {
+	mockClient(t, &th.MockedEC2InstanceConnectSvc{
+		SendSSHPublicKeyFailed: true,
+	})
+
+	err := ec2ichelper.SendSSHPublicKey(nil, "us-east-1a", "i-12345", "ssh-rsa AAAA")
+	th.Nok(t, err)
+}
+
+func TestGetSSHArgs_Interactive(t *testing.T) {
+	args := ec2ichelper.GetSSHArgs("/tmp/key.pem", "1.2.3.4", "", 22)
+	th.Equals(t, []string{"-i/tmp/key.pem", "ec2-user@1.2.3.4", "-oStrictHostKeyChecking=no"}, args)
+}
+
+func TestGetSSHArgs_Command(t *testing.T) {
+	args := ec2ichelper.GetSSHArgs("/tmp/key.pem", "1.2.3.4", "exit", 22)
+	th.Equals(t, []string{"-i/tmp/key.pem", "ec2-user@1.2.3.4", "-oStrictHostKeyChecking=no", "exit"}, args)
+}
+
+func TestGetSSHArgs_NonDefaultPort(t *testing.T) {
+	args := ec2ichelper.GetSSHArgs("/tmp/key.pem", "1.2.3.4", "exit", 2222)
+	th.Equals(t, []string{"-i/tmp/key.pem", "ec2-user@1.2.3.4", "-oStrictHostKeyChecking=no", "-p2222", "exit"},
+		args)
+}
+
+func TestGetSSHArgsForUser(t *testing.T) {
+	args := ec2ichelper.GetSSHArgsForUser("/tmp/key.pem", "ubuntu", "1.2.3.4", "", 22)
+	th.Equals(t, []string{"-i/tmp/key.pem", "ubuntu@1.2.3.4", "-oStrictHostKeyChecking=no"}, args)
+}
+
+func TestGetSsmSessionArgs(t *testing.T) {
+	args := ec2ichelper.GetSsmSessionArgs("i-12345", "us-east-2")
+	th.Equals(t, []string{"ssm", "start-session", "--target", "i-12345", "--region", "us-east-2"}, args)
+}
+
+func TestFindKeyFile(t *testing.T) {
+	const testKeyName = "unit_test_key_pair"
+	th.Equals(t, "", ec2ichelper.FindKeyFile(testKeyName))
+
+	err := ioutil.WriteFile(testKeyName+".pem", []byte("key"), 0600)
+	th.Ok(t, err)
+	defer os.Remove(testKeyName + ".pem")
+	th.Equals(t, testKeyName+".pem", ec2ichelper.FindKeyFile(testKeyName))
+}
+
 // A helper function to decide whether a string is Base64 encoded or not
 func isBase64(s string) bool {
 	_, err := base64.StdEncoding.DecodeString(s)
@@ -0,0 +1,104 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package elbhelper
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+)
+
+// The ARN of an Elastic Load Balancing target group, ending with the name and id of the target group
+var targetGroupArnRegex = regexp.MustCompile(
+	`^arn:aws[a-z-]*:elasticloadbalancing:[a-z0-9-]+:[0-9]{12}:targetgroup/[a-zA-Z0-9-]{1,32}/[0-9a-f]{16}$`)
+
+type TargetGroupProvider interface {
+	DescribeTargetGroups(input *elbv2.DescribeTargetGroupsInput) (*elbv2.DescribeTargetGroupsOutput, error)
+	RegisterTargets(input *elbv2.RegisterTargetsInput) (*elbv2.RegisterTargetsOutput, error)
+}
+
+type ELBHelper struct {
+	Client TargetGroupProvider
+}
+
+func New(sess *session.Session) *ELBHelper {
+	return &ELBHelper{
+		Client: elbv2.New(sess),
+	}
+}
+
+// Validate the ARN of a target group
+func ValidateTargetGroupArn(arn string) error {
+	if !targetGroupArnRegex.MatchString(arn) {
+		return fmt.Errorf("%s is not the ARN of a target group, such as "+
+			"arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/my-targets/73e2d6bc24d8a067", arn)
+	}
+
+	return nil
+}
+
+/*
+Get the target group of the ARN.
+Empty result is not allowed.
+*/
+func (e *ELBHelper) GetTargetGroup(arn string) (*elbv2.TargetGroup, error) {
+	output, err := e.Client.DescribeTargetGroups(&elbv2.DescribeTargetGroupsInput{
+		TargetGroupArns: aws.StringSlice([]string{arn}),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(output.TargetGroups) <= 0 {
+		return nil, fmt.Errorf("Target group %s is not found", arn)
+	}
+
+	return output.TargetGroups[0], nil
+}
+
+/*
+Validate that instances of the VPC can be registered with the target group by instance id. The target group must
+be in the same VPC, and target instances rather than IP addresses or Lambda functions.
+*/
+func ValidateTargetGroupForVpc(targetGroup *elbv2.TargetGroup, vpcId string) error {
+	name := aws.StringValue(targetGroup.TargetGroupName)
+	if targetType := aws.StringValue(targetGroup.TargetType); targetType != elbv2.TargetTypeEnumInstance {
+		return fmt.Errorf("Target group %s targets %s, so instances can't be registered with it by id", name,
+			targetType)
+	}
+	if targetGroupVpcId := aws.StringValue(targetGroup.VpcId); targetGroupVpcId != vpcId {
+		return fmt.Errorf("Target group %s is in VPC %s, but the instance is in VPC %s", name, targetGroupVpcId,
+			vpcId)
+	}
+
+	return nil
+}
+
+// Register the instances with the target group, on the port of the target group
+func (e *ELBHelper) RegisterInstances(targetGroupArn string, instanceIds []string) error {
+	targets := []*elbv2.TargetDescription{}
+	for _, instanceId := range instanceIds {
+		targets = append(targets, &elbv2.TargetDescription{
+			Id: aws.String(instanceId),
+		})
+	}
+
+	_, err := e.Client.RegisterTargets(&elbv2.RegisterTargetsInput{
+		TargetGroupArn: aws.String(targetGroupArn),
+		Targets:        targets,
+	})
+	return err
+}
@@ -0,0 +1,113 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package elbhelper_test
+
+import (
+	"errors"
+	"testing"
+
+	"simple-ec2/pkg/elbhelper"
+	th "simple-ec2/test/testhelper"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+)
+
+const testTargetGroupArn = "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/my-targets/73e2d6bc24d8a067"
+
+var testTargetGroup = &elbv2.TargetGroup{
+	TargetGroupArn:  aws.String(testTargetGroupArn),
+	TargetGroupName: aws.String("my-targets"),
+	TargetType:      aws.String(elbv2.TargetTypeEnumInstance),
+	VpcId:           aws.String("vpc-12345"),
+}
+
+func TestValidateTargetGroupArn_Success(t *testing.T) {
+	th.Ok(t, elbhelper.ValidateTargetGroupArn(testTargetGroupArn))
+	th.Ok(t, elbhelper.ValidateTargetGroupArn(
+		"arn:aws-cn:elasticloadbalancing:cn-north-1:123456789012:targetgroup/my-targets/73e2d6bc24d8a067"))
+}
+
+func TestValidateTargetGroupArn_Invalid(t *testing.T) {
+	for _, arn := range []string{
+		"my-targets",
+		"arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/my-lb/50dc6c495c0c9188",
+		"arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/my-targets",
+	} {
+		th.Nok(t, elbhelper.ValidateTargetGroupArn(arn))
+	}
+}
+
+func TestGetTargetGroup_Success(t *testing.T) {
+	e := &elbhelper.ELBHelper{Client: &th.MockedELBSvc{TargetGroups: []*elbv2.TargetGroup{testTargetGroup}}}
+
+	targetGroup, err := e.GetTargetGroup(testTargetGroupArn)
+	th.Ok(t, err)
+	th.Equals(t, testTargetGroup, targetGroup)
+}
+
+func TestGetTargetGroup_NotFound(t *testing.T) {
+	e := &elbhelper.ELBHelper{Client: &th.MockedELBSvc{}}
+
+	_, err := e.GetTargetGroup(testTargetGroupArn)
+	th.Nok(t, err)
+}
+
+func TestGetTargetGroup_DescribeTargetGroupsError(t *testing.T) {
+	e := &elbhelper.ELBHelper{Client: &th.MockedELBSvc{DescribeTargetGroupsError: errors.New("Test error")}}
+
+	_, err := e.GetTargetGroup(testTargetGroupArn)
+	th.Nok(t, err)
+}
+
+func TestValidateTargetGroupForVpc_Success(t *testing.T) {
+	th.Ok(t, elbhelper.ValidateTargetGroupForVpc(testTargetGroup, "vpc-12345"))
+}
+
+func TestValidateTargetGroupForVpc_OtherVpc(t *testing.T) {
+	err := elbhelper.ValidateTargetGroupForVpc(testTargetGroup, "vpc-67890")
+	th.Nok(t, err)
+	th.Equals(t, "Target group my-targets is in VPC vpc-12345, but the instance is in VPC vpc-67890", err.Error())
+}
+
+func TestValidateTargetGroupForVpc_IpTargetType(t *testing.T) {
+	targetGroup := *testTargetGroup
+	targetGroup.TargetType = aws.String(elbv2.TargetTypeEnumIp)
+
+	th.Nok(t, elbhelper.ValidateTargetGroupForVpc(&targetGroup, "vpc-12345"))
+}
+
+func TestRegisterInstances_Success(t *testing.T) {
+	mockedSvc := &th.MockedELBSvc{}
+	e := &elbhelper.ELBHelper{Client: mockedSvc}
+
+	err := e.RegisterInstances(testTargetGroupArn, []string{"i-12345", "i-67890"})
+	th.Ok(t, err)
+	th.Equals(t, []*elbv2.RegisterTargetsInput{
+		{
+			TargetGroupArn: aws.String(testTargetGroupArn),
+			Targets: []*elbv2.TargetDescription{
+				{Id: aws.String("i-12345")},
+				{Id: aws.String("i-67890")},
+			},
+		},
+	}, mockedSvc.RegisterTargetsInputs)
+}
+
+func TestRegisterInstances_RegisterTargetsError(t *testing.T) {
+	e := &elbhelper.ELBHelper{Client: &th.MockedELBSvc{RegisterTargetsError: errors.New("Test error")}}
+
+	err := e.RegisterInstances(testTargetGroupArn, []string{"i-12345"})
+	th.Nok(t, err)
+}
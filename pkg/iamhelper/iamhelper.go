@@ -14,12 +14,37 @@
 package iamhelper
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/iam"
 )
 
+// The service whose service-linked role lets EC2 launch Spot instances on behalf of the account
+const SpotServiceName = "spot.amazonaws.com"
+
+// The instance profile, and its role, letting instances be managed by Systems Manager, created when needed
+const SsmInstanceProfileName = "simple-ec2-ssm"
+
+// The managed policy letting the SSM Agent of an instance register with Systems Manager
+const ssmManagedInstancePolicyName = "AmazonSSMManagedInstanceCore"
+
+// The trust policy letting EC2 instances assume a role
+const ec2AssumeRolePolicy = `{"Version":"2012-10-17","Statement":[{"Effect":"Allow",` +
+	`"Principal":{"Service":"ec2.amazonaws.com"},"Action":"sts:AssumeRole"}]}`
+
 type ProfileProvider interface {
 	ListInstanceProfiles(input *iam.ListInstanceProfilesInput) (*iam.ListInstanceProfilesOutput, error)
+	CreateServiceLinkedRole(input *iam.CreateServiceLinkedRoleInput) (*iam.CreateServiceLinkedRoleOutput, error)
+	GetInstanceProfile(input *iam.GetInstanceProfileInput) (*iam.GetInstanceProfileOutput, error)
+	CreateRole(input *iam.CreateRoleInput) (*iam.CreateRoleOutput, error)
+	AttachRolePolicy(input *iam.AttachRolePolicyInput) (*iam.AttachRolePolicyOutput, error)
+	CreateInstanceProfile(input *iam.CreateInstanceProfileInput) (*iam.CreateInstanceProfileOutput, error)
+	AddRoleToInstanceProfile(input *iam.AddRoleToInstanceProfileInput) (*iam.AddRoleToInstanceProfileOutput, error)
 }
 
 type IAMHelper struct {
@@ -31,3 +56,88 @@ func New(sess *session.Session) *IAMHelper {
 		Client: iam.New(sess),
 	}
 }
+
+/*
+Create the service-linked role for EC2 Spot, AWSServiceRoleForEC2Spot.
+A role that already exists, for example created concurrently, is not an error.
+*/
+func (i *IAMHelper) CreateSpotServiceLinkedRole() error {
+	_, err := i.Client.CreateServiceLinkedRole(&iam.CreateServiceLinkedRoleInput{
+		AWSServiceName: aws.String(SpotServiceName),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeInvalidInputException &&
+		strings.Contains(aerr.Message(), "has been taken") {
+		return nil
+	}
+
+	return err
+}
+
+/*
+Get the instance profile letting instances be managed by Systems Manager, SsmInstanceProfileName, creating it with
+a role of the same name if it doesn't exist. The region decides the partition of the managed policy.
+Return true if the instance profile was created, since EC2 may only accept a new instance profile after a delay.
+*/
+func (i *IAMHelper) GetOrCreateSsmInstanceProfile(region string) (bool, error) {
+	output, err := i.Client.GetInstanceProfile(&iam.GetInstanceProfileInput{
+		InstanceProfileName: aws.String(SsmInstanceProfileName),
+	})
+	if err == nil && len(output.InstanceProfile.Roles) > 0 {
+		return false, nil
+	}
+	if err != nil && !isNoSuchEntity(err) {
+		return false, err
+	}
+	isProfileMissing := err != nil
+
+	// The role may be left from an earlier attempt, in which case attaching the policy again has no effect
+	_, err = i.Client.CreateRole(&iam.CreateRoleInput{
+		RoleName:                 aws.String(SsmInstanceProfileName),
+		AssumeRolePolicyDocument: aws.String(ec2AssumeRolePolicy),
+		Description:              aws.String("Lets instances launched by simple-ec2 be managed by Systems Manager"),
+	})
+	if err != nil && !isEntityAlreadyExists(err) {
+		return false, err
+	}
+
+	partition := endpoints.AwsPartitionID
+	if p, found := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region); found {
+		partition = p.ID()
+	}
+	_, err = i.Client.AttachRolePolicy(&iam.AttachRolePolicyInput{
+		RoleName:  aws.String(SsmInstanceProfileName),
+		PolicyArn: aws.String(fmt.Sprintf("arn:%s:iam::aws:policy/%s", partition, ssmManagedInstancePolicyName)),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if isProfileMissing {
+		_, err = i.Client.CreateInstanceProfile(&iam.CreateInstanceProfileInput{
+			InstanceProfileName: aws.String(SsmInstanceProfileName),
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	_, err = i.Client.AddRoleToInstanceProfile(&iam.AddRoleToInstanceProfileInput{
+		InstanceProfileName: aws.String(SsmInstanceProfileName),
+		RoleName:            aws.String(SsmInstanceProfileName),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func isNoSuchEntity(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == iam.ErrCodeNoSuchEntityException
+}
+
+func isEntityAlreadyExists(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == iam.ErrCodeEntityAlreadyExistsException
+}
@@ -0,0 +1,104 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iamhelper_test
+
+import (
+	"errors"
+	"testing"
+
+	"simple-ec2/pkg/iamhelper"
+	th "simple-ec2/test/testhelper"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+)
+
+func TestCreateSpotServiceLinkedRole_Success(t *testing.T) {
+	mockedIam := &th.MockedIAMSvc{}
+	i := &iamhelper.IAMHelper{Client: mockedIam}
+
+	err := i.CreateSpotServiceLinkedRole()
+	th.Ok(t, err)
+	th.Equals(t, []string{iamhelper.SpotServiceName}, mockedIam.ServiceLinkedRoleServices)
+}
+
+func TestCreateSpotServiceLinkedRole_AlreadyExists(t *testing.T) {
+	i := &iamhelper.IAMHelper{Client: &th.MockedIAMSvc{
+		CreateServiceLinkedRoleError: awserr.New(iam.ErrCodeInvalidInputException,
+			"Service role name AWSServiceRoleForEC2Spot has been taken in this account, please try a different suffix.",
+			nil),
+	}}
+
+	err := i.CreateSpotServiceLinkedRole()
+	th.Ok(t, err)
+}
+
+func TestCreateSpotServiceLinkedRole_Error(t *testing.T) {
+	i := &iamhelper.IAMHelper{Client: &th.MockedIAMSvc{
+		CreateServiceLinkedRoleError: errors.New("Test error"),
+	}}
+
+	err := i.CreateSpotServiceLinkedRole()
+	th.Nok(t, err)
+}
+
+func TestGetOrCreateSsmInstanceProfile_Exists(t *testing.T) {
+	mockedIam := &th.MockedIAMSvc{InstanceProfiles: []*iam.InstanceProfile{
+		{
+			InstanceProfileName: aws.String(iamhelper.SsmInstanceProfileName),
+			Roles:               []*iam.Role{{RoleName: aws.String(iamhelper.SsmInstanceProfileName)}},
+		},
+	}}
+	i := &iamhelper.IAMHelper{Client: mockedIam}
+
+	isCreated, err := i.GetOrCreateSsmInstanceProfile("us-east-1")
+	th.Ok(t, err)
+	th.Assert(t, !isCreated, "An existing instance profile should not be created")
+	th.Equals(t, 0, len(mockedIam.CreatedRoles))
+}
+
+func TestGetOrCreateSsmInstanceProfile_Create(t *testing.T) {
+	mockedIam := &th.MockedIAMSvc{}
+	i := &iamhelper.IAMHelper{Client: mockedIam}
+
+	isCreated, err := i.GetOrCreateSsmInstanceProfile("cn-north-1")
+	th.Ok(t, err)
+	th.Assert(t, isCreated, "A missing instance profile should be created")
+	th.Equals(t, []string{iamhelper.SsmInstanceProfileName}, mockedIam.CreatedRoles)
+	th.Equals(t, []string{"arn:aws-cn:iam::aws:policy/AmazonSSMManagedInstanceCore"}, mockedIam.AttachedPolicyArns)
+	th.Equals(t, 1, len(mockedIam.InstanceProfiles))
+	th.Equals(t, iamhelper.SsmInstanceProfileName, *mockedIam.InstanceProfiles[0].Roles[0].RoleName)
+}
+
+func TestGetOrCreateSsmInstanceProfile_RoleExists(t *testing.T) {
+	mockedIam := &th.MockedIAMSvc{
+		CreateRoleError: awserr.New(iam.ErrCodeEntityAlreadyExistsException, "Role already exists", nil),
+	}
+	i := &iamhelper.IAMHelper{Client: mockedIam}
+
+	// The role left from an earlier attempt is added to the new instance profile
+	isCreated, err := i.GetOrCreateSsmInstanceProfile("us-east-1")
+	th.Ok(t, err)
+	th.Assert(t, isCreated, "A missing instance profile should be created")
+	th.Equals(t, []string{"arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore"}, mockedIam.AttachedPolicyArns)
+	th.Equals(t, 1, len(mockedIam.InstanceProfiles[0].Roles))
+}
+
+func TestGetOrCreateSsmInstanceProfile_Error(t *testing.T) {
+	i := &iamhelper.IAMHelper{Client: &th.MockedIAMSvc{GetInstanceProfileError: errors.New("Test error")}}
+
+	_, err := i.GetOrCreateSsmInstanceProfile("us-east-1")
+	th.Nok(t, err)
+}
@@ -0,0 +1,238 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iampolicy
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"simple-ec2/pkg/cfn"
+	"simple-ec2/pkg/ec2helper"
+	ec2ichelper "simple-ec2/pkg/ec2instanceconnecthelper"
+	"simple-ec2/pkg/elbhelper"
+	"simple-ec2/pkg/iamhelper"
+	"simple-ec2/pkg/stshelper"
+
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// The workflows a policy can be printed for
+const (
+	WorkflowLaunch    = "launch"
+	WorkflowConnect   = "connect"
+	WorkflowTerminate = "terminate"
+	WorkflowStart     = "start"
+	WorkflowSpot      = "spot"
+	WorkflowCleanup   = "cleanup"
+)
+
+var Workflows = []string{WorkflowLaunch, WorkflowConnect, WorkflowTerminate, WorkflowStart, WorkflowSpot,
+	WorkflowCleanup}
+
+const policyVersion = "2012-10-17"
+
+// An IAM policy document, in the format accepted by IAM
+type PolicyDocument struct {
+	Version   string
+	Statement []PolicyStatement
+}
+
+type PolicyStatement struct {
+	Effect   string
+	Action   []string
+	Resource string
+}
+
+// A service, with the interface or client simple-ec2 calls it through
+type api struct {
+	actionPrefix string
+	client       reflect.Type
+}
+
+var (
+	ec2Api                = api{"ec2", reflect.TypeOf((*ec2helper.EC2Svc)(nil)).Elem()}
+	cfnApi                = api{"cloudformation", reflect.TypeOf((*cfn.CfnSvc)(nil)).Elem()}
+	iamApi                = api{"iam", reflect.TypeOf((*iamhelper.ProfileProvider)(nil)).Elem()}
+	stsApi                = api{"sts", reflect.TypeOf((*stshelper.IdentityProvider)(nil)).Elem()}
+	ec2InstanceConnectApi = api{"ec2-instance-connect", reflect.TypeOf((*ec2ichelper.KeyPusher)(nil)).Elem()}
+	ssmApi                = api{"ssm", reflect.TypeOf(&ssm.SSM{})}
+	elbApi                = api{"elasticloadbalancing", reflect.TypeOf((*elbhelper.TargetGroupProvider)(nil)).Elem()}
+)
+
+// The methods of an API called by a workflow
+type apiCalls struct {
+	api     api
+	methods []string
+}
+
+// The calls made by every workflow, checking the credentials and the region
+var commonCalls = []apiCalls{
+	{stsApi, []string{"GetCallerIdentity"}},
+	{ec2Api, []string{"DescribeRegions"}},
+}
+
+var launchCalls = []apiCalls{
+	{ec2Api, []string{
+		"DescribeAvailabilityZones",
+		"DescribeLaunchTemplatesPages",
+		"DescribeLaunchTemplateVersionsPages",
+		"DescribeInstanceTypesPages",
+		"DescribeInstanceTypeOfferingsPages",
+		"DescribeImagesPages",
+		"DescribeVpcsPages",
+		"DescribeSubnetsPages",
+		"DescribeRouteTablesPages",
+		"DescribeSecurityGroupsPages",
+		"CreateSecurityGroup",
+		"AuthorizeSecurityGroupIngress",
+		"DescribeKeyPairs",
+		"CreateKeyPair",
+		"DescribeInstancesPages",
+		"CreateTags",
+		"RunInstances",
+		"CreateNetworkInterface",
+		"AttachNetworkInterface",
+		"WaitUntilInstanceRunning",
+		"GetConsoleOutput",
+	}},
+	{cfnApi, []string{"CreateStack", "DescribeStackResources", "DescribeStackEventsPages", "DeleteStack"}},
+	{iamApi, []string{
+		"ListInstanceProfiles",
+		"GetInstanceProfile",
+		"CreateRole",
+		"AttachRolePolicy",
+		"CreateInstanceProfile",
+		"AddRoleToInstanceProfile",
+	}},
+	{ssmApi, []string{"GetParametersByPathPages"}},
+	{elbApi, []string{"DescribeTargetGroups", "RegisterTargets"}},
+}
+
+// A Spot launch also creates a launch template and an instant fleet, on top of the calls of a launch
+var spotCalls = append(append([]apiCalls{}, launchCalls...), []apiCalls{
+	{ec2Api, []string{"CreateLaunchTemplate", "DeleteLaunchTemplate", "CreateFleet"}},
+	{iamApi, []string{"CreateServiceLinkedRole"}},
+}...)
+
+var workflowCalls = map[string][]apiCalls{
+	WorkflowLaunch: launchCalls,
+	WorkflowConnect: {
+		{ec2Api, []string{"DescribeInstancesPages"}},
+		{ec2InstanceConnectApi, []string{"SendSSHPublicKey"}},
+	},
+	WorkflowTerminate: {
+		{ec2Api, []string{"DescribeInstancesPages", "TerminateInstances"}},
+	},
+	WorkflowStart: {
+		{ec2Api, []string{"DescribeInstancesPages", "StartInstances", "WaitUntilInstanceRunning"}},
+	},
+	WorkflowSpot: spotCalls,
+	WorkflowCleanup: {
+		{ec2Api, []string{
+			"DescribeInstancesPages",
+			"DescribeSecurityGroupsPages",
+			"DeleteSecurityGroup",
+			"DescribeLaunchTemplatesPages",
+			"DeleteLaunchTemplate",
+			"DescribeFleetsPages",
+		}},
+		{cfnApi, []string{"DescribeStacksPages", "DescribeStackResources", "DeleteStack"}},
+	},
+}
+
+/*
+The actions needed without simple-ec2 calling them itself: passing the role of the instance profile to the instance,
+and the calls of the price lookup
+*/
+var launchExtraActions = []string{
+	"iam:PassRole",
+	"ec2:DescribeSpotPriceHistory",
+	"pricing:GetProducts",
+}
+
+var workflowExtraActions = map[string][]string{
+	WorkflowLaunch: launchExtraActions,
+	WorkflowSpot:   launchExtraActions,
+	// A Session Manager session is started by the AWS CLI
+	WorkflowConnect: {"ssm:StartSession"},
+}
+
+// The actions of the waiters, which poll a describe call
+var waiterActions = map[string]string{
+	"WaitUntilInstanceRunning": "DescribeInstances",
+}
+
+// Get the IAM action of an API method, such as "ec2:DescribeImages" for DescribeImagesPages
+func getAction(a api, method string) (string, error) {
+	if _, found := a.client.MethodByName(method); !found {
+		return "", fmt.Errorf("%s has no method %s", a.client, method)
+	}
+
+	action, isWaiter := waiterActions[method]
+	if !isWaiter {
+		action = strings.TrimSuffix(method, "Pages")
+	}
+	return a.actionPrefix + ":" + action, nil
+}
+
+// Get the sorted IAM actions used by a workflow
+func GetActions(workflow string) ([]string, error) {
+	calls, found := workflowCalls[workflow]
+	if !found {
+		return nil, fmt.Errorf("Unknown workflow %s. Valid workflows are %s", workflow,
+			strings.Join(Workflows, ", "))
+	}
+
+	actionSet := map[string]bool{}
+	for _, call := range append(append([]apiCalls{}, commonCalls...), calls...) {
+		for _, method := range call.methods {
+			action, err := getAction(call.api, method)
+			if err != nil {
+				return nil, err
+			}
+			actionSet[action] = true
+		}
+	}
+	for _, action := range workflowExtraActions[workflow] {
+		actionSet[action] = true
+	}
+
+	actions := []string{}
+	for action := range actionSet {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions, nil
+}
+
+// Get a policy document allowing the actions used by a workflow, ready to be attached to a user or role
+func GetPolicyDocument(workflow string) (*PolicyDocument, error) {
+	actions, err := GetActions(workflow)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PolicyDocument{
+		Version: policyVersion,
+		Statement: []PolicyStatement{
+			{
+				Effect:   "Allow",
+				Action:   actions,
+				Resource: "*",
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,135 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iampolicy_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"simple-ec2/pkg/cfn"
+	"simple-ec2/pkg/ec2helper"
+	"simple-ec2/pkg/iampolicy"
+	th "simple-ec2/test/testhelper"
+)
+
+func toSet(actions []string) map[string]bool {
+	actionSet := map[string]bool{}
+	for _, action := range actions {
+		actionSet[action] = true
+	}
+	return actionSet
+}
+
+func TestGetActions_Launch(t *testing.T) {
+	actions, err := iampolicy.GetActions(iampolicy.WorkflowLaunch)
+	th.Ok(t, err)
+
+	actionSet := toSet(actions)
+	for _, action := range []string{
+		"ec2:RunInstances",
+		"ec2:DescribeInstanceTypes",
+		"ec2:DescribeImages",
+		"ec2:DescribeSubnets",
+		"ec2:DescribeRouteTables",
+		"ec2:CreateSecurityGroup",
+		"ec2:AuthorizeSecurityGroupIngress",
+		"ec2:CreateTags",
+		"ec2:DescribeInstances",
+		"cloudformation:CreateStack",
+		"iam:ListInstanceProfiles",
+		"iam:PassRole",
+		"iam:CreateInstanceProfile",
+		"iam:AttachRolePolicy",
+		"sts:GetCallerIdentity",
+		"elasticloadbalancing:DescribeTargetGroups",
+		"elasticloadbalancing:RegisterTargets",
+	} {
+		th.Assert(t, actionSet[action], action+" should be allowed for launch")
+	}
+
+	// The Spot actions are only needed by Spot launches
+	th.Assert(t, !actionSet["ec2:CreateFleet"], "ec2:CreateFleet should not be allowed for launch")
+}
+
+func TestGetActions_Spot(t *testing.T) {
+	actions, err := iampolicy.GetActions(iampolicy.WorkflowSpot)
+	th.Ok(t, err)
+
+	actionSet := toSet(actions)
+	for _, action := range []string{
+		"ec2:RunInstances",
+		"ec2:CreateLaunchTemplate",
+		"ec2:DeleteLaunchTemplate",
+		"ec2:CreateFleet",
+		"iam:CreateServiceLinkedRole",
+	} {
+		th.Assert(t, actionSet[action], action+" should be allowed for spot")
+	}
+}
+
+func TestGetActions_Connect(t *testing.T) {
+	actions, err := iampolicy.GetActions(iampolicy.WorkflowConnect)
+	th.Ok(t, err)
+	th.Equals(t, []string{
+		"ec2-instance-connect:SendSSHPublicKey",
+		"ec2:DescribeInstances",
+		"ec2:DescribeRegions",
+		"ssm:StartSession",
+		"sts:GetCallerIdentity",
+	}, actions)
+}
+
+func TestGetActions_UnknownWorkflow(t *testing.T) {
+	_, err := iampolicy.GetActions("stop")
+	th.Nok(t, err)
+}
+
+// Every method of the service interfaces is called by some workflow, so new calls can't be missed in the policies
+func TestGetActions_AllMethodsCovered(t *testing.T) {
+	allActions := map[string]bool{}
+	for _, workflow := range iampolicy.Workflows {
+		actions, err := iampolicy.GetActions(workflow)
+		th.Ok(t, err)
+		for action := range toSet(actions) {
+			allActions[action] = true
+		}
+	}
+
+	for prefix, client := range map[string]reflect.Type{
+		"ec2":            reflect.TypeOf((*ec2helper.EC2Svc)(nil)).Elem(),
+		"cloudformation": reflect.TypeOf((*cfn.CfnSvc)(nil)).Elem(),
+	} {
+		for i := 0; i < client.NumMethod(); i++ {
+			method := client.Method(i).Name
+			if strings.HasPrefix(method, "WaitUntil") {
+				continue
+			}
+			action := prefix + ":" + strings.TrimSuffix(method, "Pages")
+			th.Assert(t, allActions[action], action+" should be allowed by a workflow")
+		}
+	}
+}
+
+func TestGetPolicyDocument(t *testing.T) {
+	policy, err := iampolicy.GetPolicyDocument(iampolicy.WorkflowTerminate)
+	th.Ok(t, err)
+
+	policyJson, err := json.Marshal(policy)
+	th.Ok(t, err)
+	th.Equals(t, `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["ec2:DescribeInstances",`+
+		`"ec2:DescribeRegions","ec2:TerminateInstances","sts:GetCallerIdentity"],"Resource":"*"}]}`,
+		string(policyJson))
+}
@@ -0,0 +1,138 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package output
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The characters of a command line argument that don't need quoting in a shell
+var safeShellArgRegex = regexp.MustCompile(`^[a-zA-Z0-9._\-:/=@,+]+$`)
+
+/*
+Render the AWS CLI command equivalent to an API call made with the AWS SDK for Go, such as
+"aws ec2 run-instances --image-id ami-12345 ..." for the input of RunInstances. Each field set in the input is an
+option named after the field. Lists of strings are separate arguments, and structures are JSON, as the CLI accepts.
+*/
+func RenderCliCommand(service, operation string, input interface{}) (string, error) {
+	args := []string{"aws", service, operation}
+
+	value := reflect.Indirect(reflect.ValueOf(input))
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		fieldValue := value.Field(i)
+
+		// The SDK structures have an unexported field for their metadata
+		if field.PkgPath != "" || fieldValue.IsZero() {
+			continue
+		}
+
+		option := "--" + toKebabCase(field.Name)
+		switch fieldValue.Interface().(type) {
+		case *bool:
+			// Booleans are switches, which are negated with a prefix
+			if !fieldValue.Elem().Bool() {
+				option = "--no-" + toKebabCase(field.Name)
+			}
+			args = append(args, option)
+		case *string:
+			args = append(args, option, quoteShellArg(fieldValue.Elem().String()))
+		case *int64:
+			args = append(args, option, strconv.FormatInt(fieldValue.Elem().Int(), 10))
+		case []*string:
+			args = append(args, option)
+			for j := 0; j < fieldValue.Len(); j++ {
+				args = append(args, quoteShellArg(fieldValue.Index(j).Elem().String()))
+			}
+		default:
+			structure, err := renderCliJson(fieldValue.Interface())
+			if err != nil {
+				return "", err
+			}
+			args = append(args, option, quoteShellArg(structure))
+		}
+	}
+
+	return strings.Join(args, " "), nil
+}
+
+// Render a structure of the SDK as compact JSON, leaving out the fields that aren't set
+func renderCliJson(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	var structure interface{}
+	err = json.Unmarshal(data, &structure)
+	if err != nil {
+		return "", err
+	}
+
+	data, err = json.Marshal(removeNullFields(structure))
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// Remove the null fields of decoded JSON objects, at any depth
+func removeNullFields(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if field == nil {
+				delete(value, key)
+			} else {
+				value[key] = removeNullFields(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = removeNullFields(item)
+		}
+	}
+
+	return v
+}
+
+// Convert the name of a field to the name of its CLI option, such as "security-group-ids" for SecurityGroupIds
+func toKebabCase(name string) string {
+	runes := []rune(name)
+	var builder strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (!unicode.IsUpper(runes[i-1]) ||
+			(i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			builder.WriteRune('-')
+		}
+		builder.WriteRune(unicode.ToLower(r))
+	}
+
+	return builder.String()
+}
+
+// Quote an argument for a POSIX shell, unless it only has characters the shell doesn't interpret
+func quoteShellArg(arg string) string {
+	if safeShellArgRegex.MatchString(arg) {
+		return arg
+	}
+
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
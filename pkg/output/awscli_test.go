@@ -0,0 +1,65 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package output_test
+
+import (
+	"strings"
+	"testing"
+
+	"simple-ec2/pkg/output"
+	th "simple-ec2/test/testhelper"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestRenderCliCommand_RunInstances(t *testing.T) {
+	command, err := output.RenderCliCommand("ec2", "run-instances", &ec2.RunInstancesInput{
+		ImageId:          aws.String("ami-12345"),
+		InstanceType:     aws.String("t2.micro"),
+		SecurityGroupIds: aws.StringSlice([]string{"sg-12345", "sg-67890"}),
+		MaxCount:         aws.Int64(2),
+		EbsOptimized:     aws.Bool(false),
+		TagSpecifications: []*ec2.TagSpecification{
+			{
+				ResourceType: aws.String(ec2.ResourceTypeInstance),
+				Tags: []*ec2.Tag{
+					{Key: aws.String("Name"), Value: aws.String("test")},
+				},
+			},
+		},
+	})
+	th.Ok(t, err)
+
+	th.Assert(t, strings.HasPrefix(command, "aws ec2 run-instances "), "The command should run run-instances")
+	for _, part := range []string{
+		"--image-id ami-12345",
+		"--instance-type t2.micro",
+		"--security-group-ids sg-12345 sg-67890",
+		"--max-count 2",
+		"--no-ebs-optimized",
+		`--tag-specifications '[{"ResourceType":"instance","Tags":[{"Key":"Name","Value":"test"}]}]'`,
+	} {
+		th.Assert(t, strings.Contains(command, part), command+" should contain "+part)
+	}
+	th.Assert(t, !strings.Contains(command, "--subnet-id"), "Fields that aren't set should be left out")
+}
+
+func TestRenderCliCommand_Quoting(t *testing.T) {
+	command, err := output.RenderCliCommand("ec2", "run-instances", &ec2.RunInstancesInput{
+		UserData: aws.String("echo 'hello world'"),
+	})
+	th.Ok(t, err)
+	th.Equals(t, `aws ec2 run-instances --user-data 'echo '\''hello world'\'''`, command)
+}
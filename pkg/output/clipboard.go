@@ -0,0 +1,65 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/atotto/clipboard"
+)
+
+// The user of the images launched by simple-ec2, as used by the connect command
+const sshUserName = "ec2-user"
+
+// Clipboard writes text to a clipboard, so that tests can replace the system clipboard
+type Clipboard interface {
+	WriteAll(text string) error
+}
+
+// SystemClipboard writes to the clipboard of the operating system
+type SystemClipboard struct{}
+
+func (SystemClipboard) WriteAll(text string) error {
+	return clipboard.WriteAll(text)
+}
+
+/*
+Format the key details of the launched instances to be copied: the instance id and, when the instance
+has an address, the SSH command to connect to it. The public address is preferred over the private one.
+*/
+func FormatClipboardPayload(summaries []*InstanceSummary) string {
+	lines := []string{}
+	for _, summary := range summaries {
+		lines = append(lines, "Instance ID: "+summary.InstanceID)
+
+		address := summary.PublicDNS
+		if address == "" {
+			address = summary.PublicIP
+		}
+		if address == "" {
+			address = summary.PrivateIP
+		}
+		if address != "" {
+			lines = append(lines, fmt.Sprintf("SSH command: ssh %s@%s", sshUserName, address))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Copy the key details of the launched instances to the clipboard
+func CopyToClipboard(c Clipboard, summaries []*InstanceSummary) error {
+	return c.WriteAll(FormatClipboardPayload(summaries))
+}
@@ -0,0 +1,56 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package output_test
+
+import (
+	"errors"
+	"testing"
+
+	"simple-ec2/pkg/output"
+	th "simple-ec2/test/testhelper"
+)
+
+var testClipboardSummaries = []*output.InstanceSummary{
+	{InstanceID: "i-12345", PublicDNS: "ec2-1-2-3-4.compute-1.amazonaws.com", PrivateIP: "10.0.0.10"},
+	{InstanceID: "i-67890", PrivateIP: "10.0.0.11"},
+	{InstanceID: "i-abcde"},
+}
+
+func TestFormatClipboardPayload(t *testing.T) {
+	const expectedPayload = "Instance ID: i-12345\n" +
+		"SSH command: ssh ec2-user@ec2-1-2-3-4.compute-1.amazonaws.com\n" +
+		"Instance ID: i-67890\n" +
+		"SSH command: ssh ec2-user@10.0.0.11\n" +
+		"Instance ID: i-abcde"
+
+	th.Equals(t, expectedPayload, output.FormatClipboardPayload(testClipboardSummaries))
+}
+
+func TestCopyToClipboard_Success(t *testing.T) {
+	mockedClipboard := &th.MockedClipboard{}
+
+	err := output.CopyToClipboard(mockedClipboard, testClipboardSummaries[:1])
+	th.Ok(t, err)
+	th.Equals(t, "Instance ID: i-12345\nSSH command: ssh ec2-user@ec2-1-2-3-4.compute-1.amazonaws.com",
+		mockedClipboard.Text)
+}
+
+func TestCopyToClipboard_WriteAllError(t *testing.T) {
+	mockedClipboard := &th.MockedClipboard{
+		WriteAllError: errors.New("Test error"),
+	}
+
+	err := output.CopyToClipboard(mockedClipboard, testClipboardSummaries)
+	th.Nok(t, err)
+}
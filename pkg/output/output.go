@@ -0,0 +1,301 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"text/template"
+
+	"simple-ec2/pkg/config"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+/*
+A summary of a launched instance, exposed to user-supplied output templates.
+Field names are part of the CLI interface and documented in the README.
+*/
+type InstanceSummary struct {
+	InstanceID   string
+	InstanceType string
+	ImageID      string
+	State        string
+	Region       string
+	SubnetID     string
+	PrivateIP    string
+	PublicIP     string
+	PublicDNS    string
+}
+
+// Create an instance summary from an EC2 instance
+func NewInstanceSummary(instance *ec2.Instance, region string) *InstanceSummary {
+	summary := &InstanceSummary{
+		InstanceID:   aws.StringValue(instance.InstanceId),
+		InstanceType: aws.StringValue(instance.InstanceType),
+		ImageID:      aws.StringValue(instance.ImageId),
+		Region:       region,
+		SubnetID:     aws.StringValue(instance.SubnetId),
+		PrivateIP:    aws.StringValue(instance.PrivateIpAddress),
+		PublicIP:     aws.StringValue(instance.PublicIpAddress),
+		PublicDNS:    aws.StringValue(instance.PublicDnsName),
+	}
+	if instance.State != nil {
+		summary.State = aws.StringValue(instance.State.Name)
+	}
+
+	return summary
+}
+
+// A summary of a terminated instance, printed as structured output
+type TerminationSummary struct {
+	InstanceID    string `json:"instanceId"`
+	PreviousState string `json:"previousState"`
+}
+
+// Create termination summaries from the state changes returned by the termination
+func NewTerminationSummaries(stateChanges []*ec2.InstanceStateChange) []*TerminationSummary {
+	summaries := []*TerminationSummary{}
+	for _, stateChange := range stateChanges {
+		summary := &TerminationSummary{
+			InstanceID: aws.StringValue(stateChange.InstanceId),
+		}
+		if stateChange.PreviousState != nil {
+			summary.PreviousState = aws.StringValue(stateChange.PreviousState.Name)
+		}
+		summaries = append(summaries, summary)
+	}
+
+	return summaries
+}
+
+/*
+A launch resolved from the config, printed as structured output when only describing the launch. Unlike the config,
+which only has ids, it describes the resources the ids refer to.
+*/
+type LaunchDescription struct {
+	Image          *ImageDescription           `json:"image"`
+	InstanceType   *InstanceTypeDescription    `json:"instanceType"`
+	Vpc            *VpcDescription             `json:"vpc,omitempty"`
+	Subnet         *SubnetDescription          `json:"subnet,omitempty"`
+	SecurityGroups []*SecurityGroupDescription `json:"securityGroups"`
+}
+
+type ImageDescription struct {
+	ImageID         string `json:"imageId"`
+	Name            string `json:"name"`
+	Description     string `json:"description,omitempty"`
+	OwnerID         string `json:"ownerId"`
+	Architecture    string `json:"architecture"`
+	PlatformDetails string `json:"platformDetails"`
+	RootDeviceType  string `json:"rootDeviceType"`
+	CreationDate    string `json:"creationDate"`
+}
+
+type InstanceTypeDescription struct {
+	InstanceType  string   `json:"instanceType"`
+	VCpus         int64    `json:"vCpus"`
+	MemoryMiB     int64    `json:"memoryMiB"`
+	Architectures []string `json:"architectures"`
+}
+
+type VpcDescription struct {
+	VpcID     string `json:"vpcId"`
+	CidrBlock string `json:"cidrBlock"`
+	IsDefault bool   `json:"isDefault"`
+}
+
+type SubnetDescription struct {
+	SubnetID            string `json:"subnetId"`
+	AvailabilityZone    string `json:"availabilityZone"`
+	AvailabilityZoneID  string `json:"availabilityZoneId"`
+	CidrBlock           string `json:"cidrBlock"`
+	MapPublicIpOnLaunch bool   `json:"mapPublicIpOnLaunch"`
+}
+
+type SecurityGroupDescription struct {
+	GroupID     string `json:"groupId"`
+	GroupName   string `json:"groupName"`
+	Description string `json:"description"`
+}
+
+/*
+Create a launch description from the detailed config. The VPC and subnet are left out when a new VPC is created,
+since they don't exist yet.
+*/
+func NewLaunchDescription(detailedConfig *config.DetailedInfo) *LaunchDescription {
+	description := &LaunchDescription{
+		SecurityGroups: []*SecurityGroupDescription{},
+	}
+
+	if image := detailedConfig.Image; image != nil {
+		description.Image = &ImageDescription{
+			ImageID:         aws.StringValue(image.ImageId),
+			Name:            aws.StringValue(image.Name),
+			Description:     aws.StringValue(image.Description),
+			OwnerID:         aws.StringValue(image.OwnerId),
+			Architecture:    aws.StringValue(image.Architecture),
+			PlatformDetails: aws.StringValue(image.PlatformDetails),
+			RootDeviceType:  aws.StringValue(image.RootDeviceType),
+			CreationDate:    aws.StringValue(image.CreationDate),
+		}
+	}
+
+	if instanceTypeInfo := detailedConfig.InstanceTypeInfo; instanceTypeInfo != nil {
+		description.InstanceType = &InstanceTypeDescription{
+			InstanceType:  aws.StringValue(instanceTypeInfo.InstanceType),
+			Architectures: []string{},
+		}
+		if instanceTypeInfo.VCpuInfo != nil {
+			description.InstanceType.VCpus = aws.Int64Value(instanceTypeInfo.VCpuInfo.DefaultVCpus)
+		}
+		if instanceTypeInfo.MemoryInfo != nil {
+			description.InstanceType.MemoryMiB = aws.Int64Value(instanceTypeInfo.MemoryInfo.SizeInMiB)
+		}
+		if instanceTypeInfo.ProcessorInfo != nil {
+			description.InstanceType.Architectures = aws.StringValueSlice(
+				instanceTypeInfo.ProcessorInfo.SupportedArchitectures)
+		}
+	}
+
+	if vpc := detailedConfig.Vpc; vpc != nil {
+		description.Vpc = &VpcDescription{
+			VpcID:     aws.StringValue(vpc.VpcId),
+			CidrBlock: aws.StringValue(vpc.CidrBlock),
+			IsDefault: aws.BoolValue(vpc.IsDefault),
+		}
+	}
+
+	if subnet := detailedConfig.Subnet; subnet != nil {
+		description.Subnet = &SubnetDescription{
+			SubnetID:            aws.StringValue(subnet.SubnetId),
+			AvailabilityZone:    aws.StringValue(subnet.AvailabilityZone),
+			AvailabilityZoneID:  aws.StringValue(subnet.AvailabilityZoneId),
+			CidrBlock:           aws.StringValue(subnet.CidrBlock),
+			MapPublicIpOnLaunch: aws.BoolValue(subnet.MapPublicIpOnLaunch),
+		}
+	}
+
+	for _, securityGroup := range detailedConfig.SecurityGroups {
+		description.SecurityGroups = append(description.SecurityGroups, &SecurityGroupDescription{
+			GroupID:     aws.StringValue(securityGroup.GroupId),
+			GroupName:   aws.StringValue(securityGroup.GroupName),
+			Description: aws.StringValue(securityGroup.Description),
+		})
+	}
+
+	return description
+}
+
+// The instances of a launch, printed as structured output for scripts once launched
+type LaunchResult struct {
+	InstanceIDs      []string `json:"instanceIds"`
+	Region           string   `json:"region"`
+	InstanceType     string   `json:"instanceType"`
+	ImageID          string   `json:"imageId"`
+	SubnetID         string   `json:"subnetId"`
+	SecurityGroupIDs []string `json:"securityGroupIds"`
+	CapacityType     string   `json:"capacityType"`
+}
+
+/*
+Create a launch result from the launched instances. The instances of a launch share their configuration, so it is
+read from the first instance, which reflects a new VPC or a Spot size fallback unlike the config.
+*/
+func NewLaunchResult(instances []*ec2.Instance, region, capacityType string) *LaunchResult {
+	result := &LaunchResult{
+		InstanceIDs:      []string{},
+		Region:           region,
+		SecurityGroupIDs: []string{},
+		CapacityType:     capacityType,
+	}
+
+	for _, instance := range instances {
+		result.InstanceIDs = append(result.InstanceIDs, aws.StringValue(instance.InstanceId))
+	}
+	if len(instances) > 0 {
+		instance := instances[0]
+		result.InstanceType = aws.StringValue(instance.InstanceType)
+		result.ImageID = aws.StringValue(instance.ImageId)
+		result.SubnetID = aws.StringValue(instance.SubnetId)
+		for _, securityGroup := range instance.SecurityGroups {
+			result.SecurityGroupIDs = append(result.SecurityGroupIDs, aws.StringValue(securityGroup.GroupId))
+		}
+	}
+
+	return result
+}
+
+// A failure of validating a flag, printed as structured output. The field is the name of the flag
+type ValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Render a value as indented JSON
+func RenderJson(v interface{}) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// JsonLinesWriter streams values as JSON lines, one compact JSON object per line
+type JsonLinesWriter struct {
+	encoder *json.Encoder
+}
+
+// Create a JSON lines writer writing to w
+func NewJsonLinesWriter(w io.Writer) *JsonLinesWriter {
+	return &JsonLinesWriter{encoder: json.NewEncoder(w)}
+}
+
+// Write a value as a single JSON line
+func (w *JsonLinesWriter) Write(v interface{}) error {
+	return w.encoder.Encode(v)
+}
+
+// Parse a user-supplied output template
+func ParseTemplate(text string) (*template.Template, error) {
+	return template.New("output").Parse(text)
+}
+
+/*
+Validate a user-supplied output template before any instance is launched. Parsing doesn't catch unknown fields,
+such as a misspelled field name, so the template is also rendered with an empty instance summary.
+*/
+func ValidateTemplate(text string) error {
+	tmpl, err := ParseTemplate(text)
+	if err != nil {
+		return err
+	}
+
+	_, err = RenderTemplate(tmpl, &InstanceSummary{})
+	return err
+}
+
+// Render an instance summary through a parsed output template
+func RenderTemplate(tmpl *template.Template, summary *InstanceSummary) (string, error) {
+	builder := &strings.Builder{}
+	err := tmpl.Execute(builder, summary)
+	if err != nil {
+		return "", err
+	}
+
+	return builder.String(), nil
+}
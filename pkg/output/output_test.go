@@ -0,0 +1,203 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package output_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"simple-ec2/pkg/config"
+	"simple-ec2/pkg/output"
+	th "simple-ec2/test/testhelper"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+var testInstance = &ec2.Instance{
+	InstanceId:       aws.String("i-12345"),
+	InstanceType:     aws.String("t2.micro"),
+	ImageId:          aws.String("ami-12345"),
+	SubnetId:         aws.String("subnet-12345"),
+	PrivateIpAddress: aws.String("10.0.0.10"),
+	PublicDnsName:    aws.String("ec2-1-2-3-4.compute-1.amazonaws.com"),
+	State: &ec2.InstanceState{
+		Name: aws.String(ec2.InstanceStateNamePending),
+	},
+}
+
+func TestNewInstanceSummary(t *testing.T) {
+	summary := output.NewInstanceSummary(testInstance, "us-east-1")
+	th.Equals(t, "i-12345", summary.InstanceID)
+	th.Equals(t, "us-east-1", summary.Region)
+	th.Equals(t, ec2.InstanceStateNamePending, summary.State)
+	th.Equals(t, "", summary.PublicIP)
+}
+
+func TestRenderTemplate_Success(t *testing.T) {
+	tmpl, err := output.ParseTemplate("{{.InstanceID}} {{.PublicDNS}}")
+	th.Ok(t, err)
+
+	actualOutput, err := output.RenderTemplate(tmpl, output.NewInstanceSummary(testInstance, "us-east-1"))
+	th.Ok(t, err)
+	th.Equals(t, "i-12345 ec2-1-2-3-4.compute-1.amazonaws.com", actualOutput)
+}
+
+func TestRenderTemplate_UnknownField(t *testing.T) {
+	tmpl, err := output.ParseTemplate("{{.InstanceName}}")
+	th.Ok(t, err)
+
+	_, err = output.RenderTemplate(tmpl, output.NewInstanceSummary(testInstance, "us-east-1"))
+	th.Nok(t, err)
+}
+
+func TestParseTemplate_Invalid(t *testing.T) {
+	_, err := output.ParseTemplate("{{.InstanceID")
+	th.Nok(t, err)
+}
+
+func TestValidateTemplate_Success(t *testing.T) {
+	err := output.ValidateTemplate("{{.InstanceID}} {{.PublicDNS}}")
+	th.Ok(t, err)
+}
+
+func TestValidateTemplate_UnknownField(t *testing.T) {
+	err := output.ValidateTemplate("{{.PublicDns}}")
+	th.Nok(t, err)
+}
+
+func TestValidateTemplate_Invalid(t *testing.T) {
+	err := output.ValidateTemplate("{{.InstanceID")
+	th.Nok(t, err)
+}
+
+func TestRenderJson_TerminationSummaries(t *testing.T) {
+	stateChanges := []*ec2.InstanceStateChange{
+		{
+			InstanceId:    aws.String("i-12345"),
+			PreviousState: &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+			CurrentState:  &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameShuttingDown)},
+		},
+		{
+			InstanceId:    aws.String("i-67890"),
+			PreviousState: &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameStopped)},
+			CurrentState:  &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameShuttingDown)},
+		},
+	}
+
+	rendered, err := output.RenderJson(output.NewTerminationSummaries(stateChanges))
+	th.Ok(t, err)
+
+	parsed := []map[string]string{}
+	err = json.Unmarshal([]byte(rendered), &parsed)
+	th.Ok(t, err)
+	th.Equals(t, []map[string]string{
+		{"instanceId": "i-12345", "previousState": ec2.InstanceStateNameRunning},
+		{"instanceId": "i-67890", "previousState": ec2.InstanceStateNameStopped},
+	}, parsed)
+}
+
+func TestRenderJson_NoTerminatedInstances(t *testing.T) {
+	rendered, err := output.RenderJson(output.NewTerminationSummaries(nil))
+	th.Ok(t, err)
+	th.Equals(t, "[]", rendered)
+}
+
+func TestRenderJson_LaunchResult(t *testing.T) {
+	instances := []*ec2.Instance{
+		{
+			InstanceId:   aws.String("i-12345"),
+			InstanceType: aws.String("t3.micro"),
+			ImageId:      aws.String("ami-12345"),
+			SubnetId:     aws.String("subnet-12345"),
+			SecurityGroups: []*ec2.GroupIdentifier{
+				{GroupId: aws.String("sg-12345"), GroupName: aws.String("default")},
+			},
+		},
+		{
+			InstanceId:   aws.String("i-67890"),
+			InstanceType: aws.String("t3.micro"),
+			ImageId:      aws.String("ami-12345"),
+			SubnetId:     aws.String("subnet-12345"),
+		},
+	}
+
+	rendered, err := output.RenderJson(output.NewLaunchResult(instances, "us-east-1", "On-Demand"))
+	th.Ok(t, err)
+
+	parsed := map[string]interface{}{}
+	err = json.Unmarshal([]byte(rendered), &parsed)
+	th.Ok(t, err)
+	th.Equals(t, map[string]interface{}{
+		"instanceIds":      []interface{}{"i-12345", "i-67890"},
+		"region":           "us-east-1",
+		"instanceType":     "t3.micro",
+		"imageId":          "ami-12345",
+		"subnetId":         "subnet-12345",
+		"securityGroupIds": []interface{}{"sg-12345"},
+		"capacityType":     "On-Demand",
+	}, parsed)
+}
+
+func TestNewLaunchDescription(t *testing.T) {
+	description := output.NewLaunchDescription(&config.DetailedInfo{
+		Image: &ec2.Image{
+			ImageId:      aws.String("ami-12345"),
+			Name:         aws.String("amzn2-ami-hvm-2.0.20230404.1-x86_64-gp2"),
+			Architecture: aws.String("x86_64"),
+		},
+		InstanceTypeInfo: &ec2.InstanceTypeInfo{
+			InstanceType: aws.String("t2.micro"),
+			VCpuInfo:     &ec2.VCpuInfo{DefaultVCpus: aws.Int64(1)},
+			MemoryInfo:   &ec2.MemoryInfo{SizeInMiB: aws.Int64(1024)},
+		},
+		Subnet: &ec2.Subnet{
+			SubnetId:         aws.String("subnet-12345"),
+			AvailabilityZone: aws.String("us-east-2a"),
+		},
+		SecurityGroups: []*ec2.SecurityGroup{
+			{GroupId: aws.String("sg-12345"), GroupName: aws.String("default")},
+		},
+	})
+
+	descriptionJson, err := json.Marshal(description)
+	th.Ok(t, err)
+	for _, part := range []string{
+		`"name":"amzn2-ami-hvm-2.0.20230404.1-x86_64-gp2"`,
+		`"vCpus":1`,
+		`"availabilityZone":"us-east-2a"`,
+		`"securityGroups":[{"groupId":"sg-12345","groupName":"default","description":""}]`,
+	} {
+		th.Assert(t, strings.Contains(string(descriptionJson), part), string(descriptionJson)+" should contain "+part)
+	}
+
+	// The VPC isn't resolved, so it is left out
+	th.Assert(t, !strings.Contains(string(descriptionJson), `"vpc"`), "A missing VPC should be left out")
+}
+
+func TestJsonLinesWriter(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer := output.NewJsonLinesWriter(buffer)
+
+	th.Ok(t, writer.Write(output.TerminationSummary{InstanceID: "i-12345", PreviousState: "running"}))
+	th.Ok(t, writer.Write(output.TerminationSummary{InstanceID: "i-67890", PreviousState: "stopped"}))
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	th.Equals(t, []string{
+		`{"instanceId":"i-12345","previousState":"running"}`,
+		`{"instanceId":"i-67890","previousState":"stopped"}`,
+	}, lines)
+}
@@ -17,6 +17,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -30,22 +31,29 @@ import (
 	"simple-ec2/pkg/table"
 
 	"github.com/aws/amazon-ec2-instance-selector/v2/pkg/ec2pricing"
+	"github.com/aws/amazon-ec2-instance-selector/v2/pkg/instancetypes"
+	"github.com/aws/amazon-ec2-instance-selector/v2/pkg/selector"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/iam"
-	"github.com/briandowns/spinner"
 	"golang.org/x/exp/slices"
 )
 
+// The error returned when none of the instance types suggested by Instance Selector has a compatible AMI
+var ErrNoCompatibleInstanceType = errors.New("No suggested instance types have a compatible AMI")
+
 var DefaultCapacityTypeText = struct {
 	OnDemand, Spot string
 }{
-	OnDemand: "On-Demand",
-	Spot:     "Spot",
+	OnDemand: config.CapacityTypeOnDemand,
+	Spot:     config.CapacityTypeSpot,
 }
 
+// Whether the launch confirmation is a single summary line instead of the full table
+var IsCompactConfirmation bool
+
 type CheckInput func(*ec2helper.EC2Helper, string) bool
 
 type AskQuestionInput struct {
@@ -101,6 +109,7 @@ func AskRegion(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
 	err = qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
 		Rows:           questionModel.CreateSingleLineRows(data),
 		QuestionString: question,
+		HelpString:     "The region the instance is launched in. Resources such as VPCs, subnets and images are listed for this region.",
 		DefaultOption:  *defaultOption,
 		IndexedOptions: indexedOptions,
 		HeaderStrings:  headers,
@@ -114,6 +123,40 @@ func AskRegion(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
 	return &answer, nil
 }
 
+// Ask for the profile to switch to, when the credentials of the current profile can't be used
+func AskProfile(qh *questionModel.QuestionModelHelper, profiles []string, currentProfile string) (string, error) {
+	data := [][]string{}
+	indexedOptions := []string{}
+	defaultOption := ""
+	for _, profile := range profiles {
+		indexedOptions = append(indexedOptions, profile)
+		data = append(data, []string{profile})
+
+		// Offer another profile than the failing one by default
+		if defaultOption == "" && profile != currentProfile {
+			defaultOption = profile
+		}
+	}
+
+	question := fmt.Sprintf("The credentials of profile %s can't be used. Select a profile to switch to:",
+		currentProfile)
+
+	model := &questionModel.SingleSelectList{}
+	err := qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
+		Rows:           questionModel.CreateSingleLineRows(data),
+		QuestionString: question,
+		HelpString:     "Profiles are read from the shared AWS config and credentials files. Set AWS_PROFILE to skip this question.",
+		DefaultOption:  defaultOption,
+		IndexedOptions: indexedOptions,
+		HeaderStrings:  []string{"Profile"},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return model.GetChoice(), nil
+}
+
 func getRegionDescriptions() *map[string]string {
 	partition := endpoints.AwsPartition()
 	regions := partition.Regions()
@@ -329,9 +372,11 @@ func AskInstanceType(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelp
 	model := &questionModel.PlainText{}
 	err = qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
 		QuestionString: question,
+		HelpString:     "The instance type sets the vCPUs, memory, storage and network performance of the instance, and its price.",
 		DefaultOption:  *defaultOption,
 		EC2Helper:      h,
 		Fns:            []questionModel.CheckInput{instanceValidation},
+		Suggestions:    stringOptions,
 	})
 
 	if err != nil {
@@ -380,10 +425,21 @@ func AskInstanceTypeMemory(h *ec2helper.EC2Helper, qh *questionModel.QuestionMod
 	return model.GetTextAnswer(), nil
 }
 
+// Ask if the users allow burstable instance types, the t family, which may not sustain a high CPU load
+func AskInstanceTypeBurstable(qh *questionModel.QuestionModelHelper) (bool, error) {
+	question := "Allow burstable (t-family) instance types?"
+	answer, err := questionModel.AskYesNoQuestion(qh, question, true)
+	if err != nil {
+		return false, err
+	}
+
+	return answer == cli.ResponseYes, nil
+}
+
 // Ask the users to select an instance type given the options from Instance Selector
 func AskInstanceTypeInstanceSelector(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
 	instanceSelector ec2helper.InstanceSelector,
-	vcpus, memory string) (*string, error) {
+	vcpus, memory string, burstableOk bool) (*string, error) {
 	// Parse string to numbers
 	vcpusInt, err := strconv.Atoi(vcpus)
 	if err != nil {
@@ -394,8 +450,53 @@ func AskInstanceTypeInstanceSelector(h *ec2helper.EC2Helper, qh *questionModel.Q
 		return nil, err
 	}
 
+	filters, err := ec2helper.GetInstanceSelectorFilters(vcpusInt, memoryInt)
+	if err != nil {
+		return nil, err
+	}
+	if !burstableOk {
+		filters.Burstable = aws.Bool(false)
+	}
+
+	return AskInstanceTypeFromFilters(h, qh, instanceSelector, filters)
+}
+
+/*
+Ask the users for the vCPUs and memory and if burstable instance types are allowed, then to select an instance type
+given the options from Instance Selector.
+The vCPUs and memory are asked again if none of the options has a compatible AMI.
+*/
+func AskInstanceTypeFromRequirements(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
+	instanceSelector ec2helper.InstanceSelector) (*string, error) {
+	for {
+		vcpus, err := AskInstanceTypeVCpu(h, qh)
+		if err != nil {
+			return nil, err
+		}
+
+		memory, err := AskInstanceTypeMemory(h, qh)
+		if err != nil {
+			return nil, err
+		}
+
+		burstableOk, err := AskInstanceTypeBurstable(qh)
+		if err != nil {
+			return nil, err
+		}
+
+		instanceType, err := AskInstanceTypeInstanceSelector(h, qh, instanceSelector, vcpus, memory, burstableOk)
+		if err != ErrNoCompatibleInstanceType {
+			return instanceType, err
+		}
+		fmt.Println(err.Error() + ". Please change the requirements.")
+	}
+}
+
+// Ask the users to select an instance type given the options from Instance Selector for the filters
+func AskInstanceTypeFromFilters(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
+	instanceSelector ec2helper.InstanceSelector, filters *selector.Filters) (*string, error) {
 	// get instance types from instance selector
-	instanceTypes, err := h.GetInstanceTypesFromInstanceSelector(instanceSelector, vcpusInt, memoryInt)
+	instanceTypes, err := h.GetInstanceTypesFromInstanceSelector(instanceSelector, filters)
 	if err != nil {
 		return nil, err
 	}
@@ -416,26 +517,138 @@ func AskInstanceTypeInstanceSelector(h *ec2helper.EC2Helper, qh *questionModel.Q
 			indexedOptions = append(indexedOptions, *instanceType.InstanceType)
 		}
 	} else {
-		return nil, errors.New("No suggested instance types available. Please enter vCPUs and memory again. ")
+		return nil, errors.New("No suggested instance types available. Please change the requirements and try again. ")
 	}
 
 	question := "Select an instance type:"
 	headers := []string{"Instance Type", "vCPUs", "Memory", "Instance Storage"}
 
-	model := &questionModel.SingleSelectList{}
+	/*
+		Keep asking until the selected instance type has at least one compatible AMI.
+		Instance types without a compatible AMI are removed from the options before asking again.
+	*/
+	for {
+		model := &questionModel.SingleSelectList{}
+		err = qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
+			QuestionString: question,
+			IndexedOptions: indexedOptions,
+			Rows:           questionModel.CreateSingleLineRows(data),
+			HeaderStrings:  headers,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		answer := model.GetChoice()
+		answerIndex := slices.Index(indexedOptions, answer)
+		if answerIndex == -1 {
+			return &answer, nil
+		}
+
+		hasImage, err := hasCompatibleImage(h, &instanceTypes[answerIndex].InstanceTypeInfo)
+		if err != nil {
+			return nil, err
+		}
+		if hasImage {
+			return &answer, nil
+		}
+
+		fmt.Printf("No compatible AMI found for instance type %s. Please select another instance type.\n", answer)
+		instanceTypes = slices.Delete(instanceTypes, answerIndex, answerIndex+1)
+		indexedOptions = slices.Delete(indexedOptions, answerIndex, answerIndex+1)
+		data = slices.Delete(data, answerIndex, answerIndex+1)
+		if len(indexedOptions) <= 0 {
+			return nil, ErrNoCompatibleInstanceType
+		}
+	}
+}
+
+/*
+Tell if there is a default AMI compatible with the architectures and root device type of the instance type.
+If the architectures of the instance type are unknown, the instance type is assumed to be compatible.
+*/
+func hasCompatibleImage(h *ec2helper.EC2Helper, instanceType *ec2.InstanceTypeInfo) (bool, error) {
+	if instanceType.ProcessorInfo == nil || len(instanceType.ProcessorInfo.SupportedArchitectures) <= 0 {
+		return true, nil
+	}
+
+	// An instance type that doesn't support the forced root device type has no compatible image
+	rootDeviceType, err := h.GetRootDeviceType(instanceType)
+	if err != nil {
+		return false, nil
+	}
+
+	images, err := h.GetLatestImages(&rootDeviceType, instanceType.ProcessorInfo.SupportedArchitectures)
+	if err != nil {
+		return false, err
+	}
+
+	return images != nil && len(*images) > 0, nil
+}
+
+/*
+Ask the users to select several instance types, such as for benchmarking them against each other. The selected
+instance types are returned in the order of the options, and the ones without a compatible AMI are left out.
+*/
+func AskInstanceTypes(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
+	defaultInstanceTypes []string) ([]string, error) {
+	instanceTypes, err := h.GetInstanceTypesInRegion()
+	if err != nil {
+		return nil, err
+	}
+
+	data := [][]string{}
+	indexedOptions := []string{}
+	for _, instanceType := range instanceTypes {
+		memory := ""
+		if instanceType.MemoryInfo != nil {
+			memory = strconv.FormatFloat(float64(aws.Int64Value(instanceType.MemoryInfo.SizeInMiB))/1024, 'f', 2,
+				64) + " GiB"
+		}
+		vcpus := ""
+		if instanceType.VCpuInfo != nil {
+			vcpus = strconv.FormatInt(aws.Int64Value(instanceType.VCpuInfo.DefaultVCpus), 10)
+		}
+		data = append(data, []string{*instanceType.InstanceType, vcpus, memory})
+		indexedOptions = append(indexedOptions, *instanceType.InstanceType)
+	}
+
+	model := &questionModel.MultiSelectList{}
 	err = qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
-		QuestionString: question,
-		IndexedOptions: indexedOptions,
-		Rows:           questionModel.CreateSingleLineRows(data),
-		HeaderStrings:  headers,
+		QuestionString:    "Select the instance types to launch one instance of each:",
+		HelpString:        "One On-Demand instance is launched for each instance type, with the same configuration.",
+		DefaultOptionList: defaultInstanceTypes,
+		IndexedOptions:    indexedOptions,
+		HeaderStrings:     []string{"Instance Type", "vCPUs", "Memory"},
+		Rows:              questionModel.CreateSingleLineRows(data),
 	})
-
 	if err != nil {
 		return nil, err
 	}
 
-	answer := model.GetChoice()
-	return &answer, nil
+	selectedValues := model.GetSelectedValues()
+	selectedInstanceTypes := []string{}
+	for i, instanceType := range indexedOptions {
+		if !slices.Contains(selectedValues, instanceType) {
+			continue
+		}
+
+		hasImage, err := hasCompatibleImage(h, instanceTypes[i])
+		if err != nil {
+			return nil, err
+		}
+		if !hasImage {
+			fmt.Printf("No compatible AMI found for instance type %s, so it is left out\n", instanceType)
+			continue
+		}
+		selectedInstanceTypes = append(selectedInstanceTypes, instanceType)
+	}
+	if len(selectedInstanceTypes) <= 0 {
+		return nil, errors.New("None of the selected instance types has a compatible AMI")
+	}
+
+	return selectedInstanceTypes, nil
 }
 
 /*
@@ -450,21 +663,17 @@ func AskImage(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
 		return nil, err
 	}
 
-	// Use instance-store if supported
-	rootDeviceType := "ebs"
-	if *instanceTypeInfo.InstanceStorageSupported {
-		rootDeviceType = "instance-store"
+	rootDeviceType, err := h.GetRootDeviceType(instanceTypeInfo)
+	if err != nil {
+		return nil, err
 	}
 
-	s := spinner.New(spinner.CharSets[11], 100*time.Millisecond)
-	s.Suffix = " fetching images"
-	s.Color("blue", "bold")
-	s.Start()
+	stopSpinner := cli.StartSpinner(" fetching images")
 	defaultImages, err := h.GetLatestImages(&rootDeviceType, instanceTypeInfo.ProcessorInfo.SupportedArchitectures)
+	stopSpinner()
 	if err != nil {
 		return nil, err
 	}
-	s.Stop()
 
 	data := [][]string{}
 	indexedOptions := []string{}
@@ -494,18 +703,27 @@ func AskImage(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
 			image, found := (*defaultImages)[osName]
 			if found {
 				indexedOptions = append(indexedOptions, *image.ImageId)
-				data = append(data, []string{osName, *image.ImageId, *image.CreationDate})
+				data = append(data, []string{osName, *image.ImageId, *image.CreationDate,
+					ec2helper.GetImageDeprecationStatus(image, time.Now())})
 			}
 		}
 	}
 
-	headers := []string{"Operating System", "Image ID", "Creation Date"}
+	// The deprecation column is only shown when an image is deprecated or deprecating soon
+	headers := []string{"Operating System", "Image ID", "Creation Date", "Deprecation"}
+	if !hasColumnValue(data, len(headers)-1) {
+		headers = headers[:len(headers)-1]
+		for i := range data {
+			data[i] = data[i][:len(headers)]
+		}
+	}
 	question := "Select an AMI for the instance:"
 
 	model := &questionModel.SingleSelectList{}
 	err = qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
 		HeaderStrings:  headers,
 		QuestionString: question,
+		HelpString:     "The Amazon Machine Image (AMI) the instance is launched from, which provides its operating system and software.",
 		DefaultOption:  defaultOption,
 		Rows:           questionModel.CreateSingleLineRows(data),
 		IndexedOptions: indexedOptions,
@@ -600,6 +818,7 @@ func AskIamProfile(qh *questionModel.QuestionModelHelper, i *iamhelper.IAMHelper
 	model := &questionModel.SingleSelectList{}
 	err = qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
 		QuestionString: question,
+		HelpString:     "An IAM instance profile gives the instance a role, whose permissions its applications use to call AWS services.",
 		DefaultOption:  defaultOptionValue,
 		IndexedOptions: indexedOptions,
 		HeaderStrings:  headers,
@@ -613,6 +832,67 @@ func AskIamProfile(qh *questionModel.QuestionModelHelper, i *iamhelper.IAMHelper
 	return model.GetChoice(), nil
 }
 
+// Ask the users to select a key pair for SSH access, create a new one, or launch without a key pair
+func AskKeyPair(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper, defaultKeyPair string) (string, error) {
+	keyPairs, err := h.GetKeyPairs()
+	if err != nil {
+		return "", err
+	}
+
+	defaultOptionValue := cli.ResponseNo
+	data := [][]string{}
+	indexedOptions := []string{}
+	for _, keyPair := range keyPairs {
+		keyName := aws.StringValue(keyPair.KeyName)
+		indexedOptions = append(indexedOptions, keyName)
+		data = append(data, []string{keyName, aws.StringValue(keyPair.KeyType)})
+		if defaultKeyPair == keyName {
+			defaultOptionValue = keyName
+		}
+	}
+
+	// Add the options to create a key pair and to launch without one at the end
+	indexedOptions = append(indexedOptions, cli.ResponseNew, cli.ResponseNo)
+	data = append(data, []string{"Create new key pair"}, []string{"No key pair"})
+
+	model := &questionModel.SingleSelectList{}
+	err = qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
+		QuestionString: "Select a key pair:",
+		HelpString: "The public key of the key pair is placed on the instance, so you can SSH in with the private " +
+			"key. A new key pair is saved to a .pem file in the working directory.",
+		DefaultOption:  defaultOptionValue,
+		IndexedOptions: indexedOptions,
+		HeaderStrings:  []string{"Key Pair Name", "Key Type"},
+		Rows:           questionModel.CreateSingleLineRows(data),
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return model.GetChoice(), nil
+}
+
+// Ask the users to enter the name of a new key pair
+func AskKeyPairName(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper) (string, error) {
+	model := &questionModel.PlainText{}
+	err := qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
+		QuestionString: "What should the new key pair be named?",
+		HelpString:     "The private key is saved to a .pem file with the same name in the working directory.",
+		DefaultOption:  "simple-ec2-" + time.Now().Format("20060102-150405"),
+		EC2Helper:      h,
+		Fns: []questionModel.CheckInput{func(h *ec2helper.EC2Helper, keyName string) bool {
+			return ec2helper.ValidateKeyPairName(keyName) == nil
+		}},
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return model.GetTextAnswer(), nil
+}
+
 // Ask if the users want to set an auto-termination timer for the instance
 func AskAutoTerminationTimerMinutes(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
 	defaultTimer int) (string, error) {
@@ -625,6 +905,7 @@ func AskAutoTerminationTimerMinutes(h *ec2helper.EC2Helper, qh *questionModel.Qu
 	model := &questionModel.PlainText{}
 	err := qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
 		QuestionString: question,
+		HelpString:     "The instance is terminated automatically after this many minutes, so it is not left running by mistake.",
 		DefaultOption:  defaultOption,
 		EC2Helper:      h,
 		Fns:            []questionModel.CheckInput{ec2helper.ValidateInteger},
@@ -637,6 +918,138 @@ func AskAutoTerminationTimerMinutes(h *ec2helper.EC2Helper, qh *questionModel.Qu
 	return model.GetTextAnswer(), nil
 }
 
+// Ask the users for the number of instances to launch
+func AskInstanceCount(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper, defaultCount int) (string, error) {
+	defaultOption := "1"
+	if defaultCount > 1 {
+		defaultOption = strconv.Itoa(defaultCount)
+	}
+
+	model := &questionModel.PlainText{}
+	err := qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
+		QuestionString: "How many instances should be launched?",
+		HelpString:     "The instances are launched at once with the same configuration.",
+		DefaultOption:  defaultOption,
+		EC2Helper:      h,
+		Fns:            []questionModel.CheckInput{ec2helper.ValidatePositiveInteger},
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return model.GetTextAnswer(), nil
+}
+
+/*
+Ask the users to enter the size of the root EBS volume in GiB, which can't be smaller than the snapshot of the image.
+The snapshot size is the default, keeping the size of the image.
+*/
+func AskEbsVolumeSize(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper, snapshotSize,
+	defaultSize int64) (string, error) {
+	defaultOption := strconv.FormatInt(snapshotSize, 10)
+	if defaultSize > snapshotSize {
+		defaultOption = strconv.FormatInt(defaultSize, 10)
+	}
+
+	model := &questionModel.PlainText{}
+	err := qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
+		QuestionString: "What size should the root EBS volume be in GiB?",
+		HelpString:     fmt.Sprintf("The size can't be smaller than the %d GiB snapshot of the image.", snapshotSize),
+		DefaultOption:  defaultOption,
+		EC2Helper:      h,
+		Fns: []questionModel.CheckInput{func(h *ec2helper.EC2Helper, sizeString string) bool {
+			size, err := strconv.ParseInt(sizeString, 10, 64)
+			return err == nil && size >= snapshotSize
+		}},
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return model.GetTextAnswer(), nil
+}
+
+// Ask the users to select the type of the root EBS volume
+func AskEbsVolumeType(qh *questionModel.QuestionModelHelper, defaultVolumeType string) (string, error) {
+	defaultOption := ec2.VolumeTypeGp3
+	if slices.Contains(ec2helper.RootVolumeTypes, defaultVolumeType) {
+		defaultOption = defaultVolumeType
+	}
+
+	data := [][]string{
+		{ec2.VolumeTypeGp2, "General Purpose SSD, with IOPS scaling with the size"},
+		{ec2.VolumeTypeGp3, "General Purpose SSD, with optional IOPS and throughput"},
+		{ec2.VolumeTypeIo1, "Provisioned IOPS SSD"},
+		{ec2.VolumeTypeIo2, "Provisioned IOPS SSD, with higher durability"},
+	}
+
+	model := &questionModel.SingleSelectList{}
+	err := qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
+		QuestionString: "Select the type of the root EBS volume",
+		HelpString:     "Provisioned IOPS volumes need their IOPS, which are asked next.",
+		DefaultOption:  defaultOption,
+		IndexedOptions: ec2helper.RootVolumeTypes,
+		Rows:           questionModel.CreateSingleLineRows(data),
+		HeaderStrings:  []string{"Volume Type", "Description"},
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return model.GetChoice(), nil
+}
+
+// Ask the users to enter the provisioned IOPS of the root EBS volume
+func AskEbsVolumeIops(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper, volumeType string,
+	defaultIops int64) (string, error) {
+	defaultOption := "3000"
+	if defaultIops > 0 {
+		defaultOption = strconv.FormatInt(defaultIops, 10)
+	}
+
+	model := &questionModel.PlainText{}
+	err := qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
+		QuestionString: fmt.Sprintf("How many IOPS should the %s root volume provision?", volumeType),
+		HelpString:     "The maximum IOPS depend on the size of the volume.",
+		DefaultOption:  defaultOption,
+		EC2Helper:      h,
+		Fns:            []questionModel.CheckInput{ec2helper.ValidatePositiveInteger},
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return model.GetTextAnswer(), nil
+}
+
+// Ask the users to enter the throughput of a gp3 root EBS volume in MiB/s
+func AskEbsVolumeThroughput(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
+	defaultThroughput int64) (string, error) {
+	defaultOption := "125"
+	if defaultThroughput > 0 {
+		defaultOption = strconv.FormatInt(defaultThroughput, 10)
+	}
+
+	model := &questionModel.PlainText{}
+	err := qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
+		QuestionString: "What throughput should the gp3 root volume have in MiB/s?",
+		HelpString:     "gp3 volumes include 125 MiB/s. More throughput is charged separately.",
+		DefaultOption:  defaultOption,
+		EC2Helper:      h,
+		Fns:            []questionModel.CheckInput{ec2helper.ValidatePositiveInteger},
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return model.GetTextAnswer(), nil
+}
+
 // Ask the users to select a VPC
 func AskVpc(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper, defaultVpcId string) (*string, error) {
 	vpcs, err := h.GetAllVpcs()
@@ -663,7 +1076,12 @@ func AskVpc(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper, defau
 				defaultOptionValue = *vpc.VpcId
 			}
 
-			data = append(data, []string{vpcName, *vpc.CidrBlock})
+			isDefault := cli.ResponseNo
+			if aws.BoolValue(vpc.IsDefault) {
+				isDefault = cli.ResponseYes
+			}
+
+			data = append(data, []string{vpcName, *vpc.CidrBlock, isDefault})
 		}
 	}
 
@@ -671,23 +1089,39 @@ func AskVpc(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper, defau
 	data = append(data, []string{fmt.Sprintf("Create new VPC with default CIDR and %d subnets", cfn.RequiredAvailabilityZones)})
 
 	question := "Select the VPC for the instance:"
-	headers := []string{"VPC", "CIDR Block"}
+	headers := []string{"VPC", "CIDR Block", "Default"}
 
-	model := &questionModel.SingleSelectList{}
-	err = qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
-		QuestionString: question,
-		DefaultOption:  defaultOptionValue,
-		IndexedOptions: indexedOptions,
-		Rows:           questionModel.CreateSingleLineRows(data),
-		HeaderStrings:  headers,
-	})
+	// A new VPC is created with a slow CloudFormation stack, so the users confirm it or select the VPC again
+	for {
+		model := &questionModel.SingleSelectList{}
+		err = qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
+			QuestionString: question,
+			HelpString:     "The VPC is the virtual network the instance is launched in. A new VPC is created with a CloudFormation stack.",
+			DefaultOption:  defaultOptionValue,
+			IndexedOptions: indexedOptions,
+			Rows:           questionModel.CreateSingleLineRows(data),
+			HeaderStrings:  headers,
+		})
 
-	if err != nil {
-		return nil, err
-	}
+		if err != nil {
+			return nil, err
+		}
 
-	answer := model.GetChoice()
-	return &answer, nil
+		answer := model.GetChoice()
+		if answer != cli.ResponseNew {
+			return &answer, nil
+		}
+
+		confirmation, err := questionModel.AskYesNoQuestion(qh, fmt.Sprintf("A CloudFormation stack with a new VPC, "+
+			"%d subnets, an internet gateway and a route table will be created at launch, which takes a few minutes. "+
+			"Create a new VPC?", cfn.RequiredAvailabilityZones), false)
+		if err != nil {
+			return nil, err
+		}
+		if confirmation == cli.ResponseYes {
+			return &answer, nil
+		}
+	}
 }
 
 // Ask the users to select a subnet
@@ -698,12 +1132,25 @@ func AskSubnet(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
 		return nil, err
 	}
 
+	// Only the subnets in available zones, of the zone type if specified, are offered
+	zoneTypes, err := h.GetZoneTypesByName()
+	if err != nil {
+		return nil, err
+	}
+
 	data := [][]string{}
 	indexedOptions := []string{}
+	zoneWarnings := map[string]string{}
 	var defaultOptionValue *string = nil
 
 	// Add security groups to the data for table
 	for _, subnet := range subnets {
+		zoneType, found := zoneTypes[aws.StringValue(subnet.AvailabilityZone)]
+		if !found {
+			continue
+		}
+		zoneWarnings[*subnet.SubnetId] = ec2helper.GetZoneTypeWarning(*subnet.AvailabilityZone, zoneType)
+
 		if defaultSubnetId != "" && *subnet.SubnetId == defaultSubnetId {
 			defaultOptionValue = subnet.SubnetId
 		}
@@ -715,19 +1162,23 @@ func AskSubnet(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
 			subnetName = fmt.Sprintf("%s(%s)", *subnetTagName, *subnet.SubnetId)
 		}
 
-		data = append(data, []string{subnetName, *subnet.AvailabilityZone, *subnet.CidrBlock})
+		data = append(data, []string{subnetName, *subnet.AvailabilityZone, zoneType, *subnet.CidrBlock})
 	}
 
+	if len(indexedOptions) <= 0 {
+		return nil, errors.New("No subnet in an available zone of the VPC")
+	}
 	if defaultOptionValue == nil {
-		defaultOptionValue = subnets[0].SubnetId
+		defaultOptionValue = &indexedOptions[0]
 	}
 
 	question := "Select the subnet for the instance:"
-	headers := []string{"Subnet", "Availability Zone", "CIDR Block"}
+	headers := []string{"Subnet", "Availability Zone", "Zone Type", "CIDR Block"}
 
 	model := &questionModel.SingleSelectList{}
 	err = qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
 		QuestionString: question,
+		HelpString:     "The subnet sets the availability zone of the instance, and the range of its private IP address.",
 		DefaultOption:  *defaultOptionValue,
 		IndexedOptions: indexedOptions,
 		Rows:           questionModel.CreateSingleLineRows(data),
@@ -739,6 +1190,10 @@ func AskSubnet(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
 	}
 
 	answer := model.GetChoice()
+	if zoneWarnings[answer] != "" {
+		fmt.Println(zoneWarnings[answer])
+	}
+
 	return &answer, nil
 }
 
@@ -750,6 +1205,12 @@ func AskSubnetPlaceholder(h *ec2helper.EC2Helper, qh *questionModel.QuestionMode
 		return nil, err
 	}
 
+	// The subnets of the new VPC are only created in availability zones
+	availabilityZones = ec2helper.FilterZonesByType(availabilityZones, ec2helper.ZoneTypeAvailabilityZone)
+	if len(availabilityZones) <= 0 {
+		return nil, errors.New("A new VPC can only be created with availability zones, not Local or Wavelength Zones")
+	}
+
 	data := [][]string{}
 	indexedOptions := []string{}
 
@@ -824,6 +1285,7 @@ func AskSecurityGroups(qh *questionModel.QuestionModelHelper,
 	model := &questionModel.MultiSelectList{}
 	err := qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
 		QuestionString:    question,
+		HelpString:        "Security groups act as firewalls of the instance, controlling the traffic it sends and receives.",
 		DefaultOptionList: defaultOptionList,
 		IndexedOptions:    indexedOptions,
 		HeaderStrings:     headers,
@@ -898,6 +1360,8 @@ func AskConfirmationWithTemplate(h *ec2helper.EC2Helper, qh *questionModel.Quest
 		instanceType = *templateData.InstanceType
 	}
 	data = append(data, []string{"Instance Type", instanceType})
+	data = append(data, []string{cli.ResourceInstanceCount,
+		strconv.FormatInt(ec2helper.GetInstanceCount(simpleConfig), 10)})
 
 	// Append image id
 	imageId := "not specified"
@@ -922,38 +1386,17 @@ func AskConfirmationWithTemplate(h *ec2helper.EC2Helper, qh *questionModel.Quest
 	return &answer, nil
 }
 
-// Print confirmation information for instance launch and ask for confirmation
+/*
+Print confirmation information for instance launch and ask for confirmation. In compact confirmation,
+only a summary line is printed and the configuration can't be edited.
+*/
 func AskConfirmationWithInput(qh *questionModel.QuestionModelHelper, simpleConfig *config.SimpleInfo,
 	detailedConfig *config.DetailedInfo, allowEdit bool) (string, error) {
-	// If new subnets will be created, skip formatting the subnet info.
-	subnetInfo := "New Subnet"
-	subnet := detailedConfig.Subnet
-	if simpleConfig.NewVPC {
-		/*
-			If the subnet id is not empty and is not a real subnet id,
-			it will be a placeholder subnet with an availability zone.
-		*/
-		if simpleConfig.SubnetId != "" && simpleConfig.SubnetId[0:6] != "subnet" {
-			subnetInfo += " in " + simpleConfig.SubnetId
-		}
-	} else {
-		subnetInfo = *subnet.SubnetId
-		subnetTagName := ec2helper.GetTagName(subnet.Tags)
-		if subnetTagName != nil {
-			subnetInfo = fmt.Sprintf("%s(%s)", *subnetTagName, *subnet.SubnetId)
-		}
+	if IsCompactConfirmation {
+		return questionModel.AskYesNoQuestion(qh, FormatCompactSummary(simpleConfig, detailedConfig), false)
 	}
 
-	// If a new VPC will be created, skip formatting
-	vpcInfo := "New VPC"
-	vpc := detailedConfig.Vpc
-	if !simpleConfig.NewVPC {
-		vpcInfo = *vpc.VpcId
-		vpcTagName := ec2helper.GetTagName(vpc.Tags)
-		if vpcTagName != nil {
-			vpcInfo = fmt.Sprintf("%s(%s)", *vpcTagName, *vpc.VpcId)
-		}
-	}
+	vpcInfo, subnetInfo := getNetworkInfo(simpleConfig, detailedConfig)
 
 	// Get display data ready
 	data := [][]string{
@@ -961,6 +1404,7 @@ func AskConfirmationWithInput(qh *questionModel.QuestionModelHelper, simpleConfi
 		{cli.ResourceVpc, vpcInfo},
 		{cli.ResourceSubnet, subnetInfo},
 		{cli.ResourceInstanceType, simpleConfig.InstanceType},
+		{cli.ResourceInstanceCount, strconv.FormatInt(ec2helper.GetInstanceCount(simpleConfig), 10)},
 		{cli.ResourceCapacityType, simpleConfig.CapacityType},
 		{cli.ResourceImage, simpleConfig.ImageId},
 	}
@@ -971,10 +1415,17 @@ func AskConfirmationWithInput(qh *questionModel.QuestionModelHelper, simpleConfi
 		cli.ResourceVpc,
 		cli.ResourceSubnet,
 		cli.ResourceInstanceType,
+		cli.ResourceInstanceCount,
 		cli.ResourceCapacityType,
 		cli.ResourceImage,
 	}
 
+	// Append the configurations that are only shown, which the confirmation form shows too
+	for _, row := range getConfirmationInfoRows(simpleConfig, detailedConfig) {
+		rows = append(rows, [][]string{row})
+		indexedOptions = append(indexedOptions, "")
+	}
+
 	/*
 		Append all security groups.
 		If security groups were successfully parsed into the detailed config, append them here.
@@ -1010,6 +1461,11 @@ func AskConfirmationWithInput(qh *questionModel.QuestionModelHelper, simpleConfi
 			rows = append(rows, [][]string{{cli.ResourceAutoTerminationTimer, "None"}})
 		}
 		indexedOptions = append(indexedOptions, cli.ResourceAutoTerminationTimer)
+
+		if simpleConfig.AutoTerminationTimerMinutes > 0 {
+			rows = append(rows, [][]string{{cli.ResourceAutoTerminationAction, formatAutoTerminationAction(simpleConfig)}})
+			indexedOptions = append(indexedOptions, "")
+		}
 	}
 
 	// Append all EBS blocks, if applicable
@@ -1033,10 +1489,15 @@ func AskConfirmationWithInput(qh *questionModel.QuestionModelHelper, simpleConfi
 		indexedOptions = append(indexedOptions, cli.ResourceIamInstanceProfile)
 	}
 
-	if simpleConfig.BootScriptFilePath != "" {
-		rows = append(rows, [][]string{{cli.ResourceBootScriptFilePath, simpleConfig.BootScriptFilePath}})
+	bootScriptFilePath := ec2helper.GetBootScriptFilePath(simpleConfig, detailedConfig.Image)
+	if bootScriptFilePath != "" {
+		rows = append(rows, [][]string{{cli.ResourceBootScriptFilePath, bootScriptFilePath}})
 		indexedOptions = append(indexedOptions, cli.ResourceBootScriptFilePath)
 	}
+	if allowEdit {
+		rows = append(rows, [][]string{{cli.ResourceUserData, formatUserData(simpleConfig, detailedConfig)}})
+		indexedOptions = append(indexedOptions, cli.ResourceUserData)
+	}
 	if len(simpleConfig.UserTags) != 0 {
 		var tags [][]string
 		index := 0
@@ -1067,6 +1528,341 @@ func AskConfirmationWithInput(qh *questionModel.QuestionModelHelper, simpleConfi
 	return model.GetChoice(), nil
 }
 
+/*
+Get the rows of the configurations that the confirmation table and the confirmation form only show, as pairs of
+the resource name and the value. The rows of unset configurations are left out.
+*/
+func getConfirmationInfoRows(simpleConfig *config.SimpleInfo, detailedConfig *config.DetailedInfo) [][]string {
+	rows := [][]string{{cli.ResourceBootMode, formatBootMode(simpleConfig, detailedConfig)}}
+	if deprecation := formatImageDeprecation(detailedConfig.Image); deprecation != "" {
+		rows = append(rows, []string{cli.ResourceImageDeprecation, deprecation})
+	}
+	if simpleConfig.SpotBlockDurationMinutes > 0 {
+		rows = append(rows, []string{cli.ResourceSpotBlockDuration,
+			formatSpotBlockDuration(simpleConfig.SpotBlockDurationMinutes)})
+	}
+	if capacityReservation := formatCapacityReservation(simpleConfig); capacityReservation != "" {
+		rows = append(rows, []string{cli.ResourceCapacityReservation, capacityReservation})
+	}
+	if privateDnsName := formatPrivateDnsName(simpleConfig); privateDnsName != "" {
+		rows = append(rows, []string{cli.ResourcePrivateDnsName, privateDnsName})
+	}
+	if simpleConfig.CpuCredits != "" {
+		rows = append(rows, []string{cli.ResourceCpuCredits, simpleConfig.CpuCredits})
+	}
+	if simpleConfig.AssociateCarrierIp {
+		rows = append(rows, []string{cli.ResourceCarrierIp, cli.ResponseYes})
+	}
+	if simpleConfig.NoPublicIp {
+		rows = append(rows, []string{cli.ResourcePublicIp, cli.ResponseNo})
+	}
+	if simpleConfig.PrivateIpAddress != "" {
+		rows = append(rows, []string{cli.ResourcePrivateIpAddress, simpleConfig.PrivateIpAddress})
+	}
+	if simpleConfig.SecondarySubnetId != "" {
+		secondarySubnetInfo := simpleConfig.SecondarySubnetId
+		if len(simpleConfig.SecondarySecurityGroupIds) > 0 {
+			secondarySubnetInfo += fmt.Sprintf(" (%s)", strings.Join(simpleConfig.SecondarySecurityGroupIds, ", "))
+		}
+		rows = append(rows, []string{cli.ResourceSecondarySubnet, secondarySubnetInfo})
+	}
+	if simpleConfig.HostResourceGroupArn != "" {
+		rows = append(rows, []string{cli.ResourceHostResourceGroup, simpleConfig.HostResourceGroupArn})
+	}
+	if simpleConfig.Affinity != "" {
+		rows = append(rows, []string{cli.ResourceAffinity, simpleConfig.Affinity})
+	}
+	if simpleConfig.KeyPairName != "" {
+		rows = append(rows, []string{cli.ResourceKeyPair, simpleConfig.KeyPairName})
+	}
+	if simpleConfig.Imds != "" {
+		rows = append(rows, []string{cli.ResourceImds, simpleConfig.Imds})
+	}
+	if simpleConfig.RootVolumeSize > 0 {
+		rows = append(rows, []string{cli.ResourceRootVolumeSize, strconv.FormatInt(simpleConfig.RootVolumeSize, 10)})
+	}
+	if simpleConfig.RootVolumeType != "" {
+		rows = append(rows, []string{cli.ResourceRootVolumeType, formatRootVolumeType(simpleConfig)})
+	}
+
+	// The network and EBS performance of the instance type
+	if networkPerformance := formatNetworkPerformance(detailedConfig.InstanceTypeInfo); networkPerformance != "" {
+		rows = append(rows, []string{cli.ResourceNetworkPerformance, networkPerformance})
+	}
+	if ebsBandwidth := formatEbsBandwidth(detailedConfig.InstanceTypeInfo); ebsBandwidth != "" {
+		rows = append(rows, []string{cli.ResourceEbsBandwidth, ebsBandwidth})
+	}
+
+	return rows
+}
+
+// Format what happens to the instances when the auto-termination timer expires
+func formatAutoTerminationAction(simpleConfig *config.SimpleInfo) string {
+	return ec2helper.GetShutdownBehavior(simpleConfig.AutoTerminationTimerAction)
+}
+
+// Format the launch configuration as a single summary line, such as "t3.micro / ami-12345 / subnet-12345 / On-Demand"
+func FormatCompactSummary(simpleConfig *config.SimpleInfo, detailedConfig *config.DetailedInfo) string {
+	subnetInfo := simpleConfig.SubnetId
+	if simpleConfig.NewVPC {
+		_, subnetInfo = getNetworkInfo(simpleConfig, detailedConfig)
+	}
+	capacityType := simpleConfig.CapacityType
+	if capacityType == "" {
+		capacityType = DefaultCapacityTypeText.OnDemand
+	}
+
+	imageInfo := simpleConfig.ImageId
+	if deprecation := formatImageDeprecation(detailedConfig.Image); deprecation != "" {
+		imageInfo += fmt.Sprintf(" (%s)", deprecation)
+	}
+
+	instanceTypeInfo := simpleConfig.InstanceType
+	if instanceCount := ec2helper.GetInstanceCount(simpleConfig); instanceCount > 1 {
+		instanceTypeInfo = fmt.Sprintf("%d x %s", instanceCount, instanceTypeInfo)
+	}
+
+	return fmt.Sprintf("%s / %s / %s / %s — launch?", instanceTypeInfo, imageInfo, subnetInfo, capacityType)
+}
+
+// Format the deprecation of the image as a warning, or an empty string if the image isn't deprecated soon
+func formatImageDeprecation(image *ec2.Image) string {
+	status := ec2helper.GetImageDeprecationStatus(image, time.Now())
+	if status == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("Warning: the image is %s", status)
+}
+
+// Whether any row has a non-empty value in the column
+func hasColumnValue(data [][]string, column int) bool {
+	for _, row := range data {
+		if column < len(row) && row[column] != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Get the display information of the VPC and subnet, which may be placeholders for new infrastructure
+func getNetworkInfo(simpleConfig *config.SimpleInfo, detailedConfig *config.DetailedInfo) (vpcInfo, subnetInfo string) {
+	// If new subnets will be created, skip formatting the subnet info.
+	subnetInfo = "New Subnet"
+	subnet := detailedConfig.Subnet
+	if simpleConfig.NewVPC {
+		/*
+			If the subnet id is not empty and is not a real subnet id,
+			it will be a placeholder subnet with an availability zone.
+		*/
+		if simpleConfig.SubnetId != "" && simpleConfig.SubnetId[0:6] != "subnet" {
+			subnetInfo += " in " + simpleConfig.SubnetId
+		}
+	} else {
+		subnetInfo = *subnet.SubnetId
+		subnetTagName := ec2helper.GetTagName(subnet.Tags)
+		if subnetTagName != nil {
+			subnetInfo = fmt.Sprintf("%s(%s)", *subnetTagName, *subnet.SubnetId)
+		}
+	}
+
+	// If a new VPC will be created, skip formatting
+	vpcInfo = "New VPC"
+	vpc := detailedConfig.Vpc
+	if !simpleConfig.NewVPC {
+		vpcInfo = *vpc.VpcId
+		vpcTagName := ec2helper.GetTagName(vpc.Tags)
+		if vpcTagName != nil {
+			vpcInfo = fmt.Sprintf("%s(%s)", *vpcTagName, *vpc.VpcId)
+		}
+	}
+
+	return vpcInfo, subnetInfo
+}
+
+/*
+AskConfirmationForm asks the user to review all configurations in a single form. Simple values are edited
+in place and applied to the config when the form is submitted. The answer is yes or no when the form is
+submitted or cancelled, or the resource type of a configuration that needs its own question.
+*/
+func AskConfirmationForm(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
+	simpleConfig *config.SimpleInfo, detailedConfig *config.DetailedInfo) (string, error) {
+	vpcInfo, subnetInfo := getNetworkInfo(simpleConfig, detailedConfig)
+
+	fields := []questionModel.FormField{
+		{Name: cli.ResourceRegion, Value: simpleConfig.Region},
+		{Name: cli.ResourceVpc, Value: vpcInfo, Option: cli.ResourceVpc},
+		{Name: cli.ResourceSubnet, Value: subnetInfo, Option: cli.ResourceSubnet},
+		{Name: cli.ResourceInstanceType, Value: simpleConfig.InstanceType, Option: cli.ResourceInstanceType},
+		{
+			Name:    cli.ResourceInstanceCount,
+			Value:   strconv.FormatInt(ec2helper.GetInstanceCount(simpleConfig), 10),
+			InPlace: true,
+			Fns:     []questionModel.CheckInput{ec2helper.ValidatePositiveInteger},
+		},
+		{Name: cli.ResourceCapacityType, Value: simpleConfig.CapacityType, Option: cli.ResourceCapacityType},
+		{
+			Name:    cli.ResourceImage,
+			Value:   simpleConfig.ImageId,
+			InPlace: true,
+			Fns:     []questionModel.CheckInput{ec2helper.ValidateImageId},
+		},
+	}
+
+	// Add the configurations that are only shown, which the confirmation table shows too
+	for _, row := range getConfirmationInfoRows(simpleConfig, detailedConfig) {
+		fields = append(fields, questionModel.FormField{Name: row[0], Value: row[1]})
+	}
+
+	if detailedConfig.SecurityGroups != nil {
+		groupIds := []string{}
+		for _, group := range detailedConfig.SecurityGroups {
+			groupIds = append(groupIds, *group.GroupId)
+		}
+		fields = append(fields, questionModel.FormField{Name: cli.ResourceSecurityGroup,
+			Value: strings.Join(groupIds, ", "), Option: cli.ResourceSecurityGroup})
+	} else if len(simpleConfig.SecurityGroupIds) > 0 {
+		fields = append(fields, questionModel.FormField{Name: cli.ResourceSecurityGroupPlaceholder,
+			Value: simpleConfig.SecurityGroupIds[0]})
+	}
+
+	if ec2helper.HasEbsVolume(detailedConfig.Image) {
+		fields = append(fields, questionModel.FormField{Name: cli.ResourceKeepEbsVolume,
+			Value: strconv.FormatBool(simpleConfig.KeepEbsVolumeAfterTermination), Option: cli.ResourceKeepEbsVolume})
+	}
+
+	if detailedConfig.Image.PlatformDetails != nil && ec2helper.IsLinux(*detailedConfig.Image.PlatformDetails) {
+		fields = append(fields, questionModel.FormField{
+			Name:    cli.ResourceAutoTerminationTimer,
+			Value:   strconv.Itoa(simpleConfig.AutoTerminationTimerMinutes),
+			InPlace: true,
+			Fns:     []questionModel.CheckInput{ec2helper.ValidateInteger},
+		})
+		if simpleConfig.AutoTerminationTimerMinutes > 0 {
+			fields = append(fields, questionModel.FormField{
+				Name:  cli.ResourceAutoTerminationAction,
+				Value: formatAutoTerminationAction(simpleConfig),
+			})
+		}
+	}
+
+	// An empty boot script filepath removes the boot script
+	noEntryValidation := func(h *ec2helper.EC2Helper, filepath string) bool {
+		return filepath == ""
+	}
+	fields = append(fields,
+		questionModel.FormField{
+			Name:   cli.ResourceIamInstanceProfile,
+			Value:  simpleConfig.IamInstanceProfile,
+			Option: cli.ResourceIamInstanceProfile,
+		},
+		questionModel.FormField{
+			Name:    cli.ResourceBootScriptFilePath,
+			Value:   ec2helper.GetBootScriptFilePath(simpleConfig, detailedConfig.Image),
+			InPlace: true,
+			Fns:     []questionModel.CheckInput{ec2helper.ValidateFilepath, noEntryValidation},
+		},
+		questionModel.FormField{
+			Name:   cli.ResourceUserData,
+			Value:  formatUserData(simpleConfig, detailedConfig),
+			Option: cli.ResourceUserData,
+		})
+
+	tags := []string{}
+	for k, v := range simpleConfig.UserTags {
+		tags = append(tags, fmt.Sprintf("%s|%s", k, v))
+	}
+	sort.Strings(tags)
+	fields = append(fields, questionModel.FormField{Name: cli.ResourceUserTags, Value: strings.Join(tags, ", "),
+		Option: cli.ResourceUserTags})
+
+	initialValues := map[string]string{}
+	for _, field := range fields {
+		initialValues[field.Name] = field.Value
+	}
+
+	model := &questionModel.Form{}
+	err := qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
+		EC2Helper:  h,
+		FormFields: fields,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Apply the values edited in place. All of them are used to assemble the user data
+	isUserDataInputChanged := false
+	for _, field := range model.GetFields() {
+		switch field.Name {
+		case cli.ResourceInstanceCount:
+			// The user data doesn't depend on the count
+			simpleConfig.InstanceCount, _ = strconv.Atoi(field.Value)
+			continue
+		case cli.ResourceImage:
+			simpleConfig.ImageId = field.Value
+		case cli.ResourceAutoTerminationTimer:
+			simpleConfig.AutoTerminationTimerMinutes, _ = strconv.Atoi(field.Value)
+		case cli.ResourceBootScriptFilePath:
+			// The shown boot script may be the one for the platform, which only applies to images of the platform
+			if field.Value != initialValues[field.Name] {
+				ec2helper.SetBootScriptFilePath(simpleConfig, detailedConfig.Image, field.Value)
+			}
+		default:
+			continue
+		}
+		if field.Value != initialValues[field.Name] {
+			isUserDataInputChanged = true
+		}
+	}
+
+	// The edited user data would silently override the changes, so it's assembled again instead
+	if isUserDataInputChanged && detailedConfig.UserData != nil {
+		detailedConfig.UserData = nil
+		fmt.Println("The edited user data was discarded, since the values it is assembled from changed")
+	}
+
+	return model.GetChoice(), nil
+}
+
+// Summarize the user data for the confirmation, including whether it has been edited
+func formatUserData(simpleConfig *config.SimpleInfo, detailedConfig *config.DetailedInfo) string {
+	userData := ec2helper.GetUserData(simpleConfig, detailedConfig)
+	edited := ""
+	if detailedConfig.UserData != nil {
+		userData = *detailedConfig.UserData
+		edited = ", edited"
+	}
+	if userData == "" {
+		return "None" + edited
+	}
+
+	lineCount := strings.Count(strings.TrimSuffix(userData, "\n"), "\n") + 1
+	return fmt.Sprintf("%d line(s)%s", lineCount, edited)
+}
+
+/*
+Show the user data in a text area, starting with the specified user data. Return the user data and
+whether it has been saved by the user. The user data is unchanged if the edit is discarded.
+*/
+func AskUserData(qh *questionModel.QuestionModelHelper, userData string) (string, bool, error) {
+	question := "Review and edit the user data passed to the instance, before base64 encoding: "
+
+	model := &questionModel.TextArea{}
+	err := qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
+		DefaultOption:  userData,
+		QuestionString: question,
+	})
+	if err != nil {
+		return "", false, err
+	}
+	if !model.IsSubmitted() {
+		return userData, false, nil
+	}
+
+	return model.GetTextAnswer(), true, nil
+}
+
 // Ask if the user wants to save the config as a JSON config file
 func AskSaveConfig(qh *questionModel.QuestionModelHelper) (string, error) {
 	question := "Do you want to save the configuration above as a JSON file that can be used in non-interactive mode and as question defaults? "
@@ -1079,6 +1875,23 @@ func AskSaveConfig(qh *questionModel.QuestionModelHelper) (string, error) {
 	return answer, nil
 }
 
+/*
+Decide whether to save the config. The user is only asked when saving is not already requested and asking is allowed,
+so that wrappers can suppress the question
+*/
+func IsSaveConfigRequired(qh *questionModel.QuestionModelHelper, isSaveRequested, isAskAllowed bool) (bool, error) {
+	if isSaveRequested || !isAskAllowed {
+		return isSaveRequested, nil
+	}
+
+	answer, err := AskSaveConfig(qh)
+	if err != nil {
+		return false, err
+	}
+
+	return answer == cli.ResponseYes, nil
+}
+
 // Ask the instance id to be connected
 func AskInstanceId(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper) (*string, error) {
 	// Only include running states
@@ -1116,8 +1929,44 @@ func AskInstanceId(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper
 	return &answer, err
 }
 
-// Ask the instance IDs to be terminated
-func AskInstanceIds(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper, addedInstanceIds []string) ([]string, error) {
+// Ask the instance to reconnect to among the instances of the connect history
+func AskRecentConnection(qh *questionModel.QuestionModelHelper,
+	entries []config.ConnectHistoryEntry) (*config.ConnectHistoryEntry, error) {
+	if len(entries) <= 0 {
+		return nil, errors.New("No recent connection. Connect to an instance with --instance-id or --interactive first")
+	}
+
+	data := [][]string{}
+	indexedOptions := []string{}
+	for _, entry := range entries {
+		indexedOptions = append(indexedOptions, entry.InstanceId)
+		data = append(data, []string{entry.InstanceId, entry.Alias, entry.Region,
+			entry.ConnectedAt.Local().Format("2006-01-02 15:04")})
+	}
+
+	model := &questionModel.SingleSelectList{}
+	err := qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
+		Rows:           questionModel.CreateSingleLineRows(data),
+		QuestionString: "Select the instance you want to reconnect to: ",
+		HeaderStrings:  []string{"Instance", "Name", "Region", "Last Connected"},
+		IndexedOptions: indexedOptions,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	answer := model.GetChoice()
+	for _, entry := range entries {
+		if entry.InstanceId == answer {
+			return &entry, nil
+		}
+	}
+	return nil, fmt.Errorf("Instance %s is not a recent connection", answer)
+}
+
+// Ask the instance IDs to be terminated, among the instances matching the filters if any
+func AskInstanceIds(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper, addedInstanceIds []string,
+	filters []*ec2.Filter) ([]string, error) {
 	// Only include non-terminated states
 	states := []string{
 		ec2.InstanceStateNamePending,
@@ -1126,7 +1975,7 @@ func AskInstanceIds(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelpe
 		ec2.InstanceStateNameStopped,
 	}
 
-	instances, err := h.GetInstancesByState(states)
+	instances, err := h.GetInstancesByState(states, filters...)
 	if err != nil {
 		return nil, err
 	}
@@ -1140,6 +1989,9 @@ func AskInstanceIds(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelpe
 
 	// There are no instances available for termination in selected region
 	if len(data) <= 0 && len(addedInstanceIds) == 0 {
+		if len(filters) > 0 {
+			return nil, errors.New("No instance with the filter tags available in selected region for termination")
+		}
 		return nil, errors.New("No instance available in selected region for termination")
 	}
 
@@ -1163,6 +2015,29 @@ func AskInstanceIds(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelpe
 	return answer, err
 }
 
+// Ask the IDs of the stopped instances to be started
+func AskStoppedInstanceIds(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper) ([]string, error) {
+	instances, err := h.GetInstancesByState([]string{ec2.InstanceStateNameStopped})
+	if err != nil {
+		return nil, err
+	}
+
+	data, indexedOptions, _, rows := table.AppendInstances([][]string{}, []string{}, instances, []string{})
+	if len(data) <= 0 {
+		return nil, errors.New("No stopped instance available in selected region to start")
+	}
+
+	model := &questionModel.MultiSelectList{}
+	err = qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
+		QuestionString: "Select the instances you want to start: ",
+		HeaderStrings:  []string{"Instance", "Tag-Key", "Tag-Value"},
+		IndexedOptions: indexedOptions,
+		Rows:           rows,
+	})
+
+	return model.GetSelectedValues(), err
+}
+
 // AskBootScriptConfirmation confirms if the user should be prompted to enter in a bootscript
 func AskBootScriptConfirmation(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
 	defaultBootScript string) (string, error) {
@@ -1236,15 +2111,45 @@ func AskUserTags(h *ec2helper.EC2Helper, qh *questionModel.QuestionModelHelper,
 }
 
 // AskTerminationConfirmation confirms if the user wants to terminate the selected instanceIds
-func AskTerminationConfirmation(qh *questionModel.QuestionModelHelper, instanceIds []string) (string, error) {
-	question := fmt.Sprintf("Are you sure you want to terminate %d instance(s): %s ", len(instanceIds), instanceIds)
-	answer, err := questionModel.AskYesNoQuestion(qh, question, false)
+func AskTerminationConfirmation(qh *questionModel.QuestionModelHelper, instanceIds []string) (bool, error) {
+	summary := fmt.Sprintf("%d instance(s) will be terminated: %s.", len(instanceIds), instanceIds)
+	return cli.ConfirmDestructive(summary, func(question string) (string, error) {
+		return questionModel.AskYesNoQuestion(qh, question, false)
+	})
+}
 
-	if err != nil {
-		return "", err
+// AskCleanupConfirmation confirms if the user wants to delete the orphaned resources found by cleanup
+func AskCleanupConfirmation(qh *questionModel.QuestionModelHelper, resourceCount int) (bool, error) {
+	summary := fmt.Sprintf("%d orphaned resource(s) will be deleted.", resourceCount)
+	return cli.ConfirmDestructive(summary, func(question string) (string, error) {
+		return questionModel.AskYesNoQuestion(qh, question, false)
+	})
+}
+
+/*
+GetCapacityTypeFromFlags gets the capacity type from the capacity type flag, matched case-insensitively,
+and the Spot shorthand flag. An empty result means the capacity type is not specified.
+*/
+func GetCapacityTypeFromFlags(capacityType string, isSpot bool) (string, error) {
+	if strings.EqualFold(capacityType, DefaultCapacityTypeText.OnDemand) {
+		capacityType = DefaultCapacityTypeText.OnDemand
+	} else if strings.EqualFold(capacityType, DefaultCapacityTypeText.Spot) {
+		capacityType = DefaultCapacityTypeText.Spot
+	} else if capacityType != "" {
+		return "", fmt.Errorf("Capacity type must be \"%s\" or \"%s\".%s", DefaultCapacityTypeText.OnDemand,
+			DefaultCapacityTypeText.Spot, cli.DidYouMean(capacityType,
+				[]string{DefaultCapacityTypeText.OnDemand, DefaultCapacityTypeText.Spot}))
+	}
+
+	if isSpot {
+		if capacityType == DefaultCapacityTypeText.OnDemand {
+			return "", fmt.Errorf("You can't launch a Spot instance with the %s capacity type",
+				DefaultCapacityTypeText.OnDemand)
+		}
+		capacityType = DefaultCapacityTypeText.Spot
 	}
 
-	return answer, nil
+	return capacityType, nil
 }
 
 /*
@@ -1253,21 +2158,6 @@ pricing of each type before selection.
 */
 func AskCapacityType(qh *questionModel.QuestionModelHelper, instanceType string,
 	region string, defaultCapacityType string) (string, error) {
-	ec2Pricing := ec2pricing.New(session.New().Copy(aws.NewConfig().WithRegion(region)))
-	onDemandPrice, err := ec2Pricing.GetOnDemandInstanceTypeCost(instanceType)
-	formattedOnDemandPrice := "N/A"
-	if err == nil {
-		onDemandPrice = math.Round(onDemandPrice*10000) / 10000
-		formattedOnDemandPrice = fmt.Sprintf("$%s/hr", strconv.FormatFloat(onDemandPrice, 'f', -1, 64))
-	}
-
-	spotPrice, err := ec2Pricing.GetSpotInstanceTypeNDayAvgCost(instanceType, []string{}, 1)
-	formattedSpotPrice := "N/A"
-	if err == nil {
-		spotPrice = math.Round(spotPrice*10000) / 10000
-		formattedSpotPrice = fmt.Sprintf("$%s/hr", strconv.FormatFloat(spotPrice, 'f', -1, 64))
-	}
-
 	question := fmt.Sprintf("Select capacity type. Spot instances are available at up to a 90%% discount compared to On-Demand instances,\n" +
 		"but they may get interrupted by EC2 with a 2-minute warning")
 
@@ -1277,13 +2167,22 @@ func AskCapacityType(qh *questionModel.QuestionModelHelper, instanceType string,
 		defaultOption = defaultCapacityType
 	}
 
-	data := [][]string{{DefaultCapacityTypeText.OnDemand, formattedOnDemandPrice}, {DefaultCapacityTypeText.Spot, formattedSpotPrice}}
-
-	headers := []string{"Capacity Type", "Price"}
+	// The prices are left out rather than shown as N/A in the regions they aren't published for
+	data := [][]string{{DefaultCapacityTypeText.OnDemand}, {DefaultCapacityTypeText.Spot}}
+	headers := []string{"Capacity Type"}
+	if IsPricingSupported(region) {
+		formattedOnDemandPrice, formattedSpotPrice := GetFormattedPrices(instanceType, region)
+		data = [][]string{{DefaultCapacityTypeText.OnDemand, formattedOnDemandPrice},
+			{DefaultCapacityTypeText.Spot, formattedSpotPrice}}
+		headers = append(headers, "Price")
+	} else {
+		question += fmt.Sprintf("\nPrices are not available in region %s", region)
+	}
 
 	model := &questionModel.SingleSelectList{}
-	err = qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
+	err := qh.Svc.AskQuestion(model, &questionModel.QuestionInput{
 		QuestionString: question,
+		HelpString:     "On-Demand instances run until they are stopped. Spot instances are cheaper, but may be interrupted by EC2.",
 		DefaultOption:  defaultOption,
 		IndexedOptions: indexedOptions,
 		Rows:           questionModel.CreateSingleLineRows(data),
@@ -1297,6 +2196,310 @@ func AskCapacityType(qh *questionModel.QuestionModelHelper, instanceType string,
 	return model.GetChoice(), nil
 }
 
+// Format the boot mode of the image, and whether NitroTPM is enabled
+func formatBootMode(simpleConfig *config.SimpleInfo, detailedConfig *config.DetailedInfo) string {
+	bootMode := ec2helper.GetImageBootMode(detailedConfig.Image)
+	if simpleConfig.NitroTpm {
+		bootMode += " with NitroTPM"
+	}
+
+	return bootMode
+}
+
+// Format the Spot block duration, noting that it is priced differently from regular Spot instances
+func formatSpotBlockDuration(minutes int) string {
+	return fmt.Sprintf("%d (block pricing differs from Spot pricing)", minutes)
+}
+
+// Format the type of the root volume with its IOPS and throughput. Empty means the type of the image is used
+func formatRootVolumeType(simpleConfig *config.SimpleInfo) string {
+	volumeType := simpleConfig.RootVolumeType
+	if simpleConfig.RootVolumeIops > 0 {
+		volumeType += fmt.Sprintf(", %d IOPS", simpleConfig.RootVolumeIops)
+	}
+	if simpleConfig.RootVolumeThroughput > 0 {
+		volumeType += fmt.Sprintf(", %d MiB/s", simpleConfig.RootVolumeThroughput)
+	}
+
+	return volumeType
+}
+
+// Format the capacity reservation, either an explicit reservation or a preference. Empty means the AWS default
+func formatCapacityReservation(simpleConfig *config.SimpleInfo) string {
+	if simpleConfig.CapacityReservationId != "" {
+		return simpleConfig.CapacityReservationId
+	}
+	if simpleConfig.CapacityReservationPreference != "" {
+		return "Preference: " + simpleConfig.CapacityReservationPreference
+	}
+
+	return ""
+}
+
+// Format the private DNS name options. Empty means the options of the subnet are used
+func formatPrivateDnsName(simpleConfig *config.SimpleInfo) string {
+	options := []string{}
+	if simpleConfig.PrivateDnsHostnameType != "" {
+		options = append(options, "Hostname type: "+simpleConfig.PrivateDnsHostnameType)
+	}
+	if simpleConfig.EnableResourceNameDnsARecord {
+		options = append(options, "A record")
+	}
+	if simpleConfig.EnableResourceNameDnsAAAARecord {
+		options = append(options, "AAAA record")
+	}
+
+	return strings.Join(options, ", ")
+}
+
+// Format the network performance of the instance type, such as "Up to 5 Gigabit". Empty means it's unknown
+func formatNetworkPerformance(instanceTypeInfo *ec2.InstanceTypeInfo) string {
+	if instanceTypeInfo == nil || instanceTypeInfo.NetworkInfo == nil {
+		return ""
+	}
+
+	return aws.StringValue(instanceTypeInfo.NetworkInfo.NetworkPerformance)
+}
+
+/*
+Format the EBS-optimized bandwidth of the instance type, with the maximum bandwidth if it can burst above
+its baseline. Empty means the instance type isn't EBS-optimized.
+*/
+func formatEbsBandwidth(instanceTypeInfo *ec2.InstanceTypeInfo) string {
+	if instanceTypeInfo == nil || instanceTypeInfo.EbsInfo == nil || instanceTypeInfo.EbsInfo.EbsOptimizedInfo == nil {
+		return ""
+	}
+
+	ebsOptimizedInfo := instanceTypeInfo.EbsInfo.EbsOptimizedInfo
+	baseline := aws.Int64Value(ebsOptimizedInfo.BaselineBandwidthInMbps)
+	maximum := aws.Int64Value(ebsOptimizedInfo.MaximumBandwidthInMbps)
+	if maximum > baseline {
+		return fmt.Sprintf("%d Mbps (up to %d Mbps)", baseline, maximum)
+	}
+
+	return fmt.Sprintf("%d Mbps", baseline)
+}
+
+// Looks up the prices of instance types, implemented by the pricing client of instance selector
+type InstancePricer interface {
+	GetOnDemandInstanceTypeCost(instanceType string) (float64, error)
+	GetSpotInstanceTypeNDayAvgCost(instanceType string, availabilityZones []string, days int) (float64, error)
+}
+
+// Create the client looking up the prices of a region. Replaced with a mock in tests
+var NewPricingClient = func(region string) InstancePricer {
+	return ec2pricing.New(session.New().Copy(aws.NewConfig().WithRegion(region)))
+}
+
+/*
+Tell if prices can be looked up in the region. The prices are only published for the regions of the aws partition,
+not for partitions such as China and GovCloud.
+*/
+func IsPricingSupported(region string) bool {
+	partition, found := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region)
+	return found && partition.ID() == endpoints.AwsPartitionID
+}
+
+/*
+GetFormattedPrices gets the hourly On-Demand and Spot prices of the instance type in the region.
+A price is "N/A" if it can't be fetched, and both are without a lookup in regions prices aren't published for.
+*/
+func GetFormattedPrices(instanceType, region string) (formattedOnDemandPrice, formattedSpotPrice string) {
+	if !IsPricingSupported(region) {
+		return "N/A", "N/A"
+	}
+
+	ec2Pricing := NewPricingClient(region)
+	onDemandPrice, err := getHourlyPrice(ec2Pricing, instanceType, false)
+	formattedOnDemandPrice = "N/A"
+	if err == nil {
+		formattedOnDemandPrice = FormatHourlyPrice(onDemandPrice)
+	}
+
+	spotPrice, err := getHourlyPrice(ec2Pricing, instanceType, true)
+	formattedSpotPrice = "N/A"
+	if err == nil {
+		formattedSpotPrice = FormatHourlyPrice(spotPrice)
+	}
+
+	return formattedOnDemandPrice, formattedSpotPrice
+}
+
+// Get the hourly On-Demand price of the instance type, or its average Spot price over the last day
+func getHourlyPrice(pricer InstancePricer, instanceType string, isSpot bool) (float64, error) {
+	if isSpot {
+		return pricer.GetSpotInstanceTypeNDayAvgCost(instanceType, []string{}, 1)
+	}
+
+	return pricer.GetOnDemandInstanceTypeCost(instanceType)
+}
+
+// Format an hourly price rounded to a hundredth of a cent, such as "$0.0104/hr"
+func FormatHourlyPrice(price float64) string {
+	price = math.Round(price*10000) / 10000
+	return fmt.Sprintf("$%s/hr", strconv.FormatFloat(price, 'f', -1, 64))
+}
+
+/*
+Get the instance type with the lowest hourly On-Demand or Spot price in the region, along with the price.
+Instance types whose price can't be looked up are skipped, and the first instance type wins a tie.
+*/
+func GetCheapestInstanceType(instanceTypes []*instancetypes.Details, region string,
+	isSpot bool) (*instancetypes.Details, float64, error) {
+	if !IsPricingSupported(region) {
+		return nil, 0, fmt.Errorf("Prices are not available in region %s", region)
+	}
+
+	pricer := NewPricingClient(region)
+	var cheapestInstanceType *instancetypes.Details
+	var cheapestPrice float64
+	for _, instanceType := range instanceTypes {
+		price, err := getHourlyPrice(pricer, aws.StringValue(instanceType.InstanceType), isSpot)
+		if err != nil {
+			continue
+		}
+		if cheapestInstanceType == nil || price < cheapestPrice {
+			cheapestInstanceType = instanceType
+			cheapestPrice = price
+		}
+	}
+
+	if cheapestInstanceType == nil {
+		return nil, 0, errors.New("No price found for any of the instance types")
+	}
+
+	return cheapestInstanceType, cheapestPrice, nil
+}
+
+/*
+Format the estimated monthly cost of the root EBS volumes of the instances, which the instance prices don't include.
+The cost is "N/A" if the image has no EBS root volume, the price of the volume type is unknown, or the region
+isn't priced like the aws partition.
+*/
+func formatStorageCost(simpleConfig *config.SimpleInfo, detailedConfig *config.DetailedInfo) string {
+	rootVolume := ec2helper.GetRootEbsVolume(detailedConfig.Image)
+	if rootVolume == nil || !IsPricingSupported(simpleConfig.Region) {
+		return "N/A"
+	}
+
+	volumeType := aws.StringValue(rootVolume.VolumeType)
+	if simpleConfig.RootVolumeType != "" {
+		volumeType = simpleConfig.RootVolumeType
+	}
+	volumeSize := aws.Int64Value(rootVolume.VolumeSize)
+	if simpleConfig.RootVolumeSize > 0 {
+		volumeSize = simpleConfig.RootVolumeSize
+	}
+
+	cost, err := ec2helper.GetEbsMonthlyStorageCost(volumeType, volumeSize)
+	if err != nil {
+		return "N/A"
+	}
+	cost *= float64(ec2helper.GetInstanceCount(simpleConfig))
+
+	return fmt.Sprintf("$%.2f/month for %d GiB of %s, excluding data transfer", cost, volumeSize, volumeType)
+}
+
+/*
+GetLaunchPreview validates the config against AWS without launching an instance, and returns a table
+previewing the launch. The preview includes the resolved resources, the estimated cost and the result
+of a dry run, which reveals missing permissions.
+*/
+func GetLaunchPreview(h *ec2helper.EC2Helper, simpleConfig *config.SimpleInfo,
+	detailedConfig *config.DetailedInfo) string {
+	vpcInfo, subnetInfo := getNetworkInfo(simpleConfig, detailedConfig)
+
+	imageInfo := simpleConfig.ImageId
+	if detailedConfig.Image.Name != nil {
+		imageInfo = fmt.Sprintf("%s(%s)", *detailedConfig.Image.Name, simpleConfig.ImageId)
+	}
+
+	data := [][]string{
+		{cli.ResourceRegion, simpleConfig.Region},
+		{cli.ResourceVpc, vpcInfo},
+		{cli.ResourceSubnet, subnetInfo},
+		{cli.ResourceInstanceType, simpleConfig.InstanceType},
+		{cli.ResourceInstanceCount, strconv.FormatInt(ec2helper.GetInstanceCount(simpleConfig), 10)},
+		{cli.ResourceCapacityType, simpleConfig.CapacityType},
+		{cli.ResourceImage, imageInfo},
+		{cli.ResourceBootMode, formatBootMode(simpleConfig, detailedConfig)},
+	}
+
+	if deprecation := formatImageDeprecation(detailedConfig.Image); deprecation != "" {
+		data = append(data, []string{cli.ResourceImageDeprecation, deprecation})
+	}
+
+	if simpleConfig.SpotBlockDurationMinutes > 0 {
+		data = append(data, []string{cli.ResourceSpotBlockDuration,
+			formatSpotBlockDuration(simpleConfig.SpotBlockDurationMinutes)})
+	}
+	if capacityReservation := formatCapacityReservation(simpleConfig); capacityReservation != "" {
+		data = append(data, []string{cli.ResourceCapacityReservation, capacityReservation})
+	}
+	if privateDnsName := formatPrivateDnsName(simpleConfig); privateDnsName != "" {
+		data = append(data, []string{cli.ResourcePrivateDnsName, privateDnsName})
+	}
+	if simpleConfig.CpuCredits != "" {
+		data = append(data, []string{cli.ResourceCpuCredits, simpleConfig.CpuCredits})
+	}
+	if simpleConfig.AssociateCarrierIp {
+		data = append(data, []string{cli.ResourceCarrierIp, cli.ResponseYes})
+	}
+	if simpleConfig.NoPublicIp {
+		data = append(data, []string{cli.ResourcePublicIp, cli.ResponseNo})
+	}
+	if simpleConfig.HostResourceGroupArn != "" {
+		data = append(data, []string{cli.ResourceHostResourceGroup, simpleConfig.HostResourceGroupArn})
+	}
+	if simpleConfig.Affinity != "" {
+		data = append(data, []string{cli.ResourceAffinity, simpleConfig.Affinity})
+	}
+	if simpleConfig.KeyPairName != "" {
+		data = append(data, []string{cli.ResourceKeyPair, simpleConfig.KeyPairName})
+	}
+	if simpleConfig.Imds != "" {
+		data = append(data, []string{cli.ResourceImds, simpleConfig.Imds})
+	}
+	if simpleConfig.RootVolumeSize > 0 {
+		data = append(data, []string{cli.ResourceRootVolumeSize, strconv.FormatInt(simpleConfig.RootVolumeSize, 10)})
+	}
+	if simpleConfig.RootVolumeType != "" {
+		data = append(data, []string{cli.ResourceRootVolumeType, formatRootVolumeType(simpleConfig)})
+	}
+	if networkPerformance := formatNetworkPerformance(detailedConfig.InstanceTypeInfo); networkPerformance != "" {
+		data = append(data, []string{cli.ResourceNetworkPerformance, networkPerformance})
+	}
+	if ebsBandwidth := formatEbsBandwidth(detailedConfig.InstanceTypeInfo); ebsBandwidth != "" {
+		data = append(data, []string{cli.ResourceEbsBandwidth, ebsBandwidth})
+	}
+	if detailedConfig.SecurityGroups != nil {
+		data, _ = table.AppendSecurityGroups(data, detailedConfig.SecurityGroups)
+	}
+	if simpleConfig.IamInstanceProfile != "" {
+		data = append(data, []string{cli.ResourceIamInstanceProfile, simpleConfig.IamInstanceProfile})
+	}
+
+	onDemandPrice, spotPrice := GetFormattedPrices(simpleConfig.InstanceType, simpleConfig.Region)
+	data = append(data, []string{"Estimated On-Demand Price", onDemandPrice},
+		[]string{"Estimated Spot Price", spotPrice})
+	data = append(data, []string{"Estimated Storage Cost", formatStorageCost(simpleConfig, detailedConfig)})
+
+	// Spot instances are launched with a launch template and a fleet, which need other permissions
+	dryRunResult := "Passed"
+	var err error
+	if simpleConfig.CapacityType == DefaultCapacityTypeText.Spot {
+		err = h.DryRunLaunchSpotInstance(simpleConfig, detailedConfig)
+	} else {
+		err = h.DryRunLaunchInstance(simpleConfig, detailedConfig)
+	}
+	if err != nil {
+		dryRunResult = "Failed: " + err.Error()
+	}
+	data = append(data, []string{"Dry Run", dryRunResult})
+
+	return table.BuildTable(data, []string{"Configuration", "Value"})
+}
+
 // askConfigTableQuestion asks the user to create an instance based on given configurations
 func askConfigTableQuestion(qh *questionModel.QuestionModelHelper, tableData [][]string) (string, error) {
 	question := "Please confirm if you would like to launch instance with following options:"
@@ -32,10 +32,12 @@ import (
 
 	"github.com/aws/amazon-ec2-instance-selector/v2/pkg/instancetypes"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/iam"
 	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/exp/slices"
 )
 
 var testEC2 = &ec2helper.EC2Helper{
@@ -373,6 +375,80 @@ func TestAskImage_Success(t *testing.T) {
 	th.Equals(t, expectedImage, *answer.ImageId)
 }
 
+func TestAskImage_NonTerminalSpinner(t *testing.T) {
+	const testInstanceType = ec2.InstanceTypeT2Micro
+
+	testEC2 = ec2helper.New(session.Must(session.NewSession()))
+	testEC2.Svc = &th.MockedEC2Svc{
+		InstanceTypes: []*ec2.InstanceTypeInfo{
+			{
+				InstanceType:  aws.String(testInstanceType),
+				ProcessorInfo: &ec2.ProcessorInfo{SupportedArchitectures: defaultArchitecture},
+			},
+		},
+		Images: []*ec2.Image{
+			{
+				ImageId:      aws.String("ami-12345"),
+				CreationDate: aws.String("some time"),
+			},
+		},
+	}
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+
+	// Stdout is a pipe, as when output is redirected, so the spinner is skipped
+	err := th.TakeOverStdout()
+	th.Ok(t, err)
+	_, err = question.AskImage(testEC2, testQMHelper, testInstanceType, "")
+	stdout := th.ReadStdout()
+	th.Ok(t, err)
+
+	th.Assert(t, !strings.ContainsAny(stdout, "\r\x1b"), "No spinner control sequences should be written")
+}
+
+func TestAskImage_DeprecatedImage(t *testing.T) {
+	const testInstanceType = ec2.InstanceTypeT2Micro
+	deprecationTime := time.Now().Add(-24 * time.Hour).UTC()
+
+	testEC2.Svc = &th.MockedEC2Svc{
+		InstanceTypes: []*ec2.InstanceTypeInfo{
+			{
+				InstanceType:             aws.String(testInstanceType),
+				InstanceStorageSupported: aws.Bool(true),
+				ProcessorInfo:            &ec2.ProcessorInfo{SupportedArchitectures: defaultArchitecture},
+			},
+		},
+		Images: []*ec2.Image{
+			{
+				ImageId:         aws.String("ami-12345"),
+				CreationDate:    aws.String("some time"),
+				DeprecationTime: aws.String(deprecationTime.Format(time.RFC3339)),
+			},
+		},
+	}
+	mockedQMHelperSvc := &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+	testQMHelper.Svc = mockedQMHelperSvc
+
+	_, err := question.AskImage(testEC2, testQMHelper, testInstanceType, "")
+	th.Ok(t, err)
+
+	questionInput := mockedQMHelperSvc.QuestionInputs[0]
+	th.Equals(t, "Deprecation", questionInput.HeaderStrings[len(questionInput.HeaderStrings)-1])
+	row := questionInput.Rows[0][0]
+	th.Equals(t, "deprecated since "+deprecationTime.Format("2006-01-02"), row[len(row)-1])
+}
+
 func TestAskImage_NoImage(t *testing.T) {
 	const testInstanceType = ec2.InstanceTypeT2Micro
 
@@ -485,6 +561,100 @@ func TestAskAutoTerminationTimerMinutes(t *testing.T) {
 	th.Ok(t, err)
 }
 
+func TestAskInstanceCount(t *testing.T) {
+	const expectedAnswer = "3"
+
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Runes: []rune(expectedAnswer),
+				Type:  tea.KeyRunes,
+			},
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+
+	answer, err := question.AskInstanceCount(testEC2, testQMHelper, 1)
+	th.Equals(t, expectedAnswer, answer)
+
+	th.Ok(t, err)
+}
+
+func TestAskEbsVolumeSize(t *testing.T) {
+	const expectedAnswer = "30"
+
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Runes: []rune(expectedAnswer),
+				Type:  tea.KeyRunes,
+			},
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+
+	answer, err := question.AskEbsVolumeSize(testEC2, testQMHelper, 8, 0)
+	th.Equals(t, expectedAnswer, answer)
+
+	th.Ok(t, err)
+}
+
+func TestAskEbsVolumeType(t *testing.T) {
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+
+	answer, err := question.AskEbsVolumeType(testQMHelper, ec2.VolumeTypeIo2)
+	th.Ok(t, err)
+	th.Equals(t, ec2.VolumeTypeIo2, answer)
+}
+
+func TestAskKeyPair(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		KeyPairs: []*ec2.KeyPairInfo{
+			{KeyName: aws.String("key-1")},
+			{KeyName: aws.String("key-2")},
+		},
+	}
+
+	// The options end with creating a key pair and no key pair, the default
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyUp,
+			},
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+
+	answer, err := question.AskKeyPair(testEC2, testQMHelper, "")
+	th.Ok(t, err)
+	th.Equals(t, cli.ResponseNew, answer)
+
+	// The default key pair is selected by default
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+
+	answer, err = question.AskKeyPair(testEC2, testQMHelper, "key-2")
+	th.Ok(t, err)
+	th.Equals(t, "key-2", answer)
+}
+
 func TestAskVpc_Success(t *testing.T) {
 	const expectedVpc = "vpc-12345"
 
@@ -522,6 +692,95 @@ func TestAskVpc_Success(t *testing.T) {
 	th.Equals(t, expectedVpc, *answer)
 }
 
+func TestAskVpc_DefaultColumn(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		Vpcs: []*ec2.Vpc{
+			{
+				VpcId:     aws.String("vpc-12345"),
+				CidrBlock: aws.String("some block"),
+				IsDefault: aws.Bool(false),
+			},
+			{
+				VpcId:     aws.String("vpc-67890"),
+				CidrBlock: aws.String("some other block"),
+				IsDefault: aws.Bool(true),
+			},
+		},
+	}
+
+	mockedQMHelperSvc := &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+	testQMHelper.Svc = mockedQMHelperSvc
+
+	answer, err := question.AskVpc(testEC2, testQMHelper, "")
+	th.Ok(t, err)
+	th.Equals(t, "vpc-67890", *answer)
+
+	questionInput := mockedQMHelperSvc.QuestionInputs[0]
+	th.Equals(t, "Default", questionInput.HeaderStrings[2])
+	th.Equals(t, []string{"vpc-12345", "some block", cli.ResponseNo}, questionInput.Rows[0][0])
+	th.Equals(t, []string{"vpc-67890", "some other block", cli.ResponseYes}, questionInput.Rows[1][0])
+}
+
+func TestAskVpc_NewVpcDeclined(t *testing.T) {
+	const expectedVpc = "vpc-12345"
+	testEC2.Svc = &th.MockedEC2Svc{
+		Vpcs: []*ec2.Vpc{
+			{
+				VpcId:     aws.String(expectedVpc),
+				CidrBlock: aws.String("some block"),
+				IsDefault: aws.Bool(true),
+			},
+		},
+	}
+
+	// Select a new VPC, decline it, then select the existing VPC
+	mockedQMHelperSvc := &th.MockedQMHelperSvc{
+		UserInputsPerQuestion: [][]tea.Msg{
+			{tea.KeyMsg{Type: tea.KeyDown}, tea.KeyMsg{Type: tea.KeyEnter}},
+			{tea.KeyMsg{Type: tea.KeyEnter}},
+			{tea.KeyMsg{Type: tea.KeyEnter}},
+		},
+	}
+	testQMHelper.Svc = mockedQMHelperSvc
+
+	answer, err := question.AskVpc(testEC2, testQMHelper, "")
+	th.Ok(t, err)
+	th.Equals(t, expectedVpc, *answer)
+	th.Equals(t, 3, len(mockedQMHelperSvc.QuestionInputs))
+	th.Assert(t, strings.Contains(mockedQMHelperSvc.QuestionInputs[1].QuestionString, "3 subnets"),
+		"The confirmation should explain the stack: "+mockedQMHelperSvc.QuestionInputs[1].QuestionString)
+	th.Equals(t, mockedQMHelperSvc.QuestionInputs[0].QuestionString, mockedQMHelperSvc.QuestionInputs[2].QuestionString)
+}
+
+func TestAskVpc_NewVpcConfirmed(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		Vpcs: []*ec2.Vpc{
+			{
+				VpcId:     aws.String("vpc-12345"),
+				CidrBlock: aws.String("some block"),
+				IsDefault: aws.Bool(true),
+			},
+		},
+	}
+
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputsPerQuestion: [][]tea.Msg{
+			{tea.KeyMsg{Type: tea.KeyDown}, tea.KeyMsg{Type: tea.KeyEnter}},
+			{tea.KeyMsg{Type: tea.KeyUp}, tea.KeyMsg{Type: tea.KeyEnter}},
+		},
+	}
+
+	answer, err := question.AskVpc(testEC2, testQMHelper, "")
+	th.Ok(t, err)
+	th.Equals(t, cli.ResponseNew, *answer)
+}
+
 func TestAskVpc_DescribeVpcsPagesError(t *testing.T) {
 	testEC2.Svc = &th.MockedEC2Svc{
 		DescribeVpcsPagesError: errors.New("Test error"),
@@ -564,6 +823,11 @@ func TestAskSubnet_Success(t *testing.T) {
 				AvailabilityZone: aws.String("some az"),
 			},
 		},
+		AvailabilityZones: []*ec2.AvailabilityZone{
+			{
+				ZoneName: aws.String("some az"),
+			},
+		},
 	}
 
 	testQMHelper.Svc = &th.MockedQMHelperSvc{
@@ -598,6 +862,55 @@ func TestAskSubnet_DescribeSubnetsPagesError(t *testing.T) {
 	th.Nok(t, err)
 }
 
+func TestAskSubnet_ZoneType(t *testing.T) {
+	const testVpc = "vpc-12345"
+
+	testEC2.Svc = &th.MockedEC2Svc{
+		Subnets: []*ec2.Subnet{
+			{
+				SubnetId:         aws.String("subnet-12345"),
+				VpcId:            aws.String(testVpc),
+				CidrBlock:        aws.String("some block"),
+				AvailabilityZone: aws.String("us-west-2a"),
+			},
+			{
+				SubnetId:         aws.String("subnet-67890"),
+				VpcId:            aws.String(testVpc),
+				CidrBlock:        aws.String("some block"),
+				AvailabilityZone: aws.String("us-west-2-lax-1a"),
+			},
+		},
+		AvailabilityZones: []*ec2.AvailabilityZone{
+			{
+				ZoneName: aws.String("us-west-2a"),
+				ZoneType: aws.String(ec2helper.ZoneTypeAvailabilityZone),
+			},
+			{
+				ZoneName: aws.String("us-west-2-lax-1a"),
+				ZoneType: aws.String(ec2helper.ZoneTypeLocalZone),
+			},
+		},
+	}
+	testEC2.ZoneType = ec2helper.ZoneTypeLocalZone
+	defer func() {
+		testEC2.ZoneType = ""
+	}()
+
+	mockedQMHelperSvc := &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+	testQMHelper.Svc = mockedQMHelperSvc
+
+	answer, err := question.AskSubnet(testEC2, testQMHelper, testVpc, "")
+	th.Ok(t, err)
+	th.Equals(t, "subnet-67890", *answer)
+	th.Equals(t, []string{"subnet-67890"}, mockedQMHelperSvc.QuestionInputs[0].IndexedOptions)
+}
+
 func TestAskSubnetPlaceholder_Success(t *testing.T) {
 	const expectedAz = "us-east-1"
 
@@ -989,8 +1302,28 @@ func TestAskConfirmationWithInput_Success_NewInfrastructure(t *testing.T) {
 	th.Ok(t, err)
 }
 
-func TestAskSaveConfig(t *testing.T) {
-	const expectedAnswer = cli.ResponseYes
+func TestFormatCompactSummary(t *testing.T) {
+	simpleConfig := &config.SimpleInfo{
+		InstanceType: "t3.micro",
+		ImageId:      "ami-12345",
+		SubnetId:     "subnet-12345",
+		CapacityType: question.DefaultCapacityTypeText.Spot,
+	}
+
+	summary := question.FormatCompactSummary(simpleConfig, testDetailedConfig)
+	th.Equals(t, "t3.micro / ami-12345 / subnet-12345 / Spot — launch?", summary)
+
+	// New subnets are shown with their availability zone, and the capacity type defaults to On-Demand
+	simpleConfig.NewVPC = true
+	simpleConfig.SubnetId = "us-east-1a"
+	simpleConfig.CapacityType = ""
+	summary = question.FormatCompactSummary(simpleConfig, testDetailedConfig)
+	th.Equals(t, "t3.micro / ami-12345 / New Subnet in us-east-1a / On-Demand — launch?", summary)
+}
+
+func TestAskConfirmationWithInput_CompactConfirmation(t *testing.T) {
+	question.IsCompactConfirmation = true
+	defer func() { question.IsCompactConfirmation = false }()
 
 	testQMHelper.Svc = &th.MockedQMHelperSvc{
 		UserInputs: []tea.Msg{
@@ -1003,35 +1336,598 @@ func TestAskSaveConfig(t *testing.T) {
 		},
 	}
 
-	answer, err := question.AskSaveConfig(testQMHelper)
-	th.Equals(t, expectedAnswer, answer)
-
+	answer, err := question.AskConfirmationWithInput(testQMHelper, testSimpleConfig, testDetailedConfig, true)
 	th.Ok(t, err)
+	th.Equals(t, cli.ResponseYes, answer)
 }
 
-func TestAskInstanceId_Success(t *testing.T) {
-	const expectedInstance = "i-12345"
-
-	testEC2.Svc = &th.MockedEC2Svc{
-		Instances: []*ec2.Instance{
-			{
-				InstanceId: aws.String(expectedInstance),
-			},
-			{
-				InstanceId: aws.String("i-67890"),
-			},
-		},
-	}
+func TestAskConfirmationWithInput_DeprecatedImage(t *testing.T) {
+	deprecationTime := time.Now().Add(7 * 24 * time.Hour).UTC()
+	detailedConfig := *testDetailedConfig
+	image := *testDetailedConfig.Image
+	image.DeprecationTime = aws.String(deprecationTime.Format(time.RFC3339))
+	detailedConfig.Image = &image
 
-	testQMHelper.Svc = &th.MockedQMHelperSvc{
+	mockedQMHelperSvc := &th.MockedQMHelperSvc{
 		UserInputs: []tea.Msg{
 			tea.KeyMsg{
 				Type: tea.KeyEnter,
 			},
 		},
 	}
+	testQMHelper.Svc = mockedQMHelperSvc
 
-	answer, err := question.AskInstanceId(testEC2, testQMHelper)
+	_, err := question.AskConfirmationWithInput(testQMHelper, testSimpleConfig, &detailedConfig, false)
+	th.Ok(t, err)
+
+	expectedRow := []string{cli.ResourceImageDeprecation,
+		"Warning: the image is to be deprecated on " + deprecationTime.Format("2006-01-02")}
+	isWarned := false
+	for _, row := range mockedQMHelperSvc.QuestionInputs[0].Rows {
+		if slices.Equal(row[0], expectedRow) {
+			isWarned = true
+		}
+	}
+	th.Assert(t, isWarned, "The confirmation should warn about the deprecated image")
+}
+
+func TestAskConfirmationWithInput_InstanceTypePerformance(t *testing.T) {
+	detailedConfig := *testDetailedConfig
+	detailedConfig.InstanceTypeInfo = &ec2.InstanceTypeInfo{
+		InstanceType: aws.String(ec2.InstanceTypeM5Large),
+		NetworkInfo: &ec2.NetworkInfo{
+			NetworkPerformance: aws.String("Up to 10 Gigabit"),
+		},
+		EbsInfo: &ec2.EbsInfo{
+			EbsOptimizedInfo: &ec2.EbsOptimizedInfo{
+				BaselineBandwidthInMbps: aws.Int64(650),
+				MaximumBandwidthInMbps:  aws.Int64(4750),
+			},
+		},
+	}
+
+	mockedQMHelperSvc := &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+	testQMHelper.Svc = mockedQMHelperSvc
+
+	_, err := question.AskConfirmationWithInput(testQMHelper, testSimpleConfig, &detailedConfig, false)
+	th.Ok(t, err)
+
+	expectedRows := [][]string{
+		{cli.ResourceNetworkPerformance, "Up to 10 Gigabit"},
+		{cli.ResourceEbsBandwidth, "650 Mbps (up to 4750 Mbps)"},
+	}
+	for _, expectedRow := range expectedRows {
+		isRendered := false
+		for _, row := range mockedQMHelperSvc.QuestionInputs[0].Rows {
+			if slices.Equal(row[0], expectedRow) {
+				isRendered = true
+			}
+		}
+		th.Assert(t, isRendered, "The confirmation should show the "+expectedRow[0])
+	}
+}
+
+/*
+AskConfirmationForm Tests
+*/
+
+func TestAskConfirmationForm_Submit(t *testing.T) {
+	testFormConfig := &config.SimpleInfo{
+		Region:       "us-east-1",
+		ImageId:      "ami-12345",
+		InstanceType: ec2.InstanceTypeT2Micro,
+		SubnetId:     "subnet-12345",
+	}
+
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+
+	answer, err := question.AskConfirmationForm(testEC2, testQMHelper, testFormConfig, testDetailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, cli.ResponseYes, answer)
+}
+
+func TestAskConfirmationForm_Cancel(t *testing.T) {
+	testFormConfig := &config.SimpleInfo{
+		Region:       "us-east-1",
+		ImageId:      "ami-12345",
+		InstanceType: ec2.InstanceTypeT2Micro,
+		SubnetId:     "subnet-12345",
+	}
+
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyDown,
+			},
+			tea.KeyMsg{
+				Type: tea.KeyDown,
+			},
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+
+	answer, err := question.AskConfirmationForm(testEC2, testQMHelper, testFormConfig, testDetailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, cli.ResponseNo, answer)
+}
+
+func TestAskConfirmationForm_DispatchField(t *testing.T) {
+	testFormConfig := &config.SimpleInfo{
+		Region:       "us-east-1",
+		ImageId:      "ami-12345",
+		InstanceType: ec2.InstanceTypeT2Micro,
+		SubnetId:     "subnet-12345",
+	}
+
+	// The user tags are the last field, right above the submit option
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyUp,
+			},
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+
+	answer, err := question.AskConfirmationForm(testEC2, testQMHelper, testFormConfig, testDetailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, cli.ResourceUserTags, answer)
+}
+
+func TestAskConfirmationForm_EditInPlace(t *testing.T) {
+	testFormConfig := &config.SimpleInfo{
+		Region:                      "us-east-1",
+		ImageId:                     "ami-12345",
+		InstanceType:                ec2.InstanceTypeT2Micro,
+		SubnetId:                    "subnet-12345",
+		AutoTerminationTimerMinutes: 30,
+	}
+
+	// Move to the auto-termination timer above its action, replace its value, then move back to submit the form
+	userInputs := []tea.Msg{}
+	for i := 0; i < 6; i++ {
+		userInputs = append(userInputs, tea.KeyMsg{Type: tea.KeyUp})
+	}
+	userInputs = append(userInputs,
+		tea.KeyMsg{Type: tea.KeyEnter},
+		tea.KeyMsg{Type: tea.KeyBackspace},
+		tea.KeyMsg{Type: tea.KeyBackspace},
+		tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("45")},
+		tea.KeyMsg{Type: tea.KeyEnter},
+	)
+	for i := 0; i < 6; i++ {
+		userInputs = append(userInputs, tea.KeyMsg{Type: tea.KeyDown})
+	}
+	userInputs = append(userInputs, tea.KeyMsg{Type: tea.KeyEnter})
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: userInputs,
+	}
+
+	answer, err := question.AskConfirmationForm(testEC2, testQMHelper, testFormConfig, testDetailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, cli.ResponseYes, answer)
+	th.Equals(t, 45, testFormConfig.AutoTerminationTimerMinutes)
+}
+
+func TestAskConfirmationForm_EditInPlaceDiscardsEditedUserData(t *testing.T) {
+	testFormConfig := &config.SimpleInfo{
+		Region:                      "us-east-1",
+		ImageId:                     "ami-12345",
+		InstanceType:                ec2.InstanceTypeT2Micro,
+		SubnetId:                    "subnet-12345",
+		AutoTerminationTimerMinutes: 30,
+	}
+	detailedConfig := *testDetailedConfig
+	detailedConfig.UserData = aws.String("#!/bin/bash\necho edited\n")
+
+	// The user data has been edited, then the auto-termination timer is changed in place
+	userInputs := []tea.Msg{}
+	for i := 0; i < 6; i++ {
+		userInputs = append(userInputs, tea.KeyMsg{Type: tea.KeyUp})
+	}
+	userInputs = append(userInputs,
+		tea.KeyMsg{Type: tea.KeyEnter},
+		tea.KeyMsg{Type: tea.KeyBackspace},
+		tea.KeyMsg{Type: tea.KeyBackspace},
+		tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("45")},
+		tea.KeyMsg{Type: tea.KeyEnter},
+	)
+	for i := 0; i < 6; i++ {
+		userInputs = append(userInputs, tea.KeyMsg{Type: tea.KeyDown})
+	}
+	userInputs = append(userInputs, tea.KeyMsg{Type: tea.KeyEnter})
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: userInputs,
+	}
+
+	answer, err := question.AskConfirmationForm(testEC2, testQMHelper, testFormConfig, &detailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, cli.ResponseYes, answer)
+	th.Equals(t, 45, testFormConfig.AutoTerminationTimerMinutes)
+	th.Assert(t, detailedConfig.UserData == nil, "The edited user data should be discarded")
+}
+
+func TestAskConfirmationForm_KeepsEditedUserData(t *testing.T) {
+	testFormConfig := &config.SimpleInfo{
+		Region:       "us-east-1",
+		ImageId:      "ami-12345",
+		InstanceType: ec2.InstanceTypeT2Micro,
+		SubnetId:     "subnet-12345",
+	}
+	detailedConfig := *testDetailedConfig
+	detailedConfig.UserData = aws.String("#!/bin/bash\necho edited\n")
+
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{Type: tea.KeyEnter},
+		},
+	}
+
+	answer, err := question.AskConfirmationForm(testEC2, testQMHelper, testFormConfig, &detailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, cli.ResponseYes, answer)
+	th.Equals(t, "#!/bin/bash\necho edited\n", aws.StringValue(detailedConfig.UserData))
+}
+
+func TestAskConfirmationForm_SameRowsAsTable(t *testing.T) {
+	testFormConfig := &config.SimpleInfo{
+		Region:                      "us-east-1",
+		ImageId:                     "ami-12345",
+		InstanceType:                ec2.InstanceTypeT2Micro,
+		SubnetId:                    "subnet-12345",
+		AutoTerminationTimerMinutes: 30,
+		AutoTerminationTimerAction:  ec2.ShutdownBehaviorStop,
+		PrivateIpAddress:            "10.0.0.10",
+		SecondarySubnetId:           "subnet-67890",
+	}
+
+	mockedQMHelperSvc := &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{Type: tea.KeyEnter},
+		},
+	}
+	testQMHelper.Svc = mockedQMHelperSvc
+	_, err := question.AskConfirmationForm(testEC2, testQMHelper, testFormConfig, testDetailedConfig)
+	th.Ok(t, err)
+	formValues := map[string]string{}
+	for _, field := range mockedQMHelperSvc.QuestionInputs[0].FormFields {
+		formValues[field.Name] = field.Value
+	}
+
+	mockedQMHelperSvc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{Type: tea.KeyEnter},
+		},
+	}
+	testQMHelper.Svc = mockedQMHelperSvc
+	_, err = question.AskConfirmationWithInput(testQMHelper, testFormConfig, testDetailedConfig, true)
+	th.Ok(t, err)
+
+	// The rows shown in the table are in the form too, with the same values
+	for _, name := range []string{cli.ResourcePrivateIpAddress, cli.ResourceSecondarySubnet,
+		cli.ResourceAutoTerminationAction, cli.ResourceBootMode} {
+		isInTable := false
+		for _, row := range mockedQMHelperSvc.QuestionInputs[0].Rows {
+			if len(row) == 1 && row[0][0] == name {
+				isInTable = true
+				th.Equals(t, row[0][1], formValues[name])
+			}
+		}
+		th.Assert(t, isInTable, "The table should show the "+name)
+	}
+	th.Equals(t, ec2.ShutdownBehaviorStop, formValues[cli.ResourceAutoTerminationAction])
+}
+
+func TestAskConfirmationForm_KeepsPlatformBootScript(t *testing.T) {
+	testFormConfig := &config.SimpleInfo{
+		Region:                    "us-east-1",
+		ImageId:                   "ami-12345",
+		InstanceType:              ec2.InstanceTypeT2Micro,
+		SubnetId:                  "subnet-12345",
+		BootScriptLinuxFilePath:   "linux_boot_script",
+		BootScriptWindowsFilePath: "windows_boot_script",
+	}
+
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{Type: tea.KeyEnter},
+		},
+	}
+
+	// The Linux boot script must not become the boot script for all platforms
+	answer, err := question.AskConfirmationForm(testEC2, testQMHelper, testFormConfig, testDetailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, cli.ResponseYes, answer)
+	th.Equals(t, "", testFormConfig.BootScriptFilePath)
+	th.Equals(t, "linux_boot_script", testFormConfig.BootScriptLinuxFilePath)
+	th.Equals(t, "windows_boot_script", testFormConfig.BootScriptWindowsFilePath)
+}
+
+func TestAskConfirmationForm_EditInPlaceInvalid(t *testing.T) {
+	testFormConfig := &config.SimpleInfo{
+		Region:                      "us-east-1",
+		ImageId:                     "ami-12345",
+		InstanceType:                ec2.InstanceTypeT2Micro,
+		SubnetId:                    "subnet-12345",
+		AutoTerminationTimerMinutes: 30,
+	}
+
+	// An invalid value is rejected and the edit is abandoned
+	userInputs := []tea.Msg{}
+	for i := 0; i < 6; i++ {
+		userInputs = append(userInputs, tea.KeyMsg{Type: tea.KeyUp})
+	}
+	userInputs = append(userInputs,
+		tea.KeyMsg{Type: tea.KeyEnter},
+		tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("abc")},
+		tea.KeyMsg{Type: tea.KeyEnter},
+		tea.KeyMsg{Type: tea.KeyEsc},
+	)
+	for i := 0; i < 6; i++ {
+		userInputs = append(userInputs, tea.KeyMsg{Type: tea.KeyDown})
+	}
+	userInputs = append(userInputs, tea.KeyMsg{Type: tea.KeyEnter})
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: userInputs,
+	}
+
+	answer, err := question.AskConfirmationForm(testEC2, testQMHelper, testFormConfig, testDetailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, cli.ResponseYes, answer)
+	th.Equals(t, 30, testFormConfig.AutoTerminationTimerMinutes)
+}
+
+/*
+GetLaunchPreview Tests
+*/
+
+func TestGetLaunchPreview_Success(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{}
+	testEC2.Svc = mockedSvc
+
+	preview := question.GetLaunchPreview(testEC2, testSimpleConfig, testDetailedConfig)
+	th.Equals(t, 0, mockedSvc.RunInstancesCalls)
+	th.Assert(t, strings.Contains(preview, testSimpleConfig.InstanceType), "The preview should include the instance type")
+	th.Assert(t, strings.Contains(preview, testSimpleConfig.ImageId), "The preview should include the image")
+	th.Assert(t, strings.Contains(preview, testSimpleConfig.SubnetId), "The preview should include the subnet")
+	th.Assert(t, strings.Contains(preview, "Estimated On-Demand Price"), "The preview should include the cost")
+	th.Assert(t, strings.Contains(preview, "Passed"), "The dry run should pass")
+}
+
+func TestGetLaunchPreview_DryRunFailed(t *testing.T) {
+	mockedSvc := &th.MockedEC2Svc{
+		RunInstancesError: awserr.New("UnauthorizedOperation",
+			"You are not authorized to perform this operation.", nil),
+	}
+	testEC2.Svc = mockedSvc
+
+	// Earlier tests may have changed the shared config to Spot, which dry runs a fleet instead
+	simpleConfig := *testSimpleConfig
+	simpleConfig.CapacityType = question.DefaultCapacityTypeText.OnDemand
+
+	preview := question.GetLaunchPreview(testEC2, &simpleConfig, testDetailedConfig)
+	th.Equals(t, 0, mockedSvc.RunInstancesCalls)
+	th.Assert(t, strings.Contains(preview, "UnauthorizedOperation"), "The preview should include the permission issue")
+}
+
+func TestGetLaunchPreview_SpotDryRunFailed(t *testing.T) {
+	simpleConfig := *testSimpleConfig
+	simpleConfig.CapacityType = question.DefaultCapacityTypeText.Spot
+	mockedSvc := &th.MockedEC2Svc{
+		CreateFleetError: awserr.New("UnauthorizedOperation",
+			"You are not authorized to perform this operation.", nil),
+	}
+	testEC2.Svc = mockedSvc
+
+	preview := question.GetLaunchPreview(testEC2, &simpleConfig, testDetailedConfig)
+	th.Equals(t, 0, len(mockedSvc.RunInstancesInputs))
+	th.Assert(t, strings.Contains(preview, "UnauthorizedOperation"), "The preview should include the permission issue")
+}
+
+func TestGetLaunchPreview_StorageCost(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{}
+	simpleConfig := *testSimpleConfig
+	simpleConfig.InstanceCount = 2
+	detailedConfig := *testDetailedConfig
+	image := *testDetailedConfig.Image
+	image.RootDeviceName = aws.String("device 1")
+	detailedConfig.Image = &image
+
+	// Two root volumes of 10 GiB of gp2
+	preview := question.GetLaunchPreview(testEC2, &simpleConfig, &detailedConfig)
+	th.Assert(t, strings.Contains(preview, "$2.00/month for 10 GiB of gp2"),
+		"The preview should include the storage cost of the root volumes")
+
+	// The volume flags replace the size and type of the image
+	simpleConfig.RootVolumeSize = 100
+	simpleConfig.RootVolumeType = ec2.VolumeTypeGp3
+	preview = question.GetLaunchPreview(testEC2, &simpleConfig, &detailedConfig)
+	th.Assert(t, strings.Contains(preview, "$16.00/month for 100 GiB of gp3"),
+		"The preview should include the storage cost of the changed root volumes")
+}
+
+func TestGetLaunchPreview_PricingUnsupported(t *testing.T) {
+	pricing := &th.MockedPricing{PricingError: errors.New("Test error")}
+	mockPricingClient(t, pricing)
+	testEC2.Svc = &th.MockedEC2Svc{}
+	simpleConfig := *testSimpleConfig
+	simpleConfig.Region = "us-gov-west-1"
+	detailedConfig := *testDetailedConfig
+	image := *testDetailedConfig.Image
+	image.RootDeviceName = aws.String("device 1")
+	detailedConfig.Image = &image
+
+	// The costs are N/A without any lookup, and the rest of the preview is still built
+	preview := question.GetLaunchPreview(testEC2, &simpleConfig, &detailedConfig)
+	th.Equals(t, 0, pricing.LookupCalls)
+	th.Assert(t, !strings.Contains(preview, "/month"), "The storage cost should be N/A")
+	th.Assert(t, !strings.Contains(preview, "/hr"), "The prices should be N/A")
+	th.Assert(t, strings.Contains(preview, "Passed"), "The dry run should pass")
+}
+
+func TestGetLaunchPreview_StorageCostUnknown(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{}
+
+	// The image of the test config has no EBS root volume
+	preview := question.GetLaunchPreview(testEC2, testSimpleConfig, testDetailedConfig)
+	th.Assert(t, strings.Contains(preview, "Estimated Storage Cost"), "The preview should include the storage cost")
+	th.Assert(t, !strings.Contains(preview, "/month"), "The storage cost should be N/A")
+}
+
+/*
+AskUserData Tests
+*/
+
+func TestAskUserData_Edit(t *testing.T) {
+	const testUserData = "#!/bin/bash\necho hello\n"
+
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("echo edited")},
+			tea.KeyMsg{Type: tea.KeyCtrlS},
+		},
+	}
+
+	userData, saved, err := question.AskUserData(testQMHelper, testUserData)
+	th.Ok(t, err)
+	th.Equals(t, true, saved)
+	th.Equals(t, testUserData+"echo edited", userData)
+}
+
+func TestAskUserData_Discard(t *testing.T) {
+	const testUserData = "#!/bin/bash\necho hello\n"
+
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("echo edited")},
+			tea.KeyMsg{Type: tea.KeyEsc},
+		},
+	}
+
+	userData, saved, err := question.AskUserData(testQMHelper, testUserData)
+	th.Ok(t, err)
+	th.Equals(t, false, saved)
+	th.Equals(t, testUserData, userData)
+}
+
+func TestAskConfirmationForm_DispatchUserData(t *testing.T) {
+	testFormConfig := &config.SimpleInfo{
+		Region:       "us-east-1",
+		ImageId:      "ami-12345",
+		InstanceType: ec2.InstanceTypeT2Micro,
+		SubnetId:     "subnet-12345",
+	}
+
+	// The user data is right above the user tags
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{Type: tea.KeyUp},
+			tea.KeyMsg{Type: tea.KeyUp},
+			tea.KeyMsg{Type: tea.KeyEnter},
+		},
+	}
+
+	answer, err := question.AskConfirmationForm(testEC2, testQMHelper, testFormConfig, testDetailedConfig)
+	th.Ok(t, err)
+	th.Equals(t, cli.ResourceUserData, answer)
+}
+
+func TestAskSaveConfig(t *testing.T) {
+	const expectedAnswer = cli.ResponseYes
+
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyUp,
+			},
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+
+	answer, err := question.AskSaveConfig(testQMHelper)
+	th.Equals(t, expectedAnswer, answer)
+
+	th.Ok(t, err)
+}
+
+func TestIsSaveConfigRequired_Ask(t *testing.T) {
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyUp,
+			},
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+
+	isSaveRequired, err := question.IsSaveConfigRequired(testQMHelper, false, true)
+	th.Ok(t, err)
+	th.Equals(t, true, isSaveRequired)
+}
+
+func TestIsSaveConfigRequired_NoSavePrompt(t *testing.T) {
+	mockedQMHelperSvc := &th.MockedQMHelperSvc{}
+	testQMHelper.Svc = mockedQMHelperSvc
+
+	isSaveRequired, err := question.IsSaveConfigRequired(testQMHelper, false, false)
+	th.Ok(t, err)
+	th.Equals(t, false, isSaveRequired)
+	th.Equals(t, 0, len(mockedQMHelperSvc.QuestionInputs))
+}
+
+func TestIsSaveConfigRequired_SaveRequested(t *testing.T) {
+	mockedQMHelperSvc := &th.MockedQMHelperSvc{}
+	testQMHelper.Svc = mockedQMHelperSvc
+
+	isSaveRequired, err := question.IsSaveConfigRequired(testQMHelper, true, false)
+	th.Ok(t, err)
+	th.Equals(t, true, isSaveRequired)
+	th.Equals(t, 0, len(mockedQMHelperSvc.QuestionInputs))
+}
+
+func TestAskInstanceId_Success(t *testing.T) {
+	const expectedInstance = "i-12345"
+
+	testEC2.Svc = &th.MockedEC2Svc{
+		Instances: []*ec2.Instance{
+			{
+				InstanceId: aws.String(expectedInstance),
+			},
+			{
+				InstanceId: aws.String("i-67890"),
+			},
+		},
+	}
+
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+
+	answer, err := question.AskInstanceId(testEC2, testQMHelper)
 	th.Ok(t, err)
 	th.Equals(t, expectedInstance, *answer)
 }
@@ -1070,6 +1966,33 @@ func TestAskInstanceId_DescribeInstancesPagesError(t *testing.T) {
 	th.Nok(t, err)
 }
 
+func TestAskRecentConnection_Success(t *testing.T) {
+	entries := []config.ConnectHistoryEntry{
+		{InstanceId: "i-12345", Region: "us-east-1", Alias: "web", ConnectedAt: time.Now()},
+		{InstanceId: "i-67890", Region: "us-west-2", ConnectedAt: time.Now().Add(-time.Hour)},
+	}
+
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyDown,
+			},
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+
+	answer, err := question.AskRecentConnection(testQMHelper, entries)
+	th.Ok(t, err)
+	th.Equals(t, entries[1], *answer)
+}
+
+func TestAskRecentConnection_NoEntry(t *testing.T) {
+	_, err := question.AskRecentConnection(testQMHelper, []config.ConnectHistoryEntry{})
+	th.Nok(t, err)
+}
+
 func TestAskInstanceIds_Success(t *testing.T) {
 	expectedInstances := []string{"i-12345"}
 
@@ -1094,7 +2017,7 @@ func TestAskInstanceIds_Success(t *testing.T) {
 
 	addedInstances := []string{"i-67890"}
 
-	answer, err := question.AskInstanceIds(testEC2, testQMHelper, addedInstances)
+	answer, err := question.AskInstanceIds(testEC2, testQMHelper, addedInstances, nil)
 	th.Ok(t, err)
 	th.Equals(t, expectedInstances, answer)
 }
@@ -1111,7 +2034,83 @@ func TestAskInstanceIds_NoInstance(t *testing.T) {
 		},
 	}
 
-	_, err := question.AskInstanceIds(testEC2, testQMHelper, addedInstances)
+	_, err := question.AskInstanceIds(testEC2, testQMHelper, addedInstances, nil)
+	th.Nok(t, err)
+}
+
+func TestAskInstanceIds_FilterTags(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		Instances: []*ec2.Instance{
+			{
+				InstanceId: aws.String("i-12345"),
+				Tags:       []*ec2.Tag{{Key: aws.String("Environment"), Value: aws.String("prod")}},
+			},
+			{
+				InstanceId: aws.String("i-67890"),
+				Tags:       []*ec2.Tag{{Key: aws.String("Environment"), Value: aws.String("scratch")}},
+			},
+		},
+	}
+
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+
+	filters := []*ec2.Filter{{Name: aws.String("tag:Environment"), Values: aws.StringSlice([]string{"scratch"})}}
+
+	answer, err := question.AskInstanceIds(testEC2, testQMHelper, []string{}, filters)
+	th.Ok(t, err)
+	th.Equals(t, []string{"i-67890"}, answer)
+}
+
+func TestAskInstanceIds_FilterTagsNoInstance(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		Instances: []*ec2.Instance{
+			{
+				InstanceId: aws.String("i-12345"),
+				Tags:       []*ec2.Tag{{Key: aws.String("Environment"), Value: aws.String("prod")}},
+			},
+		},
+	}
+
+	filters := []*ec2.Filter{{Name: aws.String("tag:Environment"), Values: aws.StringSlice([]string{"scratch"})}}
+
+	_, err := question.AskInstanceIds(testEC2, testQMHelper, []string{}, filters)
+	th.Nok(t, err)
+	th.Equals(t, "No instance with the filter tags available in selected region for termination", err.Error())
+}
+
+func TestAskStoppedInstanceIds_Success(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{
+		Instances: []*ec2.Instance{
+			{
+				InstanceId: aws.String("i-12345"),
+				State:      &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameStopped)},
+			},
+		},
+	}
+
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+
+	answer, err := question.AskStoppedInstanceIds(testEC2, testQMHelper)
+	th.Ok(t, err)
+	th.Equals(t, []string{"i-12345"}, answer)
+}
+
+func TestAskStoppedInstanceIds_NoInstance(t *testing.T) {
+	testEC2.Svc = &th.MockedEC2Svc{}
+
+	_, err := question.AskStoppedInstanceIds(testEC2, testQMHelper)
 	th.Nok(t, err)
 }
 
@@ -1129,10 +2128,27 @@ func TestAskInstanceIds_DescribeInstancesPagesError(t *testing.T) {
 		},
 	}
 
-	_, err := question.AskInstanceIds(testEC2, testQMHelper, addedInstances)
+	_, err := question.AskInstanceIds(testEC2, testQMHelper, addedInstances, nil)
 	th.Nok(t, err)
 }
 
+func TestAskTerminationConfirmation_DefaultsToNo(t *testing.T) {
+	mockedQMHelperSvc := &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+	testQMHelper.Svc = mockedQMHelperSvc
+
+	isConfirmed, err := question.AskTerminationConfirmation(testQMHelper, []string{"i-12345"})
+	th.Ok(t, err)
+	th.Assert(t, !isConfirmed, "The termination should not be confirmed by default")
+	th.Assert(t, strings.HasPrefix(mockedQMHelperSvc.QuestionInputs[0].QuestionString,
+		"1 instance(s) will be terminated: [i-12345]."), "The question should summarize the termination")
+}
+
 /*
 Instance Selector Question Tests
 */
@@ -1178,7 +2194,7 @@ func TestAskInstanceTypeInstanceSelector_Success(t *testing.T) {
 		},
 	}
 
-	answer, err := question.AskInstanceTypeInstanceSelector(testEC2, testQMHelper, testSelector, "2", "4")
+	answer, err := question.AskInstanceTypeInstanceSelector(testEC2, testQMHelper, testSelector, "2", "4", true)
 	th.Ok(t, err)
 	th.Equals(t, testInstanceType, *answer)
 }
@@ -1192,7 +2208,7 @@ func TestAskInstanceTypeInstanceSelector_BadVcpus(t *testing.T) {
 		},
 	}
 
-	_, err := question.AskInstanceTypeInstanceSelector(testEC2, testQMHelper, testSelector, "a", "4")
+	_, err := question.AskInstanceTypeInstanceSelector(testEC2, testQMHelper, testSelector, "a", "4", true)
 	th.Nok(t, err)
 }
 
@@ -1205,7 +2221,7 @@ func TestAskInstanceTypeInstanceSelector_BadMemory(t *testing.T) {
 		},
 	}
 
-	_, err := question.AskInstanceTypeInstanceSelector(testEC2, testQMHelper, testSelector, "2", "a")
+	_, err := question.AskInstanceTypeInstanceSelector(testEC2, testQMHelper, testSelector, "2", "a", true)
 	th.Nok(t, err)
 }
 
@@ -1222,7 +2238,7 @@ func TestAskInstanceTypeInstanceSelector_NoResult(t *testing.T) {
 		},
 	}
 
-	_, err := question.AskInstanceTypeInstanceSelector(testEC2, testQMHelper, testSelector, "2", "4")
+	_, err := question.AskInstanceTypeInstanceSelector(testEC2, testQMHelper, testSelector, "2", "4", true)
 	th.Nok(t, err)
 }
 
@@ -1240,10 +2256,254 @@ func TestAskInstanceTypeInstanceSelector_SelectorError(t *testing.T) {
 		},
 	}
 
-	_, err := question.AskInstanceTypeInstanceSelector(testEC2, testQMHelper, testSelector, "2", "4")
+	_, err := question.AskInstanceTypeInstanceSelector(testEC2, testQMHelper, testSelector, "2", "4", true)
 	th.Nok(t, err)
 }
 
+func TestAskInstanceTypeInstanceSelector_NoCompatibleImage(t *testing.T) {
+	const expectedInstanceType = "t3.micro"
+
+	testEC2 = ec2helper.New(session.Must(session.NewSession()))
+	testEC2.Svc = &th.MockedEC2Svc{
+		Images: []*ec2.Image{
+			{
+				ImageId:      aws.String("ami-12345"),
+				CreationDate: aws.String("some time"),
+				Architecture: aws.String("x86_64"),
+			},
+		},
+	}
+	testSelector = &th.MockedSelector{
+		InstanceTypes: []*instancetypes.Details{
+			{
+				InstanceTypeInfo: ec2.InstanceTypeInfo{
+					InstanceType:             aws.String("t4g.micro"),
+					VCpuInfo:                 &ec2.VCpuInfo{DefaultVCpus: aws.Int64(2)},
+					MemoryInfo:               &ec2.MemoryInfo{SizeInMiB: aws.Int64(1024)},
+					InstanceStorageSupported: aws.Bool(false),
+					ProcessorInfo:            &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"arm64"})},
+				},
+			},
+			{
+				InstanceTypeInfo: ec2.InstanceTypeInfo{
+					InstanceType:             aws.String(expectedInstanceType),
+					VCpuInfo:                 &ec2.VCpuInfo{DefaultVCpus: aws.Int64(2)},
+					MemoryInfo:               &ec2.MemoryInfo{SizeInMiB: aws.Int64(1024)},
+					InstanceStorageSupported: aws.Bool(false),
+					ProcessorInfo:            &ec2.ProcessorInfo{SupportedArchitectures: defaultArchitecture},
+				},
+			},
+		},
+	}
+
+	// The same input selects the first option each time the question is asked
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+
+	answer, err := question.AskInstanceTypeInstanceSelector(testEC2, testQMHelper, testSelector, "2", "1", true)
+	th.Ok(t, err)
+	th.Equals(t, expectedInstanceType, *answer)
+}
+
+func TestAskInstanceTypeBurstable(t *testing.T) {
+	// Burstable instance types are allowed by default
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+
+	answer, err := question.AskInstanceTypeBurstable(testQMHelper)
+	th.Ok(t, err)
+	th.Equals(t, true, answer)
+
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyDown,
+			},
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+
+	answer, err = question.AskInstanceTypeBurstable(testQMHelper)
+	th.Ok(t, err)
+	th.Equals(t, false, answer)
+}
+
+func TestAskInstanceTypeInstanceSelector_NotBurstable(t *testing.T) {
+	const expectedInstanceType = "m5.large"
+
+	testEC2 = ec2helper.New(session.Must(session.NewSession()))
+	testEC2.Svc = &th.MockedEC2Svc{
+		Images: []*ec2.Image{
+			{
+				ImageId:      aws.String("ami-12345"),
+				CreationDate: aws.String("some time"),
+				Architecture: aws.String("x86_64"),
+			},
+		},
+	}
+	mockedSelector := &th.MockedSelector{
+		InstanceTypes: []*instancetypes.Details{
+			{
+				InstanceTypeInfo: ec2.InstanceTypeInfo{
+					InstanceType:             aws.String("t3.large"),
+					VCpuInfo:                 &ec2.VCpuInfo{DefaultVCpus: aws.Int64(2)},
+					MemoryInfo:               &ec2.MemoryInfo{SizeInMiB: aws.Int64(8192)},
+					InstanceStorageSupported: aws.Bool(false),
+					ProcessorInfo:            &ec2.ProcessorInfo{SupportedArchitectures: defaultArchitecture},
+				},
+			},
+			{
+				InstanceTypeInfo: ec2.InstanceTypeInfo{
+					InstanceType:             aws.String(expectedInstanceType),
+					VCpuInfo:                 &ec2.VCpuInfo{DefaultVCpus: aws.Int64(2)},
+					MemoryInfo:               &ec2.MemoryInfo{SizeInMiB: aws.Int64(8192)},
+					InstanceStorageSupported: aws.Bool(false),
+					ProcessorInfo:            &ec2.ProcessorInfo{SupportedArchitectures: defaultArchitecture},
+				},
+			},
+		},
+	}
+
+	// The first option is selected, which is no longer the t type
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+
+	answer, err := question.AskInstanceTypeInstanceSelector(testEC2, testQMHelper, mockedSelector, "2", "8", false)
+	th.Ok(t, err)
+	th.Equals(t, expectedInstanceType, *answer)
+	th.Equals(t, false, *mockedSelector.LastFilters.Burstable)
+}
+
+func TestAskInstanceTypeFromRequirements_NoCompatibleImage(t *testing.T) {
+	const expectedInstanceType = "t3.micro"
+
+	testEC2 = ec2helper.New(session.Must(session.NewSession()))
+	testEC2.Svc = &th.MockedEC2Svc{
+		Images: []*ec2.Image{
+			{
+				ImageId:      aws.String("ami-12345"),
+				CreationDate: aws.String("some time"),
+				Architecture: aws.String("x86_64"),
+			},
+		},
+	}
+
+	// No image is compatible with the only option for the first requirements, so the requirements are asked again
+	mockedSelector := &th.MockedSelector{
+		InstanceTypesPerCall: [][]*instancetypes.Details{
+			{
+				{
+					InstanceTypeInfo: ec2.InstanceTypeInfo{
+						InstanceType:             aws.String("t4g.micro"),
+						VCpuInfo:                 &ec2.VCpuInfo{DefaultVCpus: aws.Int64(2)},
+						MemoryInfo:               &ec2.MemoryInfo{SizeInMiB: aws.Int64(1024)},
+						InstanceStorageSupported: aws.Bool(false),
+						ProcessorInfo: &ec2.ProcessorInfo{
+							SupportedArchitectures: aws.StringSlice([]string{"arm64"}),
+						},
+					},
+				},
+			},
+		},
+		InstanceTypes: []*instancetypes.Details{
+			{
+				InstanceTypeInfo: ec2.InstanceTypeInfo{
+					InstanceType:             aws.String(expectedInstanceType),
+					VCpuInfo:                 &ec2.VCpuInfo{DefaultVCpus: aws.Int64(2)},
+					MemoryInfo:               &ec2.MemoryInfo{SizeInMiB: aws.Int64(1024)},
+					InstanceStorageSupported: aws.Bool(false),
+					ProcessorInfo:            &ec2.ProcessorInfo{SupportedArchitectures: defaultArchitecture},
+				},
+			},
+		},
+	}
+
+	// The same input accepts the default vCPUs and memory, and selects the first option
+	mockedQMHelperSvc := &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+	testQMHelper.Svc = mockedQMHelperSvc
+
+	answer, err := question.AskInstanceTypeFromRequirements(testEC2, testQMHelper, mockedSelector)
+	th.Ok(t, err)
+	th.Equals(t, expectedInstanceType, *answer)
+	th.Equals(t, 2, mockedSelector.FilterVerboseCalls)
+
+	vcpuQuestions := 0
+	for _, questionInput := range mockedQMHelperSvc.QuestionInputs {
+		if strings.Contains(questionInput.QuestionString, "vCPUs") {
+			vcpuQuestions++
+		}
+	}
+	th.Equals(t, 2, vcpuQuestions)
+}
+
+func TestAskInstanceTypes_NoCompatibleImage(t *testing.T) {
+	testEC2 = ec2helper.New(session.Must(session.NewSession()))
+	testEC2.Svc = &th.MockedEC2Svc{
+		Images: []*ec2.Image{
+			{
+				ImageId:      aws.String("ami-12345"),
+				CreationDate: aws.String("some time"),
+				Architecture: aws.String("x86_64"),
+			},
+		},
+		InstanceTypes: []*ec2.InstanceTypeInfo{
+			{
+				InstanceType:  aws.String("t4g.micro"),
+				ProcessorInfo: &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"arm64"})},
+			},
+			{
+				InstanceType:  aws.String("t3.micro"),
+				ProcessorInfo: &ec2.ProcessorInfo{SupportedArchitectures: defaultArchitecture},
+			},
+			{
+				InstanceType:  aws.String("m5.large"),
+				ProcessorInfo: &ec2.ProcessorInfo{SupportedArchitectures: defaultArchitecture},
+			},
+		},
+	}
+
+	// Select every option, in reverse order
+	testQMHelper.Svc = &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{Type: tea.KeyDown},
+			tea.KeyMsg{Type: tea.KeyDown},
+			tea.KeyMsg{Type: tea.KeyEnter},
+			tea.KeyMsg{Type: tea.KeyUp},
+			tea.KeyMsg{Type: tea.KeyEnter},
+			tea.KeyMsg{Type: tea.KeyUp},
+			tea.KeyMsg{Type: tea.KeyEnter},
+		},
+	}
+
+	// The instance types are kept in the order of the options, without the one lacking a compatible AMI
+	answer, err := question.AskInstanceTypes(testEC2, testQMHelper, nil)
+	th.Ok(t, err)
+	th.Equals(t, []string{"t3.micro", "m5.large"}, answer)
+}
+
 func TestAskIamProfile_Success(t *testing.T) {
 	expectedProfileName := "profile2"
 	testProfiles := []*iam.InstanceProfile{
@@ -1305,6 +2565,40 @@ func TestAskIamProfile_Error(t *testing.T) {
 	th.Nok(t, err)
 }
 
+func TestGetCapacityTypeFromFlags_SpotShorthand(t *testing.T) {
+	capacityType, err := question.GetCapacityTypeFromFlags("", true)
+	th.Ok(t, err)
+	th.Equals(t, question.DefaultCapacityTypeText.Spot, capacityType)
+
+	capacityType, err = question.GetCapacityTypeFromFlags("spot", true)
+	th.Ok(t, err)
+	th.Equals(t, question.DefaultCapacityTypeText.Spot, capacityType)
+}
+
+func TestGetCapacityTypeFromFlags_SpotShorthandOnDemandConflict(t *testing.T) {
+	_, err := question.GetCapacityTypeFromFlags("On-Demand", true)
+	th.Nok(t, err)
+}
+
+func TestGetCapacityTypeFromFlags_CapacityType(t *testing.T) {
+	capacityType, err := question.GetCapacityTypeFromFlags("on-demand", false)
+	th.Ok(t, err)
+	th.Equals(t, question.DefaultCapacityTypeText.OnDemand, capacityType)
+
+	capacityType, err = question.GetCapacityTypeFromFlags("", false)
+	th.Ok(t, err)
+	th.Equals(t, "", capacityType)
+
+	_, err = question.GetCapacityTypeFromFlags("Reserved", false)
+	th.Nok(t, err)
+}
+
+func TestGetCapacityTypeFromFlags_Suggestion(t *testing.T) {
+	_, err := question.GetCapacityTypeFromFlags("Spto", false)
+	th.Nok(t, err)
+	th.Assert(t, strings.HasSuffix(err.Error(), "Did you mean \"Spot\"?"), "No suggestion in: "+err.Error())
+}
+
 func TestAskCapacityType(t *testing.T) {
 	testRegion := "us-east-1"
 	expectedCapacity := question.DefaultCapacityTypeText.Spot
@@ -1786,6 +3080,14 @@ func TestAskSubnet_WithDefault(t *testing.T) {
 				AvailabilityZone: aws.String("some az"),
 			},
 		},
+		AvailabilityZones: []*ec2.AvailabilityZone{
+			{
+				ZoneName: aws.String("some az"),
+			},
+			{
+				ZoneName: aws.String("some other az"),
+			},
+		},
 	}
 
 	testQMHelper.Svc = &th.MockedQMHelperSvc{
@@ -1952,3 +3254,167 @@ func TestAskCapacityType_WithDefault(t *testing.T) {
 
 	th.Ok(t, err)
 }
+
+// Replace the pricing client with a mock, restored when the test ends
+func mockPricingClient(t *testing.T, pricing *th.MockedPricing) {
+	newPricingClient := question.NewPricingClient
+	question.NewPricingClient = func(region string) question.InstancePricer {
+		return pricing
+	}
+	t.Cleanup(func() {
+		question.NewPricingClient = newPricingClient
+	})
+}
+
+func TestIsPricingSupported(t *testing.T) {
+	th.Equals(t, true, question.IsPricingSupported("us-east-1"))
+	th.Equals(t, false, question.IsPricingSupported("cn-north-1"))
+	th.Equals(t, false, question.IsPricingSupported("us-gov-west-1"))
+}
+
+func TestAskCapacityType_Prices(t *testing.T) {
+	mockPricingClient(t, &th.MockedPricing{OnDemandPrice: 0.0104, SpotPrice: 0.0031})
+	mockedSvc := &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+	testQMHelper.Svc = mockedSvc
+
+	answer, err := question.AskCapacityType(testQMHelper, testInstanceType, "us-east-1", "")
+	th.Ok(t, err)
+	th.Equals(t, question.DefaultCapacityTypeText.OnDemand, answer)
+	th.Equals(t, []string{"Capacity Type", "Price"}, mockedSvc.QuestionInputs[0].HeaderStrings)
+	th.Equals(t, questionModel.CreateSingleLineRows([][]string{
+		{question.DefaultCapacityTypeText.OnDemand, "$0.0104/hr"},
+		{question.DefaultCapacityTypeText.Spot, "$0.0031/hr"},
+	}), mockedSvc.QuestionInputs[0].Rows)
+}
+
+func TestAskCapacityType_PricingError(t *testing.T) {
+	mockPricingClient(t, &th.MockedPricing{PricingError: errors.New("Test error")})
+	mockedSvc := &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyDown,
+			},
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+	testQMHelper.Svc = mockedSvc
+
+	// The prices are N/A, but the capacity type is still asked
+	answer, err := question.AskCapacityType(testQMHelper, testInstanceType, "us-east-1", "")
+	th.Ok(t, err)
+	th.Equals(t, question.DefaultCapacityTypeText.Spot, answer)
+	th.Equals(t, questionModel.CreateSingleLineRows([][]string{
+		{question.DefaultCapacityTypeText.OnDemand, "N/A"},
+		{question.DefaultCapacityTypeText.Spot, "N/A"},
+	}), mockedSvc.QuestionInputs[0].Rows)
+}
+
+func newCandidateInstanceTypes(instanceTypes ...string) []*instancetypes.Details {
+	candidates := []*instancetypes.Details{}
+	for _, instanceType := range instanceTypes {
+		candidates = append(candidates, &instancetypes.Details{
+			InstanceTypeInfo: ec2.InstanceTypeInfo{InstanceType: aws.String(instanceType)},
+		})
+	}
+	return candidates
+}
+
+func TestGetCheapestInstanceType_OnDemand(t *testing.T) {
+	mockPricingClient(t, &th.MockedPricing{
+		OnDemandPrices: map[string]float64{"m5.xlarge": 0.192, "m6i.xlarge": 0.192, "c5.xlarge": 0.17},
+		SpotPrices:     map[string]float64{"m5.xlarge": 0.05},
+	})
+	candidates := newCandidateInstanceTypes("m5.xlarge", "c5.xlarge", "m6i.xlarge")
+
+	instanceType, price, err := question.GetCheapestInstanceType(candidates, "us-east-1", false)
+	th.Ok(t, err)
+	th.Equals(t, candidates[1], instanceType)
+	th.Equals(t, 0.17, price)
+}
+
+func TestGetCheapestInstanceType_Spot(t *testing.T) {
+	mockPricingClient(t, &th.MockedPricing{
+		OnDemandPrices: map[string]float64{"m5.xlarge": 0.192, "c5.xlarge": 0.17},
+		SpotPrices:     map[string]float64{"m5.xlarge": 0.05, "c5.xlarge": 0.06},
+	})
+	candidates := newCandidateInstanceTypes("m5.xlarge", "c5.xlarge")
+
+	instanceType, price, err := question.GetCheapestInstanceType(candidates, "us-east-1", true)
+	th.Ok(t, err)
+	th.Equals(t, candidates[0], instanceType)
+	th.Equals(t, 0.05, price)
+}
+
+func TestGetCheapestInstanceType_Tie(t *testing.T) {
+	mockPricingClient(t, &th.MockedPricing{OnDemandPrices: map[string]float64{"m5.xlarge": 0.192, "m6i.xlarge": 0.192}})
+	candidates := newCandidateInstanceTypes("m6i.xlarge", "m5.xlarge")
+
+	// The first instance type wins a tie
+	instanceType, _, err := question.GetCheapestInstanceType(candidates, "us-east-1", false)
+	th.Ok(t, err)
+	th.Equals(t, candidates[0], instanceType)
+}
+
+func TestGetCheapestInstanceType_MissingPrice(t *testing.T) {
+	mockPricingClient(t, &th.MockedPricing{OnDemandPrices: map[string]float64{"m5.xlarge": 0.192}})
+	candidates := newCandidateInstanceTypes("c7a.xlarge", "m5.xlarge")
+
+	// Instance types without a price are skipped
+	instanceType, _, err := question.GetCheapestInstanceType(candidates, "us-east-1", false)
+	th.Ok(t, err)
+	th.Equals(t, candidates[1], instanceType)
+}
+
+func TestGetCheapestInstanceType_NoPrice(t *testing.T) {
+	mockPricingClient(t, &th.MockedPricing{PricingError: errors.New("Test error")})
+
+	_, _, err := question.GetCheapestInstanceType(newCandidateInstanceTypes("m5.xlarge"), "us-east-1", false)
+	th.Nok(t, err)
+}
+
+func TestGetCheapestInstanceType_PricingUnsupported(t *testing.T) {
+	pricing := &th.MockedPricing{OnDemandPrices: map[string]float64{"m5.xlarge": 0.192}}
+	mockPricingClient(t, pricing)
+
+	_, _, err := question.GetCheapestInstanceType(newCandidateInstanceTypes("m5.xlarge"), "cn-north-1", false)
+	th.Nok(t, err)
+	th.Equals(t, 0, pricing.LookupCalls)
+}
+
+func TestFormatHourlyPrice(t *testing.T) {
+	th.Equals(t, "$0.0104/hr", question.FormatHourlyPrice(0.0104))
+	th.Equals(t, "$0.1235/hr", question.FormatHourlyPrice(0.12345678))
+}
+
+func TestAskCapacityType_PricingUnsupported(t *testing.T) {
+	pricing := &th.MockedPricing{PricingError: errors.New("Test error")}
+	mockPricingClient(t, pricing)
+	mockedSvc := &th.MockedQMHelperSvc{
+		UserInputs: []tea.Msg{
+			tea.KeyMsg{
+				Type: tea.KeyDown,
+			},
+			tea.KeyMsg{
+				Type: tea.KeyEnter,
+			},
+		},
+	}
+	testQMHelper.Svc = mockedSvc
+
+	// Prices aren't looked up in China regions, and the question has no price column
+	answer, err := question.AskCapacityType(testQMHelper, testInstanceType, "cn-north-1", "")
+	th.Ok(t, err)
+	th.Equals(t, question.DefaultCapacityTypeText.Spot, answer)
+	th.Equals(t, 0, pricing.LookupCalls)
+	th.Equals(t, []string{"Capacity Type"}, mockedSvc.QuestionInputs[0].HeaderStrings)
+	th.Assert(t, strings.Contains(mockedSvc.QuestionInputs[0].QuestionString, "cn-north-1"),
+		"The question should tell that prices are not available in the region")
+}
@@ -26,11 +26,12 @@ is the yes/no option to confirm the launch of the instance.
 */
 type Confirmation struct {
 	lists      []SingleSelectList
-	choice     string // The chosen option
-	focusIndex int    // The index of the cursor
-	allowEdit  bool   // Whether the configurations list is selectable
-	errorMsg   string // An error message to be presented if a config is selected which cant be reconfigured
-	err        error  // An error caught during the question
+	choice     string       // The chosen option
+	focusIndex int          // The index of the cursor
+	allowEdit  bool         // Whether the configurations list is selectable
+	errorMsg   string       // An error message to be presented if a config is selected which cant be reconfigured
+	help       questionHelp // The help of the question, toggled with the help key
+	err        error        // An error caught during the question
 }
 
 // InitializeModel initializes the model based on the passed in question input
@@ -54,8 +55,12 @@ func (c *Confirmation) InitializeModel(input *QuestionInput) {
 		DefaultOption:  cli.ResponseNo,
 		Rows:           CreateSingleLineRows(yesNoData),
 	})
+	// The lists are parts of the confirmation, which has the help instead
+	configList.help = questionHelp{}
+	yesNoList.help = questionHelp{}
 	c.lists = append(c.lists, configList, yesNoList)
 	c.focusIndex = 1
+	c.help = newQuestionHelp(input, singleSelectKeys)
 }
 
 // Init defines an optional command that can be run when the question is asked.
@@ -118,6 +123,9 @@ func (c *Confirmation) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return c, tea.Quit
 			}
 		}
+		if c.help.toggle(msg) {
+			return c, nil
+		}
 
 	case error:
 		c.err = msg
@@ -142,6 +150,7 @@ func (c *Confirmation) View() string {
 	b.WriteString(c.lists[0].View())
 	b.WriteRune('\n')
 	b.WriteString(c.lists[1].View())
+	b.WriteString(c.help.view())
 	return b.String()
 }
 
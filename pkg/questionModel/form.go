@@ -0,0 +1,227 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package questionModel
+
+import (
+	"fmt"
+	"simple-ec2/pkg/cli"
+	"simple-ec2/pkg/ec2helper"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	formSubmitText = "Launch"
+	formCancelText = "Cancel"
+)
+
+// FormField represents a single configuration in a Form question
+type FormField struct {
+	Name    string       // The name of the configuration
+	Value   string       // The current value of the configuration
+	Option  string       // Returned as the choice when a field that can't be edited in place is selected
+	InPlace bool         // Whether the value can be edited in place with a text input
+	Fns     []CheckInput // List of functions to validate the value edited in place
+}
+
+/*
+Form represents a question in which the user reviews all configurations at once. Every configuration
+can be navigated to. Simple values are edited in place, while other configurations are handed back to the
+caller with their option, so the existing question for them can be asked. The form is submitted once with
+all in place edits applied.
+*/
+type Form struct {
+	fields     []FormField
+	question   string               // The question being asked
+	ec2Helper  *ec2helper.EC2Helper // EC2Helper to provide validation methods for values edited in place
+	focusIndex int                  // The index of the cursor. Fields come first, followed by submit and cancel
+	editing    bool                 // Whether the focused field is being edited in place
+	textInput  textinput.Model      // The text input used for editing in place
+	choice     string               // The chosen option
+	errorMsg   string               // An error message to be presented for invalid selections or input
+	help       questionHelp         // The help of the question, toggled with the help key
+	err        error                // An error caught during the question
+}
+
+// InitializeModel initializes the model based on the passed in question input
+func (f *Form) InitializeModel(input *QuestionInput) {
+	f.question = input.QuestionString
+	if f.question == "" {
+		f.question = "Please review the options below. Select a configuration to edit it, then launch the instance:"
+	}
+	f.fields = append([]FormField{}, input.FormFields...)
+	f.ec2Helper = input.EC2Helper
+	f.textInput = textinput.New()
+	f.focusIndex = len(f.fields)
+	f.help = newQuestionHelp(input, formKeys)
+}
+
+// Init defines an optional command that can be run when the question is asked.
+func (f *Form) Init() tea.Cmd {
+	return nil
+}
+
+/*
+Update is called when a message is received. Handles user input to traverse through the fields, edit
+values in place and submit the form.
+*/
+func (f *Form) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.Type == tea.KeyCtrlC {
+			f.err = exitError
+			return f, tea.Quit
+		}
+		if f.editing {
+			return f, f.updateEditing(msg)
+		}
+
+		switch msg.Type {
+		case tea.KeyUp:
+			if f.focusIndex > 0 {
+				f.focusIndex--
+			}
+
+		case tea.KeyDown:
+			if f.focusIndex < len(f.fields)+1 {
+				f.focusIndex++
+			}
+
+		case tea.KeyEnter:
+			f.errorMsg = ""
+			switch {
+			case f.focusIndex == len(f.fields):
+				f.choice = cli.ResponseYes
+				return f, tea.Quit
+			case f.focusIndex == len(f.fields)+1:
+				f.choice = cli.ResponseNo
+				return f, tea.Quit
+			case f.fields[f.focusIndex].InPlace:
+				f.editing = true
+				f.textInput.SetValue(f.fields[f.focusIndex].Value)
+				f.textInput.CursorEnd()
+				return f, f.textInput.Focus()
+			case f.fields[f.focusIndex].Option != "":
+				f.choice = f.fields[f.focusIndex].Option
+				return f, tea.Quit
+			default:
+				f.errorMsg = "This configuration can't be modified!"
+			}
+		}
+		if f.help.toggle(msg) {
+			return f, nil
+		}
+
+	case error:
+		f.err = msg
+		return f, tea.Quit
+	}
+
+	if f.editing {
+		f.textInput, cmd = f.textInput.Update(msg)
+	}
+	return f, cmd
+}
+
+// updateEditing handles user input while the focused field is edited in place
+func (f *Form) updateEditing(msg tea.KeyMsg) tea.Cmd {
+	var cmd tea.Cmd
+
+	switch msg.Type {
+	case tea.KeyEsc:
+		f.errorMsg = ""
+		f.editing = false
+		f.textInput.Blur()
+		return nil
+
+	case tea.KeyEnter:
+		value := f.textInput.Value()
+		if !f.isValidInput(value) {
+			f.errorMsg = fmt.Sprintf("%s is an invalid answer. Enter a valid answer.", value)
+			return nil
+		}
+		f.errorMsg = ""
+		f.fields[f.focusIndex].Value = value
+		f.editing = false
+		f.textInput.Blur()
+		return nil
+	}
+
+	f.textInput, cmd = f.textInput.Update(msg)
+	return cmd
+}
+
+// isValidInput determines whether a value edited in place is valid based on the field's validation functions
+func (f *Form) isValidInput(value string) bool {
+	fns := f.fields[f.focusIndex].Fns
+	if f.ec2Helper != nil && fns != nil {
+		for _, fn := range fns {
+			if fn(f.ec2Helper, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// View renders the view for the question. The view is rendered after every update
+func (f *Form) View() string {
+	nameWidth := 0
+	for _, field := range f.fields {
+		if len(field.Name) > nameWidth {
+			nameWidth = len(field.Name)
+		}
+	}
+
+	b := strings.Builder{}
+	b.WriteString(f.question + "\n\n")
+	if f.errorMsg != "" {
+		b.WriteString(smallLeftPadding.Copy().Inherit(errorStyle).Render(f.errorMsg) + "\n")
+	}
+	for index, field := range f.fields {
+		value := field.Value
+		if f.editing && index == f.focusIndex {
+			value = f.textInput.View()
+		}
+		line := fmt.Sprintf("%-*s %s %s", nameWidth, field.Name, columnSeperator, value)
+		b.WriteString(f.renderLine(line, index) + "\n")
+	}
+	b.WriteRune('\n')
+	b.WriteString(f.renderLine(formSubmitText, len(f.fields)) + "\n")
+	b.WriteString(f.renderLine(formCancelText, len(f.fields)+1) + "\n")
+	b.WriteString(f.help.view())
+	return b.String()
+}
+
+// renderLine renders a line of the form, highlighting it if it's focused
+func (f *Form) renderLine(line string, index int) string {
+	if index == f.focusIndex {
+		return smallLeftPadding.Render(">") + xSmallLeftPadding.Copy().Inherit(focused).Render(line)
+	}
+	return largeLeftPadding.Render(line)
+}
+
+// GetChoice gets the selected choice
+func (f *Form) GetChoice() string { return f.choice }
+
+// GetFields gets the fields with the values edited in place
+func (f *Form) GetFields() []FormField { return f.fields }
+
+// getError gets the error from the question if one arose
+func (f *Form) GetError() error { return f.err }
@@ -141,6 +141,16 @@ func (kv *KeyValue) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				kv.submitButtonFocused = !kv.submitButtonFocused
 			}
 
+		case tea.KeyCtrlX:
+			// Clear all tags, such as the defaults, and set the focus back to the first text input
+			kv.clearTags()
+			kv.focusIndex = 0
+			kv.submitButtonFocused = false
+			kv.inputs[1].Blur()
+			kv.inputs[1].PromptStyle = smallLeftPadding
+			kv.inputs[1].TextStyle = noStyle
+			return kv, kv.focusInput(kv.focusIndex)
+
 		case tea.KeyBackspace:
 			isDeleted := kv.deleteTag()
 			// If there are no more tags then set the focus back to the first text input
@@ -182,6 +192,8 @@ func (kv *KeyValue) View() string {
 	addButton := kv.createButton(addButtonText, !kv.submitButtonFocused)
 	submitButton := kv.createButton(submitButtonText, kv.submitButtonFocused)
 	b.WriteString(fmt.Sprintf(smallLeftPadding.Render("\n\n%s  %s\n"), addButton, submitButton))
+	b.WriteString(helpStyle.Render(fmt.Sprintf("%s • backspace: delete tag • ctrl+x: clear all tags",
+		formatTagCount(len(kv.tags)))) + "\n")
 
 	return b.String()
 }
@@ -209,6 +221,24 @@ func (kv *KeyValue) addTag() {
 	}
 }
 
+// clearTags deletes all tags in the tag list
+func (kv *KeyValue) clearTags() {
+	kv.tags = nil
+	kv.tagList.InitializeModel(&QuestionInput{
+		Rows:          CreateSingleLineRows(kv.tags),
+		HeaderStrings: tagHeaders,
+	})
+	kv.tagList.list.Select(-1)
+}
+
+// formatTagCount formats the number of tags in the tag list
+func formatTagCount(count int) string {
+	if count == 1 {
+		return "1 tag"
+	}
+	return fmt.Sprintf("%d tags", count)
+}
+
 // areButtonsFocused determines if one of the buttons are focused
 func (kv *KeyValue) areButtonsFocused() bool { return kv.focusIndex == len(kv.inputs) }
 
@@ -33,6 +33,7 @@ type MultiSelectList struct {
 	err             error           // An error caught during the question
 	displayErrorMsg bool            // If the error message should be displayed
 	errorMsg        string          // Error msg allerting the user they have to choose an option
+	help            questionHelp    // The help of the question, toggled with the help key
 }
 
 // InitializeModel initializes the model based on the passed in question input.
@@ -62,6 +63,7 @@ func (m *MultiSelectList) InitializeModel(input *QuestionInput) {
 	m.itemMap = itemMap
 	m.question = input.QuestionString
 	m.errorMsg = "Please choose at least one option"
+	m.help = newQuestionHelp(input, multiSelectKeys)
 
 	// Create selected map and select defaults
 	m.selected = make(map[int]item)
@@ -100,6 +102,9 @@ func (m *MultiSelectList) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			m.selectItem()
 		}
+		if m.help.toggle(msg) {
+			return m, nil
+		}
 
 	case error:
 		m.err = msg
@@ -126,6 +131,7 @@ func (m *MultiSelectList) View() string {
 		b.WriteString(xLargeLeftPadding.Render(m.header) + "\n")
 	}
 	b.WriteString(m.list.View())
+	b.WriteString(m.help.view())
 	return b.String()
 }
 
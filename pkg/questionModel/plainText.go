@@ -14,6 +14,7 @@ package questionModel
 
 import (
 	"fmt"
+	"simple-ec2/pkg/cli"
 	"simple-ec2/pkg/ec2helper"
 	"strings"
 
@@ -26,9 +27,11 @@ type PlainText struct {
 	textInput         textinput.Model      // The text input
 	question          string               // The question being asked
 	validFunctions    []CheckInput         // List of functions to validate the input
+	suggestions       []string             // List of valid answers to suggest from if the input is invalid
 	EC2Helper         *ec2helper.EC2Helper // EC2Helper to provide validation methods for text inputs
 	invalidMsg        string               // Message to display if input is invalid
 	displayInvalidMsg bool                 // If the invalid message should be displayed or not
+	help              questionHelp         // The help of the question, toggled with the help key
 	err               error                // An error caught during the question
 
 }
@@ -42,7 +45,9 @@ func (pt *PlainText) InitializeModel(input *QuestionInput) {
 	pt.textInput = ti
 	pt.question = input.QuestionString
 	pt.validFunctions = input.Fns
+	pt.suggestions = input.Suggestions
 	pt.EC2Helper = input.EC2Helper
+	pt.help = newQuestionHelp(input, plainTextKeys)
 }
 
 // Init defines an optional command that can be run when the question is asked.
@@ -80,6 +85,10 @@ func (pt *PlainText) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return pt, nil
 			}
 		}
+		// The help key is only toggled on an empty input, so it can still be typed in answers
+		if pt.textInput.Value() == "" && pt.help.toggle(msg) {
+			return pt, nil
+		}
 
 	case error:
 		pt.err = msg
@@ -97,9 +106,11 @@ func (pt *PlainText) View() string {
 		b.WriteString(pt.question + "\n\n")
 	}
 	if pt.displayInvalidMsg {
-		b.WriteString(smallLeftPadding.Copy().Inherit(errorStyle).Render(fmt.Sprintf("%s is an invalid answer. Enter a valid answer.", pt.invalidMsg)) + "\n")
+		b.WriteString(smallLeftPadding.Copy().Inherit(errorStyle).Render(fmt.Sprintf("%s is an invalid answer. Enter a valid answer.%s", pt.invalidMsg,
+			cli.DidYouMean(pt.invalidMsg, pt.suggestions))) + "\n")
 	}
 	b.WriteString(smallLeftPadding.Render(pt.textInput.View()) + "\n")
+	b.WriteString(pt.help.view())
 	return b.String()
 }
 
@@ -16,6 +16,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"simple-ec2/pkg/cli"
 	"simple-ec2/pkg/ec2helper"
 	"strings"
@@ -24,6 +25,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/olekukonko/tablewriter"
+	"golang.org/x/term"
 )
 
 const (
@@ -32,8 +34,24 @@ const (
 	headerSeperator    = "─"
 	rowColIntersect    = "┼"
 	tableLineMaxLength = 300
+	tableMargin        = 14 // The width taken by the padding and check boxes in front of table rows
+	columnOverhead     = 3  // The width taken by the padding and separator of each column
+	minColumnWidth     = 8
+	ellipsis           = "…"
+	helpKey            = "?" // Toggles the help of a question
 )
 
+// Key bindings explained in the help of each kind of question
+const (
+	singleSelectKeys = "↑/↓: move • enter: select • ctrl+c: exit"
+	multiSelectKeys  = "↑/↓: move • space: select or unselect • enter on SUBMIT: confirm • ctrl+c: exit"
+	plainTextKeys    = "type an answer • enter: confirm, or accept the default if empty • ctrl+c: exit"
+	formKeys         = "↑/↓: move • enter: edit a configuration, or submit • esc: cancel an edit • ctrl+c: exit"
+)
+
+// IsTableWrap wraps long cell content into more lines instead of truncating it with an ellipsis
+var IsTableWrap = false
+
 var (
 	// Styling to add left padding to strings
 	noStyle           = lipgloss.NewStyle()
@@ -72,6 +90,9 @@ type QuestionInput struct {
 	QuestionString    string               // The Question being asked
 	EC2Helper         *ec2helper.EC2Helper // EC2Helper to provide validation methods for text inputs
 	Fns               []CheckInput         // List of input check functions to validate text inputs
+	Suggestions       []string             // List of valid answers suggested when a text input is invalid
+	FormFields        []FormField          // List of fields reviewed and edited in form questions
+	HelpString        string               // Explains the field being asked for in the help of the question
 }
 
 /*
@@ -119,6 +140,47 @@ func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list
 	fmt.Fprintf(w, str)
 }
 
+// questionHelp is the help of a question, explaining its field and key bindings when toggled with the help key
+type questionHelp struct {
+	text    string // Explains the field being asked for
+	keys    string // Explains the key bindings of the question
+	isShown bool   // If the help is shown or only hinted at
+}
+
+// newQuestionHelp creates the help of a question with the key bindings of its kind
+func newQuestionHelp(input *QuestionInput, keys string) questionHelp {
+	return questionHelp{
+		text: input.HelpString,
+		keys: keys,
+	}
+}
+
+// toggle shows or hides the help if the message is the help key. Returns whether the help was toggled
+func (h *questionHelp) toggle(msg tea.KeyMsg) bool {
+	if h.keys == "" || msg.String() != helpKey {
+		return false
+	}
+	h.isShown = !h.isShown
+	return true
+}
+
+// view renders the help if it is shown, otherwise a hint of the help key. Questions without help render nothing
+func (h *questionHelp) view() string {
+	if h.keys == "" {
+		return ""
+	}
+	if !h.isShown {
+		return helpStyle.Render(helpKey + ": help")
+	}
+
+	b := strings.Builder{}
+	if h.text != "" {
+		b.WriteString(h.text + "\n")
+	}
+	b.WriteString(h.keys + " • " + helpKey + ": hide help")
+	return helpStyle.Render(b.String())
+}
+
 /*
 AskQuestion initializes the given question model with question input and asks the question. Finishes
 when answer is given, or user exits out of the question. Returns the error from the question
@@ -149,7 +211,8 @@ createItems creates the items for a list in a question. The items are made from
 the table's header, and a map to retrieve indexed answers.
 */
 func createItems(input *QuestionInput) (header string, itemList []list.Item, itemMap map[item]string) {
-	tableString := createQuestionTable(combineRows(input.Rows), input.HeaderStrings)
+	rows := FitTableRows(input.Rows, input.HeaderStrings, getTableWidth(), IsTableWrap)
+	tableString := createQuestionTable(combineRows(rows), input.HeaderStrings)
 	optionStrings := strings.Split(strings.TrimSuffix(tableString, "\n"), "\n")
 
 	// Remove Empty Lines
@@ -173,9 +236,9 @@ func createItems(input *QuestionInput) (header string, itemList []list.Item, ite
 	itemMap = map[item]string{}
 	index := 0
 	b := strings.Builder{}
-	if input.Rows != nil {
+	if rows != nil {
 		// Create an item for each Row
-		for i, row := range input.Rows {
+		for i, row := range rows {
 			b.Reset()
 			// Combine lines to form the Row
 			for i := 0; i < len(row); i++ {
@@ -279,6 +342,139 @@ func createQuestionTable(tableData [][]string, headers []string) string {
 	return tableString
 }
 
+// getTableWidth gets the maximum width of question tables, which is the width of the terminal if known
+func getTableWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		return tableLineMaxLength
+	}
+	return width
+}
+
+/*
+FitTableRows fits the rows of a question table in a width by narrowing its widest columns. Cells wider than
+their column are truncated with an ellipsis, or wrapped into more lines of their row if wrap is true.
+*/
+func FitTableRows(rows []Row, headers []string, width int, wrap bool) []Row {
+	if rows == nil {
+		return nil
+	}
+
+	// Columns are never narrower than their headers, or than the minimum width unless their cells are
+	columnWidths := []int{}
+	minWidths := []int{}
+	for _, row := range rows {
+		for _, line := range row {
+			for index, cell := range line {
+				for len(columnWidths) <= index {
+					columnWidths = append(columnWidths, 0)
+					minWidths = append(minWidths, 0)
+				}
+				columnWidths[index] = max(columnWidths[index], len([]rune(cell)))
+			}
+		}
+	}
+	for index := range columnWidths {
+		minWidths[index] = min(columnWidths[index], minColumnWidth)
+		if index < len(headers) {
+			minWidths[index] = max(minWidths[index], len([]rune(headers[index])))
+		}
+	}
+	narrowColumns(columnWidths, minWidths, width-tableMargin-len(columnWidths)*columnOverhead)
+
+	fittedRows := []Row{}
+	for _, row := range rows {
+		fittedRow := Row{}
+		for _, line := range row {
+			if wrap {
+				fittedRow = append(fittedRow, wrapLine(line, columnWidths)...)
+			} else {
+				fittedRow = append(fittedRow, truncateLine(line, columnWidths))
+			}
+		}
+		fittedRows = append(fittedRows, fittedRow)
+	}
+	return fittedRows
+}
+
+// narrowColumns narrows the widest columns one at a time, until the columns fit in the available width
+func narrowColumns(columnWidths []int, minWidths []int, availableWidth int) {
+	totalWidth := 0
+	for _, columnWidth := range columnWidths {
+		totalWidth += columnWidth
+	}
+
+	for totalWidth > availableWidth {
+		widest := -1
+		for index, columnWidth := range columnWidths {
+			if columnWidth > minWidths[index] && (widest < 0 || columnWidth > columnWidths[widest]) {
+				widest = index
+			}
+		}
+		if widest < 0 {
+			return
+		}
+		columnWidths[widest]--
+		totalWidth--
+	}
+}
+
+// truncateLine truncates the cells of a table line wider than their columns, ending them with an ellipsis
+func truncateLine(line []string, columnWidths []int) []string {
+	truncatedLine := []string{}
+	for index, cell := range line {
+		runes := []rune(cell)
+		if len(runes) > columnWidths[index] {
+			cell = string(runes[:columnWidths[index]-1]) + ellipsis
+		}
+		truncatedLine = append(truncatedLine, cell)
+	}
+	return truncatedLine
+}
+
+// wrapLine wraps the cells of a table line wider than their columns, spreading them over more lines
+func wrapLine(line []string, columnWidths []int) [][]string {
+	wrappedCells := [][]string{}
+	numLines := 1
+	for index, cell := range line {
+		wrappedCell := wrapText(cell, columnWidths[index])
+		wrappedCells = append(wrappedCells, wrappedCell)
+		numLines = max(numLines, len(wrappedCell))
+	}
+
+	wrappedLines := [][]string{}
+	for lineIndex := 0; lineIndex < numLines; lineIndex++ {
+		wrappedLine := []string{}
+		for _, wrappedCell := range wrappedCells {
+			if lineIndex < len(wrappedCell) {
+				wrappedLine = append(wrappedLine, wrappedCell[lineIndex])
+			} else {
+				wrappedLine = append(wrappedLine, "")
+			}
+		}
+		wrappedLines = append(wrappedLines, wrappedLine)
+	}
+	return wrappedLines
+}
+
+// wrapText wraps text into lines no wider than the width, breaking at spaces where possible
+func wrapText(text string, width int) []string {
+	lines := []string{}
+	runes := []rune(text)
+	for len(runes) > width {
+		breakIndex := width
+		for index := width; index > 0; index-- {
+			if runes[index] == ' ' {
+				breakIndex = index
+				break
+			}
+		}
+		lines = append(lines, strings.TrimRight(string(runes[:breakIndex]), " "))
+		runes = []rune(strings.TrimLeft(string(runes[breakIndex:]), " "))
+	}
+	return append(lines, string(runes))
+}
+
 // styleTableItemRows styles multiple items in a single row
 func styleTableItemRows(tableItemRow string, multiRowStyle lipgloss.Style, defaultStyle lipgloss.Style,
 	firstRowStyle lipgloss.Style) string {
@@ -0,0 +1,150 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package questionModel_test
+
+import (
+	"strings"
+	"testing"
+
+	"simple-ec2/pkg/questionModel"
+	th "simple-ec2/test/testhelper"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestFitTableRows_Truncate(t *testing.T) {
+	rows := []questionModel.Row{
+		{{"ami-12345", strings.Repeat("a", 100)}},
+	}
+
+	fittedRows := questionModel.FitTableRows(rows, []string{"Image", "Description"}, 60, false)
+	th.Equals(t, []questionModel.Row{
+		{{"ami-12345", strings.Repeat("a", 30) + "…"}},
+	}, fittedRows)
+}
+
+func TestFitTableRows_Wrap(t *testing.T) {
+	rows := []questionModel.Row{
+		{{"1.", "one two three four five six"}},
+	}
+
+	fittedRows := questionModel.FitTableRows(rows, nil, 32, true)
+	th.Equals(t, []questionModel.Row{
+		{
+			{"1.", "one two"},
+			{"", "three four"},
+			{"", "five six"},
+		},
+	}, fittedRows)
+}
+
+func TestFitTableRows_WrapWithoutSpaces(t *testing.T) {
+	rows := []questionModel.Row{
+		{{"1.", strings.Repeat("b", 25)}},
+	}
+
+	fittedRows := questionModel.FitTableRows(rows, nil, 32, true)
+	th.Equals(t, []questionModel.Row{
+		{
+			{"1.", strings.Repeat("b", 10)},
+			{"", strings.Repeat("b", 10)},
+			{"", strings.Repeat("b", 5)},
+		},
+	}, fittedRows)
+}
+
+func TestFitTableRows_Fits(t *testing.T) {
+	rows := []questionModel.Row{
+		{{"1.", "us-east-1", "US East (N. Virginia)"}},
+		{{"2.", "us-west-2", "US West (Oregon)"}},
+	}
+
+	fittedRows := questionModel.FitTableRows(rows, []string{"", "Region", "Description"}, 80, false)
+	th.Equals(t, rows, fittedRows)
+}
+
+func TestFitTableRows_HeaderWidth(t *testing.T) {
+	rows := []questionModel.Row{
+		{{strings.Repeat("c", 50)}},
+	}
+
+	fittedRows := questionModel.FitTableRows(rows, []string{"A very long header name"}, 20, false)
+	th.Equals(t, []questionModel.Row{
+		{{strings.Repeat("c", 22) + "…"}},
+	}, fittedRows)
+}
+
+func TestKeyValue_TagCount(t *testing.T) {
+	kv := &questionModel.KeyValue{}
+	kv.InitializeModel(&questionModel.QuestionInput{DefaultOption: "a|1, b|2, c|3"})
+
+	th.Assert(t, strings.Contains(kv.View(), "3 tags"), "The view should show the number of tags")
+}
+
+func TestKeyValue_ClearAll(t *testing.T) {
+	kv := &questionModel.KeyValue{}
+	kv.InitializeModel(&questionModel.QuestionInput{DefaultOption: "a|1, b|2, c|3"})
+
+	kv.Update(tea.KeyMsg{Type: tea.KeyCtrlX})
+	th.Equals(t, "", kv.TagsToString())
+	th.Assert(t, strings.Contains(kv.View(), "0 tags"), "The view should show no tags")
+
+	// Tags can be added again after clearing
+	kv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	kv.Update(tea.KeyMsg{Type: tea.KeyTab})
+	kv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("4")})
+	kv.Update(tea.KeyMsg{Type: tea.KeyTab})
+	kv.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	th.Equals(t, "d|4", kv.TagsToString())
+	th.Assert(t, strings.Contains(kv.View(), "1 tag "), "The view should show the added tag")
+}
+
+func TestSingleSelectList_ToggleHelp(t *testing.T) {
+	s := &questionModel.SingleSelectList{}
+	s.InitializeModel(&questionModel.QuestionInput{
+		QuestionString: "Pick one",
+		HelpString:     "The option to pick",
+		IndexedOptions: []string{"a", "b"},
+		Rows:           questionModel.CreateSingleLineRows([][]string{{"a"}, {"b"}}),
+	})
+	hiddenView := s.View()
+	th.Assert(t, !strings.Contains(hiddenView, "The option to pick"), "The help should be hidden by default")
+
+	s.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("?")})
+	shownView := s.View()
+	th.Assert(t, shownView != hiddenView, "Toggling the help should change the view")
+	th.Assert(t, strings.Contains(shownView, "The option to pick"), "The view should show the help text")
+	th.Assert(t, strings.Contains(shownView, "enter: select"), "The view should show the key bindings")
+
+	s.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("?")})
+	th.Equals(t, hiddenView, s.View())
+}
+
+func TestPlainText_HelpKeyInAnswer(t *testing.T) {
+	pt := &questionModel.PlainText{}
+	pt.InitializeModel(&questionModel.QuestionInput{
+		QuestionString: "Enter text",
+		HelpString:     "The text to enter",
+	})
+
+	pt.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("?")})
+	th.Assert(t, strings.Contains(pt.View(), "The text to enter"), "The help should be toggled on an empty input")
+	pt.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("?")})
+
+	// Once an answer is typed, the help key is part of the answer
+	pt.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	pt.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("?")})
+	th.Assert(t, strings.Contains(pt.View(), "a?"), "The help key should be typed in the answer")
+	th.Assert(t, !strings.Contains(pt.View(), "The text to enter"), "The help should stay hidden")
+}
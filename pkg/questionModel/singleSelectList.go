@@ -29,6 +29,7 @@ type SingleSelectList struct {
 	itemMap  map[item]string // Maps the item chosen to the answer value
 	header   string          // The header for the item list table
 	question string          // The question being asked
+	help     questionHelp    // The help of the question, toggled with the help key
 	err      error           // An error caught during the question
 }
 
@@ -55,6 +56,7 @@ func (s *SingleSelectList) InitializeModel(input *QuestionInput) {
 	s.header = header
 	s.itemMap = itemMap
 	s.question = input.QuestionString
+	s.help = newQuestionHelp(input, singleSelectKeys)
 }
 
 // Init defines an optional command that can be run when the question is asked.
@@ -79,6 +81,9 @@ func (s *SingleSelectList) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			s.selectItem()
 			return s, tea.Quit
 		}
+		if s.help.toggle(msg) {
+			return s, nil
+		}
 
 	case error:
 		s.err = msg
@@ -101,6 +106,7 @@ func (s *SingleSelectList) View() string {
 		b.WriteString(mediumLeftPadding.Render(s.header) + "\n")
 	}
 	b.WriteString(s.list.View())
+	b.WriteString(s.help.view())
 	return b.String()
 }
 
@@ -121,5 +127,6 @@ func (s *SingleSelectList) selectItem() {
 // PrintTable prints the selection table
 func (s *SingleSelectList) PrintTable() string {
 	s.list.Select(-1)
+	s.help = questionHelp{}
 	return s.View()
 }
@@ -0,0 +1,111 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package questionModel
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	textAreaWidth     = 100
+	textAreaMaxHeight = 20
+	textAreaCharLimit = 16384 // The size limit of EC2 user data. A limit of 0 blocks typing in this textarea version
+)
+
+// TextArea represents a question with a multi-line text input
+type TextArea struct {
+	textArea  textarea.Model // The multi-line text input
+	question  string         // The question being asked
+	submitted bool           // If the text was saved, rather than discarded
+	err       error          // An error caught during the question
+}
+
+// InitializeModel initializes the model based on the passed in question input
+func (ta *TextArea) InitializeModel(input *QuestionInput) {
+	textArea := textarea.New()
+	textArea.CharLimit = textAreaCharLimit
+	textArea.ShowLineNumbers = true
+	textArea.SetWidth(textAreaWidth)
+	height := strings.Count(input.DefaultOption, "\n") + 2
+	if height > textAreaMaxHeight {
+		height = textAreaMaxHeight
+	}
+	textArea.SetHeight(height)
+	textArea.SetValue(input.DefaultOption)
+	textArea.Focus()
+
+	ta.textArea = textArea
+	ta.question = input.QuestionString
+}
+
+// Init defines an optional command that can be run when the question is asked.
+func (ta *TextArea) Init() tea.Cmd {
+	return textarea.Blink
+}
+
+/*
+Update is called when a message is received. Enter inserts a new line, so the text is saved with
+ctrl+s and discarded with esc
+*/
+func (ta *TextArea) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.Type {
+		case tea.KeyCtrlC:
+			ta.err = exitError
+			return ta, tea.Quit
+
+		case tea.KeyCtrlS:
+			ta.submitted = true
+			ta.textArea.Blur()
+			return ta, tea.Quit
+
+		case tea.KeyEsc:
+			ta.textArea.Blur()
+			return ta, tea.Quit
+		}
+
+	case error:
+		ta.err = msg
+		return ta, tea.Quit
+	}
+
+	ta.textArea, cmd = ta.textArea.Update(msg)
+	return ta, cmd
+}
+
+// View renders the view for the question. The view is rendered after every update
+func (ta *TextArea) View() string {
+	b := strings.Builder{}
+	if ta.question != "" {
+		b.WriteString(ta.question + "\n\n")
+	}
+	b.WriteString(smallLeftPadding.Render(ta.textArea.View()) + "\n")
+	b.WriteString(helpStyle.Render("ctrl+s: save • esc: discard") + "\n")
+	return b.String()
+}
+
+// GetError gets the error from the question if one arose
+func (ta *TextArea) GetError() error { return ta.err }
+
+// GetTextAnswer gets the text from the text area
+func (ta *TextArea) GetTextAnswer() string { return ta.textArea.Value() }
+
+// IsSubmitted tells whether the text was saved, rather than discarded
+func (ta *TextArea) IsSubmitted() bool { return ta.submitted }
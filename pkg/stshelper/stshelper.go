@@ -0,0 +1,167 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stshelper
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/defaults"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const CredentialsErrorMessage = "Your AWS credentials are missing or expired; " +
+	"run `aws configure` or refresh your SSO session"
+
+// The profile used when none is selected with the AWS_PROFILE environment variable
+const DefaultProfile = "default"
+
+// Profile sections of the shared config file are prefixed, unlike those of the shared credentials file
+const sharedConfigProfilePrefix = "profile "
+
+// Error codes returned by AWS when the credentials are missing, invalid or expired
+var credentialsErrorCodes = []string{
+	"NoCredentialProviders",
+	"ExpiredToken",
+	"ExpiredTokenException",
+	"InvalidClientTokenId",
+	"UnrecognizedClientException",
+}
+
+type IdentityProvider interface {
+	GetCallerIdentity(input *sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error)
+}
+
+type STSHelper struct {
+	Client IdentityProvider
+}
+
+func New(sess *session.Session) *STSHelper {
+	return &STSHelper{
+		Client: sts.New(sess),
+	}
+}
+
+/*
+Check that usable credentials are available by calling GetCallerIdentity.
+Credential failures are replaced with a targeted message, while other errors are returned as is.
+*/
+func (s *STSHelper) CheckCredentials() error {
+	_, err := s.Client.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err == nil {
+		return nil
+	}
+
+	if aerr, ok := err.(awserr.Error); ok {
+		for _, code := range credentialsErrorCodes {
+			if aerr.Code() == code {
+				return errors.New(CredentialsErrorMessage)
+			}
+		}
+	}
+
+	return err
+}
+
+// Get the profile selected with the AWS_PROFILE environment variable, or the default profile
+func GetCurrentProfile() string {
+	if profile := os.Getenv("AWS_PROFILE"); profile != "" {
+		return profile
+	}
+
+	return DefaultProfile
+}
+
+// Get the paths of the shared config and credentials files, which can be overridden with environment variables
+func GetSharedFilenames() (configFilename, credentialsFilename string) {
+	configFilename = os.Getenv("AWS_CONFIG_FILE")
+	if configFilename == "" {
+		configFilename = defaults.SharedConfigFilename()
+	}
+	credentialsFilename = os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if credentialsFilename == "" {
+		credentialsFilename = defaults.SharedCredentialsFilename()
+	}
+
+	return configFilename, credentialsFilename
+}
+
+/*
+Get the sorted names of the profiles defined in the shared config and credentials files.
+A profile defined in both files is only listed once, and a missing file defines no profile.
+*/
+func GetProfileNames(configFilename, credentialsFilename string) ([]string, error) {
+	configProfiles, err := readProfileNames(configFilename, true)
+	if err != nil {
+		return nil, err
+	}
+	credentialsProfiles, err := readProfileNames(credentialsFilename, false)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := []string{}
+	isListed := map[string]bool{}
+	for _, profile := range append(configProfiles, credentialsProfiles...) {
+		if !isListed[profile] {
+			isListed[profile] = true
+			profiles = append(profiles, profile)
+		}
+	}
+	sort.Strings(profiles)
+
+	return profiles, nil
+}
+
+/*
+Read the names of the profiles defined by the sections of a shared file. In the config file, profile sections
+are prefixed with "profile " except for the default profile, and other sections such as sso-session are skipped.
+*/
+func readProfileNames(filename string, isConfigFile bool) ([]string, error) {
+	file, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	profiles := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+			continue
+		}
+
+		section := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(line, "["), "]"))
+		if isConfigFile && section != DefaultProfile {
+			if !strings.HasPrefix(section, sharedConfigProfilePrefix) {
+				continue
+			}
+			section = strings.TrimSpace(strings.TrimPrefix(section, sharedConfigProfilePrefix))
+		}
+		if section != "" {
+			profiles = append(profiles, section)
+		}
+	}
+
+	return profiles, scanner.Err()
+}
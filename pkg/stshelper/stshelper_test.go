@@ -0,0 +1,115 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stshelper_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"simple-ec2/pkg/stshelper"
+	th "simple-ec2/test/testhelper"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+func TestCheckCredentials_Success(t *testing.T) {
+	s := &stshelper.STSHelper{Client: &th.MockedSTSSvc{}}
+
+	err := s.CheckCredentials()
+	th.Ok(t, err)
+}
+
+func TestCheckCredentials_ExpiredToken(t *testing.T) {
+	s := &stshelper.STSHelper{Client: &th.MockedSTSSvc{
+		GetCallerIdentityError: awserr.New("ExpiredToken", "The security token included in the request is expired", nil),
+	}}
+
+	err := s.CheckCredentials()
+	th.Nok(t, err)
+	th.Equals(t, stshelper.CredentialsErrorMessage, err.Error())
+}
+
+func TestCheckCredentials_NoCredentials(t *testing.T) {
+	s := &stshelper.STSHelper{Client: &th.MockedSTSSvc{
+		GetCallerIdentityError: awserr.New("NoCredentialProviders", "no valid providers in chain", nil),
+	}}
+
+	err := s.CheckCredentials()
+	th.Nok(t, err)
+	th.Equals(t, stshelper.CredentialsErrorMessage, err.Error())
+}
+
+func TestCheckCredentials_OtherError(t *testing.T) {
+	s := &stshelper.STSHelper{Client: &th.MockedSTSSvc{
+		GetCallerIdentityError: errors.New("Test error"),
+	}}
+
+	err := s.CheckCredentials()
+	th.Nok(t, err)
+	th.Equals(t, "Test error", err.Error())
+}
+
+const testSharedConfig = `[default]
+region = us-east-1
+
+# A comment
+[profile dev]
+region = us-west-2
+[ profile prod ]
+sso_session = my-sso
+
+[sso-session my-sso]
+sso_region = us-east-1
+`
+
+const testSharedCredentials = `[default]
+aws_access_key_id = AKIAEXAMPLE
+[legacy]
+aws_access_key_id = AKIAEXAMPLE
+[dev]
+aws_access_key_id = AKIAEXAMPLE
+`
+
+func TestGetProfileNames(t *testing.T) {
+	configFilename := t.TempDir() + "/config"
+	credentialsFilename := t.TempDir() + "/credentials"
+	th.Ok(t, os.WriteFile(configFilename, []byte(testSharedConfig), 0644))
+	th.Ok(t, os.WriteFile(credentialsFilename, []byte(testSharedCredentials), 0644))
+
+	profiles, err := stshelper.GetProfileNames(configFilename, credentialsFilename)
+	th.Ok(t, err)
+	th.Equals(t, []string{"default", "dev", "legacy", "prod"}, profiles)
+}
+
+func TestGetProfileNames_MissingFiles(t *testing.T) {
+	configFilename := t.TempDir() + "/config"
+	th.Ok(t, os.WriteFile(configFilename, []byte(testSharedConfig), 0644))
+
+	profiles, err := stshelper.GetProfileNames(configFilename, t.TempDir()+"/credentials")
+	th.Ok(t, err)
+	th.Equals(t, []string{"default", "dev", "prod"}, profiles)
+
+	profiles, err = stshelper.GetProfileNames(t.TempDir()+"/config", t.TempDir()+"/credentials")
+	th.Ok(t, err)
+	th.Equals(t, []string{}, profiles)
+}
+
+func TestGetCurrentProfile(t *testing.T) {
+	t.Setenv("AWS_PROFILE", "")
+	th.Equals(t, stshelper.DefaultProfile, stshelper.GetCurrentProfile())
+
+	t.Setenv("AWS_PROFILE", "dev")
+	th.Equals(t, "dev", stshelper.GetCurrentProfile())
+}
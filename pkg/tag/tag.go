@@ -15,12 +15,34 @@ package tag
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 )
 
+// The tag marking the resources created by simple-ec2
+const (
+	CreatedByKey   = "CreatedBy"
+	CreatedByValue = "simple-ec2"
+)
+
+/*
+The tag of the instances launched without a public IP address, which simple-ec2 connect reaches with Session Manager
+instead of SSH
+*/
+const (
+	ConnectWithKey = "simple-ec2:connect-with"
+	ConnectWithSsm = "ssm"
+)
+
+// The separators between the key and the value of a tag, in flags and in interactive mode
+const (
+	FlagSeparator        = "="
+	InteractiveSeparator = "|"
+)
+
 // Get the tags for resources created by simple-ec2
 func GetSimpleEc2Tags() *map[string]string {
 	now := time.Now()
@@ -29,7 +51,7 @@ func GetSimpleEc2Tags() *map[string]string {
 		now.Second(), zone)
 
 	tags := map[string]string{
-		"CreatedBy":   "simple-ec2",
+		CreatedByKey:  CreatedByValue,
 		"CreatedTime": nowString,
 	}
 	return &tags
@@ -45,3 +67,32 @@ func GetTagAsFilter(userTags map[string]string) (filters []*ec2.Filter, err erro
 	}
 	return filters, nil
 }
+
+/*
+Parse tags of the form key<separator>value into a map, trimming the whitespace around keys and values.
+Empty keys are rejected. When a key is defined more than once, the last value wins and the key is returned
+in the duplicate keys. No tags result in a nil map.
+*/
+func ParseTags(rawTags []string, separator string) (tags map[string]string, duplicateKeys []string, err error) {
+	for _, rawTag := range rawTags {
+		kv := strings.SplitN(rawTag, separator, 2)
+		if len(kv) != 2 {
+			return nil, nil, fmt.Errorf("Tag \"%s\" must be of the form key%svalue", rawTag, separator)
+		}
+
+		key := strings.TrimSpace(kv[0])
+		if key == "" {
+			return nil, nil, fmt.Errorf("Tag \"%s\" has an empty key", rawTag)
+		}
+
+		if tags == nil {
+			tags = map[string]string{}
+		}
+		if _, found := tags[key]; found {
+			duplicateKeys = append(duplicateKeys, key)
+		}
+		tags[key] = strings.TrimSpace(kv[1])
+	}
+
+	return tags, duplicateKeys, nil
+}
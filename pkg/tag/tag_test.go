@@ -75,3 +75,39 @@ func TestGetTagAsFilter(t *testing.T) {
 		th.Assert(t, thisTagMatches, fmt.Sprintf("Unable to find matching actual tag filter for expected tag filter %s", *expectedTag.Name))
 	}
 }
+
+func TestParseTags_Flags(t *testing.T) {
+	tags, duplicateKeys, err := tag.ParseTags([]string{" env = prod", "url=a=b", "team="}, tag.FlagSeparator)
+	th.Ok(t, err)
+	th.Equals(t, map[string]string{"env": "prod", "url": "a=b", "team": ""}, tags)
+	th.Equals(t, 0, len(duplicateKeys))
+}
+
+func TestParseTags_Interactive(t *testing.T) {
+	tags, _, err := tag.ParseTags([]string{"tag1|val1", " tag2|val2"}, tag.InteractiveSeparator)
+	th.Ok(t, err)
+	th.Equals(t, map[string]string{"tag1": "val1", "tag2": "val2"}, tags)
+}
+
+func TestParseTags_DuplicateKeys(t *testing.T) {
+	tags, duplicateKeys, err := tag.ParseTags([]string{"env=dev", "env =prod"}, tag.FlagSeparator)
+	th.Ok(t, err)
+	th.Equals(t, map[string]string{"env": "prod"}, tags)
+	th.Equals(t, []string{"env"}, duplicateKeys)
+}
+
+func TestParseTags_EmptyKey(t *testing.T) {
+	_, _, err := tag.ParseTags([]string{"env=prod", " =value"}, tag.FlagSeparator)
+	th.Nok(t, err)
+}
+
+func TestParseTags_NoSeparator(t *testing.T) {
+	_, _, err := tag.ParseTags([]string{"env"}, tag.FlagSeparator)
+	th.Nok(t, err)
+}
+
+func TestParseTags_NoTags(t *testing.T) {
+	tags, _, err := tag.ParseTags(nil, tag.FlagSeparator)
+	th.Ok(t, err)
+	th.Assert(t, tags == nil, "No tags should result in a nil map")
+}
@@ -1,6 +1,7 @@
 package cfn_e2e
 
 import (
+	"context"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/service/ec2"
@@ -42,7 +43,7 @@ func TestCreateStackAndGetResources(t *testing.T) {
 		c.Svc = cloudformation.New(sess)
 	}
 
-	vpcId, subnetIds, instanceId, _, err := c.CreateStackAndGetResources(testAvailabilityZones,
+	vpcId, subnetIds, instanceId, _, err := c.CreateStackAndGetResources(context.Background(), testAvailabilityZones,
 		aws.String(testStackName), cfn.E2eCfnTestCloudformationTemplate)
 	if err != nil {
 		t.Fatal(err)
@@ -14,6 +14,7 @@
 package connect_e2e
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -48,7 +49,7 @@ func TestSetupEnvironment(t *testing.T) {
 		c.Svc = cloudformation.New(sess)
 	}
 
-	_, _, instanceId, _, err = c.CreateStackAndGetResources(nil, aws.String(testStackName),
+	_, _, instanceId, _, err = c.CreateStackAndGetResources(context.Background(), nil, aws.String(testStackName),
 		cfn.E2eConnectTestCloudformationTemplate)
 	if err != nil {
 		t.Fatal(err)
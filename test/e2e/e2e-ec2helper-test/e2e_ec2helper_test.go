@@ -14,6 +14,7 @@
 package ec2helper_e2e
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"testing"
@@ -57,7 +58,7 @@ func TestSetupEnvironment(t *testing.T) {
 		c.Svc = cloudformation.New(sess)
 	}
 
-	vpcId, subnetIds, instanceId, resources, err = c.CreateStackAndGetResources(nil, aws.String(testStackName),
+	vpcId, subnetIds, instanceId, resources, err = c.CreateStackAndGetResources(context.Background(), nil, aws.String(testStackName),
 		cfn.E2eEc2helperTestCloudformationTemplate)
 	th.Ok(t, err)
 
@@ -149,7 +150,9 @@ func TestGetInstanceTypesFromInstanceSelector(t *testing.T) {
 	th.Assert(t, h != nil, "EC2Helper was not initialized successfully")
 
 	instanceSelector := selector.New(h.Sess)
-	_, err := h.GetInstanceTypesFromInstanceSelector(instanceSelector, 2, 4)
+	filters, err := ec2helper.GetInstanceSelectorFilters(2, 4)
+	th.Ok(t, err)
+	_, err = h.GetInstanceTypesFromInstanceSelector(instanceSelector, filters)
 	th.Ok(t, err)
 }
 
@@ -264,7 +267,7 @@ func TestCreateSecurityGroupForSsh(t *testing.T) {
 	th.Assert(t, vpcId != nil, "No test VPC found")
 
 	// Create the security group
-	newSecurityGroupId, err := h.CreateSecurityGroupForSsh(*vpcId)
+	newSecurityGroupId, err := h.CreateSecurityGroupForSsh(*vpcId, 22, "")
 	th.Ok(t, err)
 	th.Assert(t, newSecurityGroupId != nil, "new security group should not be nil")
 
@@ -337,7 +340,7 @@ func TestParseConfig(t *testing.T) {
 func TestGetDefaultSimpleConfig(t *testing.T) {
 	th.Assert(t, h != nil, "EC2Helper was not initialized successfully")
 
-	simpleConfig, err := h.GetDefaultSimpleConfig()
+	simpleConfig, err := h.GetDefaultSimpleConfig("")
 	th.Ok(t, err)
 	th.Assert(t, simpleConfig.InstanceType != "", "InstanceType should not be empty")
 	th.Assert(t, simpleConfig.ImageId != "", "ImageId should not be empty")
@@ -26,6 +26,9 @@ type MockedCfnSvc struct {
 	StackResources                []*cfn.StackResource
 	StackId                       *string
 	EventCounter                  int
+	DeletedStackNames             []string
+	DescribeStacksPagesError      error
+	Stacks                        []*cfn.Stack
 }
 
 func (c *MockedCfnSvc) CreateStack(input *cfn.CreateStackInput) (*cfn.CreateStackOutput, error) {
@@ -61,5 +64,18 @@ func (c *MockedCfnSvc) DescribeStackEventsPages(input *cfn.DescribeStackEventsIn
 }
 
 func (c *MockedCfnSvc) DeleteStack(input *cfn.DeleteStackInput) (*cfn.DeleteStackOutput, error) {
+	c.DeletedStackNames = append(c.DeletedStackNames, *input.StackName)
 	return nil, c.DeleteStackError
 }
+
+func (c *MockedCfnSvc) DescribeStacksPages(input *cfn.DescribeStacksInput, fn func(*cfn.DescribeStacksOutput, bool) bool) error {
+	output := &cfn.DescribeStacksOutput{
+		Stacks: c.Stacks,
+	}
+
+	for {
+		if !fn(output, true) {
+			return c.DescribeStacksPagesError
+		}
+	}
+}
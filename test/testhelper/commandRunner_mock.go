@@ -0,0 +1,35 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package testhelper
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+type MockedCommandRunner struct {
+	WaitForSSHError error
+	RunCommandError error
+	Calls           []string // The steps taken, such as "wait i-12345" and "run i-12345 setup.sh", in order
+}
+
+func (r *MockedCommandRunner) WaitForSSH(instance *ec2.Instance) error {
+	r.Calls = append(r.Calls, "wait "+aws.StringValue(instance.InstanceId))
+	return r.WaitForSSHError
+}
+
+func (r *MockedCommandRunner) RunCommand(instance *ec2.Instance, command string) error {
+	r.Calls = append(r.Calls, "run "+aws.StringValue(instance.InstanceId)+" "+command)
+	return r.RunCommandError
+}
@@ -0,0 +1,40 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package testhelper
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2instanceconnect"
+)
+
+type MockedEC2InstanceConnectSvc struct {
+	SendSSHPublicKeyError  error
+	SendSSHPublicKeyFailed bool
+	SendSSHPublicKeyInputs []*ec2instanceconnect.SendSSHPublicKeyInput
+}
+
+func (s *MockedEC2InstanceConnectSvc) SendSSHPublicKey(input *ec2instanceconnect.SendSSHPublicKeyInput) (
+	*ec2instanceconnect.SendSSHPublicKeyOutput, error) {
+	if s.SendSSHPublicKeyError != nil {
+		return nil, s.SendSSHPublicKeyError
+	}
+
+	s.SendSSHPublicKeyInputs = append(s.SendSSHPublicKeyInputs, input)
+
+	output := &ec2instanceconnect.SendSSHPublicKeyOutput{
+		RequestId: aws.String("request-id"),
+		Success:   aws.Bool(!s.SendSSHPublicKeyFailed),
+	}
+	return output, nil
+}
@@ -0,0 +1,52 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package testhelper
+
+import (
+	"github.com/aws/aws-sdk-go/service/elbv2"
+)
+
+type MockedELBSvc struct {
+	TargetGroups              []*elbv2.TargetGroup
+	DescribeTargetGroupsError error
+	RegisterTargetsError      error
+	RegisterTargetsInputs     []*elbv2.RegisterTargetsInput
+}
+
+// Describe the target groups of the requested ARNs
+func (e *MockedELBSvc) DescribeTargetGroups(input *elbv2.DescribeTargetGroupsInput) (*elbv2.DescribeTargetGroupsOutput, error) {
+	if e.DescribeTargetGroupsError != nil {
+		return nil, e.DescribeTargetGroupsError
+	}
+
+	targetGroups := []*elbv2.TargetGroup{}
+	for _, arn := range input.TargetGroupArns {
+		for _, targetGroup := range e.TargetGroups {
+			if *targetGroup.TargetGroupArn == *arn {
+				targetGroups = append(targetGroups, targetGroup)
+			}
+		}
+	}
+
+	return &elbv2.DescribeTargetGroupsOutput{TargetGroups: targetGroups}, nil
+}
+
+func (e *MockedELBSvc) RegisterTargets(input *elbv2.RegisterTargetsInput) (*elbv2.RegisterTargetsOutput, error) {
+	e.RegisterTargetsInputs = append(e.RegisterTargetsInputs, input)
+	if e.RegisterTargetsError != nil {
+		return nil, e.RegisterTargetsError
+	}
+
+	return &elbv2.RegisterTargetsOutput{}, nil
+}
@@ -15,12 +15,19 @@ package testhelper
 
 import (
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/iam"
 )
 
 type MockedIAMSvc struct {
-	ListInstanceProfilesError error
-	InstanceProfiles          []*iam.InstanceProfile
+	ListInstanceProfilesError    error
+	CreateServiceLinkedRoleError error
+	InstanceProfiles             []*iam.InstanceProfile
+	ServiceLinkedRoleServices    []string
+	GetInstanceProfileError      error
+	CreateRoleError              error
+	CreatedRoles                 []string
+	AttachedPolicyArns           []string
 }
 
 func (i *MockedIAMSvc) ListInstanceProfiles(input *iam.ListInstanceProfilesInput) (*iam.ListInstanceProfilesOutput, error) {
@@ -31,3 +38,57 @@ func (i *MockedIAMSvc) ListInstanceProfiles(input *iam.ListInstanceProfilesInput
 	}
 	return output, i.ListInstanceProfilesError
 }
+
+func (i *MockedIAMSvc) CreateServiceLinkedRole(input *iam.CreateServiceLinkedRoleInput) (*iam.CreateServiceLinkedRoleOutput, error) {
+	if i.CreateServiceLinkedRoleError != nil {
+		return nil, i.CreateServiceLinkedRoleError
+	}
+
+	i.ServiceLinkedRoleServices = append(i.ServiceLinkedRoleServices, *input.AWSServiceName)
+	return &iam.CreateServiceLinkedRoleOutput{}, nil
+}
+
+// Get the instance profile of the name among the instance profiles, or a NoSuchEntity error
+func (i *MockedIAMSvc) GetInstanceProfile(input *iam.GetInstanceProfileInput) (*iam.GetInstanceProfileOutput, error) {
+	if i.GetInstanceProfileError != nil {
+		return nil, i.GetInstanceProfileError
+	}
+
+	for _, profile := range i.InstanceProfiles {
+		if *profile.InstanceProfileName == *input.InstanceProfileName {
+			return &iam.GetInstanceProfileOutput{InstanceProfile: profile}, nil
+		}
+	}
+	return nil, awserr.New(iam.ErrCodeNoSuchEntityException, "Instance profile not found", nil)
+}
+
+func (i *MockedIAMSvc) CreateRole(input *iam.CreateRoleInput) (*iam.CreateRoleOutput, error) {
+	if i.CreateRoleError != nil {
+		return nil, i.CreateRoleError
+	}
+
+	i.CreatedRoles = append(i.CreatedRoles, *input.RoleName)
+	return &iam.CreateRoleOutput{Role: &iam.Role{RoleName: input.RoleName}}, nil
+}
+
+func (i *MockedIAMSvc) AttachRolePolicy(input *iam.AttachRolePolicyInput) (*iam.AttachRolePolicyOutput, error) {
+	i.AttachedPolicyArns = append(i.AttachedPolicyArns, *input.PolicyArn)
+	return &iam.AttachRolePolicyOutput{}, nil
+}
+
+func (i *MockedIAMSvc) CreateInstanceProfile(input *iam.CreateInstanceProfileInput) (*iam.CreateInstanceProfileOutput, error) {
+	profile := &iam.InstanceProfile{InstanceProfileName: input.InstanceProfileName}
+	i.InstanceProfiles = append(i.InstanceProfiles, profile)
+	return &iam.CreateInstanceProfileOutput{InstanceProfile: profile}, nil
+}
+
+// Add the role to the instance profile of the name among the instance profiles
+func (i *MockedIAMSvc) AddRoleToInstanceProfile(input *iam.AddRoleToInstanceProfileInput) (*iam.AddRoleToInstanceProfileOutput, error) {
+	for _, profile := range i.InstanceProfiles {
+		if *profile.InstanceProfileName == *input.InstanceProfileName {
+			profile.Roles = append(profile.Roles, &iam.Role{RoleName: input.RoleName})
+			return &iam.AddRoleToInstanceProfileOutput{}, nil
+		}
+	}
+	return nil, awserr.New(iam.ErrCodeNoSuchEntityException, "Instance profile not found", nil)
+}
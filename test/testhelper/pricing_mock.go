@@ -0,0 +1,59 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package testhelper
+
+import (
+	"fmt"
+)
+
+type MockedPricing struct {
+	OnDemandPrice float64
+	SpotPrice     float64
+	// The prices of each instance type in place of the prices above, when set. Other instance types have no price
+	OnDemandPrices map[string]float64
+	SpotPrices     map[string]float64
+	PricingError   error
+	LookupCalls    int
+}
+
+func (p *MockedPricing) GetOnDemandInstanceTypeCost(instanceType string) (float64, error) {
+	p.LookupCalls++
+	if p.PricingError != nil {
+		return 0, p.PricingError
+	}
+
+	return getMockedPrice(p.OnDemandPrice, p.OnDemandPrices, instanceType)
+}
+
+func (p *MockedPricing) GetSpotInstanceTypeNDayAvgCost(instanceType string, availabilityZones []string,
+	days int) (float64, error) {
+	p.LookupCalls++
+	if p.PricingError != nil {
+		return 0, p.PricingError
+	}
+
+	return getMockedPrice(p.SpotPrice, p.SpotPrices, instanceType)
+}
+
+func getMockedPrice(price float64, prices map[string]float64, instanceType string) (float64, error) {
+	if prices == nil {
+		return price, nil
+	}
+
+	price, found := prices[instanceType]
+	if !found {
+		return 0, fmt.Errorf("No price for instance type %s", instanceType)
+	}
+	return price, nil
+}
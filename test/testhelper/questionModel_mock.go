@@ -7,13 +7,20 @@ import (
 )
 
 type MockedQMHelperSvc struct {
-	UserInputs []tea.Msg
+	UserInputs            []tea.Msg
+	UserInputsPerQuestion [][]tea.Msg // The inputs of each question in order, used instead of UserInputs if set
+	QuestionInputs        []*questionModel.QuestionInput
 }
 
 func (m *MockedQMHelperSvc) AskQuestion(model questionModel.QuestionModel, questionInput *questionModel.QuestionInput) error {
 	var err error
+	userInputs := m.UserInputs
+	if m.UserInputsPerQuestion != nil {
+		userInputs = m.UserInputsPerQuestion[len(m.QuestionInputs)]
+	}
+	m.QuestionInputs = append(m.QuestionInputs, questionInput)
 	model.InitializeModel(questionInput)
-	for _, input := range m.UserInputs {
+	for _, input := range userInputs {
 		model.Update(input)
 		if model.GetError() != nil {
 			err = model.GetError()
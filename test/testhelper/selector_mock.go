@@ -14,15 +14,42 @@
 package testhelper
 
 import (
+	"strings"
+
 	"github.com/aws/amazon-ec2-instance-selector/v2/pkg/instancetypes"
 	"github.com/aws/amazon-ec2-instance-selector/v2/pkg/selector"
 )
 
 type MockedSelector struct {
-	SelectorError error
-	InstanceTypes []*instancetypes.Details
+	SelectorError        error
+	InstanceTypes        []*instancetypes.Details
+	InstanceTypesPerCall [][]*instancetypes.Details // Returned in order, one per call, before InstanceTypes
+	FilterVerboseCalls   int
+	LastFilters          selector.Filters
 }
 
 func (s *MockedSelector) FilterVerbose(filters selector.Filters) ([]*instancetypes.Details, error) {
-	return s.InstanceTypes, s.SelectorError
+	s.FilterVerboseCalls++
+	s.LastFilters = filters
+	instanceTypes := s.InstanceTypes
+	if s.FilterVerboseCalls <= len(s.InstanceTypesPerCall) {
+		instanceTypes = s.InstanceTypesPerCall[s.FilterVerboseCalls-1]
+	}
+
+	return filterBurstable(instanceTypes, filters.Burstable), s.SelectorError
+}
+
+// Exclude the burstable instance types when they aren't allowed, as instance selector does by the t family
+func filterBurstable(instanceTypes []*instancetypes.Details, burstable *bool) []*instancetypes.Details {
+	if burstable == nil || *burstable {
+		return instanceTypes
+	}
+
+	filtered := []*instancetypes.Details{}
+	for _, instanceType := range instanceTypes {
+		if !strings.HasPrefix(*instanceType.InstanceType, "t") {
+			filtered = append(filtered, instanceType)
+		}
+	}
+	return filtered
 }